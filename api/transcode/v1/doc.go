@@ -0,0 +1,6 @@
+// Package transcodev1 is the gRPC API for submitting pipeline jobs to a
+// server started with "cli grpc" and following their progress. Clients in
+// other languages generate their stubs from transcode.proto.
+package transcodev1
+
+//go:generate protoc -I ../../.. --go_out=../../.. --go_opt=paths=source_relative --go-grpc_out=../../.. --go-grpc_opt=paths=source_relative api/transcode/v1/transcode.proto
@@ -0,0 +1,1541 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        v5.29.3
+// source: api/transcode/v1/transcode.proto
+
+// Job submission and monitoring for the dotgo-transcode pipeline.
+
+package transcodev1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// JobState is the lifecycle position of a job.
+type JobState int32
+
+const (
+	JobState_JOB_STATE_UNSPECIFIED JobState = 0
+	JobState_JOB_STATE_QUEUED      JobState = 1
+	JobState_JOB_STATE_RUNNING     JobState = 2
+	JobState_JOB_STATE_SUCCEEDED   JobState = 3
+	JobState_JOB_STATE_FAILED      JobState = 4
+	JobState_JOB_STATE_CANCELLED   JobState = 5
+	// Preempted by higher-priority work; resumes when a slot frees up.
+	JobState_JOB_STATE_PAUSED JobState = 6
+)
+
+// Enum value maps for JobState.
+var (
+	JobState_name = map[int32]string{
+		0: "JOB_STATE_UNSPECIFIED",
+		1: "JOB_STATE_QUEUED",
+		2: "JOB_STATE_RUNNING",
+		3: "JOB_STATE_SUCCEEDED",
+		4: "JOB_STATE_FAILED",
+		5: "JOB_STATE_CANCELLED",
+		6: "JOB_STATE_PAUSED",
+	}
+	JobState_value = map[string]int32{
+		"JOB_STATE_UNSPECIFIED": 0,
+		"JOB_STATE_QUEUED":      1,
+		"JOB_STATE_RUNNING":     2,
+		"JOB_STATE_SUCCEEDED":   3,
+		"JOB_STATE_FAILED":      4,
+		"JOB_STATE_CANCELLED":   5,
+		"JOB_STATE_PAUSED":      6,
+	}
+)
+
+func (x JobState) Enum() *JobState {
+	p := new(JobState)
+	*p = x
+	return p
+}
+
+func (x JobState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (JobState) Descriptor() protoreflect.EnumDescriptor {
+	return file_api_transcode_v1_transcode_proto_enumTypes[0].Descriptor()
+}
+
+func (JobState) Type() protoreflect.EnumType {
+	return &file_api_transcode_v1_transcode_proto_enumTypes[0]
+}
+
+func (x JobState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use JobState.Descriptor instead.
+func (JobState) EnumDescriptor() ([]byte, []int) {
+	return file_api_transcode_v1_transcode_proto_rawDescGZIP(), []int{0}
+}
+
+type SubmitJobRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Profile:
+	//
+	//	*SubmitJobRequest_ProfilePath
+	//	*SubmitJobRequest_ProfileDocument
+	Profile isSubmitJobRequest_Profile `protobuf_oneof:"profile"`
+	// Overlay profile files on the server, merged on top of the profile in order.
+	Overlays []string `protobuf:"bytes,3,rep,name=overlays,proto3" json:"overlays,omitempty"`
+	// "hls" (default) or "dash".
+	StreamFormat string `protobuf:"bytes,4,opt,name=stream_format,json=streamFormat,proto3" json:"stream_format,omitempty"`
+	// Stage names to skip (e.g. "thumbnail", "preview").
+	SkipStages []string `protobuf:"bytes,5,rep,name=skip_stages,json=skipStages,proto3" json:"skip_stages,omitempty"`
+	// Client-chosen key (e.g. a CMS asset revision). Resubmitting with the
+	// same key returns the original job whatever its state; reusing it for a
+	// different source or settings fails with ALREADY_EXISTS.
+	IdempotencyKey string `protobuf:"bytes,6,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	// Queue a new job even if an identical one (same source content and
+	// settings) is queued, running, or has succeeded.
+	Force bool `protobuf:"varint,7,opt,name=force,proto3" json:"force,omitempty"`
+	// Queue order: higher runs first. -1 is back catalog, 0 (default) normal,
+	// 1 new release. On servers with preemption enabled, higher-priority jobs
+	// pause lower-priority running ones.
+	Priority      int32 `protobuf:"varint,8,opt,name=priority,proto3" json:"priority,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitJobRequest) Reset() {
+	*x = SubmitJobRequest{}
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitJobRequest) ProtoMessage() {}
+
+func (x *SubmitJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitJobRequest.ProtoReflect.Descriptor instead.
+func (*SubmitJobRequest) Descriptor() ([]byte, []int) {
+	return file_api_transcode_v1_transcode_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *SubmitJobRequest) GetProfile() isSubmitJobRequest_Profile {
+	if x != nil {
+		return x.Profile
+	}
+	return nil
+}
+
+func (x *SubmitJobRequest) GetProfilePath() string {
+	if x != nil {
+		if x, ok := x.Profile.(*SubmitJobRequest_ProfilePath); ok {
+			return x.ProfilePath
+		}
+	}
+	return ""
+}
+
+func (x *SubmitJobRequest) GetProfileDocument() []byte {
+	if x != nil {
+		if x, ok := x.Profile.(*SubmitJobRequest_ProfileDocument); ok {
+			return x.ProfileDocument
+		}
+	}
+	return nil
+}
+
+func (x *SubmitJobRequest) GetOverlays() []string {
+	if x != nil {
+		return x.Overlays
+	}
+	return nil
+}
+
+func (x *SubmitJobRequest) GetStreamFormat() string {
+	if x != nil {
+		return x.StreamFormat
+	}
+	return ""
+}
+
+func (x *SubmitJobRequest) GetSkipStages() []string {
+	if x != nil {
+		return x.SkipStages
+	}
+	return nil
+}
+
+func (x *SubmitJobRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+func (x *SubmitJobRequest) GetForce() bool {
+	if x != nil {
+		return x.Force
+	}
+	return false
+}
+
+func (x *SubmitJobRequest) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+type isSubmitJobRequest_Profile interface {
+	isSubmitJobRequest_Profile()
+}
+
+type SubmitJobRequest_ProfilePath struct {
+	// Profile file on the server: a path, or a bare filename under profiles/.
+	ProfilePath string `protobuf:"bytes,1,opt,name=profile_path,json=profilePath,proto3,oneof"`
+}
+
+type SubmitJobRequest_ProfileDocument struct {
+	// Inline JSON or YAML profile document.
+	ProfileDocument []byte `protobuf:"bytes,2,opt,name=profile_document,json=profileDocument,proto3,oneof"`
+}
+
+func (*SubmitJobRequest_ProfilePath) isSubmitJobRequest_Profile() {}
+
+func (*SubmitJobRequest_ProfileDocument) isSubmitJobRequest_Profile() {}
+
+type SubmitJobResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Job   *Job                   `protobuf:"bytes,1,opt,name=job,proto3" json:"job,omitempty"`
+	// True when the submission attached to an existing job instead of
+	// queuing a new one.
+	Deduplicated  bool `protobuf:"varint,2,opt,name=deduplicated,proto3" json:"deduplicated,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubmitJobResponse) Reset() {
+	*x = SubmitJobResponse{}
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubmitJobResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubmitJobResponse) ProtoMessage() {}
+
+func (x *SubmitJobResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubmitJobResponse.ProtoReflect.Descriptor instead.
+func (*SubmitJobResponse) Descriptor() ([]byte, []int) {
+	return file_api_transcode_v1_transcode_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SubmitJobResponse) GetJob() *Job {
+	if x != nil {
+		return x.Job
+	}
+	return nil
+}
+
+func (x *SubmitJobResponse) GetDeduplicated() bool {
+	if x != nil {
+		return x.Deduplicated
+	}
+	return false
+}
+
+type GetJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetJobRequest) Reset() {
+	*x = GetJobRequest{}
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetJobRequest) ProtoMessage() {}
+
+func (x *GetJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetJobRequest.ProtoReflect.Descriptor instead.
+func (*GetJobRequest) Descriptor() ([]byte, []int) {
+	return file_api_transcode_v1_transcode_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetJobRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+// ListJobsRequest filters the job history. Unset fields match everything.
+type ListJobsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Any of these states.
+	States []JobState `protobuf:"varint,1,rep,packed,name=states,proto3,enum=dotgo.transcode.v1.JobState" json:"states,omitempty"`
+	// Exact source media path.
+	InputPath string `protobuf:"bytes,2,opt,name=input_path,json=inputPath,proto3" json:"input_path,omitempty"`
+	// Submitted at or after.
+	Since *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=since,proto3" json:"since,omitempty"`
+	// Submitted before.
+	Until *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=until,proto3" json:"until,omitempty"`
+	// Maximum jobs returned; 0 for all.
+	Limit         int32 `protobuf:"varint,5,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListJobsRequest) Reset() {
+	*x = ListJobsRequest{}
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListJobsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListJobsRequest) ProtoMessage() {}
+
+func (x *ListJobsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListJobsRequest.ProtoReflect.Descriptor instead.
+func (*ListJobsRequest) Descriptor() ([]byte, []int) {
+	return file_api_transcode_v1_transcode_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListJobsRequest) GetStates() []JobState {
+	if x != nil {
+		return x.States
+	}
+	return nil
+}
+
+func (x *ListJobsRequest) GetInputPath() string {
+	if x != nil {
+		return x.InputPath
+	}
+	return ""
+}
+
+func (x *ListJobsRequest) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *ListJobsRequest) GetUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Until
+	}
+	return nil
+}
+
+func (x *ListJobsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListJobsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Jobs          []*Job                 `protobuf:"bytes,1,rep,name=jobs,proto3" json:"jobs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListJobsResponse) Reset() {
+	*x = ListJobsResponse{}
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListJobsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListJobsResponse) ProtoMessage() {}
+
+func (x *ListJobsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListJobsResponse.ProtoReflect.Descriptor instead.
+func (*ListJobsResponse) Descriptor() ([]byte, []int) {
+	return file_api_transcode_v1_transcode_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListJobsResponse) GetJobs() []*Job {
+	if x != nil {
+		return x.Jobs
+	}
+	return nil
+}
+
+type CancelJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CancelJobRequest) Reset() {
+	*x = CancelJobRequest{}
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CancelJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelJobRequest) ProtoMessage() {}
+
+func (x *CancelJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelJobRequest.ProtoReflect.Descriptor instead.
+func (*CancelJobRequest) Descriptor() ([]byte, []int) {
+	return file_api_transcode_v1_transcode_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CancelJobRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+type WatchJobRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	JobId         string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchJobRequest) Reset() {
+	*x = WatchJobRequest{}
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchJobRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchJobRequest) ProtoMessage() {}
+
+func (x *WatchJobRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchJobRequest.ProtoReflect.Descriptor instead.
+func (*WatchJobRequest) Descriptor() ([]byte, []int) {
+	return file_api_transcode_v1_transcode_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *WatchJobRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+type Job struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	State JobState               `protobuf:"varint,2,opt,name=state,proto3,enum=dotgo.transcode.v1.JobState" json:"state,omitempty"`
+	// Source media path from the profile.
+	InputPath string `protobuf:"bytes,3,opt,name=input_path,json=inputPath,proto3" json:"input_path,omitempty"`
+	// Output slug derived from the input filename.
+	Slug string `protobuf:"bytes,4,opt,name=slug,proto3" json:"slug,omitempty"`
+	// Stage currently running, or the last one that ran.
+	Stage string `protobuf:"bytes,5,opt,name=stage,proto3" json:"stage,omitempty"`
+	// Progress across every pipeline stage, 0-100.
+	Percent float64 `protobuf:"fixed64,6,opt,name=percent,proto3" json:"percent,omitempty"`
+	// Failure or cancellation reason.
+	Error      string                 `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`
+	CreatedAt  *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	StartedAt  *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	FinishedAt *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=finished_at,json=finishedAt,proto3" json:"finished_at,omitempty"`
+	// Set once the job has succeeded.
+	Result *JobResult `protobuf:"bytes,11,opt,name=result,proto3" json:"result,omitempty"`
+	// Every finished stage, in order.
+	Stages []*StageTiming `protobuf:"bytes,12,rep,name=stages,proto3" json:"stages,omitempty"`
+	// Resolved profile the job runs with, as JSON.
+	ProfileJson    string `protobuf:"bytes,13,opt,name=profile_json,json=profileJson,proto3" json:"profile_json,omitempty"`
+	IdempotencyKey string `protobuf:"bytes,14,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	// Hash of the source content and settings used to detect duplicates.
+	Fingerprint   string `protobuf:"bytes,15,opt,name=fingerprint,proto3" json:"fingerprint,omitempty"`
+	Priority      int32  `protobuf:"varint,16,opt,name=priority,proto3" json:"priority,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Job) Reset() {
+	*x = Job{}
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Job) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Job) ProtoMessage() {}
+
+func (x *Job) ProtoReflect() protoreflect.Message {
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Job.ProtoReflect.Descriptor instead.
+func (*Job) Descriptor() ([]byte, []int) {
+	return file_api_transcode_v1_transcode_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Job) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Job) GetState() JobState {
+	if x != nil {
+		return x.State
+	}
+	return JobState_JOB_STATE_UNSPECIFIED
+}
+
+func (x *Job) GetInputPath() string {
+	if x != nil {
+		return x.InputPath
+	}
+	return ""
+}
+
+func (x *Job) GetSlug() string {
+	if x != nil {
+		return x.Slug
+	}
+	return ""
+}
+
+func (x *Job) GetStage() string {
+	if x != nil {
+		return x.Stage
+	}
+	return ""
+}
+
+func (x *Job) GetPercent() float64 {
+	if x != nil {
+		return x.Percent
+	}
+	return 0
+}
+
+func (x *Job) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Job) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Job) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *Job) GetFinishedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FinishedAt
+	}
+	return nil
+}
+
+func (x *Job) GetResult() *JobResult {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *Job) GetStages() []*StageTiming {
+	if x != nil {
+		return x.Stages
+	}
+	return nil
+}
+
+func (x *Job) GetProfileJson() string {
+	if x != nil {
+		return x.ProfileJson
+	}
+	return ""
+}
+
+func (x *Job) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+func (x *Job) GetFingerprint() string {
+	if x != nil {
+		return x.Fingerprint
+	}
+	return ""
+}
+
+func (x *Job) GetPriority() int32 {
+	if x != nil {
+		return x.Priority
+	}
+	return 0
+}
+
+type StageTiming struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Stage     string                 `protobuf:"bytes,1,opt,name=stage,proto3" json:"stage,omitempty"`
+	StartedAt *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=started_at,json=startedAt,proto3" json:"started_at,omitempty"`
+	Elapsed   *durationpb.Duration   `protobuf:"bytes,3,opt,name=elapsed,proto3" json:"elapsed,omitempty"`
+	// Stage error; empty on success.
+	Error         string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StageTiming) Reset() {
+	*x = StageTiming{}
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StageTiming) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StageTiming) ProtoMessage() {}
+
+func (x *StageTiming) ProtoReflect() protoreflect.Message {
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StageTiming.ProtoReflect.Descriptor instead.
+func (*StageTiming) Descriptor() ([]byte, []int) {
+	return file_api_transcode_v1_transcode_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *StageTiming) GetStage() string {
+	if x != nil {
+		return x.Stage
+	}
+	return ""
+}
+
+func (x *StageTiming) GetStartedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.StartedAt
+	}
+	return nil
+}
+
+func (x *StageTiming) GetElapsed() *durationpb.Duration {
+	if x != nil {
+		return x.Elapsed
+	}
+	return nil
+}
+
+func (x *StageTiming) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type JobResult struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	ManifestPath string                 `protobuf:"bytes,1,opt,name=manifest_path,json=manifestPath,proto3" json:"manifest_path,omitempty"`
+	MetadataPath string                 `protobuf:"bytes,2,opt,name=metadata_path,json=metadataPath,proto3" json:"metadata_path,omitempty"`
+	ChecksumPath string                 `protobuf:"bytes,3,opt,name=checksum_path,json=checksumPath,proto3" json:"checksum_path,omitempty"`
+	PreviewPath  string                 `protobuf:"bytes,4,opt,name=preview_path,json=previewPath,proto3" json:"preview_path,omitempty"`
+	Thumbnails   []string               `protobuf:"bytes,5,rep,name=thumbnails,proto3" json:"thumbnails,omitempty"`
+	Variants     []*Variant             `protobuf:"bytes,6,rep,name=variants,proto3" json:"variants,omitempty"`
+	// Source duration in seconds.
+	DurationSeconds float64 `protobuf:"fixed64,7,opt,name=duration_seconds,json=durationSeconds,proto3" json:"duration_seconds,omitempty"`
+	// Non-fatal stage errors (e.g. thumbnail failures).
+	Warnings      []string `protobuf:"bytes,8,rep,name=warnings,proto3" json:"warnings,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobResult) Reset() {
+	*x = JobResult{}
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobResult) ProtoMessage() {}
+
+func (x *JobResult) ProtoReflect() protoreflect.Message {
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobResult.ProtoReflect.Descriptor instead.
+func (*JobResult) Descriptor() ([]byte, []int) {
+	return file_api_transcode_v1_transcode_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *JobResult) GetManifestPath() string {
+	if x != nil {
+		return x.ManifestPath
+	}
+	return ""
+}
+
+func (x *JobResult) GetMetadataPath() string {
+	if x != nil {
+		return x.MetadataPath
+	}
+	return ""
+}
+
+func (x *JobResult) GetChecksumPath() string {
+	if x != nil {
+		return x.ChecksumPath
+	}
+	return ""
+}
+
+func (x *JobResult) GetPreviewPath() string {
+	if x != nil {
+		return x.PreviewPath
+	}
+	return ""
+}
+
+func (x *JobResult) GetThumbnails() []string {
+	if x != nil {
+		return x.Thumbnails
+	}
+	return nil
+}
+
+func (x *JobResult) GetVariants() []*Variant {
+	if x != nil {
+		return x.Variants
+	}
+	return nil
+}
+
+func (x *JobResult) GetDurationSeconds() float64 {
+	if x != nil {
+		return x.DurationSeconds
+	}
+	return 0
+}
+
+func (x *JobResult) GetWarnings() []string {
+	if x != nil {
+		return x.Warnings
+	}
+	return nil
+}
+
+// Variant is one encoded rung of the ladder.
+type Variant struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Width   int32                  `protobuf:"varint,1,opt,name=width,proto3" json:"width,omitempty"`
+	Height  int32                  `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	Bitrate string                 `protobuf:"bytes,3,opt,name=bitrate,proto3" json:"bitrate,omitempty"`
+	Codec   string                 `protobuf:"bytes,4,opt,name=codec,proto3" json:"codec,omitempty"`
+	// RFC 6381 CODECS value.
+	Codecs         string `protobuf:"bytes,5,opt,name=codecs,proto3" json:"codecs,omitempty"`
+	OutputFilename string `protobuf:"bytes,6,opt,name=output_filename,json=outputFilename,proto3" json:"output_filename,omitempty"`
+	FileSize       int64  `protobuf:"varint,7,opt,name=file_size,json=fileSize,proto3" json:"file_size,omitempty"`
+	// Measured average bitrate in kbps.
+	MeasuredBitrate int32                `protobuf:"varint,8,opt,name=measured_bitrate,json=measuredBitrate,proto3" json:"measured_bitrate,omitempty"`
+	EncodeTime      *durationpb.Duration `protobuf:"bytes,9,opt,name=encode_time,json=encodeTime,proto3" json:"encode_time,omitempty"`
+	// ffmpeg version the variant was encoded with.
+	FfmpegVersion string `protobuf:"bytes,10,opt,name=ffmpeg_version,json=ffmpegVersion,proto3" json:"ffmpeg_version,omitempty"`
+	// Hash of the profile settings that shaped this variant.
+	ProfileHash string `protobuf:"bytes,11,opt,name=profile_hash,json=profileHash,proto3" json:"profile_hash,omitempty"`
+	// Fingerprint of the ffmpeg command, versions, and profile hash; differs
+	// from a fresh encode's when the settings or ffmpeg changed.
+	SettingsHash  string `protobuf:"bytes,12,opt,name=settings_hash,json=settingsHash,proto3" json:"settings_hash,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Variant) Reset() {
+	*x = Variant{}
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Variant) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Variant) ProtoMessage() {}
+
+func (x *Variant) ProtoReflect() protoreflect.Message {
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Variant.ProtoReflect.Descriptor instead.
+func (*Variant) Descriptor() ([]byte, []int) {
+	return file_api_transcode_v1_transcode_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Variant) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *Variant) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *Variant) GetBitrate() string {
+	if x != nil {
+		return x.Bitrate
+	}
+	return ""
+}
+
+func (x *Variant) GetCodec() string {
+	if x != nil {
+		return x.Codec
+	}
+	return ""
+}
+
+func (x *Variant) GetCodecs() string {
+	if x != nil {
+		return x.Codecs
+	}
+	return ""
+}
+
+func (x *Variant) GetOutputFilename() string {
+	if x != nil {
+		return x.OutputFilename
+	}
+	return ""
+}
+
+func (x *Variant) GetFileSize() int64 {
+	if x != nil {
+		return x.FileSize
+	}
+	return 0
+}
+
+func (x *Variant) GetMeasuredBitrate() int32 {
+	if x != nil {
+		return x.MeasuredBitrate
+	}
+	return 0
+}
+
+func (x *Variant) GetEncodeTime() *durationpb.Duration {
+	if x != nil {
+		return x.EncodeTime
+	}
+	return nil
+}
+
+func (x *Variant) GetFfmpegVersion() string {
+	if x != nil {
+		return x.FfmpegVersion
+	}
+	return ""
+}
+
+func (x *Variant) GetProfileHash() string {
+	if x != nil {
+		return x.ProfileHash
+	}
+	return ""
+}
+
+func (x *Variant) GetSettingsHash() string {
+	if x != nil {
+		return x.SettingsHash
+	}
+	return ""
+}
+
+type JobEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	JobId string                 `protobuf:"bytes,1,opt,name=job_id,json=jobId,proto3" json:"job_id,omitempty"`
+	Time  *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=time,proto3" json:"time,omitempty"`
+	// Types that are valid to be assigned to Event:
+	//
+	//	*JobEvent_State
+	//	*JobEvent_Stage
+	//	*JobEvent_Progress
+	Event         isJobEvent_Event `protobuf_oneof:"event"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JobEvent) Reset() {
+	*x = JobEvent{}
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JobEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JobEvent) ProtoMessage() {}
+
+func (x *JobEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JobEvent.ProtoReflect.Descriptor instead.
+func (*JobEvent) Descriptor() ([]byte, []int) {
+	return file_api_transcode_v1_transcode_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *JobEvent) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+func (x *JobEvent) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *JobEvent) GetEvent() isJobEvent_Event {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *JobEvent) GetState() *Job {
+	if x != nil {
+		if x, ok := x.Event.(*JobEvent_State); ok {
+			return x.State
+		}
+	}
+	return nil
+}
+
+func (x *JobEvent) GetStage() *StageEvent {
+	if x != nil {
+		if x, ok := x.Event.(*JobEvent_Stage); ok {
+			return x.Stage
+		}
+	}
+	return nil
+}
+
+func (x *JobEvent) GetProgress() *ProgressEvent {
+	if x != nil {
+		if x, ok := x.Event.(*JobEvent_Progress); ok {
+			return x.Progress
+		}
+	}
+	return nil
+}
+
+type isJobEvent_Event interface {
+	isJobEvent_Event()
+}
+
+type JobEvent_State struct {
+	// Full job snapshot; sent first and on every state change.
+	State *Job `protobuf:"bytes,3,opt,name=state,proto3,oneof"`
+}
+
+type JobEvent_Stage struct {
+	Stage *StageEvent `protobuf:"bytes,4,opt,name=stage,proto3,oneof"`
+}
+
+type JobEvent_Progress struct {
+	Progress *ProgressEvent `protobuf:"bytes,5,opt,name=progress,proto3,oneof"`
+}
+
+func (*JobEvent_State) isJobEvent_Event() {}
+
+func (*JobEvent_Stage) isJobEvent_Event() {}
+
+func (*JobEvent_Progress) isJobEvent_Event() {}
+
+// StageEvent marks a pipeline stage starting or finishing.
+type StageEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Stage string                 `protobuf:"bytes,1,opt,name=stage,proto3" json:"stage,omitempty"`
+	// False when the stage starts, true when it finishes.
+	Finished bool `protobuf:"varint,2,opt,name=finished,proto3" json:"finished,omitempty"`
+	// Stage wall time (finished only).
+	Elapsed *durationpb.Duration `protobuf:"bytes,3,opt,name=elapsed,proto3" json:"elapsed,omitempty"`
+	// Stage error (finished only).
+	Error         string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StageEvent) Reset() {
+	*x = StageEvent{}
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StageEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StageEvent) ProtoMessage() {}
+
+func (x *StageEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StageEvent.ProtoReflect.Descriptor instead.
+func (*StageEvent) Descriptor() ([]byte, []int) {
+	return file_api_transcode_v1_transcode_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *StageEvent) GetStage() string {
+	if x != nil {
+		return x.Stage
+	}
+	return ""
+}
+
+func (x *StageEvent) GetFinished() bool {
+	if x != nil {
+		return x.Finished
+	}
+	return false
+}
+
+func (x *StageEvent) GetElapsed() *durationpb.Duration {
+	if x != nil {
+		return x.Elapsed
+	}
+	return nil
+}
+
+func (x *StageEvent) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+// ProgressEvent is an encode progress sample for one variant, or the
+// aggregate across all variants.
+type ProgressEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Variant key (e.g. "720p_3000k"); empty for aggregate events.
+	Variant   string  `protobuf:"bytes,1,opt,name=variant,proto3" json:"variant,omitempty"`
+	Percent   float64 `protobuf:"fixed64,2,opt,name=percent,proto3" json:"percent,omitempty"`
+	Aggregate bool    `protobuf:"varint,3,opt,name=aggregate,proto3" json:"aggregate,omitempty"`
+	Done      bool    `protobuf:"varint,4,opt,name=done,proto3" json:"done,omitempty"`
+	Failed    bool    `protobuf:"varint,5,opt,name=failed,proto3" json:"failed,omitempty"`
+	// Media seconds encoded per wall-clock second.
+	Speed         float64              `protobuf:"fixed64,6,opt,name=speed,proto3" json:"speed,omitempty"`
+	Eta           *durationpb.Duration `protobuf:"bytes,7,opt,name=eta,proto3" json:"eta,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ProgressEvent) Reset() {
+	*x = ProgressEvent{}
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ProgressEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ProgressEvent) ProtoMessage() {}
+
+func (x *ProgressEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_api_transcode_v1_transcode_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ProgressEvent.ProtoReflect.Descriptor instead.
+func (*ProgressEvent) Descriptor() ([]byte, []int) {
+	return file_api_transcode_v1_transcode_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ProgressEvent) GetVariant() string {
+	if x != nil {
+		return x.Variant
+	}
+	return ""
+}
+
+func (x *ProgressEvent) GetPercent() float64 {
+	if x != nil {
+		return x.Percent
+	}
+	return 0
+}
+
+func (x *ProgressEvent) GetAggregate() bool {
+	if x != nil {
+		return x.Aggregate
+	}
+	return false
+}
+
+func (x *ProgressEvent) GetDone() bool {
+	if x != nil {
+		return x.Done
+	}
+	return false
+}
+
+func (x *ProgressEvent) GetFailed() bool {
+	if x != nil {
+		return x.Failed
+	}
+	return false
+}
+
+func (x *ProgressEvent) GetSpeed() float64 {
+	if x != nil {
+		return x.Speed
+	}
+	return 0
+}
+
+func (x *ProgressEvent) GetEta() *durationpb.Duration {
+	if x != nil {
+		return x.Eta
+	}
+	return nil
+}
+
+var File_api_transcode_v1_transcode_proto protoreflect.FileDescriptor
+
+const file_api_transcode_v1_transcode_proto_rawDesc = "" +
+	"\n" +
+	" api/transcode/v1/transcode.proto\x12\x12dotgo.transcode.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xac\x02\n" +
+	"\x10SubmitJobRequest\x12#\n" +
+	"\fprofile_path\x18\x01 \x01(\tH\x00R\vprofilePath\x12+\n" +
+	"\x10profile_document\x18\x02 \x01(\fH\x00R\x0fprofileDocument\x12\x1a\n" +
+	"\boverlays\x18\x03 \x03(\tR\boverlays\x12#\n" +
+	"\rstream_format\x18\x04 \x01(\tR\fstreamFormat\x12\x1f\n" +
+	"\vskip_stages\x18\x05 \x03(\tR\n" +
+	"skipStages\x12'\n" +
+	"\x0fidempotency_key\x18\x06 \x01(\tR\x0eidempotencyKey\x12\x14\n" +
+	"\x05force\x18\a \x01(\bR\x05force\x12\x1a\n" +
+	"\bpriority\x18\b \x01(\x05R\bpriorityB\t\n" +
+	"\aprofile\"b\n" +
+	"\x11SubmitJobResponse\x12)\n" +
+	"\x03job\x18\x01 \x01(\v2\x17.dotgo.transcode.v1.JobR\x03job\x12\"\n" +
+	"\fdeduplicated\x18\x02 \x01(\bR\fdeduplicated\"&\n" +
+	"\rGetJobRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\"\xe0\x01\n" +
+	"\x0fListJobsRequest\x124\n" +
+	"\x06states\x18\x01 \x03(\x0e2\x1c.dotgo.transcode.v1.JobStateR\x06states\x12\x1d\n" +
+	"\n" +
+	"input_path\x18\x02 \x01(\tR\tinputPath\x120\n" +
+	"\x05since\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x120\n" +
+	"\x05until\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\x05until\x12\x14\n" +
+	"\x05limit\x18\x05 \x01(\x05R\x05limit\"?\n" +
+	"\x10ListJobsResponse\x12+\n" +
+	"\x04jobs\x18\x01 \x03(\v2\x17.dotgo.transcode.v1.JobR\x04jobs\")\n" +
+	"\x10CancelJobRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\"(\n" +
+	"\x0fWatchJobRequest\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\"\xef\x04\n" +
+	"\x03Job\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x122\n" +
+	"\x05state\x18\x02 \x01(\x0e2\x1c.dotgo.transcode.v1.JobStateR\x05state\x12\x1d\n" +
+	"\n" +
+	"input_path\x18\x03 \x01(\tR\tinputPath\x12\x12\n" +
+	"\x04slug\x18\x04 \x01(\tR\x04slug\x12\x14\n" +
+	"\x05stage\x18\x05 \x01(\tR\x05stage\x12\x18\n" +
+	"\apercent\x18\x06 \x01(\x01R\apercent\x12\x14\n" +
+	"\x05error\x18\a \x01(\tR\x05error\x129\n" +
+	"\n" +
+	"created_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"started_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x12;\n" +
+	"\vfinished_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"finishedAt\x125\n" +
+	"\x06result\x18\v \x01(\v2\x1d.dotgo.transcode.v1.JobResultR\x06result\x127\n" +
+	"\x06stages\x18\f \x03(\v2\x1f.dotgo.transcode.v1.StageTimingR\x06stages\x12!\n" +
+	"\fprofile_json\x18\r \x01(\tR\vprofileJson\x12'\n" +
+	"\x0fidempotency_key\x18\x0e \x01(\tR\x0eidempotencyKey\x12 \n" +
+	"\vfingerprint\x18\x0f \x01(\tR\vfingerprint\x12\x1a\n" +
+	"\bpriority\x18\x10 \x01(\x05R\bpriority\"\xa9\x01\n" +
+	"\vStageTiming\x12\x14\n" +
+	"\x05stage\x18\x01 \x01(\tR\x05stage\x129\n" +
+	"\n" +
+	"started_at\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\tstartedAt\x123\n" +
+	"\aelapsed\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\aelapsed\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"\xbd\x02\n" +
+	"\tJobResult\x12#\n" +
+	"\rmanifest_path\x18\x01 \x01(\tR\fmanifestPath\x12#\n" +
+	"\rmetadata_path\x18\x02 \x01(\tR\fmetadataPath\x12#\n" +
+	"\rchecksum_path\x18\x03 \x01(\tR\fchecksumPath\x12!\n" +
+	"\fpreview_path\x18\x04 \x01(\tR\vpreviewPath\x12\x1e\n" +
+	"\n" +
+	"thumbnails\x18\x05 \x03(\tR\n" +
+	"thumbnails\x127\n" +
+	"\bvariants\x18\x06 \x03(\v2\x1b.dotgo.transcode.v1.VariantR\bvariants\x12)\n" +
+	"\x10duration_seconds\x18\a \x01(\x01R\x0fdurationSeconds\x12\x1a\n" +
+	"\bwarnings\x18\b \x03(\tR\bwarnings\"\x9b\x03\n" +
+	"\aVariant\x12\x14\n" +
+	"\x05width\x18\x01 \x01(\x05R\x05width\x12\x16\n" +
+	"\x06height\x18\x02 \x01(\x05R\x06height\x12\x18\n" +
+	"\abitrate\x18\x03 \x01(\tR\abitrate\x12\x14\n" +
+	"\x05codec\x18\x04 \x01(\tR\x05codec\x12\x16\n" +
+	"\x06codecs\x18\x05 \x01(\tR\x06codecs\x12'\n" +
+	"\x0foutput_filename\x18\x06 \x01(\tR\x0eoutputFilename\x12\x1b\n" +
+	"\tfile_size\x18\a \x01(\x03R\bfileSize\x12)\n" +
+	"\x10measured_bitrate\x18\b \x01(\x05R\x0fmeasuredBitrate\x12:\n" +
+	"\vencode_time\x18\t \x01(\v2\x19.google.protobuf.DurationR\n" +
+	"encodeTime\x12%\n" +
+	"\x0effmpeg_version\x18\n" +
+	" \x01(\tR\rffmpegVersion\x12!\n" +
+	"\fprofile_hash\x18\v \x01(\tR\vprofileHash\x12#\n" +
+	"\rsettings_hash\x18\f \x01(\tR\fsettingsHash\"\x84\x02\n" +
+	"\bJobEvent\x12\x15\n" +
+	"\x06job_id\x18\x01 \x01(\tR\x05jobId\x12.\n" +
+	"\x04time\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12/\n" +
+	"\x05state\x18\x03 \x01(\v2\x17.dotgo.transcode.v1.JobH\x00R\x05state\x126\n" +
+	"\x05stage\x18\x04 \x01(\v2\x1e.dotgo.transcode.v1.StageEventH\x00R\x05stage\x12?\n" +
+	"\bprogress\x18\x05 \x01(\v2!.dotgo.transcode.v1.ProgressEventH\x00R\bprogressB\a\n" +
+	"\x05event\"\x89\x01\n" +
+	"\n" +
+	"StageEvent\x12\x14\n" +
+	"\x05stage\x18\x01 \x01(\tR\x05stage\x12\x1a\n" +
+	"\bfinished\x18\x02 \x01(\bR\bfinished\x123\n" +
+	"\aelapsed\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\aelapsed\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"\xd0\x01\n" +
+	"\rProgressEvent\x12\x18\n" +
+	"\avariant\x18\x01 \x01(\tR\avariant\x12\x18\n" +
+	"\apercent\x18\x02 \x01(\x01R\apercent\x12\x1c\n" +
+	"\taggregate\x18\x03 \x01(\bR\taggregate\x12\x12\n" +
+	"\x04done\x18\x04 \x01(\bR\x04done\x12\x16\n" +
+	"\x06failed\x18\x05 \x01(\bR\x06failed\x12\x14\n" +
+	"\x05speed\x18\x06 \x01(\x01R\x05speed\x12+\n" +
+	"\x03eta\x18\a \x01(\v2\x19.google.protobuf.DurationR\x03eta*\xb0\x01\n" +
+	"\bJobState\x12\x19\n" +
+	"\x15JOB_STATE_UNSPECIFIED\x10\x00\x12\x14\n" +
+	"\x10JOB_STATE_QUEUED\x10\x01\x12\x15\n" +
+	"\x11JOB_STATE_RUNNING\x10\x02\x12\x17\n" +
+	"\x13JOB_STATE_SUCCEEDED\x10\x03\x12\x14\n" +
+	"\x10JOB_STATE_FAILED\x10\x04\x12\x17\n" +
+	"\x13JOB_STATE_CANCELLED\x10\x05\x12\x14\n" +
+	"\x10JOB_STATE_PAUSED\x10\x062\xa6\x03\n" +
+	"\x10TranscodeService\x12X\n" +
+	"\tSubmitJob\x12$.dotgo.transcode.v1.SubmitJobRequest\x1a%.dotgo.transcode.v1.SubmitJobResponse\x12D\n" +
+	"\x06GetJob\x12!.dotgo.transcode.v1.GetJobRequest\x1a\x17.dotgo.transcode.v1.Job\x12U\n" +
+	"\bListJobs\x12#.dotgo.transcode.v1.ListJobsRequest\x1a$.dotgo.transcode.v1.ListJobsResponse\x12J\n" +
+	"\tCancelJob\x12$.dotgo.transcode.v1.CancelJobRequest\x1a\x17.dotgo.transcode.v1.Job\x12O\n" +
+	"\bWatchJob\x12#.dotgo.transcode.v1.WatchJobRequest\x1a\x1c.dotgo.transcode.v1.JobEvent0\x01Bq\n" +
+	"\x1acom.dotsoulja.transcode.v1B\x0eTranscodeProtoP\x01ZAgithub.com/dotsoulja/dotgo-transcode/api/transcode/v1;transcodev1b\x06proto3"
+
+var (
+	file_api_transcode_v1_transcode_proto_rawDescOnce sync.Once
+	file_api_transcode_v1_transcode_proto_rawDescData []byte
+)
+
+func file_api_transcode_v1_transcode_proto_rawDescGZIP() []byte {
+	file_api_transcode_v1_transcode_proto_rawDescOnce.Do(func() {
+		file_api_transcode_v1_transcode_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_api_transcode_v1_transcode_proto_rawDesc), len(file_api_transcode_v1_transcode_proto_rawDesc)))
+	})
+	return file_api_transcode_v1_transcode_proto_rawDescData
+}
+
+var file_api_transcode_v1_transcode_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_api_transcode_v1_transcode_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
+var file_api_transcode_v1_transcode_proto_goTypes = []any{
+	(JobState)(0),                 // 0: dotgo.transcode.v1.JobState
+	(*SubmitJobRequest)(nil),      // 1: dotgo.transcode.v1.SubmitJobRequest
+	(*SubmitJobResponse)(nil),     // 2: dotgo.transcode.v1.SubmitJobResponse
+	(*GetJobRequest)(nil),         // 3: dotgo.transcode.v1.GetJobRequest
+	(*ListJobsRequest)(nil),       // 4: dotgo.transcode.v1.ListJobsRequest
+	(*ListJobsResponse)(nil),      // 5: dotgo.transcode.v1.ListJobsResponse
+	(*CancelJobRequest)(nil),      // 6: dotgo.transcode.v1.CancelJobRequest
+	(*WatchJobRequest)(nil),       // 7: dotgo.transcode.v1.WatchJobRequest
+	(*Job)(nil),                   // 8: dotgo.transcode.v1.Job
+	(*StageTiming)(nil),           // 9: dotgo.transcode.v1.StageTiming
+	(*JobResult)(nil),             // 10: dotgo.transcode.v1.JobResult
+	(*Variant)(nil),               // 11: dotgo.transcode.v1.Variant
+	(*JobEvent)(nil),              // 12: dotgo.transcode.v1.JobEvent
+	(*StageEvent)(nil),            // 13: dotgo.transcode.v1.StageEvent
+	(*ProgressEvent)(nil),         // 14: dotgo.transcode.v1.ProgressEvent
+	(*timestamppb.Timestamp)(nil), // 15: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),   // 16: google.protobuf.Duration
+}
+var file_api_transcode_v1_transcode_proto_depIdxs = []int32{
+	8,  // 0: dotgo.transcode.v1.SubmitJobResponse.job:type_name -> dotgo.transcode.v1.Job
+	0,  // 1: dotgo.transcode.v1.ListJobsRequest.states:type_name -> dotgo.transcode.v1.JobState
+	15, // 2: dotgo.transcode.v1.ListJobsRequest.since:type_name -> google.protobuf.Timestamp
+	15, // 3: dotgo.transcode.v1.ListJobsRequest.until:type_name -> google.protobuf.Timestamp
+	8,  // 4: dotgo.transcode.v1.ListJobsResponse.jobs:type_name -> dotgo.transcode.v1.Job
+	0,  // 5: dotgo.transcode.v1.Job.state:type_name -> dotgo.transcode.v1.JobState
+	15, // 6: dotgo.transcode.v1.Job.created_at:type_name -> google.protobuf.Timestamp
+	15, // 7: dotgo.transcode.v1.Job.started_at:type_name -> google.protobuf.Timestamp
+	15, // 8: dotgo.transcode.v1.Job.finished_at:type_name -> google.protobuf.Timestamp
+	10, // 9: dotgo.transcode.v1.Job.result:type_name -> dotgo.transcode.v1.JobResult
+	9,  // 10: dotgo.transcode.v1.Job.stages:type_name -> dotgo.transcode.v1.StageTiming
+	15, // 11: dotgo.transcode.v1.StageTiming.started_at:type_name -> google.protobuf.Timestamp
+	16, // 12: dotgo.transcode.v1.StageTiming.elapsed:type_name -> google.protobuf.Duration
+	11, // 13: dotgo.transcode.v1.JobResult.variants:type_name -> dotgo.transcode.v1.Variant
+	16, // 14: dotgo.transcode.v1.Variant.encode_time:type_name -> google.protobuf.Duration
+	15, // 15: dotgo.transcode.v1.JobEvent.time:type_name -> google.protobuf.Timestamp
+	8,  // 16: dotgo.transcode.v1.JobEvent.state:type_name -> dotgo.transcode.v1.Job
+	13, // 17: dotgo.transcode.v1.JobEvent.stage:type_name -> dotgo.transcode.v1.StageEvent
+	14, // 18: dotgo.transcode.v1.JobEvent.progress:type_name -> dotgo.transcode.v1.ProgressEvent
+	16, // 19: dotgo.transcode.v1.StageEvent.elapsed:type_name -> google.protobuf.Duration
+	16, // 20: dotgo.transcode.v1.ProgressEvent.eta:type_name -> google.protobuf.Duration
+	1,  // 21: dotgo.transcode.v1.TranscodeService.SubmitJob:input_type -> dotgo.transcode.v1.SubmitJobRequest
+	3,  // 22: dotgo.transcode.v1.TranscodeService.GetJob:input_type -> dotgo.transcode.v1.GetJobRequest
+	4,  // 23: dotgo.transcode.v1.TranscodeService.ListJobs:input_type -> dotgo.transcode.v1.ListJobsRequest
+	6,  // 24: dotgo.transcode.v1.TranscodeService.CancelJob:input_type -> dotgo.transcode.v1.CancelJobRequest
+	7,  // 25: dotgo.transcode.v1.TranscodeService.WatchJob:input_type -> dotgo.transcode.v1.WatchJobRequest
+	2,  // 26: dotgo.transcode.v1.TranscodeService.SubmitJob:output_type -> dotgo.transcode.v1.SubmitJobResponse
+	8,  // 27: dotgo.transcode.v1.TranscodeService.GetJob:output_type -> dotgo.transcode.v1.Job
+	5,  // 28: dotgo.transcode.v1.TranscodeService.ListJobs:output_type -> dotgo.transcode.v1.ListJobsResponse
+	8,  // 29: dotgo.transcode.v1.TranscodeService.CancelJob:output_type -> dotgo.transcode.v1.Job
+	12, // 30: dotgo.transcode.v1.TranscodeService.WatchJob:output_type -> dotgo.transcode.v1.JobEvent
+	26, // [26:31] is the sub-list for method output_type
+	21, // [21:26] is the sub-list for method input_type
+	21, // [21:21] is the sub-list for extension type_name
+	21, // [21:21] is the sub-list for extension extendee
+	0,  // [0:21] is the sub-list for field type_name
+}
+
+func init() { file_api_transcode_v1_transcode_proto_init() }
+func file_api_transcode_v1_transcode_proto_init() {
+	if File_api_transcode_v1_transcode_proto != nil {
+		return
+	}
+	file_api_transcode_v1_transcode_proto_msgTypes[0].OneofWrappers = []any{
+		(*SubmitJobRequest_ProfilePath)(nil),
+		(*SubmitJobRequest_ProfileDocument)(nil),
+	}
+	file_api_transcode_v1_transcode_proto_msgTypes[11].OneofWrappers = []any{
+		(*JobEvent_State)(nil),
+		(*JobEvent_Stage)(nil),
+		(*JobEvent_Progress)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_transcode_v1_transcode_proto_rawDesc), len(file_api_transcode_v1_transcode_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   14,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_api_transcode_v1_transcode_proto_goTypes,
+		DependencyIndexes: file_api_transcode_v1_transcode_proto_depIdxs,
+		EnumInfos:         file_api_transcode_v1_transcode_proto_enumTypes,
+		MessageInfos:      file_api_transcode_v1_transcode_proto_msgTypes,
+	}.Build()
+	File_api_transcode_v1_transcode_proto = out.File
+	file_api_transcode_v1_transcode_proto_goTypes = nil
+	file_api_transcode_v1_transcode_proto_depIdxs = nil
+}
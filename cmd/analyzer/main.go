@@ -40,6 +40,16 @@ func main() {
 		fmt.Printf("  Framerate: %.3f fps\n", info.Framerate)
 		fmt.Printf("  Keyframe Interval: %.3f frames\n", info.KeyframeInterval)
 		fmt.Printf("  Keyframes: %v\n", info.Keyframes)
+		fmt.Printf("  Container: %s\n", info.Container)
+		if info.Image != nil {
+			fmt.Printf("  Image: %s, %d frame(s) (duration and framerate are synthetic)\n", info.Image.Kind, info.Image.Frames)
+		}
+		fmt.Printf("  Pixel Format: %s (%d-bit)\n", info.PixelFormat, info.BitDepth)
+		fmt.Println("  Streams:")
+		for _, st := range info.Streams {
+			fmt.Printf("    • #%d %-8s %-10s lang=%-4s ch=%d %dkbps %s\n",
+				st.Index, st.Type, st.Codec, st.Language, st.Channels, st.Bitrate, st.Title)
+		}
 		fmt.Println()
 	}
 }
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/errclass"
+	"github.com/dotsoulja/dotgo-transcode/internal/notify"
+	"github.com/dotsoulja/dotgo-transcode/pipeline"
+)
+
+// stageEvents writes stage boundaries of the CLI run to the -events stream
+// and reports how the run finished to the -notify sinks. With neither
+// configured every method does nothing.
+type stageEvents struct {
+	w     *pipeline.EventWriter
+	stage string
+	start time.Time
+
+	notifier pipeline.Notifier
+	run      pipeline.Notification // Filled in and sent when the run finishes
+	started  time.Time             // When the run started
+}
+
+// begin starts stage.
+func (s *stageEvents) begin(stage string) {
+	s.stage, s.start = stage, time.Now()
+	s.w.StageStarted(stage)
+}
+
+// end finishes the current stage; an error also ends the job.
+func (s *stageEvents) end(err error) {
+	s.w.StageFinished(s.stage, time.Since(s.start), err)
+	if err != nil {
+		s.w.Finished(err)
+		s.notify(err)
+	}
+}
+
+// warn records a non-fatal error in stage.
+func (s *stageEvents) warn(stage string, err error) {
+	s.w.Error(stage, err, false)
+	s.run.Warnings = append(s.run.Warnings, err.Error())
+}
+
+// finish ends a successful job.
+func (s *stageEvents) finish() {
+	s.w.Finished(nil)
+	s.notify(nil)
+}
+
+// notify sends the run's outcome to the notifier, logging delivery failures.
+func (s *stageEvents) notify(err error) {
+	if s.notifier == nil {
+		return
+	}
+	n := s.run
+	n.Time = time.Now()
+	n.Elapsed = n.Time.Sub(s.started)
+	switch {
+	case err != nil:
+		n.Kind, n.Error, n.Class = notify.JobFailed, err.Error(), errclass.Classify(err)
+	case len(n.Warnings) > 0:
+		n.Kind = notify.JobWarnings
+	default:
+		n.Kind = notify.JobSucceeded
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := s.notifier.Notify(ctx, n); err != nil {
+		log.Printf("⚠️ Failed to send notifications: %v", err)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"time"
+
+	transcodev1 "github.com/dotsoulja/dotgo-transcode/api/transcode/v1"
+	"github.com/dotsoulja/dotgo-transcode/internal/grpcapi"
+	"github.com/dotsoulja/dotgo-transcode/internal/jobs"
+	"github.com/dotsoulja/dotgo-transcode/internal/jobs/sqlitestore"
+	"github.com/dotsoulja/dotgo-transcode/internal/notify"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/logging"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
+	"github.com/dotsoulja/dotgo-transcode/internal/workspace"
+	"github.com/dotsoulja/dotgo-transcode/pipeline"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// runGRPC implements the "grpc" command:
+//
+//	cli grpc [-addr 127.0.0.1:9090] [-root .] [-concurrency 1] [-preempt] [-db jobs.db] [-log-dir dir] [-workdir dir] [-workdir-quota 20G] [-keep-failed-workdir] [-drain-timeout 5m] [-retries 0] [-retry-delay 10s] [-notify sinks.yaml] [-require-tenant] [-tenants tenants.yaml] [-reflection]
+//
+// Serves the TranscodeService API (api/transcode/v1/transcode.proto): clients
+// submit jobs by profile path or inline profile, stream stage and progress
+// events, and cancel jobs. With -db, jobs are recorded in a SQLite database
+// (see cli history) and unfinished jobs resume on restart; otherwise they
+// live in memory until the server exits. Jobs run in priority order; with
+// -preempt, a higher-priority submission pauses a lower-priority running job
+// (SIGSTOP) until a slot frees up. Each job gets a scratch directory under
+// -workdir for intermediates, removed when the job ends; a job whose scratch
+// directory outgrows -workdir-quota fails. With -retries, a job failing with
+// a transient error (see errclass) is queued again up to that many times,
+// after -retry-delay and twice as long for each later retry.
+//
+// On SIGTERM or interrupt the server stops accepting jobs and lets running
+// ones finish for up to -drain-timeout (a second signal cuts this short),
+// then interrupts the rest. Queued and interrupted jobs resume on the next
+// start when -db is set.
+//
+// Calls are scoped to a tenant: jobs are submitted under it and other
+// tenants' jobs are hidden. With -tenants, a JSON or YAML file mapping each
+// tenant to its API tokens (see grpcapi.LoadTenants), calls authenticate
+// with "authorization: Bearer <token>" metadata and act for that token's
+// tenant. Without it the tenant is taken from "dotgo-tenant" metadata as
+// sent, so run behind a proxy that authenticates callers and sets it; with
+// -require-tenant, calls without that metadata are rejected.
+//
+// The server listens on loopback unless -addr names another interface, and
+// only registers the reflection service with -reflection. Profile files,
+// inputs, and outputs of submitted jobs must lie under -root (the working
+// directory by default; -root "" lifts the restriction). Inline profiles are
+// not expanded with ${VAR} references.
+//
+// With -notify, failed jobs and jobs finishing with warnings are reported to
+// the Slack, email, PagerDuty, or webhook sinks the file lists (see
+// notify.Config).
+// Returns the process exit code: 0 on shutdown, 1 if the server fails.
+func runGRPC(args []string) int {
+	fs := flag.NewFlagSet("grpc", flag.ExitOnError)
+	addr := fs.String("addr", "127.0.0.1:9090", "listen address (use :9090 to accept remote connections)")
+	root := fs.String("root", ".", "reject jobs whose profile, input, or output lies outside this directory (\"\" for no restriction)")
+	concurrency := fs.Int("concurrency", 1, "number of jobs to run at once")
+	preempt := fs.Bool("preempt", false, "pause lower-priority running jobs when higher-priority work is queued (Unix only)")
+	dbPath := fs.String("db", "", "record jobs in this SQLite database so history and unfinished jobs survive restarts")
+	logDir := fs.String("log-dir", "", "if set, also write per-job JSON logs to this directory")
+	workDir := fs.String("workdir", "", "parent of per-job scratch directories (default: system temporary directory)")
+	workQuota := fs.String("workdir-quota", "", "fail a job whose scratch directory grows beyond this size (e.g. 20G)")
+	keepFailed := fs.Bool("keep-failed-workdir", false, "keep the scratch directory of failed and cancelled jobs for inspection")
+	drainTimeout := fs.Duration("drain-timeout", defaultDrainTimeout, "on SIGTERM or interrupt, let running jobs finish for this long before interrupting them")
+	retries := fs.Int("retries", 0, "queue a job again up to this many times when it fails with a transient error (timeout, stall, I/O)")
+	retryDelay := fs.Duration("retry-delay", 10*time.Second, "wait before the first retry, doubling for each later one (up to 10m, with jitter)")
+	notifyConfig := fs.String("notify", "", "report finished jobs to the notification sinks listed in this JSON or YAML file")
+	requireTenant := fs.Bool("require-tenant", false, "reject calls without dotgo-tenant metadata instead of letting them see every tenant")
+	tenantTokens := fs.String("tenants", "", "authenticate calls with the per-tenant bearer tokens listed in this JSON or YAML file")
+	withReflection := fs.Bool("reflection", false, "register the gRPC reflection service (for grpcurl and similar tools)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: cli grpc [-addr 127.0.0.1:9090] [-root .] [-concurrency 1] [-preempt] [-db jobs.db] [-log-dir dir] [-workdir dir] [-workdir-quota 20G] [-keep-failed-workdir] [-drain-timeout 5m] [-retries 0] [-retry-delay 10s] [-notify sinks.yaml] [-require-tenant] [-tenants tenants.yaml] [-reflection]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	scratch := pipeline.WorkspaceOptions{Root: *workDir, KeepOnFailure: *keepFailed}
+	if *workQuota != "" {
+		quota, err := workspace.ParseSize(*workQuota)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return 2
+		}
+		scratch.Quota = quota
+	}
+
+	lis, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+
+	opts := jobs.Options{
+		Concurrency: *concurrency,
+		Preempt:     *preempt,
+		Retries:     *retries,
+		RetryDelay:  *retryDelay,
+		Root:        *root,
+		Logger:      stagelog.Std,
+		Pipeline: []pipeline.Option{
+			pipeline.WithLogOptions(logging.Options{Format: "json", JobLogDir: *logDir}),
+			pipeline.WithWorkspace(scratch),
+		},
+	}
+	var tenants map[string][]string
+	if *tenantTokens != "" {
+		var err error
+		if tenants, err = grpcapi.LoadTenants(*tenantTokens); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return 1
+		}
+	}
+	if *notifyConfig != "" {
+		notifier, err := notify.LoadConfig(*notifyConfig)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return 1
+		}
+		opts.Notifier = notifier
+	}
+	if *dbPath != "" {
+		store, err := sqlitestore.Open(*dbPath)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return 1
+		}
+		opts.Store = store
+	}
+	manager, err := jobs.NewManagerWithOptions(opts)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	server := grpc.NewServer()
+	transcodev1.RegisterTranscodeServiceServer(server, &grpcapi.Server{Jobs: manager, RequireTenant: *requireTenant, Tenants: tenants})
+	if *withReflection {
+		reflection.Register(server)
+	}
+
+	drain, kill, stop := shutdownContexts(*drainTimeout)
+	defer stop()
+	go func() {
+		<-drain.Done()
+		// Finishing or interrupting the jobs ends every WatchJob stream, so
+		// GracefulStop only waits for in-flight unary calls
+		if err := manager.Shutdown(kill); err != nil {
+			fmt.Printf("❌ %v\n", err)
+		}
+		server.GracefulStop()
+	}()
+
+	fmt.Printf("📡 TranscodeService listening on %s (%d concurrent job(s))\n", lis.Addr(), *concurrency)
+	if err := server.Serve(lis); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	fmt.Println("👋 gRPC server stopped")
+	return 0
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/jobs"
+	"github.com/dotsoulja/dotgo-transcode/internal/jobs/sqlitestore"
+)
+
+// runHistory implements the "history" command:
+//
+//	cli history -db jobs.db [-state failed] [-input path] [-since 24h] [-limit 20] [job-id]
+//
+// Lists jobs recorded by "cli grpc -db", newest first. Given a job ID, prints
+// that job's stage timings, warnings, and the full profile it ran with, to
+// audit what was encoded with which settings.
+// Returns the process exit code: 1 if the database can't be read.
+func runHistory(args []string) int {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	dbPath := fs.String("db", "", "job database written by cli grpc -db (required)")
+	state := fs.String("state", "", "only jobs in these states (comma-separated: queued, running, paused, succeeded, failed, cancelled)")
+	input := fs.String("input", "", "only jobs for this source path")
+	since := fs.Duration("since", 0, "only jobs submitted within this long (e.g. 24h)")
+	limit := fs.Int("limit", 20, "maximum jobs listed (0 for all)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: cli history -db jobs.db [-state failed] [-input path] [-since 24h] [-limit 20] [job-id]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *dbPath == "" {
+		fs.Usage()
+		return 2
+	}
+	store, err := sqlitestore.Open(*dbPath)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	defer store.Close()
+	ctx := context.Background()
+
+	if fs.NArg() > 0 {
+		job, err := store.Get(ctx, fs.Arg(0))
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return 1
+		}
+		printJob(job)
+		return 0
+	}
+
+	filter := jobs.Filter{InputPath: *input, Limit: *limit}
+	if *state != "" {
+		for _, s := range strings.Split(*state, ",") {
+			filter.States = append(filter.States, jobs.State(strings.TrimSpace(s)))
+		}
+	}
+	if *since > 0 {
+		filter.Since = time.Now().Add(-*since)
+	}
+	list, err := store.List(ctx, filter)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	if len(list) == 0 {
+		fmt.Println("📭 No matching jobs")
+		return 0
+	}
+	for _, job := range list {
+		fmt.Printf("%s %-9s %-40s %s\n", stateIcon(job.State), job.State, job.ID, job.InputPath)
+		if job.Error != "" {
+			fmt.Printf("     ❌ %s (%s)\n", job.Error, job.Class)
+		}
+	}
+	return 0
+}
+
+// printJob writes one job's full record.
+func printJob(job jobs.Job) {
+	fmt.Printf("%s %s (%s)\n", stateIcon(job.State), job.ID, job.State)
+	fmt.Printf("   📁 Input:     %s\n", job.InputPath)
+	fmt.Printf("   🕒 Submitted: %s\n", job.Created.Format(time.RFC3339))
+	if job.Request.Priority != jobs.PriorityNormal {
+		fmt.Printf("   🚦 Priority:  %s\n", job.Request.Priority)
+	}
+	if job.Request.IdempotencyKey != "" {
+		fmt.Printf("   🔑 Key:       %s\n", job.Request.IdempotencyKey)
+	}
+	if job.Hash != "" {
+		fmt.Printf("   🧬 Hash:      %s\n", job.Hash)
+	}
+	if !job.Finished.IsZero() {
+		fmt.Printf("   🏁 Finished:  %s (%s)\n", job.Finished.Format(time.RFC3339), job.Finished.Sub(job.Created).Round(time.Second))
+	}
+	if job.Error != "" {
+		fmt.Printf("   ❌ Error:     %s\n", job.Error)
+	}
+	if job.Class != "" {
+		fmt.Printf("   🏷️ Class:     %s (retryable: %t)\n", job.Class, job.Class.IsRetryable())
+	}
+	if job.Retries > 0 {
+		fmt.Printf("   🔁 Retries:   %d\n", job.Retries)
+	}
+	if job.Report != nil && job.Report.ManifestPath != "" {
+		fmt.Printf("   📜 Manifest:  %s\n", job.Report.ManifestPath)
+	}
+	if len(job.Stages) > 0 {
+		fmt.Println("   ⏱️ Stages:")
+		for _, st := range job.Stages {
+			line := fmt.Sprintf("      • %-10s %s", st.Stage, st.Elapsed.Round(time.Millisecond))
+			if st.Err != "" {
+				line += " ❌ " + st.Err
+			}
+			fmt.Println(line)
+		}
+	}
+	for _, w := range job.Warnings {
+		fmt.Printf("   ⚠️ %s\n", w)
+	}
+	if job.Profile != nil {
+		data, err := json.MarshalIndent(job.Profile, "   ", "  ")
+		if err == nil {
+			fmt.Printf("   🎛️ Profile:\n   %s\n", data)
+		}
+	}
+}
+
+// stateIcon returns a status emoji for a job state.
+func stateIcon(s jobs.State) string {
+	switch s {
+	case jobs.Succeeded:
+		return "✅"
+	case jobs.Failed:
+		return "❌"
+	case jobs.Cancelled:
+		return "🛑"
+	case jobs.Running:
+		return "⏳"
+	case jobs.Paused:
+		return "⏸️"
+	default:
+		return "🕒"
+	}
+}
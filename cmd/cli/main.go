@@ -1,33 +1,134 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
-	"path/filepath"
+	"os"
 	"strings"
 	"time"
 
 	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/cluster"
+	"github.com/dotsoulja/dotgo-transcode/internal/executil"
 	"github.com/dotsoulja/dotgo-transcode/internal/manifester"
+	"github.com/dotsoulja/dotgo-transcode/internal/playcheck"
 	"github.com/dotsoulja/dotgo-transcode/internal/scaler"
 	"github.com/dotsoulja/dotgo-transcode/internal/segmenter"
 	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
 	"github.com/dotsoulja/dotgo-transcode/internal/utils/logging"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/namer"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
 	"github.com/dotsoulja/dotgo-transcode/internal/utils/thumbnailer"
+	"github.com/dotsoulja/dotgo-transcode/pipeline"
 )
 
 func main() {
 	start := time.Now()
-	logger := &logging.UnifiedLogger{}
 
-	profileName := "sample_profile.json"
-	streamFormat := "hls" // or "dash"
+	// Subcommands
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(runValidate(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "variants" {
+		os.Exit(runVariants(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		os.Exit(runServe(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "verify" {
+		os.Exit(runVerify(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "worker" {
+		os.Exit(runWorker(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "grpc" {
+		os.Exit(runGRPC(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "history" {
+		os.Exit(runHistory(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "upgrade" {
+		os.Exit(runUpgrade(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "presets" {
+		os.Exit(runPresets(os.Args[2:]))
+	}
+
+	logLevel := flag.String("log-level", "info", "minimum log level: debug, info, warn, error")
+	logFormat := flag.String("log-format", "pretty", "log output format: pretty, text, json")
+	logDir := flag.String("log-dir", "", "if set, also write per-job JSON logs to this directory")
+	verbosityFlag := flag.String("verbosity", "normal", "output volume: quiet, normal, debug")
+	dryRun := flag.Bool("dry-run", false, "analyze and print every planned command and output without executing")
+	formatFlag := flag.String("format", "", "stream format: hls or dash (default: the profile's stream_format, else hls)")
+	profileFlag := flag.String("profile", "sample_profile.json", "profile path, bare filename under profiles/, built-in preset (see cli presets), or - for stdin")
+	analysisCache := flag.String("analysis-cache", "", "cache media analysis: \"sidecar\" (next to input) or a cache directory")
+	keyframeMode := flag.String("keyframes", "packets", "keyframe extraction: packets (fast), keyonly, frames (slow, most robust)")
+	keyframeWindow := flag.Duration("keyframe-window", 0, "sample keyframes from only this much of the input (e.g. 10m); 0 reads it all")
+	keyframeTimeout := flag.Duration("keyframe-timeout", 0, "stop keyframe extraction after this long, keeping what was sampled; 0 for no limit")
+	detectScan := flag.Bool("detect-scan", false, "decode a sample with idet to detect interlaced/telecined sources")
+	loudness := flag.Bool("loudness", false, "measure loudness (LUFS), true peak, and silent intervals of the primary audio")
+	blackFreeze := flag.Bool("black-freeze", false, "detect black and frozen intervals (better thumbnails, dead recording check)")
+	fingerprint := flag.Bool("fingerprint", false, "compute a perceptual fingerprint for duplicate detection")
+	deepScan := flag.Bool("deep-scan", false, "decode the whole input to find corruption or truncation before transcoding")
+	queueDir := flag.String("queue", "", "distribute variant encodes to workers polling this shared queue directory (see cli worker)")
+	verifyPlayback := flag.Bool("verify", false, "play back the master manifest afterwards and fail on codec, resolution, or timestamp discrepancies")
+	inputFlag := flag.String("input", "", "source media, replacing the profile's input_path (required with built-in presets)")
+	outputFlag := flag.String("output", "", "output directory, replacing the profile's output_dir")
+	eventsFlag := flag.String("events", "", "write JSON-lines progress events to this file, - for stdout, or fd:N")
+	notifyFlag := flag.String("notify", "", "report how the run finished to the sinks listed in this JSON or YAML file")
+	var overlays overlayFlag
+	flag.Var(&overlays, "overlay", "overlay profile merged on top of -profile (repeatable)")
+	flag.Parse()
+
+	profileName := *profileFlag
+
+	level, err := logging.ParseLevel(*logLevel)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	verbosity, err := stagelog.ParseVerbosity(*verbosityFlag)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	executil.SetVerbosity(verbosity)
+
+	mode, err := analyzer.ParseKeyframeMode(*keyframeMode)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	analyzer.SetKeyframeMode(mode)
+
+	switch *analysisCache {
+	case "":
+	case "sidecar":
+		analyzer.SetCache(analyzer.SidecarCache{})
+	default:
+		analyzer.SetCache(analyzer.DirCache{Dir: *analysisCache})
+	}
+
+	jobLogger, closeLog, err := logging.NewJobLogger(logging.Options{
+		Level:     level,
+		Format:    *logFormat,
+		JobLogDir: *logDir,
+	}, profileSlug(profileName))
+	if err != nil {
+		log.Fatalf("❌ Failed to configure logging: %v", err)
+	}
+	defer closeLog.Close()
+	logging.SetDefault(jobLogger)
+	logger := stagelog.Filter(jobLogger, verbosity)
 
 	// Load transcode profile
-	profile, err := transcoder.LoadProfile(profileName)
+	profile, err := transcoder.LoadProfileWithPaths(profileName, *inputFlag, *outputFlag, overlays...)
 	if err != nil {
 		log.Fatalf("❌ Failed to load profile: %v", err)
 	}
+	streamFormat := profile.DeliveryFormat()
+	if *formatFlag != "" {
+		streamFormat = *formatFlag
+	}
 
 	fmt.Println("\n🎬 Loaded TranscodeProfile:")
 	fmt.Printf("   📁 InputPath:        %s\n", profile.InputPath)
@@ -43,13 +144,56 @@ func main() {
 		fmt.Printf("    • [%d] %s @ %s\n", i, v.Resolution, v.Bitrate)
 	}
 
+	// Machine-readable events for wrapping orchestrators
+	var events stageEvents
+	if *eventsFlag != "" {
+		w, err := pipeline.OpenEventStream(*eventsFlag)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		defer w.Close()
+		events.w = pipeline.NewEventWriter(w, profile.OutputSlug())
+	}
+	if *notifyFlag != "" {
+		n, err := pipeline.LoadNotifier(*notifyFlag)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		events.notifier = n
+		events.run = pipeline.Notification{
+			JobID:     profile.OutputSlug(),
+			InputPath: profile.InputPath,
+			Slug:      profile.OutputSlug(),
+		}
+		events.started = start
+	}
+
 	// Analyze input media once (shared across pipeline)
-	media, err := analyzer.AnalyzeMedia(profile.InputPath, profile.SegmentLength, logger)
+	events.begin(pipeline.StageAnalyze)
+	media, err := analyzer.AnalyzeMediaWithOptions(profile.InputPath, profile.SegmentLength, logger, profile.ImageAnalyzeOptions(analyzer.AnalyzeOptions{
+		DetectScan:      *detectScan,
+		DeepScan:        *deepScan,
+		KeyframeWindow:  *keyframeWindow,
+		KeyframeTimeout: *keyframeTimeout,
+		Loudness:        *loudness,
+		BlackFreeze:     *blackFreeze,
+		Fingerprint:     *fingerprint,
+	}))
 	if err != nil {
+		events.end(err)
 		log.Fatalf("❌ Failed to analyze media: %v", err)
 	}
-	fmt.Printf("\n🧠 MediaInfo: Duration=%.2fs, Width=%d, Height=%d, Bitrate=%dkbps\n",
-		media.Duration, media.Width, media.Height, media.Bitrate)
+	if err := media.Integrity.Err(); err != nil {
+		events.end(err)
+		log.Fatalf("❌ Source failed integrity scan: %v", err)
+	}
+	if err := profile.ApplyLanguages(media); err != nil {
+		events.end(err)
+		log.Fatalf("❌ %v", err)
+	}
+	events.end(nil)
+	fmt.Printf("\n🧠 MediaInfo: Duration=%.2fs, Width=%d, Height=%d, Bitrate=%dkbps, PixFmt=%s, Scan=%s\n",
+		media.Duration, media.Width, media.Height, media.Bitrate, media.PixelFormat, media.Scan.Summary())
 
 	// Define client context for resolution selection
 	ctx := scaler.ClientContext{
@@ -67,9 +211,32 @@ func main() {
 	}
 	fmt.Printf("\n🚀 Initial resolution selected: %s\n", initialPreset.Preset.LabelWithDimensions())
 
+	// Dry run: print the full command plan and exit without executing anything
+	if *dryRun {
+		pipeline.BuildPlan(profile, media, streamFormat, logger).Print(os.Stdout)
+		events.w.Finished(nil)
+		return
+	}
+
 	// Transcode media into adaptive variants
 	fmt.Println("\n🎞️ Starting transcoding...")
-	result, err := transcoder.Transcode(profile, media, logger)
+	events.begin(pipeline.StageTranscode)
+	var topts transcoder.TranscodeOptions
+	if events.w != nil {
+		topts.Progress = events.w.Progress
+	}
+	var result *transcoder.TranscodeResult
+	if *queueDir != "" {
+		queue, qerr := cluster.NewDirQueue(*queueDir)
+		if qerr != nil {
+			log.Fatalf("❌ Failed to open queue: %v", qerr)
+		}
+		coordinator := &cluster.Coordinator{Queue: queue}
+		result, err = coordinator.Transcode(context.Background(), profile, media, logger, topts)
+	} else {
+		result, err = transcoder.TranscodeWithOptions(context.Background(), profile, media, logger, topts)
+	}
+	events.end(err)
 	if err != nil {
 		log.Fatalf("❌ Transcoding failed: %v", err)
 	}
@@ -83,12 +250,18 @@ func main() {
 		fmt.Println("⚠️ Transcoding completed with errors:")
 		for _, e := range result.Errors {
 			fmt.Printf("   ❌ [%s:%s] %s\n", e.Stage, e.Operation, e.Message)
+			events.warn(pipeline.StageTranscode, e)
+		}
+		if result.Degraded {
+			fmt.Printf("   ⚠️ Degraded: publishing the %d variant(s) that succeeded\n", len(result.Variants))
 		}
 	}
 
 	// Segment each variant using shared MediaInfo
 	fmt.Println("\n✂️ Starting segmentation...")
-	segResult, err := segmenter.SegmentMedia(result, streamFormat, media)
+	events.begin(pipeline.StageSegment)
+	segResult, err := segmenter.SegmentMedia(result, streamFormat, media, logger)
+	events.end(err)
 	if err != nil {
 		log.Fatalf("❌ Segmentation failed: %v", err)
 	}
@@ -101,31 +274,75 @@ func main() {
 		fmt.Println("⚠️ Segmentation completed with errors:")
 		for _, e := range segResult.Errors {
 			fmt.Printf("   ❌ [%s] %s\n", e.Op, e.Msg)
+			events.warn(pipeline.StageSegment, e)
 		}
 	}
 
 	// 🖼️ Generating thumbnails...
 	fmt.Println("\n🖼️ Generating thumbnails...")
-	basename := filepath.Base(profile.InputPath)                 // "thelostboys.mp4"
-	name := strings.TrimSuffix(basename, filepath.Ext(basename)) // "thelostboys"
-	_, err = thumbnailer.GenerateThumbnails(*media, *result, name)
+	events.begin(pipeline.StageThumbnail)
+	thumbs, err := thumbnailer.GenerateThumbnailsContext(context.Background(), *media, *result, profile.OutputSlug(), logger, thumbnailer.Options{})
+	segResult.Thumbnails = thumbs.Paths(segResult.OutputDir)
 	if err != nil {
 		log.Printf("❌ Thumbnail generation failed: %v", err)
+		events.warn(pipeline.StageThumbnail, err)
 	}
+	events.end(nil)
 
 	// Generate master manifest from segmented variants
 	fmt.Println("\n🧾 Generating master manifest...")
-	manifestPath, err := manifester.GenerateMasterManifest(segResult, profile.PreserveManifest)
+	events.begin(pipeline.StageManifest)
+	var segmented []transcoder.ResolutionVariant
+	for _, m := range segResult.Manifests {
+		segmented = append(segmented, segResult.Variants[m])
+	}
+	if err := profile.CheckMinimumLadder(segmented); err != nil {
+		events.end(err)
+		log.Fatalf("❌ Not writing the master manifest: %v", err)
+	}
+	manifestPath, err := manifester.GenerateMasterManifest(segResult, profile.PreserveManifest, logger)
+	events.end(err)
 	if err != nil {
 		log.Fatalf("❌ Manifest generation failed: %v", err)
 	}
 	fmt.Printf("📜 Master manifest generated at: %s\n", manifestPath)
 
+	// Optionally play the result back before declaring success
+	if *verifyPlayback && streamFormat == "hls" {
+		fmt.Println("\n▶️ Verifying playback...")
+		events.begin(pipeline.StageVerify)
+		if !printPlaybackCheck(manifestPath, playcheck.DefaultTolerance) {
+			events.end(fmt.Errorf("playback check failed for %s", manifestPath))
+			log.Fatalf("❌ Playback check failed for %s", manifestPath)
+		}
+		events.end(nil)
+	}
+	events.finish()
+
 	// Final summary
 	fmt.Println("\n📦 Final Report")
 	fmt.Printf("   🎞️ Input: %s\n", profile.InputPath)
 	fmt.Printf("   📐 Variants: %d\n", len(result.Variants))
 	fmt.Printf("   📄 Manifests: %d\n", len(segResult.Manifests))
+	fmt.Printf("   🖼️ Thumbnails: %d\n", len(segResult.Thumbnails))
 	fmt.Printf("   ⚠️ Errors: %d\n", len(result.Errors)+len(segResult.Errors))
 	fmt.Printf("   🕒 Total pipeline time: %s\n", time.Since(start))
 }
+
+// profileSlug derives the per-job log name from the profile argument.
+func profileSlug(profileName string) string {
+	if profileName == transcoder.StdinProfile {
+		return "stdin"
+	}
+	return namer.SlugFromPath(profileName)
+}
+
+// overlayFlag collects repeated -overlay values in order.
+type overlayFlag []string
+
+func (o *overlayFlag) String() string { return strings.Join(*o, ",") }
+
+func (o *overlayFlag) Set(v string) error {
+	*o = append(*o, v)
+	return nil
+}
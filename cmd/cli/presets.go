@@ -0,0 +1,32 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+)
+
+// runPresets implements the "presets" command:
+//
+//	cli presets
+//
+// Lists the built-in profiles, which -profile accepts by name:
+//
+//	cli -profile streaming-high -input media/movie.mp4 -output media/output
+//
+// Returns the process exit code.
+func runPresets(args []string) int {
+	fs := flag.NewFlagSet("presets", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: cli presets")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	fmt.Println("\n🎛️ Built-in presets")
+	for _, p := range transcoder.Presets() {
+		fmt.Printf("   • %-18s %s\n", p.Name, p.Description)
+	}
+	return 0
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/dotsoulja/dotgo-transcode/pipeline"
+)
+
+// jobProgressPrinter prints whole-job progress when the stage changes, every
+// 5 points in between, and when the job is done.
+func jobProgressPrinter() pipeline.JobProgressFunc {
+	var mu sync.Mutex
+	stage, last := "", -1
+	return func(p pipeline.JobProgress) {
+		mu.Lock()
+		defer mu.Unlock()
+		step := int(p.Percent) / 5
+		if p.Stage == stage && step == last && !p.Done {
+			return
+		}
+		stage, last = p.Stage, step
+		if p.Done {
+			fmt.Println("📈 Job 100%")
+			return
+		}
+		fmt.Printf("📈 Job %3.0f%% (%s %.0f%%)\n", p.Percent, p.Stage, p.StagePercent)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/devserver"
+)
+
+// runServe implements the "serve" command:
+//
+//	cli serve [-addr 127.0.0.1:8080] [-origin url] [-no-ranges] [dir]
+//
+// Hosts dir (default media/output) over HTTP with streaming MIME types, and
+// serves an hls.js test page listing every master manifest, so fresh output
+// can be previewed immediately. It listens on loopback and sends no CORS
+// headers unless -addr and -origin say otherwise. Not meant for production.
+// Returns the process exit code: 1 if the server fails to start.
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "127.0.0.1:8080", "listen address (e.g. :8080 to serve the whole network)")
+	origin := fs.String("origin", "", "Access-Control-Allow-Origin value, e.g. * (default: no CORS headers)")
+	noRanges := fs.Bool("no-ranges", false, "ignore Range requests and always serve whole files")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: cli serve [-addr 127.0.0.1:8080] [-origin url] [-no-ranges] [dir]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	dir := "media/output"
+	if fs.NArg() > 0 {
+		dir = fs.Arg(0)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		fmt.Printf("❌ %s is not a directory\n", dir)
+		return 1
+	}
+
+	host := *addr
+	if strings.HasPrefix(host, ":") {
+		host = "localhost" + host
+	}
+	fmt.Printf("📡 Serving %s on http://%s\n", dir, host)
+	fmt.Printf("▶️  Test player: http://%s%s\n", host, devserver.PlayerPath)
+	for _, m := range devserver.Masters(dir) {
+		fmt.Printf("   • http://%s/%s\n", host, m)
+	}
+
+	handler := devserver.Handler(dir, devserver.Options{AllowOrigin: *origin, NoRanges: *noRanges})
+	if err := http.ListenAndServe(*addr, handler); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// defaultDrainTimeout is how long servers and workers let running work
+// finish after SIGTERM or an interrupt before interrupting it.
+const defaultDrainTimeout = 5 * time.Minute
+
+// shutdownContexts returns a drain context, done on the first SIGTERM or
+// interrupt, and a kill context, done once timeout has passed after that or
+// on a second signal. Work stops being accepted when drain is done and is
+// interrupted when kill is. The returned func releases the signal handler.
+func shutdownContexts(timeout time.Duration) (drain, kill context.Context, stop func()) {
+	drain, stopDrain := context.WithCancel(context.Background())
+	kill, stopKill := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			fmt.Printf("\n🚰 %s: finishing running work (up to %s; signal again to stop now)\n", sig, timeout)
+		case <-kill.Done():
+			return
+		}
+		stopDrain()
+		select {
+		case <-signals:
+		case <-time.After(timeout):
+		case <-kill.Done():
+		}
+		stopKill()
+	}()
+	return drain, kill, func() {
+		signal.Stop(signals)
+		stopDrain()
+		stopKill()
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dotsoulja/dotgo-transcode/pipeline"
+)
+
+// runUpgrade implements the "upgrade" command:
+//
+//	cli upgrade -profile p.json [-overlay o.json] [-format hls] [-ffmpeg-changes] [-prune] [-plan] [-events dest]
+//
+// Brings an existing output directory in line with a changed profile: rungs
+// whose recorded encoder settings still match are kept, new or changed rungs
+// (e.g. an added 1440p, or 1080p switched to HEVC) are encoded, and the master
+// manifest is rewritten for the whole ladder. With -plan it only prints what
+// would happen.
+// Returns the process exit code: 1 if planning or the run fails.
+func runUpgrade(args []string) int {
+	fs := flag.NewFlagSet("upgrade", flag.ExitOnError)
+	profilePath := fs.String("profile", "", "profile path or bare filename under profiles/ (required)")
+	format := fs.String("format", "", "stream format: hls or dash (default: the profile's stream_format, else hls)")
+	ffmpegChanges := fs.Bool("ffmpeg-changes", false, "also re-encode rungs produced by a different ffmpeg version")
+	prune := fs.Bool("prune", false, "delete rungs the profile no longer lists after the new manifest is written")
+	planOnly := fs.Bool("plan", false, "print the upgrade plan without encoding anything")
+	eventsTarget := fs.String("events", "", "write JSON-lines progress events to this file, - for stdout, or fd:N")
+	var overlays overlayFlag
+	fs.Var(&overlays, "overlay", "overlay profile merged on top of -profile (repeatable)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: cli upgrade -profile p.json [-overlay o.json] [-format hls] [-ffmpeg-changes] [-prune] [-plan] [-events dest]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *profilePath == "" {
+		fs.Usage()
+		return 2
+	}
+
+	opts := []pipeline.Option{
+		pipeline.WithUpgrade(pipeline.UpgradeOptions{FFmpegChanges: *ffmpegChanges, Prune: *prune}),
+		pipeline.WithDryRun(*planOnly),
+		pipeline.WithJobProgress(jobProgressPrinter()),
+	}
+	if *eventsTarget != "" {
+		w, err := pipeline.OpenEventStream(*eventsTarget)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			return 1
+		}
+		defer w.Close()
+		opts = append(opts, pipeline.WithEventStream(w))
+	}
+
+	report, err := pipeline.Run(pipeline.Config{
+		ProfilePath:  *profilePath,
+		Overlays:     overlays,
+		StreamFormat: *format,
+	}, opts...)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	if *planOnly {
+		return 0
+	}
+	if report.Upgrade != nil {
+		report.Upgrade.Print(os.Stdout)
+	}
+	fmt.Printf("\n✅ Upgrade complete: %d variant(s), manifest %s\n", report.VariantCount, report.ManifestPath)
+	return 0
+}
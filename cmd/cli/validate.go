@@ -0,0 +1,63 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
+)
+
+// runValidate implements the "validate" command:
+//
+//	cli validate [-overlay file]... [-probe] profile...
+//
+// Every problem in each profile is reported at once with its field path, along
+// with the defaults that will be applied. With -probe the input media is analyzed
+// so segment length can be checked against the source GOP.
+// Returns the process exit code: 0 if all profiles are valid, 1 otherwise.
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	var overlays overlayFlag
+	fs.Var(&overlays, "overlay", "overlay profile merged on top of each profile (repeatable)")
+	probe := fs.Bool("probe", false, "analyze the input media to check segment length against its GOP")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: cli validate [-overlay file]... [-probe] profile...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	status := 0
+	for _, name := range fs.Args() {
+		fmt.Printf("\n🔎 %s\n", name)
+		profile, err := transcoder.ReadProfileLayers(name, overlays...)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			status = 1
+			continue
+		}
+
+		var media *analyzer.MediaInfo
+		if *probe && profile.InputPath != "" {
+			media, err = analyzer.AnalyzeMediaWithOptions(profile.InputPath, profile.SegmentLength, stagelog.Nop, profile.ImageAnalyzeOptions(analyzer.AnalyzeOptions{}))
+			if err != nil {
+				fmt.Printf("⚠️ Could not analyze input, skipping media checks: %v\n", err)
+				media = nil
+			}
+		}
+
+		report := transcoder.ValidateProfile(*profile, media)
+		report.Print(os.Stdout)
+		if !report.OK() {
+			status = 1
+		}
+	}
+	return status
+}
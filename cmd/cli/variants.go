@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
+)
+
+// runVariants implements the "variants" command:
+//
+//	cli variants [-slug name] [-container ext] dir...
+//
+// Lists the encoded variants found in each slug output directory, with the
+// dimensions, size, and bitrate ffprobe reports. Useful before re-running
+// segmentation or thumbnails on existing outputs.
+// Returns the process exit code: 0 if every directory was readable, 1 otherwise.
+func runVariants(args []string) int {
+	fs := flag.NewFlagSet("variants", flag.ExitOnError)
+	slug := fs.String("slug", "", "only list variants for this slug (default: any)")
+	container := fs.String("container", "", "only list variants in this container, e.g. mp4 (default: any)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: cli variants [-slug name] [-container ext] dir...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	status := 0
+	for _, dir := range fs.Args() {
+		fmt.Printf("\n📂 %s\n", dir)
+		variants, err := transcoder.DiscoverVariants(dir, *slug, *container, stagelog.Nop)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			status = 1
+			continue
+		}
+		if len(variants) == 0 {
+			fmt.Println("   (no variants found)")
+			continue
+		}
+		for _, v := range variants {
+			fmt.Printf("   • %-40s %dx%d @ %s (measured %d kbps, %d bytes)\n",
+				v.OutputFilename, v.Width, v.Height, v.Bitrate, v.Stats.MeasuredBitrate, v.Stats.FileSize)
+		}
+	}
+	return status
+}
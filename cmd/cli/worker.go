@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/cluster"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
+)
+
+// runWorker implements the "worker" command:
+//
+//	cli worker -queue dir [-id name] [-poll 2s] [-scratch dir] [-drain-timeout 5m]
+//
+// Claims variant tasks from a shared queue directory and encodes them until
+// SIGTERM or interrupt, then finishes the running encode for up to
+// -drain-timeout (a second signal cuts this short); an encode interrupted
+// that way goes back to the queue. Start one per node (or per GPU); run the
+// main command with -queue pointing at the same directory to coordinate.
+// Returns the process exit code: 0 on shutdown, 1 if the queue is unusable.
+func runWorker(args []string) int {
+	fs := flag.NewFlagSet("worker", flag.ExitOnError)
+	queueDir := fs.String("queue", "", "shared queue directory (required)")
+	id := fs.String("id", "", "worker name reported in results (default hostname-pid)")
+	poll := fs.Duration("poll", cluster.DefaultPollInterval, "how often to look for work when idle")
+	scratch := fs.String("scratch", "", "directory for encode intermediates, kept across retries of a task (default under the system temp dir)")
+	drainTimeout := fs.Duration("drain-timeout", defaultDrainTimeout, "on SIGTERM or interrupt, let the running encode finish for this long before returning it to the queue")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: cli worker -queue dir [-id name] [-poll 2s] [-scratch dir] [-drain-timeout 5m]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *queueDir == "" {
+		fs.Usage()
+		return 2
+	}
+	queue, err := cluster.NewDirQueue(*queueDir)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+
+	drain, kill, stop := shutdownContexts(*drainTimeout)
+	defer stop()
+	w := &cluster.Worker{ID: *id, Queue: queue, Logger: stagelog.Std, PollInterval: *poll, Scratch: *scratch}
+	if err := w.RunDraining(drain, kill); err != nil && drain.Err() == nil {
+		fmt.Printf("❌ %v\n", err)
+		return 1
+	}
+	fmt.Printf("👋 Worker stopped at %s\n", time.Now().Format(time.TimeOnly))
+	return 0
+}
@@ -2,9 +2,15 @@ package analyzer
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"os/exec"
 	"sync"
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/executil"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
 )
 
 // AnalyzeMedia extracts metadata from a media file using ffprobe.
@@ -23,21 +29,130 @@ import (
 // Returns:
 //   - MediaInfo: populated metadata struct
 //   - error: if any subprocess or parsing fails
+//
+// When a process-wide cache is installed via SetCache, unchanged files are served
+// from the cache instead of being re-probed. Keyframes are extracted with the
+// process-wide mode set by SetKeyframeMode (KeyframePackets by default).
 func AnalyzeMedia(path string, segmentLength int, logger AnalyzerLogger) (*MediaInfo, error) {
+	return AnalyzeMediaWithOptions(path, segmentLength, logger, AnalyzeOptions{})
+}
+
+// AnalyzeOptions customizes a single analysis. Zero values fall back to the
+// process-wide defaults (SetCache, SetKeyframeMode).
+type AnalyzeOptions struct {
+	Cache        Cache        // Analysis cache; nil uses DefaultCache()
+	KeyframeMode KeyframeMode // Keyframe extraction speed/accuracy; "" uses DefaultKeyframeMode()
+	DetectScan   bool         // Run the idet filter to detect interlacing/telecine beyond container flags
+	DeepScan     bool         // Decode the whole file to find corruption and truncation (slow)
+	Loudness     bool         // Measure EBU R128 loudness, true peak, and silence on the primary audio
+	BlackFreeze  bool         // Detect black and frozen intervals in the primary video
+	Fingerprint  bool         // Compute a perceptual fingerprint for duplicate detection
+
+	// KeyframeWindow stops keyframe extraction once a keyframe past this much
+	// media has been seen, bounding analysis of long files. The interval is
+	// then averaged over the sample. 0 reads the whole file.
+	KeyframeWindow time.Duration
+	// KeyframeTimeout bounds the wall time of keyframe extraction. When it
+	// elapses, the keyframes read so far are kept. 0 means no limit.
+	KeyframeTimeout time.Duration
+
+	// StillDuration is the synthetic duration reported for a single image
+	// input; 0 uses DefaultStillDuration.
+	StillDuration time.Duration
+	// ImageFramerate is the synthetic framerate reported for image inputs,
+	// and the rate a sequence's images play at; 0 uses DefaultImageFramerate.
+	ImageFramerate float64
+}
+
+// features lists the optional analysis results these options require,
+// used to decide whether a cached entry is complete enough to reuse.
+func (o AnalyzeOptions) features(segmentLength int) []string {
+	var f []string
+	if segmentLength == 0 {
+		f = append(f, featureKeyframes)
+	}
+	if o.DetectScan {
+		f = append(f, featureScan)
+	}
+	if o.DeepScan {
+		f = append(f, featureIntegrity)
+	}
+	if o.Loudness {
+		f = append(f, featureLoudness)
+	}
+	if o.BlackFreeze {
+		f = append(f, featureBlackFreeze)
+	}
+	if o.Fingerprint {
+		f = append(f, featureFingerprint)
+	}
+	return f
+}
+
+// withDefaults fills unset options from the process-wide defaults.
+func (o AnalyzeOptions) withDefaults() AnalyzeOptions {
+	if o.Cache == nil {
+		o.Cache = DefaultCache()
+	}
+	if o.KeyframeMode == "" {
+		o.KeyframeMode = DefaultKeyframeMode()
+	}
+	return o
+}
+
+var (
+	defaultModeMu sync.RWMutex
+	defaultMode   = KeyframePackets
+)
+
+// SetKeyframeMode sets the process-wide keyframe extraction mode used by AnalyzeMedia.
+func SetKeyframeMode(m KeyframeMode) {
+	defaultModeMu.Lock()
+	defer defaultModeMu.Unlock()
+	if m == "" {
+		m = KeyframePackets
+	}
+	defaultMode = m
+}
+
+// DefaultKeyframeMode returns the process-wide keyframe extraction mode.
+func DefaultKeyframeMode() KeyframeMode {
+	defaultModeMu.RLock()
+	defer defaultModeMu.RUnlock()
+	return defaultMode
+}
+
+// analyzeMedia performs the uncached ffprobe analysis behind AnalyzeMedia.
+func analyzeMedia(ctx context.Context, path string, segmentLength int, opts AnalyzeOptions, logger AnalyzerLogger) (*MediaInfo, error) {
+	logger = stagelog.OrStd(logger)
+
+	// Image sequences are probed as one input starting at their first image
+	var image *ImageSource
+	if ImageInputKind(path) == ImageSequence {
+		seq, err := ScanImageSequence(path)
+		if err != nil {
+			return nil, &AnalyzerError{Op: "scan_image_sequence", Path: path, Err: err}
+		}
+		image = seq
+	}
+
 	// Run ffprobe to extract format and stream-level metadata
-	cmd := exec.Command(
-		"ffprobe",
-		"-v", "error",
-		"-print_format", "json",
-		"-show_format",
-		"-show_streams",
-		path,
-	)
-
-	var out bytes.Buffer
+	args := []string{"-v", "error", "-print_format", "json", "-show_format", "-show_streams"}
+	args = append(args, imageProbeArgs(image)...)
+	cmd := exec.CommandContext(ctx, "ffprobe", append(args, path)...)
+
+	var out, stderr bytes.Buffer
 	cmd.Stdout = &out
+	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
+	err := cmd.Run()
+	executil.RecordUsage(ctx, cmd.ProcessState)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = context.Cause(ctx)
+		} else {
+			err = classifyProbeFailure(stderr.String(), err)
+		}
 		return nil, &AnalyzerError{
 			Op:   "exec_ffprobe",
 			Path: path,
@@ -79,19 +194,41 @@ func AnalyzeMedia(path string, segmentLength int, logger AnalyzerLogger) (*Media
 		}
 	}
 
-	// Extract codec and resolution from video/audio streams
-	for _, stream := range probe.Streams {
-		switch stream.CodecType {
-		case "video":
-			info.VideoCodec = stream.CodecName
+	// Enumerate every stream, then derive primary video/audio from the inventory.
+	// Cover art (attached pictures) is never treated as the primary video.
+	info.Container = probe.Format.FormatName
+	info.Tags = probe.Format.Tags
+	info.Streams = buildStreamInventory(probe.Streams)
+	for _, stream := range info.Streams {
+		if stream.Type == StreamVideo && !stream.AttachedPic && info.VideoCodec == "" {
+			info.VideoCodec = stream.Codec
 			info.Width = stream.Width
 			info.Height = stream.Height
-		case "audio":
-			info.AudioCodec = stream.CodecName
+			info.PixelFormat = stream.PixelFormat
+			info.BitDepth = stream.BitDepth
+			info.Color = stream.Color
+			info.Framerate = stream.Framerate
+			info.Scan = scanFromFieldOrder(stream.FieldOrder)
 		}
 	}
+	if audio := info.PrimaryAudio(); audio != nil {
+		info.AudioCodec = audio.Codec
+	}
+
+	// Stills and sequences get the duration and framerate they'll be encoded with
+	if image == nil && isImageFormat(info.Container) && info.VideoCodec != "" {
+		image = &ImageSource{Kind: ImageStill, Frames: 1}
+	}
+	if image != nil {
+		applyImageTiming(info, image, opts)
+		logger.LogStage("image", fmt.Sprintf("🖼️ Image %s: %d frame(s), %.2fs at %.3g fps", image.Kind, image.Frames, info.Duration, info.Framerate))
+	}
 
-	logger.LogStage("streams", "Extracted codec and resolution metadata")
+	logger.LogStage("streams", fmt.Sprintf("Extracted %d streams (%d audio, %d subtitle, %d data)",
+		len(info.Streams), len(info.AudioTracks()), len(info.SubtitleTracks()), len(info.DataStreams())))
+	if forced := info.ForcedSubtitles(); len(forced) > 0 {
+		logger.LogStage("streams", fmt.Sprintf("💬 %d forced subtitle track(s) for foreign-language dialogue", len(forced)))
+	}
 
 	// Extract framerate (required for keyframe estimation)
 	var frWg sync.WaitGroup
@@ -100,6 +237,9 @@ func AnalyzeMedia(path string, segmentLength int, logger AnalyzerLogger) (*Media
 	frWg.Add(1)
 	go func() {
 		defer frWg.Done()
+		if info.Framerate > 0 {
+			return // already known from the stream inventory
+		}
 		logger.LogStage("framerate", "Extracting framerate")
 		if fr, err := extractFramerate(path); err == nil {
 			mu.Lock()
@@ -112,7 +252,10 @@ func AnalyzeMedia(path string, segmentLength int, logger AnalyzerLogger) (*Media
 	frWg.Wait()
 
 	// Conditionally extract keyframes (only if segmentLength == 0)
-	if segmentLength == 0 {
+	if image != nil {
+		logger.LogStage("keyframes", "⏩ Skipping keyframe analysis (image input)")
+	} else if segmentLength == 0 {
+		var kfErr error
 		var kfWg sync.WaitGroup
 		kfWg.Add(1)
 		go func() {
@@ -122,20 +265,88 @@ func AnalyzeMedia(path string, segmentLength int, logger AnalyzerLogger) (*Media
 			framerate := info.Framerate
 			mu.Unlock()
 
-			if kf, interval, err := extractKeyframes(path, duration, framerate, logger); err == nil {
+			if kf, interval, partial, err := extractKeyframes(ctx, path, duration, framerate, opts, logger); err == nil {
 				mu.Lock()
 				info.Keyframes = kf
 				info.KeyframeInterval = interval
+				info.KeyframesPartial = partial
 				mu.Unlock()
 			} else {
 				logger.LogError("keyframes", err)
+				kfErr = err
 			}
 		}()
 		kfWg.Wait()
+		if kfErr != nil && ctx.Err() != nil {
+			return nil, kfErr // Cancelled; other keyframe failures are not fatal
+		}
 	} else {
 		logger.LogStage("keyframes", "⏩ Skipping keyframe analysis (segment length manually set)")
 	}
 
+	// Optional: detect interlacing/telecine by inspecting decoded fields
+	if opts.DetectScan && info.VideoCodec != "" {
+		logger.LogStage("scan", "Detecting scan type (idet)")
+		if scan, err := detectScan(path, info.Duration); err == nil {
+			info.Scan = mergeScan(info.Scan, scan)
+			logger.LogStage("scan", fmt.Sprintf("🎞️ Scan type: %s", info.Scan.Summary()))
+		} else {
+			logger.LogError("scan", err)
+		}
+	}
+
+	// Optional: loudness and silence of the primary audio track
+	if audio := info.PrimaryAudio(); opts.Loudness && audio != nil {
+		logger.LogStage("loudness", "🔊 Measuring loudness and silence")
+		if loud, err := measureLoudness(path, *audio, info.Duration); err == nil {
+			info.Loudness = loud
+			logger.LogStage("loudness", fmt.Sprintf("🔊 %.1f LUFS, true peak %.1f dBTP, %d silent interval(s)",
+				loud.IntegratedLUFS, loud.TruePeak, len(loud.Silences)))
+		} else {
+			logger.LogError("loudness", err)
+		}
+	}
+
+	// Optional: black and frozen stretches of the primary video
+	if opts.BlackFreeze && info.VideoCodec != "" {
+		logger.LogStage("blackfreeze", "⬛ Detecting black and frozen frames")
+		if black, frozen, err := detectBlackFreeze(path, info.Duration); err == nil {
+			info.BlackIntervals = black
+			info.FreezeIntervals = frozen
+			logger.LogStage("blackfreeze", fmt.Sprintf("⬛ %d black, %d frozen interval(s)", len(black), len(frozen)))
+			if info.IsDeadRecording() {
+				logger.LogStage("blackfreeze", "⚠️ Video is almost entirely black or frozen (dead recording?)")
+			}
+		} else {
+			logger.LogError("blackfreeze", err)
+		}
+	}
+
+	// Optional: perceptual fingerprint for duplicate detection
+	if opts.Fingerprint && info.VideoCodec != "" {
+		logger.LogStage("fingerprint", "🔖 Computing perceptual fingerprint")
+		if fp, err := computeFingerprint(path); err == nil {
+			info.Perceptual = fp
+		} else {
+			logger.LogError("fingerprint", err)
+		}
+	}
+
+	// Optional: decode everything to catch corruption before hours of transcoding
+	if opts.DeepScan {
+		logger.LogStage("integrity", "🩺 Decoding full file to check integrity")
+		report, err := scanIntegrity(path, info.Duration, logger)
+		if err != nil {
+			return nil, err
+		}
+		info.Integrity = report
+		if report.OK {
+			logger.LogStage("integrity", "✅ No decode errors found")
+		} else {
+			logger.LogError("integrity", report.Err())
+		}
+	}
+
 	logger.LogStage("complete", "✅ Media analysis complete")
 	return info, nil
 }
@@ -0,0 +1,140 @@
+package analyzer
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+)
+
+// Black/freeze detection thresholds.
+const (
+	blackMinDuration  = 0.5  // Shortest black stretch reported (seconds)
+	blackPixelThresh  = 0.10 // Luma below which a pixel counts as black (0..1)
+	freezeNoiseDB     = -60  // Frame difference below which frames count as identical (dB)
+	freezeMinDuration = 2.0  // Shortest frozen stretch reported (seconds)
+
+	// deadRecordingFraction is the share of duration that must be black or
+	// frozen for a file to be flagged as a dead recording.
+	deadRecordingFraction = 0.9
+)
+
+var (
+	blackRe       = regexp.MustCompile(`black_start:\s*(-?[\d.]+)\s+black_end:\s*(-?[\d.]+)`)
+	freezeStartRe = regexp.MustCompile(`freeze_start:\s*(-?[\d.]+)`)
+	freezeEndRe   = regexp.MustCompile(`freeze_end:\s*(-?[\d.]+)`)
+	freezeMarker  = regexp.MustCompile(`freeze_(?:start|end):\s*-?[\d.]+`)
+)
+
+// detectBlackFreeze decodes the primary video through blackdetect and
+// freezedetect in one pass and returns the black and frozen intervals.
+// The whole track is decoded, so this scales with duration.
+func detectBlackFreeze(path string, duration float64) (black, frozen []Interval, err error) {
+	filter := fmt.Sprintf("blackdetect=d=%g:pix_th=%.2f,freezedetect=n=%ddB:d=%g",
+		blackMinDuration, blackPixelThresh, freezeNoiseDB, freezeMinDuration)
+	cmd := exec.Command(
+		"ffmpeg",
+		"-hide_banner",
+		"-nostats",
+		"-i", path,
+		"-map", "0:V:0",
+		"-vf", filter,
+		"-an", "-sn", "-dn",
+		"-f", "null", "-",
+	)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, nil, &AnalyzerError{Op: "exec_ffmpeg_blackfreeze", Path: path, Err: err}
+	}
+	black, frozen = parseBlackFreeze(stderr.String(), duration)
+	return black, frozen, nil
+}
+
+// parseBlackFreeze extracts blackdetect and freezedetect intervals from
+// ffmpeg's stderr. A freeze still open at end of file is closed at duration.
+func parseBlackFreeze(output string, duration float64) (black, frozen []Interval) {
+	for _, m := range blackRe.FindAllStringSubmatch(output, -1) {
+		start, _ := strconv.ParseFloat(m[1], 64)
+		end, _ := strconv.ParseFloat(m[2], 64)
+		black = append(black, Interval{Start: start, End: end})
+	}
+
+	var start float64
+	var open bool
+	for _, marker := range freezeMarker.FindAllString(output, -1) {
+		if s := freezeStartRe.FindStringSubmatch(marker); s != nil {
+			start, _ = strconv.ParseFloat(s[1], 64)
+			open = true
+			continue
+		}
+		if e := freezeEndRe.FindStringSubmatch(marker); e != nil && open {
+			end, _ := strconv.ParseFloat(e[1], 64)
+			frozen = append(frozen, Interval{Start: start, End: end})
+			open = false
+		}
+	}
+	frozen = closeOpenInterval(frozen, start, open, duration)
+	return black, frozen
+}
+
+// IsDeadRecording reports whether at least 90% of the video is black or frozen,
+// e.g. a camera left running with the lens cap on. Always false unless black
+// and freeze detection ran.
+func (m *MediaInfo) IsDeadRecording() bool {
+	if m.Duration <= 0 || (m.BlackIntervals == nil && m.FreezeIntervals == nil) {
+		return false
+	}
+	all := append(append([]Interval(nil), m.BlackIntervals...), m.FreezeIntervals...)
+	live := 0.0
+	for _, gap := range Complement(all, m.Duration, 0) {
+		live += gap.Duration()
+	}
+	return (m.Duration-live)/m.Duration >= deadRecordingFraction
+}
+
+// InBlackOrFrozen reports whether t falls inside a detected black or frozen
+// interval, returning that interval.
+func (m *MediaInfo) InBlackOrFrozen(t float64) (Interval, bool) {
+	for _, iv := range m.BlackIntervals {
+		if iv.Contains(t) {
+			return iv, true
+		}
+	}
+	for _, iv := range m.FreezeIntervals {
+		if iv.Contains(t) {
+			return iv, true
+		}
+	}
+	return Interval{}, false
+}
+
+// BreakPoints returns natural ad-insertion points: the midpoint of each black
+// interval, skipping the head and tail of the file. When loudness analysis ran,
+// only black intervals that are also silent qualify.
+func (m *MediaInfo) BreakPoints() []float64 {
+	var points []float64
+	for _, b := range m.BlackIntervals {
+		mid := b.Start + b.Duration()/2
+		if b.Start <= 0 || (m.Duration > 0 && b.End >= m.Duration-blackMinDuration) {
+			continue // leader or end credits fade, not a break
+		}
+		if m.Loudness != nil && !overlapsAny(b, m.Loudness.Silences) {
+			continue
+		}
+		points = append(points, mid)
+	}
+	return points
+}
+
+// overlapsAny reports whether iv overlaps any of the given intervals.
+func overlapsAny(iv Interval, others []Interval) bool {
+	for _, o := range others {
+		if iv.Start < o.End && o.Start < iv.End {
+			return true
+		}
+	}
+	return false
+}
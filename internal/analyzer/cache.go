@@ -0,0 +1,345 @@
+package analyzer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
+)
+
+// cacheVersion is bumped whenever MediaInfo or analysis semantics change,
+// invalidating every previously cached entry.
+const cacheVersion = 9
+
+// SidecarSuffix is appended to the media path for SidecarCache entries
+// (e.g. "movie.mp4" -> "movie.mp4.mediainfo.json").
+const SidecarSuffix = ".mediainfo.json"
+
+// hashSampleBytes is how much of the file head, middle, and tail feeds the content hash.
+const hashSampleBytes = 1 << 20
+
+// Fingerprint identifies a specific version of a media file.
+// Size and ModTime are cheap to check; ContentHash is a SHA-256 over the size and
+// sampled head/middle/tail bytes, which survives copies and touches that change mtime.
+type Fingerprint struct {
+	Size        int64     `json:"size"`
+	ModTime     time.Time `json:"mod_time"`
+	ContentHash string    `json:"content_hash,omitempty"`
+}
+
+// CacheEntry is a cached analysis result for one media file.
+type CacheEntry struct {
+	Version      int          `json:"version"`
+	Path         string       `json:"path"`
+	Fingerprint  Fingerprint  `json:"fingerprint"`
+	HasKeyframes bool         `json:"has_keyframes"`           // False when keyframe analysis was skipped
+	KeyframeMode KeyframeMode `json:"keyframe_mode,omitempty"` // Extraction mode; "" means frame-level (pre-mode entries)
+	Features     []string     `json:"features,omitempty"`      // Optional analysis passes included in Info (e.g. "scan")
+	Info         MediaInfo    `json:"info"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+// Cache stores analysis results keyed by absolute media path.
+// Implementations only persist entries; freshness is checked by the analyzer
+// against the file's current fingerprint, so stores can stay simple.
+// Load returns (nil, nil) on a miss.
+type Cache interface {
+	Load(path string) (*CacheEntry, error)
+	Store(path string, entry *CacheEntry) error
+}
+
+var (
+	defaultCacheMu sync.RWMutex
+	defaultCache   Cache
+)
+
+// SetCache installs a process-wide cache used by AnalyzeMedia. Pass nil to disable caching
+// (the default).
+func SetCache(c Cache) {
+	defaultCacheMu.Lock()
+	defer defaultCacheMu.Unlock()
+	defaultCache = c
+}
+
+// DefaultCache returns the process-wide cache, or nil if caching is disabled.
+func DefaultCache() Cache {
+	defaultCacheMu.RLock()
+	defer defaultCacheMu.RUnlock()
+	return defaultCache
+}
+
+// AnalyzeMediaWithCache is AnalyzeMedia backed by cache. A fresh entry for an
+// unchanged file is returned without running ffprobe; otherwise the file is analyzed
+// and the result stored. Cache failures are logged and never fail the analysis.
+// A nil cache falls back to DefaultCache().
+func AnalyzeMediaWithCache(path string, segmentLength int, logger AnalyzerLogger, cache Cache) (*MediaInfo, error) {
+	return AnalyzeMediaWithOptions(path, segmentLength, logger, AnalyzeOptions{Cache: cache})
+}
+
+// AnalyzeMediaWithOptions is AnalyzeMedia with per-call cache and keyframe mode.
+// When no cache is configured (neither in opts nor process-wide), the file is
+// analyzed unconditionally.
+func AnalyzeMediaWithOptions(path string, segmentLength int, logger AnalyzerLogger, opts AnalyzeOptions) (*MediaInfo, error) {
+	return AnalyzeMediaContext(context.Background(), path, segmentLength, logger, opts)
+}
+
+// AnalyzeMediaContext is AnalyzeMediaWithOptions with a context: cancelling it
+// kills the running ffprobe and returns its cause.
+func AnalyzeMediaContext(ctx context.Context, path string, segmentLength int, logger AnalyzerLogger, opts AnalyzeOptions) (*MediaInfo, error) {
+	logger = stagelog.OrStd(logger)
+	opts = opts.withDefaults()
+	cache, mode := opts.Cache, opts.KeyframeMode
+	// Image timing comes from the options, and probing images is cheap
+	if cache == nil || ImageInputKind(path) != ImageNone {
+		return analyzeMedia(ctx, path, segmentLength, opts, logger)
+	}
+
+	key, err := filepath.Abs(path)
+	if err != nil {
+		key = path
+	}
+	fp, err := statFingerprint(path)
+	if err != nil {
+		// Let the analyzer report the missing/unreadable file
+		return analyzeMedia(ctx, path, segmentLength, opts, logger)
+	}
+
+	entry, err := cache.Load(key)
+	if err != nil {
+		logger.LogError("cache", fmt.Errorf("load analysis cache for %s: %w", path, err))
+	}
+	required := opts.features(segmentLength)
+	if entry != nil && entry.usable(required, opts) {
+		if entry.Fingerprint.Size == fp.Size && entry.Fingerprint.ModTime.Equal(fp.ModTime) {
+			logger.LogStage("cache", "♻️ Using cached media analysis")
+			info := entry.Info
+			return &info, nil
+		}
+		// mtime changed: compare content before discarding the entry
+		if entry.Fingerprint.Size == fp.Size && entry.Fingerprint.ContentHash != "" {
+			if hash, err := ContentHash(path); err == nil && hash == entry.Fingerprint.ContentHash {
+				logger.LogStage("cache", "♻️ Using cached media analysis (content unchanged)")
+				entry.Fingerprint.ModTime = fp.ModTime
+				if err := cache.Store(key, entry); err != nil {
+					logger.LogError("cache", fmt.Errorf("refresh analysis cache for %s: %w", path, err))
+				}
+				info := entry.Info
+				return &info, nil
+			}
+		}
+	}
+
+	info, err := analyzeMedia(ctx, path, segmentLength, opts, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if hash, err := ContentHash(path); err == nil {
+		fp.ContentHash = hash
+	}
+	if err := cache.Store(key, &CacheEntry{
+		Version:      cacheVersion,
+		Path:         key,
+		Fingerprint:  fp,
+		HasKeyframes: segmentLength == 0,
+		KeyframeMode: mode,
+		Features:     required,
+		Info:         *info,
+		CreatedAt:    time.Now().UTC(),
+	}); err != nil {
+		logger.LogError("cache", fmt.Errorf("store analysis cache for %s: %w", path, err))
+	}
+	return info, nil
+}
+
+// Optional analysis features recorded in CacheEntry.Features.
+const (
+	featureKeyframes   = "keyframes"
+	featureScan        = "scan"
+	featureIntegrity   = "integrity"
+	featureLoudness    = "loudness"
+	featureBlackFreeze = "blackfreeze"
+	featureFingerprint = "fingerprint"
+)
+
+// usable reports whether the entry matches the current cache version and
+// includes every required feature. Frame-level keyframe requests are only
+// satisfied by frame-level entries; faster modes accept any keyframe entry.
+// Sampled keyframes only satisfy requests that would sample too.
+func (e *CacheEntry) usable(required []string, opts AnalyzeOptions) bool {
+	mode := opts.KeyframeMode
+	if e.Version != cacheVersion {
+		return false
+	}
+	for _, f := range required {
+		if f == featureKeyframes {
+			if !e.HasKeyframes {
+				return false
+			}
+			if mode == KeyframeFrames && e.KeyframeMode != "" && e.KeyframeMode != KeyframeFrames {
+				return false
+			}
+			if e.Info.KeyframesPartial && opts.KeyframeWindow == 0 && opts.KeyframeTimeout == 0 {
+				return false
+			}
+			continue
+		}
+		if !slices.Contains(e.Features, f) {
+			return false
+		}
+	}
+	return true
+}
+
+// statFingerprint returns the size and modification time of path.
+func statFingerprint(path string) (Fingerprint, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+	return Fingerprint{Size: st.Size(), ModTime: st.ModTime().UTC()}, nil
+}
+
+// ContentHash returns a hex SHA-256 over the file size and up to 1 MiB sampled
+// from the head, middle, and tail of the file. It reads at most 3 MiB regardless
+// of file size, so it is cheap enough to compute on every cache store.
+func ContentHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	st, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	size := st.Size()
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%d:", size)
+	for _, off := range []int64{0, size/2 - hashSampleBytes/2, size - hashSampleBytes} {
+		if off < 0 {
+			off = 0
+		}
+		if _, err := io.Copy(h, io.NewSectionReader(f, off, hashSampleBytes)); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// SidecarCache stores each entry next to its media file as <media>.mediainfo.json.
+// Convenient for local libraries; requires write access to the media directory.
+type SidecarCache struct{}
+
+// Load reads the sidecar file for path.
+func (SidecarCache) Load(path string) (*CacheEntry, error) {
+	return readEntry(path + SidecarSuffix)
+}
+
+// Store writes the sidecar file for path.
+func (SidecarCache) Store(path string, entry *CacheEntry) error {
+	return writeEntry(path+SidecarSuffix, entry)
+}
+
+// DirCache stores entries in a single directory, named by a hash of the media path.
+// Suitable for read-only media mounts and shared cache volumes.
+type DirCache struct {
+	Dir string
+}
+
+// Load reads the entry for path from the cache directory.
+func (c DirCache) Load(path string) (*CacheEntry, error) {
+	return readEntry(c.entryPath(path))
+}
+
+// Store writes the entry for path into the cache directory.
+func (c DirCache) Store(path string, entry *CacheEntry) error {
+	if err := os.MkdirAll(c.Dir, os.ModePerm); err != nil {
+		return err
+	}
+	return writeEntry(c.entryPath(path), entry)
+}
+
+func (c DirCache) entryPath(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:12])+".json")
+}
+
+// MemoryCache keeps entries in memory for the life of the process.
+// Useful for long-running services that analyze the same files repeatedly.
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]CacheEntry
+}
+
+// Load returns a copy of the in-memory entry for path.
+func (c *MemoryCache) Load(path string) (*CacheEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[path]
+	if !ok {
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+// Store saves a copy of entry for path.
+func (c *MemoryCache) Store(path string, entry *CacheEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]CacheEntry)
+	}
+	c.entries[path] = *entry
+	return nil
+}
+
+// readEntry decodes a cache entry file, treating a missing file as a miss.
+func readEntry(file string) (*CacheEntry, error) {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// writeEntry encodes entry to file atomically via a temp file and rename.
+func writeEntry(file string, entry *CacheEntry) error {
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
@@ -0,0 +1,37 @@
+package analyzer
+
+// Content classes reported by ContentClass.
+const (
+	ContentFilm       = "film"       // Continuous motion: film, TV, camera footage
+	ContentScreencast = "screencast" // Screen recordings and slides: mostly static, often at low frame rates
+)
+
+// Screencast heuristics: screen recordings either run at a reduced frame rate
+// or sit still for long stretches (frozen intervals need BlackFreeze analysis).
+const (
+	screencastMaxFramerate   = 15.0
+	screencastFrozenFraction = 0.3
+)
+
+// ContentClass guesses whether the video is a screencast or film from
+// analysis already run: a frame rate of 15 fps or less, or frozen intervals
+// covering at least 30% of the duration, mark a screencast. Anything else
+// with a known frame rate is film; "" when there's nothing to go on.
+func (m *MediaInfo) ContentClass() string {
+	if m.Framerate > 0 && m.Framerate <= screencastMaxFramerate {
+		return ContentScreencast
+	}
+	if m.Duration > 0 {
+		var frozen float64
+		for _, iv := range m.FreezeIntervals {
+			frozen += iv.Duration()
+		}
+		if frozen/m.Duration >= screencastFrozenFraction {
+			return ContentScreencast
+		}
+	}
+	if m.Framerate > 0 {
+		return ContentFilm
+	}
+	return ""
+}
@@ -1,6 +1,11 @@
 package analyzer
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/errclass"
+)
 
 // AnalyzerError represents an error during media analysis.
 // Includes operation context and file path for forensic clarity
@@ -17,3 +22,15 @@ func (e *AnalyzerError) Error() string {
 func (e *AnalyzerError) Unwrap() error {
 	return e.Err
 }
+
+// Class classifies the failure (see errclass) by its cause, or as a corrupt
+// input when ffprobe couldn't read the file for a reason the cause doesn't show.
+func (e *AnalyzerError) Class() errclass.Class {
+	if class := errclass.Classify(e.Err); class != errclass.None && class != errclass.Unknown {
+		return class
+	}
+	if strings.Contains(e.Op, "ffprobe") {
+		return errclass.InputCorrupt
+	}
+	return errclass.Unknown
+}
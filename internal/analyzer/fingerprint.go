@@ -0,0 +1,140 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"os/exec"
+	"strings"
+)
+
+// Perceptual fingerprint parameters.
+const (
+	fingerprintInterval  = 2.0  // Seconds between sampled frames
+	fingerprintMaxFrames = 1024 // Sampling stops after this many frames (~34 minutes)
+	hashWidth            = 9    // dHash compares 9 horizontally adjacent pixels...
+	hashHeight           = 8    // ...across 8 rows, giving 64 bits per frame
+
+	// matchDistance is the maximum Hamming distance (of 64 bits) for two frame
+	// hashes to count as the same picture after re-encoding or rescaling.
+	matchDistance = 10
+)
+
+// PerceptualFingerprint is a compact visual signature of the primary video:
+// a 64-bit difference hash (dHash) for a frame every fingerprintInterval
+// seconds. Sampling at a fixed rate keeps hash i at the same media time in
+// every copy, whatever its length, so copies with a shifted start line up.
+// Unlike the cache Fingerprint it survives re-encoding, rescaling, and
+// container changes, so ingest systems can spot duplicate uploads.
+type PerceptualFingerprint struct {
+	Hashes []uint64 // One dHash per sampled frame, in time order, fingerprintInterval apart
+}
+
+// String encodes the fingerprint as colon-separated hex hashes for storage.
+func (p *PerceptualFingerprint) String() string {
+	if p == nil {
+		return ""
+	}
+	parts := make([]string, len(p.Hashes))
+	for i, h := range p.Hashes {
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], h)
+		parts[i] = hex.EncodeToString(b[:])
+	}
+	return strings.Join(parts, ":")
+}
+
+// ParsePerceptualFingerprint decodes the output of PerceptualFingerprint.String.
+func ParsePerceptualFingerprint(s string) (*PerceptualFingerprint, error) {
+	p := &PerceptualFingerprint{}
+	if s == "" {
+		return p, nil
+	}
+	for _, part := range strings.Split(s, ":") {
+		b, err := hex.DecodeString(part)
+		if err != nil || len(b) != 8 {
+			return nil, fmt.Errorf("invalid fingerprint hash %q", part)
+		}
+		p.Hashes = append(p.Hashes, binary.BigEndian.Uint64(b))
+	}
+	return p, nil
+}
+
+// Similarity returns the fraction (0..1) of sampled frames that match between
+// two fingerprints, trying alignment shifts of up to a quarter of the shorter
+// one so trimmed copies still match.
+// Values above ~0.8 indicate a duplicate or near-duplicate.
+func (p *PerceptualFingerprint) Similarity(other *PerceptualFingerprint) float64 {
+	if p == nil || other == nil || len(p.Hashes) == 0 || len(other.Hashes) == 0 {
+		return 0
+	}
+	n := min(len(p.Hashes), len(other.Hashes))
+	maxShift := n / 4
+
+	best := 0.0
+	for shift := -maxShift; shift <= maxShift; shift++ {
+		matched, compared := 0, 0
+		for i := range p.Hashes {
+			j := i + shift
+			if j < 0 || j >= len(other.Hashes) {
+				continue
+			}
+			compared++
+			if bits.OnesCount64(p.Hashes[i]^other.Hashes[j]) <= matchDistance {
+				matched++
+			}
+		}
+		// Normalize by the longer fingerprint so a short clip isn't a "duplicate" of a feature
+		if score := float64(matched) / float64(max(len(p.Hashes), len(other.Hashes))); compared > 0 && score > best {
+			best = score
+		}
+	}
+	return best
+}
+
+// computeFingerprint samples a frame every fingerprintInterval seconds,
+// downscales each to 9x8 grayscale through ffmpeg, and hashes them.
+func computeFingerprint(path string) (*PerceptualFingerprint, error) {
+	filter := fmt.Sprintf("fps=1/%g,scale=%d:%d:flags=area,format=gray", fingerprintInterval, hashWidth, hashHeight)
+
+	cmd := exec.Command(
+		"ffmpeg",
+		"-hide_banner",
+		"-v", "error",
+		"-i", path,
+		"-map", "0:V:0",
+		"-vf", filter,
+		"-frames:v", fmt.Sprint(fingerprintMaxFrames),
+		"-f", "rawvideo", "-",
+	)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return nil, &AnalyzerError{Op: "exec_ffmpeg_fingerprint", Path: path, Err: err}
+	}
+	return hashFrames(out.Bytes()), nil
+}
+
+// hashFrames computes a dHash for each 9x8 grayscale frame in raw: bit n is set
+// when a pixel is brighter than its right-hand neighbour.
+func hashFrames(raw []byte) *PerceptualFingerprint {
+	const frameSize = hashWidth * hashHeight
+	p := &PerceptualFingerprint{}
+	for off := 0; off+frameSize <= len(raw); off += frameSize {
+		frame := raw[off : off+frameSize]
+		var h uint64
+		for y := 0; y < hashHeight; y++ {
+			row := frame[y*hashWidth : (y+1)*hashWidth]
+			for x := 0; x < hashWidth-1; x++ {
+				h <<= 1
+				if row[x] > row[x+1] {
+					h |= 1
+				}
+			}
+		}
+		p.Hashes = append(p.Hashes, h)
+	}
+	return p
+}
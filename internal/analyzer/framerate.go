@@ -13,7 +13,7 @@ func extractFramerate(path string) (float64, error) {
 	cmd := exec.Command(
 		"ffprobe",
 		"-v", "error",
-		"-select_streams", "v:0",
+		"-select_streams", "V:0",
 		"-show_entries", "stream=r_frame_rate",
 		"-of", "json",
 		path,
@@ -0,0 +1,146 @@
+package analyzer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Synthetic timing given to image inputs when AnalyzeOptions leaves it unset.
+const (
+	DefaultStillDuration  = 5 * time.Second // How long a single image plays
+	DefaultImageFramerate = 25.0            // Frames per second of a still, and the rate a sequence's images are played at
+)
+
+// ImageKind tells single images from numbered image sequences.
+type ImageKind string
+
+// Image input kinds reported by ImageInputKind and ImageSource.Kind.
+const (
+	ImageNone     ImageKind = ""         // Not an image input
+	ImageStill    ImageKind = "still"    // One image (poster, slate, rating card) looped into a clip
+	ImageSequence ImageKind = "sequence" // Numbered images named by a printf pattern (e.g. "frames/shot_%04d.png")
+)
+
+// ImageSource describes an image input (MediaInfo.Image). Its Duration and
+// Framerate in MediaInfo are synthetic: a still plays for the configured
+// duration, a sequence for one frame per image at the configured rate.
+type ImageSource struct {
+	Kind        ImageKind
+	Frames      int // Images read: 1 for a still, the contiguous run from StartNumber for a sequence
+	StartNumber int // Index of a sequence's first image (ffmpeg's -start_number)
+}
+
+// stillExtensions are the file extensions treated as still images.
+var stillExtensions = []string{".png", ".jpg", ".jpeg", ".webp", ".bmp", ".tif", ".tiff"}
+
+// sequencePattern matches the frame-number placeholder of an image
+// sequence, e.g. %d or %04d.
+var sequencePattern = regexp.MustCompile(`%(0?[1-9]?)d`)
+
+// ImageInputKind reports from its name whether path is a still image or an
+// image sequence pattern. Extensionless images are only recognized once
+// probed (see MediaInfo.Image).
+func ImageInputKind(path string) ImageKind {
+	name := filepath.Base(path)
+	if len(sequencePattern.FindAllStringIndex(name, -1)) == 1 {
+		return ImageSequence
+	}
+	if slices.Contains(stillExtensions, strings.ToLower(filepath.Ext(name))) {
+		return ImageStill
+	}
+	return ImageNone
+}
+
+// isImageFormat reports whether ffprobe's container name is an image
+// demuxer (image2 for files, png_pipe, jpeg_pipe, ... for single images).
+func isImageFormat(format string) bool {
+	return format == "image2" || strings.HasSuffix(format, "_pipe")
+}
+
+// ScanImageSequence finds the images matching a sequence pattern like
+// "frames/shot_%04d.png". Like ffmpeg, it reads the contiguous run of
+// numbers from the lowest one present, so a gap ends the sequence.
+func ScanImageSequence(pattern string) (*ImageSource, error) {
+	dir, name := filepath.Split(pattern)
+	loc := sequencePattern.FindStringSubmatchIndex(name)
+	if loc == nil {
+		return nil, fmt.Errorf("%q is not an image sequence pattern (want e.g. shot_%%04d.png)", pattern)
+	}
+	digits := `\d+`
+	if width := name[loc[2]:loc[3]]; width != "" {
+		n, _ := strconv.Atoi(strings.TrimPrefix(width, "0"))
+		digits = fmt.Sprintf(`\d{%d,}`, n)
+	}
+	re := regexp.MustCompile("^" + regexp.QuoteMeta(name[:loc[0]]) + "(" + digits + ")" + regexp.QuoteMeta(name[loc[1]:]) + "$")
+
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	present := make(map[int]bool)
+	first := -1
+	for _, e := range entries {
+		m := re.FindStringSubmatch(e.Name())
+		if m == nil || e.IsDir() {
+			continue
+		}
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+		present[n] = true
+		if first < 0 || n < first {
+			first = n
+		}
+	}
+	if first < 0 {
+		return nil, fmt.Errorf("no images match %q: %w", pattern, os.ErrNotExist)
+	}
+	frames := 0
+	for present[first+frames] {
+		frames++
+	}
+	return &ImageSource{Kind: ImageSequence, Frames: frames, StartNumber: first}, nil
+}
+
+// imageProbeArgs returns the ffprobe input options reading image, nil for
+// anything but a sequence.
+func imageProbeArgs(image *ImageSource) []string {
+	if image == nil || image.Kind != ImageSequence {
+		return nil
+	}
+	return []string{"-f", "image2", "-start_number", strconv.Itoa(image.StartNumber)}
+}
+
+// applyImageTiming replaces the timing ffprobe reports for an image input
+// with the synthetic duration and framerate the clip will be encoded with.
+// Images have no keyframe cadence worth aligning to, nor a meaningful
+// bitrate.
+func applyImageTiming(info *MediaInfo, image *ImageSource, opts AnalyzeOptions) {
+	framerate := opts.ImageFramerate
+	if framerate <= 0 {
+		framerate = DefaultImageFramerate
+	}
+	info.Image = image
+	info.Framerate = framerate
+	info.Bitrate = 0
+	info.Scan = ScanInfo{Type: ScanProgressive}
+	if image.Kind == ImageSequence {
+		info.Duration = float64(image.Frames) / framerate
+		return
+	}
+	duration := opts.StillDuration
+	if duration <= 0 {
+		duration = DefaultStillDuration
+	}
+	info.Duration = duration.Seconds()
+}
@@ -0,0 +1,161 @@
+package analyzer
+
+import (
+	"bufio"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/errclass"
+)
+
+// Sentinel errors for broken sources. Match with errors.Is.
+var (
+	// ErrMissingMoov means an MP4/MOV has no moov atom, usually because the
+	// upload or recording was interrupted before the index was written.
+	ErrMissingMoov = errclass.New(errclass.InputCorrupt, "moov atom not found (file incomplete or still being written)")
+
+	// ErrCorruptMedia means the integrity scan found decode errors or truncation.
+	ErrCorruptMedia = errclass.New(errclass.InputCorrupt, "media failed integrity scan")
+)
+
+// maxIntegrityErrors caps how many decoder messages are kept in the report.
+const maxIntegrityErrors = 50
+
+// truncationTolerance is how far short of the reported duration decoding may
+// end (in seconds) before the file is considered truncated.
+const truncationTolerance = 1.0
+
+// IntegrityReport is the result of a full decode of the source.
+type IntegrityReport struct {
+	OK              bool     // No decode errors and no truncation
+	ErrorCount      int      // Total decoder error lines
+	Errors          []string // First decoder error lines (capped)
+	Truncated       bool     // Decoding ended well before the container duration
+	DecodedDuration float64  // Seconds of media actually decoded
+}
+
+// Err returns nil for a clean file, or an error wrapping ErrCorruptMedia that
+// summarizes what was found.
+func (r *IntegrityReport) Err() error {
+	if r == nil || r.OK {
+		return nil
+	}
+	var parts []string
+	if r.Truncated {
+		parts = append(parts, fmt.Sprintf("truncated after %.2fs", r.DecodedDuration))
+	}
+	if r.ErrorCount > 0 {
+		msg := fmt.Sprintf("%d decode error(s)", r.ErrorCount)
+		if len(r.Errors) > 0 {
+			msg += fmt.Sprintf(", first: %s", r.Errors[0])
+		}
+		parts = append(parts, msg)
+	}
+	return fmt.Errorf("%w: %s", ErrCorruptMedia, strings.Join(parts, "; "))
+}
+
+// scanIntegrity decodes every video and audio frame (ffmpeg -v error -f null -)
+// and reports decode errors and truncation. Progress is emitted from ffmpeg's
+// -progress output so long files show movement while they are scanned.
+func scanIntegrity(path string, duration float64, logger AnalyzerLogger) (*IntegrityReport, error) {
+	cmd := exec.Command(
+		"ffmpeg",
+		"-hide_banner",
+		"-nostats",
+		"-v", "error",
+		"-progress", "pipe:1",
+		"-i", path,
+		"-map", "0:v?",
+		"-map", "0:a?",
+		"-f", "null", "-",
+	)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, &AnalyzerError{Op: "pipe_ffmpeg_integrity", Path: path, Err: err}
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, &AnalyzerError{Op: "pipe_ffmpeg_integrity", Path: path, Err: err}
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, &AnalyzerError{Op: "start_ffmpeg_integrity", Path: path, Err: err}
+	}
+
+	report := &IntegrityReport{}
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	// Decoder errors arrive on stderr (only errors, thanks to -v error)
+	go func() {
+		defer wg.Done()
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			report.ErrorCount++
+			if len(report.Errors) < maxIntegrityErrors {
+				report.Errors = append(report.Errors, line)
+			}
+		}
+	}()
+
+	// Progress key=value pairs arrive on stdout
+	go func() {
+		defer wg.Done()
+		scanner := bufio.NewScanner(stdout)
+		var lastPercent int
+		for scanner.Scan() {
+			val, ok := strings.CutPrefix(scanner.Text(), "out_time_us=")
+			if !ok {
+				continue
+			}
+			us, err := strconv.ParseInt(val, 10, 64)
+			if err != nil || us <= 0 {
+				continue
+			}
+			report.DecodedDuration = float64(us) / 1e6
+			if duration > 0 {
+				if pct := int(report.DecodedDuration / duration * 100); pct >= lastPercent+5 {
+					lastPercent = pct
+					logger.LogProgress("integrity", float64(pct))
+				}
+			}
+		}
+	}()
+
+	wg.Wait()
+	waitErr := cmd.Wait()
+
+	report.Truncated = duration > 0 && report.DecodedDuration < duration-truncationTolerance
+	report.OK = report.ErrorCount == 0 && !report.Truncated && waitErr == nil
+	if waitErr != nil && report.ErrorCount == 0 {
+		// ffmpeg failed without printing a decoder error (e.g. unreadable input)
+		return nil, &AnalyzerError{Op: "exec_ffmpeg_integrity", Path: path, Err: waitErr}
+	}
+	return report, nil
+}
+
+// classifyProbeFailure maps well-known ffprobe stderr messages onto sentinel errors.
+func classifyProbeFailure(stderr string, err error) error {
+	if strings.Contains(stderr, "moov atom not found") {
+		return fmt.Errorf("%w: %v", ErrMissingMoov, err)
+	}
+	if msg := strings.TrimSpace(stderr); msg != "" {
+		return fmt.Errorf("%v: %s", err, lastLine(msg))
+	}
+	return err
+}
+
+// lastLine returns the final line of s.
+func lastLine(s string) string {
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
@@ -0,0 +1,50 @@
+package analyzer
+
+import "sort"
+
+// Interval is a time range in seconds within the media.
+type Interval struct {
+	Start float64 // Start time in seconds
+	End   float64 // End time in seconds
+}
+
+// Duration returns the interval length in seconds.
+func (i Interval) Duration() float64 {
+	return i.End - i.Start
+}
+
+// Contains reports whether t falls inside the interval.
+func (i Interval) Contains(t float64) bool {
+	return t >= i.Start && t < i.End
+}
+
+// Complement returns the gaps between intervals within [0, duration), dropping
+// gaps shorter than minLength. Input intervals may overlap or be unsorted.
+func Complement(intervals []Interval, duration, minLength float64) []Interval {
+	sorted := append([]Interval(nil), intervals...)
+	sort.Slice(sorted, func(a, b int) bool { return sorted[a].Start < sorted[b].Start })
+
+	var out []Interval
+	cursor := 0.0
+	for _, iv := range sorted {
+		if iv.Start > cursor && iv.Start-cursor >= minLength {
+			out = append(out, Interval{Start: cursor, End: iv.Start})
+		}
+		if iv.End > cursor {
+			cursor = iv.End
+		}
+	}
+	if duration > cursor && duration-cursor >= minLength {
+		out = append(out, Interval{Start: cursor, End: duration})
+	}
+	return out
+}
+
+// closeOpenInterval finishes an interval whose end marker never arrived
+// (e.g. silence running to end of file) at the media duration.
+func closeOpenInterval(intervals []Interval, start float64, open bool, duration float64) []Interval {
+	if open && duration > start {
+		intervals = append(intervals, Interval{Start: start, End: duration})
+	}
+	return intervals
+}
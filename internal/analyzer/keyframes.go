@@ -2,36 +2,116 @@ package analyzer
 
 import (
 	"bufio"
-	"log"
+	"context"
+	"errors"
+	"fmt"
 	"os/exec"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/executil"
+)
+
+// KeyframeMode selects the speed/accuracy trade-off for keyframe extraction.
+type KeyframeMode string
+
+const (
+	// KeyframePackets reads demuxed packet flags without decoding (default).
+	// Orders of magnitude faster than frame decoding and exact for well-formed containers.
+	KeyframePackets KeyframeMode = "packets"
+
+	// KeyframeKeyOnly decodes keyframes only (-skip_frame nokey). Slower than packets
+	// but trusts the decoder instead of container flags.
+	KeyframeKeyOnly KeyframeMode = "keyonly"
+
+	// KeyframeFrames decodes metadata for every frame. Slowest (minutes on long
+	// movies) but most robust for broken or unusual streams.
+	KeyframeFrames KeyframeMode = "frames"
 )
 
+// ParseKeyframeMode converts a mode name into a KeyframeMode. Empty selects KeyframePackets.
+func ParseKeyframeMode(s string) (KeyframeMode, error) {
+	switch KeyframeMode(strings.ToLower(strings.TrimSpace(s))) {
+	case "", KeyframePackets:
+		return KeyframePackets, nil
+	case KeyframeKeyOnly:
+		return KeyframeKeyOnly, nil
+	case KeyframeFrames:
+		return KeyframeFrames, nil
+	}
+	return "", fmt.Errorf("unknown keyframe mode %q (want packets, keyonly, or frames)", s)
+}
+
+// keyframeCommand builds the ffprobe invocation for mode, killed when ctx is done.
+func keyframeCommand(ctx context.Context, path string, mode KeyframeMode) *exec.Cmd {
+	switch mode {
+	case KeyframeFrames:
+		return exec.CommandContext(ctx,
+			"ffprobe",
+			"-v", "error",
+			"-select_streams", "V:0",
+			"-show_entries", "frame=pts_time,key_frame",
+			"-of", "compact",
+			path,
+		)
+	case KeyframeKeyOnly:
+		return exec.CommandContext(ctx,
+			"ffprobe",
+			"-v", "error",
+			"-skip_frame", "nokey",
+			"-select_streams", "V:0",
+			"-show_entries", "frame=pts_time,key_frame",
+			"-of", "compact",
+			path,
+		)
+	default:
+		return exec.CommandContext(ctx,
+			"ffprobe",
+			"-v", "error",
+			"-select_streams", "V:0",
+			"-show_entries", "packet=pts_time,flags",
+			"-of", "compact",
+			path,
+		)
+	}
+}
+
 // extractKeyframes streams ffprobe output to identify keyframes in real time.
-// It parses frame-level metadata from compact output, filters for keyframes,
-// and calculates the average interval between them. Progress is emitted via the AnalyzerLogger.
+// Depending on mode it parses packet flags (fast), keyframe-only decode output,
+// or full frame-level metadata, then calculates the average interval between keyframes.
+// Progress is emitted via the AnalyzerLogger.
 //
 // This version avoids buffering delays by reading ffprobe output line-by-line,
 // uses actual duration and framerate to estimate total frames, and throttles progress
-// updates based on frame count to avoid flooding the terminal. It also logs every keyframe
-// detection attempt and exposes silent failures in timestamp parsing.
-func extractKeyframes(path string, duration, framerate float64, logger AnalyzerLogger) ([]float64, float64, error) {
-	logger.LogStage("keyframes", "Streaming ffprobe frame metadata")
-
-	cmd := exec.Command(
-		"ffprobe",
-		"-v", "error",
-		"-select_streams", "v",
-		"-show_entries", "frame=pts_time,key_frame",
-		"-of", "compact",
-		path,
-	)
+// updates based on line count to avoid flooding the terminal. It also exposes silent
+// failures in timestamp parsing. Packets arrive in decode order, so timestamps are
+// sorted before the interval is computed.
+//
+// Output is consumed as it arrives, so ffprobe blocks on the pipe rather than
+// buffering ahead. Extraction stops early, killing ffprobe, once a keyframe past
+// opts.KeyframeWindow is seen or opts.KeyframeTimeout elapses; the keyframes
+// sampled so far are returned with partial set. Cancelling ctx aborts with an error.
+func extractKeyframes(ctx context.Context, path string, duration, framerate float64, opts AnalyzeOptions, logger AnalyzerLogger) (keyframes []float64, interval float64, partial bool, err error) {
+	mode := opts.KeyframeMode
+	if mode == "" {
+		mode = KeyframePackets
+	}
+	logger.LogStage("keyframes", fmt.Sprintf("Streaming ffprobe %s metadata", mode))
+
+	probeCtx, cancel := context.WithCancel(ctx)
+	if opts.KeyframeTimeout > 0 {
+		probeCtx, cancel = context.WithTimeout(ctx, opts.KeyframeTimeout)
+	}
+	defer cancel()
+	cmd := keyframeCommand(probeCtx, path, mode)
+	window := opts.KeyframeWindow.Seconds()
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		logger.LogError("keyframes", err)
-		return nil, 0, &AnalyzerError{
+		return nil, 0, false, &AnalyzerError{
 			Op:   "pipe_ffprobe_keyframes",
 			Path: path,
 			Err:  err,
@@ -40,7 +120,7 @@ func extractKeyframes(path string, duration, framerate float64, logger AnalyzerL
 
 	if err := cmd.Start(); err != nil {
 		logger.LogError("keyframes", err)
-		return nil, 0, &AnalyzerError{
+		return nil, 0, false, &AnalyzerError{
 			Op:   "start_ffprobe_keyframes",
 			Path: path,
 			Err:  err,
@@ -51,28 +131,33 @@ func extractKeyframes(path string, duration, framerate float64, logger AnalyzerL
 	var timestamps []float64
 	var frameCount int
 
-	// Estimate total frames using duration × framerate
+	// Estimate total frames using duration × framerate; keyframe-only output
+	// has no per-frame lines, so progress there follows keyframe timestamps instead
 	estimatedTotalFrames := int(duration * framerate)
-	log.Printf("Estimated total frames : %d, by using duration %d and framerate %d", estimatedTotalFrames, int(duration), int(framerate))
+	logger.LogStage("keyframes", fmt.Sprintf("Estimated total frames: %d (duration %.0fs × %.3f fps)", estimatedTotalFrames, duration, framerate))
 	const emitEveryNFrames = 5000 // Throttle progress updates
 
 	// Stream and parse compact frame lines
-	for {
+	var stopped bool
+	for !stopped {
 		line, err := reader.ReadString('\n')
 		if err != nil {
 			break // EOF or pipe closed
 		}
 
-		frameCount++ // ✅ Count every frame
+		frameCount++ // ✅ Count every frame (or packet)
 
 		// Parse keyframe flag and timestamp
 		var isKeyframe bool
 		var ts *float64
 
-		for part := range strings.SplitSeq(line, "|") {
+		for part := range strings.SplitSeq(strings.TrimSpace(line), "|") {
 			if part == "key_frame=1" {
 				isKeyframe = true
 			}
+			if flags, ok := strings.CutPrefix(part, "flags="); ok && strings.Contains(flags, "K") {
+				isKeyframe = true
+			}
 
 			if val, ok := strings.CutPrefix(part, "pts_time="); ok {
 				val = strings.Trim(val, "|\n\r ")
@@ -80,7 +165,7 @@ func extractKeyframes(path string, duration, framerate float64, logger AnalyzerL
 				if err == nil {
 					ts = &parsed
 				} else {
-					log.Printf("⚠️ Failed to parse pts_time '%s' in line: %s", val, strings.TrimSpace(line))
+					logger.LogStage("keyframes", fmt.Sprintf("⚠️ Failed to parse pts_time '%s' in line: %s", val, strings.TrimSpace(line)))
 				}
 			}
 		}
@@ -88,38 +173,66 @@ func extractKeyframes(path string, duration, framerate float64, logger AnalyzerL
 		if isKeyframe {
 			if ts != nil {
 				timestamps = append(timestamps, *ts)
+				stopped = window > 0 && *ts > window
 			} else {
-				log.Printf("⚠️ Keyframe detected but missing pts_time: %s", strings.TrimSpace(line))
+				logger.LogStage("keyframes", fmt.Sprintf("⚠️ Keyframe detected but missing pts_time: %s", strings.TrimSpace(line)))
 			}
 		}
 
 		// Emit progress every N frames
-		if frameCount%emitEveryNFrames == 0 && estimatedTotalFrames > 0 {
+		if mode == KeyframeKeyOnly {
+			if isKeyframe && ts != nil && duration > 0 && len(timestamps)%100 == 0 {
+				logger.LogProgress("keyframes", *ts/duration*100)
+			}
+		} else if frameCount%emitEveryNFrames == 0 && estimatedTotalFrames > 0 {
 			percent := float64(frameCount) / float64(estimatedTotalFrames) * 100
 			logger.LogProgress("keyframes", percent)
 		}
 	}
 
-	if err := cmd.Wait(); err != nil {
+	if stopped {
+		cancel() // Enough sampled; don't let ffprobe read the rest of the file
+	}
+	err = cmd.Wait()
+	executil.RecordUsage(ctx, cmd.ProcessState)
+	switch {
+	case ctx.Err() != nil:
+		return nil, 0, false, &AnalyzerError{
+			Op:   "wait_ffprobe_keyframes",
+			Path: path,
+			Err:  context.Cause(ctx),
+		}
+	case stopped:
+		partial = true
+		logger.LogStage("keyframes", fmt.Sprintf("⏩ Sampled keyframes from the first %s of media", opts.KeyframeWindow))
+	case errors.Is(probeCtx.Err(), context.DeadlineExceeded):
+		partial = true
+		covered := 0.0
+		if len(timestamps) > 0 {
+			covered = slices.Max(timestamps)
+		}
+		logger.LogStage("keyframes", fmt.Sprintf("⏱️ Keyframe deadline of %s reached after %.0fs of media", opts.KeyframeTimeout, covered))
+	case err != nil:
 		logger.LogError("keyframes", err)
-		return nil, 0, &AnalyzerError{
+		return nil, 0, false, &AnalyzerError{
 			Op:   "wait_ffprobe_keyframes",
 			Path: path,
 			Err:  err,
 		}
 	}
 
-	log.Printf("🧮 Parsed %d frames, found %d keyframes", frameCount, len(timestamps))
+	logger.LogStage("keyframes", fmt.Sprintf("🧮 Parsed %d entries, found %d keyframes", frameCount, len(timestamps)))
+	sort.Float64s(timestamps)
 
 	// Fallback if too few keyframes found
-	if frameCount > 5000 && len(timestamps) < 2 {
+	if mode != KeyframeKeyOnly && frameCount > 5000 && len(timestamps) < 2 {
 		logger.LogStage("keyframes", "⚠️ Parsed over 5000 frames but found less than 2 keyframes — skipping interval calculation")
-		return timestamps, 0, nil
+		return timestamps, 0, partial, nil
 	}
 
 	if len(timestamps) < 2 {
 		logger.LogStage("keyframes", "Not enough keyframes found to calculate interval")
-		return timestamps, 0, nil
+		return timestamps, 0, partial, nil
 	}
 
 	// Calculate average interval between keyframes
@@ -130,5 +243,5 @@ func extractKeyframes(path string, duration, framerate float64, logger AnalyzerL
 	avgInterval := total / float64(len(timestamps)-1)
 
 	logger.LogStage("keyframes", "✅ Keyframe extraction complete")
-	return timestamps, avgInterval, nil
+	return timestamps, avgInterval, partial, nil
 }
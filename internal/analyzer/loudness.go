@@ -0,0 +1,131 @@
+package analyzer
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+)
+
+// Silence detection thresholds.
+const (
+	silenceNoiseDB     = -50.0 // Level below which audio counts as silent (dBFS)
+	silenceMinDuration = 2.0   // Minimum silent stretch reported (seconds)
+)
+
+// LoudnessInfo holds EBU R128 loudness measurements and silent intervals for
+// the primary audio track.
+type LoudnessInfo struct {
+	StreamIndex    int        // Container index of the measured audio stream
+	IntegratedLUFS float64    // Integrated loudness (LUFS)
+	LoudnessRange  float64    // Loudness range (LU)
+	TruePeak       float64    // Maximum true peak (dBTP)
+	Silences       []Interval // Stretches quieter than -50 dBFS for at least 2s
+}
+
+// NonSilent returns the audible stretches of at least minLength seconds,
+// useful for picking preview or trailer sections.
+func (l *LoudnessInfo) NonSilent(duration, minLength float64) []Interval {
+	if l == nil {
+		return Complement(nil, duration, minLength)
+	}
+	return Complement(l.Silences, duration, minLength)
+}
+
+// SilentFraction returns the share of duration that is silent (0..1).
+func (l *LoudnessInfo) SilentFraction(duration float64) float64 {
+	if l == nil || duration <= 0 {
+		return 0
+	}
+	var silent float64
+	for _, s := range l.Silences {
+		silent += s.Duration()
+	}
+	return silent / duration
+}
+
+var (
+	ebuIntegrated   = regexp.MustCompile(`(?s)Integrated loudness:\s*I:\s*(-?[\d.]+|-inf)\s*LUFS`)
+	ebuRange        = regexp.MustCompile(`(?s)Loudness range:\s*LRA:\s*(-?[\d.]+)\s*LU`)
+	ebuTruePeak     = regexp.MustCompile(`(?s)True peak:\s*Peak:\s*(-?[\d.]+|-inf)\s*dBFS`)
+	silenceStartRe  = regexp.MustCompile(`silence_start:\s*(-?[\d.]+)`)
+	silenceEndRe    = regexp.MustCompile(`silence_end:\s*(-?[\d.]+)`)
+	silenceMarkerRe = regexp.MustCompile(`silence_(?:start|end):\s*-?[\d.]+`)
+)
+
+// measureLoudness decodes one audio stream through ebur128 and silencedetect in
+// a single pass. The whole track is decoded, so this scales with duration.
+func measureLoudness(path string, stream StreamInfo, duration float64) (*LoudnessInfo, error) {
+	filter := fmt.Sprintf("ebur128=peak=true,silencedetect=noise=%gdB:d=%g", silenceNoiseDB, silenceMinDuration)
+	cmd := exec.Command(
+		"ffmpeg",
+		"-hide_banner",
+		"-nostats",
+		"-i", path,
+		"-map", fmt.Sprintf("0:%d", stream.Index),
+		"-af", filter,
+		"-f", "null", "-",
+	)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, &AnalyzerError{Op: "exec_ffmpeg_loudness", Path: path, Err: err}
+	}
+
+	info, err := parseLoudness(stderr.String(), duration)
+	if err != nil {
+		return nil, &AnalyzerError{Op: "parse_loudness", Path: path, Err: err}
+	}
+	info.StreamIndex = stream.Index
+	return info, nil
+}
+
+// parseLoudness extracts the ebur128 summary and silencedetect markers from
+// ffmpeg's stderr. Silence still open at end of file is closed at duration.
+func parseLoudness(output string, duration float64) (*LoudnessInfo, error) {
+	info := &LoudnessInfo{}
+
+	m := ebuIntegrated.FindStringSubmatch(output)
+	if m == nil {
+		return nil, fmt.Errorf("ebur128 summary not found")
+	}
+	info.IntegratedLUFS = parseLevel(m[1])
+	if m := ebuRange.FindStringSubmatch(output); m != nil {
+		info.LoudnessRange, _ = strconv.ParseFloat(m[1], 64)
+	}
+	if m := ebuTruePeak.FindStringSubmatch(output); m != nil {
+		info.TruePeak = parseLevel(m[1])
+	}
+
+	var start float64
+	var open bool
+	for _, marker := range silenceMarkerRe.FindAllString(output, -1) {
+		if s := silenceStartRe.FindStringSubmatch(marker); s != nil {
+			start, _ = strconv.ParseFloat(s[1], 64)
+			if start < 0 {
+				start = 0
+			}
+			open = true
+			continue
+		}
+		if e := silenceEndRe.FindStringSubmatch(marker); e != nil && open {
+			end, _ := strconv.ParseFloat(e[1], 64)
+			info.Silences = append(info.Silences, Interval{Start: start, End: end})
+			open = false
+		}
+	}
+	info.Silences = closeOpenInterval(info.Silences, start, open, duration)
+	return info, nil
+}
+
+// parseLevel parses a dB/LUFS value, mapping "-inf" (digital silence) to -144,
+// the floor of 24-bit audio, so the value stays JSON-encodable.
+func parseLevel(s string) float64 {
+	if s == "-inf" {
+		return -144
+	}
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
@@ -3,14 +3,204 @@ package analyzer
 // MediaInfo holds all extracted metadata about a media file.
 // This struct is the foundation for resolution scaling, segment alignment,
 // codec decisions, and adaptive streaming logic.
+//
+// The top-level video/audio fields describe the primary streams; Streams
+// enumerates every stream so audio and subtitle decisions can consider all tracks.
 type MediaInfo struct {
-	Width            int       // Video width in pixels
-	Height           int       // Video height in pixels
-	Duration         float64   // Total duration in seconds
-	AudioCodec       string    // Audio codec used (e.g. "aac")
-	VideoCodec       string    // Video codec used (e.g. "h264")
-	Bitrate          int       // Overall bitrate in kbps
-	Framerate        float64   // Frames per second (parsed from r_frame_rate)
-	KeyframeInterval float64   // Average seconds between keyframes
-	Keyframes        []float64 // Timestamps of keyframes in seconds
+	Width            int                    // Video width in pixels
+	Height           int                    // Video height in pixels
+	Duration         float64                // Total duration in seconds
+	AudioCodec       string                 // Audio codec used (e.g. "aac")
+	VideoCodec       string                 // Video codec used (e.g. "h264")
+	Bitrate          int                    // Overall bitrate in kbps
+	Framerate        float64                // Frames per second (parsed from r_frame_rate)
+	KeyframeInterval float64                // Average seconds between keyframes
+	Keyframes        []float64              // Timestamps of keyframes in seconds
+	KeyframesPartial bool                   // Keyframes cover only the start of the file (see AnalyzeOptions.KeyframeWindow)
+	Container        string                 // Container format name as reported by ffprobe (e.g. "mov,mp4,m4a,3gp,3g2,mj2")
+	Tags             map[string]string      // Container tags (e.g. "title", "language")
+	PixelFormat      string                 // Primary video pixel format (e.g. "yuv420p10le")
+	BitDepth         int                    // Primary video bit depth (e.g. 8, 10)
+	Color            ColorInfo              // Primary video color metadata
+	Scan             ScanInfo               // Primary video scan type (progressive/interlaced/telecine)
+	Streams          []StreamInfo           // Every stream in container order
+	Integrity        *IntegrityReport       // Full-decode scan result; nil unless DeepScan was requested
+	Loudness         *LoudnessInfo          // Primary audio loudness and silence; nil unless Loudness was requested
+	BlackIntervals   []Interval             // Black stretches of the primary video (BlackFreeze only)
+	FreezeIntervals  []Interval             // Frozen stretches of the primary video (BlackFreeze only)
+	Perceptual       *PerceptualFingerprint // Visual signature for duplicate detection; nil unless Fingerprint was requested
+	Image            *ImageSource           // Still image or image sequence; Duration and Framerate are then synthetic. nil for video
+}
+
+// ColorInfo describes video color metadata. Empty fields mean unspecified.
+type ColorInfo struct {
+	Range     string // "tv" (limited) or "pc" (full)
+	Space     string // Matrix coefficients (e.g. "bt709", "bt2020nc")
+	Transfer  string // Transfer characteristics (e.g. "bt709", "smpte2084", "arib-std-b67")
+	Primaries string // Color primaries (e.g. "bt709", "bt2020")
+}
+
+// IsHDR reports whether the transfer function is PQ (HDR10) or HLG.
+func (c ColorInfo) IsHDR() bool {
+	return c.Transfer == "smpte2084" || c.Transfer == "arib-std-b67"
+}
+
+// Stream types reported in StreamInfo.Type.
+const (
+	StreamVideo      = "video"
+	StreamAudio      = "audio"
+	StreamSubtitle   = "subtitle"
+	StreamData       = "data"
+	StreamAttachment = "attachment"
+)
+
+// StreamInfo describes a single stream in the container.
+// Fields that don't apply to the stream type are left zero.
+type StreamInfo struct {
+	Index    int               // Stream index within the container
+	Type     string            // StreamVideo, StreamAudio, StreamSubtitle, StreamData, StreamAttachment
+	Codec    string            // Codec name (e.g. "h264", "eac3", "subrip")
+	Profile  string            // Codec profile (e.g. "High", "LC")
+	Bitrate  int               // Stream bitrate in kbps, if reported
+	Language string            // RFC 5646 language tag normalized from the container's (e.g. "en" for "eng"), "" if untagged
+	Title    string            // Stream title tag, if present
+	Default  bool              // Default disposition flag
+	Forced   bool              // Forced disposition flag (e.g. forced-narrative subtitles)
+	Tags     map[string]string // All stream tags
+
+	// Video
+	Width       int       // Width in pixels
+	Height      int       // Height in pixels
+	Framerate   float64   // Frames per second
+	PixelFormat string    // Pixel format (e.g. "yuv420p")
+	BitDepth    int       // Bits per component
+	Color       ColorInfo // Color metadata
+	AttachedPic bool      // Cover art rather than a real video track
+	FieldOrder  string    // Container field order ("progressive", "tt", "bb", "tb", "bt", "unknown")
+
+	// Audio
+	Channels      int    // Channel count
+	ChannelLayout string // Channel layout (e.g. "stereo", "5.1(side)")
+	SampleRate    int    // Sample rate in Hz
+}
+
+// textSubtitleCodecs are the subtitle codecs carried as text, which ffmpeg can
+// convert between formats and render with the subtitles filter. Others (PGS,
+// VobSub, DVB) are bitmaps.
+var textSubtitleCodecs = map[string]bool{
+	"subrip":   true,
+	"srt":      true,
+	"ass":      true,
+	"ssa":      true,
+	"mov_text": true,
+	"webvtt":   true,
+	"text":     true,
+	"ttml":     true,
+}
+
+// IsTextSubtitle reports whether s is a subtitle stream carried as text
+// rather than bitmaps.
+func (s StreamInfo) IsTextSubtitle() bool {
+	return s.Type == StreamSubtitle && textSubtitleCodecs[s.Codec]
+}
+
+// StreamsOfType returns the streams of the given type in container order.
+func (m *MediaInfo) StreamsOfType(streamType string) []StreamInfo {
+	var out []StreamInfo
+	for _, s := range m.Streams {
+		if s.Type == streamType {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// AudioTracks returns every audio stream.
+func (m *MediaInfo) AudioTracks() []StreamInfo {
+	return m.StreamsOfType(StreamAudio)
+}
+
+// SubtitleTracks returns every subtitle stream.
+func (m *MediaInfo) SubtitleTracks() []StreamInfo {
+	return m.StreamsOfType(StreamSubtitle)
+}
+
+// ForcedSubtitles returns the subtitle streams flagged forced: translations of
+// foreign-language dialogue meant to show even with subtitles turned off.
+func (m *MediaInfo) ForcedSubtitles() []StreamInfo {
+	var out []StreamInfo
+	for _, s := range m.SubtitleTracks() {
+		if s.Forced {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// PrimaryForcedSubtitle returns the forced subtitle matching the primary
+// audio's language, else the first forced subtitle. Returns nil if there is none.
+func (m *MediaInfo) PrimaryForcedSubtitle() *StreamInfo {
+	forced := m.ForcedSubtitles()
+	if len(forced) == 0 {
+		return nil
+	}
+	if audio := m.PrimaryAudio(); audio != nil && audio.Language != "" {
+		for i := range forced {
+			if forced[i].Language == audio.Language {
+				return &forced[i]
+			}
+		}
+	}
+	return &forced[0]
+}
+
+// SubtitlePosition returns the position of stream index among the subtitle
+// streams (the N in ffmpeg's 0:s:N), or -1 if it isn't a subtitle stream.
+func (m *MediaInfo) SubtitlePosition(index int) int {
+	for i, s := range m.SubtitleTracks() {
+		if s.Index == index {
+			return i
+		}
+	}
+	return -1
+}
+
+// DataStreams returns every data stream (e.g. timecode, chapters metadata).
+func (m *MediaInfo) DataStreams() []StreamInfo {
+	return m.StreamsOfType(StreamData)
+}
+
+// PrimaryAudio returns the main audio track: the track flagged default, else the
+// first audio track. Returns nil if there is no audio.
+func (m *MediaInfo) PrimaryAudio() *StreamInfo {
+	var first *StreamInfo
+	for i := range m.Streams {
+		s := &m.Streams[i]
+		if s.Type != StreamAudio {
+			continue
+		}
+		if s.Default {
+			return s
+		}
+		if first == nil {
+			first = s
+		}
+	}
+	return first
+}
+
+// VideoBitrate returns the primary video bitrate in kbps: the first real video
+// stream's reported bitrate, else the overall bitrate less the reported audio
+// bitrates. Returns 0 when neither is known.
+func (m *MediaInfo) VideoBitrate() int {
+	for _, s := range m.Streams {
+		if s.Type == StreamVideo && !s.AttachedPic && s.Bitrate > 0 {
+			return s.Bitrate
+		}
+	}
+	kbps := m.Bitrate
+	for _, s := range m.AudioTracks() {
+		kbps -= s.Bitrate
+	}
+	return max(kbps, 0)
 }
@@ -0,0 +1,90 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"os/exec"
+)
+
+// OutputProbe holds container-level measurements of an encoded output file.
+type OutputProbe struct {
+	Duration float64 // Duration in seconds
+	Bitrate  int     // Measured average bitrate in kbps
+	Size     int64   // File size in bytes
+	Width    int     // First video stream width in pixels (0 if no video)
+	Height   int     // First video stream height in pixels (0 if no video)
+
+	VideoCodec   string  // First video stream codec (e.g. "hevc")
+	VideoProfile string  // First video stream profile (e.g. "Main 10")
+	VideoLevel   int     // First video stream level as reported by ffprobe (e.g. 40 for h264 4.0, 120 for hevc 4.0)
+	PixelFormat  string  // First video stream pixel format (e.g. "yuv420p")
+	Framerate    float64 // First video stream frame rate (parsed from r_frame_rate)
+	AudioCodec   string  // First audio stream codec (e.g. "aac")
+	AudioProfile string  // First audio stream profile (e.g. "LC", "HE-AAC")
+	SampleRate   int     // First audio stream sample rate in Hz
+	Channels     int     // First audio stream channel count
+}
+
+// ProbeOutput runs a lightweight ffprobe (format plus stream codecs and dimensions) on an
+// encoded file to measure what the encoder actually produced.
+func ProbeOutput(path string) (*OutputProbe, error) {
+	cmd := exec.Command(
+		"ffprobe",
+		"-v", "error",
+		"-print_format", "json",
+		"-show_entries", "format=duration,bit_rate,size:stream=codec_type,codec_name,profile,level,pix_fmt,width,height,r_frame_rate,sample_rate,channels",
+		path,
+	)
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, &AnalyzerError{Op: "exec_ffprobe_output", Path: path, Err: classifyProbeFailure(stderr.String(), err)}
+	}
+
+	var probe struct {
+		Streams []struct {
+			CodecType string `json:"codec_type"`
+			CodecName string `json:"codec_name"`
+			Profile   string `json:"profile"`
+			Level     int    `json:"level"`
+			PixFmt    string `json:"pix_fmt"`
+			Width     int    `json:"width"`
+			Height    int    `json:"height"`
+			Rate      string `json:"r_frame_rate"`
+			Sample    string `json:"sample_rate"`
+			Channels  int    `json:"channels"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+			BitRate  string `json:"bit_rate"`
+			Size     string `json:"size"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &probe); err != nil {
+		return nil, &AnalyzerError{Op: "unmarshal_ffprobe_output", Path: path, Err: err}
+	}
+
+	result := &OutputProbe{}
+	result.Duration, _ = parseFloat(probe.Format.Duration)
+	if br, err := parseInt(probe.Format.BitRate); err == nil {
+		result.Bitrate = br / 1000
+	}
+	if size, err := parseInt(probe.Format.Size); err == nil {
+		result.Size = int64(size)
+	}
+	for _, s := range probe.Streams {
+		switch {
+		case s.CodecType == StreamVideo && result.VideoCodec == "":
+			result.Width, result.Height = s.Width, s.Height
+			result.VideoCodec, result.VideoProfile, result.VideoLevel = s.CodecName, s.Profile, s.Level
+			result.PixelFormat = s.PixFmt
+			result.Framerate, _ = parseRatio(s.Rate)
+		case s.CodecType == StreamAudio && result.AudioCodec == "":
+			result.AudioCodec, result.AudioProfile = s.CodecName, s.Profile
+			result.SampleRate, _ = parseInt(s.Sample)
+			result.Channels = s.Channels
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,166 @@
+package analyzer
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+)
+
+// Scan types reported in ScanInfo.Type.
+const (
+	ScanUnknown     = "unknown"
+	ScanProgressive = "progressive"
+	ScanInterlaced  = "interlaced"
+	ScanTelecine    = "telecine" // Progressive film carried in interlaced fields (3:2 pulldown)
+)
+
+// Field orders reported in ScanInfo.FieldOrder.
+const (
+	FieldTFF = "tff" // Top field first
+	FieldBFF = "bff" // Bottom field first
+)
+
+// scanSampleFrames is how many frames idet inspects.
+const scanSampleFrames = 1000
+
+// ScanInfo describes how the primary video is scanned. Type and FieldOrder come
+// from container flags and, when idet detection ran, from decoded field analysis.
+type ScanInfo struct {
+	Type       string // ScanProgressive, ScanInterlaced, ScanTelecine, or ScanUnknown
+	FieldOrder string // FieldTFF or FieldBFF for interlaced content, "" otherwise
+	Detected   bool   // True when idet analysis confirmed the type
+
+	// idet multi-frame counts (only set when Detected)
+	TFF, BFF, Progressive, Undetermined int
+	RepeatedFields                      float64 // Fraction of frames with a repeated field (pulldown indicator)
+}
+
+// NeedsDeinterlace reports whether the video should be deinterlaced or inverse-telecined.
+func (s ScanInfo) NeedsDeinterlace() bool {
+	return s.Type == ScanInterlaced || s.Type == ScanTelecine
+}
+
+// Summary renders a short description (e.g. "interlaced (tff, detected)").
+func (s ScanInfo) Summary() string {
+	out := s.Type
+	if out == "" {
+		out = ScanUnknown
+	}
+	if s.FieldOrder != "" {
+		out += " (" + s.FieldOrder
+		if s.Detected {
+			out += ", detected"
+		}
+		return out + ")"
+	}
+	if s.Detected {
+		out += " (detected)"
+	}
+	return out
+}
+
+// scanFromFieldOrder maps ffprobe's field_order onto ScanInfo.
+func scanFromFieldOrder(order string) ScanInfo {
+	switch order {
+	case "progressive":
+		return ScanInfo{Type: ScanProgressive}
+	case "tt", "tb":
+		return ScanInfo{Type: ScanInterlaced, FieldOrder: FieldTFF}
+	case "bb", "bt":
+		return ScanInfo{Type: ScanInterlaced, FieldOrder: FieldBFF}
+	}
+	return ScanInfo{Type: ScanUnknown}
+}
+
+// mergeScan prefers detected results over container flags, but keeps the
+// container field order when detection couldn't decide one.
+func mergeScan(flags, detected ScanInfo) ScanInfo {
+	if detected.Type == ScanUnknown {
+		flags.TFF, flags.BFF = detected.TFF, detected.BFF
+		flags.Progressive, flags.Undetermined = detected.Progressive, detected.Undetermined
+		flags.RepeatedFields = detected.RepeatedFields
+		return flags
+	}
+	if detected.FieldOrder == "" && detected.Type == ScanInterlaced {
+		detected.FieldOrder = flags.FieldOrder
+	}
+	return detected
+}
+
+var (
+	idetMulti    = regexp.MustCompile(`Multi frame detection:\s*TFF:\s*(\d+)\s*BFF:\s*(\d+)\s*Progressive:\s*(\d+)\s*Undetermined:\s*(\d+)`)
+	idetRepeated = regexp.MustCompile(`Repeated Fields:\s*Neither:\s*(\d+)\s*Top:\s*(\d+)\s*Bottom:\s*(\d+)`)
+)
+
+// detectScan decodes a sample of the video through ffmpeg's idet filter and
+// classifies it as progressive, interlaced, or telecined. Sampling starts 10%
+// into long files to skip black leaders and studio logos.
+func detectScan(path string, duration float64) (ScanInfo, error) {
+	args := []string{"-hide_banner", "-nostats"}
+	if duration > 60 {
+		args = append(args, "-ss", fmt.Sprintf("%.2f", duration*0.1))
+	}
+	args = append(args,
+		"-i", path,
+		"-map", "0:V:0",
+		"-vf", "idet",
+		"-frames:v", strconv.Itoa(scanSampleFrames),
+		"-an", "-sn", "-dn",
+		"-f", "null", "-",
+	)
+	cmd := exec.Command("ffmpeg", args...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return ScanInfo{}, &AnalyzerError{Op: "exec_ffmpeg_idet", Path: path, Err: err}
+	}
+	return parseIdet(stderr.String()), nil
+}
+
+// parseIdet classifies idet's summary output. Interlacing requires more than a
+// quarter of decided frames to be TFF/BFF; telecine is progressive content where
+// over 15% of frames repeat a field.
+func parseIdet(output string) ScanInfo {
+	scan := ScanInfo{Type: ScanUnknown, Detected: true}
+
+	m := idetMulti.FindStringSubmatch(output)
+	if m == nil {
+		scan.Detected = false
+		return scan
+	}
+	scan.TFF, _ = strconv.Atoi(m[1])
+	scan.BFF, _ = strconv.Atoi(m[2])
+	scan.Progressive, _ = strconv.Atoi(m[3])
+	scan.Undetermined, _ = strconv.Atoi(m[4])
+
+	if r := idetRepeated.FindStringSubmatch(output); r != nil {
+		neither, _ := strconv.Atoi(r[1])
+		top, _ := strconv.Atoi(r[2])
+		bottom, _ := strconv.Atoi(r[3])
+		if total := neither + top + bottom; total > 0 {
+			scan.RepeatedFields = float64(top+bottom) / float64(total)
+		}
+	}
+
+	decided := scan.TFF + scan.BFF + scan.Progressive
+	if decided == 0 {
+		return scan
+	}
+	interlaced := scan.TFF + scan.BFF
+	switch {
+	case float64(interlaced)/float64(decided) > 0.25:
+		scan.Type = ScanInterlaced
+		scan.FieldOrder = FieldTFF
+		if scan.BFF > scan.TFF {
+			scan.FieldOrder = FieldBFF
+		}
+	case scan.RepeatedFields > 0.15:
+		scan.Type = ScanTelecine
+	default:
+		scan.Type = ScanProgressive
+	}
+	return scan
+}
@@ -0,0 +1,139 @@
+package analyzer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/executil"
+)
+
+// SegmentProbe holds what ffprobe reports for a single media segment.
+type SegmentProbe struct {
+	VideoCodec string  // First video stream codec (e.g. "h264")
+	Width      int     // Video width in pixels
+	Height     int     // Video height in pixels
+	StartTime  float64 // Timestamp of the first video packet in seconds
+	EndTime    float64 // Timestamp just past the last video packet in seconds
+	Packets    int     // Video packets in the segment
+	Backwards  int     // Video packets whose DTS did not increase over the previous packet
+}
+
+// Duration returns the span covered by the segment's video packets.
+func (p *SegmentProbe) Duration() float64 {
+	return p.EndTime - p.StartTime
+}
+
+// ProbeStartTime returns the start time in seconds of the first video stream
+// of path, a segment or an HLS media playlist (whose segments keep their
+// muxed timestamps, e.g. the 1.4 s offset ffmpeg gives MPEG-TS).
+func ProbeStartTime(ctx context.Context, path string) (float64, error) {
+	cmd := exec.CommandContext(ctx,
+		"ffprobe",
+		"-v", "error",
+		"-print_format", "json",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=start_time",
+		path,
+	)
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	executil.RecordUsage(ctx, cmd.ProcessState)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = context.Cause(ctx)
+		} else {
+			err = classifyProbeFailure(stderr.String(), err)
+		}
+		return 0, &AnalyzerError{Op: "exec_ffprobe_start", Path: path, Err: err}
+	}
+
+	var probe struct {
+		Streams []struct {
+			StartTime string `json:"start_time"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &probe); err != nil {
+		return 0, &AnalyzerError{Op: "unmarshal_ffprobe_start", Path: path, Err: err}
+	}
+	if len(probe.Streams) == 0 {
+		return 0, &AnalyzerError{Op: "probe_start", Path: path, Err: fmt.Errorf("no video stream")}
+	}
+	start, err := parseFloat(probe.Streams[0].StartTime)
+	if err != nil {
+		return 0, &AnalyzerError{Op: "parse_start_time", Path: path, Err: err}
+	}
+	return start, nil
+}
+
+// ProbeSegment reads the video stream parameters and packet timestamps of a
+// segment. fMP4 segments must be prefixed with their init segment first.
+// Cancelling ctx kills ffprobe.
+func ProbeSegment(ctx context.Context, path string) (*SegmentProbe, error) {
+	cmd := exec.CommandContext(ctx,
+		"ffprobe",
+		"-v", "error",
+		"-print_format", "json",
+		"-select_streams", "v:0",
+		"-show_entries", "stream=codec_name,width,height:packet=dts_time,duration_time",
+		path,
+	)
+	var out, stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	executil.RecordUsage(ctx, cmd.ProcessState)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = context.Cause(ctx)
+		} else {
+			err = classifyProbeFailure(stderr.String(), err)
+		}
+		return nil, &AnalyzerError{Op: "exec_ffprobe_segment", Path: path, Err: err}
+	}
+
+	var probe struct {
+		Streams []struct {
+			CodecName string `json:"codec_name"`
+			Width     int    `json:"width"`
+			Height    int    `json:"height"`
+		} `json:"streams"`
+		Packets []struct {
+			DTSTime      string `json:"dts_time"`
+			DurationTime string `json:"duration_time"`
+		} `json:"packets"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &probe); err != nil {
+		return nil, &AnalyzerError{Op: "unmarshal_ffprobe_segment", Path: path, Err: err}
+	}
+
+	result := &SegmentProbe{}
+	if len(probe.Streams) > 0 {
+		s := probe.Streams[0]
+		result.VideoCodec, result.Width, result.Height = s.CodecName, s.Width, s.Height
+	}
+	prev, first := 0.0, true
+	for _, pkt := range probe.Packets {
+		dts, err := parseFloat(pkt.DTSTime)
+		if err != nil {
+			continue
+		}
+		dur, _ := parseFloat(pkt.DurationTime)
+		if first {
+			result.StartTime = dts
+			first = false
+		} else if dts <= prev {
+			result.Backwards++
+		}
+		if end := dts + dur; end > result.EndTime {
+			result.EndTime = end
+		}
+		prev = dts
+		result.Packets++
+	}
+	return result, nil
+}
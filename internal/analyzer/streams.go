@@ -0,0 +1,105 @@
+package analyzer
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/langtag"
+)
+
+// buildStreamInventory converts raw ffprobe streams into StreamInfo entries.
+func buildStreamInventory(streams []ffprobeStream) []StreamInfo {
+	out := make([]StreamInfo, 0, len(streams))
+	for _, s := range streams {
+		out = append(out, toStreamInfo(s))
+	}
+	return out
+}
+
+// toStreamInfo maps a single ffprobe stream onto StreamInfo.
+func toStreamInfo(s ffprobeStream) StreamInfo {
+	info := StreamInfo{
+		Index:    s.Index,
+		Type:     s.CodecType,
+		Codec:    s.CodecName,
+		Profile:  s.Profile,
+		Language: langtag.Normalize(tagValue(s.Tags, "language")),
+		Title:    tagValue(s.Tags, "title"),
+		Default:  s.Disposition["default"] == 1,
+		Forced:   s.Disposition["forced"] == 1,
+		Tags:     s.Tags,
+	}
+	if br, err := parseInt(s.BitRate); err == nil {
+		info.Bitrate = br / 1000
+	}
+
+	switch s.CodecType {
+	case StreamVideo:
+		info.Width = s.Width
+		info.Height = s.Height
+		info.PixelFormat = s.PixFmt
+		info.BitDepth = streamBitDepth(s)
+		info.AttachedPic = s.Disposition["attached_pic"] == 1
+		info.FieldOrder = s.FieldOrder
+		info.Color = ColorInfo{
+			Range:     s.ColorRange,
+			Space:     s.ColorSpace,
+			Transfer:  s.ColorTransfer,
+			Primaries: s.ColorPrimaries,
+		}
+		if fr, err := parseRatio(s.RFrameRate); err == nil {
+			info.Framerate = fr
+		}
+	case StreamAudio:
+		info.Channels = s.Channels
+		info.ChannelLayout = s.ChannelLayout
+		if sr, err := parseInt(s.SampleRate); err == nil {
+			info.SampleRate = sr
+		}
+	}
+	return info
+}
+
+// pixFmtDepth extracts the bit depth embedded in pixel format names (e.g. "yuv420p10le").
+var pixFmtDepth = regexp.MustCompile(`p(\d{1,2})(le|be)?$`)
+
+// streamBitDepth returns the bit depth from bits_per_raw_sample, falling back
+// to the pixel format name, and finally 8 for known 8-bit formats.
+func streamBitDepth(s ffprobeStream) int {
+	if d, err := strconv.Atoi(s.BitsPerRawSample); err == nil && d > 0 {
+		return d
+	}
+	return pixelFormatDepth(s.PixFmt)
+}
+
+// pixelFormatDepth infers bit depth from a pixel format name. Returns 0 if unknown.
+func pixelFormatDepth(pixFmt string) int {
+	if pixFmt == "" {
+		return 0
+	}
+	if m := pixFmtDepth.FindStringSubmatch(pixFmt); m != nil {
+		if d, err := strconv.Atoi(m[1]); err == nil {
+			return d
+		}
+	}
+	switch {
+	case strings.HasPrefix(pixFmt, "yuv"), strings.HasPrefix(pixFmt, "yuvj"), strings.HasPrefix(pixFmt, "nv12"),
+		strings.HasPrefix(pixFmt, "rgb24"), strings.HasPrefix(pixFmt, "bgr24"), pixFmt == "gray":
+		return 8
+	}
+	return 0
+}
+
+// tagValue looks up a tag case-insensitively (containers differ on tag casing).
+func tagValue(tags map[string]string, key string) string {
+	if v, ok := tags[key]; ok {
+		return v
+	}
+	for k, v := range tags {
+		if strings.EqualFold(k, key) {
+			return v
+		}
+	}
+	return ""
+}
@@ -8,18 +8,34 @@ type ffprobeOutput struct {
 	Format  ffprobeFormat   `json:"format"`  // container-level metadata
 }
 
-// ffprobeStream represents a single stream (video or audio) in ffprobe output
+// ffprobeStream represents a single stream (video, audio, subtitle, data) in ffprobe output
 type ffprobeStream struct {
-	CodecType  string `json:"codec_type"`             // "video" or "audio"
-	CodecName  string `json:"codec_name"`             // e.g. "h264"
-	Width      int    `json:"width,omitempty"`        // only for video
-	Height     int    `json:"height,omitempty"`       // only for video
-	BitRate    string `json:"bit_rate,omitempty"`     // e.g. "1000k"
-	RFrameRate string `json:"r_frame_rate,omitempty"` // raw framerate string
+	Index            int               `json:"index"`                         // stream index within the container
+	CodecType        string            `json:"codec_type"`                    // "video", "audio", "subtitle", "data", "attachment"
+	CodecName        string            `json:"codec_name"`                    // e.g. "h264"
+	Profile          string            `json:"profile,omitempty"`             // e.g. "High", "LC"
+	Width            int               `json:"width,omitempty"`               // only for video
+	Height           int               `json:"height,omitempty"`              // only for video
+	BitRate          string            `json:"bit_rate,omitempty"`            // bits per second
+	RFrameRate       string            `json:"r_frame_rate,omitempty"`        // raw framerate string
+	PixFmt           string            `json:"pix_fmt,omitempty"`             // e.g. "yuv420p10le"
+	BitsPerRawSample string            `json:"bits_per_raw_sample,omitempty"` // e.g. "10"
+	ColorRange       string            `json:"color_range,omitempty"`         // "tv" or "pc"
+	ColorSpace       string            `json:"color_space,omitempty"`         // e.g. "bt709"
+	ColorTransfer    string            `json:"color_transfer,omitempty"`      // e.g. "smpte2084"
+	ColorPrimaries   string            `json:"color_primaries,omitempty"`     // e.g. "bt2020"
+	Channels         int               `json:"channels,omitempty"`            // only for audio
+	ChannelLayout    string            `json:"channel_layout,omitempty"`      // e.g. "5.1(side)"
+	SampleRate       string            `json:"sample_rate,omitempty"`         // e.g. "48000"
+	FieldOrder       string            `json:"field_order,omitempty"`         // "progressive", "tt", "bb", "tb", "bt", "unknown"
+	Tags             map[string]string `json:"tags,omitempty"`                // language, title, handler_name, ...
+	Disposition      map[string]int    `json:"disposition,omitempty"`         // default, forced, attached_pic, ...
 }
 
 // ffprobeFormat represents the container-level metadata
 type ffprobeFormat struct {
-	Duration string `json:"duration"` // in seconds
-	BitRate  string `json:"bit_rate"` // in bits per second
+	FormatName string            `json:"format_name"`    // e.g. "mov,mp4,m4a,3gp,3g2,mj2"
+	Duration   string            `json:"duration"`       // in seconds
+	BitRate    string            `json:"bit_rate"`       // in bits per second
+	Tags       map[string]string `json:"tags,omitempty"` // title, language, encoder, ...
 }
@@ -0,0 +1,179 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/namer"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
+)
+
+// Defaults for Coordinator and Worker timing.
+const (
+	DefaultPollInterval = 2 * time.Second
+	DefaultHeartbeat    = 15 * time.Second
+	DefaultStaleAfter   = 2 * time.Minute
+	DefaultMaxAttempts  = 3
+)
+
+// Coordinator splits jobs into per-variant tasks and collects the results.
+type Coordinator struct {
+	Queue        Queue
+	PollInterval time.Duration // How often to check for results (default DefaultPollInterval)
+	StaleAfter   time.Duration // Requeue tasks whose worker stopped heartbeating (default DefaultStaleAfter)
+	MaxAttempts  int           // Fail stale tasks already claimed this often instead of requeueing them (default DefaultMaxAttempts)
+}
+
+// Transcode runs profile's ladder across the cluster and returns the same
+// result a local transcoder.TranscodeWithOptions would. Failed rungs are
+// recorded in result.Errors; the call only errors when the job cannot be
+// dispatched or ctx is cancelled. opts.OnVariant fires as rungs complete;
+// per-variant progress stays on the workers, so only the final progress
+// event is emitted.
+func (c *Coordinator) Transcode(ctx context.Context, profile *transcoder.TranscodeProfile, media *analyzer.MediaInfo, logger stagelog.Logger, opts transcoder.TranscodeOptions) (*transcoder.TranscodeResult, error) {
+	logger = stagelog.OrStd(logger)
+	poll := orDuration(c.PollInterval, DefaultPollInterval)
+	stale := orDuration(c.StaleAfter, DefaultStaleAfter)
+	maxAttempts := c.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	// Resolve the slug and claim its directory once, so every worker writes
+	// into the same place
+	plan := transcoder.PlanTranscode(profile, media, logger)
+	if plan.Err != nil {
+		return nil, transcoder.NewTranscoderError("validation", "ladder", profile.InputPath, plan.SlugDir,
+			"no usable ladder for the source", nil, 0, plan.Err)
+	}
+	if err := os.MkdirAll(plan.SlugDir, os.ModePerm); err != nil {
+		return nil, transcoder.NewTranscoderError("filesystem", "mkdir", profile.InputPath, plan.SlugDir,
+			"failed to create slug directory", nil, 0, err)
+	}
+	if profile.Layout().UsesSlug() {
+		if err := namer.Claim(plan.SlugDir, profile.InputPath); err != nil {
+			logger.LogError("filesystem", err)
+		}
+	}
+
+	start := time.Now()
+	jobID := fmt.Sprintf("%s-%d", plan.Slug, start.UnixNano())
+	encodes := opts.Pending(plan)
+	pending := make(map[string]transcoder.PlannedVariant, len(encodes))
+	for _, pv := range encodes {
+		task := Task{
+			ID:      jobID + "-" + pv.Key,
+			JobID:   jobID,
+			Key:     pv.Key,
+			Profile: narrow(profile, plan.Slug, pv.Variant),
+			Media:   media,
+		}
+		if err := c.Queue.Enqueue(ctx, task); err != nil {
+			return nil, fmt.Errorf("failed to enqueue %s: %w", task.ID, err)
+		}
+		pending[task.ID] = pv
+	}
+	logger.LogStage("cluster", fmt.Sprintf("📤 Dispatched %d variant task(s) for job %s", len(pending), jobID))
+
+	result := &transcoder.TranscodeResult{
+		InputPath:   profile.InputPath,
+		OutputDir:   plan.SlugDir,
+		Duration:    media.Duration,
+		Success:     true,
+		Profile:     profile,
+		Variants:    append([]transcoder.ResolutionVariant(nil), opts.Keep...),
+		Adjustments: plan.Adjusted,
+	}
+
+	// Workers join the bumpers; the result only records how long they play
+	if pre, post, err := profile.BumperDurations(ctx); err != nil {
+		logger.LogError("bumpers", err)
+	} else {
+		result.BumperDuration, result.PreRoll = pre+post, pre
+	}
+
+	failFast := profile.PartialFailureSettings().Policy == transcoder.PartialFailFast
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+	for len(pending) > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+		if n, err := c.Queue.Requeue(ctx, stale, maxAttempts); err != nil {
+			logger.LogError("cluster", err)
+		} else if n > 0 {
+			logger.LogStage("cluster", fmt.Sprintf("♻️ Requeued %d task(s) from unresponsive workers", n))
+		}
+
+		for id, pv := range pending {
+			res, err := c.Queue.Result(ctx, id)
+			if err != nil {
+				logger.LogError("cluster", err)
+				continue
+			}
+			if res == nil {
+				continue
+			}
+			if err := c.Queue.Acknowledge(ctx, id); err != nil {
+				logger.LogError("cluster", err)
+			}
+			delete(pending, id)
+			if res.Variant != nil {
+				result.Variants = append(result.Variants, *res.Variant)
+				if opts.OnVariant != nil {
+					opts.OnVariant(*res.Variant)
+				}
+				logger.LogVariant(pv.Key, fmt.Sprintf("✅ Encoded on %s", res.Worker))
+				continue
+			}
+			result.Success = false
+			result.Errors = append(result.Errors, transcoder.NewTranscoderError(
+				"execution", "transcode", profile.InputPath, pv.OutputPath,
+				fmt.Sprintf("worker %s failed", res.Worker), nil, 0, errors.New(res.Error),
+			))
+			logger.LogVariant(pv.Key, fmt.Sprintf("❌ Failed on %s: %s", res.Worker, res.Error))
+			if failFast {
+				// Tasks already claimed run to completion on their workers
+				logger.LogStage("cluster", fmt.Sprintf("⏹️ Abandoning %d remaining task(s) after a failure", len(pending)))
+				clear(pending)
+				break
+			}
+		}
+	}
+
+	result.WallTime = time.Since(start)
+	if opts.Progress != nil {
+		opts.Progress(transcoder.ProgressEvent{Percent: 100, Aggregate: true, Active: len(encodes), Done: true})
+	}
+	logger.LogStage("cluster", fmt.Sprintf("🏁 Job %s finished in %s", jobID, result.WallTime))
+	if err := result.ApplyPartialPolicy(); err != nil {
+		logger.LogError("cluster", err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// narrow copies profile with a single variant and the coordinator's slug
+// pinned, so every worker resolves the same output paths.
+func narrow(profile *transcoder.TranscodeProfile, slug string, v transcoder.Variant) transcoder.TranscodeProfile {
+	p := *profile
+	p.Variants = []transcoder.Variant{v}
+	p.Slug = slug
+	p.Extends = ""
+	return p
+}
+
+// orDuration returns d, or def when d is not positive.
+func orDuration(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
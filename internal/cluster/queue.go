@@ -0,0 +1,384 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Queue carries tasks from the coordinator to workers and results back.
+// Implementations must let exactly one worker claim each task.
+type Queue interface {
+	Enqueue(ctx context.Context, task Task) error
+	// Claim hands the oldest pending task to worker, or returns nil, nil when
+	// nothing is pending.
+	Claim(ctx context.Context, worker string) (*Task, error)
+	// Touch records that the worker holding taskID is still alive.
+	Touch(ctx context.Context, taskID string) error
+	// Release returns a claimed task to the pending set in one step, so the
+	// claim cannot also be requeued as stale.
+	Release(ctx context.Context, task Task) error
+	Complete(ctx context.Context, result TaskResult) error
+	// Result returns the result for taskID, or nil, nil if it isn't done yet.
+	Result(ctx context.Context, taskID string) (*TaskResult, error)
+	// Acknowledge drops the result for taskID once the coordinator has it.
+	Acknowledge(ctx context.Context, taskID string) error
+	// Requeue returns claimed tasks not touched within staleAfter to the
+	// pending set (their worker died) and reports how many were moved.
+	// Tasks already claimed maxAttempts times are completed with an error
+	// instead, so a task that kills its workers cannot cycle forever.
+	Requeue(ctx context.Context, staleAfter time.Duration, maxAttempts int) (int, error)
+}
+
+// DefaultRetention is how long DirQueue keeps results nobody acknowledged,
+// e.g. those of a coordinator that died.
+const DefaultRetention = 24 * time.Hour
+
+// abandoned is the result Requeue records for a task out of attempts.
+func abandoned(task Task) TaskResult {
+	return TaskResult{
+		TaskID: task.ID,
+		JobID:  task.JobID,
+		Worker: task.Worker,
+		Error:  fmt.Sprintf("worker stopped responding; giving up after %d attempt(s)", task.Attempt),
+	}
+}
+
+// undecodable is the result Claim records for a task file it can't decode.
+func undecodable(id, worker string, err error) TaskResult {
+	return TaskResult{
+		TaskID: id,
+		Worker: worker,
+		Error:  fmt.Sprintf("task file is not valid: %v", err),
+	}
+}
+
+// DirQueue is a Queue in a directory shared by every node. Tasks move between
+// pending/, claimed/, and done/ with atomic renames, so it needs no server;
+// any filesystem with atomic rename (local disk, NFSv4) works.
+type DirQueue struct {
+	Dir       string
+	Retention time.Duration // How long unacknowledged results stay in done/ (default DefaultRetention)
+}
+
+// NewDirQueue creates the queue directories under dir.
+func NewDirQueue(dir string) (*DirQueue, error) {
+	q := &DirQueue{Dir: dir}
+	for _, sub := range []string{"pending", "claimed", "done"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), os.ModePerm); err != nil {
+			return nil, fmt.Errorf("failed to create queue directory: %w", err)
+		}
+	}
+	return q, nil
+}
+
+func (q *DirQueue) path(state, id string) string {
+	return filepath.Join(q.Dir, state, id+".json")
+}
+
+// Enqueue writes task to pending/.
+func (q *DirQueue) Enqueue(ctx context.Context, task Task) error {
+	return writeJSONAtomic(q.path("pending", task.ID), task)
+}
+
+// Claim renames the oldest pending task into claimed/. Losing a rename race
+// to another worker just moves on to the next task, and a task file that
+// doesn't decode is completed with an error in its place.
+func (q *DirQueue) Claim(ctx context.Context, worker string) (*Task, error) {
+	entries, err := os.ReadDir(filepath.Join(q.Dir, "pending"))
+	if err != nil {
+		return nil, err
+	}
+	// A pending file's mtime is when it joined pending/: Enqueue and Release
+	// write it and Requeue touches it, so this matches MemoryQueue's order
+	type pendingTask struct {
+		id     string
+		queued time.Time
+	}
+	var tasks []pendingTask
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue // claimed by another worker since ReadDir
+		}
+		tasks = append(tasks, pendingTask{strings.TrimSuffix(e.Name(), ".json"), info.ModTime()})
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if !tasks[i].queued.Equal(tasks[j].queued) {
+			return tasks[i].queued.Before(tasks[j].queued)
+		}
+		return tasks[i].id < tasks[j].id
+	})
+	for _, t := range tasks {
+		id := t.id
+		claimed := q.path("claimed", id)
+		if err := os.Rename(q.path("pending", id), claimed); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue // another worker won
+			}
+			return nil, err
+		}
+		data, err := os.ReadFile(claimed)
+		if err != nil {
+			return nil, err
+		}
+		var task Task
+		if err := json.Unmarshal(data, &task); err != nil {
+			// Requeue would only hand it back; fail it so the queue moves on
+			if err := q.Complete(ctx, undecodable(id, worker, err)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		task.Attempt++
+		task.Worker = worker
+		if err := writeJSONAtomic(claimed, task); err != nil {
+			return nil, err
+		}
+		return &task, nil
+	}
+	return nil, nil
+}
+
+// Touch bumps the claimed file's modification time.
+func (q *DirQueue) Touch(ctx context.Context, taskID string) error {
+	now := time.Now()
+	return os.Chtimes(q.path("claimed", taskID), now, now)
+}
+
+// Release writes task over its claim, which keeps the claim fresh, and
+// renames the claim back into pending/.
+func (q *DirQueue) Release(ctx context.Context, task Task) error {
+	claimed := q.path("claimed", task.ID)
+	if err := writeJSONAtomic(claimed, task); err != nil {
+		return err
+	}
+	return os.Rename(claimed, q.path("pending", task.ID))
+}
+
+// Complete writes the result to done/ and drops the claim.
+func (q *DirQueue) Complete(ctx context.Context, result TaskResult) error {
+	if err := writeJSONAtomic(q.path("done", result.TaskID), result); err != nil {
+		return err
+	}
+	if err := os.Remove(q.path("claimed", result.TaskID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Result reads done/<taskID>.json if present.
+func (q *DirQueue) Result(ctx context.Context, taskID string) (*TaskResult, error) {
+	var result TaskResult
+	if err := readJSON(q.path("done", taskID), &result); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Acknowledge removes done/<taskID>.json.
+func (q *DirQueue) Acknowledge(ctx context.Context, taskID string) error {
+	if err := os.Remove(q.path("done", taskID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Requeue moves stale claims back to pending/, or to done/ as failures once
+// they are out of attempts. It also deletes results older than Retention.
+func (q *DirQueue) Requeue(ctx context.Context, staleAfter time.Duration, maxAttempts int) (int, error) {
+	entries, err := os.ReadDir(filepath.Join(q.Dir, "claimed"))
+	if err != nil {
+		return 0, err
+	}
+	moved := 0
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || time.Since(info.ModTime()) < staleAfter {
+			continue
+		}
+		id := strings.TrimSuffix(e.Name(), ".json")
+		var task Task
+		if err := readJSON(q.path("claimed", id), &task); err == nil && task.Attempt >= maxAttempts {
+			if err := q.Complete(ctx, abandoned(task)); err != nil {
+				return moved, err
+			}
+			continue
+		}
+		if err := os.Rename(q.path("claimed", id), q.path("pending", id)); err == nil {
+			now := time.Now()
+			os.Chtimes(q.path("pending", id), now, now) // back of the line, as Claim orders by mtime
+			moved++
+		}
+	}
+	return moved, q.prune()
+}
+
+// prune deletes results older than Retention.
+func (q *DirQueue) prune() error {
+	retention := orDuration(q.Retention, DefaultRetention)
+	entries, err := os.ReadDir(filepath.Join(q.Dir, "done"))
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || time.Since(info.ModTime()) < retention {
+			continue
+		}
+		if err := os.Remove(filepath.Join(q.Dir, "done", e.Name())); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeJSONAtomic writes v next to path and renames it into place so readers
+// never see a partial file.
+func writeJSONAtomic(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// readJSON decodes the JSON file at path into v.
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// MemoryQueue is an in-process Queue, for running coordinator and workers in
+// one binary (e.g. several worker goroutines sharing a GPU pool) and for tests.
+type MemoryQueue struct {
+	mu      sync.Mutex
+	pending []Task
+	claimed map[string]claim
+	done    map[string]TaskResult
+}
+
+// claim is a task held by a worker and when it was last touched.
+type claim struct {
+	task    Task
+	touched time.Time
+}
+
+// NewMemoryQueue returns an empty in-process queue.
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{claimed: make(map[string]claim), done: make(map[string]TaskResult)}
+}
+
+// Enqueue appends task to the pending list.
+func (q *MemoryQueue) Enqueue(ctx context.Context, task Task) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending = append(q.pending, task)
+	return nil
+}
+
+// Claim pops the oldest pending task.
+func (q *MemoryQueue) Claim(ctx context.Context, worker string) (*Task, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) == 0 {
+		return nil, nil
+	}
+	task := q.pending[0]
+	q.pending = q.pending[1:]
+	task.Attempt++
+	task.Worker = worker
+	q.claimed[task.ID] = claim{task: task, touched: time.Now()}
+	return &task, nil
+}
+
+// Touch refreshes the claim on taskID.
+func (q *MemoryQueue) Touch(ctx context.Context, taskID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if c, ok := q.claimed[taskID]; ok {
+		c.touched = time.Now()
+		q.claimed[taskID] = c
+	}
+	return nil
+}
+
+// Release drops the claim and appends task to the pending list.
+func (q *MemoryQueue) Release(ctx context.Context, task Task) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.claimed, task.ID)
+	q.pending = append(q.pending, task)
+	return nil
+}
+
+// Complete records result and drops the claim.
+func (q *MemoryQueue) Complete(ctx context.Context, result TaskResult) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.claimed, result.TaskID)
+	q.done[result.TaskID] = result
+	return nil
+}
+
+// Result returns the result for taskID if it is done.
+func (q *MemoryQueue) Result(ctx context.Context, taskID string) (*TaskResult, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if r, ok := q.done[taskID]; ok {
+		return &r, nil
+	}
+	return nil, nil
+}
+
+// Acknowledge forgets the result for taskID.
+func (q *MemoryQueue) Acknowledge(ctx context.Context, taskID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.done, taskID)
+	return nil
+}
+
+// Requeue returns stale claims to the pending list, or records them as
+// failures once they are out of attempts.
+func (q *MemoryQueue) Requeue(ctx context.Context, staleAfter time.Duration, maxAttempts int) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	moved := 0
+	for id, c := range q.claimed {
+		if time.Since(c.touched) < staleAfter {
+			continue
+		}
+		delete(q.claimed, id)
+		if c.task.Attempt >= maxAttempts {
+			q.done[id] = abandoned(c.task)
+			continue
+		}
+		q.pending = append(q.pending, c.task)
+		moved++
+	}
+	return moved, nil
+}
@@ -0,0 +1,36 @@
+// Package cluster spreads transcoding across machines. A Coordinator splits
+// a job into one task per ladder rung and publishes the tasks on a shared
+// Queue; Workers on any node claim tasks, encode the rung into the shared
+// output directory, and report back. The coordinator reassembles the results
+// into a TranscodeResult so segmentation and manifests run exactly as they
+// do for a local encode.
+//
+// Input and output paths must resolve to the same files on every node (e.g.
+// a shared NFS or SMB mount at the same path).
+package cluster
+
+import (
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+)
+
+// Task is one rung of a job, self-contained so any worker can run it.
+type Task struct {
+	ID      string                      `json:"id"`               // Unique task ID (job ID plus variant key)
+	JobID   string                      `json:"job_id"`           // Job the task belongs to
+	Key     string                      `json:"key"`              // Variant key (e.g. "720p_3000k")
+	Profile transcoder.TranscodeProfile `json:"profile"`          // Job profile narrowed to this task's single variant
+	Media   *analyzer.MediaInfo         `json:"media"`            // Source analysis, so workers skip re-probing
+	Attempt int                         `json:"attempt"`          // Number of times the task has been claimed
+	Worker  string                      `json:"worker,omitempty"` // Worker holding the latest claim
+}
+
+// TaskResult is a worker's report for one task.
+type TaskResult struct {
+	TaskID    string                        `json:"task_id"`
+	JobID     string                        `json:"job_id"`
+	Worker    string                        `json:"worker"`              // ID of the worker that ran the task
+	Variant   *transcoder.ResolutionVariant `json:"variant,omitempty"`   // Encoded variant on success
+	Error     string                        `json:"error,omitempty"`     // Failure message, "" on success
+	Retryable bool                          `json:"retryable,omitempty"` // Whether the failure was transient
+}
@@ -0,0 +1,161 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
+)
+
+// Worker claims tasks from a Queue and encodes them.
+type Worker struct {
+	ID           string // Worker name reported in results (default: hostname-pid)
+	Queue        Queue
+	Logger       stagelog.Logger // Optional; nil falls back to stagelog.Std
+	PollInterval time.Duration   // How often to look for work when idle (default DefaultPollInterval)
+	Heartbeat    time.Duration   // How often to touch a claimed task (default DefaultHeartbeat)
+	MaxAttempts  int             // Transient failures are retried until a task has been claimed this often (default DefaultMaxAttempts)
+	Scratch      string          // Directory for encode intermediates such as checkpointed chunks, one subdirectory per task (default: under os.TempDir())
+}
+
+// Run processes tasks until ctx is cancelled. A task interrupted by the
+// cancellation goes back to the queue.
+func (w *Worker) Run(ctx context.Context) error {
+	return w.RunDraining(ctx, ctx)
+}
+
+// RunDraining is Run with graceful shutdown: once ctx is done the worker
+// stops claiming tasks but finishes the one it is encoding, unless kill is
+// done first; a task interrupted that way goes back to the queue for another
+// worker instead of failing.
+func (w *Worker) RunDraining(ctx, kill context.Context) error {
+	w.init()
+	w.Logger.LogStage("worker", fmt.Sprintf("👷 Worker %s waiting for tasks", w.ID))
+	for {
+		ran, err := w.runOnce(ctx, kill)
+		if err != nil {
+			w.Logger.LogError("worker", err)
+		}
+		if ran && ctx.Err() == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(w.PollInterval):
+		}
+	}
+}
+
+// RunOnce claims and runs a single task. Reports whether a task was found.
+func (w *Worker) RunOnce(ctx context.Context) (bool, error) {
+	w.init()
+	return w.runOnce(ctx, ctx)
+}
+
+// runOnce claims a task unless ctx is done and encodes it until kill is done.
+func (w *Worker) runOnce(ctx, kill context.Context) (bool, error) {
+	if ctx.Err() != nil {
+		return false, nil
+	}
+	task, err := w.Queue.Claim(ctx, w.ID)
+	if err != nil || task == nil {
+		return false, err
+	}
+	w.Logger.LogVariant(task.Key, fmt.Sprintf("📥 Claimed %s (attempt %d)", task.ID, task.Attempt))
+
+	// Keep the claim alive while encoding
+	hbCtx, stop := context.WithCancel(kill)
+	defer stop()
+	go func() {
+		ticker := time.NewTicker(w.Heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-hbCtx.Done():
+				return
+			case <-ticker.C:
+				if err := w.Queue.Touch(hbCtx, task.ID); err != nil {
+					w.Logger.LogError("worker", err)
+				}
+			}
+		}
+	}()
+
+	scratch := filepath.Join(w.Scratch, task.ID)
+	result := w.encode(kill, task, scratch)
+	stop()
+
+	// The queue is still reachable when the encode was interrupted
+	qctx := context.WithoutCancel(kill)
+	switch {
+	case kill.Err() != nil:
+		w.Logger.LogVariant(task.Key, "🛑 Interrupted by shutdown, returning task to the queue")
+		task.Attempt-- // Shutdown isn't the task's failure
+		return true, w.Queue.Release(qctx, *task)
+	case result.Error != "" && result.Retryable && task.Attempt < w.MaxAttempts:
+		// The scratch directory stays, so a retry here resumes its chunks
+		w.Logger.LogVariant(task.Key, fmt.Sprintf("🔁 Transient failure, requeueing: %s", result.Error))
+		return true, w.Queue.Release(qctx, *task)
+	}
+	if err := os.RemoveAll(scratch); err != nil {
+		w.Logger.LogError("worker", err)
+	}
+	return true, w.Queue.Complete(qctx, result)
+}
+
+// encode runs the task's single-variant transcode, keeping intermediates in
+// scratch.
+func (w *Worker) encode(ctx context.Context, task *Task, scratch string) TaskResult {
+	result := TaskResult{TaskID: task.ID, JobID: task.JobID, Worker: w.ID}
+	if task.Media == nil {
+		result.Error = "task has no media analysis"
+		return result
+	}
+	profile := task.Profile
+	res, err := transcoder.TranscodeWithOptions(ctx, &profile, task.Media, w.Logger, transcoder.TranscodeOptions{Scratch: scratch, Workdir: scratch})
+	if err != nil {
+		result.Error = err.Error()
+		result.Retryable = isRetryable(err)
+		return result
+	}
+	if len(res.Errors) > 0 {
+		result.Error = res.Errors[0].Error()
+		result.Retryable = res.Errors[0].Retryable()
+		return result
+	}
+	if len(res.Variants) == 0 {
+		result.Error = "variant was skipped (source resolution too low or unknown label)"
+		return result
+	}
+	result.Variant = &res.Variants[0]
+	return result
+}
+
+// init fills in defaults.
+func (w *Worker) init() {
+	w.Logger = stagelog.OrStd(w.Logger)
+	if w.ID == "" {
+		host, _ := os.Hostname()
+		w.ID = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	if w.Scratch == "" {
+		w.Scratch = filepath.Join(os.TempDir(), "dotgo-worker-"+w.ID)
+	}
+	w.PollInterval = orDuration(w.PollInterval, DefaultPollInterval)
+	w.Heartbeat = orDuration(w.Heartbeat, DefaultHeartbeat)
+	if w.MaxAttempts <= 0 {
+		w.MaxAttempts = DefaultMaxAttempts
+	}
+}
+
+// isRetryable reports whether err is a transient transcoder failure.
+func isRetryable(err error) bool {
+	var te *transcoder.TranscoderError
+	return errors.As(err, &te) && te.Retryable()
+}
@@ -0,0 +1,57 @@
+package drm
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// KeyInfo is key material written to disk for ffmpeg's -hls_key_info_file.
+// Files live in a private temporary directory, never in the output tree, so
+// the content key is not published alongside the segments.
+type KeyInfo struct {
+	Dir     string // Temporary directory holding both files
+	KeyFile string // Raw 16-byte key
+	Path    string // Key info file passed to -hls_key_info_file
+}
+
+// WriteKeyInfo writes key to a new temporary directory under root (the
+// system temporary directory when empty) along with a key info file listing
+// the key URI, the key file path, and the IV (when set).
+// Call Remove when packaging is done.
+func WriteKeyInfo(key *Key, root string) (*KeyInfo, error) {
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp(root, "dotgo-drm-")
+	if err != nil {
+		return nil, fmt.Errorf("drm: failed to create key directory: %w", err)
+	}
+	info := &KeyInfo{
+		Dir:     dir,
+		KeyFile: filepath.Join(dir, "content.key"),
+		Path:    filepath.Join(dir, "content.keyinfo"),
+	}
+	if err := os.WriteFile(info.KeyFile, key.Key, 0o600); err != nil {
+		info.Remove()
+		return nil, fmt.Errorf("drm: failed to write key file: %w", err)
+	}
+	body := key.LicenseURL + "\n" + info.KeyFile + "\n"
+	if key.IV != nil {
+		body += hex.EncodeToString(key.IV) + "\n"
+	}
+	if err := os.WriteFile(info.Path, []byte(body), 0o600); err != nil {
+		info.Remove()
+		return nil, fmt.Errorf("drm: failed to write key info file: %w", err)
+	}
+	return info, nil
+}
+
+// Remove deletes the temporary key directory. Safe to call on nil.
+func (i *KeyInfo) Remove() error {
+	if i == nil || i.Dir == "" {
+		return nil
+	}
+	return os.RemoveAll(i.Dir)
+}
@@ -0,0 +1,156 @@
+// Package errclass sorts pipeline failures into a small set of classes
+// (corrupt input, missing encoder, full disk, transient I/O, cancellation)
+// so the job queue and callers can decide whether to retry without
+// string-matching error messages. Stage error types (TranscoderError,
+// SegmenterError, AnalyzerError) and sentinel errors made with New report
+// their class through a Class method; Classify finds it anywhere in a
+// wrapped chain and otherwise falls back to the underlying system error and
+// ffmpeg's stderr.
+package errclass
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/executil"
+)
+
+// Class is the kind of failure an error represents.
+type Class string
+
+// Error classes.
+const (
+	None           Class = ""                // No error
+	Unknown        Class = "unknown"         // Not recognized; retrying is unlikely to help
+	InputCorrupt   Class = "input_corrupt"   // The source is truncated, corrupt, or not media
+	InputMissing   Class = "input_missing"   // The source doesn't exist
+	InvalidConfig  Class = "invalid_config"  // The profile or request is invalid
+	EncoderMissing Class = "encoder_missing" // ffmpeg or ffprobe, or a codec, filter, or option they need, isn't available
+	DiskFull       Class = "disk_full"       // Out of disk space or over the workspace quota
+	TransientIO    Class = "transient_io"    // Timeout, stall, or network/storage hiccup; may succeed if re-run
+	Cancelled      Class = "cancelled"       // Cancelled by the caller or its deadline
+)
+
+// IsRetryable reports whether re-running the failed work as-is may succeed.
+// Only transient I/O failures qualify; a full disk needs space freed first.
+func (c Class) IsRetryable() bool {
+	return c == TransientIO
+}
+
+// Classifier is implemented by errors that know their class. Errors
+// wrapping a cause should classify it first (see Classify) and fall back to
+// what they know about the failed operation.
+type Classifier interface {
+	Class() Class
+}
+
+// Classify returns the class of err, None for nil. The outermost Classifier
+// in the chain decides; when there is none or it returns Unknown, err is
+// classified from cancellation, system errors, and ffmpeg's stderr.
+func Classify(err error) Class {
+	if err == nil {
+		return None
+	}
+	var c Classifier
+	if errors.As(err, &c) {
+		if class := c.Class(); class != Unknown {
+			return class
+		}
+	}
+	return classifyCause(err)
+}
+
+// IsRetryable reports whether err is classified as retryable.
+func IsRetryable(err error) bool {
+	return Classify(err).IsRetryable()
+}
+
+// New returns a sentinel error with message msg that Classify reports as
+// class. Match it with errors.Is as usual.
+func New(class Class, msg string) error {
+	return &classed{msg: msg, class: class}
+}
+
+// classed is an error created by New.
+type classed struct {
+	msg   string
+	class Class
+}
+
+func (e *classed) Error() string { return e.msg }
+
+func (e *classed) Class() Class { return e.class }
+
+// classifyCause classifies err from cancellation, system errors, and
+// ffmpeg's stderr anywhere in its chain, ignoring Classifier methods.
+func classifyCause(err error) Class {
+	var cmdErr *executil.CommandError
+	isCmd := errors.As(err, &cmdErr)
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded),
+		isCmd && cmdErr.Reason == executil.ReasonCanceled:
+		return Cancelled
+	case isCmd && cmdErr.Retryable(), errors.Is(err, executil.ErrTimeout), errors.Is(err, executil.ErrStalled):
+		return TransientIO
+	case errors.Is(err, syscall.ENOSPC), errors.Is(err, syscall.EDQUOT):
+		return DiskFull
+	case errors.Is(err, exec.ErrNotFound):
+		return EncoderMissing
+	case errors.Is(err, os.ErrNotExist):
+		return InputMissing
+	case errors.Is(err, syscall.EIO), errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ETIMEDOUT),
+		errors.Is(err, os.ErrDeadlineExceeded):
+		return TransientIO
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return TransientIO
+	}
+	return classifyStderr(executil.StderrTail(err))
+}
+
+// stderrPatterns map ffmpeg and OS messages to classes, checked in order.
+var stderrPatterns = []struct {
+	text  string
+	class Class
+}{
+	{"no space left on device", DiskFull},
+	{"not enough space on the disk", DiskFull},
+	{"disk quota exceeded", DiskFull},
+	{"unknown encoder", EncoderMissing},
+	{"encoder not found", EncoderMissing},
+	{"unrecognized option", EncoderMissing},
+	{"no such filter", EncoderMissing},
+	{"connection reset", TransientIO},
+	{"connection timed out", TransientIO},
+	{"connection refused", TransientIO},
+	{"input/output error", TransientIO},
+	{"resource temporarily unavailable", TransientIO},
+	{"stale file handle", TransientIO},
+	{"server returned 5", TransientIO},
+	{"no such file or directory", InputMissing},
+	{"invalid data found when processing input", InputCorrupt},
+	{"moov atom not found", InputCorrupt},
+	{"error while decoding", InputCorrupt},
+	{"corrupt", InputCorrupt},
+}
+
+// classifyStderr classifies an ffmpeg stderr tail, Unknown when no known
+// message appears.
+func classifyStderr(stderr string) Class {
+	if stderr == "" {
+		return Unknown
+	}
+	stderr = strings.ToLower(stderr)
+	for _, p := range stderrPatterns {
+		if strings.Contains(stderr, p.text) {
+			return p.class
+		}
+	}
+	return Unknown
+}
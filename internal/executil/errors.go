@@ -0,0 +1,115 @@
+package executil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// StderrTailBytes is the maximum amount of stderr retained for failed commands.
+const StderrTailBytes = 8 * 1024
+
+// CommandError is returned when an executed command fails.
+// It carries the last StderrTailBytes of stderr so failures can be diagnosed
+// without re-running ffmpeg by hand.
+type CommandError struct {
+	Command []string // Command that was executed
+	Reason  string   // Why the command failed: "exit", "timeout", "stalled", "canceled"
+	Stderr  string   // Tail of stderr output (progress noise removed)
+	Err     error    // Underlying error (usually *exec.ExitError)
+}
+
+// Failure reasons reported in CommandError.Reason.
+const (
+	ReasonExit     = "exit"
+	ReasonTimeout  = "timeout"
+	ReasonStalled  = "stalled"
+	ReasonCanceled = "canceled"
+)
+
+// Error returns the failure summary; the stderr tail is available via the Stderr field.
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("command failed: %v", e.Err)
+}
+
+// Unwrap returns the underlying error for compatibility with errors.Is/As.
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether re-running the command may succeed.
+// Timeouts and stalls are treated as transient; non-zero exits and cancellations are not.
+func (e *CommandError) Retryable() bool {
+	return e.Reason == ReasonTimeout || e.Reason == ReasonStalled
+}
+
+// IsRetryable reports whether err wraps a retryable CommandError.
+func IsRetryable(err error) bool {
+	var cmdErr *CommandError
+	return errors.As(err, &cmdErr) && cmdErr.Retryable()
+}
+
+// StderrTail returns the stderr tail carried by err, or "" if err is not a CommandError.
+func StderrTail(err error) string {
+	var cmdErr *CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.Stderr
+	}
+	return ""
+}
+
+// tailBuffer is an io.Writer that keeps only the last max bytes written.
+type tailBuffer struct {
+	mu  sync.Mutex
+	max int
+	buf []byte
+}
+
+// newTailBuffer creates a tailBuffer retaining up to max bytes.
+func newTailBuffer(max int) *tailBuffer {
+	return &tailBuffer{max: max}
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if over := len(t.buf) - t.max; over > 0 {
+		t.buf = append(t.buf[:0], t.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+// WriteLine appends a single line, skipping ffmpeg progress noise.
+func (t *tailBuffer) WriteLine(line string) {
+	if isProgressLine(line) {
+		return
+	}
+	_, _ = t.Write([]byte(line + "\n"))
+}
+
+// String returns the retained tail.
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return strings.TrimSpace(string(t.buf))
+}
+
+// isProgressLine reports whether line is ffmpeg progress output rather than a diagnostic.
+// Matches "-progress" key=value pairs (e.g. "out_time=...") and "-stats" lines ("frame= ... speed=").
+func isProgressLine(line string) bool {
+	if strings.HasPrefix(line, "frame=") && strings.Contains(line, "speed=") {
+		return true
+	}
+	key, _, ok := strings.Cut(line, "=")
+	if !ok || key == "" {
+		return false
+	}
+	for _, r := range key {
+		if (r < 'a' || r > 'z') && r != '_' && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
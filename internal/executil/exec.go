@@ -4,23 +4,119 @@ package executil
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
 )
 
+// verbosity holds the process-wide default output level for executed commands.
+var verbosity atomic.Int32
+
+// SetVerbosity sets the default command output level, used by commands whose
+// context carries no level of its own (see WithVerbosity):
+//   - Quiet suppresses the command echo
+//   - Normal echoes each command before running it
+//   - Debug additionally streams full ffmpeg stderr to the log
+func SetVerbosity(v stagelog.Verbosity) {
+	verbosity.Store(int32(v))
+}
+
+// verbosityKey is the context key under which WithVerbosity stores a level.
+type verbosityKey struct{}
+
+// WithVerbosity returns a context whose commands run at v, overriding the
+// SetVerbosity default. Concurrent jobs each carry their own level this way.
+func WithVerbosity(ctx context.Context, v stagelog.Verbosity) context.Context {
+	return context.WithValue(ctx, verbosityKey{}, v)
+}
+
+// currentVerbosity returns the level carried by ctx, or the default.
+func currentVerbosity(ctx context.Context) stagelog.Verbosity {
+	if v, ok := ctx.Value(verbosityKey{}).(stagelog.Verbosity); ok {
+		return v
+	}
+	return stagelog.Verbosity(verbosity.Load())
+}
+
+// echoCommand logs the command line unless running quietly.
+func echoCommand(ctx context.Context, prefix string, cmd []string) {
+	if currentVerbosity(ctx) != stagelog.Quiet {
+		log.Printf("%s: %s", prefix, strings.Join(cmd, " "))
+	}
+}
+
+// debugWriter returns a writer that forwards output to the log in Debug mode, or nil otherwise.
+func debugWriter(ctx context.Context) *lineLogger {
+	if currentVerbosity(ctx) == stagelog.Debug {
+		return &lineLogger{}
+	}
+	return nil
+}
+
+// lineLogger forwards written bytes to the log line by line.
+type lineLogger struct{ buf []byte }
+
+func (l *lineLogger) Write(p []byte) (int, error) {
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i < 0 {
+			break
+		}
+		log.Printf("[ffmpeg] %s", bytes.TrimRight(l.buf[:i], "\r"))
+		l.buf = l.buf[i+1:]
+	}
+	return len(p), nil
+}
+
 // RunCommand executes a shell command using os/exec.
-// Logs the command and returns any execution error.
+// Logs the command and returns any execution error. Failures are returned as
+// *CommandError carrying the tail of stderr.
 func RunCommand(cmd []string) error {
-	log.Printf("🚀 Executing command: %s", strings.Join(cmd, " "))
-	execCmd := exec.Command(cmd[0], cmd[1:]...)
+	return RunCommandContext(context.Background(), cmd, DefaultLimits())
+}
+
+// RunCommandContext is RunCommand with cancellation and a wall-clock timeout.
+// The process is killed when ctx is done or limits.Timeout elapses; the returned
+// *CommandError reports the reason and whether the failure is retryable.
+func RunCommandContext(ctx context.Context, cmd []string, limits Limits) error {
+	echoCommand(ctx, "🚀 Executing command", cmd)
+	ctx, cancel := limits.apply(ctx)
+	defer cancel(nil)
+
+	execCmd := exec.CommandContext(ctx, cmd[0], cmd[1:]...)
+	execCmd.WaitDelay = killGracePeriod
+	tail := newTailBuffer(StderrTailBytes)
 	execCmd.Stdout = nil
-	execCmd.Stderr = nil
-	return execCmd.Run()
+	execCmd.Stderr = tail
+	if w := debugWriter(ctx); w != nil {
+		execCmd.Stderr = io.MultiWriter(tail, w)
+	}
+	pauser := pauserFrom(ctx)
+	if err := pauser.wait(ctx); err != nil {
+		return newCommandError(ctx, cmd, tail, err)
+	}
+	if err := limits.start(execCmd); err != nil {
+		return newCommandError(ctx, cmd, tail, err)
+	}
+	defer pauser.track(execCmd.Process)()
+	err := execCmd.Wait()
+	RecordUsage(ctx, execCmd.ProcessState)
+	if err != nil {
+		return newCommandError(ctx, cmd, tail, err)
+	}
+	return nil
 }
 
 // RunCommandWithProgress executes a shell command and streams stderr output to extract
@@ -29,9 +125,23 @@ func RunCommand(cmd []string) error {
 //
 // Progress updates are emitted via the onProgress callback, throttled to avoid flooding.
 // This function is concurrency-safe and designed for long-running transcoding tasks.
+// Failures are returned as *CommandError carrying the tail of stderr (progress lines excluded).
 func RunCommandWithProgress(cmd []string, duration float64, onProgress func(percent float64)) error {
-	log.Printf("🚀 Executing command with progress: %s", strings.Join(cmd, " "))
-	execCmd := exec.Command(cmd[0], cmd[1:]...)
+	return RunCommandWithProgressContext(context.Background(), cmd, duration, DefaultLimits(), onProgress)
+}
+
+// RunCommandWithProgressContext is RunCommandWithProgress with cancellation,
+// a wall-clock timeout, and a no-progress watchdog. When limits.StallTimeout is
+// set, the process is killed if the reported media timestamp hasn't advanced
+// within that window, turning ffmpeg hangs into retryable errors.
+func RunCommandWithProgressContext(ctx context.Context, cmd []string, duration float64, limits Limits, onProgress func(percent float64)) error {
+	echoCommand(ctx, "🚀 Executing command with progress", cmd)
+	debug := currentVerbosity(ctx) == stagelog.Debug
+	ctx, cancel := limits.apply(ctx)
+	defer cancel(nil)
+
+	execCmd := exec.CommandContext(ctx, cmd[0], cmd[1:]...)
+	execCmd.WaitDelay = killGracePeriod
 
 	// Open stderr pipe for streaming ffmpeg output
 	stderr, err := execCmd.StderrPipe()
@@ -39,16 +149,42 @@ func RunCommandWithProgress(cmd []string, duration float64, onProgress func(perc
 		return fmt.Errorf("failed to get stderr pipe: %w", err)
 	}
 
-	// Start the command execution
-	if err := execCmd.Start(); err != nil {
+	// Hold off while the job is paused, then start at the configured priority
+	pauser := pauserFrom(ctx)
+	if err := pauser.wait(ctx); err != nil {
 		return fmt.Errorf("failed to start command: %w", err)
 	}
+	if err := limits.start(execCmd); err != nil {
+		return fmt.Errorf("failed to start command: %w", err)
+	}
+	defer pauser.track(execCmd.Process)()
 
 	reader := bufio.NewReader(stderr)
+	tail := newTailBuffer(StderrTailBytes)
 	var lastEmit time.Time
+	readDone := make(chan struct{})
+
+	// Track when the media timestamp last advanced for the stall watchdog
+	var lastAdvance atomic.Int64
+	lastAdvance.Store(time.Now().UnixNano())
+	var lastTs float64
+	report := func(ts float64) {
+		if ts <= 0 {
+			return
+		}
+		if ts > lastTs {
+			lastTs = ts
+			lastAdvance.Store(time.Now().UnixNano())
+		}
+		if duration > 0 && time.Since(lastEmit) > 2*time.Second {
+			onProgress((ts / duration) * 100)
+			lastEmit = time.Now()
+		}
+	}
 
 	// Stream stderr line-by-line to extract progress
 	go func() {
+		defer close(readDone)
 		for {
 			line, err := reader.ReadString('\n')
 			if err != nil {
@@ -56,40 +192,83 @@ func RunCommandWithProgress(cmd []string, duration float64, onProgress func(perc
 			}
 
 			line = strings.TrimSpace(line)
+			tail.WriteLine(line)
+			if debug {
+				log.Printf("[ffmpeg] %s", line)
+			}
 
 			// Parse traditional ffmpeg progress lines (e.g. "time=00:01:23.45")
 			if strings.Contains(line, "time=") {
-				if ts := extractTimestamp(line); ts > 0 && duration > 0 {
-					percent := (ts / duration) * 100
-					if time.Since(lastEmit) > 2*time.Second {
-						onProgress(percent)
-						lastEmit = time.Now()
-					}
-				}
+				report(extractTimestamp(line))
 			}
 
 			// Parse structured progress lines from "-progress pipe:2" (e.g. "out_time=00:01:23.45")
 			if strings.HasPrefix(line, "out_time=") {
-				ts := parseTimestamp(strings.TrimPrefix(line, "out_time="))
-				if ts > 0 && duration > 0 {
-					percent := (ts / duration) * 100
-					if time.Since(lastEmit) > 2*time.Second {
-						onProgress(percent)
-						lastEmit = time.Now()
-					}
-				}
+				report(parseTimestamp(strings.TrimPrefix(line, "out_time=")))
 			}
 		}
 	}()
 
-	// Wait for command to complete
-	if err := execCmd.Wait(); err != nil {
-		return fmt.Errorf("command failed: %w", err)
+	// Kill the process if progress stops advancing
+	if limits.StallTimeout > 0 {
+		go watchStall(&lastAdvance, limits.StallTimeout, pauser, readDone, cancel)
+	}
+
+	// Drain stderr before Wait closes the pipe, then wait for command to complete
+	<-readDone
+	err = execCmd.Wait()
+	RecordUsage(ctx, execCmd.ProcessState)
+	if err != nil {
+		return newCommandError(ctx, cmd, tail, err)
 	}
 
 	return nil
 }
 
+// watchStall cancels the command once lastAdvance is older than window.
+// Time spent paused by pauser doesn't count. Exits when done is closed (the
+// command's output stream ended).
+func watchStall(lastAdvance *atomic.Int64, window time.Duration, pauser *Pauser, done <-chan struct{}, cancel context.CancelCauseFunc) {
+	interval := window / 4
+	if interval > 5*time.Second {
+		interval = 5 * time.Second
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if pauser.Paused() {
+				lastAdvance.Store(time.Now().UnixNano())
+				continue
+			}
+			if time.Since(time.Unix(0, lastAdvance.Load())) > window {
+				cancel(fmt.Errorf("%w: no progress for %s", ErrStalled, window))
+				return
+			}
+		}
+	}
+}
+
+// ExitCode returns the process exit code carried by err, 0 for a nil error,
+// or -1 when the command failed without producing an exit status (e.g. not found).
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
 // extractTimestamp parses ffmpeg time=HH:MM:SS.xx from stderr and returns seconds.
 func extractTimestamp(line string) float64 {
 	re := regexp.MustCompile(`time=(\d+):(\d+):(\d+\.\d+)`)
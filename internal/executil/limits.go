@@ -0,0 +1,102 @@
+package executil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// ErrTimeout is reported when a command exceeds its wall-clock timeout.
+var ErrTimeout = errors.New("command timed out")
+
+// ErrStalled is reported when the no-progress watchdog kills a command.
+var ErrStalled = errors.New("command stalled")
+
+// killGracePeriod bounds how long Wait blocks on I/O after the process is killed.
+const killGracePeriod = 5 * time.Second
+
+// Limits bounds the time and host resources a single command may use.
+// Zero values disable the corresponding control.
+type Limits struct {
+	Timeout      time.Duration // Maximum wall-clock time per command, not counting time suspended by a Pauser
+	StallTimeout time.Duration // Kill if progress hasn't advanced for this long (progress-aware commands only)
+	Nice         int           // CPU niceness 1-19 (Unix); mapped to below-normal/idle priority class on Windows
+	IdleIO       bool          // Run in the idle I/O scheduling class (Linux ionice -c3)
+}
+
+var (
+	defaultLimitsMu sync.RWMutex
+	defaultLimits   Limits
+)
+
+// SetDefaultLimits sets the limits applied by RunCommand and RunCommandWithProgress.
+func SetDefaultLimits(l Limits) {
+	defaultLimitsMu.Lock()
+	defer defaultLimitsMu.Unlock()
+	defaultLimits = l
+}
+
+// DefaultLimits returns the process-wide default limits.
+func DefaultLimits() Limits {
+	defaultLimitsMu.RLock()
+	defer defaultLimitsMu.RUnlock()
+	return defaultLimits
+}
+
+// apply derives a cancelable context from ctx honoring l.Timeout. Time spent
+// suspended by ctx's Pauser doesn't count toward the timeout.
+// The returned cancel func must be called to release resources.
+func (l Limits) apply(ctx context.Context) (context.Context, context.CancelCauseFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	if l.Timeout <= 0 {
+		return ctx, cancel
+	}
+	go pauserFrom(ctx).expire(ctx, l.Timeout, func() {
+		cancel(fmt.Errorf("%w after %s", ErrTimeout, l.Timeout))
+	})
+	return ctx, cancel
+}
+
+// start launches cmd at the scheduling priority from l. The priority is set
+// before the process runs where the platform allows (creation flags, or the
+// nice/ionice wrappers); otherwise it is applied right after start, and a
+// failure to apply it kills the process and is returned.
+func (l Limits) start(cmd *exec.Cmd) error {
+	adjust := prepareProcess(cmd, l)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if !adjust {
+		return nil
+	}
+	if err := adjustProcess(cmd.Process.Pid, l); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return fmt.Errorf("failed to lower priority for pid %d: %w", cmd.Process.Pid, err)
+	}
+	return nil
+}
+
+// newCommandError builds a CommandError, attributing the failure to a timeout,
+// stall, or cancellation when ctx was terminated before the process exited.
+func newCommandError(ctx context.Context, cmd []string, tail *tailBuffer, err error) *CommandError {
+	cmdErr := &CommandError{Command: cmd, Stderr: tail.String(), Err: err, Reason: ReasonExit}
+	if ctx.Err() == nil {
+		return cmdErr
+	}
+
+	cause := context.Cause(ctx)
+	switch {
+	case errors.Is(cause, ErrTimeout):
+		cmdErr.Reason = ReasonTimeout
+	case errors.Is(cause, ErrStalled):
+		cmdErr.Reason = ReasonStalled
+	default:
+		cmdErr.Reason = ReasonCanceled
+	}
+	cmdErr.Err = fmt.Errorf("%w (%v)", cause, err)
+	return cmdErr
+}
@@ -0,0 +1,187 @@
+package executil
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+// Pauser suspends and resumes every command started under a context carrying
+// it (see WithPauser), so a scheduler can preempt a low-priority job without
+// losing its encode progress. Processes are stopped with SIGSTOP and resumed
+// with SIGCONT; commands started while paused wait for Resume. A suspended
+// ffmpeg keeps its memory. Limits.Timeout and the stall watchdog are held off
+// while paused.
+//
+// A nil *Pauser is valid and never pauses.
+type Pauser struct {
+	mu      sync.Mutex
+	paused  bool
+	resumed chan struct{} // Closed on Resume; waited on by commands starting while paused
+	changed chan struct{} // Closed and replaced on every Pause and Resume
+	procs   map[*os.Process]struct{}
+}
+
+// NewPauser returns a Pauser in the running state.
+func NewPauser() *Pauser {
+	return &Pauser{changed: make(chan struct{}), procs: make(map[*os.Process]struct{})}
+}
+
+// pauserKey is the context key under which WithPauser stores a Pauser.
+type pauserKey struct{}
+
+// WithPauser returns a context whose commands are controlled by p.
+func WithPauser(ctx context.Context, p *Pauser) context.Context {
+	return context.WithValue(ctx, pauserKey{}, p)
+}
+
+// pauserFrom returns the Pauser carried by ctx, or nil.
+func pauserFrom(ctx context.Context) *Pauser {
+	p, _ := ctx.Value(pauserKey{}).(*Pauser)
+	return p
+}
+
+// CanPause reports whether this platform can suspend processes.
+func CanPause() bool {
+	return canSuspend
+}
+
+// Pause suspends every running command and holds back new ones.
+func (p *Pauser) Pause() error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.paused {
+		return nil
+	}
+	p.paused = true
+	p.resumed = make(chan struct{})
+	p.notifyLocked()
+	var first error
+	for proc := range p.procs {
+		if err := suspend(proc); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Resume continues every suspended command and releases held ones.
+func (p *Pauser) Resume() error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.paused {
+		return nil
+	}
+	p.paused = false
+	close(p.resumed)
+	p.notifyLocked()
+	var first error
+	for proc := range p.procs {
+		if err := resume(proc); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Paused reports whether p is currently paused.
+func (p *Pauser) Paused() bool {
+	if p == nil {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused
+}
+
+// notifyLocked wakes everything waiting for the next Pause or Resume.
+func (p *Pauser) notifyLocked() {
+	close(p.changed)
+	p.changed = make(chan struct{})
+}
+
+// state returns whether p is paused and a channel closed when that changes.
+// A nil Pauser is never paused and never changes.
+func (p *Pauser) state() (bool, <-chan struct{}) {
+	if p == nil {
+		return false, nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.paused, p.changed
+}
+
+// expire calls fn once p has been running, not paused, for timeout in
+// total, unless ctx is done first.
+func (p *Pauser) expire(ctx context.Context, timeout time.Duration, fn func()) {
+	remaining := timeout
+	for {
+		paused, changed := p.state()
+		if paused {
+			select {
+			case <-changed:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		started := time.Now()
+		timer := time.NewTimer(remaining)
+		select {
+		case <-timer.C:
+			fn()
+			return
+		case <-changed:
+			timer.Stop()
+			remaining -= time.Since(started)
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// wait blocks while p is paused, returning early with ctx's error.
+func (p *Pauser) wait(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	paused, resumed := p.paused, p.resumed
+	p.mu.Unlock()
+	if !paused {
+		return nil
+	}
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// track registers a started process, suspending it at once if p was paused
+// in the meantime. The returned func unregisters it.
+func (p *Pauser) track(proc *os.Process) func() {
+	if p == nil {
+		return func() {}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.procs[proc] = struct{}{}
+	if p.paused {
+		suspend(proc)
+	}
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		delete(p.procs, proc)
+	}
+}
@@ -0,0 +1,24 @@
+//go:build !unix
+
+package executil
+
+import (
+	"errors"
+	"os"
+)
+
+// canSuspend reports that processes can't be suspended on this platform.
+const canSuspend = false
+
+// errNoSuspend is returned when pausing is attempted without job control signals.
+var errNoSuspend = errors.New("suspending processes is not supported on this platform")
+
+// suspend is unsupported without job control signals.
+func suspend(proc *os.Process) error {
+	return errNoSuspend
+}
+
+// resume is unsupported without job control signals.
+func resume(proc *os.Process) error {
+	return errNoSuspend
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package executil
+
+import (
+	"os"
+	"syscall"
+)
+
+// canSuspend reports that job control signals are available.
+const canSuspend = true
+
+// suspend stops proc with SIGSTOP.
+func suspend(proc *os.Process) error {
+	return proc.Signal(syscall.SIGSTOP)
+}
+
+// resume continues proc with SIGCONT.
+func resume(proc *os.Process) error {
+	return proc.Signal(syscall.SIGCONT)
+}
@@ -0,0 +1,48 @@
+//go:build linux
+
+package executil
+
+import (
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// ioprio_set constants from linux/ioprio.h.
+const (
+	ioprioWhoProcess = 1
+	ioprioClassIdle  = 3
+	ioprioClassShift = 13
+)
+
+// prepareProcess runs cmd under nice and ionice -c3 so the process starts
+// at the lowered priority instead of being adjusted after it has begun work.
+// It reports whether adjustProcess must still run after start, which is the
+// case when a wrapper isn't on PATH.
+func prepareProcess(cmd *exec.Cmd, l Limits) bool {
+	adjust := false
+	if l.IdleIO && !wrapCommand(cmd, "ionice", "-c", "3", "-t") {
+		adjust = true
+	}
+	if l.Nice > 0 && !wrapCommand(cmd, "nice", "-n", strconv.Itoa(l.Nice)) {
+		adjust = true
+	}
+	return adjust
+}
+
+// adjustProcess lowers CPU priority (nice) and optionally moves the process into
+// the idle I/O scheduling class (ionice -c3) once it has started.
+func adjustProcess(pid int, l Limits) error {
+	if l.Nice > 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, pid, l.Nice); err != nil {
+			return err
+		}
+	}
+	if l.IdleIO {
+		prio := uintptr(ioprioClassIdle << ioprioClassShift)
+		if _, _, errno := syscall.Syscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(pid), prio); errno != 0 {
+			return errno
+		}
+	}
+	return nil
+}
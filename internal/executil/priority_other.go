@@ -0,0 +1,11 @@
+//go:build !unix && !windows
+
+package executil
+
+import "os/exec"
+
+// prepareProcess is a no-op on platforms without priority controls.
+func prepareProcess(cmd *exec.Cmd, l Limits) bool { return false }
+
+// adjustProcess is a no-op on platforms without priority controls.
+func adjustProcess(pid int, l Limits) error { return nil }
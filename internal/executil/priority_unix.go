@@ -0,0 +1,25 @@
+//go:build unix && !linux
+
+package executil
+
+import (
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// prepareProcess runs cmd under nice so the process starts at the lowered
+// priority. It reports whether adjustProcess must still run after start,
+// which is the case when nice isn't on PATH. Idle I/O scheduling is
+// Linux-only and ignored here.
+func prepareProcess(cmd *exec.Cmd, l Limits) bool {
+	return l.Nice > 0 && !wrapCommand(cmd, "nice", "-n", strconv.Itoa(l.Nice))
+}
+
+// adjustProcess lowers CPU priority (nice) once the process has started.
+func adjustProcess(pid int, l Limits) error {
+	if l.Nice > 0 {
+		return syscall.Setpriority(syscall.PRIO_PROCESS, pid, l.Nice)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+//go:build windows
+
+package executil
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Windows priority classes passed via process creation flags.
+const (
+	belowNormalPriorityClass = 0x00004000
+	idlePriorityClass        = 0x00000040
+)
+
+// prepareProcess maps Nice onto a Windows priority class before the process starts:
+// 1-9 -> BELOW_NORMAL_PRIORITY_CLASS, 10+ -> IDLE_PRIORITY_CLASS. No
+// adjustment is needed after start.
+func prepareProcess(cmd *exec.Cmd, l Limits) bool {
+	var class uint32
+	switch {
+	case l.Nice >= 10:
+		class = idlePriorityClass
+	case l.Nice > 0:
+		class = belowNormalPriorityClass
+	default:
+		return false
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= class
+	return false
+}
+
+// adjustProcess is a no-op on Windows; priority is set at creation time.
+func adjustProcess(pid int, l Limits) error { return nil }
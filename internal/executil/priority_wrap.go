@@ -0,0 +1,26 @@
+//go:build unix
+
+package executil
+
+import "os/exec"
+
+// wrapCommand rewrites cmd to run through a wrapper that execs it, such as
+// "nice -n 10", keeping the process ID the caller tracks. It reports false,
+// leaving cmd unchanged, when the wrapper isn't on PATH.
+func wrapCommand(cmd *exec.Cmd, name string, args ...string) bool {
+	if cmd.Err != nil {
+		// Start reports the lookup failure; there is nothing to wrap
+		return true
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return false
+	}
+	wrapped := make([]string, 0, len(args)+len(cmd.Args)+1)
+	wrapped = append(wrapped, name)
+	wrapped = append(wrapped, args...)
+	wrapped = append(wrapped, cmd.Path)
+	wrapped = append(wrapped, cmd.Args[1:]...)
+	cmd.Path, cmd.Args = path, wrapped
+	return true
+}
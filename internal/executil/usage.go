@@ -0,0 +1,95 @@
+package executil
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+// ResourceUsage is the host resources used by the commands of a job.
+type ResourceUsage struct {
+	Processes    int           `json:"processes"`     // Commands that ran to exit
+	CPUTime      time.Duration `json:"cpu_time"`      // User plus system CPU time
+	UserTime     time.Duration `json:"user_time"`     // CPU time in user mode
+	SystemTime   time.Duration `json:"system_time"`   // CPU time in the kernel
+	PeakRSS      int64         `json:"peak_rss"`      // Largest resident set of any single command, in bytes (Unix only)
+	BytesRead    int64         `json:"bytes_read"`    // Block I/O read from storage, in bytes (Unix only; page cache hits don't count)
+	BytesWritten int64         `json:"bytes_written"` // Block I/O written to storage, in bytes (Unix only)
+}
+
+// Add returns the combined usage of u and o; PeakRSS is the larger of the two.
+func (u ResourceUsage) Add(o ResourceUsage) ResourceUsage {
+	u.Processes += o.Processes
+	u.CPUTime += o.CPUTime
+	u.UserTime += o.UserTime
+	u.SystemTime += o.SystemTime
+	u.PeakRSS = max(u.PeakRSS, o.PeakRSS)
+	u.BytesRead += o.BytesRead
+	u.BytesWritten += o.BytesWritten
+	return u
+}
+
+// Meter totals the resource usage of every command started under a context
+// carrying it (see WithMeter), for cost attribution per job. Commands run
+// through RunCommandContext and RunCommandWithProgressContext are recorded
+// automatically; callers running their own exec.Cmd use RecordUsage.
+//
+// A nil *Meter is valid and records nothing.
+type Meter struct {
+	mu    sync.Mutex
+	usage ResourceUsage
+}
+
+// NewMeter returns an empty Meter.
+func NewMeter() *Meter {
+	return &Meter{}
+}
+
+// meterKey is the context key under which WithMeter stores a Meter.
+type meterKey struct{}
+
+// WithMeter returns a context whose commands are recorded by m.
+func WithMeter(ctx context.Context, m *Meter) context.Context {
+	return context.WithValue(ctx, meterKey{}, m)
+}
+
+// meterFrom returns the Meter carried by ctx, or nil.
+func meterFrom(ctx context.Context) *Meter {
+	m, _ := ctx.Value(meterKey{}).(*Meter)
+	return m
+}
+
+// Usage returns the usage recorded so far.
+func (m *Meter) Usage() ResourceUsage {
+	if m == nil {
+		return ResourceUsage{}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.usage
+}
+
+// record adds the usage of an exited process.
+func (m *Meter) record(state *os.ProcessState) {
+	if m == nil || state == nil {
+		return
+	}
+	u := ResourceUsage{
+		Processes:  1,
+		UserTime:   state.UserTime(),
+		SystemTime: state.SystemTime(),
+	}
+	u.CPUTime = u.UserTime + u.SystemTime
+	u.PeakRSS, u.BytesRead, u.BytesWritten = processIO(state)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usage = m.usage.Add(u)
+}
+
+// RecordUsage adds the usage of an exited process to the Meter carried by
+// ctx, if any. state is the command's ProcessState once Wait returned; a nil
+// state (the process never started) is ignored.
+func RecordUsage(ctx context.Context, state *os.ProcessState) {
+	meterFrom(ctx).record(state)
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package executil
+
+import "os"
+
+// processIO is unsupported without rusage; only CPU time is recorded.
+func processIO(state *os.ProcessState) (peakRSS, read, written int64) {
+	return 0, 0, 0
+}
@@ -0,0 +1,26 @@
+//go:build unix
+
+package executil
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// blockSize is the unit of the rusage block I/O counters.
+const blockSize = 512
+
+// processIO returns the peak resident set and block I/O of an exited
+// process, in bytes, from its rusage.
+func processIO(state *os.ProcessState) (peakRSS, read, written int64) {
+	ru, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || ru == nil {
+		return 0, 0, 0
+	}
+	peakRSS = int64(ru.Maxrss)
+	if runtime.GOOS != "darwin" && runtime.GOOS != "ios" {
+		peakRSS *= 1024 // Kilobytes everywhere but Apple platforms
+	}
+	return peakRSS, int64(ru.Inblock) * blockSize, int64(ru.Oublock) * blockSize
+}
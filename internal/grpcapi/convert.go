@@ -0,0 +1,131 @@
+package grpcapi
+
+import (
+	"encoding/json"
+	"time"
+
+	transcodev1 "github.com/dotsoulja/dotgo-transcode/api/transcode/v1"
+	"github.com/dotsoulja/dotgo-transcode/internal/jobs"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// states maps job states to their wire values.
+var states = map[jobs.State]transcodev1.JobState{
+	jobs.Queued:    transcodev1.JobState_JOB_STATE_QUEUED,
+	jobs.Running:   transcodev1.JobState_JOB_STATE_RUNNING,
+	jobs.Succeeded: transcodev1.JobState_JOB_STATE_SUCCEEDED,
+	jobs.Failed:    transcodev1.JobState_JOB_STATE_FAILED,
+	jobs.Cancelled: transcodev1.JobState_JOB_STATE_CANCELLED,
+	jobs.Paused:    transcodev1.JobState_JOB_STATE_PAUSED,
+}
+
+// stateFromProto maps a wire job state back to a job state.
+func stateFromProto(s transcodev1.JobState) jobs.State {
+	for state, wire := range states {
+		if wire == s {
+			return state
+		}
+	}
+	return ""
+}
+
+// jobProto converts a job snapshot to its wire form.
+func jobProto(job jobs.Job) *transcodev1.Job {
+	out := &transcodev1.Job{
+		Id:             job.ID,
+		State:          states[job.State],
+		InputPath:      job.InputPath,
+		Slug:           job.Slug,
+		Stage:          job.Stage,
+		Percent:        job.Percent,
+		Error:          job.Error,
+		CreatedAt:      timestamp(job.Created),
+		StartedAt:      timestamp(job.Started),
+		FinishedAt:     timestamp(job.Finished),
+		IdempotencyKey: job.Request.IdempotencyKey,
+		Fingerprint:    job.Hash,
+		Priority:       int32(job.Request.Priority),
+	}
+	for _, st := range job.Stages {
+		out.Stages = append(out.Stages, &transcodev1.StageTiming{
+			Stage:     st.Stage,
+			StartedAt: timestamp(st.Started),
+			Elapsed:   durationpb.New(st.Elapsed),
+			Error:     st.Err,
+		})
+	}
+	if job.Profile != nil {
+		if data, err := json.Marshal(job.Profile); err == nil {
+			out.ProfileJson = string(data)
+		}
+	}
+	if r := job.Report; r != nil {
+		res := &transcodev1.JobResult{
+			ManifestPath:    r.ManifestPath,
+			MetadataPath:    r.MetadataPath,
+			ChecksumPath:    r.ChecksumPath,
+			PreviewPath:     r.PreviewPath,
+			Thumbnails:      r.Thumbnails,
+			DurationSeconds: r.Duration,
+			Warnings:        job.Warnings,
+		}
+		for _, v := range r.Variants {
+			res.Variants = append(res.Variants, &transcodev1.Variant{
+				Width:           int32(v.Width),
+				Height:          int32(v.Height),
+				Bitrate:         v.Bitrate,
+				Codec:           v.Codec,
+				Codecs:          v.Codecs,
+				OutputFilename:  v.OutputFilename,
+				FileSize:        v.Stats.FileSize,
+				MeasuredBitrate: int32(v.Stats.MeasuredBitrate),
+				EncodeTime:      durationpb.New(v.Stats.WallTime),
+				FfmpegVersion:   v.Settings.FFmpeg,
+				ProfileHash:     v.Settings.ProfileHash,
+				SettingsHash:    v.Settings.Hash,
+			})
+		}
+		out.Result = res
+	}
+	return out
+}
+
+// eventProto converts a job event to its wire form.
+func eventProto(ev jobs.Event) *transcodev1.JobEvent {
+	out := &transcodev1.JobEvent{JobId: ev.JobID, Time: timestamp(ev.Time)}
+	switch ev.Kind {
+	case jobs.EventState:
+		out.Event = &transcodev1.JobEvent_State{State: jobProto(*ev.Job)}
+	case jobs.EventStage:
+		s := ev.Stage
+		stage := &transcodev1.StageEvent{Stage: s.Stage, Finished: s.Finished, Error: s.Err}
+		if s.Finished {
+			stage.Elapsed = durationpb.New(s.Elapsed)
+		}
+		out.Event = &transcodev1.JobEvent_Stage{Stage: stage}
+	case jobs.EventProgress:
+		p := ev.Progress
+		progress := &transcodev1.ProgressEvent{
+			Variant:   p.Variant,
+			Percent:   p.Percent,
+			Aggregate: p.Aggregate,
+			Done:      p.Done,
+			Failed:    p.Failed,
+			Speed:     p.Speed,
+		}
+		if p.ETA > 0 {
+			progress.Eta = durationpb.New(p.ETA)
+		}
+		out.Event = &transcodev1.JobEvent_Progress{Progress: progress}
+	}
+	return out
+}
+
+// timestamp converts t, leaving zero times unset.
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
@@ -0,0 +1,194 @@
+// Package grpcapi serves the transcodev1.TranscodeService gRPC API on top of
+// a jobs.Manager, so other services can submit pipeline jobs, follow their
+// stages and progress as a stream, and cancel them with typed stubs.
+//
+// Calls act for a tenant: submitted jobs are namespaced under it, and jobs of
+// other tenants are neither listed nor found. With Server.Tenants the tenant
+// is the one the call's bearer token belongs to. Without it the tenant is
+// whatever the TenantHeader metadata claims, which is only safe behind a
+// proxy that authenticates callers and sets the header; calls without the
+// header then see every tenant unless the server requires one.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	transcodev1 "github.com/dotsoulja/dotgo-transcode/api/transcode/v1"
+	"github.com/dotsoulja/dotgo-transcode/internal/jobs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TenantHeader is the metadata key naming the tenant a call acts for.
+const TenantHeader = "dotgo-tenant"
+
+// Server implements transcodev1.TranscodeServiceServer.
+type Server struct {
+	transcodev1.UnimplementedTranscodeServiceServer
+	Jobs          *jobs.Manager
+	RequireTenant bool // Reject calls without TenantHeader metadata
+
+	// Tenants maps each tenant to the API tokens that act for it (see
+	// LoadTenants). When set, every call must authenticate with
+	// "authorization: Bearer <token>" metadata.
+	Tenants map[string][]string
+}
+
+// Register adds a TranscodeService backed by m to g, accepting calls with or
+// without TenantHeader metadata. Register a Server directly to require it.
+func Register(g *grpc.Server, m *jobs.Manager) {
+	transcodev1.RegisterTranscodeServiceServer(g, &Server{Jobs: m})
+}
+
+// tenant returns the tenant a call acts for, "" for every tenant.
+func (s *Server) tenant(ctx context.Context) (string, error) {
+	md := incoming(ctx)
+	if len(s.Tenants) > 0 {
+		return s.authenticate(md)
+	}
+	tenant := first(md.Get(TenantHeader))
+	if tenant == "" && s.RequireTenant {
+		return "", status.Errorf(codes.Unauthenticated, "missing %s metadata", TenantHeader)
+	}
+	return tenant, nil
+}
+
+// lookup returns job id if the call's tenant may see it. Jobs of other
+// tenants are reported as not found, so their IDs don't leak.
+func (s *Server) lookup(ctx context.Context, id string) (jobs.Job, error) {
+	tenant, err := s.tenant(ctx)
+	if err != nil {
+		return jobs.Job{}, err
+	}
+	job, err := s.Jobs.Get(id)
+	if err != nil {
+		return jobs.Job{}, toStatus(err)
+	}
+	if tenant != "" && job.Tenant != tenant {
+		return jobs.Job{}, status.Errorf(codes.NotFound, "%v: %s", jobs.ErrNotFound, id)
+	}
+	return job, nil
+}
+
+// SubmitJob queues a pipeline run, or returns the job it duplicates.
+func (s *Server) SubmitJob(ctx context.Context, req *transcodev1.SubmitJobRequest) (*transcodev1.SubmitJobResponse, error) {
+	tenant, err := s.tenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	job, existing, err := s.Jobs.Submit(jobs.Request{
+		ProfilePath:    req.GetProfilePath(),
+		Profile:        req.GetProfileDocument(),
+		Overlays:       req.GetOverlays(),
+		Format:         req.GetStreamFormat(),
+		SkipStages:     req.GetSkipStages(),
+		IdempotencyKey: req.GetIdempotencyKey(),
+		Force:          req.GetForce(),
+		Priority:       jobs.Priority(req.GetPriority()),
+		Tenant:         tenant,
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &transcodev1.SubmitJobResponse{Job: jobProto(job), Deduplicated: existing}, nil
+}
+
+// GetJob returns a job's current state.
+func (s *Server) GetJob(ctx context.Context, req *transcodev1.GetJobRequest) (*transcodev1.Job, error) {
+	job, err := s.lookup(ctx, req.GetJobId())
+	if err != nil {
+		return nil, err
+	}
+	return jobProto(job), nil
+}
+
+// ListJobs returns the job history matching the request, newest first.
+func (s *Server) ListJobs(ctx context.Context, req *transcodev1.ListJobsRequest) (*transcodev1.ListJobsResponse, error) {
+	tenant, err := s.tenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	filter := jobs.Filter{Tenant: tenant, InputPath: req.GetInputPath(), Limit: int(req.GetLimit())}
+	for _, st := range req.GetStates() {
+		filter.States = append(filter.States, stateFromProto(st))
+	}
+	if req.GetSince() != nil {
+		filter.Since = req.GetSince().AsTime()
+	}
+	if req.GetUntil() != nil {
+		filter.Until = req.GetUntil().AsTime()
+	}
+	list, err := s.Jobs.List(filter)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	resp := &transcodev1.ListJobsResponse{}
+	for _, job := range list {
+		resp.Jobs = append(resp.Jobs, jobProto(job))
+	}
+	return resp, nil
+}
+
+// CancelJob stops a queued or running job.
+func (s *Server) CancelJob(ctx context.Context, req *transcodev1.CancelJobRequest) (*transcodev1.Job, error) {
+	if _, err := s.lookup(ctx, req.GetJobId()); err != nil {
+		return nil, err
+	}
+	job, err := s.Jobs.Cancel(req.GetJobId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return jobProto(job), nil
+}
+
+// WatchJob streams job events until the job finishes, the server shuts down,
+// or the client goes away. The stream always ends with the job's latest state.
+func (s *Server) WatchJob(req *transcodev1.WatchJobRequest, stream transcodev1.TranscodeService_WatchJobServer) error {
+	ctx := stream.Context()
+	if _, err := s.lookup(ctx, req.GetJobId()); err != nil {
+		return err
+	}
+	events, err := s.Jobs.Watch(ctx, req.GetJobId())
+	if err != nil {
+		return toStatus(err)
+	}
+	sentFinal := false
+	for ev := range events {
+		if err := stream.Send(eventProto(ev)); err != nil {
+			return err
+		}
+		sentFinal = ev.Kind == jobs.EventState && ev.Job.State.Terminal()
+	}
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	if !sentFinal {
+		// The final state was dropped while this stream was behind, or the
+		// server is shutting down before the job finished
+		job, err := s.Jobs.Get(req.GetJobId())
+		if err != nil {
+			return toStatus(err)
+		}
+		return stream.Send(eventProto(jobs.Event{JobID: job.ID, Time: time.Now(), Kind: jobs.EventState, Job: &job}))
+	}
+	return nil
+}
+
+// toStatus maps job manager errors to gRPC status codes.
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, jobs.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, jobs.ErrInvalidRequest):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, jobs.ErrKeyConflict):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, jobs.ErrClosed):
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,113 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/layout"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v3"
+)
+
+// LoadTenants reads a JSON or YAML file mapping each tenant to the API
+// tokens that act for it, for Server.Tenants:
+//
+//	acme: ["${ACME_TOKEN}"]
+//	globex: ["token-1", "token-2"]
+//
+// Environment variables are expanded, so tokens needn't be stored in the
+// file. Tenant names must be valid output directory names (see
+// layout.ValidateTenant), and a token may belong to one tenant only.
+func LoadTenants(path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tenant tokens: %w", err)
+	}
+	data = transcoder.ExpandEnv(data)
+
+	var tenants map[string][]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &tenants)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tenants)
+	default:
+		return nil, fmt.Errorf("unsupported tenant token file extension %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tenant tokens %s: %w", path, err)
+	}
+	owner := make(map[string]string)
+	for tenant, tokens := range tenants {
+		if tenant == "" {
+			return nil, fmt.Errorf("tenant tokens %s: empty tenant name", path)
+		}
+		if err := layout.ValidateTenant(tenant); err != nil {
+			return nil, fmt.Errorf("tenant tokens %s: %w", path, err)
+		}
+		for _, token := range tokens {
+			if token == "" {
+				return nil, fmt.Errorf("tenant tokens %s: empty token for %s", path, tenant)
+			}
+			if other, ok := owner[token]; ok && other != tenant {
+				return nil, fmt.Errorf("tenant tokens %s: a token is listed for both %s and %s", path, other, tenant)
+			}
+			owner[token] = tenant
+		}
+	}
+	return tenants, nil
+}
+
+// authenticate returns the tenant whose token the call's "authorization:
+// Bearer <token>" metadata carries. A TenantHeader sent alongside must name
+// the same tenant.
+func (s *Server) authenticate(md metadata.MD) (string, error) {
+	token, ok := strings.CutPrefix(first(md.Get("authorization")), "Bearer ")
+	tenant := ""
+	if ok && token != "" {
+		tenant = s.tokenTenant(token)
+	}
+	if tenant == "" {
+		return "", status.Error(codes.Unauthenticated, "missing or unknown bearer token")
+	}
+	if header := first(md.Get(TenantHeader)); header != "" && header != tenant {
+		return "", status.Errorf(codes.PermissionDenied, "token does not act for tenant %q", header)
+	}
+	return tenant, nil
+}
+
+// tokenTenant returns the tenant token belongs to, or "". Every token is
+// compared in constant time, so response times don't reveal near misses.
+func (s *Server) tokenTenant(token string) string {
+	found := ""
+	for tenant, tokens := range s.Tenants {
+		for _, t := range tokens {
+			if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+				found = tenant
+			}
+		}
+	}
+	return found
+}
+
+// first returns the first of values, or "".
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// incoming returns the call's metadata, empty when it has none.
+func incoming(ctx context.Context) metadata.MD {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return md
+}
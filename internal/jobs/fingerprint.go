@@ -0,0 +1,36 @@
+package jobs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+)
+
+// Fingerprint identifies a job by what it would produce: a hex SHA-256 over
+// the source content (sampled, see analyzer.ContentHash), the resolved
+// profile (input and output paths included), the stream format, and the
+// skipped stages. Resubmitting the same file with the same settings matches;
+// replacing the file at that path or changing any setting doesn't. When the
+// input can't be read only its path stands in for the content.
+func Fingerprint(profile *transcoder.TranscodeProfile, format string, skip []string) (string, error) {
+	source, err := analyzer.ContentHash(profile.InputPath)
+	if err != nil {
+		source = "path:" + profile.InputPath
+	}
+	settings, err := json.Marshal(profile)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode profile: %w", err)
+	}
+	skip = slices.Clone(skip)
+	slices.Sort(skip)
+
+	h := sha256.New()
+	fmt.Fprintf(h, "source=%s\nformat=%s\nskip=%v\nprofile=", source, format, skip)
+	h.Write(settings)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,137 @@
+// Package jobs runs pipeline jobs in the background for long-lived services
+// such as the gRPC API. A Manager queues submitted jobs, runs a bounded number
+// at a time, lets callers cancel them, and publishes stage transitions and
+// encode progress to any number of watchers. Jobs are recorded in a Store, so
+// with a persistent store (see package sqlitestore) history survives restarts.
+package jobs
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/errclass"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/pipeline"
+)
+
+// Errors returned by Manager; match with errors.Is.
+var (
+	ErrNotFound       = errors.New("job not found")
+	ErrInvalidRequest = errclass.New(errclass.InvalidConfig, "invalid job request")
+	ErrClosed         = errors.New("job manager is shut down")
+	ErrKeyConflict    = errors.New("idempotency key already used for a different request")
+)
+
+// SkippableStages are the stages a Request may skip: optional outputs no
+// other stage reads. Skipping anything else (e.g. pipeline.StageEncrypt,
+// which would publish clear content) is rejected with ErrInvalidRequest.
+var SkippableStages = []string{
+	pipeline.StageThumbnail,
+	pipeline.StagePreview,
+	pipeline.StageSubtitle,
+	pipeline.StageAudio,
+	pipeline.StageProgressive,
+	pipeline.StageVerify,
+	pipeline.StageMetadata,
+	pipeline.StageChecksum,
+}
+
+// State is the lifecycle position of a job.
+type State string
+
+// Job states. Queued, Running, and Paused jobs may still change; the rest are final.
+const (
+	Queued    State = "queued"
+	Running   State = "running"
+	Paused    State = "paused" // Preempted by higher-priority work; resumes when a slot frees up
+	Succeeded State = "succeeded"
+	Failed    State = "failed"
+	Cancelled State = "cancelled"
+)
+
+// Terminal reports whether s is a final state.
+func (s State) Terminal() bool {
+	return s == Succeeded || s == Failed || s == Cancelled
+}
+
+// Request describes a pipeline run to queue.
+type Request struct {
+	ProfilePath string   // Profile file path or bare filename under profiles/
+	Profile     []byte   // Inline JSON or YAML profile; used instead of ProfilePath when set
+	Overlays    []string // Overlay profile files merged on top, in order
+	Format      string   // "hls" or "dash"; defaults to the profile's stream_format
+	SkipStages  []string // Stage names to skip, from SkippableStages (e.g. pipeline.StageThumbnail)
+	Priority    Priority // Queue order and preemption class (default PriorityNormal)
+
+	// Tenant namespaces the job (see TranscodeProfile.Tenant), overriding the
+	// profile's own tenant when set. Idempotency keys and deduplication are
+	// scoped to it, and Filter.Tenant lists one tenant's jobs.
+	Tenant string
+
+	// IdempotencyKey is a client-chosen key (e.g. a CMS asset revision). A
+	// repeat submission with the same key returns the original job, whatever
+	// its state, instead of queuing another.
+	IdempotencyKey string
+	// Force queues a new job even when an identical one is queued, running,
+	// or already succeeded (see Fingerprint). Idempotency keys still apply.
+	Force bool
+}
+
+// Job is a snapshot of a submitted run.
+type Job struct {
+	ID        string
+	State     State
+	Request   Request
+	Profile   *transcoder.TranscodeProfile // Resolved profile the job runs with
+	Tenant    string                       // Tenant the job belongs to, "" for none
+	InputPath string                       // Source media path from the profile
+	Slug      string                       // Output slug
+	Hash      string                       // Fingerprint of source and settings, for deduplication
+	Stage     string                       // Stage currently running, or the last one that ran
+	Stages    []StageTiming                // Every finished stage, in order
+	Percent   float64                      // Progress across every pipeline stage, 0-100
+	Error     string                       // Failure or cancellation reason
+	Class     errclass.Class               // Kind of failure, for retry decisions; "" unless failed or cancelled
+	Retries   int                          // Times the job was queued again after a retryable failure
+	Warnings  []string                     // Non-fatal stage errors from the report
+	Created   time.Time                    // When the job was submitted
+	Started   time.Time                    // When a runner picked it up; zero while queued
+	Finished  time.Time                    // When it reached a terminal state
+	Report    *pipeline.Report             // Pipeline report, once the job has succeeded
+}
+
+// StageTiming records how one stage of a job went.
+type StageTiming struct {
+	Stage   string
+	Started time.Time
+	Elapsed time.Duration
+	Err     string // Stage error, "" on success
+}
+
+// EventKind tells which field of an Event is set.
+type EventKind string
+
+// Event kinds.
+const (
+	EventState    EventKind = "state"    // Job snapshot after a state change
+	EventStage    EventKind = "stage"    // A stage started or finished
+	EventProgress EventKind = "progress" // Encode progress sample
+)
+
+// Event is a job update delivered to watchers.
+type Event struct {
+	JobID    string
+	Time     time.Time
+	Kind     EventKind
+	Job      *Job                    // Set for EventState
+	Stage    *StageChange            // Set for EventStage
+	Progress *pipeline.ProgressEvent // Set for EventProgress
+}
+
+// StageChange marks a stage boundary.
+type StageChange struct {
+	Stage    string        // One of the pipeline.Stage* names
+	Finished bool          // False when the stage starts, true when it ends
+	Elapsed  time.Duration // Stage wall time (finished only)
+	Err      string        // Stage error (finished only)
+}
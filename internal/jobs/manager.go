@@ -0,0 +1,958 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/errclass"
+	"github.com/dotsoulja/dotgo-transcode/internal/executil"
+	"github.com/dotsoulja/dotgo-transcode/internal/notify"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/layout"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
+	"github.com/dotsoulja/dotgo-transcode/pipeline"
+)
+
+// watchBuffer is how many events a watcher may fall behind before progress
+// and stage events are dropped for it.
+const watchBuffer = 64
+
+// defaultRetryDelay is the wait before a job's first retry.
+const defaultRetryDelay = 10 * time.Second
+
+// maxRetryDelay caps the wait between retries.
+const maxRetryDelay = 10 * time.Minute
+
+// notifyTimeout bounds how long delivering one job's notifications may take.
+const notifyTimeout = 30 * time.Second
+
+// Options configures a Manager.
+type Options struct {
+	Concurrency int               // Jobs run at once (default 1)
+	Preempt     bool              // Pause lower-priority running jobs for higher-priority queued ones (Unix only)
+	Store       Store             // Job persistence (default: in memory, lost on exit)
+	Logger      stagelog.Logger   // Store failures and resumed jobs (default stagelog.Std)
+	Notifier    notify.Notifier   // Told about failed and finished jobs (see notify.LoadConfig); nil for none
+	Retries     int               // Times a job failing with a retryable error (see errclass) is queued again (default 0)
+	RetryDelay  time.Duration     // Wait before the first retry, doubling for each later one (default 10s)
+	Root        string            // If set, profile files, inputs, and outputs of submitted jobs must lie under this directory
+	Pipeline    []pipeline.Option // Applied to every run (e.g. pipeline.WithLogOptions)
+}
+
+// Manager queues and runs pipeline jobs, recording each in its Store.
+type Manager struct {
+	opts        []pipeline.Option
+	store       Store
+	logger      stagelog.Logger
+	notifier    notify.Notifier
+	retries     int
+	retryDelay  time.Duration
+	root        string
+	concurrency int
+	preempt     bool
+
+	ctx  context.Context
+	stop context.CancelFunc
+	wg   sync.WaitGroup
+
+	// Snapshots are written by saveLoop, outside mu, so a slow store doesn't
+	// stall every call that takes the lock
+	saveMu   sync.Mutex
+	unsaved  map[string]Job // Latest snapshot of each job not yet written
+	saveWake chan struct{}
+	saveStop chan struct{}
+	saveDone chan struct{}
+
+	mu       sync.Mutex
+	closed   bool
+	draining bool              // Shutdown in progress: no new jobs start
+	jobs     map[string]*entry // Jobs queued or run by this process
+	pending  []*entry          // Queued jobs, highest priority first, then oldest
+	active   int               // Running jobs holding a slot (paused ones don't)
+}
+
+// entry is a job and its run state, guarded by Manager.mu.
+type entry struct {
+	job      Job
+	cancel   context.CancelFunc // Cancels the run; nil until it starts
+	pauser   *executil.Pauser   // Suspends the run's ffmpeg processes when preempted
+	watchers map[chan Event]struct{}
+
+	interrupted bool        // Cancelled by Shutdown; stays unfinished to resume later
+	retry       *time.Timer // Queues the job again once its retry delay passes; nil unless waiting
+}
+
+// NewManager starts a manager with an in-memory store running up to
+// concurrency jobs at once. opts apply to every run.
+func NewManager(concurrency int, opts ...pipeline.Option) *Manager {
+	m, _ := NewManagerWithOptions(Options{Concurrency: concurrency, Pipeline: opts})
+	return m
+}
+
+// NewManagerWithOptions starts a manager. Jobs the store holds as queued,
+// running, or paused (the previous process stopped before they finished) are
+// queued again by priority, oldest first.
+func NewManagerWithOptions(o Options) (*Manager, error) {
+	if o.Concurrency < 1 {
+		o.Concurrency = 1
+	}
+	if o.Store == nil {
+		o.Store = NewMemoryStore()
+	}
+	if o.RetryDelay <= 0 {
+		o.RetryDelay = defaultRetryDelay
+	}
+	if o.Root != "" {
+		root, err := resolvePath(o.Root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve root: %w", err)
+		}
+		o.Root = root
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	m := &Manager{
+		opts:        o.Pipeline,
+		store:       o.Store,
+		logger:      stagelog.OrStd(o.Logger),
+		notifier:    o.Notifier,
+		retries:     max(o.Retries, 0),
+		retryDelay:  o.RetryDelay,
+		root:        o.Root,
+		concurrency: o.Concurrency,
+		preempt:     o.Preempt,
+		ctx:         ctx,
+		stop:        stop,
+		jobs:        make(map[string]*entry),
+		unsaved:     make(map[string]Job),
+		saveWake:    make(chan struct{}, 1),
+		saveStop:    make(chan struct{}),
+		saveDone:    make(chan struct{}),
+	}
+	go m.saveLoop()
+	if m.preempt && !executil.CanPause() {
+		m.logger.LogStage("jobs", "⚠️ Preemption needs job control signals, unavailable on this platform; priorities only order the queue")
+		m.preempt = false
+	}
+
+	unfinished, err := m.store.List(ctx, Filter{States: []State{Queued, Running, Paused}})
+	if err != nil {
+		stop()
+		close(m.saveStop)
+		return nil, fmt.Errorf("failed to load unfinished jobs: %w", err)
+	}
+	for i := len(unfinished) - 1; i >= 0; i-- {
+		job := unfinished[i]
+		if job.Profile == nil {
+			continue
+		}
+		// Start over; stages of the interrupted attempt are discarded
+		job.State, job.Stage, job.Percent, job.Started = Queued, "", 0, time.Time{}
+		job.Stages, job.Warnings, job.Error, job.Class = nil, nil, "", ""
+		e := &entry{job: job, watchers: make(map[chan Event]struct{})}
+		m.jobs[job.ID] = e
+		m.enqueueLocked(e)
+		m.saveLocked(e)
+	}
+	if len(m.pending) > 0 {
+		m.logger.LogStage("jobs", fmt.Sprintf("♻️ Resuming %d unfinished job(s)", len(m.pending)))
+	}
+
+	m.mu.Lock()
+	m.dispatchLocked()
+	m.mu.Unlock()
+	return m, nil
+}
+
+// Submit loads and validates the request's profile and queues the job.
+// Profile errors, stages outside SkippableStages, and (with Options.Root)
+// paths outside the root, including profiles named by "extends", are
+// returned immediately, wrapped in ErrInvalidRequest.
+//
+// Duplicate submissions attach to the earlier job instead, reported by
+// existing: a request reusing an idempotency key within its tenant gets that
+// key's job (or ErrKeyConflict if the source or settings differ), and, unless req.Force is
+// set, a request whose Fingerprint matches a queued, running, paused, or
+// succeeded job gets that job. A queued job attached to by a higher-priority
+// request is promoted to that priority.
+func (m *Manager) Submit(req Request) (job Job, existing bool, err error) {
+	if err := checkSkipStages(req.SkipStages); err != nil {
+		return Job{}, false, fmt.Errorf("%w: skip_stages: %v", ErrInvalidRequest, err)
+	}
+	if filepath.Ext(req.ProfilePath) != "" {
+		// Names without an extension are built-in presets
+		if err := m.checkPath("profile", req.ProfilePath); err != nil {
+			return Job{}, false, err
+		}
+	}
+	for _, overlay := range req.Overlays {
+		if err := m.checkPath("overlay", overlay); err != nil {
+			return Job{}, false, err
+		}
+	}
+	profile, err := m.loadProfile(req)
+	if err != nil {
+		return Job{}, false, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+	if req.Tenant != "" {
+		if err := layout.ValidateTenant(req.Tenant); err != nil {
+			return Job{}, false, fmt.Errorf("%w: tenant: %v", ErrInvalidRequest, err)
+		}
+		profile.Tenant = req.Tenant
+	}
+	req.Tenant = profile.Tenant
+	if err := m.checkPath("input", profile.InputPath); err != nil {
+		return Job{}, false, err
+	}
+	if err := m.checkPath("output", profile.SlugDir()); err != nil {
+		return Job{}, false, err
+	}
+	if req.Format == "" {
+		req.Format = profile.DeliveryFormat()
+	}
+	if req.Format != "hls" && req.Format != "dash" {
+		return Job{}, false, fmt.Errorf("%w: unsupported stream format %q", ErrInvalidRequest, req.Format)
+	}
+	hash, err := Fingerprint(profile, req.Format, req.SkipStages)
+	if err != nil {
+		return Job{}, false, err
+	}
+
+	now := time.Now()
+	slug := profile.OutputSlug()
+	e := &entry{
+		job: Job{
+			ID:        fmt.Sprintf("%s-%d", slug, now.UnixNano()),
+			State:     Queued,
+			Request:   req,
+			Profile:   profile,
+			Tenant:    profile.Tenant,
+			InputPath: profile.InputPath,
+			Slug:      slug,
+			Hash:      hash,
+			Created:   now,
+		},
+		watchers: make(map[chan Event]struct{}),
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed || m.draining {
+		return Job{}, false, ErrClosed
+	}
+	if dup, ok, err := m.duplicateLocked(req, hash); err != nil || ok {
+		if ok {
+			m.logger.LogStage("jobs", fmt.Sprintf("🔁 Duplicate submission for %s attached to %s (%s)", profile.InputPath, dup.ID, dup.State))
+			dup = m.promoteLocked(dup, req.Priority)
+		}
+		return dup, ok, err
+	}
+	if err := m.store.Put(m.ctx, e.job); err != nil {
+		return Job{}, false, fmt.Errorf("failed to record job: %w", err)
+	}
+	m.jobs[e.job.ID] = e
+	m.enqueueLocked(e)
+	m.dispatchLocked()
+	return e.job, false, nil
+}
+
+// duplicateLocked finds the earlier job a request should attach to.
+func (m *Manager) duplicateLocked(req Request, hash string) (Job, bool, error) {
+	if req.IdempotencyKey != "" {
+		list, err := m.store.List(m.ctx, Filter{Key: req.IdempotencyKey, Tenant: req.Tenant, Limit: 1})
+		if err != nil {
+			return Job{}, false, err
+		}
+		if len(list) > 0 {
+			if list[0].Hash != hash {
+				return Job{}, false, fmt.Errorf("%w: %q was used for job %s", ErrKeyConflict, req.IdempotencyKey, list[0].ID)
+			}
+			return m.liveLocked(list[0]), true, nil
+		}
+	}
+	if req.Force {
+		return Job{}, false, nil
+	}
+	list, err := m.store.List(m.ctx, Filter{Hash: hash, States: []State{Queued, Running, Paused, Succeeded}, Limit: 1})
+	if err != nil || len(list) == 0 {
+		return Job{}, false, err
+	}
+	return m.liveLocked(list[0]), true, nil
+}
+
+// promoteLocked raises queued job dup to priority p if it is lower, so a
+// duplicate high-priority submission doesn't wait behind back-catalog work.
+// Returns the job's current snapshot, or dup unchanged when it only exists in
+// the store.
+func (m *Manager) promoteLocked(dup Job, p Priority) Job {
+	e, ok := m.jobs[dup.ID]
+	if !ok {
+		return dup
+	}
+	if e.job.State == Queued && e.job.Request.Priority < p {
+		e.job.Request.Priority = p
+		m.removePending(e)
+		m.enqueueLocked(e)
+		m.saveLocked(e)
+		m.dispatchLocked()
+	}
+	return e.job
+}
+
+// liveLocked returns the in-process snapshot of a stored job when there is one.
+func (m *Manager) liveLocked(job Job) Job {
+	if e, ok := m.jobs[job.ID]; ok {
+		return e.job
+	}
+	return job
+}
+
+// checkSkipStages rejects stages outside SkippableStages and skip lists that
+// break a stage's prerequisites.
+func checkSkipStages(names []string) error {
+	for _, name := range names {
+		if !slices.Contains(SkippableStages, name) {
+			return fmt.Errorf("stage %q cannot be skipped", name)
+		}
+	}
+	return pipeline.CheckSkipStages(names)
+}
+
+// checkPath returns an error wrapping ErrInvalidRequest when the Options.Root
+// is set and path, after resolving symlinks, lies outside it.
+func (m *Manager) checkPath(what, path string) error {
+	if err := m.underRoot(path); err != nil {
+		return fmt.Errorf("%w: %s %v", ErrInvalidRequest, what, err)
+	}
+	return nil
+}
+
+// underRoot returns an error when the Options.Root is set and path, after
+// resolving symlinks, lies outside it.
+func (m *Manager) underRoot(path string) error {
+	if m.root == "" {
+		return nil
+	}
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	rel, err := filepath.Rel(m.root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s is outside %s", path, m.root)
+	}
+	return nil
+}
+
+// resolvePath returns path made absolute with symlinks resolved. Components
+// that don't exist yet (e.g. an output directory) are kept as written under
+// their nearest existing parent.
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rest := ""
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(resolved, rest), nil
+		}
+		if filepath.Dir(dir) == dir {
+			return abs, nil
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+	}
+}
+
+// loadProfile reads the inline profile or the profile file named by req.
+// With Options.Root set, every file read (including "extends" targets) must
+// lie under the root.
+func (m *Manager) loadProfile(req Request) (*transcoder.TranscodeProfile, error) {
+	switch {
+	case len(req.Profile) > 0:
+		return transcoder.ParseProfileChecked(req.Profile, m.underRoot, req.Overlays...)
+	case req.ProfilePath == "":
+		return nil, errors.New("no profile given")
+	case req.ProfilePath == transcoder.StdinProfile:
+		return nil, errors.New("profiles cannot be read from the server's stdin")
+	default:
+		return transcoder.LoadProfileLayersChecked(req.ProfilePath, m.underRoot, req.Overlays...)
+	}
+}
+
+// Get returns the current state of job id, live for jobs this process ran
+// and from the store otherwise.
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	e, ok := m.jobs[id]
+	var job Job
+	if ok {
+		job = e.job
+	}
+	m.mu.Unlock()
+	if ok {
+		return job, nil
+	}
+	return m.store.Get(m.ctx, id)
+}
+
+// List returns the job history matching filter, newest first. Jobs this
+// process is running carry live stage and progress.
+func (m *Manager) List(filter Filter) ([]Job, error) {
+	list, err := m.store.List(m.ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, job := range list {
+		list[i] = m.liveLocked(job)
+	}
+	return list, nil
+}
+
+// Cancel stops job id. Queued jobs are cancelled at once; running and paused
+// jobs are interrupted (killing any ffmpeg process) and reach Cancelled when
+// their pipeline returns. Cancelling a finished job returns it unchanged.
+func (m *Manager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.jobs[id]
+	if !ok {
+		return m.store.Get(m.ctx, id)
+	}
+	switch e.job.State {
+	case Queued:
+		m.removePending(e)
+		if e.retry != nil {
+			e.retry.Stop()
+			e.retry = nil
+		}
+		e.job.Class = errclass.Cancelled
+		m.finishLocked(e, Cancelled, "cancelled before start", nil)
+	case Running, Paused:
+		e.cancel()
+	}
+	return e.job, nil
+}
+
+// Watch returns a channel delivering the job's current state, then every
+// update until the job finishes or ctx is done, after which it is closed.
+// Progress and stage events are dropped while the receiver is behind; the
+// final state is always delivered unless the receiver is far behind, so
+// callers should Get the job after the channel closes if they need it.
+func (m *Manager) Watch(ctx context.Context, id string) (<-chan Event, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ch := make(chan Event, watchBuffer)
+	e, ok := m.jobs[id]
+	if !ok {
+		// Finished before this process started
+		job, err := m.store.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		ch <- stateEvent(job)
+		close(ch)
+		return ch, nil
+	}
+
+	ch <- stateEvent(e.job)
+	if e.job.State.Terminal() {
+		close(ch)
+		return ch, nil
+	}
+	e.watchers[ch] = struct{}{}
+	go func() {
+		<-ctx.Done()
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if _, ok := e.watchers[ch]; ok {
+			delete(e.watchers, ch)
+			close(ch)
+		}
+	}()
+	return ch, nil
+}
+
+// Close stops accepting jobs, interrupts running ones, waits for the runners
+// to return, and closes the store (see Shutdown to let running jobs finish). Interrupted and still queued jobs stay
+// recorded as unfinished, so a manager on the same store resumes them.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	m.mu.Unlock()
+
+	m.stop()
+	m.wg.Wait()
+
+	// Release watchers of jobs that will never run in this process
+	m.mu.Lock()
+	for _, e := range m.jobs {
+		for ch := range e.watchers {
+			close(ch)
+		}
+		e.watchers = nil
+	}
+	m.mu.Unlock()
+
+	close(m.saveStop)
+	<-m.saveDone
+	return m.store.Close()
+}
+
+// Shutdown drains the manager for a clean exit: it stops accepting and
+// starting jobs, interrupts paused ones, and lets running jobs finish until
+// ctx is done, when the rest are interrupted. It then closes like Close.
+// Queued and interrupted jobs stay recorded as unfinished, so a manager on
+// the same store resumes them; with the in-memory store they are lost.
+// Calling Shutdown again while draining interrupts running jobs at once.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	if m.closed || m.draining {
+		m.mu.Unlock()
+		return m.Close()
+	}
+	m.draining = true
+	running := 0
+	for _, e := range m.jobs {
+		switch e.job.State {
+		case Paused:
+			// Resuming would exceed the slots; start over on the next run
+			e.interrupted = true
+			e.cancel()
+		case Running:
+			running++
+		}
+	}
+	if running > 0 {
+		m.logger.LogStage("jobs", fmt.Sprintf("🚰 Draining %d running job(s)", running))
+	}
+	if len(m.pending) > 0 {
+		m.logger.LogStage("jobs", fmt.Sprintf("📋 %d queued job(s) left for the next start", len(m.pending)))
+	}
+	m.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		m.logger.LogStage("jobs", "⏱️ Drain deadline passed; interrupting running jobs")
+	}
+	return m.Close()
+}
+
+// enqueueLocked inserts e into the pending list behind every job of equal
+// or higher priority.
+func (m *Manager) enqueueLocked(e *entry) {
+	i := len(m.pending)
+	for i > 0 && m.pending[i-1].job.Request.Priority < e.job.Request.Priority {
+		i--
+	}
+	m.pending = slices.Insert(m.pending, i, e)
+}
+
+// dispatchLocked fills free slots, resuming paused jobs ahead of queued ones
+// of no higher priority. With preemption enabled, while every slot is busy
+// and the head of the queue outranks a running job, that job is paused to
+// make room.
+func (m *Manager) dispatchLocked() {
+	for !m.closed && !m.draining {
+		var next *entry
+		if len(m.pending) > 0 {
+			next = m.pending[0]
+		}
+		if m.active < m.concurrency {
+			if p := m.pausedLocked(); p != nil && (next == nil || p.job.Request.Priority >= next.job.Request.Priority) {
+				m.resumeLocked(p)
+				continue
+			}
+			if next == nil {
+				return
+			}
+			m.pending = m.pending[1:]
+			m.startLocked(next)
+			continue
+		}
+		if !m.preempt || next == nil {
+			return
+		}
+		victim := m.victimLocked(next.job.Request.Priority)
+		if victim == nil {
+			return
+		}
+		m.pauseLocked(victim, next)
+	}
+}
+
+// pausedLocked returns the paused job to resume first: highest priority, then oldest.
+func (m *Manager) pausedLocked() *entry {
+	var best *entry
+	for _, e := range m.jobs {
+		if e.job.State != Paused {
+			continue
+		}
+		if best == nil || e.job.Request.Priority > best.job.Request.Priority ||
+			(e.job.Request.Priority == best.job.Request.Priority && e.job.Created.Before(best.job.Created)) {
+			best = e
+		}
+	}
+	return best
+}
+
+// victimLocked returns the running job to preempt for work at priority p:
+// the lowest-priority one below p, and among those the most recently
+// started, so older jobs finish first.
+func (m *Manager) victimLocked(p Priority) *entry {
+	var victim *entry
+	for _, e := range m.jobs {
+		if e.job.State != Running || e.job.Request.Priority >= p {
+			continue
+		}
+		if victim == nil || e.job.Request.Priority < victim.job.Request.Priority ||
+			(e.job.Request.Priority == victim.job.Request.Priority && e.job.Started.After(victim.job.Started)) {
+			victim = e
+		}
+	}
+	return victim
+}
+
+// pauseLocked suspends running job e to free its slot for waiting.
+func (m *Manager) pauseLocked(e, waiting *entry) {
+	if err := e.pauser.Pause(); err != nil {
+		// Usually a process that exited between stages; the rest are stopped
+		m.logger.LogError("jobs", fmt.Errorf("pausing %s: %w", e.job.ID, err))
+	}
+	m.active--
+	m.logger.LogStage("jobs", fmt.Sprintf("⏸️ Paused %s (%s priority) for %s (%s priority)",
+		e.job.ID, e.job.Request.Priority, waiting.job.ID, waiting.job.Request.Priority))
+	m.setStateLocked(e, Paused)
+}
+
+// resumeLocked continues paused job e in a free slot.
+func (m *Manager) resumeLocked(e *entry) {
+	if err := e.pauser.Resume(); err != nil {
+		m.logger.LogError("jobs", fmt.Errorf("resuming %s: %w", e.job.ID, err))
+	}
+	m.active++
+	m.logger.LogStage("jobs", fmt.Sprintf("▶️ Resumed %s", e.job.ID))
+	m.setStateLocked(e, Running)
+}
+
+// setStateLocked records a non-terminal state change and tells watchers.
+func (m *Manager) setStateLocked(e *entry, state State) {
+	e.job.State = state
+	m.saveLocked(e)
+	m.publishLocked(e, stateEvent(e.job))
+}
+
+// startLocked takes a slot and runs e in the background.
+func (m *Manager) startLocked(e *entry) {
+	ctx, cancel := context.WithCancel(m.ctx)
+	e.cancel = cancel
+	e.pauser = executil.NewPauser()
+	e.job.Started = time.Now()
+	m.active++
+	m.setStateLocked(e, Running)
+	m.wg.Add(1)
+	go m.run(executil.WithPauser(ctx, e.pauser), e)
+}
+
+// run executes one job's pipeline, records the outcome, and hands its slot on.
+func (m *Manager) run(ctx context.Context, e *entry) {
+	defer m.wg.Done()
+	defer e.cancel()
+
+	m.mu.Lock()
+	config := pipeline.Config{
+		Profile:      e.job.Profile,
+		StreamFormat: e.job.Request.Format,
+		SkipStages:   e.job.Request.SkipStages,
+	}
+	m.mu.Unlock()
+
+	opts := append([]pipeline.Option{}, m.opts...)
+	opts = append(opts,
+		pipeline.WithBeforeStage(func(_ context.Context, ev pipeline.StageEvent) error {
+			m.stageChanged(e, StageChange{Stage: ev.Stage})
+			return nil
+		}),
+		pipeline.WithAfterStage(func(_ context.Context, ev pipeline.StageEvent) error {
+			change := StageChange{Stage: ev.Stage, Finished: true, Elapsed: ev.Elapsed}
+			if ev.Err != nil {
+				change.Err = ev.Err.Error()
+			}
+			m.stageChanged(e, change)
+			return nil
+		}),
+		pipeline.WithProgress(func(ev pipeline.ProgressEvent) {
+			m.progressed(e, ev)
+		}),
+		pipeline.WithJobProgress(func(p pipeline.JobProgress) {
+			m.mu.Lock()
+			e.job.Percent = p.Percent
+			m.mu.Unlock()
+		}),
+	)
+	report, err := runPipeline(ctx, config, opts)
+
+	m.mu.Lock()
+	if e.job.State == Running {
+		m.active--
+	}
+	switch {
+	case err == nil:
+		m.finishLocked(e, Succeeded, "", report)
+	case m.ctx.Err() != nil || e.interrupted:
+		// Shutting down: leave the job recorded as running so it resumes
+		m.logger.LogStage("jobs", fmt.Sprintf("⏸️ Interrupted %s by shutdown", e.job.ID))
+	case ctx.Err() != nil:
+		e.job.Class = errclass.Cancelled
+		m.finishLocked(e, Cancelled, err.Error(), nil)
+	case errclass.IsRetryable(err) && e.job.Retries < m.retries:
+		m.retryLocked(e, err)
+	default:
+		e.job.Class = errclass.Classify(err)
+		m.finishLocked(e, Failed, err.Error(), nil)
+	}
+	m.dispatchLocked()
+	job := e.job
+	m.mu.Unlock()
+
+	// Delivered after the slot is handed on, so slow sinks don't hold up the
+	// queue; the runner stays counted until they return, so Close waits
+	m.notify(job)
+}
+
+// runPipeline runs one job's pipeline, turning a panic into an error so a
+// bad job fails on its own instead of taking down every other job.
+func runPipeline(ctx context.Context, config pipeline.Config, opts []pipeline.Option) (report *pipeline.Report, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			report, err = nil, fmt.Errorf("pipeline panicked: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return pipeline.RunContext(ctx, config, opts...)
+}
+
+// notify tells the notifier how a finished job went. Cancelled and
+// interrupted jobs aren't reported. Delivery failures are logged.
+func (m *Manager) notify(job Job) {
+	if m.notifier == nil {
+		return
+	}
+	n := notify.Notification{
+		JobID:     job.ID,
+		Tenant:    job.Tenant,
+		InputPath: job.InputPath,
+		Slug:      job.Slug,
+		Error:     job.Error,
+		Class:     job.Class,
+		Warnings:  job.Warnings,
+		Elapsed:   job.Finished.Sub(job.Started),
+		Time:      job.Finished,
+	}
+	switch {
+	case job.State == Failed:
+		n.Kind = notify.JobFailed
+	case job.State == Succeeded && len(job.Warnings) > 0:
+		n.Kind = notify.JobWarnings
+	case job.State == Succeeded:
+		n.Kind = notify.JobSucceeded
+	default:
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	if err := m.notifier.Notify(ctx, n); err != nil {
+		m.logger.LogError("jobs", fmt.Errorf("failed to send notifications for job %s: %w", job.ID, err))
+	}
+}
+
+// retryLocked queues e again after a retryable failure, once a delay that
+// doubles with each retry has passed; the job is recorded as queued while it
+// waits. Stages of the failed attempt are discarded, as when resuming after a
+// restart.
+func (m *Manager) retryLocked(e *entry, err error) {
+	e.job.Retries++
+	delay := retryDelay(m.retryDelay, e.job.Retries)
+	m.logger.LogStage("jobs", fmt.Sprintf("🔁 Retrying %s (%d/%d) in %s after %s failure: %v", e.job.ID, e.job.Retries, m.retries, delay.Round(time.Second), errclass.Classify(err), err))
+	e.job.Stage, e.job.Percent, e.job.Started = "", 0, time.Time{}
+	e.job.Stages, e.job.Warnings = nil, nil
+	m.setStateLocked(e, Queued)
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if e.retry != timer || m.closed {
+			// Cancelled while waiting, or left queued for the next start
+			return
+		}
+		e.retry = nil
+		m.enqueueLocked(e)
+		m.dispatchLocked()
+	})
+	e.retry = timer
+}
+
+// retryDelay returns the wait before retry n: base doubled for each earlier
+// retry, capped at maxRetryDelay, with up to half of it taken off at random so
+// jobs failing together don't all come back at once.
+func retryDelay(base time.Duration, n int) time.Duration {
+	d := maxRetryDelay
+	if shift := n - 1; shift < 32 && base<<shift > 0 && base<<shift < maxRetryDelay {
+		d = base << shift
+	}
+	return d - rand.N(d/2+1)
+}
+
+// stageChanged records a stage boundary, persisting finished stages.
+func (m *Manager) stageChanged(e *entry, change StageChange) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e.job.Stage = change.Stage
+	if change.Finished {
+		e.job.Stages = append(e.job.Stages, StageTiming{
+			Stage:   change.Stage,
+			Started: time.Now().Add(-change.Elapsed),
+			Elapsed: change.Elapsed,
+			Err:     change.Err,
+		})
+		m.saveLocked(e)
+	}
+	m.publishLocked(e, Event{JobID: e.job.ID, Time: time.Now(), Kind: EventStage, Stage: &change})
+}
+
+// progressed publishes every encode progress sample.
+func (m *Manager) progressed(e *entry, ev pipeline.ProgressEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.publishLocked(e, Event{JobID: e.job.ID, Time: time.Now(), Kind: EventProgress, Progress: &ev})
+}
+
+// finishLocked moves e to a terminal state, persists it, delivers the final
+// snapshot, and closes its watchers.
+func (m *Manager) finishLocked(e *entry, state State, msg string, report *pipeline.Report) {
+	e.job.State = state
+	e.job.Error = msg
+	e.job.Report = report
+	e.job.Finished = time.Now()
+	if report != nil {
+		for _, err := range report.Errors {
+			e.job.Warnings = append(e.job.Warnings, err.Error())
+		}
+	}
+	m.saveLocked(e)
+	m.publishLocked(e, stateEvent(e.job))
+	for ch := range e.watchers {
+		close(ch)
+	}
+	e.watchers = nil
+}
+
+// saveLocked queues a snapshot of e for saveLoop to write to the store.
+// Snapshots not yet written are replaced, so only the latest is saved.
+func (m *Manager) saveLocked(e *entry) {
+	m.saveMu.Lock()
+	m.unsaved[e.job.ID] = e.job
+	m.saveMu.Unlock()
+	select {
+	case m.saveWake <- struct{}{}:
+	default:
+	}
+}
+
+// Failed snapshot writes are retried after saveRetryMin, doubling up to
+// saveRetryMax while the store keeps failing.
+const (
+	saveRetryMin = time.Second
+	saveRetryMax = time.Minute
+)
+
+// saveLoop writes queued snapshots until Close, then writes the last ones.
+func (m *Manager) saveLoop() {
+	defer close(m.saveDone)
+	var retry <-chan time.Time
+	delay := saveRetryMin
+	for {
+		select {
+		case <-m.saveWake:
+			if retry != nil {
+				continue // Wait out the backoff; the retry writes these too
+			}
+		case <-retry:
+		case <-m.saveStop:
+			m.flushSaves()
+			return
+		}
+		if m.flushSaves() {
+			retry, delay = nil, saveRetryMin
+			continue
+		}
+		retry = time.After(delay)
+		delay = min(delay*2, saveRetryMax)
+	}
+}
+
+// flushSaves writes every queued snapshot and reports whether all of them
+// were written. Failures are logged, not fatal: the job keeps running and a
+// failed snapshot is queued again unless a newer one has replaced it.
+func (m *Manager) flushSaves() bool {
+	m.saveMu.Lock()
+	batch := m.unsaved
+	m.unsaved = make(map[string]Job)
+	m.saveMu.Unlock()
+	ok := true
+	for id, job := range batch {
+		if err := m.store.Put(context.Background(), job); err != nil {
+			m.logger.LogError("jobs", fmt.Errorf("failed to record job %s: %w", id, err))
+			m.saveMu.Lock()
+			if _, newer := m.unsaved[id]; !newer {
+				m.unsaved[id] = job
+			}
+			m.saveMu.Unlock()
+			ok = false
+		}
+	}
+	return ok
+}
+
+// publishLocked hands ev to every watcher with room for it.
+func (m *Manager) publishLocked(e *entry, ev Event) {
+	for ch := range e.watchers {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// removePending drops e from the pending list.
+func (m *Manager) removePending(e *entry) {
+	for i, p := range m.pending {
+		if p == e {
+			m.pending = append(m.pending[:i], m.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// stateEvent wraps a job snapshot in an event.
+func stateEvent(job Job) Event {
+	return Event{JobID: job.ID, Time: time.Now(), Kind: EventState, Job: &job}
+}
@@ -0,0 +1,33 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSubmitRejectsInlineExtendsOutsideRoot(t *testing.T) {
+	root, outside := t.TempDir(), t.TempDir()
+	secret := filepath.Join(outside, "secret.yaml")
+	if err := os.WriteFile(secret, []byte("output_dir: ${HOME}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	m, err := NewManagerWithOptions(Options{Root: root})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	doc := fmt.Sprintf(`{"extends": %q, "input_path": %q, "output_dir": %q}`,
+		secret, filepath.Join(root, "in.mp4"), filepath.Join(root, "out"))
+	_, _, err = m.Submit(Request{Profile: []byte(doc)})
+	if !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("Submit error = %v, want ErrInvalidRequest", err)
+	}
+	if !strings.Contains(err.Error(), "outside") {
+		t.Errorf("Submit error = %v, want it to name the path outside the root", err)
+	}
+}
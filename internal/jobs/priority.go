@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Priority orders queued jobs: higher runs first, ties in submission order.
+// With Options.Preempt, a higher-priority job arriving while every slot is
+// busy pauses the lowest-priority running job until a slot frees up.
+type Priority int
+
+// Named priority classes. Any other integer is allowed for finer ordering.
+const (
+	PriorityLow    Priority = -1 // Back catalog and bulk re-encodes
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 1 // New releases and urgent fixes
+)
+
+// String returns the class name, or the number for unnamed priorities.
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	default:
+		return strconv.Itoa(int(p))
+	}
+}
+
+// ParsePriority accepts a class name ("low", "normal", "high") or an integer.
+func ParsePriority(s string) (Priority, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "low":
+		return PriorityLow, nil
+	case "", "normal":
+		return PriorityNormal, nil
+	case "high":
+		return PriorityHigh, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("unknown priority %q (want low, normal, high, or an integer)", s)
+	}
+	return Priority(n), nil
+}
@@ -0,0 +1,346 @@
+// Package sqlitestore is a jobs.Store backed by a SQLite database file, so a
+// job server's history (profiles, stage timings, reports) survives restarts
+// and can be audited later. Requires a cgo-enabled build.
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/errclass"
+	"github.com/dotsoulja/dotgo-transcode/internal/jobs"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/pipeline"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// schema creates the tables on first open. Times are Unix nanoseconds, 0 when unset.
+const schema = `
+CREATE TABLE IF NOT EXISTS jobs (
+	id          TEXT PRIMARY KEY,
+	state       TEXT NOT NULL,
+	input_path  TEXT NOT NULL,
+	slug        TEXT NOT NULL,
+	stage       TEXT NOT NULL DEFAULT '',
+	percent     REAL NOT NULL DEFAULT 0,
+	error       TEXT NOT NULL DEFAULT '',
+	created_at  INTEGER NOT NULL,
+	started_at  INTEGER NOT NULL DEFAULT 0,
+	finished_at INTEGER NOT NULL DEFAULT 0,
+	request     TEXT NOT NULL,
+	profile     TEXT NOT NULL,
+	report      TEXT NOT NULL DEFAULT '',
+	warnings    TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS jobs_created ON jobs (created_at);
+CREATE INDEX IF NOT EXISTS jobs_state ON jobs (state);
+CREATE INDEX IF NOT EXISTS jobs_input ON jobs (input_path);
+CREATE TABLE IF NOT EXISTS job_stages (
+	job_id     TEXT NOT NULL REFERENCES jobs (id) ON DELETE CASCADE,
+	seq        INTEGER NOT NULL,
+	stage      TEXT NOT NULL,
+	started_at INTEGER NOT NULL,
+	elapsed_ns INTEGER NOT NULL,
+	error      TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (job_id, seq)
+);
+`
+
+// migrations upgrade databases created by older versions. Entry i moves the
+// schema from user_version i to i+1; Open applies the missing ones in order.
+var migrations = []string{
+	`ALTER TABLE jobs ADD COLUMN idempotency_key TEXT NOT NULL DEFAULT '';
+	ALTER TABLE jobs ADD COLUMN fingerprint TEXT NOT NULL DEFAULT '';
+	CREATE INDEX IF NOT EXISTS jobs_idempotency_key ON jobs (idempotency_key);
+	CREATE INDEX IF NOT EXISTS jobs_fingerprint ON jobs (fingerprint);`,
+	`ALTER TABLE jobs ADD COLUMN tenant TEXT NOT NULL DEFAULT '';
+	CREATE INDEX IF NOT EXISTS jobs_tenant ON jobs (tenant, created_at);`,
+	`ALTER TABLE jobs ADD COLUMN error_class TEXT NOT NULL DEFAULT '';
+	ALTER TABLE jobs ADD COLUMN retries INTEGER NOT NULL DEFAULT 0;`,
+}
+
+// jobColumns lists the jobs columns in scanJob order.
+const jobColumns = "id, state, input_path, slug, stage, percent, error, created_at, started_at, finished_at, request, profile, report, warnings, idempotency_key, fingerprint, tenant, error_class, retries"
+
+// Store is a jobs.Store in a SQLite database.
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens (creating if needed) the database at path.
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?_busy_timeout=5000&_journal_mode=WAL&_foreign_keys=on")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open job database: %w", err)
+	}
+	// A single connection serializes writers, which SQLite requires anyway
+	db.SetMaxOpenConns(1)
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize job database %s: %w", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+// migrate creates the base schema and applies pending migrations.
+func migrate(db *sql.DB) error {
+	if _, err := db.Exec(schema); err != nil {
+		return err
+	}
+	var version int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Put upserts job and replaces its stage timings.
+func (s *Store) Put(ctx context.Context, job jobs.Job) error {
+	request, err := json.Marshal(job.Request)
+	if err != nil {
+		return err
+	}
+	profile, err := json.Marshal(job.Profile)
+	if err != nil {
+		return err
+	}
+	report, err := encodeReport(job.Report)
+	if err != nil {
+		return err
+	}
+	warnings, err := json.Marshal(job.Warnings)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, `INSERT OR REPLACE INTO jobs (`+jobColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		job.ID, string(job.State), job.InputPath, job.Slug, job.Stage, job.Percent, job.Error,
+		unixNano(job.Created), unixNano(job.Started), unixNano(job.Finished),
+		string(request), string(profile), report, string(warnings), job.Request.IdempotencyKey, job.Hash, job.Tenant, string(job.Class), job.Retries)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM job_stages WHERE job_id = ?`, job.ID); err != nil {
+		return err
+	}
+	for i, st := range job.Stages {
+		_, err := tx.ExecContext(ctx, `INSERT INTO job_stages (job_id, seq, stage, started_at, elapsed_ns, error) VALUES (?, ?, ?, ?, ?, ?)`,
+			job.ID, i, st.Stage, unixNano(st.Started), int64(st.Elapsed), st.Err)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Get returns job id with its stage timings.
+func (s *Store) Get(ctx context.Context, id string) (jobs.Job, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+jobColumns+` FROM jobs WHERE id = ?`, id)
+	job, err := scanJob(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return jobs.Job{}, fmt.Errorf("%w: %s", jobs.ErrNotFound, id)
+	}
+	if err != nil {
+		return jobs.Job{}, err
+	}
+	if job.Stages, err = s.stages(ctx, id); err != nil {
+		return jobs.Job{}, err
+	}
+	return job, nil
+}
+
+// List returns matching jobs with their stage timings, newest first.
+func (s *Store) List(ctx context.Context, filter jobs.Filter) ([]jobs.Job, error) {
+	var where []string
+	var args []any
+	if len(filter.States) > 0 {
+		marks := make([]string, len(filter.States))
+		for i, st := range filter.States {
+			marks[i] = "?"
+			args = append(args, string(st))
+		}
+		where = append(where, "state IN ("+strings.Join(marks, ", ")+")")
+	}
+	if filter.Tenant != "" {
+		where = append(where, "tenant = ?")
+		args = append(args, filter.Tenant)
+	}
+	if filter.InputPath != "" {
+		where = append(where, "input_path = ?")
+		args = append(args, filter.InputPath)
+	}
+	if filter.Key != "" {
+		where = append(where, "idempotency_key = ?")
+		args = append(args, filter.Key)
+	}
+	if filter.Hash != "" {
+		where = append(where, "fingerprint = ?")
+		args = append(args, filter.Hash)
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, filter.Since.UnixNano())
+	}
+	if !filter.Until.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, filter.Until.UnixNano())
+	}
+
+	query := `SELECT ` + jobColumns + ` FROM jobs`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY created_at DESC"
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	var out []jobs.Job
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		out = append(out, job)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range out {
+		if out[i].Stages, err = s.stages(ctx, out[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// stages loads the stage timings of job id in order.
+func (s *Store) stages(ctx context.Context, id string) ([]jobs.StageTiming, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT stage, started_at, elapsed_ns, error FROM job_stages WHERE job_id = ? ORDER BY seq`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []jobs.StageTiming
+	for rows.Next() {
+		var st jobs.StageTiming
+		var started, elapsed int64
+		if err := rows.Scan(&st.Stage, &started, &elapsed, &st.Err); err != nil {
+			return nil, err
+		}
+		st.Started, st.Elapsed = fromUnixNano(started), time.Duration(elapsed)
+		out = append(out, st)
+	}
+	return out, rows.Err()
+}
+
+// scanner is satisfied by *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanJob reads one jobs row selected with jobColumns.
+func scanJob(row scanner) (jobs.Job, error) {
+	var job jobs.Job
+	var state, request, profile, report, warnings, key, class string
+	var created, started, finished int64
+	err := row.Scan(&job.ID, &state, &job.InputPath, &job.Slug, &job.Stage, &job.Percent, &job.Error,
+		&created, &started, &finished, &request, &profile, &report, &warnings, &key, &job.Hash, &job.Tenant, &class, &job.Retries)
+	if err != nil {
+		return job, err
+	}
+	job.State = jobs.State(state)
+	job.Class = errclass.Class(class)
+	job.Created, job.Started, job.Finished = fromUnixNano(created), fromUnixNano(started), fromUnixNano(finished)
+	if err := json.Unmarshal([]byte(request), &job.Request); err != nil {
+		return job, fmt.Errorf("job %s: bad request column: %w", job.ID, err)
+	}
+	job.Request.IdempotencyKey = key
+	if profile != "null" {
+		job.Profile = &transcoder.TranscodeProfile{}
+		if err := json.Unmarshal([]byte(profile), job.Profile); err != nil {
+			return job, fmt.Errorf("job %s: bad profile column: %w", job.ID, err)
+		}
+	}
+	if warnings != "" {
+		if err := json.Unmarshal([]byte(warnings), &job.Warnings); err != nil {
+			return job, fmt.Errorf("job %s: bad warnings column: %w", job.ID, err)
+		}
+	}
+	if report != "" {
+		job.Report = &pipeline.Report{}
+		if err := json.Unmarshal([]byte(report), job.Report); err != nil {
+			return job, fmt.Errorf("job %s: bad report column: %w", job.ID, err)
+		}
+		for _, w := range job.Warnings {
+			job.Report.Errors = append(job.Report.Errors, errors.New(w))
+		}
+	}
+	return job, nil
+}
+
+// encodeReport serializes r without its errors, which are interfaces and
+// don't round-trip through JSON; they are stored as the job's warnings.
+func encodeReport(r *pipeline.Report) (string, error) {
+	if r == nil {
+		return "", nil
+	}
+	c := *r
+	c.Errors = nil
+	data, err := json.Marshal(c)
+	return string(data), err
+}
+
+// unixNano converts t, mapping the zero time to 0.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// fromUnixNano is the inverse of unixNano.
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
@@ -0,0 +1,117 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store persists jobs. The Manager saves a job whenever its state changes or
+// a stage finishes; progress samples are not persisted.
+type Store interface {
+	// Put creates or replaces the stored copy of job.
+	Put(ctx context.Context, job Job) error
+	// Get returns job id, or an error wrapping ErrNotFound.
+	Get(ctx context.Context, id string) (Job, error)
+	// List returns jobs matching filter, newest first.
+	List(ctx context.Context, filter Filter) ([]Job, error)
+	Close() error
+}
+
+// Filter narrows a job history query. Zero fields match everything.
+type Filter struct {
+	States    []State   // Any of these states
+	Tenant    string    // Exact tenant; jobs of every tenant match when empty
+	InputPath string    // Exact source path
+	Key       string    // Exact idempotency key
+	Hash      string    // Exact fingerprint
+	Since     time.Time // Submitted at or after
+	Until     time.Time // Submitted before
+	Limit     int       // Maximum jobs returned (0 for all)
+}
+
+// Match reports whether job passes the filter, ignoring Limit.
+func (f Filter) Match(job Job) bool {
+	if len(f.States) > 0 {
+		found := false
+		for _, s := range f.States {
+			found = found || s == job.State
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Tenant != "" && job.Tenant != f.Tenant {
+		return false
+	}
+	if f.InputPath != "" && job.InputPath != f.InputPath {
+		return false
+	}
+	if f.Key != "" && job.Request.IdempotencyKey != f.Key {
+		return false
+	}
+	if f.Hash != "" && job.Hash != f.Hash {
+		return false
+	}
+	if !f.Since.IsZero() && job.Created.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !job.Created.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// MemoryStore keeps jobs in memory for the life of the process.
+type MemoryStore struct {
+	mu   sync.Mutex
+	jobs map[string]Job
+}
+
+// NewMemoryStore returns an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{jobs: make(map[string]Job)}
+}
+
+// Put stores job.
+func (s *MemoryStore) Put(ctx context.Context, job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.ID] = job
+	return nil
+}
+
+// Get returns job id.
+func (s *MemoryStore) Get(ctx context.Context, id string) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return job, nil
+}
+
+// List returns matching jobs, newest first.
+func (s *MemoryStore) List(ctx context.Context, filter Filter) ([]Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Job
+	for _, job := range s.jobs {
+		if filter.Match(job) {
+			out = append(out, job)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Created.After(out[j].Created) })
+	if filter.Limit > 0 && len(out) > filter.Limit {
+		out = out[:filter.Limit]
+	}
+	return out, nil
+}
+
+// Close is a no-op.
+func (s *MemoryStore) Close() error {
+	return nil
+}
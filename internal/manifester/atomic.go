@@ -0,0 +1,92 @@
+package manifester
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DefaultMasterBackups is how many previous masters are kept when
+// ManifestOptions.Backups is zero.
+const DefaultMasterBackups = 1
+
+// writeMaster replaces the master manifest at path with data atomically,
+// first keeping up to backups previous versions beside it: the last one as
+// <path>.bak, older ones as <path>.bak.1, <path>.bak.2, and so on. Players
+// fetching the master mid-write see either the old or the new one in full.
+func writeMaster(path string, data []byte, backups int) error {
+	if backups > 0 {
+		if err := backupMaster(path, backups); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", filepath.Base(path), err)
+		}
+	}
+	return writeFileAtomic(path, data)
+}
+
+// backupMaster rotates the backups of path and copies its current content to
+// <path>.bak. A missing master needs no backup.
+func backupMaster(path string, backups int) error {
+	current, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// Drop the oldest backup and shift the rest up by one
+	_ = os.Remove(backupPath(path, backups-1))
+	for i := backups - 2; i >= 0; i-- {
+		if err := os.Rename(backupPath(path, i), backupPath(path, i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return writeFileAtomic(backupPath(path, 0), current)
+}
+
+// backupPath returns the name of the i-th newest backup of path.
+func backupPath(path string, i int) string {
+	if i == 0 {
+		return path + ".bak"
+	}
+	return fmt.Sprintf("%s.bak.%d", path, i)
+}
+
+// writeFileAtomic writes data to a temp file next to path, syncs it to disk,
+// and renames it over path, so a crash leaves either the old file or the new
+// one, never a truncated mix.
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	cleanup := func(err error) error {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		return cleanup(err)
+	}
+	// CreateTemp uses 0600; manifests are served to players
+	if err := tmp.Chmod(0644); err != nil {
+		return cleanup(err)
+	}
+	if err := tmp.Sync(); err != nil {
+		return cleanup(err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	// Persist the rename; directories can't be synced on every platform
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		d.Close()
+	}
+	return nil
+}
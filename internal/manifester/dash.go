@@ -4,53 +4,223 @@ package manifester
 
 import (
 	"fmt"
-	"os"
+	"math"
+	"path"
 	"path/filepath"
+	"strings"
 
 	"github.com/dotsoulja/dotgo-transcode/internal/segmenter"
 )
 
-// generateDASHMaster creates a basic DASH .mpd manifest referencing all variants.
-// For simplicity, this assumes ffmpeg has already generated compliant segment sets.
+// generateDASHMaster creates a DASH .mpd manifest listing every variant.
+// The SegmentTemplates ffmpeg (or the packager) wrote into each variant MPD
+// are copied into the master with their paths made relative to it.
 //
 // Output:
 //
 //	media/output/<slug>/master.mpd
 //
-// References:
+// Reads:
 //
 //	<resolution>/<resolution>.mpd
-func generateDASHMaster(seg *segmenter.SegmentResult) (string, error) {
-	masterPath := filepath.Join(seg.OutputDir, "master.mpd")
-	f, err := os.Create(masterPath)
-	if err != nil {
-		return "", NewManifesterError("write_file", "failed to create DASH master manifest", err)
+//
+// Audio renditions become audio/mp4 adaptation sets with their channel
+// configuration, and subtitle renditions text/vtt adaptation sets pointing at
+// the WebVTT files. With ad breaks in opts the master is multi-period (see
+// generateMultiPeriodDASH).
+func generateDASHMaster(seg *segmenter.SegmentResult, opts ManifestOptions) (string, error) {
+	masterPath := MasterPath(seg.OutputDir, "dash")
+	if len(opts.AdBreaks) > 0 {
+		return generateMultiPeriodDASH(seg, opts, masterPath)
+	}
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" xmlns:cenc="urn:mpeg:cenc:2013" type="static" minBufferTime="PT1.5S" profiles="urn:mpeg:dash:profile:isoff-live:2011">` + "\n")
+	b.WriteString(`  <Period>` + "\n")
+	if err := writeDASHSets(&b, seg, opts, 0, math.Inf(1)); err != nil {
+		return "", err
 	}
-	defer f.Close()
+	b.WriteString(`  </Period>` + "\n")
+	b.WriteString(`</MPD>` + "\n")
 
-	_, _ = f.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
-	_, _ = f.WriteString(`<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" type="static" minBufferTime="PT1.5S" profiles="urn:mpeg:dash:profile:isoff-on-demand:2011">` + "\n")
-	_, _ = f.WriteString(`  <Period>` + "\n")
+	if err := writeMaster(masterPath, []byte(b.String()), opts.backups()); err != nil {
+		return "", NewManifesterError("write_file", "failed to create DASH master manifest", err)
+	}
+	return masterPath, nil
+}
 
+// writeDASHSets writes the video, audio, and subtitle adaptation sets of the
+// period covering content seconds [from, to). Video and audio list only the
+// segments in that span (see writeSegmentTemplate). Audio muxed into the
+// variants is segmented as its own stream, so it gets its own adaptation set.
+func writeDASHSets(b *strings.Builder, seg *segmenter.SegmentResult, opts ManifestOptions, from, to float64) error {
+	var muxedAudio strings.Builder
 	for _, manifest := range seg.Manifests {
 		label := extractLabel(manifest)
-		bitrate := estimateBitrate(label)
+		streams, err := readDASHStreams(manifest)
+		if err != nil {
+			return NewManifesterError("read_manifest", "failed to read "+manifest, err)
+		}
+		dir := path.Dir(manifestURI(seg, manifest))
+
+		video := -1
+		for i, s := range streams {
+			if s.ContentType == "video" && video < 0 {
+				video = i
+			}
+		}
+		if video < 0 {
+			return NewManifesterError("read_manifest", "no video representation in "+manifest, nil)
+		}
+		mimeType, codecs, dims := describeVariant(seg, manifest, streams[video])
+		b.WriteString(fmt.Sprintf(
+			`    <AdaptationSet mimeType="%s" codecs="%s" segmentAlignment="true" bitstreamSwitching="true">`+"\n"+
+				`%s`+
+				`      <Representation id="%s" bandwidth="%d"%s>`+"\n",
+			mimeType, xmlEscape(codecs), protection(streams[video].Protection, "      "), label, estimateBitrate(label), dims,
+		))
+		if err := writeSegmentTemplate(b, streams[video].Template, dir, from, to); err != nil {
+			return NewManifesterError("read_manifest", "failed to list segments of "+manifest, err)
+		}
+		b.WriteString(`      </Representation>` + "\n")
+		b.WriteString(`    </AdaptationSet>` + "\n")
 
-		// Reference manifest as <resolution>/<resolution>.mpd
-		uri := filepath.Join(label, filepath.Base(manifest))
+		for _, s := range streams {
+			if s.ContentType != "audio" {
+				continue
+			}
+			fmt.Fprintf(&muxedAudio, `      <Representation id="%s-audio" codecs="%s" bandwidth="%d">`+"\n"+`%s`,
+				label, xmlEscape(orDefault(s.Codecs, "mp4a.40.2")), orDefault(s.Bandwidth, 128000), protection(s.Protection, "        "))
+			if err := writeSegmentTemplate(&muxedAudio, s.Template, dir, from, to); err != nil {
+				return NewManifesterError("read_manifest", "failed to list segments of "+manifest, err)
+			}
+			muxedAudio.WriteString(`      </Representation>` + "\n")
+		}
+	}
+	if muxedAudio.Len() > 0 {
+		b.WriteString(`    <AdaptationSet contentType="audio" mimeType="audio/mp4" segmentAlignment="true">` + "\n")
+		b.WriteString(`      <Role schemeIdUri="urn:mpeg:dash:role:2011" value="main"/>` + "\n")
+		b.WriteString(muxedAudio.String())
+		b.WriteString(`    </AdaptationSet>` + "\n")
+	}
 
-		_, _ = f.WriteString(fmt.Sprintf(
-			`    <AdaptationSet mimeType="video/mp4" codecs="avc1.64001f" segmentAlignment="true" bitstreamSwitching="true">`+"\n"+
-				`      <Representation id="%s" bandwidth="%d">`+"\n"+
+	for i, a := range opts.Audio {
+		if a.Playlist == "" {
+			// Muxed into the video representations already
+			continue
+		}
+		manifest := filepath.Join(seg.OutputDir, filepath.FromSlash(a.Playlist))
+		streams, err := readDASHStreams(manifest)
+		if err != nil {
+			return NewManifesterError("read_manifest", "failed to read "+manifest, err)
+		}
+		if len(streams) == 0 {
+			return NewManifesterError("read_manifest", "no audio representation in "+manifest, nil)
+		}
+		role := "alternate"
+		if a.Default {
+			role = "main"
+		}
+		if a.Role != "" {
+			role = a.Role
+		}
+		accessibility := ""
+		if a.Role == "description" {
+			// Visually impaired audience, per the TV-Anytime AudioPurposeCS
+			accessibility = `      <Accessibility schemeIdUri="urn:tva:metadata:cs:AudioPurposeCS:2007" value="1"/>` + "\n"
+		}
+		lang := ""
+		if a.Language != "" {
+			lang = fmt.Sprintf(` lang="%s"`, xmlEscape(a.Language))
+		}
+		b.WriteString(fmt.Sprintf(
+			`    <AdaptationSet contentType="audio" mimeType="audio/mp4" codecs="%s"%s>`+"\n"+
+				`      <Role schemeIdUri="urn:mpeg:dash:role:2011" value="%s"/>`+"\n"+
+				`%s`+
+				`      <Label>%s</Label>`+"\n"+
+				`      <AudioChannelConfiguration schemeIdUri="urn:mpeg:dash:23003:3:audio_channel_configuration:2011" value="%d"/>`+"\n"+
+				`%s`+
+				`      <Representation id="audio-%d" bandwidth="%d">`+"\n",
+			xmlEscape(a.Codecs), lang, role, accessibility, xmlEscape(a.Name), a.Channels, protection(streams[0].Protection, "      "), i, a.Bitrate,
+		))
+		if err := writeSegmentTemplate(b, streams[0].Template, path.Dir(a.Playlist), from, to); err != nil {
+			return NewManifesterError("read_manifest", "failed to list segments of "+manifest, err)
+		}
+		b.WriteString(`      </Representation>` + "\n")
+		b.WriteString(`    </AdaptationSet>` + "\n")
+	}
+
+	// A WebVTT sidecar is one file rather than segments, so it has no
+	// timeline to cut; later periods shift its cues with SegmentBase instead
+	segmentBase := ""
+	if from > 0 {
+		segmentBase = fmt.Sprintf(`        <SegmentBase timescale="1000" presentationTimeOffset="%d"/>`+"\n", int64(math.Round(from*1000)))
+	}
+	for i, s := range opts.Subtitles {
+		uri := s.VTT
+		if opts.Signer != nil {
+			signed, err := signURI(opts.Signer, uri, uri)
+			if err != nil {
+				return NewManifesterError("sign", "failed to sign "+uri, err)
+			}
+			uri = signed
+		}
+		role := "subtitle"
+		if s.Forced {
+			role = "forced-subtitle"
+		}
+		lang := ""
+		if s.Language != "" {
+			lang = fmt.Sprintf(` lang="%s"`, xmlEscape(s.Language))
+		}
+		b.WriteString(fmt.Sprintf(
+			`    <AdaptationSet contentType="text" mimeType="text/vtt"%s>`+"\n"+
+				`      <Role schemeIdUri="urn:mpeg:dash:role:2011" value="%s"/>`+"\n"+
+				`      <Label>%s</Label>`+"\n"+
+				`      <Representation id="subtitle-%d" bandwidth="256">`+"\n"+
 				`        <BaseURL>%s</BaseURL>`+"\n"+
+				`%s`+
 				`      </Representation>`+"\n"+
 				`    </AdaptationSet>`+"\n",
-			label, bitrate, uri,
+			lang, role, xmlEscape(s.Name), i, xmlEscape(uri), segmentBase,
 		))
 	}
+	return nil
+}
 
-	_, _ = f.WriteString(`  </Period>` + "\n")
-	_, _ = f.WriteString(`</MPD>` + "\n")
+// describeVariant returns the mimeType, video codec string, and width and
+// height attributes of a variant's video, preferring what the transcode
+// recorded over what the variant MPD says.
+func describeVariant(seg *segmenter.SegmentResult, manifest string, video dashStream) (mimeType, codecs, dims string) {
+	mimeType, codecs = orDefault(video.MimeType, "video/mp4"), orDefault(video.Codecs, "avc1.64001f")
+	if v, ok := seg.Variants[manifest]; ok {
+		if c, _, _ := strings.Cut(v.Codecs, ","); c != "" {
+			codecs = c
+		}
+		if strings.EqualFold(filepath.Ext(v.OutputFilename), ".webm") {
+			mimeType = "video/webm"
+		}
+		if v.Width > 0 && v.Height > 0 {
+			dims = fmt.Sprintf(` width="%d" height="%d"`, v.Width, v.Height)
+		}
+	}
+	return mimeType, codecs, dims
+}
 
-	return masterPath, nil
+// protection indents ContentProtection elements copied from a variant MPD.
+func protection(elements []string, indent string) string {
+	var b strings.Builder
+	for _, e := range elements {
+		b.WriteString(indent + e + "\n")
+	}
+	return b.String()
+}
+
+// orDefault returns v, or def when v is the zero value.
+func orDefault[T comparable](v, def T) T {
+	var zero T
+	if v == zero {
+		return def
+	}
+	return v
 }
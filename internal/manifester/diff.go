@@ -0,0 +1,200 @@
+package manifester
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/segmenter"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
+)
+
+// Kinds of change in a RenditionChange.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeUpdated = "updated"
+)
+
+// ManifestDiff is what reconciling a run into an existing HLS master would
+// change (see DiffMasterManifest). Variants are matched by label and
+// renditions by type, GROUP-ID, and NAME.
+type ManifestDiff struct {
+	Master     string            // Master manifest path
+	Exists     bool              // A master exists; without one every variant is added
+	Added      []ManifestMeta    // Variants new to the master
+	Removed    []ManifestMeta    // Variants the master would no longer list
+	Updated    []VariantChange   // Variants listed with different attributes
+	Unchanged  []string          // Labels of variants left as they are
+	Renditions []RenditionChange // Audio, subtitle, and caption renditions added, removed, or updated
+}
+
+// VariantChange is a variant whose master entry would change.
+type VariantChange struct {
+	Label   string
+	Before  ManifestMeta
+	After   ManifestMeta
+	Changes []string // Changed attributes, e.g. "BANDWIDTH 3000000 -> 3300000"
+}
+
+// RenditionChange is an #EXT-X-MEDIA rendition that would change.
+type RenditionChange struct {
+	Type   string // "AUDIO", "SUBTITLES", or "CLOSED-CAPTIONS"
+	Group  string // GROUP-ID
+	Name   string // NAME
+	Change string // ChangeAdded, ChangeRemoved, or ChangeUpdated
+}
+
+// Empty reports whether reconciling would leave the master as it is.
+func (d *ManifestDiff) Empty() bool {
+	return d.Exists && len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Updated) == 0 && len(d.Renditions) == 0
+}
+
+// Lines renders the diff one change per line, prefixed "+" for additions,
+// "-" for removals, and "~" for updates.
+func (d *ManifestDiff) Lines() []string {
+	var lines []string
+	for _, e := range d.Added {
+		lines = append(lines, fmt.Sprintf("+ %s (%s, %d bps) %s", e.Label, e.Resolution, e.Bitrate, e.ManifestURL))
+	}
+	for _, e := range d.Removed {
+		lines = append(lines, fmt.Sprintf("- %s (%s, %d bps) %s", e.Label, e.Resolution, e.Bitrate, e.ManifestURL))
+	}
+	for _, c := range d.Updated {
+		lines = append(lines, fmt.Sprintf("~ %s: %s", c.Label, strings.Join(c.Changes, ", ")))
+	}
+	for _, r := range d.Renditions {
+		mark := map[string]string{ChangeAdded: "+", ChangeRemoved: "-", ChangeUpdated: "~"}[r.Change]
+		lines = append(lines, fmt.Sprintf("%s %s %q in group %q", mark, r.Type, r.Name, r.Group))
+	}
+	return lines
+}
+
+// DiffMasterManifest reports what GenerateMasterManifestWithOptions with
+// preserve set would change in the existing HLS master, without writing
+// anything, so ladder changes on live titles can be reviewed first. DASH
+// masters are regenerated rather than reconciled and are not supported.
+func DiffMasterManifest(seg *segmenter.SegmentResult, logger stagelog.Logger, opts ManifestOptions) (*ManifestDiff, error) {
+	logger = stagelog.OrStd(logger)
+	if seg == nil {
+		return nil, NewManifesterError("validate", "no manifests to aggregate", nil)
+	}
+	if !strings.EqualFold(seg.Format, "hls") {
+		return nil, NewManifesterError("validate", "manifest diff supports HLS only; DASH masters are regenerated", nil)
+	}
+
+	masterPath := MasterPath(seg.OutputDir, "hls")
+	diff := &ManifestDiff{Master: masterPath}
+	existing, err := os.ReadFile(masterPath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		diff.Added = orderEntries(hlsEntries(seg))
+		diff.Renditions = renditionChanges(hlsRenditions{}, opts)
+		return diff, nil
+	case err != nil:
+		return nil, NewManifesterError("read_file", "failed to read existing HLS master.m3u8", err)
+	}
+	diff.Exists = true
+
+	m, err := mergeHLSMaster(seg, string(existing), opts, logger)
+	if err != nil {
+		return nil, err
+	}
+	before := make(map[string]ManifestMeta, len(m.before))
+	for _, e := range m.before {
+		before[e.Label] = e
+	}
+	after := make(map[string]bool, len(m.after))
+	for _, e := range m.after {
+		after[e.Label] = true
+		old, ok := before[e.Label]
+		switch changes := variantChanges(old, e); {
+		case !ok:
+			diff.Added = append(diff.Added, e)
+		case len(changes) > 0:
+			diff.Updated = append(diff.Updated, VariantChange{Label: e.Label, Before: old, After: e, Changes: changes})
+		default:
+			diff.Unchanged = append(diff.Unchanged, e.Label)
+		}
+	}
+	for _, e := range m.before {
+		if !after[e.Label] {
+			diff.Removed = append(diff.Removed, e)
+		}
+	}
+	diff.Renditions = renditionChanges(m.renditions, m.opts)
+	return diff, nil
+}
+
+// variantChanges lists the master attributes that differ between entries.
+func variantChanges(before, after ManifestMeta) []string {
+	var changes []string
+	if before.Bitrate != after.Bitrate {
+		changes = append(changes, fmt.Sprintf("BANDWIDTH %d -> %d", before.Bitrate, after.Bitrate))
+	}
+	if before.Resolution != after.Resolution {
+		changes = append(changes, fmt.Sprintf("RESOLUTION %q -> %q", before.Resolution, after.Resolution))
+	}
+	if before.Codecs != after.Codecs {
+		changes = append(changes, fmt.Sprintf("CODECS %q -> %q", before.Codecs, after.Codecs))
+	}
+	if before.ManifestURL != after.ManifestURL {
+		changes = append(changes, fmt.Sprintf("URI %q -> %q", before.ManifestURL, after.ManifestURL))
+	}
+	return changes
+}
+
+// renditionChanges compares the existing renditions with the merged ones by
+// the #EXT-X-MEDIA tags they render to.
+func renditionChanges(existing hlsRenditions, merged ManifestOptions) []RenditionChange {
+	type key struct{ typ, group, name string }
+	var order []key
+	before := make(map[key]string)
+	after := make(map[key]string)
+	add := func(m map[key]string, k key, tag string) {
+		if _, ok := before[k]; !ok {
+			if _, ok := after[k]; !ok {
+				order = append(order, k)
+			}
+		}
+		m[k] = tag
+	}
+	for _, a := range existing.Audio {
+		add(before, key{"AUDIO", a.groupID(), a.Name}, audioTag(a, a.Playlist))
+	}
+	for _, s := range existing.Subtitles {
+		add(before, key{"SUBTITLES", s.groupID(), s.Name}, subtitleTag(s, s.Playlist))
+	}
+	for _, c := range existing.ClosedCaptions {
+		add(before, key{"CLOSED-CAPTIONS", c.groupID(), c.Name}, captionTag(c))
+	}
+	for _, a := range merged.Audio {
+		add(after, key{"AUDIO", a.groupID(), a.Name}, audioTag(a, a.Playlist))
+	}
+	for _, s := range merged.Subtitles {
+		add(after, key{"SUBTITLES", s.groupID(), s.Name}, subtitleTag(s, s.Playlist))
+	}
+	for _, c := range merged.ClosedCaptions {
+		add(after, key{"CLOSED-CAPTIONS", c.groupID(), c.Name}, captionTag(c))
+	}
+
+	var changes []RenditionChange
+	for _, k := range order {
+		old, hadOld := before[k]
+		tag, hasNew := after[k]
+		change := ""
+		switch {
+		case !hadOld:
+			change = ChangeAdded
+		case !hasNew:
+			change = ChangeRemoved
+		case old != tag:
+			change = ChangeUpdated
+		default:
+			continue
+		}
+		changes = append(changes, RenditionChange{Type: k.typ, Group: k.group, Name: k.name, Change: change})
+	}
+	return changes
+}
@@ -3,17 +3,25 @@
 package manifester
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/dotsoulja/dotgo-transcode/internal/segmenter"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
 )
 
 // generateHLSMaster creates a master .m3u8 playlist referencing all HLS variants.
-// Each variant includes resolution and bitrate metadata for adaptive playback.
+// Each variant includes resolution and bitrate metadata for adaptive playback,
+// plus CODECS when the segmenter knows which variant a manifest came from.
 //
 // Output:
 //
@@ -22,32 +30,329 @@ import (
 // References:
 //
 //	<resolution_bitrate>/<resolution_bitrate>.m3u8
-func generateHLSMaster(seg *segmenter.SegmentResult) (string, error) {
-	masterPath := filepath.Join(seg.OutputDir, "master.m3u8")
-	f, err := os.Create(masterPath)
-	if err != nil {
+func generateHLSMaster(seg *segmenter.SegmentResult, opts ManifestOptions) (string, error) {
+	masterPath := MasterPath(seg.OutputDir, "hls")
+	entries := orderEntries(hlsEntries(seg))
+	if err := writeHLSMaster(masterPath, entries, opts); err != nil {
 		return "", NewManifesterError("write_file", "failed to create HLS master playlist", err)
 	}
-	defer f.Close()
-
-	_, _ = f.WriteString("#EXTM3U\n")
-	_, _ = f.WriteString("#EXT-X-VERSION:3\n")
+	return masterPath, nil
+}
 
+// hlsEntries builds one master entry per segmented variant. Resolution and
+// bitrate are parsed from the label; dimensions and CODECS come from the
+// transcoded variant when the segment result carries it.
+func hlsEntries(seg *segmenter.SegmentResult) []ManifestMeta {
+	entries := make([]ManifestMeta, 0, len(seg.Manifests))
 	for _, manifest := range seg.Manifests {
 		label := extractLabel(manifest)
-		bitrate := estimateBitrate(label)
-		res := resolutionFromLabel(label)
+		meta := ManifestMeta{
+			Label:       label,
+			Bitrate:     estimateBitrate(label),
+			Resolution:  resolutionFromLabel(label),
+			ManifestURL: manifestURI(seg, manifest), // relative to the master (<label>/<label>.m3u8 by default)
+		}
+		if v, ok := seg.Variants[manifest]; ok {
+			if v.Width > 0 && v.Height > 0 {
+				meta.Resolution = fmt.Sprintf("%dx%d", v.Width, v.Height)
+			}
+			meta.Codec = v.Codec
+			meta.Codecs = v.Codecs
+		}
+		entries = append(entries, meta)
+	}
+	return entries
+}
+
+// codecRank orders codec families by efficiency; players that can decode a
+// higher-ranked codec should prefer it.
+func codecRank(codec string) int {
+	switch codec {
+	case "av1":
+		return 3
+	case "hevc", "vp9":
+		return 2
+	}
+	return 1
+}
+
+// orderEntries sorts entries with the most efficient codec tier first and
+// ascending bandwidth within a tier. When more than one codec family is
+// present, each entry gets a SCORE so capable players pick the efficient tier
+// and everything else falls back to H.264 via CODECS.
+func orderEntries(entries []ManifestMeta) []ManifestMeta {
+	sort.SliceStable(entries, func(i, j int) bool {
+		ri, rj := codecRank(entries[i].Codec), codecRank(entries[j].Codec)
+		if ri != rj {
+			return ri > rj
+		}
+		return entries[i].Bitrate < entries[j].Bitrate
+	})
 
-		// Reference manifest as <label>/<label>.m3u8
-		uri := filepath.Join(label, fmt.Sprintf("%s.m3u8", label))
+	families := make(map[int]bool)
+	maxBitrate := 0
+	for _, e := range entries {
+		families[codecRank(e.Codec)] = true
+		if e.Bitrate > maxBitrate {
+			maxBitrate = e.Bitrate
+		}
+	}
+	for i := range entries {
+		entries[i].Score = 0
+		if len(families) > 1 && maxBitrate > 0 {
+			// Tier decides the integer part, bandwidth breaks ties within a tier
+			entries[i].Score = float64(codecRank(entries[i].Codec)) + 0.9*float64(entries[i].Bitrate)/float64(maxBitrate)
+		}
+	}
+	return entries
+}
 
-		_, _ = f.WriteString(fmt.Sprintf(
-			"#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%s\n%s\n",
-			bitrate, res, uri,
-		))
+// writeHLSMaster writes entries as a master playlist, declaring the lowest
+// #EXT-X-VERSION the features in use allow (tiers carried in fMP4 segments,
+// HEVC and AV1, need 7) and failing when that exceeds opts.MaxVersion. Session data, the
+// start offset, and subtitle and audio renditions from opts precede the
+// variants, and opts.Signer, when set, appends a token to each variant and
+// rendition URI. Each audio group repeats the variants after the plain ones,
+// with the group's codec in CODECS so players that can't decode it skip them;
+// demuxed variants have no plain listing.
+func writeHLSMaster(masterPath string, entries []ManifestMeta, opts ManifestOptions) error {
+	version, err := negotiateHLSVersion(hlsFeatures(entries, opts), opts.MaxVersion)
+	if err != nil {
+		return err
 	}
 
-	return masterPath, nil
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	b.WriteString(fmt.Sprintf("#EXT-X-VERSION:%d\n", version))
+	if opts.Start != nil {
+		b.WriteString(startTag(*opts.Start) + "\n")
+	}
+	for _, d := range opts.SessionData {
+		tag, err := sessionDataTag(d)
+		if err != nil {
+			return err
+		}
+		b.WriteString(tag + "\n")
+	}
+	for _, s := range opts.Subtitles {
+		uri, err := signedURI(opts.Signer, s.Playlist)
+		if err != nil {
+			return err
+		}
+		b.WriteString(subtitleTag(s, uri) + "\n")
+	}
+	for _, c := range opts.ClosedCaptions {
+		b.WriteString(captionTag(c) + "\n")
+	}
+	groups := audioGroups(opts.Audio)
+	for _, a := range opts.Audio {
+		uri, err := signedURI(opts.Signer, a.Playlist)
+		if err != nil {
+			return err
+		}
+		b.WriteString(audioTag(a, uri) + "\n")
+	}
+	if !opts.Demuxed || len(groups) == 0 {
+		groups = append([]audioGroup{{}}, groups...)
+	}
+	for _, group := range groups {
+		for _, e := range entries {
+			b.WriteString(fmt.Sprintf("#EXT-X-STREAM-INF:BANDWIDTH=%d", e.Bitrate+group.bitrate))
+			if e.Resolution != "" {
+				b.WriteString(",RESOLUTION=" + e.Resolution)
+			}
+			if codecs := group.codecs(e.Codecs, opts.Demuxed); codecs != "" {
+				b.WriteString(fmt.Sprintf(",CODECS=\"%s\"", codecs))
+			}
+			if e.Score > 0 {
+				b.WriteString(fmt.Sprintf(",SCORE=%.2f", e.Score))
+			}
+			if group.id != "" {
+				b.WriteString(fmt.Sprintf(",AUDIO=\"%s\"", group.id))
+			}
+			if len(opts.Subtitles) > 0 {
+				b.WriteString(fmt.Sprintf(",SUBTITLES=\"%s\"", opts.Subtitles[0].groupID()))
+			}
+			if len(opts.ClosedCaptions) > 0 {
+				b.WriteString(fmt.Sprintf(",CLOSED-CAPTIONS=\"%s\"", opts.ClosedCaptions[0].groupID()))
+			}
+			uri, err := signedURI(opts.Signer, e.ManifestURL)
+			if err != nil {
+				return err
+			}
+			b.WriteString("\n" + uri + "\n")
+		}
+	}
+	return writeMaster(masterPath, []byte(b.String()), opts.backups())
+}
+
+// audioGroup is one HLS audio GROUP-ID: the renditions sharing a codec.
+type audioGroup struct {
+	id      string // GROUP-ID, "" for variants without alternate audio
+	entry   string // RFC 6381 CODECS entry of the group's codec
+	bitrate int    // Highest rendition bitrate, added to variant BANDWIDTH
+}
+
+// audioGroups groups renditions by GROUP-ID (by default their codec), in
+// order of first appearance.
+func audioGroups(renditions []AudioRendition) []audioGroup {
+	var groups []audioGroup
+	index := make(map[string]int)
+	for _, a := range renditions {
+		id := a.groupID()
+		i, ok := index[id]
+		if !ok {
+			i = len(groups)
+			index[id] = i
+			groups = append(groups, audioGroup{id: id})
+		}
+		if groups[i].entry == "" {
+			groups[i].entry = a.Codecs
+		}
+		if a.Bitrate > groups[i].bitrate {
+			groups[i].bitrate = a.Bitrate
+		}
+	}
+	return groups
+}
+
+// codecs returns the CODECS value for a variant listed in the group. RFC 8216
+// requires every format any rendition of the variant uses, so the group's
+// audio is added to the variant's own codecs: its video, plus the audio
+// muxed into it unless the variants are demuxed (video-only).
+func (g audioGroup) codecs(variant string, demuxed bool) string {
+	if g.id == "" || variant == "" || g.entry == "" {
+		return variant
+	}
+	if demuxed {
+		variant, _, _ = strings.Cut(variant, ",")
+	}
+	for _, c := range strings.Split(variant, ",") {
+		if strings.TrimSpace(c) == g.entry {
+			return variant
+		}
+	}
+	return variant + "," + g.entry
+}
+
+// audioGroupID returns the GROUP-ID for renditions of codec.
+func audioGroupID(codec string) string {
+	return "audio-" + codec
+}
+
+// audioTag renders the #EXT-X-MEDIA entry for a served from uri.
+func audioTag(a AudioRendition, uri string) string {
+	tag := fmt.Sprintf("#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID=\"%s\",NAME=\"%s\"", a.groupID(), a.Name)
+	if a.Language != "" {
+		tag += fmt.Sprintf(",LANGUAGE=\"%s\"", a.Language)
+	}
+	if a.Default {
+		tag += ",DEFAULT=YES"
+	} else {
+		tag += ",DEFAULT=NO"
+	}
+	tag += ",AUTOSELECT=YES"
+	if a.Characteristics != "" {
+		tag += fmt.Sprintf(",CHARACTERISTICS=\"%s\"", a.Characteristics)
+	}
+	if a.Channels > 0 {
+		tag += fmt.Sprintf(",CHANNELS=\"%d\"", a.Channels)
+	}
+	if uri == "" {
+		return tag
+	}
+	return tag + fmt.Sprintf(",URI=\"%s\"", uri)
+}
+
+// subtitleGroup is the GROUP-ID shared by every subtitle rendition.
+const subtitleGroup = "subs"
+
+// subtitleTag renders the #EXT-X-MEDIA entry for s served from uri.
+func subtitleTag(s SubtitleRendition, uri string) string {
+	tag := fmt.Sprintf("#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID=\"%s\",NAME=\"%s\"", s.groupID(), s.Name)
+	if s.Language != "" {
+		tag += fmt.Sprintf(",LANGUAGE=\"%s\"", s.Language)
+	}
+	if s.Default {
+		tag += ",DEFAULT=YES"
+	} else {
+		tag += ",DEFAULT=NO"
+	}
+	if s.Forced {
+		tag += ",FORCED=YES"
+	}
+	return tag + fmt.Sprintf(",AUTOSELECT=YES,URI=\"%s\"", uri)
+}
+
+// captionGroup is the default GROUP-ID of closed caption renditions.
+const captionGroup = "cc"
+
+// captionTag renders the #EXT-X-MEDIA entry for embedded captions c.
+func captionTag(c ClosedCaptionRendition) string {
+	tag := fmt.Sprintf("#EXT-X-MEDIA:TYPE=CLOSED-CAPTIONS,GROUP-ID=\"%s\",NAME=\"%s\"", c.groupID(), c.Name)
+	if c.Language != "" {
+		tag += fmt.Sprintf(",LANGUAGE=\"%s\"", c.Language)
+	}
+	if c.Default {
+		tag += ",DEFAULT=YES"
+	} else {
+		tag += ",DEFAULT=NO"
+	}
+	return tag + fmt.Sprintf(",AUTOSELECT=YES,INSTREAM-ID=\"%s\"", c.InstreamID)
+}
+
+// signedURI appends signer's token to a relative uri; nil signers and
+// absolute URIs pass through.
+func signedURI(signer URLSigner, uri string) (string, error) {
+	if signer == nil || uri == "" || isAbsoluteURI(uri) {
+		return uri, nil
+	}
+	signed, err := signURI(signer, uri, uri)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign %s: %w", uri, err)
+	}
+	return signed, nil
+}
+
+// startTag renders #EXT-X-START for s.
+func startTag(s transcoder.StartOffset) string {
+	tag := "#EXT-X-START:TIME-OFFSET=" + strconv.FormatFloat(s.TimeOffset, 'f', -1, 64)
+	if s.Precise {
+		tag += ",PRECISE=YES"
+	}
+	return tag
+}
+
+// sessionDataTag renders #EXT-X-SESSION-DATA for d. Inline JSON payloads are
+// embedded as base64 data: URIs so players need no extra request.
+func sessionDataTag(d transcoder.SessionData) (string, error) {
+	tag := fmt.Sprintf("#EXT-X-SESSION-DATA:DATA-ID=\"%s\"", d.ID)
+	switch {
+	case d.Value != "":
+		tag += fmt.Sprintf(",VALUE=\"%s\"", d.Value)
+	case d.URI != "":
+		tag += fmt.Sprintf(",URI=\"%s\"", d.URI)
+	case d.JSON != nil:
+		payload, err := json.Marshal(d.JSON)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode session data %q: %w", d.ID, err)
+		}
+		tag += fmt.Sprintf(",URI=\"data:application/json;base64,%s\"", base64.StdEncoding.EncodeToString(payload))
+	}
+	if d.Language != "" {
+		tag += fmt.Sprintf(",LANGUAGE=\"%s\"", d.Language)
+	}
+	return tag, nil
+}
+
+// manifestURI returns the variant manifest path relative to the master
+// manifest, following whatever variant layout the segmenter used.
+func manifestURI(seg *segmenter.SegmentResult, manifest string) string {
+	if rel, err := filepath.Rel(seg.OutputDir, manifest); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	label := extractLabel(manifest)
+	return label + "/" + filepath.Base(manifest)
 }
 
 // extractLabel returns the base filename without extension.
@@ -93,98 +398,228 @@ func resolutionFromLabel(label string) string {
 }
 
 // reconcileHLSMaster merges existing and new manifests, preserving canonical order.
-// Useful when adding new variants to an existing master.m3u8
-func reconcileHLSMaster(seg *segmenter.SegmentResult) (string, error) {
-	masterPath := filepath.Join(seg.OutputDir, "master.m3u8")
+// Useful when adding new variants, audio languages, or subtitles to an
+// existing master.m3u8: #EXT-X-MEDIA renditions survive alongside the variants.
+func reconcileHLSMaster(seg *segmenter.SegmentResult, opts ManifestOptions, logger stagelog.Logger) (string, error) {
+	masterPath := MasterPath(seg.OutputDir, "hls")
 
 	// Read existing master .m3u8
-	fmt.Println("🔄 Reconciling with existing master manifest...")
+	logger.LogStage("reconcile", "🔄 Reconciling with existing master manifest...")
 	existing, err := os.ReadFile(masterPath)
 	if err != nil {
 		return "", NewManifesterError(
 			"read_file", "failed to read existing HLS master.m3u8", err,
 		)
 	}
+	m, err := mergeHLSMaster(seg, string(existing), opts, logger)
+	if err != nil {
+		return "", err
+	}
+
+	logger.LogStage("reconcile", fmt.Sprintf("Reconciled entries: %v", m.after))
+	// Write reconciled manifest
+	if err := writeHLSMaster(masterPath, m.after, m.opts); err != nil {
+		return "", NewManifesterError(
+			"write_file", "failed to write reconciled master.m3u8", err,
+		)
+	}
+
+	return masterPath, nil
+}
+
+// hlsMerge is an existing master merged with a run's variants and
+// renditions, before anything is written.
+type hlsMerge struct {
+	before     []ManifestMeta  // Variants of the existing master
+	renditions hlsRenditions   // Renditions of the existing master
+	after      []ManifestMeta  // Merged variants in canonical order
+	opts       ManifestOptions // opts with the existing renditions merged in
+}
+
+// mergeHLSMaster merges the existing master content with seg's variants and
+// opts' renditions: new variants replace existing ones with the same label.
+func mergeHLSMaster(seg *segmenter.SegmentResult, existing string, opts ManifestOptions, logger stagelog.Logger) (*hlsMerge, error) {
+	if !isM3U(existing) {
+		return nil, NewManifesterError(
+			"parse", "existing master.m3u8 is not an M3U playlist (missing #EXTM3U)", nil,
+		)
+	}
 
 	// Parse existing entries
-	fmt.Printf("Raw entries: \n%s\n", string(existing))
-	existingEntries := parseHLSManifest(string(existing))
-	fmt.Println("Existing entries:", existingEntries)
+	existingEntries := parseHLSManifest(existing)
+	logger.LogStage("reconcile", fmt.Sprintf("Existing entries: %v", existingEntries))
 
-	newEntries := make(map[string]ManifestMeta)
-	for _, manifest := range seg.Manifests {
-		label := extractLabel(manifest)
-		newEntries[label] = ManifestMeta{
-			Label:       label,
-			Bitrate:     estimateBitrate(label),
-			Resolution:  resolutionFromLabel(label),
-			ManifestURL: filepath.Join(label, filepath.Base(manifest)),
+	// Keep existing audio, subtitle, and caption renditions, merging new
+	// ones into their groups
+	renditions := parseHLSRenditions(existing)
+	if n := renditions.count(); n > 0 {
+		logger.LogStage("reconcile", fmt.Sprintf("Existing renditions: %d audio, %d subtitle, %d closed caption", len(renditions.Audio), len(renditions.Subtitles), len(renditions.ClosedCaptions)))
+	}
+
+	// Variants listed only within an audio group, as in demuxed masters,
+	// carry the group's audio in BANDWIDTH; take it out so the write
+	// doesn't add it twice. A master listing every variant that way stays
+	// demuxed.
+	merged := mergeRenditions(renditions, opts)
+	groupBitrate := make(map[string]int)
+	for _, g := range audioGroups(merged.Audio) {
+		groupBitrate[g.id] = g.bitrate
+	}
+	audioOnly := audioOnlyVariants(existing)
+	for i, entry := range existingEntries {
+		if group, ok := audioOnly[entry.ManifestURL]; ok {
+			existingEntries[i].Bitrate = max(entry.Bitrate-groupBitrate[group], 0)
 		}
 	}
+	if len(existingEntries) > 0 && len(audioOnly) == len(existingEntries) {
+		merged.Demuxed = true
+	}
+
+	newEntries := make(map[string]ManifestMeta)
+	for _, entry := range hlsEntries(seg) {
+		newEntries[entry.Label] = entry
+	}
 
 	// Merge and deduplicate
-	merged := make(map[string]ManifestMeta)
+	entries := make(map[string]ManifestMeta)
 	for _, entry := range existingEntries {
-		merged[entry.Label] = entry
+		entries[entry.Label] = entry
 	}
 	for label, entry := range newEntries {
-		merged[label] = entry // overwrite if exists
+		entries[label] = entry // overwrite if exists
 	}
 
-	// Sort by canonical resolution order
-	order := []string{"144p", "240p", "360p", "480p", "720p", "1080p", "1440p", "2160p"}
-	var sorted []ManifestMeta
-	for _, res := range order {
-		for label, entry := range merged {
-			if strings.HasPrefix(label, res) {
-				sorted = append(sorted, entry)
-			}
-		}
-	}
-
-	fmt.Printf("Reconciled entries: %v\n", sorted)
-	// Write reconciled manifest
-	f, err := os.Create(masterPath)
-	if err != nil {
-		return "", NewManifesterError(
-			"write_file", "failed to write reconciled master.m3u8", err,
-		)
+	// Sort by codec tier and bandwidth (label order first so ties are stable)
+	labels := make([]string, 0, len(entries))
+	for label := range entries {
+		labels = append(labels, label)
 	}
-	defer f.Close()
-
-	_, _ = f.WriteString("#EXTM3U\n")
-	_, _ = f.WriteString("#EXT-X-VERSION:3\n")
-	for _, entry := range sorted {
-		_, _ = f.WriteString(fmt.Sprintf(
-			"#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%s\n%s\n",
-			entry.Bitrate, entry.Resolution, entry.ManifestURL,
-		))
+	sort.Strings(labels)
+	sorted := make([]ManifestMeta, 0, len(labels))
+	for _, label := range labels {
+		sorted = append(sorted, entries[label])
 	}
 
-	return masterPath, nil
+	return &hlsMerge{
+		before:     existingEntries,
+		renditions: renditions,
+		after:      orderEntries(sorted),
+		opts:       merged,
+	}, nil
 }
 
 // parseHLSManifest extracts ManifestMeta entries from raw master.m3u8 content.
-// Used during reconciliation to preserve existing variants.
+// Used during reconciliation to preserve existing variants, including those
+// of masters written by other tools: attributes may come in any order, with
+// extras, and RESOLUTION may be missing for audio-only variants. A variant
+// listed once per audio group is kept once, preferring its listing without
+// AUDIO; ones listed only with AUDIO keep their first listing.
 func parseHLSManifest(raw string) []ManifestMeta {
-	lines := strings.Split(raw, "\n")
 	var entries []ManifestMeta
+	index := make(map[string]int) // URI -> entry
+	plain := make(map[string]bool)
+	for _, st := range parseHLSStreams(raw) {
+		bitrate, err := strconv.Atoi(st.attrs["BANDWIDTH"])
+		if err != nil {
+			continue
+		}
+		hasAudio := st.attrs["AUDIO"] != ""
+		meta := ManifestMeta{
+			Label:       extractLabel(st.uri),
+			Bitrate:     bitrate,
+			Resolution:  st.attrs["RESOLUTION"],
+			ManifestURL: st.uri,
+			Codecs:      st.attrs["CODECS"],
+			Codec:       helpers.VideoFamilyFromCodecs(st.attrs["CODECS"]),
+		}
+		i, seen := index[st.uri]
+		switch {
+		case !seen:
+			index[st.uri] = len(entries)
+			entries = append(entries, meta)
+		case !hasAudio && !plain[st.uri]:
+			entries[i] = meta
+		default:
+			continue
+		}
+		if !hasAudio {
+			plain[st.uri] = true
+		}
+	}
+	return uniqueLabels(entries)
+}
 
-	for i := 0; i < len(lines)-1; i++ {
-		if strings.HasPrefix(lines[i], "#EXT-X-STREAM-INF") {
-			meta := ManifestMeta{}
-			inf := lines[i]
-			next := lines[i+1]
+// audioOnlyVariants maps the URI of each variant the master lists only with
+// AUDIO, never on its own, to the first audio group it is listed in.
+func audioOnlyVariants(raw string) map[string]string {
+	groups := make(map[string]string)
+	plain := make(map[string]bool)
+	for _, st := range parseHLSStreams(raw) {
+		switch group := st.attrs["AUDIO"]; {
+		case group == "":
+			plain[st.uri] = true
+		case groups[st.uri] == "":
+			groups[st.uri] = group
+		}
+	}
+	for uri := range plain {
+		delete(groups, uri)
+	}
+	return groups
+}
 
-			_, err := fmt.Sscanf(inf, "#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%s", &meta.Bitrate, &meta.Resolution)
-			if err != nil {
-				continue
-			}
+// hlsStream is one #EXT-X-STREAM-INF entry: its attributes and the URI on
+// the next line that isn't blank or a tag.
+type hlsStream struct {
+	attrs map[string]string
+	uri   string // Without the query a signed run appended; re-signed on write
+}
 
-			meta.ManifestURL = next
-			meta.Label = extractLabel(next)
-			entries = append(entries, meta)
+// parseHLSStreams returns the variant streams listed in a master playlist.
+// I-frame playlists (#EXT-X-I-FRAME-STREAM-INF) are not variants and are
+// skipped.
+func parseHLSStreams(raw string) []hlsStream {
+	var streams []hlsStream
+	var pending map[string]string
+	for _, line := range strings.Split(strings.TrimPrefix(raw, "\ufeff"), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			pending = helpers.ParseHLSAttributes(strings.TrimPrefix(line, "#EXT-X-STREAM-INF:"))
+		case strings.HasPrefix(line, "#"):
+		case pending != nil:
+			uri, _, _ := strings.Cut(line, "?")
+			streams = append(streams, hlsStream{attrs: pending, uri: uri})
+			pending = nil
+		}
+	}
+	return streams
+}
+
+// isM3U reports whether raw starts with the #EXTM3U header.
+func isM3U(raw string) bool {
+	return strings.HasPrefix(strings.TrimSpace(strings.TrimPrefix(raw, "\ufeff")), "#EXTM3U")
+}
+
+// uniqueLabels relabels entries whose playlist filenames collide, as with
+// masters that name every variant playlist "index.m3u8": such entries take
+// their directory name instead, or the whole path when that collides too.
+func uniqueLabels(entries []ManifestMeta) []ManifestMeta {
+	count := make(map[string]int)
+	for _, e := range entries {
+		count[e.Label]++
+	}
+	for i, e := range entries {
+		if count[e.Label] < 2 {
+			continue
+		}
+		if dir := path.Base(path.Dir(e.ManifestURL)); dir != "." && dir != "/" && count[dir] == 0 {
+			entries[i].Label = dir
+			count[dir]++
+			continue
 		}
+		entries[i].Label = strings.TrimSuffix(e.ManifestURL, path.Ext(e.ManifestURL))
 	}
 	return entries
 }
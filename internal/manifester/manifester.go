@@ -4,28 +4,143 @@
 package manifester
 
 import (
+	"path/filepath"
 	"strings"
 
 	"github.com/dotsoulja/dotgo-transcode/internal/segmenter"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
 )
 
 // GenerateMasterManifest creates a multi-variant manifest for adaptive playback.
 // It accepts a SegmentResult and writes a master playlist referencing all variants.
-// Supports "hls" (.m3u8) and "dash" (.mpd) formats.
-func GenerateMasterManifest(seg *segmenter.SegmentResult, preserve bool) (string, error) {
+// Supports "hls" (.m3u8) and "dash" (.mpd) formats. A nil logger falls back to stagelog.Std.
+// The master is replaced atomically, keeping the previous one as a .bak.
+func GenerateMasterManifest(seg *segmenter.SegmentResult, preserve bool, logger stagelog.Logger) (string, error) {
+	return GenerateMasterManifestWithOptions(seg, preserve, logger, ManifestOptions{})
+}
+
+// ManifestOptions customizes a single GenerateMasterManifest run.
+type ManifestOptions struct {
+	// Signer, when set, appends a query token to every variant URI in the
+	// master and every segment URI in the variant manifests, for CDNs that
+	// require signed URLs. See HMACSigner and TokenPlaceholder.
+	Signer URLSigner
+
+	// SessionData and Start are written into the HLS master as
+	// #EXT-X-SESSION-DATA and #EXT-X-START tags. Ignored for DASH.
+	SessionData []transcoder.SessionData
+	Start       *transcoder.StartOffset
+
+	// Subtitles are listed as an HLS SUBTITLES group every variant
+	// references, or as text adaptation sets in DASH.
+	Subtitles []SubtitleRendition
+
+	// Audio renditions are listed as HLS audio groups, one per codec, with
+	// every variant repeated per group, or as audio adaptation sets in DASH.
+	Audio []AudioRendition
+
+	// Demuxed marks video-only variants whose audio is an Audio rendition:
+	// the HLS master lists them only within audio groups, never on their own.
+	Demuxed bool
+
+	// ClosedCaptions declare captions embedded in the video as an HLS
+	// CLOSED-CAPTIONS group every variant references. Ignored for DASH.
+	ClosedCaptions []ClosedCaptionRendition
+
+	// MaxVersion is the highest #EXT-X-VERSION the HLS master may declare,
+	// for players that only support older versions; features that need a
+	// later one fail the write. Zero allows any. Ignored for DASH.
+	MaxVersion int
+
+	// AdBreaks make the DASH master multi-period: the content is split at
+	// each mid-roll and an ad period inserted per break, in order. Duration
+	// is the content length in seconds they are placed against; zero takes
+	// it from the segment result's media. Ignored for HLS.
+	AdBreaks []AdBreak
+	Duration float64
+
+	// Backups is how many previous masters to keep beside the new one as
+	// master.m3u8.bak, master.m3u8.bak.1, ... (or master.mpd.bak). Zero
+	// keeps DefaultMasterBackups; negative keeps none.
+	Backups int
+}
+
+// backups returns the number of previous masters to keep.
+func (o ManifestOptions) backups() int {
+	if o.Backups == 0 {
+		return DefaultMasterBackups
+	}
+	return max(o.Backups, 0)
+}
+
+// GenerateMasterManifestWithOptions is GenerateMasterManifest with per-run
+// options such as URL signing.
+func GenerateMasterManifestWithOptions(seg *segmenter.SegmentResult, preserve bool, logger stagelog.Logger, opts ManifestOptions) (string, error) {
+	logger = stagelog.OrStd(logger)
+
 	if seg == nil || len(seg.Manifests) == 0 {
 		return "", NewManifesterError("validate", "no manifests to aggregate", nil)
 	}
 
 	switch strings.ToLower(seg.Format) {
 	case "hls":
+		if opts.Signer != nil {
+			logger.LogStage("manifest", "🔏 Signing variant and segment URIs")
+			for _, manifest := range seg.Manifests {
+				if err := signHLSPlaylist(seg.OutputDir, manifest, opts.Signer); err != nil {
+					return "", NewManifesterError("sign", "failed to sign "+manifest, err)
+				}
+			}
+			for _, s := range opts.Subtitles {
+				playlist := filepath.Join(seg.OutputDir, filepath.FromSlash(s.Playlist))
+				if err := signHLSPlaylist(seg.OutputDir, playlist, opts.Signer); err != nil {
+					return "", NewManifesterError("sign", "failed to sign "+playlist, err)
+				}
+			}
+			for _, a := range opts.Audio {
+				if a.Playlist == "" {
+					continue
+				}
+				playlist := filepath.Join(seg.OutputDir, filepath.FromSlash(a.Playlist))
+				if err := signHLSPlaylist(seg.OutputDir, playlist, opts.Signer); err != nil {
+					return "", NewManifesterError("sign", "failed to sign "+playlist, err)
+				}
+			}
+		}
 		if preserve {
-			return reconcileHLSMaster(seg)
+			return reconcileHLSMaster(seg, opts, logger)
 		}
-		return generateHLSMaster(seg)
+		return generateHLSMaster(seg, opts)
 	case "dash":
-		return generateDASHMaster(seg)
+		if opts.Signer != nil {
+			logger.LogStage("manifest", "🔏 Signing representation and segment template URIs")
+			for _, manifest := range seg.Manifests {
+				if err := signDASHManifest(seg.OutputDir, manifest, opts.Signer); err != nil {
+					return "", NewManifesterError("sign", "failed to sign "+manifest, err)
+				}
+			}
+			for _, a := range opts.Audio {
+				if a.Playlist == "" {
+					continue
+				}
+				manifest := filepath.Join(seg.OutputDir, filepath.FromSlash(a.Playlist))
+				if err := signDASHManifest(seg.OutputDir, manifest, opts.Signer); err != nil {
+					return "", NewManifesterError("sign", "failed to sign "+manifest, err)
+				}
+			}
+		}
+		return generateDASHMaster(seg, opts)
 	default:
 		return "", NewManifesterError("validate", "unsupported format: "+seg.Format, nil)
 	}
 }
+
+// MasterPath returns the path GenerateMasterManifest writes for the given format.
+// e.g. "hls" -> <outputDir>/master.m3u8, "dash" -> <outputDir>/master.mpd
+func MasterPath(outputDir, format string) string {
+	if strings.EqualFold(format, "dash") {
+		return filepath.Join(outputDir, "master.mpd")
+	}
+	return filepath.Join(outputDir, "master.m3u8")
+}
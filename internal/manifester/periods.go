@@ -0,0 +1,135 @@
+package manifester
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/segmenter"
+)
+
+// AdBreak is an ad period in a multi-period DASH master. The SSAI service
+// replaces it with ads; until then players see the slate, or skip the
+// placeholder period.
+type AdBreak struct {
+	ID       string  // Period id (e.g. "ad-1")
+	Offset   float64 // Content seconds the break plays at: 0 for a pre-roll, the content duration for a post-roll
+	Duration float64 // Break length in seconds
+	Slate    string  // MPD played during the break, relative to the master or absolute; "" for a placeholder
+}
+
+// scte35Timescale is the 90 kHz clock SCTE-35 break durations are given in.
+const scte35Timescale = 90000
+
+// generateMultiPeriodDASH writes a DASH master that splits the content into
+// periods at each mid-roll and inserts an ad period per break. Content
+// periods list the same adaptation sets, each with the segments of its span
+// and a presentationTimeOffset into the media; ad periods carry an SCTE-35
+// splice insert event so SSAI services can find and fill them.
+func generateMultiPeriodDASH(seg *segmenter.SegmentResult, opts ManifestOptions, masterPath string) (string, error) {
+	duration := opts.Duration
+	if duration <= 0 && seg.Media != nil {
+		duration = seg.Media.Duration
+	}
+	if duration <= 0 {
+		return "", NewManifesterError("validate", "ad breaks need the content duration", nil)
+	}
+
+	var periods strings.Builder
+	start, content := 0.0, 0.0 // Presentation and content time at the next period
+	total, n := duration, 0
+	for i, ad := range opts.AdBreaks {
+		offset := min(max(ad.Offset, 0), duration)
+		if offset > content {
+			n++
+			fmt.Fprintf(&periods, `  <Period id="content-%d" start="%s" duration="%s">`+"\n", n, isoDuration(start), isoDuration(offset-content))
+			if err := writeDASHSets(&periods, seg, opts, content, offset); err != nil {
+				return "", err
+			}
+			periods.WriteString(`  </Period>` + "\n")
+			start += offset - content
+			content = offset
+		}
+		if err := writeAdPeriod(&periods, seg, opts, ad, i+1, start); err != nil {
+			return "", err
+		}
+		start += ad.Duration
+		total += ad.Duration
+	}
+	if content < duration {
+		fmt.Fprintf(&periods, `  <Period id="content-%d" start="%s" duration="%s">`+"\n", n+1, isoDuration(start), isoDuration(duration-content))
+		if err := writeDASHSets(&periods, seg, opts, content, duration); err != nil {
+			return "", err
+		}
+		periods.WriteString(`  </Period>` + "\n")
+	}
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(&b, `<MPD xmlns="urn:mpeg:dash:schema:mpd:2011" xmlns:cenc="urn:mpeg:cenc:2013" xmlns:scte35="http://www.scte.org/schemas/35/2016" type="static" minBufferTime="PT1.5S" mediaPresentationDuration="%s" profiles="urn:mpeg:dash:profile:isoff-live:2011">`+"\n", isoDuration(total))
+	b.WriteString(periods.String())
+	b.WriteString(`</MPD>` + "\n")
+
+	if err := writeMaster(masterPath, []byte(b.String()), opts.backups()); err != nil {
+		return "", NewManifesterError("write_file", "failed to create DASH master manifest", err)
+	}
+	return masterPath, nil
+}
+
+// writeAdPeriod writes the period for ad, the n-th break, starting start
+// seconds into the presentation.
+func writeAdPeriod(b *strings.Builder, seg *segmenter.SegmentResult, opts ManifestOptions, ad AdBreak, n int, start float64) error {
+	ticks := int64(math.Round(ad.Duration * scte35Timescale))
+	fmt.Fprintf(b, `  <Period id="%s" start="%s" duration="%s">`+"\n", xmlEscape(ad.ID), isoDuration(start), isoDuration(ad.Duration))
+	fmt.Fprintf(b,
+		`    <EventStream schemeIdUri="urn:scte:scte35:2014:xml" timescale="%d">`+"\n"+
+			`      <Event presentationTime="0" duration="%d" id="%d">`+"\n"+
+			`        <scte35:SpliceInfoSection>`+"\n"+
+			`          <scte35:SpliceInsert spliceEventId="%d" outOfNetworkIndicator="true" spliceImmediateFlag="true">`+"\n"+
+			`            <scte35:BreakDuration autoReturn="true" duration="%d"/>`+"\n"+
+			`          </scte35:SpliceInsert>`+"\n"+
+			`        </scte35:SpliceInfoSection>`+"\n"+
+			`      </Event>`+"\n"+
+			`    </EventStream>`+"\n",
+		scte35Timescale, ticks, n, n, ticks,
+	)
+	if ad.Slate != "" {
+		uri := ad.Slate
+		if opts.Signer != nil && !isAbsoluteURI(uri) {
+			signed, err := signURI(opts.Signer, uri, uri)
+			if err != nil {
+				return NewManifesterError("sign", "failed to sign "+uri, err)
+			}
+			uri = signed
+		}
+		// Advertise the slate as the lowest variant, which it is encoded to match
+		lowest, bandwidth := "", 0
+		for _, manifest := range seg.Manifests {
+			if br := estimateBitrate(extractLabel(manifest)); bandwidth == 0 || br < bandwidth {
+				lowest, bandwidth = manifest, br
+			}
+		}
+		mimeType, codecs, dims := describeVariant(seg, lowest, dashStream{})
+		if v, ok := seg.Variants[lowest]; ok && v.Codecs != "" {
+			// The slate carries its own audio, so list both
+			codecs = v.Codecs
+		}
+		fmt.Fprintf(b,
+			`    <AdaptationSet mimeType="%s" segmentAlignment="true">`+"\n"+
+				`      <Representation id="%s-slate" mimeType="%s" codecs="%s" bandwidth="%d"%s>`+"\n"+
+				`        <BaseURL>%s</BaseURL>`+"\n"+
+				`      </Representation>`+"\n"+
+				`    </AdaptationSet>`+"\n",
+			mimeType, xmlEscape(ad.ID), mimeType, xmlEscape(codecs), bandwidth, dims, xmlEscape(uri),
+		)
+	}
+	b.WriteString(`  </Period>` + "\n")
+	return nil
+}
+
+// isoDuration formats seconds as an ISO 8601 duration (e.g. "PT90.5S").
+func isoDuration(seconds float64) string {
+	ms := math.Round(seconds * 1000)
+	return "PT" + strconv.FormatFloat(ms/1000, 'f', -1, 64) + "S"
+}
@@ -0,0 +1,228 @@
+package manifester
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+)
+
+// hlsRenditions are the #EXT-X-MEDIA renditions of an existing master
+// manifest, recovered so reconciliation keeps them alongside new ones.
+type hlsRenditions struct {
+	Audio          []AudioRendition
+	Subtitles      []SubtitleRendition
+	ClosedCaptions []ClosedCaptionRendition
+}
+
+// count returns the number of renditions.
+func (r hlsRenditions) count() int {
+	return len(r.Audio) + len(r.Subtitles) + len(r.ClosedCaptions)
+}
+
+// parseHLSRenditions extracts the audio, subtitle, and closed caption
+// renditions from raw master.m3u8 content. An audio group's CODECS entry and
+// bitrate aren't in its #EXT-X-MEDIA lines, so they are recovered from the
+// variants listed with AUDIO=: the audio half of CODECS, and BANDWIDTH less
+// that of the same variant without alternate audio.
+func parseHLSRenditions(raw string) hlsRenditions {
+	var out hlsRenditions
+	streams := parseHLSStreams(raw)
+
+	plain := make(map[string]int) // Variant URI -> BANDWIDTH without alternate audio
+	for _, st := range streams {
+		if bandwidth, err := strconv.Atoi(st.attrs["BANDWIDTH"]); err == nil && st.attrs["AUDIO"] == "" {
+			plain[st.uri] = bandwidth
+		}
+	}
+	type groupInfo struct {
+		entry   string
+		bitrate int
+	}
+	groups := make(map[string]groupInfo)
+	for _, st := range streams {
+		group := st.attrs["AUDIO"]
+		bandwidth, err := strconv.Atoi(st.attrs["BANDWIDTH"])
+		if group == "" || err != nil {
+			continue
+		}
+		g := groups[group]
+		if _, entry, ok := strings.Cut(st.attrs["CODECS"], ","); ok && g.entry == "" {
+			g.entry = entry
+		}
+		if base, ok := plain[st.uri]; ok && bandwidth-base > g.bitrate {
+			g.bitrate = bandwidth - base
+		}
+		groups[group] = g
+	}
+
+	for _, line := range strings.Split(raw, "\n") {
+		media, ok := strings.CutPrefix(strings.TrimSpace(line), "#EXT-X-MEDIA:")
+		if !ok {
+			continue
+		}
+		attrs := helpers.ParseHLSAttributes(media)
+		uri, _, _ := strings.Cut(attrs["URI"], "?") // Re-signed on write
+		switch attrs["TYPE"] {
+		case "AUDIO":
+			channels, _ := strconv.Atoi(strings.SplitN(attrs["CHANNELS"], "/", 2)[0])
+			g := groups[attrs["GROUP-ID"]]
+			out.Audio = append(out.Audio, AudioRendition{
+				Group:    attrs["GROUP-ID"],
+				Name:     attrs["NAME"],
+				Language: attrs["LANGUAGE"],
+				Default:  attrs["DEFAULT"] == "YES",
+				Codec:    audioFamilyFromEntry(g.entry),
+				Codecs:   g.entry,
+				Channels: channels,
+				Bitrate:  g.bitrate,
+				Playlist: uri,
+
+				Role:            roleFromCharacteristics(attrs["CHARACTERISTICS"]),
+				Characteristics: attrs["CHARACTERISTICS"],
+			})
+		case "SUBTITLES":
+			out.Subtitles = append(out.Subtitles, SubtitleRendition{
+				Group:    attrs["GROUP-ID"],
+				Name:     attrs["NAME"],
+				Language: attrs["LANGUAGE"],
+				Default:  attrs["DEFAULT"] == "YES",
+				Forced:   attrs["FORCED"] == "YES",
+				Playlist: uri,
+			})
+		case "CLOSED-CAPTIONS":
+			out.ClosedCaptions = append(out.ClosedCaptions, ClosedCaptionRendition{
+				Group:      attrs["GROUP-ID"],
+				Name:       attrs["NAME"],
+				Language:   attrs["LANGUAGE"],
+				Default:    attrs["DEFAULT"] == "YES",
+				InstreamID: attrs["INSTREAM-ID"],
+			})
+		}
+	}
+	return out
+}
+
+// roleFromCharacteristics returns the AudioRendition.Role implied by an HLS
+// CHARACTERISTICS value: "description" for audio description, "" otherwise.
+func roleFromCharacteristics(characteristics string) string {
+	for _, c := range strings.Split(characteristics, ",") {
+		if c == DescribesVideo {
+			return "description"
+		}
+	}
+	return ""
+}
+
+// audioFamilyFromEntry returns the audio codec family of an RFC 6381 CODECS
+// entry, or "" if unknown.
+func audioFamilyFromEntry(entry string) string {
+	switch {
+	case entry == "ec-3":
+		return "eac3"
+	case entry == "ac-3":
+		return "ac3"
+	case entry == "mp4a.40.34":
+		return "mp3"
+	case strings.HasPrefix(entry, "mp4a"):
+		return "aac"
+	case entry == "Opus":
+		return "opus"
+	case entry == "fLaC":
+		return "flac"
+	case entry == "alac":
+		return "alac"
+	}
+	return ""
+}
+
+// mergeRenditions returns opts with the renditions of an existing master
+// merged in. New renditions without an explicit group join the existing
+// group for their codec (audio) or the existing subtitle and caption groups,
+// so a language added later lands next to the ones already published. An
+// existing rendition is replaced by a new one with the same URI, or the same
+// group and NAME; a new DEFAULT=YES rendition clears the default of the
+// others in its group. Existing renditions keep their order ahead of new ones.
+func mergeRenditions(existing hlsRenditions, opts ManifestOptions) ManifestOptions {
+	// Audio: adopt existing group IDs by CODECS entry
+	audioGroupFor := make(map[string]string)
+	for _, a := range existing.Audio {
+		if _, ok := audioGroupFor[a.Codecs]; !ok && a.Codecs != "" {
+			audioGroupFor[a.Codecs] = a.Group
+		}
+	}
+	added := make([]AudioRendition, len(opts.Audio))
+	for i, a := range opts.Audio {
+		if id, ok := audioGroupFor[a.Codecs]; ok && a.Group == "" {
+			a.Group = id
+		}
+		added[i] = a
+	}
+	var audio []AudioRendition
+	for _, e := range existing.Audio {
+		keep := true
+		for _, a := range added {
+			switch {
+			case a.Playlist != "" && a.Playlist == e.Playlist, a.groupID() == e.groupID() && a.Name == e.Name:
+				keep = false
+			case a.Default && a.groupID() == e.groupID():
+				e.Default = false
+			}
+		}
+		if keep {
+			audio = append(audio, e)
+		}
+	}
+	opts.Audio = append(audio, added...)
+
+	// Subtitles: one group, shared by every variant
+	subs := make([]SubtitleRendition, len(opts.Subtitles))
+	for i, s := range opts.Subtitles {
+		if len(existing.Subtitles) > 0 && s.Group == "" {
+			s.Group = existing.Subtitles[0].groupID()
+		}
+		subs[i] = s
+	}
+	var subtitles []SubtitleRendition
+	for _, e := range existing.Subtitles {
+		keep := true
+		for _, s := range subs {
+			switch {
+			case s.Playlist != "" && s.Playlist == e.Playlist, s.groupID() == e.groupID() && s.Name == e.Name:
+				keep = false
+			case s.Default && s.groupID() == e.groupID():
+				e.Default = false
+			}
+		}
+		if keep {
+			subtitles = append(subtitles, e)
+		}
+	}
+	opts.Subtitles = append(subtitles, subs...)
+
+	// Closed captions: one group, matched by INSTREAM-ID
+	ccs := make([]ClosedCaptionRendition, len(opts.ClosedCaptions))
+	for i, c := range opts.ClosedCaptions {
+		if len(existing.ClosedCaptions) > 0 && c.Group == "" {
+			c.Group = existing.ClosedCaptions[0].groupID()
+		}
+		ccs[i] = c
+	}
+	var captions []ClosedCaptionRendition
+	for _, e := range existing.ClosedCaptions {
+		keep := true
+		for _, c := range ccs {
+			switch {
+			case c.groupID() == e.groupID() && (c.InstreamID == e.InstreamID || c.Name == e.Name):
+				keep = false
+			case c.Default && c.groupID() == e.groupID():
+				e.Default = false
+			}
+		}
+		if keep {
+			captions = append(captions, e)
+		}
+	}
+	opts.ClosedCaptions = append(captions, ccs...)
+	return opts
+}
@@ -0,0 +1,241 @@
+// Package manifester provides URL signing for generated manifests.
+// This file appends signed query tokens (or templated placeholders that a CDN
+// edge fills in) to variant and segment URIs.
+package manifester
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// URLSigner produces the query token appended to a URI in a manifest.
+// path is the referenced file relative to the output directory, in slash
+// form (e.g. "720p_3000kbps/segment_000.ts"), whatever directory the
+// referencing manifest lives in. The returned query has no leading "?".
+type URLSigner interface {
+	Sign(path string) (string, error)
+}
+
+// URLSignerFunc adapts a function into a URLSigner.
+type URLSignerFunc func(path string) (string, error)
+
+// Sign calls f.
+func (f URLSignerFunc) Sign(path string) (string, error) {
+	return f(path)
+}
+
+// TemplateSigner is implemented by URLSigners whose token is valid for every
+// URL a DASH SegmentTemplate expands to, such as placeholders the edge fills
+// in or tokens scoped to a directory. Only these can sign template-based DASH
+// manifests: a per-URL signature over "chunk-$Number%05d$.m4s" matches no
+// real segment.
+type TemplateSigner interface {
+	URLSigner
+	// SignTemplate returns the query for a SegmentTemplate pattern path.
+	SignTemplate(pattern string) (string, error)
+}
+
+// ErrTemplateSigning is returned when a signer that signs individual URLs is
+// used on a template-based DASH manifest.
+var ErrTemplateSigning = errors.New("signer can't sign DASH segment templates")
+
+// placeholderSigner appends a fixed param=placeholder query.
+type placeholderSigner struct{ query string }
+
+// Sign returns the placeholder query.
+func (s placeholderSigner) Sign(string) (string, error) { return s.query, nil }
+
+// SignTemplate returns the placeholder query; the edge fills in the token
+// for whichever segment is requested.
+func (s placeholderSigner) SignTemplate(string) (string, error) { return s.query, nil }
+
+// TokenPlaceholder returns a signer that appends param=placeholder verbatim,
+// e.g. TokenPlaceholder("token", "{{TOKEN}}") writes "segment_000.ts?token={{TOKEN}}"
+// for a CDN or origin that substitutes per-viewer tokens at request time.
+// It signs DASH segment templates too.
+func TokenPlaceholder(param, placeholder string) URLSigner {
+	return placeholderSigner{query: url.QueryEscape(param) + "=" + placeholder}
+}
+
+// HMACSigner signs URIs with an expiry and an HMAC-SHA256 over
+// "<prefix><path>?expires=<unix>", the scheme most token-auth CDNs accept.
+// Produces "expires=<unix>&signature=<hex>".
+//
+// With Directory set it signs "<prefix><dir>/?expires=<unix>" instead, where
+// dir is the directory of the referenced file, and adds "scope=<dir>/" so the
+// edge can check one token against every file of a rendition. Only
+// directory-scoped signers can sign DASH segment templates.
+type HMACSigner struct {
+	Secret    []byte           // Shared secret configured on the CDN
+	TTL       time.Duration    // How long signed URLs stay valid
+	Prefix    string           // Path prefix the CDN serves the output directory under (e.g. "/vod/movie/")
+	Directory bool             // Scope each token to the file's directory rather than the file
+	Now       func() time.Time // Clock used for expiry; nil uses time.Now
+}
+
+// NewHMACSigner returns an HMACSigner with the given secret, lifetime, and path prefix.
+func NewHMACSigner(secret []byte, ttl time.Duration, prefix string) *HMACSigner {
+	return &HMACSigner{Secret: secret, TTL: ttl, Prefix: prefix}
+}
+
+// Sign returns the expiry and signature query for path.
+func (s *HMACSigner) Sign(p string) (string, error) {
+	if len(s.Secret) == 0 {
+		return "", fmt.Errorf("hmac signer has no secret")
+	}
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	expires := strconv.FormatInt(now().Add(s.TTL).Unix(), 10)
+	scope := ""
+	if s.Directory {
+		p = path.Dir(p) + "/"
+		if p == "./" {
+			p = ""
+		}
+		scope = "scope=" + url.QueryEscape(p) + "&"
+	}
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(s.Prefix + p + "?expires=" + expires))
+	return scope + "expires=" + expires + "&signature=" + hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// SignTemplate signs the directory of a SegmentTemplate pattern. Signers
+// without Directory sign individual files, so they return ErrTemplateSigning.
+func (s *HMACSigner) SignTemplate(pattern string) (string, error) {
+	if !s.Directory {
+		return "", fmt.Errorf("%w: HMAC signatures cover single files; set Directory to scope them to each rendition", ErrTemplateSigning)
+	}
+	return s.Sign(pattern)
+}
+
+// signURI appends signer's token for target (relative to the output
+// directory) to uri, replacing any query a previous run added.
+func signURI(signer URLSigner, uri, target string) (string, error) {
+	base, _, _ := strings.Cut(uri, "?")
+	query, err := signer.Sign(target)
+	if err != nil {
+		return "", err
+	}
+	if query == "" {
+		return base, nil
+	}
+	return base + "?" + query, nil
+}
+
+// relativeTarget resolves uri, as written in the manifest at manifestPath,
+// to a slash path relative to outputDir.
+func relativeTarget(outputDir, manifestPath, uri string) string {
+	base, _, _ := strings.Cut(uri, "?")
+	full := filepath.Join(filepath.Dir(manifestPath), filepath.FromSlash(base))
+	if rel, err := filepath.Rel(outputDir, full); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path.Clean(base)
+}
+
+// hlsURIAttr matches URI="..." attributes (EXT-X-MAP, EXT-X-MEDIA, ...).
+var hlsURIAttr = regexp.MustCompile(`URI="([^"]*)"`)
+
+// signHLSPlaylist rewrites a variant playlist in place, signing every segment
+// and init-segment URI. Absolute URIs (such as key servers) are left alone.
+func signHLSPlaylist(outputDir, playlistPath string, signer URLSigner) error {
+	raw, err := os.ReadFile(playlistPath)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(string(raw), "\n")
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "#EXT-X-KEY"), strings.HasPrefix(trimmed, "#EXT-X-SESSION-KEY"):
+			// Key URIs point at the license server, which has its own auth
+		case strings.HasPrefix(trimmed, "#"):
+			var signErr error
+			lines[i] = hlsURIAttr.ReplaceAllStringFunc(line, func(m string) string {
+				uri := hlsURIAttr.FindStringSubmatch(m)[1]
+				if isAbsoluteURI(uri) {
+					return m
+				}
+				signed, err := signURI(signer, uri, relativeTarget(outputDir, playlistPath, uri))
+				if err != nil {
+					signErr = err
+					return m
+				}
+				return `URI="` + signed + `"`
+			})
+			if signErr != nil {
+				return signErr
+			}
+		case !isAbsoluteURI(trimmed):
+			signed, err := signURI(signer, trimmed, relativeTarget(outputDir, playlistPath, trimmed))
+			if err != nil {
+				return err
+			}
+			lines[i] = signed
+		}
+	}
+	return writeFileAtomic(playlistPath, []byte(strings.Join(lines, "\n")))
+}
+
+// dashTemplateAttr matches SegmentTemplate media/initialization attributes.
+var dashTemplateAttr = regexp.MustCompile(`(media|initialization)="([^"]*)"`)
+
+// signDASHManifest appends tokens to the SegmentTemplate media and
+// initialization patterns of a variant MPD. The signer sees the template
+// path (e.g. "720p_3000kbps/chunk-stream0-$Number%05d$.m4s"), so per-file
+// signatures are not possible: signers that aren't TemplateSigners are
+// rejected with ErrTemplateSigning.
+func signDASHManifest(outputDir, mpdPath string, signer URLSigner) error {
+	ts, ok := signer.(TemplateSigner)
+	if !ok {
+		return fmt.Errorf("%w: %T signs individual URLs; use TokenPlaceholder or a directory-scoped HMACSigner", ErrTemplateSigning, signer)
+	}
+	signer = URLSignerFunc(ts.SignTemplate)
+	raw, err := os.ReadFile(mpdPath)
+	if err != nil {
+		return err
+	}
+	var signErr error
+	out := dashTemplateAttr.ReplaceAllStringFunc(string(raw), func(m string) string {
+		parts := dashTemplateAttr.FindStringSubmatch(m)
+		uri := parts[2]
+		if isAbsoluteURI(uri) {
+			return m
+		}
+		// Templates are already XML-escaped; tokens must be escaped too
+		signed, err := signURI(signer, strings.ReplaceAll(uri, "&amp;", "&"), relativeTarget(outputDir, mpdPath, uri))
+		if err != nil {
+			signErr = err
+			return m
+		}
+		return parts[1] + `="` + xmlEscape(signed) + `"`
+	})
+	if signErr != nil {
+		return signErr
+	}
+	return writeFileAtomic(mpdPath, []byte(out))
+}
+
+// isAbsoluteURI reports whether uri has a scheme or is host-relative.
+func isAbsoluteURI(uri string) bool {
+	return strings.Contains(uri, "://") || strings.HasPrefix(uri, "/") || strings.HasPrefix(uri, "data:")
+}
+
+// xmlEscape escapes the characters that may appear in a signed query.
+func xmlEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "\"", "&quot;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,275 @@
+package manifester
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// dashStream is one Representation of a variant MPD as the packager wrote
+// it: what it carries and the SegmentTemplate that addresses its segments.
+type dashStream struct {
+	ID          string
+	ContentType string // "video", "audio", or "text"
+	MimeType    string
+	Codecs      string
+	Bandwidth   int
+	Template    *dashTemplate
+	Protection  []string // ContentProtection elements, verbatim
+}
+
+// dashTemplate is a SegmentTemplate with its timeline expanded.
+type dashTemplate struct {
+	Timescale      int64
+	Initialization string
+	Media          string
+	StartNumber    int64
+	Duration       int64         // Segment duration of templates without a timeline
+	Segments       []dashSegment // SegmentTimeline, one entry per segment, in media time
+}
+
+// dashSegment is a segment's start and duration in the template timescale.
+type dashSegment struct{ T, D int64 }
+
+// readDASHStreams returns the representations of the variant MPD at mpdPath
+// that have a SegmentTemplate, with $RepresentationID$ and $Bandwidth$
+// resolved so the templates stay valid under the master's ids.
+func readDASHStreams(mpdPath string) ([]dashStream, error) {
+	raw, err := os.ReadFile(mpdPath)
+	if err != nil {
+		return nil, err
+	}
+	d := xml.NewDecoder(bytes.NewReader(raw))
+	var (
+		streams     []dashStream
+		set, rep    *dashStream
+		setTemplate *dashTemplate
+	)
+	for {
+		start := d.InputOffset()
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "AdaptationSet":
+				set, setTemplate = &dashStream{}, nil
+				set.describe(t.Attr)
+			case "Representation":
+				if set == nil {
+					continue
+				}
+				s := *set
+				s.Protection = slices.Clone(set.Protection)
+				s.describe(t.Attr)
+				rep = &s
+			case "ContentProtection":
+				if err := d.Skip(); err != nil {
+					return nil, err
+				}
+				// Copied as written so DRM-specific children and prefixes survive
+				element := string(raw[start:d.InputOffset()])
+				if rep != nil {
+					rep.Protection = append(rep.Protection, element)
+				} else if set != nil {
+					set.Protection = append(set.Protection, element)
+				}
+			case "SegmentTemplate":
+				tmpl, err := readSegmentTemplate(d, t)
+				if err != nil {
+					return nil, err
+				}
+				if rep != nil {
+					rep.Template = tmpl
+				} else {
+					setTemplate = tmpl
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "Representation":
+				if rep == nil {
+					continue
+				}
+				if rep.Template == nil && setTemplate != nil {
+					tmpl := *setTemplate
+					rep.Template = &tmpl
+				}
+				if rep.Template != nil {
+					r := strings.NewReplacer("$RepresentationID$", rep.ID, "$Bandwidth$", strconv.Itoa(rep.Bandwidth))
+					rep.Template.Initialization = r.Replace(rep.Template.Initialization)
+					rep.Template.Media = r.Replace(rep.Template.Media)
+					streams = append(streams, *rep)
+				}
+				rep = nil
+			case "AdaptationSet":
+				set, setTemplate = nil, nil
+			}
+		}
+	}
+	return streams, nil
+}
+
+// describe fills in s from AdaptationSet or Representation attributes.
+func (s *dashStream) describe(attrs []xml.Attr) {
+	for _, a := range attrs {
+		switch a.Name.Local {
+		case "id":
+			s.ID = a.Value
+		case "contentType":
+			s.ContentType = a.Value
+		case "mimeType":
+			s.MimeType = a.Value
+		case "codecs":
+			s.Codecs = a.Value
+		case "bandwidth":
+			s.Bandwidth, _ = strconv.Atoi(a.Value)
+		}
+	}
+	if s.ContentType == "" {
+		s.ContentType, _, _ = strings.Cut(s.MimeType, "/")
+	}
+}
+
+// readSegmentTemplate reads the SegmentTemplate that start opens, through
+// its end element.
+func readSegmentTemplate(d *xml.Decoder, start xml.StartElement) (*dashTemplate, error) {
+	tmpl := &dashTemplate{Timescale: 1, StartNumber: 1}
+	for _, a := range start.Attr {
+		switch a.Name.Local {
+		case "timescale":
+			tmpl.Timescale, _ = strconv.ParseInt(a.Value, 10, 64)
+		case "initialization":
+			tmpl.Initialization = a.Value
+		case "media":
+			tmpl.Media = a.Value
+		case "startNumber":
+			tmpl.StartNumber, _ = strconv.ParseInt(a.Value, 10, 64)
+		case "duration":
+			tmpl.Duration, _ = strconv.ParseInt(a.Value, 10, 64)
+		}
+	}
+	if tmpl.Timescale <= 0 {
+		return nil, fmt.Errorf("SegmentTemplate timescale %d is not positive", tmpl.Timescale)
+	}
+	next := int64(0)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "S" {
+				continue
+			}
+			var dur, repeat int64
+			for _, a := range t.Attr {
+				v, _ := strconv.ParseInt(a.Value, 10, 64)
+				switch a.Name.Local {
+				case "t":
+					next = v
+				case "d":
+					dur = v
+				case "r":
+					// Negative repeats run to the end of a live period; VOD has none
+					repeat = max(v, 0)
+				}
+			}
+			if dur <= 0 {
+				return nil, fmt.Errorf("SegmentTimeline entry at %d has no duration", next)
+			}
+			for range repeat + 1 {
+				tmpl.Segments = append(tmpl.Segments, dashSegment{T: next, D: dur})
+				next += dur
+			}
+		case xml.EndElement:
+			if t.Name.Local == "SegmentTemplate" {
+				return tmpl, nil
+			}
+		}
+	}
+}
+
+// writeSegmentTemplate writes tmpl for the period covering content seconds
+// [from, to), with its paths under dir, the variant's directory relative to
+// the master. The timeline lists only the segments overlapping the period,
+// numbered from the first of them, and presentationTimeOffset (in the
+// template timescale) trims the part of that segment before from.
+func writeSegmentTemplate(b *strings.Builder, tmpl *dashTemplate, dir string, from, to float64) error {
+	initialization, media := xmlEscape(underDir(dir, tmpl.Initialization)), xmlEscape(underDir(dir, tmpl.Media))
+	if len(tmpl.Segments) == 0 {
+		if from > 0 || !math.IsInf(to, 1) {
+			return fmt.Errorf("SegmentTemplate has no SegmentTimeline to split into periods")
+		}
+		fmt.Fprintf(b, `        <SegmentTemplate timescale="%d" duration="%d" startNumber="%d" initialization="%s" media="%s"/>`+"\n",
+			tmpl.Timescale, tmpl.Duration, tmpl.StartNumber, initialization, media)
+		return nil
+	}
+
+	base := tmpl.Segments[0].T
+	lo, hi := base+int64(math.Round(from*float64(tmpl.Timescale))), int64(math.MaxInt64)
+	if !math.IsInf(to, 1) {
+		hi = base + int64(math.Round(to*float64(tmpl.Timescale)))
+	}
+	first := -1
+	var segments []dashSegment
+	for i, s := range tmpl.Segments {
+		if s.T < hi && s.T+s.D > lo {
+			if first < 0 {
+				first = i
+			}
+			segments = append(segments, s)
+		}
+	}
+	if len(segments) == 0 {
+		return fmt.Errorf("no segments between %s and %s", isoDuration(from), isoDuration(to))
+	}
+
+	offset := ""
+	if lo > 0 {
+		offset = fmt.Sprintf(` presentationTimeOffset="%d"`, lo)
+	}
+	fmt.Fprintf(b, `        <SegmentTemplate timescale="%d"%s startNumber="%d" initialization="%s" media="%s">`+"\n",
+		tmpl.Timescale, offset, tmpl.StartNumber+int64(first), initialization, media)
+	b.WriteString(`          <SegmentTimeline>` + "\n")
+	for i := 0; i < len(segments); {
+		// Collapse contiguous runs of equal durations into one S@r entry
+		j := i + 1
+		for j < len(segments) && segments[j].D == segments[i].D && segments[j].T == segments[j-1].T+segments[j-1].D {
+			j++
+		}
+		t := ""
+		if i == 0 || segments[i].T != segments[i-1].T+segments[i-1].D {
+			t = fmt.Sprintf(` t="%d"`, segments[i].T)
+		}
+		repeat := ""
+		if j-i > 1 {
+			repeat = fmt.Sprintf(` r="%d"`, j-i-1)
+		}
+		fmt.Fprintf(b, `            <S%s d="%d"%s/>`+"\n", t, segments[i].D, repeat)
+		i = j
+	}
+	b.WriteString(`          </SegmentTimeline>` + "\n")
+	b.WriteString(`        </SegmentTemplate>` + "\n")
+	return nil
+}
+
+// underDir prefixes a relative template path with dir.
+func underDir(dir, uri string) string {
+	if uri == "" || dir == "" || dir == "." || isAbsoluteURI(uri) {
+		return uri
+	}
+	return dir + "/" + uri
+}
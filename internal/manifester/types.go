@@ -9,4 +9,76 @@ type ManifestMeta struct {
 	Bitrate     int    // e.g. 3000000 (in bits per second)
 	Resolution  string // e.g. "1280x720"
 	ManifestURL string // relative or absolute path to manifest
+
+	Codecs string  // RFC 6381 CODECS, e.g. "hvc1.1.6.L120.B0,mp4a.40.2" ("" if unknown)
+	Codec  string  // Video codec family, e.g. "hevc" ("" if unknown)
+	Score  float64 // HLS SCORE preference, written only when several codec tiers are listed
+}
+
+// DescribesVideo is the HLS CHARACTERISTICS value marking an audio
+// description rendition.
+const DescribesVideo = "public.accessibility.describes-video"
+
+// AudioRendition is an alternate audio track listed in the master manifest.
+// Renditions sharing a codec form one HLS audio group, and each video variant
+// is listed once more per group. Paths are relative to the output directory.
+type AudioRendition struct {
+	Group    string // HLS GROUP-ID; default "audio-<codec>"
+	Name     string // NAME shown by players
+	Language string // LANGUAGE tag, "" if unknown
+	Default  bool   // DEFAULT=YES; at most one rendition per codec should set it
+	Codec    string // Codec family (e.g. "eac3"), naming the group
+	Codecs   string // RFC 6381 CODECS entry (e.g. "ec-3")
+	Channels int    // CHANNELS attribute (e.g. 6 for 5.1)
+	Bitrate  int    // Bits per second, added to each variant's BANDWIDTH
+	Playlist string // HLS playlist or DASH manifest (e.g. "audio/eac3-eng/eac3-eng.m3u8"); "" for audio muxed into the variants
+
+	Role            string // DASH Role beyond main/alternate: "description" or "commentary"; "" otherwise
+	Characteristics string // HLS CHARACTERISTICS (e.g. "public.accessibility.describes-video"); "" for none
+}
+
+// groupID returns the rendition's HLS GROUP-ID.
+func (a AudioRendition) groupID() string {
+	if a.Group != "" {
+		return a.Group
+	}
+	return audioGroupID(a.Codec)
+}
+
+// SubtitleRendition is a WebVTT subtitle track listed in the master manifest.
+// Paths are relative to the output directory.
+type SubtitleRendition struct {
+	Group    string // HLS GROUP-ID; default "subs"
+	Name     string // NAME shown by players
+	Language string // LANGUAGE tag, "" if unknown
+	Default  bool   // DEFAULT=YES; at most one rendition should set it
+	Forced   bool   // FORCED=YES: forced-narrative subtitles for foreign-language dialogue
+	Playlist string // HLS subtitle playlist (e.g. "subtitles/en.m3u8")
+	VTT      string // WebVTT file referenced directly by DASH (e.g. "subtitles/en.vtt")
+}
+
+// groupID returns the rendition's HLS GROUP-ID.
+func (s SubtitleRendition) groupID() string {
+	if s.Group != "" {
+		return s.Group
+	}
+	return subtitleGroup
+}
+
+// ClosedCaptionRendition declares CEA-608/708 captions carried in the video
+// of every variant. HLS only; DASH masters ignore them.
+type ClosedCaptionRendition struct {
+	Group      string // HLS GROUP-ID; default "cc"
+	Name       string // NAME shown by players
+	Language   string // LANGUAGE tag, "" if unknown
+	Default    bool   // DEFAULT=YES; at most one rendition should set it
+	InstreamID string // INSTREAM-ID (e.g. "CC1", "SERVICE1")
+}
+
+// groupID returns the rendition's HLS GROUP-ID.
+func (c ClosedCaptionRendition) groupID() string {
+	if c.Group != "" {
+		return c.Group
+	}
+	return captionGroup
 }
@@ -0,0 +1,52 @@
+package manifester
+
+import (
+	"fmt"
+	"strings"
+)
+
+// hlsFeature is something a master or its playlists use that needs a
+// minimum #EXT-X-VERSION (RFC 8216 section 7).
+type hlsFeature struct {
+	name    string
+	version int
+}
+
+// hlsFeatures lists the versioned features of a master with entries and
+// opts, and of the variant playlists it references. ffmpeg writes fMP4
+// playlists as version 7 and MPEG-TS ones with fractional EXTINF durations.
+func hlsFeatures(entries []ManifestMeta, opts ManifestOptions) []hlsFeature {
+	features := []hlsFeature{{"fractional EXTINF durations", 3}}
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		if (e.Codec == "hevc" || e.Codec == "av1") && !seen[e.Codec] {
+			seen[e.Codec] = true
+			features = append(features, hlsFeature{fmt.Sprintf("fMP4 segments (%s variants)", e.Codec), 7})
+		}
+	}
+	for _, c := range opts.ClosedCaptions {
+		if strings.HasPrefix(c.InstreamID, "SERVICE") {
+			features = append(features, hlsFeature{"CEA-708 INSTREAM-ID " + c.InstreamID, 7})
+			break
+		}
+	}
+	return features
+}
+
+// negotiateHLSVersion returns the lowest #EXT-X-VERSION supporting every
+// feature, or an error naming the features a compatibility target of limit
+// (0 for none) forbids.
+func negotiateHLSVersion(features []hlsFeature, limit int) (int, error) {
+	version := 1
+	var over []string
+	for _, f := range features {
+		version = max(version, f.version)
+		if limit > 0 && f.version > limit {
+			over = append(over, fmt.Sprintf("%s (version %d)", f.name, f.version))
+		}
+	}
+	if len(over) > 0 {
+		return 0, fmt.Errorf("HLS version target %d forbids %s", limit, strings.Join(over, "; "))
+	}
+	return version, nil
+}
@@ -0,0 +1,161 @@
+// Package metrics exposes Prometheus counters and histograms for pipeline runs.
+// A single Recorder is shared across the pipeline so long-running deployments
+// can scrape job throughput, per-stage durations, and encode speed from /metrics.
+// Job counters are labeled by tenant (see TranscodeProfile.Tenant), so one
+// deployment serving several catalogs can tell their workloads apart. Only
+// the first MaxTenantLabels tenants get a label of their own; later ones are
+// counted under OtherTenant, so callers naming arbitrary tenants can't grow
+// the series without bound.
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Recorder owns a Prometheus registry and the collectors registered on it.
+// All methods are safe for concurrent use.
+type Recorder struct {
+	registry       *prometheus.Registry
+	jobsStarted    *prometheus.CounterVec
+	jobsCompleted  *prometheus.CounterVec
+	jobsFailed     *prometheus.CounterVec
+	stageDuration  *prometheus.HistogramVec
+	realtimeFactor prometheus.Histogram
+	queueDepth     prometheus.Gauge
+
+	tenantMu sync.Mutex
+	tenants  map[string]bool // Tenants with a label of their own
+}
+
+// Tenant label limits.
+const (
+	MaxTenantLabels = 100     // Distinct tenant labels a Recorder reports
+	OtherTenant     = "other" // Label of tenants past MaxTenantLabels
+)
+
+// Default is the process-wide recorder used by the pipeline package.
+var Default = NewRecorder()
+
+// NewRecorder creates a Recorder backed by a fresh registry.
+// Go runtime and process collectors are registered alongside pipeline metrics.
+func NewRecorder() *Recorder {
+	r := &Recorder{
+		registry: prometheus.NewRegistry(),
+		tenants:  make(map[string]bool),
+		jobsStarted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "dotgo",
+			Name:      "jobs_started_total",
+			Help:      "Number of pipeline jobs started.",
+		}, []string{"tenant"}),
+		jobsCompleted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "dotgo",
+			Name:      "jobs_completed_total",
+			Help:      "Number of pipeline jobs that produced a master manifest.",
+		}, []string{"tenant"}),
+		jobsFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "dotgo",
+			Name:      "jobs_failed_total",
+			Help:      "Number of pipeline jobs that aborted with an error.",
+		}, []string{"tenant"}),
+		stageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "dotgo",
+			Name:      "stage_duration_seconds",
+			Help:      "Wall-clock duration of each pipeline stage.",
+			Buckets:   prometheus.ExponentialBuckets(0.5, 2, 16), // 0.5s .. ~4.5h
+		}, []string{"stage"}),
+		realtimeFactor: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "dotgo",
+			Name:      "encode_realtime_factor",
+			Help:      "Seconds of source media transcoded (full ladder) per second of wall-clock time.",
+			Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 4, 8, 16, 32},
+		}),
+		queueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "dotgo",
+			Name:      "queue_depth",
+			Help:      "Number of jobs waiting to be processed.",
+		}),
+	}
+
+	r.registry.MustRegister(
+		r.jobsStarted,
+		r.jobsCompleted,
+		r.jobsFailed,
+		r.stageDuration,
+		r.realtimeFactor,
+		r.queueDepth,
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+	return r
+}
+
+// JobStarted increments the started-jobs counter for tenant ("" for none).
+func (r *Recorder) JobStarted(tenant string) {
+	r.jobsStarted.WithLabelValues(r.tenantLabel(tenant)).Inc()
+}
+
+// JobCompleted increments the completed-jobs counter for tenant.
+func (r *Recorder) JobCompleted(tenant string) {
+	r.jobsCompleted.WithLabelValues(r.tenantLabel(tenant)).Inc()
+}
+
+// JobFailed increments the failed-jobs counter for tenant.
+func (r *Recorder) JobFailed(tenant string) {
+	r.jobsFailed.WithLabelValues(r.tenantLabel(tenant)).Inc()
+}
+
+// tenantLabel returns the label tenant is counted under: itself while fewer
+// than MaxTenantLabels tenants have been seen, else OtherTenant.
+func (r *Recorder) tenantLabel(tenant string) string {
+	if tenant == "" {
+		return ""
+	}
+	r.tenantMu.Lock()
+	defer r.tenantMu.Unlock()
+	if r.tenants[tenant] {
+		return tenant
+	}
+	if len(r.tenants) >= MaxTenantLabels {
+		return OtherTenant
+	}
+	r.tenants[tenant] = true
+	return tenant
+}
+
+// ObserveStage records how long a pipeline stage took (e.g. "analyze", "transcode").
+func (r *Recorder) ObserveStage(stage string, d time.Duration) {
+	r.stageDuration.WithLabelValues(stage).Observe(d.Seconds())
+}
+
+// ObserveRealtimeFactor records media seconds encoded per wall-clock second.
+// Values above 1 mean the encode ran faster than real time.
+func (r *Recorder) ObserveRealtimeFactor(mediaSeconds float64, elapsed time.Duration) {
+	if mediaSeconds <= 0 || elapsed <= 0 {
+		return
+	}
+	r.realtimeFactor.Observe(mediaSeconds / elapsed.Seconds())
+}
+
+// SetQueueDepth reports how many jobs are waiting to run.
+// Intended for schedulers and job queues sitting in front of the pipeline.
+func (r *Recorder) SetQueueDepth(n int) { r.queueDepth.Set(float64(n)) }
+
+// Registry returns the underlying registry so callers can add their own collectors.
+func (r *Recorder) Registry() *prometheus.Registry { return r.registry }
+
+// Handler returns an http.Handler serving the registry in Prometheus exposition format.
+func (r *Recorder) Handler() http.Handler {
+	return promhttp.HandlerFor(r.registry, promhttp.HandlerOpts{Registry: r.registry})
+}
+
+// ListenAndServe starts a blocking HTTP server exposing the registry at /metrics.
+func (r *Recorder) ListenAndServe(addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", r.Handler())
+	return http.ListenAndServe(addr, mux)
+}
@@ -0,0 +1,119 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"gopkg.in/yaml.v3"
+)
+
+// Sink types accepted in SinkConfig.Type.
+const (
+	SinkSlack     = "slack"
+	SinkEmail     = "email"
+	SinkPagerDuty = "pagerduty"
+	SinkWebhook   = "webhook"
+)
+
+// Config lists the notification sinks of a deployment, e.g.
+//
+//	sinks:
+//	  - type: pagerduty
+//	    routing_key: ${PD_ROUTING_KEY}
+//	    on: [failed]
+//	  - type: slack
+//	    url: ${SLACK_WEBHOOK_URL}
+//	    on: [failed, warnings]
+type Config struct {
+	Sinks []SinkConfig `json:"sinks" yaml:"sinks"`
+}
+
+// SinkConfig configures one sink. Which fields apply depends on Type.
+type SinkConfig struct {
+	Type       string   `json:"type" yaml:"type"`                                   // "slack", "email", "pagerduty", or "webhook"
+	On         []Kind   `json:"on,omitempty" yaml:"on,omitempty"`                   // Outcomes to report: "failed", "warnings", "succeeded" (default failed and warnings)
+	URL        string   `json:"url,omitempty" yaml:"url,omitempty"`                 // Slack incoming webhook or webhook endpoint
+	Channel    string   `json:"channel,omitempty" yaml:"channel,omitempty"`         // Slack channel override
+	RoutingKey string   `json:"routing_key,omitempty" yaml:"routing_key,omitempty"` // PagerDuty integration key
+	SMTP       string   `json:"smtp,omitempty" yaml:"smtp,omitempty"`               // SMTP server host:port
+	Username   string   `json:"username,omitempty" yaml:"username,omitempty"`       // SMTP user
+	Password   string   `json:"password,omitempty" yaml:"password,omitempty"`       // SMTP password
+	From       string   `json:"from,omitempty" yaml:"from,omitempty"`               // Email sender
+	To         []string `json:"to,omitempty" yaml:"to,omitempty"`                   // Email recipients
+}
+
+// LoadConfig reads a JSON or YAML sink config (by extension) and returns a
+// Notifier delivering to every sink. ${VAR} and ${VAR:-default} references
+// are expanded from the environment first, so secrets stay out of the file.
+func LoadConfig(path string) (Notifier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notification config: %w", err)
+	}
+	data = transcoder.ExpandEnv(data)
+
+	var config Config
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &config)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &config)
+	default:
+		return nil, fmt.Errorf("unsupported notification config extension %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse notification config %s: %w", path, err)
+	}
+	return config.Notifier()
+}
+
+// Notifier builds a Notifier delivering to every configured sink, or an
+// error naming the first invalid one.
+func (c Config) Notifier() (Notifier, error) {
+	var sinks Multi
+	for i, s := range c.Sinks {
+		n, err := s.notifier()
+		if err != nil {
+			return nil, fmt.Errorf("notification sink %d (%s): %w", i+1, s.Type, err)
+		}
+		for _, kind := range s.On {
+			if !slices.Contains([]Kind{JobFailed, JobWarnings, JobSucceeded}, kind) {
+				return nil, fmt.Errorf("notification sink %d (%s): unknown outcome %q; expected failed, warnings, or succeeded", i+1, s.Type, kind)
+			}
+		}
+		sinks = append(sinks, Only(n, s.On...))
+	}
+	return sinks, nil
+}
+
+// notifier builds the sink's Notifier, checking its required fields.
+func (s SinkConfig) notifier() (Notifier, error) {
+	switch s.Type {
+	case SinkSlack:
+		if s.URL == "" {
+			return nil, fmt.Errorf("url is required")
+		}
+		return Slack{WebhookURL: s.URL, Channel: s.Channel}, nil
+	case SinkWebhook:
+		if s.URL == "" {
+			return nil, fmt.Errorf("url is required")
+		}
+		return Webhook{URL: s.URL}, nil
+	case SinkPagerDuty:
+		if s.RoutingKey == "" {
+			return nil, fmt.Errorf("routing_key is required")
+		}
+		return PagerDuty{RoutingKey: s.RoutingKey}, nil
+	case SinkEmail:
+		if s.SMTP == "" || s.From == "" || len(s.To) == 0 {
+			return nil, fmt.Errorf("smtp, from, and to are required")
+		}
+		return Email{Addr: s.SMTP, Username: s.Username, Password: s.Password, From: s.From, To: s.To}, nil
+	}
+	return nil, fmt.Errorf("unknown sink type %q; expected slack, email, pagerduty, or webhook", s.Type)
+}
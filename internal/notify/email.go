@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Email sends each notification as a plain-text mail through an SMTP relay.
+// The connection is upgraded with STARTTLS when the server offers it;
+// credentials, when set, are only sent over TLS or to localhost.
+type Email struct {
+	Addr     string   // SMTP server host:port
+	Username string   // PLAIN auth user; "" sends without authenticating
+	Password string   // PLAIN auth password
+	From     string   // Sender address
+	To       []string // Recipient addresses
+}
+
+// Notify mails n to every recipient.
+func (e Email) Notify(ctx context.Context, n Notification) error {
+	if len(e.To) == 0 {
+		return fmt.Errorf("email notifier has no recipients")
+	}
+	host, _, err := net.SplitHostPort(e.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP address %q: %w", e.Addr, err)
+	}
+	if err := e.send(ctx, host, e.message(n)); err != nil {
+		if ctx.Err() != nil {
+			// Report the cancellation rather than the closed connection it caused
+			err = ctx.Err()
+		}
+		return fmt.Errorf("sending mail through %s: %w", e.Addr, err)
+	}
+	return nil
+}
+
+// send delivers msg over one SMTP session. The connection follows ctx:
+// its deadline bounds every read and write, and cancellation closes it.
+func (e Email) send(ctx context.Context, host string, msg []byte) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", e.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if e.Username != "" {
+		// PlainAuth refuses to send credentials in the clear except to localhost
+		if err := c.Auth(smtp.PlainAuth("", e.Username, e.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(e.From); err != nil {
+		return err
+	}
+	for _, to := range e.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// message formats n as an RFC 5322 message.
+func (e Email) message(n Notification) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", e.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", n.Summary()))
+	fmt.Fprintf(&b, "Date: %s\r\n", n.Time.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(n.Details(), "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,127 @@
+// Package notify tells people about finished jobs through pluggable sinks
+// (Slack, email, PagerDuty, or any JSON webhook), so encoding failures reach
+// someone instead of sitting in logs. Each sink is configured with the kinds
+// of outcome it cares about, e.g. failures paged to on-call and jobs that
+// succeeded with warnings posted to a team channel.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/errclass"
+)
+
+// Kind is the outcome a notification reports.
+type Kind string
+
+// Notification kinds.
+const (
+	JobFailed    Kind = "failed"    // The job failed
+	JobWarnings  Kind = "warnings"  // The job succeeded, but some stages reported non-fatal errors
+	JobSucceeded Kind = "succeeded" // The job succeeded cleanly
+)
+
+// DefaultKinds are the kinds a sink receives when its config lists none.
+var DefaultKinds = []Kind{JobFailed, JobWarnings}
+
+// Notification describes a finished job.
+type Notification struct {
+	Kind      Kind
+	JobID     string
+	Tenant    string // "" for none
+	InputPath string
+	Slug      string
+	Error     string         // Failure reason (JobFailed only)
+	Class     errclass.Class // Kind of failure (JobFailed only)
+	Warnings  []string       // Non-fatal stage errors
+	Elapsed   time.Duration
+	Time      time.Time // When the job finished
+}
+
+// Summary returns a one-line description, e.g. for a message title.
+func (n Notification) Summary() string {
+	name := n.Slug
+	if name == "" {
+		name = n.JobID
+	}
+	if n.Tenant != "" {
+		name = n.Tenant + "/" + name
+	}
+	switch n.Kind {
+	case JobFailed:
+		return fmt.Sprintf("❌ Transcode of %s failed", name)
+	case JobWarnings:
+		return fmt.Sprintf("⚠️ Transcode of %s finished with %d warning(s)", name, len(n.Warnings))
+	}
+	return fmt.Sprintf("✅ Transcode of %s finished", name)
+}
+
+// Details returns a multi-line description of the job and its errors.
+func (n Notification) Details() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Job: %s\n", n.JobID)
+	if n.Tenant != "" {
+		fmt.Fprintf(&b, "Tenant: %s\n", n.Tenant)
+	}
+	fmt.Fprintf(&b, "Input: %s\n", n.InputPath)
+	if n.Elapsed > 0 {
+		fmt.Fprintf(&b, "Elapsed: %s\n", n.Elapsed.Round(time.Second))
+	}
+	if n.Error != "" {
+		fmt.Fprintf(&b, "Error: %s\n", n.Error)
+	}
+	if n.Class != errclass.None {
+		fmt.Fprintf(&b, "Class: %s\n", n.Class)
+	}
+	for _, w := range n.Warnings {
+		fmt.Fprintf(&b, "Warning: %s\n", w)
+	}
+	return b.String()
+}
+
+// Notifier delivers notifications to one destination.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+// Func adapts a function into a Notifier.
+type Func func(ctx context.Context, n Notification) error
+
+// Notify calls f.
+func (f Func) Notify(ctx context.Context, n Notification) error {
+	return f(ctx, n)
+}
+
+// Only returns a Notifier passing notifications of the given kinds to n and
+// dropping the rest. With no kinds, DefaultKinds apply.
+func Only(n Notifier, kinds ...Kind) Notifier {
+	if len(kinds) == 0 {
+		kinds = DefaultKinds
+	}
+	return Func(func(ctx context.Context, note Notification) error {
+		if !slices.Contains(kinds, note.Kind) {
+			return nil
+		}
+		return n.Notify(ctx, note)
+	})
+}
+
+// Multi delivers each notification to every notifier in turn; one failing
+// doesn't stop the others. The errors are joined.
+type Multi []Notifier
+
+// Notify sends n to every notifier.
+func (m Multi) Notify(ctx context.Context, n Notification) error {
+	var errs []error
+	for _, notifier := range m {
+		if err := notifier.Notify(ctx, n); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// pagerDutyURL is the PagerDuty Events API v2 endpoint.
+const pagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDuty triggers an incident through the PagerDuty Events API v2 for
+// each notification. Repeat failures of the same input are deduplicated
+// into one open incident.
+type PagerDuty struct {
+	RoutingKey string       // Integration key of the service to page
+	Client     *http.Client // Default http.DefaultClient
+}
+
+// Notify triggers an event for n: "error" severity for failures, "warning"
+// otherwise.
+func (p PagerDuty) Notify(ctx context.Context, n Notification) error {
+	severity := "warning"
+	if n.Kind == JobFailed {
+		severity = "error"
+	}
+	dedup := n.InputPath
+	if n.Tenant != "" {
+		dedup = n.Tenant + ":" + dedup
+	}
+	return postJSON(ctx, p.Client, pagerDutyURL, map[string]any{
+		"routing_key":  p.RoutingKey,
+		"event_action": "trigger",
+		"dedup_key":    "dotgo-transcode:" + string(n.Kind) + ":" + dedup,
+		"payload": map[string]any{
+			"summary":   n.Summary(),
+			"source":    n.InputPath,
+			"severity":  severity,
+			"component": "dotgo-transcode",
+			"group":     n.Tenant,
+			"timestamp": n.Time.UTC().Format(time.RFC3339),
+			"custom_details": map[string]any{
+				"job_id":      n.JobID,
+				"slug":        n.Slug,
+				"error":       n.Error,
+				"error_class": n.Class,
+				"warnings":    n.Warnings,
+			},
+		},
+	})
+}
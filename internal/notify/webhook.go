@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Webhook POSTs each notification as JSON to URL.
+type Webhook struct {
+	URL    string
+	Client *http.Client // Default http.DefaultClient
+}
+
+// webhookBody is the JSON a Webhook posts.
+type webhookBody struct {
+	Kind      Kind     `json:"kind"`
+	Summary   string   `json:"summary"`
+	JobID     string   `json:"job_id"`
+	Tenant    string   `json:"tenant,omitempty"`
+	InputPath string   `json:"input_path"`
+	Slug      string   `json:"slug,omitempty"`
+	Error     string   `json:"error,omitempty"`
+	Class     string   `json:"error_class,omitempty"`
+	Warnings  []string `json:"warnings,omitempty"`
+	Elapsed   float64  `json:"elapsed_seconds"`
+	Time      string   `json:"time"`
+}
+
+// Notify posts n.
+func (w Webhook) Notify(ctx context.Context, n Notification) error {
+	return postJSON(ctx, w.Client, w.URL, webhookBody{
+		Kind:      n.Kind,
+		Summary:   n.Summary(),
+		JobID:     n.JobID,
+		Tenant:    n.Tenant,
+		InputPath: n.InputPath,
+		Slug:      n.Slug,
+		Error:     n.Error,
+		Class:     string(n.Class),
+		Warnings:  n.Warnings,
+		Elapsed:   n.Elapsed.Seconds(),
+		Time:      n.Time.UTC().Format(time.RFC3339),
+	})
+}
+
+// Slack posts each notification to a Slack incoming webhook.
+type Slack struct {
+	WebhookURL string
+	Channel    string       // Overrides the webhook's default channel, when the webhook allows it
+	Client     *http.Client // Default http.DefaultClient
+}
+
+// Notify posts n as a message with the details in a code block.
+func (s Slack) Notify(ctx context.Context, n Notification) error {
+	body := map[string]string{
+		"text": fmt.Sprintf("*%s*\n```%s```", n.Summary(), n.Details()),
+	}
+	if s.Channel != "" {
+		body["channel"] = s.Channel
+	}
+	return postJSON(ctx, s.Client, s.WebhookURL, body)
+}
+
+// postJSON posts body to endpoint, failing on a non-2xx response.
+func postJSON(ctx context.Context, client *http.Client, endpoint string, body any) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// Webhook paths often embed a secret, so errors only report the host
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("POST to %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		if msg = bytes.TrimSpace(msg); len(msg) > 0 {
+			return fmt.Errorf("POST to %s: %s: %s", req.URL.Host, resp.Status, msg)
+		}
+		return fmt.Errorf("POST to %s: %s", req.URL.Host, resp.Status)
+	}
+	return nil
+}
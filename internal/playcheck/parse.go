@@ -0,0 +1,76 @@
+package playcheck
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+)
+
+// stream is one #EXT-X-STREAM-INF entry of a master playlist.
+type stream struct {
+	uri        string
+	resolution string
+	codecs     string
+}
+
+// mediaSegment is one segment of a variant playlist.
+type mediaSegment struct {
+	uri      string
+	duration float64
+}
+
+// mediaPlaylist is the subset of a variant playlist the check needs.
+type mediaPlaylist struct {
+	segments  []mediaSegment
+	initURI   string // #EXT-X-MAP URI for fMP4 playlists
+	encrypted bool   // An #EXT-X-KEY with a METHOD other than NONE is present
+	endList   bool
+}
+
+// parseMaster returns the variant streams listed in a master playlist.
+func parseMaster(raw string) []stream {
+	var streams []stream
+	var pending map[string]string
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			pending = helpers.ParseHLSAttributes(strings.TrimPrefix(line, "#EXT-X-STREAM-INF:"))
+		case strings.HasPrefix(line, "#"):
+		case pending != nil:
+			streams = append(streams, stream{uri: line, resolution: pending["RESOLUTION"], codecs: pending["CODECS"]})
+			pending = nil
+		}
+	}
+	return streams
+}
+
+// parseMedia returns the segments and tags of a variant playlist.
+func parseMedia(raw string) mediaPlaylist {
+	var pl mediaPlaylist
+	duration := 0.0
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXTINF:"):
+			value, _, _ := strings.Cut(strings.TrimPrefix(line, "#EXTINF:"), ",")
+			duration, _ = strconv.ParseFloat(value, 64)
+		case strings.HasPrefix(line, "#EXT-X-MAP:"):
+			pl.initURI = helpers.ParseHLSAttributes(strings.TrimPrefix(line, "#EXT-X-MAP:"))["URI"]
+		case strings.HasPrefix(line, "#EXT-X-KEY:"):
+			if method := helpers.ParseHLSAttributes(strings.TrimPrefix(line, "#EXT-X-KEY:"))["METHOD"]; method != "" && method != "NONE" {
+				pl.encrypted = true
+			}
+		case line == "#EXT-X-ENDLIST":
+			pl.endList = true
+		case strings.HasPrefix(line, "#"):
+		default:
+			pl.segments = append(pl.segments, mediaSegment{uri: line, duration: duration})
+			duration = 0
+		}
+	}
+	return pl
+}
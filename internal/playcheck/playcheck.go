@@ -0,0 +1,300 @@
+// Package playcheck "plays" a generated HLS master manifest the way a player
+// would: it fetches every variant playlist, downloads the first, middle, and
+// last segment of each rung, and probes them to confirm the codec and
+// resolution match what the master advertises and that timestamps move
+// forward. Masters may be local paths or http(s) URLs.
+package playcheck
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+)
+
+// DefaultTolerance is how far, in seconds, a probed segment duration may
+// differ from its #EXTINF before it is reported.
+const DefaultTolerance = 0.5
+
+// Options configures Check.
+type Options struct {
+	Client    *http.Client                                                           // Client for http(s) masters (default http.DefaultClient)
+	Probe     func(ctx context.Context, path string) (*analyzer.SegmentProbe, error) // Segment prober (default analyzer.ProbeSegment)
+	Tolerance float64                                                                // Allowed #EXTINF vs probed duration drift in seconds (default DefaultTolerance)
+	Scratch   string                                                                 // Parent directory for downloaded segments (default: system temporary directory)
+}
+
+// Report is the outcome of a playback check.
+type Report struct {
+	Master string // Master manifest that was checked
+	Rungs  []Rung // One entry per #EXT-X-STREAM-INF
+}
+
+// Rung is the check result for one variant playlist.
+type Rung struct {
+	URI        string         // Variant playlist URI as written in the master
+	Resolution string         // RESOLUTION advertised in the master
+	Codecs     string         // CODECS advertised in the master
+	Segments   []SegmentCheck // Sampled segments, in playlist order
+	Skipped    string         // Why segments were not probed (e.g. encrypted); such rungs are unverified, not passing
+	Problems   []string       // Discrepancies found
+}
+
+// SegmentCheck is one sampled segment.
+type SegmentCheck struct {
+	Index    int                    // Position in the variant playlist
+	URI      string                 // Segment URI as written in the playlist
+	Duration float64                // #EXTINF duration
+	Probe    *analyzer.SegmentProbe // What ffprobe found; nil if the fetch or probe failed
+}
+
+// Problems returns every discrepancy, prefixed with its rung.
+func (r *Report) Problems() []string {
+	var all []string
+	for _, rung := range r.Rungs {
+		for _, p := range rung.Problems {
+			all = append(all, rung.URI+": "+p)
+		}
+	}
+	return all
+}
+
+// Unverified returns every rung whose segments were not probed, with the
+// reason. Err does not fail on these, so callers must not report them as
+// having passed.
+func (r *Report) Unverified() []string {
+	var out []string
+	for _, rung := range r.Rungs {
+		if rung.Skipped != "" {
+			out = append(out, rung.URI+": "+rung.Skipped)
+		}
+	}
+	return out
+}
+
+// Err returns an error listing every discrepancy, or nil if playback looks sound.
+func (r *Report) Err() error {
+	problems := r.Problems()
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("playback check failed for %s:\n  %s", r.Master, strings.Join(problems, "\n  "))
+}
+
+// Check fetches master and every rung it lists, probing sampled segments.
+// The returned error covers failures to read the master itself; problems
+// with individual rungs are recorded in the report (see Report.Err).
+func Check(ctx context.Context, master string, opts Options) (*Report, error) {
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.Probe == nil {
+		opts.Probe = analyzer.ProbeSegment
+	}
+	if opts.Tolerance <= 0 {
+		opts.Tolerance = DefaultTolerance
+	}
+	l := loader{ctx: ctx, client: opts.Client}
+
+	raw, err := l.fetch(master)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read master manifest: %w", err)
+	}
+	streams := parseMaster(string(raw))
+	if len(streams) == 0 {
+		return nil, fmt.Errorf("master manifest %s lists no variants", master)
+	}
+
+	tmp, err := os.MkdirTemp(opts.Scratch, "dotgo-playcheck-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scratch directory: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	report := &Report{Master: master}
+	for i, s := range streams {
+		rung := Rung{URI: s.uri, Resolution: s.resolution, Codecs: s.codecs}
+		checkRung(&rung, l, resolve(master, s.uri), filepath.Join(tmp, strconv.Itoa(i)), opts)
+		report.Rungs = append(report.Rungs, rung)
+	}
+	return report, nil
+}
+
+// checkRung fetches one variant playlist and probes its sampled segments.
+func checkRung(rung *Rung, l loader, playlistRef, scratch string, opts Options) {
+	raw, err := l.fetch(playlistRef)
+	if err != nil {
+		rung.Problems = append(rung.Problems, fmt.Sprintf("playlist unreadable: %v", err))
+		return
+	}
+	pl := parseMedia(string(raw))
+	if len(pl.segments) == 0 {
+		rung.Problems = append(rung.Problems, "playlist has no segments")
+		return
+	}
+	if !pl.endList {
+		rung.Problems = append(rung.Problems, "VOD playlist is missing #EXT-X-ENDLIST")
+	}
+	if pl.encrypted {
+		rung.Skipped = "segments are encrypted"
+		return
+	}
+
+	var init []byte
+	if pl.initURI != "" {
+		if init, err = l.fetch(resolve(playlistRef, pl.initURI)); err != nil {
+			rung.Problems = append(rung.Problems, fmt.Sprintf("init segment %s unreadable: %v", pl.initURI, err))
+			return
+		}
+	}
+	if err := os.MkdirAll(scratch, 0o755); err != nil {
+		rung.Problems = append(rung.Problems, err.Error())
+		return
+	}
+
+	for _, idx := range sampleIndexes(len(pl.segments)) {
+		seg := pl.segments[idx]
+		check := SegmentCheck{Index: idx, URI: seg.uri, Duration: seg.duration}
+		data, err := l.fetch(resolve(playlistRef, seg.uri))
+		if err != nil {
+			rung.Problems = append(rung.Problems, fmt.Sprintf("segment %d (%s) unreadable: %v", idx, seg.uri, err))
+			rung.Segments = append(rung.Segments, check)
+			continue
+		}
+		if len(data) == 0 {
+			rung.Problems = append(rung.Problems, fmt.Sprintf("segment %d (%s) is empty", idx, seg.uri))
+			rung.Segments = append(rung.Segments, check)
+			continue
+		}
+		// fMP4 segments only probe with their init segment in front
+		local := filepath.Join(scratch, fmt.Sprintf("segment_%d%s", idx, segmentExt(seg.uri)))
+		if err := os.WriteFile(local, append(append([]byte(nil), init...), data...), 0o644); err != nil {
+			rung.Problems = append(rung.Problems, err.Error())
+			return
+		}
+		probe, err := opts.Probe(l.ctx, local)
+		if err != nil {
+			rung.Problems = append(rung.Problems, fmt.Sprintf("segment %d (%s) failed to probe: %v", idx, seg.uri, err))
+		}
+		check.Probe = probe
+		rung.Segments = append(rung.Segments, check)
+	}
+	compare(rung, opts.Tolerance)
+}
+
+// compare checks probed segments against the master's attributes, their
+// #EXTINF durations, and each other's timestamps.
+func compare(rung *Rung, tolerance float64) {
+	wantFamily := helpers.VideoFamilyFromCodecs(rung.Codecs)
+	var prev *SegmentCheck
+	for i := range rung.Segments {
+		s := &rung.Segments[i]
+		p := s.Probe
+		if p == nil {
+			continue
+		}
+		if p.Packets == 0 {
+			rung.Problems = append(rung.Problems, fmt.Sprintf("segment %d has no video packets", s.Index))
+			continue
+		}
+		if wantFamily != "" && p.VideoCodec != wantFamily {
+			rung.Problems = append(rung.Problems, fmt.Sprintf("segment %d is %s but CODECS advertises %s", s.Index, p.VideoCodec, wantFamily))
+		}
+		if rung.Resolution != "" {
+			if got := fmt.Sprintf("%dx%d", p.Width, p.Height); got != rung.Resolution {
+				rung.Problems = append(rung.Problems, fmt.Sprintf("segment %d is %s but RESOLUTION advertises %s", s.Index, got, rung.Resolution))
+			}
+		}
+		if p.Backwards > 0 {
+			rung.Problems = append(rung.Problems, fmt.Sprintf("segment %d has %d non-monotonic timestamps", s.Index, p.Backwards))
+		}
+		if s.Duration > 0 && math.Abs(p.Duration()-s.Duration) > tolerance {
+			rung.Problems = append(rung.Problems, fmt.Sprintf("segment %d lasts %.2fs but #EXTINF says %.2fs", s.Index, p.Duration(), s.Duration))
+		}
+		if prev != nil && p.StartTime <= prev.Probe.StartTime {
+			rung.Problems = append(rung.Problems, fmt.Sprintf("segment %d starts at %.3fs, not after segment %d (%.3fs)", s.Index, p.StartTime, prev.Index, prev.Probe.StartTime))
+		}
+		prev = s
+	}
+}
+
+// sampleIndexes returns the first, middle, and last segment indexes without duplicates.
+func sampleIndexes(n int) []int {
+	var out []int
+	for _, i := range []int{0, n / 2, n - 1} {
+		if len(out) == 0 || out[len(out)-1] != i {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// segmentExt returns the file extension of a segment URI, ignoring any query.
+func segmentExt(uri string) string {
+	base, _, _ := strings.Cut(uri, "?")
+	return filepath.Ext(base)
+}
+
+// loader reads manifests and segments from disk or over HTTP.
+type loader struct {
+	ctx    context.Context
+	client *http.Client
+}
+
+// fetch returns the body of ref, a local path or an http(s) URL.
+func (l loader) fetch(ref string) ([]byte, error) {
+	if !isURL(ref) {
+		return os.ReadFile(ref)
+	}
+	req, err := http.NewRequestWithContext(l.ctx, http.MethodGet, ref, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", ref, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// resolve interprets ref relative to the manifest at base. Query strings
+// (e.g. signed-URL tokens) are kept for URLs and dropped for local files.
+func resolve(base, ref string) string {
+	if isURL(ref) {
+		return ref
+	}
+	if isURL(base) {
+		b, err := url.Parse(base)
+		if err != nil {
+			return ref
+		}
+		r, err := url.Parse(ref)
+		if err != nil {
+			return ref
+		}
+		return b.ResolveReference(r).String()
+	}
+	path, _, _ := strings.Cut(ref, "?")
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(base), filepath.FromSlash(path))
+}
+
+// isURL reports whether ref is an http(s) URL.
+func isURL(ref string) bool {
+	return strings.HasPrefix(ref, "http://") || strings.HasPrefix(ref, "https://")
+}
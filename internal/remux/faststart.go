@@ -0,0 +1,190 @@
+package remux
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// topBox is a top-level box's position in a file.
+type topBox struct {
+	typ    string
+	offset int64
+	size   int64 // Header and payload
+}
+
+// topLevelBoxes lists the top-level boxes of f in file order.
+func topLevelBoxes(f *os.File) ([]topBox, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	var out []topBox
+	var hdr [16]byte
+	for off := int64(0); off+8 <= info.Size(); {
+		if _, err := f.ReadAt(hdr[:8], off); err != nil {
+			return nil, err
+		}
+		size, headerLen := int64(binary.BigEndian.Uint32(hdr[:4])), int64(8)
+		switch size {
+		case 0:
+			size = info.Size() - off
+		case 1:
+			if _, err := f.ReadAt(hdr[8:16], off+8); err != nil {
+				return nil, err
+			}
+			size, headerLen = int64(binary.BigEndian.Uint64(hdr[8:16])), 16
+		}
+		if size < headerLen || off+size > info.Size() {
+			return nil, fmt.Errorf("invalid %q box size %d at offset %d", hdr[4:8], size, off)
+		}
+		out = append(out, topBox{typ: string(hdr[4:8]), offset: off, size: size})
+		off += size
+	}
+	return out, nil
+}
+
+// IsFaststart reports whether the MP4 at path has its moov box ahead of its
+// media data, so players and range readers can start before the whole file
+// has arrived.
+func IsFaststart(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	top, err := topLevelBoxes(f)
+	if err != nil {
+		return false, err
+	}
+	moov, mdat := indexOf(top, "moov"), indexOf(top, "mdat")
+	if moov < 0 {
+		return false, errors.New("no moov box")
+	}
+	return mdat < 0 || moov < mdat, nil
+}
+
+// Faststart copies the MP4 at src to dst with the moov box moved ahead of
+// the first mdat, shifting the chunk offsets of every track to match, so
+// the file plays while it downloads. Files already laid out that way are
+// copied unchanged. dst is written beside its final name and renamed into
+// place, so readers never see a partial file.
+func Faststart(src, dst string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	top, err := topLevelBoxes(f)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", src, err)
+	}
+	if indexOf(top, "moof") >= 0 {
+		return fmt.Errorf("%s is fragmented; faststart applies to progressive MP4 only", src)
+	}
+	moovAt, mdatAt := indexOf(top, "moov"), indexOf(top, "mdat")
+	if moovAt < 0 {
+		return fmt.Errorf("%s has no moov box", src)
+	}
+
+	order := top
+	var moov []byte
+	if mdatAt >= 0 && mdatAt < moovAt {
+		m := top[moovAt]
+		moov = make([]byte, m.size)
+		if _, err := f.ReadAt(moov, m.offset); err != nil {
+			return fmt.Errorf("failed to read moov: %w", err)
+		}
+		// Data between the first mdat and the moov moves forward by the
+		// moov's size; data after the moov stays where it was
+		from, to := top[mdatAt].offset, m.offset
+		if err := shiftChunkOffsets(moov, func(off uint64) uint64 {
+			if off >= uint64(from) && off < uint64(to) {
+				return off + uint64(m.size)
+			}
+			return off
+		}); err != nil {
+			return fmt.Errorf("%s: %w", src, err)
+		}
+		order = make([]topBox, 0, len(top))
+		order = append(order, top[:mdatAt]...)
+		order = append(order, m)
+		order = append(order, top[mdatAt:moovAt]...)
+		order = append(order, top[moovAt+1:]...)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+	tmp := dst + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	for _, b := range order {
+		if moov != nil && b.typ == "moov" {
+			_, err = out.Write(moov)
+		} else {
+			_, err = io.Copy(out, io.NewSectionReader(f, b.offset, b.size))
+		}
+		if err != nil {
+			break
+		}
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	return os.Rename(tmp, dst)
+}
+
+// shiftChunkOffsets rewrites, in place, the stco and co64 entries of every
+// track in the moov box b (header included) through shift. An stco entry
+// pushed past 4 GiB would need the table rewritten as co64, which changes
+// the moov's size, so it is reported as an error instead.
+func shiftChunkOffsets(b []byte, shift func(uint64) uint64) error {
+	moov := boxes(b)
+	if len(moov) != 1 {
+		return errors.New("malformed moov box")
+	}
+	for _, trak := range childrenOf(moov[0].payload, "trak") {
+		stbl, ok := child(trak, "mdia", "minf", "stbl")
+		if !ok {
+			continue
+		}
+		if stco, ok := child(stbl.payload, "stco"); ok && len(stco.payload) >= 8 {
+			p := stco.payload
+			for e, n := 0, int(binary.BigEndian.Uint32(p[4:])); e < n && 12+4*e <= len(p); e++ {
+				off := shift(uint64(binary.BigEndian.Uint32(p[8+4*e:])))
+				if off > math.MaxUint32 {
+					return errors.New("chunk offset beyond 4 GiB needs a co64 table")
+				}
+				binary.BigEndian.PutUint32(p[8+4*e:], uint32(off))
+			}
+		}
+		if co64, ok := child(stbl.payload, "co64"); ok && len(co64.payload) >= 8 {
+			p := co64.payload
+			for e, n := 0, int(binary.BigEndian.Uint32(p[4:])); e < n && 16+8*e <= len(p); e++ {
+				binary.BigEndian.PutUint64(p[8+8*e:], shift(binary.BigEndian.Uint64(p[8+8*e:])))
+			}
+		}
+	}
+	return nil
+}
+
+// indexOf returns the index of the first box of type typ in top, or -1.
+func indexOf(top []topBox, typ string) int {
+	for i, b := range top {
+		if b.typ == typ {
+			return i
+		}
+	}
+	return -1
+}
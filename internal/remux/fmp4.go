@@ -0,0 +1,167 @@
+package remux
+
+import (
+	"encoding/binary"
+)
+
+// Sample flags for trun entries (ISO/IEC 14496-12 section 8.8.3.1).
+const (
+	syncSampleFlags    = 0x02000000 // sample_depends_on=2 (depends on no other sample)
+	nonSyncSampleFlags = 0x01010000 // sample_depends_on=1, sample_is_non_sync_sample
+)
+
+// unityMatrix is the identity transformation matrix of mvhd and tkhd.
+var unityMatrix = []uint32{0x00010000, 0, 0, 0, 0x00010000, 0, 0, 0, 0x40000000}
+
+// box serializes an MP4 box of type typ around payload.
+func box(typ string, payload ...[]byte) []byte {
+	size := 8
+	for _, p := range payload {
+		size += len(p)
+	}
+	out := binary.BigEndian.AppendUint32(make([]byte, 0, size), uint32(size))
+	out = append(out, typ...)
+	for _, p := range payload {
+		out = append(out, p...)
+	}
+	return out
+}
+
+// fullBox serializes a full box with its version and flags.
+func fullBox(typ string, version byte, flags uint32, payload ...[]byte) []byte {
+	vf := binary.BigEndian.AppendUint32(nil, uint32(version)<<24|flags&0xFFFFFF)
+	return box(typ, append([][]byte{vf}, payload...)...)
+}
+
+// u32s encodes values as big-endian 32-bit integers.
+func u32s(values ...uint32) []byte {
+	var b []byte
+	for _, v := range values {
+		b = binary.BigEndian.AppendUint32(b, v)
+	}
+	return b
+}
+
+// initSegment builds the fMP4 initialization segment (ftyp and moov with
+// mvex) for tracks, reusing each track's sample descriptions as they are.
+func initSegment(tracks []*track) []byte {
+	ftyp := box("ftyp", []byte("iso5"), u32s(512), []byte("iso6mp41"))
+
+	mvhd := fullBox("mvhd", 0, 0,
+		u32s(0, 0, 1000, 0, 0x00010000), // creation, modification, timescale, duration, rate
+		[]byte{0x01, 0x00},              // volume
+		make([]byte, 10),                // reserved
+		u32s(unityMatrix...),
+		make([]byte, 24), // pre_defined
+		u32s(uint32(len(tracks)+1)),
+	)
+	moov := [][]byte{mvhd}
+	var trex [][]byte
+	for _, t := range tracks {
+		moov = append(moov, trackBox(t))
+		trex = append(trex, fullBox("trex", 0, 0, u32s(t.id, 1, 0, 0, 0)))
+	}
+	moov = append(moov, box("mvex", trex...))
+	return append(ftyp, box("moov", moov...)...)
+}
+
+// trackBox builds the trak of an fMP4 init segment: empty sample tables,
+// since samples are described by each fragment.
+func trackBox(t *track) []byte {
+	volume := []byte{0, 0}
+	handler, name := "vide", "VideoHandler"
+	mediaHeader := fullBox("vmhd", 0, 1, make([]byte, 8))
+	if t.kind == "soun" {
+		volume = []byte{0x01, 0x00}
+		handler, name = "soun", "SoundHandler"
+		mediaHeader = fullBox("smhd", 0, 0, make([]byte, 4))
+	}
+
+	tkhd := fullBox("tkhd", 0, 3, // enabled, in movie
+		u32s(0, 0, t.id, 0, 0), // creation, modification, track_ID, reserved, duration
+		make([]byte, 8),        // reserved
+		make([]byte, 4),        // layer, alternate_group
+		volume, []byte{0, 0},
+		u32s(unityMatrix...),
+		u32s(uint32(t.width)<<16, uint32(t.height)<<16),
+	)
+	mdhd := fullBox("mdhd", 0, 0, u32s(0, 0, t.timescale, 0), []byte{0x55, 0xC4, 0, 0}) // language "und"
+	hdlr := fullBox("hdlr", 0, 0, u32s(0), []byte(handler), make([]byte, 12), append([]byte(name), 0))
+	dinf := box("dinf", fullBox("dref", 0, 0, u32s(1), fullBox("url ", 0, 1)))
+	stbl := box("stbl",
+		t.stsd,
+		fullBox("stts", 0, 0, u32s(0)),
+		fullBox("stsc", 0, 0, u32s(0)),
+		fullBox("stsz", 0, 0, u32s(0, 0)),
+		fullBox("stco", 0, 0, u32s(0)),
+	)
+	if edts := editBox(t); edts != nil {
+		return box("trak", tkhd, edts, box("mdia", mdhd, hdlr, box("minf", mediaHeader, dinf, stbl)))
+	}
+	return box("trak", tkhd, box("mdia", mdhd, hdlr, box("minf", mediaHeader, dinf, stbl)))
+}
+
+// editBox carries t's edit list into the init segment, so fragments keep
+// their media times and players still skip priming samples and B-frame
+// delay. It returns nil for tracks without an edit offset.
+func editBox(t *track) []byte {
+	// Entries are segment_duration in the 1000 Hz movie timescale (0: the
+	// whole fragmented track), media_time, and a media rate of 1.0
+	var elst []byte
+	switch {
+	case t.editOffset > 0:
+		elst = fullBox("elst", 0, 0, u32s(1, 0, uint32(t.editOffset), 0x00010000))
+	case t.editOffset < 0:
+		delay := uint32(-t.editOffset * 1000 / int64(t.timescale))
+		elst = fullBox("elst", 0, 0, u32s(2, delay, 0xFFFFFFFF, 0x00010000, 0, 0, 0x00010000))
+	default:
+		return nil
+	}
+	return box("edts", elst)
+}
+
+// fragmentRun is one track's samples in a fragment, with their data.
+type fragmentRun struct {
+	track   *track
+	samples []sample
+	data    [][]byte
+}
+
+// fragment builds a moof and mdat holding runs, numbered sequence.
+func fragment(sequence uint32, runs []fragmentRun) []byte {
+	// moof size is fixed by the sample counts, so data offsets can be
+	// computed before it is written
+	moofSize := 8 + 16
+	for _, r := range runs {
+		moofSize += 8 + 16 + 20 + 20 + 16*len(r.samples)
+	}
+
+	trafs := [][]byte{fullBox("mfhd", 0, 0, u32s(sequence))}
+	var mdat [][]byte
+	dataOffset := moofSize + 8
+	for _, r := range runs {
+		tfhd := fullBox("tfhd", 0, 0x020000, u32s(r.track.id)) // default-base-is-moof
+		var base []byte
+		if len(r.samples) > 0 {
+			base = binary.BigEndian.AppendUint64(nil, uint64(r.samples[0].dts))
+		} else {
+			base = make([]byte, 8)
+		}
+		tfdt := fullBox("tfdt", 1, 0, base)
+
+		// data-offset, duration, size, flags, and composition offset per sample
+		entries := u32s(uint32(len(r.samples)), uint32(dataOffset))
+		for i, s := range r.samples {
+			flags := uint32(nonSyncSampleFlags)
+			if s.key {
+				flags = syncSampleFlags
+			}
+			entries = append(entries, u32s(s.duration, s.size, flags, uint32(s.cts))...)
+			dataOffset += len(r.data[i])
+			mdat = append(mdat, r.data[i])
+		}
+		trun := fullBox("trun", 1, 0x000F01, entries)
+		trafs = append(trafs, box("traf", tfhd, tfdt, trun))
+	}
+	return append(box("moof", trafs...), box("mdat", mdat...)...)
+}
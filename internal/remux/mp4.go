@@ -0,0 +1,572 @@
+package remux
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// track is one audio or video track of an MP4 file, with its sample table
+// resolved to file offsets and timestamps.
+type track struct {
+	id        uint32
+	kind      string // Handler type: "vide" or "soun"
+	timescale uint32
+	codec     string // Sample entry type (e.g. "avc1", "hvc1", "mp4a")
+	stsd      []byte // Whole stsd box, copied into fMP4 init segments
+	width     uint16 // Video dimensions from the sample entry
+	height    uint16
+
+	nalLength int      // H.264 NAL unit length prefix size (avcC)
+	sps, pps  [][]byte // H.264 parameter sets (avcC)
+	asc       []byte   // AAC AudioSpecificConfig (esds)
+
+	// editOffset is the media time presented at time zero, from the edit
+	// list: AAC encoder priming or the B-frame delay, negative after a
+	// leading empty edit
+	editOffset int64
+
+	samples []sample
+}
+
+// Limits on what an MP4 may make SegmentFile allocate, so a corrupt or
+// hostile file fails instead of exhausting memory.
+const (
+	maxMoovSize   = 256 << 20 // Bytes of the moov box
+	maxSamples    = 1 << 24   // Samples per track, over 90 hours of 48 kHz AAC
+	maxSampleSize = 64 << 20  // Bytes of one sample
+)
+
+// sample is one access unit: a video frame or an audio frame.
+type sample struct {
+	offset   int64  // File offset
+	size     uint32 // Bytes
+	dts      int64  // Decode time in track timescale units
+	cts      int32  // Composition offset: PTS = DTS + cts
+	duration uint32 // Decode duration in timescale units
+	key      bool   // Sync sample (keyframe)
+}
+
+// seconds converts a track time to seconds.
+func (t *track) seconds(v int64) float64 {
+	return float64(v) / float64(t.timescale)
+}
+
+// presentation converts a media time to seconds of presentation, applying
+// the edit list.
+func (t *track) presentation(v int64) float64 {
+	return t.seconds(v - t.editOffset)
+}
+
+// end returns the presentation time in seconds at which the track's last
+// sample ends.
+func (t *track) end() float64 {
+	if len(t.samples) == 0 {
+		return 0
+	}
+	last := t.samples[len(t.samples)-1]
+	return t.presentation(last.dts + int64(last.duration))
+}
+
+// mp4File is a progressive MP4 opened for reading samples.
+type mp4File struct {
+	f      *os.File
+	tracks []*track
+}
+
+// openMP4 reads the moov box of path and resolves the sample tables of its
+// audio and video tracks. Fragmented MP4 (samples in moof boxes) is not
+// supported.
+func openMP4(path string) (*mp4File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	moov, err := readTopLevel(f, "moov")
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	movieTimescale := uint32(0)
+	if mvhd, ok := child(moov, "mvhd"); ok && len(mvhd.payload) >= 24 {
+		if mvhd.payload[0] == 1 {
+			movieTimescale = binary.BigEndian.Uint32(mvhd.payload[20:])
+		} else {
+			movieTimescale = binary.BigEndian.Uint32(mvhd.payload[12:])
+		}
+	}
+	m := &mp4File{f: f}
+	for _, trak := range childrenOf(moov, "trak") {
+		t, err := parseTrack(trak, movieTimescale)
+		if err == nil && t != nil {
+			err = t.checkSamples(info.Size())
+		}
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if t != nil {
+			m.tracks = append(m.tracks, t)
+		}
+	}
+	if len(m.tracks) == 0 {
+		f.Close()
+		return nil, errors.New("no audio or video tracks")
+	}
+	return m, nil
+}
+
+// Close closes the file.
+func (m *mp4File) Close() error {
+	return m.f.Close()
+}
+
+// track returns the first track of kind, or nil.
+func (m *mp4File) track(kind string) *track {
+	for _, t := range m.tracks {
+		if t.kind == kind {
+			return t
+		}
+	}
+	return nil
+}
+
+// read returns the bytes of s.
+func (m *mp4File) read(s sample) ([]byte, error) {
+	buf := make([]byte, s.size)
+	_, err := m.f.ReadAt(buf, s.offset)
+	return buf, err
+}
+
+// checkSamples returns an error when a sample is larger than maxSampleSize
+// or lies past the end of a file of size bytes.
+func (t *track) checkSamples(size int64) error {
+	for i, s := range t.samples {
+		if s.size > maxSampleSize || s.offset < 0 || s.offset+int64(s.size) > size {
+			return fmt.Errorf("track %d: sample %d (%d bytes at offset %d) lies outside the file", t.id, i, s.size, s.offset)
+		}
+	}
+	return nil
+}
+
+// readTopLevel returns the payload of the first top-level box of type typ,
+// which may be at most maxMoovSize bytes.
+func readTopLevel(f *os.File, typ string) ([]byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	var hdr [16]byte
+	for off := int64(0); off+8 <= info.Size(); {
+		if _, err := f.ReadAt(hdr[:8], off); err != nil {
+			return nil, err
+		}
+		size, headerLen := int64(binary.BigEndian.Uint32(hdr[:4])), int64(8)
+		switch size {
+		case 0:
+			size = info.Size() - off
+		case 1:
+			if _, err := f.ReadAt(hdr[8:16], off+8); err != nil {
+				return nil, err
+			}
+			size, headerLen = int64(binary.BigEndian.Uint64(hdr[8:16])), 16
+		}
+		if size < headerLen || size > info.Size()-off {
+			return nil, fmt.Errorf("invalid %q box size %d at offset %d", hdr[4:8], size, off)
+		}
+		if string(hdr[4:8]) == typ {
+			if size-headerLen > maxMoovSize {
+				return nil, fmt.Errorf("%s box of %d bytes is over the %d byte limit", typ, size-headerLen, maxMoovSize)
+			}
+			payload := make([]byte, size-headerLen)
+			if _, err := f.ReadAt(payload, off+headerLen); err != nil && !errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			return payload, nil
+		}
+		off += size
+	}
+	return nil, fmt.Errorf("no %s box", typ)
+}
+
+// mp4Box is a box inside a parent's payload.
+type mp4Box struct {
+	typ     string
+	payload []byte
+	raw     []byte // Header and payload
+}
+
+// boxes splits a payload into its child boxes, stopping at the first
+// malformed one.
+func boxes(b []byte) []mp4Box {
+	var out []mp4Box
+	for len(b) >= 8 {
+		size, headerLen := uint64(binary.BigEndian.Uint32(b)), uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(b))
+		case 1:
+			if len(b) < 16 {
+				return out
+			}
+			size, headerLen = binary.BigEndian.Uint64(b[8:]), 16
+		}
+		if size < headerLen || size > uint64(len(b)) {
+			return out
+		}
+		out = append(out, mp4Box{typ: string(b[4:8]), payload: b[headerLen:size], raw: b[:size]})
+		b = b[size:]
+	}
+	return out
+}
+
+// childrenOf returns the payloads of b's children of type typ.
+func childrenOf(b []byte, typ string) [][]byte {
+	var out [][]byte
+	for _, c := range boxes(b) {
+		if c.typ == typ {
+			out = append(out, c.payload)
+		}
+	}
+	return out
+}
+
+// child follows path down from b and returns the first matching box.
+func child(b []byte, path ...string) (mp4Box, bool) {
+	var found mp4Box
+	for _, typ := range path {
+		ok := false
+		for _, c := range boxes(b) {
+			if c.typ == typ {
+				found, b, ok = c, c.payload, true
+				break
+			}
+		}
+		if !ok {
+			return mp4Box{}, false
+		}
+	}
+	return found, true
+}
+
+// parseTrack reads one trak box of a movie with timescale movieTimescale.
+// Tracks other than audio and video return nil.
+func parseTrack(trak []byte, movieTimescale uint32) (*track, error) {
+	t := &track{}
+	hdlr, ok := child(trak, "mdia", "hdlr")
+	if !ok || len(hdlr.payload) < 12 {
+		return nil, errors.New("track without a handler")
+	}
+	t.kind = string(hdlr.payload[8:12])
+	if t.kind != "vide" && t.kind != "soun" {
+		return nil, nil
+	}
+
+	if tkhd, ok := child(trak, "tkhd"); ok && len(tkhd.payload) >= 24 {
+		if tkhd.payload[0] == 1 {
+			t.id = binary.BigEndian.Uint32(tkhd.payload[20:])
+		} else {
+			t.id = binary.BigEndian.Uint32(tkhd.payload[12:])
+		}
+	}
+	mdhd, ok := child(trak, "mdia", "mdhd")
+	if !ok || len(mdhd.payload) < 24 {
+		return nil, errors.New("track without a media header")
+	}
+	if mdhd.payload[0] == 1 {
+		t.timescale = binary.BigEndian.Uint32(mdhd.payload[20:])
+	} else {
+		t.timescale = binary.BigEndian.Uint32(mdhd.payload[12:])
+	}
+	if t.timescale == 0 {
+		return nil, errors.New("track timescale is zero")
+	}
+	if elst, ok := child(trak, "edts", "elst"); ok {
+		t.parseEdits(elst.payload, movieTimescale)
+	}
+
+	stbl, ok := child(trak, "mdia", "minf", "stbl")
+	if !ok {
+		return nil, errors.New("track without a sample table")
+	}
+	stsd, ok := child(stbl.payload, "stsd")
+	if !ok || len(stsd.payload) < 8 {
+		return nil, errors.New("track without sample descriptions")
+	}
+	t.stsd = stsd.raw
+	if entries := boxes(stsd.payload[8:]); len(entries) > 0 {
+		t.parseSampleEntry(entries[0])
+	}
+
+	samples, err := sampleTable(stbl.payload)
+	if err != nil {
+		return nil, fmt.Errorf("track %d: %w", t.id, err)
+	}
+	t.samples = samples
+	return t, nil
+}
+
+// parseEdits sets editOffset from an elst box: the media time of the first
+// edit that plays media, less any empty edits (in the movie timescale)
+// before it. Later edits, which would cut the media, aren't honored.
+func (t *track) parseEdits(elst []byte, movieTimescale uint32) {
+	if len(elst) < 8 {
+		return
+	}
+	entrySize := 12
+	if elst[0] == 1 {
+		entrySize = 20
+	}
+	delay := int64(0)
+	for e, n := 0, int(binary.BigEndian.Uint32(elst[4:])); e < n && 8+entrySize*(e+1) <= len(elst); e++ {
+		entry := elst[8+entrySize*e:]
+		var duration, mediaTime int64
+		if entrySize == 20 {
+			duration, mediaTime = int64(binary.BigEndian.Uint64(entry)), int64(binary.BigEndian.Uint64(entry[8:]))
+		} else {
+			duration, mediaTime = int64(binary.BigEndian.Uint32(entry)), int64(int32(binary.BigEndian.Uint32(entry[4:])))
+		}
+		if mediaTime == -1 {
+			delay += duration
+			continue
+		}
+		t.editOffset = mediaTime
+		if delay > 0 && movieTimescale > 0 {
+			t.editOffset -= delay * int64(t.timescale) / int64(movieTimescale)
+		}
+		return
+	}
+}
+
+// parseSampleEntry records the codec, dimensions, and the decoder
+// configuration MPEG-TS output needs.
+func (t *track) parseSampleEntry(e mp4Box) {
+	t.codec = e.typ
+	switch t.kind {
+	case "vide":
+		// VisualSampleEntry: 78 bytes of fields, then child boxes
+		if len(e.payload) < 78 {
+			return
+		}
+		t.width = binary.BigEndian.Uint16(e.payload[24:])
+		t.height = binary.BigEndian.Uint16(e.payload[26:])
+		if avcC, ok := child(e.payload[78:], "avcC"); ok {
+			t.parseAVCC(avcC.payload)
+		}
+	case "soun":
+		// AudioSampleEntry: 28 bytes of fields, more in QuickTime v1/v2
+		if len(e.payload) < 28 {
+			return
+		}
+		skip := 28
+		switch binary.BigEndian.Uint16(e.payload[8:]) {
+		case 1:
+			skip += 16
+		case 2:
+			skip += 36
+		}
+		if len(e.payload) < skip {
+			return
+		}
+		if esds, ok := child(e.payload[skip:], "esds"); ok {
+			t.asc = audioSpecificConfig(esds.payload)
+		}
+	}
+}
+
+// parseAVCC reads the NAL length size and parameter sets of an
+// AVCDecoderConfigurationRecord.
+func (t *track) parseAVCC(b []byte) {
+	if len(b) < 6 {
+		return
+	}
+	t.nalLength = int(b[4]&3) + 1
+	read := func(b []byte, n int) ([][]byte, []byte) {
+		var sets [][]byte
+		for i := 0; i < n && len(b) >= 2; i++ {
+			size := int(binary.BigEndian.Uint16(b))
+			if len(b) < 2+size {
+				break
+			}
+			sets = append(sets, b[2:2+size])
+			b = b[2+size:]
+		}
+		return sets, b
+	}
+	rest := b[6:]
+	t.sps, rest = read(rest, int(b[5]&0x1F))
+	if len(rest) > 0 {
+		t.pps, _ = read(rest[1:], int(rest[0]))
+	}
+}
+
+// audioSpecificConfig extracts the DecoderSpecificInfo of an esds box.
+func audioSpecificConfig(esds []byte) []byte {
+	if len(esds) < 4 {
+		return nil
+	}
+	b := esds[4:]
+	for len(b) > 0 {
+		tag := b[0]
+		size, n := descriptorLength(b[1:])
+		b = b[1+n:]
+		if size > len(b) {
+			return nil
+		}
+		switch tag {
+		case 0x03: // ES_Descriptor
+			if len(b) < 3 {
+				return nil
+			}
+			flags, skip := b[2], 3
+			if flags&0x80 != 0 {
+				skip += 2
+			}
+			if flags&0x40 != 0 && len(b) > skip {
+				skip += 1 + int(b[skip])
+			}
+			if flags&0x20 != 0 {
+				skip += 2
+			}
+			if skip > size {
+				return nil
+			}
+			b = b[skip:size]
+		case 0x04: // DecoderConfigDescriptor
+			if size < 13 {
+				return nil
+			}
+			b = b[13:size]
+		case 0x05: // DecoderSpecificInfo
+			return b[:size]
+		default:
+			b = b[size:]
+		}
+	}
+	return nil
+}
+
+// descriptorLength decodes an MPEG-4 descriptor's variable-length size,
+// returning it and the bytes it took.
+func descriptorLength(b []byte) (size, n int) {
+	for n < 4 && n < len(b) {
+		size = size<<7 | int(b[n]&0x7F)
+		n++
+		if b[n-1]&0x80 == 0 {
+			break
+		}
+	}
+	return size, n
+}
+
+// sampleTable resolves an stbl box into samples with file offsets, decode
+// and composition times, durations, and sync flags.
+func sampleTable(stbl []byte) ([]sample, error) {
+	u32 := binary.BigEndian.Uint32
+
+	stsz, ok := child(stbl, "stsz")
+	if !ok || len(stsz.payload) < 12 {
+		return nil, errors.New("missing stsz")
+	}
+	fixed, count := u32(stsz.payload[4:]), int(u32(stsz.payload[8:]))
+	if count > maxSamples {
+		return nil, fmt.Errorf("%d samples is over the %d sample limit", count, maxSamples)
+	}
+	if fixed == 0 && len(stsz.payload) < 12+4*count {
+		return nil, errors.New("truncated stsz")
+	}
+	samples := make([]sample, count)
+	for i := range samples {
+		samples[i].size = fixed
+		if fixed == 0 {
+			samples[i].size = u32(stsz.payload[12+4*i:])
+		}
+		samples[i].key = true
+	}
+
+	// Decode times
+	stts, ok := child(stbl, "stts")
+	if !ok || len(stts.payload) < 8 {
+		return nil, errors.New("missing stts")
+	}
+	i, dts := 0, int64(0)
+	for e, n := 0, int(u32(stts.payload[4:])); e < n && 16+8*e <= len(stts.payload); e++ {
+		run, delta := int(u32(stts.payload[8+8*e:])), u32(stts.payload[12+8*e:])
+		for j := 0; j < run && i < count; j, i = j+1, i+1 {
+			samples[i].dts, samples[i].duration = dts, delta
+			dts += int64(delta)
+		}
+	}
+
+	// Composition offsets; version 1 makes them signed, and version 0
+	// offsets beyond 2^31 don't occur in practice
+	if ctts, ok := child(stbl, "ctts"); ok && len(ctts.payload) >= 8 {
+		i := 0
+		for e, n := 0, int(u32(ctts.payload[4:])); e < n && 16+8*e <= len(ctts.payload); e++ {
+			run, offset := int(u32(ctts.payload[8+8*e:])), int32(u32(ctts.payload[12+8*e:]))
+			for j := 0; j < run && i < count; j, i = j+1, i+1 {
+				samples[i].cts = offset
+			}
+		}
+	}
+
+	// Sync samples; without stss every sample is one
+	if stss, ok := child(stbl, "stss"); ok && len(stss.payload) >= 8 {
+		for i := range samples {
+			samples[i].key = false
+		}
+		for e, n := 0, int(u32(stss.payload[4:])); e < n && 12+4*e <= len(stss.payload); e++ {
+			if k := int(u32(stss.payload[8+4*e:])); k >= 1 && k <= count {
+				samples[k-1].key = true
+			}
+		}
+	}
+
+	// File offsets from chunk offsets and the sample-to-chunk runs
+	var chunks []int64
+	if stco, ok := child(stbl, "stco"); ok && len(stco.payload) >= 8 {
+		for e, n := 0, int(u32(stco.payload[4:])); e < n && 12+4*e <= len(stco.payload); e++ {
+			chunks = append(chunks, int64(u32(stco.payload[8+4*e:])))
+		}
+	} else if co64, ok := child(stbl, "co64"); ok && len(co64.payload) >= 8 {
+		for e, n := 0, int(u32(co64.payload[4:])); e < n && 16+8*e <= len(co64.payload); e++ {
+			chunks = append(chunks, int64(binary.BigEndian.Uint64(co64.payload[8+8*e:])))
+		}
+	} else {
+		return nil, errors.New("missing stco")
+	}
+	stsc, ok := child(stbl, "stsc")
+	if !ok || len(stsc.payload) < 8 {
+		return nil, errors.New("missing stsc")
+	}
+	type run struct{ first, perChunk int }
+	var runs []run
+	for e, n := 0, int(u32(stsc.payload[4:])); e < n && 20+12*e <= len(stsc.payload); e++ {
+		runs = append(runs, run{int(u32(stsc.payload[8+12*e:])), int(u32(stsc.payload[12+12*e:]))})
+	}
+	i = 0
+	for c := 0; c < len(chunks) && i < count; c++ {
+		perChunk := 0
+		for _, r := range runs {
+			if r.first <= c+1 {
+				perChunk = r.perChunk
+			}
+		}
+		offset := chunks[c]
+		for j := 0; j < perChunk && i < count; j, i = j+1, i+1 {
+			samples[i].offset = offset
+			offset += int64(samples[i].size)
+		}
+	}
+	if i < count {
+		return nil, fmt.Errorf("chunk table covers %d of %d samples", i, count)
+	}
+	return samples, nil
+}
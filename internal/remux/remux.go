@@ -0,0 +1,318 @@
+// Package remux segments already-encoded MP4 files into HLS without
+// spawning ffmpeg: it reads the MP4 sample tables, cuts at video keyframes,
+// and rewrites the samples as MPEG-TS (H.264 and AAC) or fragmented MP4
+// (any codec). Segment durations in the playlist are the exact sample
+// times between cuts rather than an encoder's estimate.
+package remux
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Options configures a SegmentFile run.
+type Options struct {
+	ManifestPath  string  // HLS media playlist to write; segments go beside it
+	SegmentLength float64 // Target segment duration in seconds; cuts land on the first keyframe after each multiple
+	FMP4          bool    // Write fMP4 segments with an init segment instead of MPEG-TS
+	VideoOnly     bool    // Leave audio out
+}
+
+// Segment is one written media segment.
+type Segment struct {
+	Path     string  // Segment file
+	Start    float64 // Presentation time of its first sample, in seconds
+	Duration float64 // Exact duration in seconds
+}
+
+// Result describes a written playlist.
+type Result struct {
+	Manifest       string
+	Init           string // fMP4 init segment, "" for MPEG-TS
+	Segments       []Segment
+	TargetDuration int // EXT-X-TARGETDURATION: the longest segment, rounded
+}
+
+// InitSegmentName is the fMP4 initialization segment written beside the playlist.
+const InitSegmentName = "init.mp4"
+
+// SegmentFile cuts input into HLS segments and writes their playlist. MPEG-TS output needs H.264 video
+// and AAC audio; other codecs need opts.FMP4.
+func SegmentFile(ctx context.Context, input string, opts Options) (*Result, error) {
+	if opts.SegmentLength <= 0 {
+		opts.SegmentLength = 4
+	}
+	m, err := openMP4(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", input, err)
+	}
+	defer m.Close()
+
+	var tracks []*track
+	if v := m.track("vide"); v != nil {
+		tracks = append(tracks, v)
+	}
+	if a := m.track("soun"); a != nil && !opts.VideoOnly {
+		tracks = append(tracks, a)
+	}
+	if len(tracks) == 0 {
+		return nil, fmt.Errorf("%s has no tracks to segment", input)
+	}
+	if !opts.FMP4 {
+		for _, t := range tracks {
+			if err := checkTS(t); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	dir := filepath.Dir(opts.ManifestPath)
+	result := &Result{Manifest: opts.ManifestPath}
+	if opts.FMP4 {
+		result.Init = filepath.Join(dir, InitSegmentName)
+		if err := os.WriteFile(result.Init, initSegment(tracks), 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	ext := ".ts"
+	if opts.FMP4 {
+		ext = ".m4s"
+	}
+	cuts, err := cutPoints(tracks, opts.SegmentLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to cut %s: %w", input, err)
+	}
+	for i, cut := range cuts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if opts.FMP4 {
+			err = writeFragment(&buf, m, cut, uint32(i+1))
+		} else {
+			err = writeTS(&buf, m, cut)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("segment %d: %w", i, err)
+		}
+		path := filepath.Join(dir, fmt.Sprintf("segment_%03d%s", i, ext))
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			return nil, err
+		}
+		result.Segments = append(result.Segments, Segment{Path: path, Start: cut.start, Duration: cut.end - cut.start})
+		result.TargetDuration = max(result.TargetDuration, int(math.Round(cut.end-cut.start)))
+	}
+	result.TargetDuration = max(result.TargetDuration, 1)
+	return result, writePlaylist(result)
+}
+
+// checkTS reports whether t can be carried in MPEG-TS by this package.
+func checkTS(t *track) error {
+	switch {
+	case t.kind == "vide" && t.codec != "avc1" && t.codec != "avc3":
+		return fmt.Errorf("MPEG-TS output supports H.264 video only, not %s; use fMP4", t.codec)
+	case t.kind == "vide" && (t.nalLength == 0 || len(t.sps) == 0):
+		return fmt.Errorf("H.264 track %d has no parameter sets", t.id)
+	case t.kind == "soun" && t.codec != "mp4a":
+		return fmt.Errorf("MPEG-TS output supports AAC audio only, not %s; use fMP4", t.codec)
+	case t.kind == "soun":
+		_, err := adtsHeader(t.asc, 0)
+		return err
+	}
+	return nil
+}
+
+// cut is one segment: a contiguous sample range per track.
+type cut struct {
+	start, end float64 // Presentation times in seconds
+	ranges     []trackRange
+}
+
+// trackRange is samples [from, to) of a track.
+type trackRange struct {
+	track    *track
+	from, to int
+}
+
+// cutPoints splits the tracks into segments. The first track (video when
+// present) decides the cuts: a segment ends at the first keyframe at or
+// after each multiple of length, so durations don't drift from the target
+// over the whole file. Other tracks are split at the same presentation times,
+// with each track's edit list applied. A lead track without samples or
+// keyframes can't be cut and returns an error.
+func cutPoints(tracks []*track, length float64) ([]cut, error) {
+	lead := tracks[0]
+	if len(lead.samples) == 0 {
+		return nil, fmt.Errorf("track %d has no samples", lead.id)
+	}
+	if !slices.ContainsFunc(lead.samples, func(s sample) bool { return s.key }) {
+		return nil, fmt.Errorf("track %d has no sync samples to cut at", lead.id)
+	}
+	var starts []int // Sample indexes of the lead track starting a segment
+	for i, s := range lead.samples {
+		if i == 0 {
+			starts = append(starts, 0)
+			continue
+		}
+		prev := lead.samples[starts[len(starts)-1]]
+		t := lead.presentation(s.dts + int64(s.cts))
+		if s.key && t >= float64(len(starts))*length-0.001 && t-lead.presentation(prev.dts+int64(prev.cts)) >= length/2 {
+			starts = append(starts, i)
+		}
+	}
+
+	end := 0.0
+	for _, t := range tracks {
+		end = max(end, t.end())
+	}
+	cuts := make([]cut, len(starts))
+	for n, i := range starts {
+		if n > 0 {
+			s := lead.samples[i]
+			cuts[n].start = lead.presentation(s.dts + int64(s.cts))
+			cuts[n-1].end = cuts[n].start
+		}
+	}
+	cuts[len(cuts)-1].end = end
+
+	for _, t := range tracks {
+		from := 0
+		for n := range cuts {
+			to := len(t.samples)
+			if n < len(cuts)-1 {
+				if t == lead {
+					to = starts[n+1]
+				} else {
+					boundary := cuts[n].end
+					to = from + sort.Search(len(t.samples)-from, func(k int) bool {
+						return t.presentation(t.samples[from+k].dts) >= boundary
+					})
+				}
+			}
+			cuts[n].ranges = append(cuts[n].ranges, trackRange{track: t, from: from, to: to})
+			from = to
+		}
+	}
+	return cuts, nil
+}
+
+// to90k converts a track's media time to the 90 kHz MPEG-TS clock, applying
+// its edit list.
+func to90k(t *track, v int64) int64 {
+	return (v-t.editOffset)*90000/int64(t.timescale) + tsOffset
+}
+
+// writeTS writes one MPEG-TS segment, interleaving the tracks by decode time.
+func writeTS(buf *bytes.Buffer, m *mp4File, c cut) error {
+	var video, audio *trackRange
+	for i := range c.ranges {
+		r := &c.ranges[i]
+		if r.track.kind == "vide" {
+			video = r
+		} else {
+			audio = r
+		}
+	}
+	w := newTSWriter(buf, video != nil, audio != nil)
+	if err := w.writeTables(); err != nil {
+		return err
+	}
+
+	vi, ai := 0, 0
+	if video != nil {
+		vi = video.from
+	}
+	if audio != nil {
+		ai = audio.from
+	}
+	for {
+		useVideo := video != nil && vi < video.to
+		useAudio := audio != nil && ai < audio.to
+		if useVideo && useAudio {
+			v, a := video.track.samples[vi], audio.track.samples[ai]
+			useVideo = video.track.presentation(v.dts) <= audio.track.presentation(a.dts)
+		}
+		switch {
+		case useVideo:
+			t := video.track
+			s := t.samples[vi]
+			data, err := m.read(s)
+			if err != nil {
+				return err
+			}
+			au, err := annexB(t, data, s.key)
+			if err != nil {
+				return err
+			}
+			if err := w.writeVideo(au, to90k(t, s.dts+int64(s.cts)), to90k(t, s.dts), s.key); err != nil {
+				return err
+			}
+			vi++
+		case useAudio:
+			t := audio.track
+			s := t.samples[ai]
+			data, err := m.read(s)
+			if err != nil {
+				return err
+			}
+			hdr, err := adtsHeader(t.asc, len(data))
+			if err != nil {
+				return err
+			}
+			if err := w.writeAudio(append(hdr, data...), to90k(t, s.dts)); err != nil {
+				return err
+			}
+			ai++
+		default:
+			return nil
+		}
+	}
+}
+
+// writeFragment writes one fMP4 media segment holding every track's samples.
+func writeFragment(buf *bytes.Buffer, m *mp4File, c cut, sequence uint32) error {
+	var runs []fragmentRun
+	for _, r := range c.ranges {
+		run := fragmentRun{track: r.track, samples: r.track.samples[r.from:r.to]}
+		for _, s := range run.samples {
+			data, err := m.read(s)
+			if err != nil {
+				return err
+			}
+			run.data = append(run.data, data)
+		}
+		runs = append(runs, run)
+	}
+	_, err := buf.Write(fragment(sequence, runs))
+	return err
+}
+
+// writePlaylist writes the VOD media playlist for result.
+func writePlaylist(result *Result) error {
+	version := 3
+	if result.Init != "" {
+		version = 7
+	}
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	fmt.Fprintf(&b, "#EXT-X-VERSION:%d\n", version)
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", result.TargetDuration)
+	b.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n")
+	b.WriteString("#EXT-X-PLAYLIST-TYPE:VOD\n")
+	if result.Init != "" {
+		fmt.Fprintf(&b, "#EXT-X-MAP:URI=\"%s\"\n", filepath.Base(result.Init))
+	}
+	for _, s := range result.Segments {
+		fmt.Fprintf(&b, "#EXTINF:%.6f,\n%s\n", s.Duration, filepath.Base(s.Path))
+	}
+	b.WriteString("#EXT-X-ENDLIST\n")
+	return os.WriteFile(result.Manifest, []byte(b.String()), 0644)
+}
@@ -0,0 +1,292 @@
+package remux
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// MPEG-TS packet identifiers and stream types, as ffmpeg's muxer assigns them.
+const (
+	tsPacketSize = 188
+	pmtPID       = 0x1000
+	videoPID     = 0x100
+	audioPID     = 0x101
+
+	streamTypeH264 = 0x1B
+	streamTypeAAC  = 0x0F // ADTS
+)
+
+// tsOffset is added to every timestamp so PTS stays positive when
+// composition offsets are negative, as ffmpeg's 1.4s muxing delay does.
+const tsOffset = 126000
+
+// tsWriter writes H.264 and AAC access units as MPEG-TS packets.
+type tsWriter struct {
+	w     io.Writer
+	video bool // Program has a video stream (PCR on the video PID)
+	audio bool // Program has an audio stream
+	cc    map[uint16]byte
+}
+
+// newTSWriter returns a writer for a program with the given streams.
+func newTSWriter(w io.Writer, video, audio bool) *tsWriter {
+	return &tsWriter{w: w, video: video, audio: audio, cc: make(map[uint16]byte)}
+}
+
+// pcrPID returns the PID carrying the program clock.
+func (t *tsWriter) pcrPID() uint16 {
+	if t.video {
+		return videoPID
+	}
+	return audioPID
+}
+
+// writeTables writes the PAT and PMT; each segment starts with them so it
+// can be decoded on its own.
+func (t *tsWriter) writeTables() error {
+	pat := []byte{
+		0x00,       // table_id
+		0xB0, 0x0D, // section_syntax_indicator, section_length 13
+		0x00, 0x01, // transport_stream_id
+		0xC1, 0x00, 0x00, // version 0, current, section 0 of 0
+		0x00, 0x01, // program_number 1
+		0xE0 | pmtPID>>8, pmtPID & 0xFF,
+	}
+	if err := t.writeSection(0, pat); err != nil {
+		return err
+	}
+
+	var streams []byte
+	if t.video {
+		streams = append(streams, streamTypeH264, 0xE0|videoPID>>8, videoPID&0xFF, 0xF0, 0x00)
+	}
+	if t.audio {
+		streams = append(streams, streamTypeAAC, 0xE0|audioPID>>8, audioPID&0xFF, 0xF0, 0x00)
+	}
+	length := 9 + len(streams) + 4
+	pmt := []byte{
+		0x02,
+		0xB0 | byte(length>>8), byte(length),
+		0x00, 0x01, // program_number 1
+		0xC1, 0x00, 0x00,
+		0xE0 | byte(t.pcrPID()>>8), byte(t.pcrPID()),
+		0xF0, 0x00, // program_info_length 0
+	}
+	return t.writeSection(pmtPID, append(pmt, streams...))
+}
+
+// writeSection writes a PSI section with its CRC in a single packet.
+func (t *tsWriter) writeSection(pid uint16, section []byte) error {
+	section = binary.BigEndian.AppendUint32(section, crc32MPEG(section))
+	pkt := bytes.Repeat([]byte{0xFF}, tsPacketSize)
+	pkt[0] = 0x47
+	pkt[1] = 0x40 | byte(pid>>8)
+	pkt[2] = byte(pid)
+	pkt[3] = 0x10 | t.next(pid)
+	pkt[4] = 0 // pointer_field
+	copy(pkt[5:], section)
+	_, err := t.w.Write(pkt)
+	return err
+}
+
+// next returns pid's continuity counter and advances it.
+func (t *tsWriter) next(pid uint16) byte {
+	cc := t.cc[pid]
+	t.cc[pid] = (cc + 1) & 0x0F
+	return cc
+}
+
+// writeVideo writes an H.264 access unit given in 90 kHz time.
+func (t *tsWriter) writeVideo(data []byte, pts, dts int64, key bool) error {
+	return t.writePES(videoPID, 0xE0, data, pts, dts, key, true)
+}
+
+// writeAudio writes ADTS-framed AAC given in 90 kHz time.
+func (t *tsWriter) writeAudio(data []byte, pts int64) error {
+	return t.writePES(audioPID, 0xC0, data, pts, pts, true, !t.video)
+}
+
+// writePES wraps data in a PES packet and splits it across TS packets. The
+// first packet flags random access and carries the PCR when asked.
+func (t *tsWriter) writePES(pid uint16, streamID byte, data []byte, pts, dts int64, random, pcr bool) error {
+	hdr := []byte{0x00, 0x00, 0x01, streamID, 0, 0, 0x80, 0x80, 5}
+	if dts != pts {
+		hdr[7], hdr[8] = 0xC0, 10
+		hdr = append(hdr, timestamp(0x3, pts)...)
+		hdr = append(hdr, timestamp(0x1, dts)...)
+	} else {
+		hdr = append(hdr, timestamp(0x2, pts)...)
+	}
+	if streamID != 0xE0 { // Video PES may be unbounded (length 0)
+		length := len(hdr) - 6 + len(data)
+		if length > 0xFFFF {
+			return errors.New("audio frame too large for a PES packet")
+		}
+		binary.BigEndian.PutUint16(hdr[4:], uint16(length))
+	}
+	payload := append(hdr, data...)
+
+	for first := true; len(payload) > 0; first = false {
+		var af []byte // Adaptation field after its length byte
+		hasAF := false
+		if first && (random || pcr) {
+			hasAF = true
+			af = []byte{0}
+			if random {
+				af[0] |= 0x40
+			}
+			if pcr {
+				af[0] |= 0x10
+				af = append(af, pcrBytes(dts)...)
+			}
+		}
+		room := tsPacketSize - 4
+		if hasAF {
+			room -= 1 + len(af)
+		}
+		if len(payload) < room {
+			// Stuff the adaptation field so the packet is full
+			stuff := room - len(payload)
+			switch {
+			case hasAF:
+				af = append(af, bytes.Repeat([]byte{0xFF}, stuff)...)
+			case stuff == 1:
+				hasAF, af = true, nil
+			default:
+				hasAF, af = true, append([]byte{0}, bytes.Repeat([]byte{0xFF}, stuff-2)...)
+			}
+			room = len(payload)
+		}
+
+		pkt := make([]byte, 4, tsPacketSize)
+		pkt[0] = 0x47
+		pkt[1] = byte(pid >> 8)
+		if first {
+			pkt[1] |= 0x40 // payload_unit_start_indicator
+		}
+		pkt[2] = byte(pid)
+		pkt[3] = 0x10 | t.next(pid)
+		if hasAF {
+			pkt[3] |= 0x20
+			pkt = append(pkt, byte(len(af)))
+			pkt = append(pkt, af...)
+		}
+		pkt = append(pkt, payload[:room]...)
+		payload = payload[room:]
+		if _, err := t.w.Write(pkt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// timestamp encodes a 33-bit PES PTS or DTS with its 4-bit prefix.
+func timestamp(prefix byte, v int64) []byte {
+	return []byte{
+		prefix<<4 | byte(v>>29)&0x0E | 1,
+		byte(v >> 22),
+		byte(v>>14)&0xFE | 1,
+		byte(v >> 7),
+		byte(v<<1)&0xFE | 1,
+	}
+}
+
+// pcrBytes encodes a program clock reference from a 90 kHz base.
+func pcrBytes(base int64) []byte {
+	return []byte{
+		byte(base >> 25),
+		byte(base >> 17),
+		byte(base >> 9),
+		byte(base >> 1),
+		byte(base<<7)&0x80 | 0x7E,
+		0x00,
+	}
+}
+
+// annexB converts a length-prefixed H.264 sample to Annex B start codes,
+// led by an access unit delimiter and, on keyframes, the SPS and PPS.
+func annexB(t *track, data []byte, key bool) ([]byte, error) {
+	startCode := []byte{0, 0, 0, 1}
+	out := append([]byte(nil), startCode...)
+	out = append(out, 0x09, 0xF0) // Access unit delimiter
+	if key {
+		for _, ps := range append(append([][]byte(nil), t.sps...), t.pps...) {
+			out = append(out, startCode...)
+			out = append(out, ps...)
+		}
+	}
+	for len(data) > 0 {
+		if len(data) < t.nalLength {
+			return nil, errors.New("truncated NAL unit length")
+		}
+		size := 0
+		for _, b := range data[:t.nalLength] {
+			size = size<<8 | int(b)
+		}
+		data = data[t.nalLength:]
+		if size > len(data) {
+			return nil, errors.New("NAL unit overruns its sample")
+		}
+		if nal := data[:size]; len(nal) > 0 && nal[0]&0x1F != 0x09 {
+			out = append(out, startCode...)
+			out = append(out, nal...)
+		}
+		data = data[size:]
+	}
+	return out, nil
+}
+
+// adtsHeader returns the 7-byte ADTS header for an AAC frame of size bytes
+// described by an AudioSpecificConfig.
+func adtsHeader(asc []byte, size int) ([]byte, error) {
+	if len(asc) < 2 {
+		return nil, errors.New("missing AAC AudioSpecificConfig")
+	}
+	objectType := int(asc[0] >> 3)
+	if objectType == 5 || objectType == 29 {
+		objectType = 2 // HE-AAC signals its AAC-LC core in ADTS
+	}
+	if objectType < 1 || objectType > 4 {
+		return nil, errors.New("AAC object type can't be carried in ADTS")
+	}
+	freq := int(asc[0]&0x07)<<1 | int(asc[1]>>7)
+	channels := int(asc[1]>>3) & 0x0F
+	length := size + 7
+	return []byte{
+		0xFF,
+		0xF1, // MPEG-4, no CRC
+		byte((objectType-1)<<6 | freq<<2 | channels>>2),
+		byte((channels&3)<<6 | length>>11),
+		byte(length >> 3),
+		byte((length&7)<<5 | 0x1F),
+		0xFC,
+	}, nil
+}
+
+// crcTable is the MPEG-2 CRC-32 table (polynomial 0x04C11DB7, unreflected).
+var crcTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		c := uint32(i) << 24
+		for range 8 {
+			if c&0x80000000 != 0 {
+				c = c<<1 ^ 0x04C11DB7
+			} else {
+				c <<= 1
+			}
+		}
+		table[i] = c
+	}
+	return table
+}()
+
+// crc32MPEG computes the CRC PSI sections end with.
+func crc32MPEG(b []byte) uint32 {
+	crc := uint32(0xFFFFFFFF)
+	for _, v := range b {
+		crc = crc<<8 ^ crcTable[byte(crc>>24)^v]
+	}
+	return crc
+}
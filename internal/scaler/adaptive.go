@@ -1,35 +1,48 @@
 package scaler
 
 // AdjustResolution dynamically selects a resolution based on bandwidth and playback health.
-// It uses failure count, bandwidth, and manual override to decide whether to drop or bump resolution.
+// It uses failure count, bandwidth, and manual override to decide whether to drop or bump resolution,
+// and never lands above the user's preference caps (MaxHeight, MaxBitrateKbps, DataSaver).
+// Bitrate thresholds are scaled to ctx.VideoCodec.
 func AdjustResolution(current ResolutionPreset, ctx ClientContext) ResolutionPreset {
-	// Manual override takes precedence
+	return adjustResolution(presetsFor(ctx.VideoCodec), current, ctx)
+}
+
+// adjustResolution implements AdjustResolution over candidates, ordered from
+// highest to lowest resolution.
+func adjustResolution(candidates []ResolutionPreset, current ResolutionPreset, ctx ClientContext) ResolutionPreset {
+	// Manual override takes precedence, within the user's caps
 	if ctx.ManualOverride != "" {
-		for _, preset := range StandardPresets {
-			if NormalizeLabel(preset.Label) == NormalizeLabel(ctx.ManualOverride) {
+		for _, preset := range candidates {
+			if NormalizeLabel(preset.Label) == NormalizeLabel(ctx.ManualOverride) && ctx.Allows(preset) {
 				return preset
 			}
 		}
 	}
 
+	// A rung above the user's caps is left even with adaptation off
+	if !ctx.Allows(current) {
+		return capResolution(candidates, current, ctx)
+	}
+
 	if !ctx.AdaptiveEnabled {
 		return current
 	}
 
 	// Drop resolution if failures exceed threshold
 	if ctx.RecentFailures >= 3 {
-		for i := len(StandardPresets) - 1; i >= 0; i-- {
-			p := StandardPresets[i]
+		for i := len(candidates) - 1; i >= 0; i-- {
+			p := candidates[i]
 			if p.MinBitrate <= ctx.BandwidthKbps && p.Height < current.Height {
 				return p
 			}
 		}
 	}
 
-	// Bump resolution if stable and bandwidth allows
+	// Bump resolution if stable and bandwidth and the user's caps allow
 	if ctx.RecentFailures == 0 {
-		for _, p := range StandardPresets {
-			if p.MinBitrate <= ctx.BandwidthKbps && p.Height > current.Height {
+		for _, p := range candidates {
+			if p.MinBitrate <= ctx.BandwidthKbps && p.Height > current.Height && ctx.Allows(p) {
 				return p
 			}
 		}
@@ -38,3 +51,23 @@ func AdjustResolution(current ResolutionPreset, ctx ClientContext) ResolutionPre
 	// No change
 	return current
 }
+
+// capResolution returns the tallest candidate within the user's caps that
+// the client's bandwidth sustains, else the lowest within the caps, else the
+// lowest candidate.
+func capResolution(candidates []ResolutionPreset, current ResolutionPreset, ctx ClientContext) ResolutionPreset {
+	if len(candidates) == 0 {
+		return current
+	}
+	lowest := candidates[len(candidates)-1]
+	for _, p := range candidates {
+		if !ctx.Allows(p) {
+			continue
+		}
+		if ctx.BandwidthKbps <= 0 || p.MinBitrate <= ctx.BandwidthKbps {
+			return p
+		}
+		lowest = p
+	}
+	return lowest
+}
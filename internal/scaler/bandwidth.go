@@ -0,0 +1,172 @@
+// Package scaler keeps rolling bandwidth estimates for playback sessions.
+// This file ingests the periodic bandwidth reports players send (e.g. with
+// their beacons) and smooths them into an estimate AdjustResolution can use
+// in place of a single static ClientContext.BandwidthKbps.
+package scaler
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Bandwidth estimation defaults.
+const (
+	DefaultBandwidthHalfLife = 10 * time.Second // Age at which a report carries half its original weight
+	DefaultBandwidthMaxAge   = 5 * time.Minute  // Sessions without a report for this long are forgotten
+)
+
+// BandwidthEstimate is an exponentially weighted moving average of a
+// session's bandwidth reports, weighted by time: a report's weight halves
+// every half-life, so a burst of reports doesn't drown out the trend and a
+// report after a long gap counts almost fully. The zero value has no
+// estimate.
+type BandwidthEstimate struct {
+	Kbps    float64   `json:"kbps"`    // Current estimate
+	Weight  float64   `json:"weight"`  // Decayed weight of the reports behind the estimate
+	Samples int       `json:"samples"` // Reports ingested
+	Updated time.Time `json:"updated"` // Time of the latest report
+}
+
+// Add folds a report of kbps measured at into the estimate with the given
+// half-life. Reports at or below zero are ignored; reports older than the
+// latest one are folded in as if they arrived with it.
+func (e *BandwidthEstimate) Add(kbps int, at time.Time, halfLife time.Duration) {
+	if kbps <= 0 {
+		return
+	}
+	if halfLife <= 0 {
+		halfLife = DefaultBandwidthHalfLife
+	}
+	if e.Samples == 0 {
+		e.Kbps, e.Weight, e.Samples, e.Updated = float64(kbps), 1, 1, at
+		return
+	}
+	elapsed := at.Sub(e.Updated)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	// Earlier reports decay by the time since the last one
+	decay := math.Exp2(-elapsed.Seconds() / halfLife.Seconds())
+	prior := e.Weight * decay
+	e.Weight = prior + 1
+	e.Kbps = (e.Kbps*prior + float64(kbps)) / e.Weight
+	e.Samples++
+	if at.After(e.Updated) {
+		e.Updated = at
+	}
+}
+
+// Value returns the estimate rounded to whole kbps, or 0 when no report
+// has been added.
+func (e BandwidthEstimate) Value() int {
+	if e.Samples == 0 {
+		return 0
+	}
+	return int(math.Round(e.Kbps))
+}
+
+// BandwidthEstimator ingests bandwidth reports for many playback sessions
+// and keeps a rolling BandwidthEstimate for each. It is safe for concurrent
+// use.
+type BandwidthEstimator struct {
+	HalfLife time.Duration // Report weight half-life; DefaultBandwidthHalfLife if zero
+	MaxAge   time.Duration // Idle time after which a session is forgotten; DefaultBandwidthMaxAge if zero
+
+	mu       sync.Mutex
+	sessions map[string]*BandwidthEstimate
+	swept    time.Time // Last time idle sessions were dropped
+}
+
+// NewBandwidthEstimator returns an estimator with the default half-life and
+// maximum age.
+func NewBandwidthEstimator() *BandwidthEstimator {
+	return &BandwidthEstimator{
+		HalfLife: DefaultBandwidthHalfLife,
+		MaxAge:   DefaultBandwidthMaxAge,
+	}
+}
+
+// Report ingests a bandwidth report for session. A zero at means now.
+func (b *BandwidthEstimator) Report(session string, kbps int, at time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	if at.IsZero() {
+		at = now
+	}
+	if b.sessions == nil {
+		b.sessions = make(map[string]*BandwidthEstimate)
+	}
+	b.expire(now)
+	e := b.sessions[session]
+	if e == nil {
+		e = &BandwidthEstimate{}
+		b.sessions[session] = e
+	}
+	e.Add(kbps, at, b.HalfLife)
+	if e.Samples == 0 {
+		delete(b.sessions, session)
+	}
+}
+
+// Estimate returns the session's current estimate in kbps, or false when
+// it has no reports or has been idle past MaxAge.
+func (b *BandwidthEstimator) Estimate(session string) (int, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	e := b.sessions[session]
+	if e == nil || b.idle(e, time.Now()) {
+		return 0, false
+	}
+	return e.Value(), true
+}
+
+// Context returns ctx with BandwidthKbps replaced by the session's rolling
+// estimate. ctx is returned unchanged when the session has no estimate.
+func (b *BandwidthEstimator) Context(session string, ctx ClientContext) ClientContext {
+	if kbps, ok := b.Estimate(session); ok {
+		ctx.BandwidthKbps = kbps
+	}
+	return ctx
+}
+
+// AdjustResolution is AdjustResolution with the session's rolling estimate
+// standing in for ctx.BandwidthKbps.
+func (b *BandwidthEstimator) AdjustResolution(session string, current ResolutionPreset, ctx ClientContext) ResolutionPreset {
+	return AdjustResolution(current, b.Context(session, ctx))
+}
+
+// Forget drops the session's estimate, e.g. when playback ends.
+func (b *BandwidthEstimator) Forget(session string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.sessions, session)
+}
+
+// expire drops sessions idle past MaxAge, sweeping at most once per MaxAge.
+// Callers hold b.mu.
+func (b *BandwidthEstimator) expire(now time.Time) {
+	if now.Sub(b.swept) < b.maxAge() {
+		return
+	}
+	b.swept = now
+	for id, e := range b.sessions {
+		if b.idle(e, now) {
+			delete(b.sessions, id)
+		}
+	}
+}
+
+// idle reports whether e's latest report is older than MaxAge.
+func (b *BandwidthEstimator) idle(e *BandwidthEstimate, now time.Time) bool {
+	return now.Sub(e.Updated) > b.maxAge()
+}
+
+// maxAge returns MaxAge or its default.
+func (b *BandwidthEstimator) maxAge() time.Duration {
+	if b.MaxAge <= 0 {
+		return DefaultBandwidthMaxAge
+	}
+	return b.MaxAge
+}
@@ -6,14 +6,27 @@ package scaler
 // It is used to guide resolution selection and adaptive scaling.
 type ClientContext struct {
 	DeviceType      string // e.g. "mobile", "desktop", "tv"
-	BandwidthKbps   int    // Current estimated bandwidth in Kbps
+	BandwidthKbps   int    // Current estimated bandwidth in Kbps (BandwidthEstimator keeps a rolling one per session)
 	PreferUpscale   bool   // If true, prefers higher resolution even if bandwidth is borderline
 	AllowLowRes     bool   // If false, restricts resolution below a certain threshold
 	ManualOverride  string // If set, forces a specific resolution (e.g. "720p")
 	RecentFailures  int    // Number of recent playback stalls or buffering events
 	AdaptiveEnabled bool   // Enables dynamic resolution switching
+	VideoCodec      string // Codec the client plays (e.g. "hevc", "av1"); scales StandardPresets' H.264 bitrate thresholds, "" for H.264
+
+	// User preference caps (e.g. account quality settings), enforced by every selection
+	MaxHeight      int  // Tallest resolution allowed in pixels (e.g. 720); 0 for no cap
+	MaxBitrateKbps int  // Highest bitrate allowed in Kbps; 0 for no cap
+	DataSaver      bool // Caps playback at DataSaverMaxHeight and DataSaverMaxKbps
 }
 
+// Data-saver caps applied when ClientContext.DataSaver is set; a lower
+// MaxHeight or MaxBitrateKbps still wins.
+const (
+	DataSaverMaxHeight = 480
+	DataSaverMaxKbps   = 1000
+)
+
 // IsMobile returns true if the device is mobile
 func (c *ClientContext) IsMobile() bool {
 	return c != nil && c.DeviceType == "mobile"
@@ -23,3 +36,43 @@ func (c *ClientContext) IsMobile() bool {
 func (c *ClientContext) IsBandwidthConstrained() bool {
 	return c != nil && c.BandwidthKbps > 0 && c.BandwidthKbps < 2500
 }
+
+// HeightCap returns the tallest resolution the user's preferences allow, or
+// 0 when uncapped.
+func (c *ClientContext) HeightCap() int {
+	if c == nil {
+		return 0
+	}
+	return minCap(c.MaxHeight, c.DataSaver, DataSaverMaxHeight)
+}
+
+// BitrateCap returns the highest bitrate in Kbps the user's preferences
+// allow, or 0 when uncapped.
+func (c *ClientContext) BitrateCap() int {
+	if c == nil {
+		return 0
+	}
+	return minCap(c.MaxBitrateKbps, c.DataSaver, DataSaverMaxKbps)
+}
+
+// Allows reports whether preset p is within the user's preference caps. A
+// preset's MinBitrate stands for its bitrate: the threshold for standard
+// presets, the rung's bandwidth for a Ladder.
+func (c *ClientContext) Allows(p ResolutionPreset) bool {
+	if h := c.HeightCap(); h > 0 && p.Height > h {
+		return false
+	}
+	if b := c.BitrateCap(); b > 0 && p.MinBitrate > b {
+		return false
+	}
+	return true
+}
+
+// minCap combines a user cap with the data-saver cap when saver is set; 0
+// means uncapped.
+func minCap(limit int, saver bool, saverLimit int) int {
+	if saver && (limit <= 0 || saverLimit < limit) {
+		return saverLimit
+	}
+	return max(limit, 0)
+}
@@ -0,0 +1,73 @@
+// Package scaler scales preset bitrate thresholds by codec efficiency.
+// This file converts StandardPresets' MinBitrate, quoted for H.264, into the
+// bitrate an HEVC, VP9, or AV1 encode needs for the same quality, so
+// efficient-codec variants aren't held to H.264 thresholds.
+package scaler
+
+import (
+	"math"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+)
+
+// CodecBitrateFactors is the bitrate each codec family needs relative to
+// H.264 for comparable quality. Families missing from the map use 1.
+var CodecBitrateFactors = map[string]float64{
+	"h264": 1.0,
+	"hevc": 0.6,
+	"vp9":  0.65,
+	"av1":  0.5,
+}
+
+// CodecFamily returns the video codec family ("h264", "hevc", "vp9", "av1")
+// of an RFC 6381 codecs string (e.g. "hvc1.1.6.L120.B0", "av01.0.08M.08,opus")
+// or an ffmpeg codec or encoder name (e.g. "libx265"), or "" when none is
+// recognized.
+func CodecFamily(codec string) string {
+	if family := helpers.VideoFamilyFromCodecs(codec); family != "" {
+		return family
+	}
+	switch family := helpers.CodecFamily(codec); family {
+	case "h264", "hevc", "vp9", "av1":
+		return family
+	}
+	return ""
+}
+
+// BitrateFactor returns the bitrate codec needs relative to H.264 (see
+// CodecBitrateFactors); 1 for H.264, unknown codecs, and "".
+func BitrateFactor(codec string) float64 {
+	if f, ok := CodecBitrateFactors[CodecFamily(codec)]; ok && f > 0 {
+		return f
+	}
+	return 1
+}
+
+// MinBitrateFor returns the preset's MinBitrate, quoted for H.264, scaled
+// to what codec needs for the same quality.
+func (r ResolutionPreset) MinBitrateFor(codec string) int {
+	return int(math.Round(float64(r.MinBitrate) * BitrateFactor(codec)))
+}
+
+// presetsFor returns StandardPresets with MinBitrate scaled to codec. The
+// standard list itself is returned for H.264 and unknown codecs.
+func presetsFor(codec string) []ResolutionPreset {
+	if BitrateFactor(codec) == 1 {
+		return StandardPresets
+	}
+	presets := make([]ResolutionPreset, len(StandardPresets))
+	for i, p := range StandardPresets {
+		p.MinBitrate = p.MinBitrateFor(codec)
+		presets[i] = p
+	}
+	return presets
+}
+
+// clientCodec returns the video codec the client plays, or "" without a
+// context.
+func clientCodec(ctx *ClientContext) string {
+	if ctx == nil {
+		return ""
+	}
+	return ctx.VideoCodec
+}
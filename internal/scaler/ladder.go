@@ -0,0 +1,188 @@
+// Package scaler provides scaling decisions over a title's actual ladder.
+// This file parses the variants a master manifest or pipeline report lists,
+// so start-rung selection and adaptive switching only pick rungs that exist.
+package scaler
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+)
+
+// Rung is one variant of a title's ladder as published.
+type Rung struct {
+	URI              string // Variant playlist URI as listed in the master manifest, relative to it
+	Width            int    // Width in pixels
+	Height           int    // Height in pixels
+	Bandwidth        int    // Peak bitrate in kbps (BANDWIDTH), what a player needs to sustain the rung
+	AverageBandwidth int    // Average bitrate in kbps (AVERAGE-BANDWIDTH), 0 if not listed
+	Codecs           string // RFC 6381 CODECS value (e.g. "avc1.64001f,mp4a.40.2")
+}
+
+// Label returns the rung's resolution label (e.g. "720p").
+func (r Rung) Label() string {
+	return fmt.Sprintf("%dp", r.Height)
+}
+
+// Preset returns the rung as a ResolutionPreset whose MinBitrate is the
+// rung's own bandwidth rather than a standard threshold.
+func (r Rung) Preset() ResolutionPreset {
+	return ResolutionPreset{
+		Width:      r.Width,
+		Height:     r.Height,
+		Label:      r.Label(),
+		MinBitrate: r.Bandwidth,
+	}
+}
+
+// Ladder is a title's variants ordered like StandardPresets, from the
+// highest resolution to the lowest; rungs of equal height are ordered by
+// bandwidth, highest first. Rung bandwidths are what the encodes actually
+// need, so ClientContext.VideoCodec doesn't scale them.
+type Ladder []Rung
+
+// NewLadder returns rungs as a Ladder, sorted. Rungs without a height are
+// dropped.
+func NewLadder(rungs []Rung) Ladder {
+	var l Ladder
+	for _, r := range rungs {
+		if r.Height > 0 {
+			l = append(l, r)
+		}
+	}
+	sort.SliceStable(l, func(i, j int) bool {
+		if l[i].Height != l[j].Height {
+			return l[i].Height > l[j].Height
+		}
+		return l[i].Bandwidth > l[j].Bandwidth
+	})
+	return l
+}
+
+// ParseMasterPlaylist reads the ladder from an HLS master playlist: one rung
+// per #EXT-X-STREAM-INF with a RESOLUTION. Audio-only entries and I-frame
+// playlists are skipped.
+func ParseMasterPlaylist(data []byte) (Ladder, error) {
+	raw := string(data)
+	if !strings.HasPrefix(strings.TrimSpace(strings.TrimPrefix(raw, "\ufeff")), "#EXTM3U") {
+		return nil, NewScalerError("ParseMasterPlaylist", "not an HLS playlist")
+	}
+	var rungs []Rung
+	var pending map[string]string
+	for _, line := range strings.Split(raw, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXT-X-STREAM-INF:"):
+			pending = helpers.ParseHLSAttributes(strings.TrimPrefix(line, "#EXT-X-STREAM-INF:"))
+		case strings.HasPrefix(line, "#"):
+		case pending != nil:
+			width, height := parseResolution(pending["RESOLUTION"])
+			bandwidth, _ := strconv.Atoi(pending["BANDWIDTH"])
+			average, _ := strconv.Atoi(pending["AVERAGE-BANDWIDTH"])
+			rungs = append(rungs, Rung{
+				URI:              line,
+				Width:            width,
+				Height:           height,
+				Bandwidth:        bandwidth / 1000,
+				AverageBandwidth: average / 1000,
+				Codecs:           pending["CODECS"],
+			})
+			pending = nil
+		}
+	}
+	l := NewLadder(rungs)
+	if len(l) == 0 {
+		return nil, NewScalerError("ParseMasterPlaylist", "no video variants found")
+	}
+	return l, nil
+}
+
+// Presets returns the ladder's rungs as presets, in ladder order.
+func (l Ladder) Presets() []ResolutionPreset {
+	presets := make([]ResolutionPreset, len(l))
+	for i, r := range l {
+		presets[i] = r.Preset()
+	}
+	return presets
+}
+
+// Find returns the first rung matching label (e.g. "720p"), or false.
+func (l Ladder) Find(label string) (Rung, bool) {
+	norm := NormalizeLabel(label)
+	for _, r := range l {
+		if NormalizeLabel(r.Label()) == norm {
+			return r, true
+		}
+	}
+	return Rung{}, false
+}
+
+// SelectResolutions is SelectResolutions over the ladder: the rungs the
+// client's bandwidth, low-resolution preference, and caps allow. Every rung
+// already fits the source, so upscaling is not considered.
+func (l Ladder) SelectResolutions(ctx *ClientContext) ([]ResolutionPreset, error) {
+	var selected []ResolutionPreset
+	for _, preset := range l.Presets() {
+		if ctx != nil && !ctx.AllowLowRes && preset.IsSD() {
+			continue
+		}
+		if ctx != nil && ctx.BandwidthKbps > 0 && preset.MinBitrate > ctx.BandwidthKbps {
+			continue
+		}
+		if !ctx.Allows(preset) {
+			continue
+		}
+		selected = append(selected, preset)
+	}
+	if len(selected) == 0 {
+		return nil, NewScalerError("Ladder.SelectResolutions", "no suitable rungs found")
+	}
+	return selected, nil
+}
+
+// SelectPreset is SelectPreset over the ladder: the highest rung the
+// client's bandwidth sustains within the user's caps, falling back to the
+// lowest rung when none does.
+func (l Ladder) SelectPreset(ctx *ClientContext) (*ScalingDecision, error) {
+	if len(l) == 0 {
+		return nil, NewScalerError("Ladder.SelectPreset", "ladder is empty")
+	}
+	for _, preset := range l.Presets() {
+		if ctx != nil && ctx.BandwidthKbps > 0 && preset.MinBitrate > ctx.BandwidthKbps {
+			continue
+		}
+		if !ctx.Allows(preset) {
+			continue
+		}
+		reason := fmt.Sprintf("Selected %s (%d kbps) from a %d-rung ladder", preset.Label, preset.MinBitrate, len(l))
+		if ctx != nil {
+			reason += fmt.Sprintf(" and client context %+v", ctx)
+		}
+		return &ScalingDecision{Preset: preset, Reason: reason}, nil
+	}
+	return &ScalingDecision{
+		Preset: l[len(l)-1].Preset(),
+		Reason: "No rung fits the client's bandwidth, falling back to the lowest",
+	}, nil
+}
+
+// AdjustResolution is AdjustResolution over the ladder, so drops, bumps,
+// and manual overrides only land on rungs that exist.
+func (l Ladder) AdjustResolution(current ResolutionPreset, ctx ClientContext) ResolutionPreset {
+	return adjustResolution(l.Presets(), current, ctx)
+}
+
+// parseResolution splits a RESOLUTION attribute ("1280x720").
+func parseResolution(s string) (width, height int) {
+	w, h, ok := strings.Cut(s, "x")
+	if !ok {
+		return 0, 0
+	}
+	width, _ = strconv.Atoi(w)
+	height, _ = strconv.Atoi(h)
+	return width, height
+}
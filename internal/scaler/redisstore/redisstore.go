@@ -0,0 +1,189 @@
+// Package redisstore is a scaler.SessionStore backed by Redis, so adaptive
+// decisions see a session's history whichever server handles the request.
+// It talks to Redis through the small Client interface rather than a
+// particular driver; wrapping a go-redis client takes a few lines:
+//
+//	type goRedis struct{ c *redis.Client }
+//
+//	func (g goRedis) Get(ctx context.Context, key string) ([]byte, error) {
+//		b, err := g.c.Get(ctx, key).Bytes()
+//		if errors.Is(err, redis.Nil) {
+//			return nil, nil
+//		}
+//		return b, err
+//	}
+//	func (g goRedis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+//		return g.c.Set(ctx, key, value, ttl).Err()
+//	}
+//	func (g goRedis) Del(ctx context.Context, key string) error {
+//		return g.c.Del(ctx, key).Err()
+//	}
+//
+// Clients that also implement TxClient get atomic session updates; with
+// go-redis, CompareAndSet is a WATCH/MULTI transaction:
+//
+//	func (g goRedis) CompareAndSet(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error) {
+//		err := g.c.Watch(ctx, func(tx *redis.Tx) error {
+//			cur, err := tx.Get(ctx, key).Bytes()
+//			if err != nil && !errors.Is(err, redis.Nil) {
+//				return err
+//			}
+//			if !bytes.Equal(cur, old) {
+//				return redis.TxFailedErr
+//			}
+//			_, err = tx.TxPipelined(ctx, func(p redis.Pipeliner) error {
+//				return p.Set(ctx, key, value, ttl).Err()
+//			})
+//			return err
+//		}, key)
+//		if errors.Is(err, redis.TxFailedErr) {
+//			return false, nil
+//		}
+//		return err == nil, err
+//	}
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/scaler"
+)
+
+// DefaultPrefix namespaces session keys.
+const DefaultPrefix = "dotgo:abr:session:"
+
+// Client is the subset of a Redis client the store needs.
+type Client interface {
+	// Get returns the value of key, or (nil, nil) when it doesn't exist.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores value under key, expiring after ttl.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Del removes key.
+	Del(ctx context.Context, key string) error
+}
+
+// TxClient is a Client that can write a key only if it hasn't changed,
+// letting Store.Update change sessions atomically.
+type TxClient interface {
+	Client
+	// CompareAndSet stores value under key, expiring after ttl, if key
+	// still holds old (nil: doesn't exist), and reports whether it did.
+	CompareAndSet(ctx context.Context, key string, old, value []byte, ttl time.Duration) (bool, error)
+}
+
+// maxUpdateAttempts bounds how often Update starts over after another
+// writer changed the session first.
+const maxUpdateAttempts = 10
+
+// Store keeps sessions as JSON under Prefix+id, each expiring TTL after its
+// last update.
+type Store struct {
+	client Client
+	Prefix string        // Key prefix; DefaultPrefix if empty
+	TTL    time.Duration // Session expiry; scaler.DefaultSessionTTL if zero
+}
+
+// New returns a store on client with the default prefix and TTL.
+func New(client Client) *Store {
+	return &Store{client: client, Prefix: DefaultPrefix, TTL: scaler.DefaultSessionTTL}
+}
+
+// Get returns session id.
+func (s *Store) Get(ctx context.Context, id string) (scaler.SessionState, error) {
+	data, err := s.client.Get(ctx, s.key(id))
+	if err != nil {
+		return scaler.SessionState{}, fmt.Errorf("failed to read session %s: %w", id, err)
+	}
+	if data == nil {
+		return scaler.SessionState{}, fmt.Errorf("%w: %s", scaler.ErrSessionNotFound, id)
+	}
+	var state scaler.SessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return scaler.SessionState{}, fmt.Errorf("failed to decode session %s: %w", id, err)
+	}
+	return state, nil
+}
+
+// Put stores state, resetting its expiry.
+func (s *Store) Put(ctx context.Context, state scaler.SessionState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode session %s: %w", state.ID, err)
+	}
+	if err := s.client.Set(ctx, s.key(state.ID), data, s.ttl()); err != nil {
+		return fmt.Errorf("failed to write session %s: %w", state.ID, err)
+	}
+	return nil
+}
+
+// Update applies fn to session id and stores the result. With a TxClient
+// the write only lands if the session is unchanged since it was read, and
+// Update starts over otherwise; with a plain Client a concurrent write from
+// another server may be lost.
+func (s *Store) Update(ctx context.Context, id string, fn func(*scaler.SessionState)) (scaler.SessionState, error) {
+	tx, ok := s.client.(TxClient)
+	if !ok {
+		state, err := s.Get(ctx, id)
+		if errors.Is(err, scaler.ErrSessionNotFound) {
+			state, err = scaler.SessionState{ID: id}, nil
+		}
+		if err != nil {
+			return scaler.SessionState{}, err
+		}
+		fn(&state)
+		return state, s.Put(ctx, state)
+	}
+	for range maxUpdateAttempts {
+		old, err := s.client.Get(ctx, s.key(id))
+		if err != nil {
+			return scaler.SessionState{}, fmt.Errorf("failed to read session %s: %w", id, err)
+		}
+		state := scaler.SessionState{ID: id}
+		if old != nil {
+			if err := json.Unmarshal(old, &state); err != nil {
+				return scaler.SessionState{}, fmt.Errorf("failed to decode session %s: %w", id, err)
+			}
+		}
+		fn(&state)
+		data, err := json.Marshal(state)
+		if err != nil {
+			return scaler.SessionState{}, fmt.Errorf("failed to encode session %s: %w", id, err)
+		}
+		written, err := tx.CompareAndSet(ctx, s.key(id), old, data, s.ttl())
+		if err != nil {
+			return scaler.SessionState{}, fmt.Errorf("failed to write session %s: %w", id, err)
+		}
+		if written {
+			return state, nil
+		}
+	}
+	return scaler.SessionState{}, fmt.Errorf("failed to write session %s: changed by another writer %d times in a row", id, maxUpdateAttempts)
+}
+
+// Delete removes session id.
+func (s *Store) Delete(ctx context.Context, id string) error {
+	if err := s.client.Del(ctx, s.key(id)); err != nil {
+		return fmt.Errorf("failed to delete session %s: %w", id, err)
+	}
+	return nil
+}
+
+// ttl returns TTL or its default.
+func (s *Store) ttl() time.Duration {
+	if s.TTL <= 0 {
+		return scaler.DefaultSessionTTL
+	}
+	return s.TTL
+}
+
+// key returns the Redis key of session id.
+func (s *Store) key(id string) string {
+	if s.Prefix == "" {
+		return DefaultPrefix + id
+	}
+	return s.Prefix + id
+}
@@ -7,11 +7,12 @@ import (
 )
 
 // SelectResolutions filters resolution presets based on source media and client context.
-// Applies bandwidth filtering, device preferences, and upscaling rules.
+// Applies bandwidth filtering, device and user preferences, and upscaling rules.
+// Bitrate thresholds are scaled to the client's video codec (see MinBitrateFor).
 func SelectResolutions(media *analyzer.MediaInfo, ctx *ClientContext) ([]ResolutionPreset, error) {
 	var selected []ResolutionPreset
 
-	for _, preset := range StandardPresets {
+	for _, preset := range presetsFor(clientCodec(ctx)) {
 		// Skip upscaling unless explicitly allowed
 		if IsUpscale(media.Width, media.Height, preset.Width, preset.Height) && (ctx == nil || !ctx.PreferUpscale) {
 			continue
@@ -22,6 +23,11 @@ func SelectResolutions(media *analyzer.MediaInfo, ctx *ClientContext) ([]Resolut
 			continue
 		}
 
+		// Skip rungs above the user's preference caps
+		if !ctx.Allows(preset) {
+			continue
+		}
+
 		// Skip if bandwidth is insufficient
 		if ctx != nil && ctx.BandwidthKbps > 0 && preset.MinBitrate > ctx.BandwidthKbps {
 			continue
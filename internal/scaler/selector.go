@@ -8,14 +8,19 @@ import (
 
 // SelectPreset chooses the best resolution preset based on source dimensions and client context.
 // Returns a ScalingDecision or fallback to default preset if no match is found.
+// Bitrate thresholds are scaled to the client's video codec (see MinBitrateFor).
 func SelectPreset(sourceWidth, sourceHeight int, ctx *ClientContext) (*ScalingDecision, error) {
-	for _, preset := range StandardPresets {
+	presets := presetsFor(clientCodec(ctx))
+	for _, preset := range presets {
 		if IsUpscale(sourceWidth, sourceHeight, preset.Width, preset.Height) && (ctx == nil || !ctx.PreferUpscale) {
 			continue
 		}
 		if ctx != nil && ctx.BandwidthKbps > 0 && preset.MinBitrate > ctx.BandwidthKbps {
 			continue
 		}
+		if !ctx.Allows(preset) {
+			continue
+		}
 		reason := fmt.Sprintf("Selected %s based on source %dx%d", preset.Label, sourceWidth, sourceHeight)
 		if ctx != nil {
 			reason += fmt.Sprintf(" and client context %+v", ctx)
@@ -23,13 +28,22 @@ func SelectPreset(sourceWidth, sourceHeight int, ctx *ClientContext) (*ScalingDe
 		return &ScalingDecision{Preset: preset, Reason: reason}, nil
 	}
 
-	for _, preset := range StandardPresets {
-		if preset.IsDefault {
+	for _, preset := range presets {
+		if preset.IsDefault && ctx.Allows(preset) {
 			return &ScalingDecision{
 				Preset: preset,
 				Reason: "No suitable preset found, falling back to default",
 			}, nil
 		}
 	}
+	// The default is above the user's caps; take the tallest preset within them
+	for _, preset := range presets {
+		if ctx.Allows(preset) {
+			return &ScalingDecision{
+				Preset: preset,
+				Reason: "No suitable preset found, falling back to the tallest within the user's caps",
+			}, nil
+		}
+	}
 	return nil, NewScalerError("SelectPreset", "no valid resolution preset found")
 }
@@ -0,0 +1,267 @@
+// Package scaler keeps per-session state for server-driven adaptive decisions.
+// This file defines the session store, its in-memory implementation, and a
+// controller that gives AdjustResolution history across requests: the rung a
+// session is on, its rolling bandwidth estimate, and the stalls seen there.
+package scaler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// ErrSessionNotFound is wrapped by SessionStore.Get errors for unknown or
+// expired sessions.
+var ErrSessionNotFound = errors.New("session not found")
+
+// DefaultSessionTTL is how long a session's state is kept after its last update.
+const DefaultSessionTTL = 30 * time.Minute
+
+// SessionState is what a SessionStore keeps for one playback session.
+type SessionState struct {
+	ID        string            `json:"id"`
+	Current   ResolutionPreset  `json:"current"`   // Rung the session was last sent to; zero before the first decision
+	Bandwidth BandwidthEstimate `json:"bandwidth"` // Rolling estimate of the session's reported bandwidth
+	Failures  int               `json:"failures"`  // Stalls reported since the session moved to Current
+	Switches  int               `json:"switches"`  // Rung changes made so far
+	Updated   time.Time         `json:"updated"`   // Time of the last change
+}
+
+// SessionStore persists session state between adaptive requests. Stores
+// expire sessions on their own (e.g. by TTL), so abandoned sessions need no
+// cleanup.
+type SessionStore interface {
+	// Get returns session id, or an error wrapping ErrSessionNotFound.
+	Get(ctx context.Context, id string) (SessionState, error)
+	// Put creates or replaces the state of session state.ID.
+	Put(ctx context.Context, state SessionState) error
+	// Delete removes session id; deleting an unknown session is not an error.
+	Delete(ctx context.Context, id string) error
+}
+
+// SessionUpdater is implemented by SessionStores that can change a session
+// atomically. SessionController uses it when its store has it, so reports
+// for one session handled by different servers don't overwrite each other.
+type SessionUpdater interface {
+	// Update calls fn on the state of session id (a fresh state with only
+	// ID set when there is none) and saves the result, with no other write
+	// to the session in between. fn may be called more than once.
+	Update(ctx context.Context, id string, fn func(*SessionState)) (SessionState, error)
+}
+
+// MemorySessionStore keeps sessions in memory, dropping each once it has
+// gone TTL without an update.
+type MemorySessionStore struct {
+	TTL time.Duration // Idle time before a session expires; DefaultSessionTTL if zero
+
+	mu       sync.Mutex
+	sessions map[string]SessionState
+	swept    time.Time // Last time expired sessions were dropped
+}
+
+// NewMemorySessionStore returns an empty in-memory store with the given
+// TTL (DefaultSessionTTL if zero).
+func NewMemorySessionStore(ttl time.Duration) *MemorySessionStore {
+	return &MemorySessionStore{TTL: ttl, sessions: make(map[string]SessionState)}
+}
+
+// Get returns session id.
+func (s *MemorySessionStore) Get(ctx context.Context, id string) (SessionState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.sessions[id]
+	if !ok || s.expired(state, time.Now()) {
+		return SessionState{}, fmt.Errorf("%w: %s", ErrSessionNotFound, id)
+	}
+	return state, nil
+}
+
+// Put stores state, dropping expired sessions at most once per TTL.
+func (s *MemorySessionStore) Put(ctx context.Context, state SessionState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.putLocked(state)
+	return nil
+}
+
+// Update applies fn to session id and stores the result.
+func (s *MemorySessionStore) Update(ctx context.Context, id string, fn func(*SessionState)) (SessionState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.sessions[id]
+	if !ok || s.expired(state, time.Now()) {
+		state = SessionState{ID: id}
+	}
+	fn(&state)
+	s.putLocked(state)
+	return state, nil
+}
+
+// putLocked stores state; s.mu must be held.
+func (s *MemorySessionStore) putLocked(state SessionState) {
+	now := time.Now()
+	if state.Updated.IsZero() {
+		state.Updated = now
+	}
+	if s.sessions == nil {
+		s.sessions = make(map[string]SessionState)
+	}
+	if now.Sub(s.swept) >= s.ttl() {
+		s.swept = now
+		for id, old := range s.sessions {
+			if s.expired(old, now) {
+				delete(s.sessions, id)
+			}
+		}
+	}
+	s.sessions[state.ID] = state
+}
+
+// Delete removes session id.
+func (s *MemorySessionStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+// expired reports whether state has gone TTL without an update.
+func (s *MemorySessionStore) expired(state SessionState, now time.Time) bool {
+	return now.Sub(state.Updated) > s.ttl()
+}
+
+// ttl returns TTL or its default.
+func (s *MemorySessionStore) ttl() time.Duration {
+	if s.TTL <= 0 {
+		return DefaultSessionTTL
+	}
+	return s.TTL
+}
+
+// SessionController makes AdjustResolution decisions for playback sessions
+// whose state lives in a SessionStore, so each request sees the session's
+// rung, bandwidth history, and stalls rather than only what it carries.
+// Requests for the same session are applied one at a time within a process;
+// across processes only if the store is a SessionUpdater.
+type SessionController struct {
+	Store    SessionStore  // Session state; a MemorySessionStore for one process, a shared store (e.g. Redis) across servers
+	Ladder   Ladder        // Rungs decisions pick from; StandardPresets when empty
+	HalfLife time.Duration // Bandwidth report half-life; DefaultBandwidthHalfLife if zero
+
+	locks [sessionLockStripes]sync.Mutex // Serialize updates by session, striped by ID hash
+}
+
+// sessionLockStripes is how many locks SessionController spreads sessions
+// over; sessions sharing a stripe wait on each other.
+const sessionLockStripes = 64
+
+// NewSessionController returns a controller over store choosing from
+// ladder (StandardPresets when empty).
+func NewSessionController(store SessionStore, ladder Ladder) *SessionController {
+	return &SessionController{Store: store, Ladder: ladder, HalfLife: DefaultBandwidthHalfLife}
+}
+
+// ReportBandwidth folds a player's bandwidth report into the session's
+// rolling estimate. A zero at means now.
+func (c *SessionController) ReportBandwidth(ctx context.Context, id string, kbps int, at time.Time) error {
+	if at.IsZero() {
+		at = time.Now()
+	}
+	return c.update(ctx, id, func(s *SessionState) {
+		s.Bandwidth.Add(kbps, at, c.HalfLife)
+	})
+}
+
+// ReportStall counts a stall or rebuffering event against the session's
+// current rung.
+func (c *SessionController) ReportStall(ctx context.Context, id string) error {
+	return c.update(ctx, id, func(s *SessionState) {
+		s.Failures++
+	})
+}
+
+// Adjust decides the session's next rung with AdjustResolution. The
+// session's stored rung replaces current once one has been decided, its
+// rolling bandwidth estimate replaces client.BandwidthKbps, and its stalls
+// on the current rung add to client.RecentFailures. Moving to another rung
+// clears the stall count.
+func (c *SessionController) Adjust(ctx context.Context, id string, current ResolutionPreset, client ClientContext) (ResolutionPreset, error) {
+	next := current
+	err := c.update(ctx, id, func(state *SessionState) {
+		from, client := current, client
+		if state.Current.Height > 0 {
+			from = state.Current
+		}
+		if kbps := state.Bandwidth.Value(); kbps > 0 {
+			client.BandwidthKbps = kbps
+		}
+		client.RecentFailures += state.Failures
+
+		if len(c.Ladder) > 0 {
+			next = c.Ladder.AdjustResolution(from, client)
+		} else {
+			next = AdjustResolution(from, client)
+		}
+		if state.Current.Height > 0 && next != state.Current {
+			state.Switches++
+			state.Failures = 0
+		}
+		state.Current = next
+	})
+	return next, err
+}
+
+// End forgets the session, e.g. when playback stops.
+func (c *SessionController) End(ctx context.Context, id string) error {
+	return c.Store.Delete(ctx, id)
+}
+
+// load returns the session's state, or a fresh one for a new session.
+func (c *SessionController) load(ctx context.Context, id string) (SessionState, error) {
+	state, err := c.Store.Get(ctx, id)
+	switch {
+	case errors.Is(err, ErrSessionNotFound):
+		return SessionState{ID: id}, nil
+	case err != nil:
+		return SessionState{}, WrapScalerError("SessionController", "failed to load session "+id, err)
+	}
+	return state, nil
+}
+
+// update applies fn to the session's state and saves it, atomically when
+// the store is a SessionUpdater.
+func (c *SessionController) update(ctx context.Context, id string, fn func(*SessionState)) error {
+	mu := c.lock(id)
+	mu.Lock()
+	defer mu.Unlock()
+	if u, ok := c.Store.(SessionUpdater); ok {
+		_, err := u.Update(ctx, id, func(state *SessionState) {
+			fn(state)
+			state.Updated = time.Now()
+		})
+		if err != nil {
+			return WrapScalerError("SessionController", "failed to update session "+id, err)
+		}
+		return nil
+	}
+	state, err := c.load(ctx, id)
+	if err != nil {
+		return err
+	}
+	fn(&state)
+	state.Updated = time.Now()
+	if err := c.Store.Put(ctx, state); err != nil {
+		return WrapScalerError("SessionController", "failed to save session "+id, err)
+	}
+	return nil
+}
+
+// lock returns the mutex serializing updates of session id.
+func (c *SessionController) lock(id string) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	return &c.locks[h.Sum32()%sessionLockStripes]
+}
@@ -0,0 +1,121 @@
+// Package scaler recommends the rung a player should start on.
+// This file ranks a title's actual ladder for a client, so a backend can
+// prime players with an initial variant that starts fast without stalling.
+package scaler
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Startup tuning. Players measure bandwidth over the first segments, so the
+// opening rung leaves headroom below the client's last estimate, and a
+// client without one starts as if bandwidth constrained.
+const (
+	StartupHeadroom        = 0.7  // Share of the estimated bandwidth the starting rung may use
+	DefaultStartupKbps     = 2500 // Bandwidth assumed when the client has no estimate
+	MobileStartupMaxHeight = 720  // Tallest starting rung on mobile devices
+)
+
+// StartupRecommendation is the rung a player should start on and the
+// alternatives in order of preference.
+type StartupRecommendation struct {
+	URI        string // Variant playlist URI of the recommended rung, relative to the master
+	Rung       Rung   // Recommended rung
+	Ranked     []Rung // Every rung, best starting choice first; Ranked[0] is Rung
+	BudgetKbps int    // Bandwidth the starting rung was chosen to fit
+	Reason     string // Explanation for the recommendation
+}
+
+// RecommendStartup parses an HLS master playlist and recommends the rung a
+// player with ctx should start on (see Ladder.RecommendStartup).
+func RecommendStartup(master []byte, ctx *ClientContext) (*StartupRecommendation, error) {
+	l, err := ParseMasterPlaylist(master)
+	if err != nil {
+		return nil, WrapScalerError("RecommendStartup", "failed to read master playlist", err)
+	}
+	return l.RecommendStartup(ctx)
+}
+
+// RecommendStartup ranks the ladder's rungs as starting choices for ctx.
+// Rungs within the startup budget (StartupHeadroom of the client's
+// bandwidth, halved after recent stalls) come first, highest bandwidth
+// first; rungs over it follow, cheapest first. Mobile clients start no
+// higher than MobileStartupMaxHeight, clients that disallow low resolutions
+// start above SD when a rung fits, rungs above the user's caps (MaxHeight,
+// MaxBitrateKbps, DataSaver) are ranked last, and a manual override that
+// names a rung in the ladder within those caps is ranked first. Codecs are
+// not considered: pass a ladder of rungs the client can decode.
+func (l Ladder) RecommendStartup(ctx *ClientContext) (*StartupRecommendation, error) {
+	if len(l) == 0 {
+		return nil, NewScalerError("Ladder.RecommendStartup", "ladder is empty")
+	}
+	var c ClientContext
+	if ctx != nil {
+		c = *ctx
+	}
+
+	bandwidth := c.BandwidthKbps
+	if bandwidth <= 0 {
+		bandwidth = DefaultStartupKbps
+	}
+	budget := int(float64(bandwidth) * StartupHeadroom)
+	// Recent stalls halve it
+	if c.RecentFailures > 0 {
+		budget /= 2
+	}
+
+	fits := func(r Rung) bool {
+		if r.Bandwidth > budget || !c.Allows(r.Preset()) {
+			return false
+		}
+		if c.IsMobile() && r.Height > MobileStartupMaxHeight {
+			return false
+		}
+		return ctx == nil || c.AllowLowRes || !r.Preset().IsSD()
+	}
+	var within, over []Rung
+	for _, r := range l {
+		if fits(r) {
+			within = append(within, r)
+		} else {
+			over = append(over, r)
+		}
+	}
+	// The ladder is ordered highest first; rungs over the budget are tried
+	// from the cheapest up, and rungs above the user's caps come last
+	slices.SortStableFunc(over, func(a, b Rung) int {
+		if ca, cb := c.Allows(a.Preset()), c.Allows(b.Preset()); ca != cb {
+			if ca {
+				return -1
+			}
+			return 1
+		}
+		return a.Bandwidth - b.Bandwidth
+	})
+	ranked := append(within, over...)
+
+	reason := fmt.Sprintf("Highest rung within %d kbps (%.0f%% of %d kbps)", budget, StartupHeadroom*100, bandwidth)
+	if len(within) == 0 {
+		reason = fmt.Sprintf("No rung fits %d kbps, starting on the cheapest", budget)
+	}
+	if c.ManualOverride != "" {
+		override := NormalizeLabel(c.ManualOverride)
+		if i := slices.IndexFunc(ranked, func(r Rung) bool { return NormalizeLabel(r.Label()) == override && c.Allows(r.Preset()) }); i >= 0 {
+			r := ranked[i]
+			ranked = append([]Rung{r}, slices.Delete(ranked, i, i+1)...)
+			reason = "Manual override " + r.Label()
+		}
+	}
+	if c.BandwidthKbps <= 0 {
+		reason += fmt.Sprintf("; no bandwidth estimate, assumed %d kbps", DefaultStartupKbps)
+	}
+
+	return &StartupRecommendation{
+		URI:        ranked[0].URI,
+		Rung:       ranked[0],
+		Ranked:     ranked,
+		BudgetKbps: budget,
+		Reason:     reason,
+	}, nil
+}
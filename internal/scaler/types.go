@@ -8,7 +8,7 @@ type ResolutionPreset struct {
 	Width      int    // Horizontal resolution in pixels (e.g. 1920)
 	Height     int    // Vertical resolution in pixels (e.g. 1080)
 	Label      string // Human-readable label (e.g. "1080p", "720p")
-	MinBitrate int    // Minimum recommended bitrate in kbps for this resolution
+	MinBitrate int    // Minimum recommended bitrate in kbps for this resolution (H.264 for StandardPresets; see MinBitrateFor)
 	IsDefault  bool   // Indicates if this preset is the default fallback
 }
 
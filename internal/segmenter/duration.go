@@ -0,0 +1,138 @@
+package segmenter
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// PlaylistDuration sums the #EXTINF durations of an HLS media playlist, the
+// duration players will see.
+func PlaylistDuration(path string) (float64, error) {
+	durations, err := SegmentDurations(path)
+	total := 0.0
+	for _, d := range durations {
+		total += d
+	}
+	return total, err
+}
+
+// SegmentDurations returns the #EXTINF duration of every segment of an HLS
+// media playlist, in order.
+func SegmentDurations(path string) ([]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var durations []float64
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		value, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "#EXTINF:")
+		if !ok {
+			continue
+		}
+		value, _, _ = strings.Cut(value, ",")
+		if d, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+			durations = append(durations, d)
+		}
+	}
+	return durations, scanner.Err()
+}
+
+// DurationStats describes how the segments of one variant playlist came out
+// against the requested segment length. Keyframes that don't land on the
+// requested boundaries stretch segments, which shows up as Max and Drift.
+type DurationStats struct {
+	Segments       int     // Number of segments
+	Total          float64 // Sum of segment durations in seconds
+	Min            float64 // Shortest segment, ignoring the last one, which is naturally short
+	Max            float64 // Longest segment
+	Mean           float64 // Mean segment duration
+	StdDev         float64 // Standard deviation of segment durations
+	Requested      int     // Segment length requested in seconds
+	TargetDuration int     // EXT-X-TARGETDURATION written, from the longest segment
+	Drift          float64 // Max minus Requested; positive when segments overrun
+	Overruns       int     // Segments more than half a second longer than Requested
+}
+
+// NewDurationStats summarizes segment durations against requested seconds.
+func NewDurationStats(durations []float64, requested int) DurationStats {
+	s := DurationStats{Segments: len(durations), Requested: requested}
+	if len(durations) == 0 {
+		return s
+	}
+	s.Min = math.Inf(1)
+	for i, d := range durations {
+		s.Total += d
+		s.Max = max(s.Max, d)
+		if i < len(durations)-1 || len(durations) == 1 {
+			s.Min = min(s.Min, d)
+		}
+		if d > float64(requested)+0.5 {
+			s.Overruns++
+		}
+		// RFC 8216: each EXTINF rounded to the nearest integer must not
+		// exceed the target duration
+		s.TargetDuration = max(s.TargetDuration, int(math.Round(d)))
+	}
+	s.TargetDuration = max(s.TargetDuration, 1)
+	s.Mean = s.Total / float64(len(durations))
+	for _, d := range durations {
+		s.StdDev += (d - s.Mean) * (d - s.Mean)
+	}
+	s.StdDev = math.Sqrt(s.StdDev / float64(len(durations)))
+	s.Drift = s.Max - float64(requested)
+	return s
+}
+
+// String renders the stats for logs (e.g. "12 segments, 3.84-6.01s (mean 4.02s), target 6s").
+func (s DurationStats) String() string {
+	return fmt.Sprintf("%d segments, %.2f-%.2fs (mean %.2fs, stddev %.2fs), target %ds", s.Segments, s.Min, s.Max, s.Mean, s.StdDev, s.TargetDuration)
+}
+
+// setTargetDuration rewrites the #EXT-X-TARGETDURATION of an HLS media
+// playlist to target, reporting whether it changed.
+func setTargetDuration(path string, target int) (bool, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	lines := strings.Split(string(raw), "\n")
+	changed := false
+	for i, line := range lines {
+		value, ok := strings.CutPrefix(strings.TrimSpace(line), "#EXT-X-TARGETDURATION:")
+		if !ok {
+			continue
+		}
+		if current, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && current == target {
+			return false, nil
+		}
+		lines[i] = fmt.Sprintf("#EXT-X-TARGETDURATION:%d", target)
+		changed = true
+		break
+	}
+	if !changed {
+		return false, nil
+	}
+	return true, os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644)
+}
+
+// measureSegments computes the duration stats of an HLS variant playlist
+// and sets its target duration from the longest segment actually written.
+func measureSegments(manifestPath string, requested int) (DurationStats, bool, error) {
+	durations, err := SegmentDurations(manifestPath)
+	if err != nil {
+		return DurationStats{}, false, err
+	}
+	stats := NewDurationStats(durations, requested)
+	if stats.Segments == 0 {
+		return stats, false, nil
+	}
+	changed, err := setTargetDuration(manifestPath, stats.TargetDuration)
+	return stats, changed, err
+}
@@ -5,22 +5,30 @@ package segmenter
 
 import (
 	"fmt"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/errclass"
+	"github.com/dotsoulja/dotgo-transcode/internal/executil"
 )
 
 // SegmenterError wraps errors that occur during segmentation.
 // Includes operation context and optional underlying error.
 type SegmenterError struct {
-	Op  string // e.g. "segment", "validate", "build_command"
-	Msg string // Human-readable summary
-	Err error  // Optional underlying error
+	Op     string // e.g. "segment", "validate", "build_command"
+	Msg    string // Human-readable summary
+	Stderr string // Tail of subprocess stderr, if available
+	Err    error  // Optional underlying error
 }
 
 // Error implements the error interface for SegmenterError
 func (e *SegmenterError) Error() string {
+	msg := fmt.Sprintf("segmenter error [%s]: %s", e.Op, e.Msg)
 	if e.Err != nil {
-		return fmt.Sprintf("segmenter error [%s]: %s: %v", e.Op, e.Msg, e.Err)
+		msg += fmt.Sprintf(": %v", e.Err)
+	}
+	if e.Stderr != "" {
+		msg += "\nStderr:\n" + e.Stderr
 	}
-	return fmt.Sprintf("segmenter error [%s]: %s", e.Op, e.Msg)
+	return msg
 }
 
 // Unwrap returns the underlying error for compatibility with errors.Is/As.
@@ -28,12 +36,28 @@ func (e *SegmenterError) Unwrap() error {
 	return e.Err
 }
 
+// Class classifies the failure by its cause (see errclass).
+func (e *SegmenterError) Class() errclass.Class {
+	if class := errclass.Classify(e.Err); class != errclass.None {
+		return class
+	}
+	return errclass.Unknown
+}
+
+// Retryable reports whether the failure was transient (e.g. ffmpeg timed out)
+// and segmentation may succeed if re-run.
+func (e *SegmenterError) Retryable() bool {
+	return e.Class().IsRetryable()
+}
+
 // NewSegmenterError creates a new SegmenterError with context
 // This is the preferred constructor for wrapping segmentation errors.
+// The stderr tail is extracted automatically when err is an executil.CommandError.
 func NewSegmenterError(op, msg string, err error) *SegmenterError {
 	return &SegmenterError{
-		Op:  op,
-		Msg: msg,
-		Err: err,
+		Op:     op,
+		Msg:    msg,
+		Stderr: executil.StderrTail(err),
+		Err:    err,
 	}
 }
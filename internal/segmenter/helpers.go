@@ -19,11 +19,13 @@ import (
 //     - outputDir: directory to write segments and manifest
 //     - manifestName: filename of the manifest (e.g. "720p.m3u8")
 //     - format: "hls" or "dash"
+//     - codec: video codec family; HEVC and AV1 use fMP4 HLS segments, which
+//       players require for those codecs
 //     - segmentLength: desired segment duration in seconds
 //     - media: optional MediaInfo for keyframe-aware alignment
 
 func buildSegmentCommand(
-	inputPath, outputDir, manifestName, format string,
+	inputPath, outputDir, manifestName, format, codec string,
 	segmentLength int, media *analyzer.MediaInfo,
 ) []string {
 	segLen := fmt.Sprintf("%d", segmentLength)
@@ -43,7 +45,17 @@ func buildSegmentCommand(
 			"-f", "hls",
 			"-hls_time", segLen,
 			"-hls_playlist_type", "vod",
-			"-hls_segment_filename", filepath.Join(outputDir, "segment_%03d.ts"),
+		}
+		if usesFMP4(codec) {
+			cmd = append(cmd,
+				"-hls_segment_type", "fmp4",
+				"-hls_segment_filename", filepath.Join(outputDir, "segment_%03d.m4s"),
+			)
+		} else {
+			cmd = append(cmd, "-hls_segment_filename", filepath.Join(outputDir, "segment_%03d.ts"))
+		}
+		if codec == "hevc" {
+			cmd = append(cmd, "-tag:v", "hvc1")
 		}
 		// Append keyframe flags if present
 		if len(forceKeyframes) > 0 {
@@ -71,6 +83,47 @@ func buildSegmentCommand(
 	}
 }
 
+// withKeyInfo inserts -hls_key_info_file before the manifest path, which is
+// always the final argument of an HLS segment command.
+func withKeyInfo(cmd []string, keyInfoFile string) []string {
+	last := len(cmd) - 1
+	out := append([]string(nil), cmd[:last]...)
+	return append(out, "-hls_key_info_file", keyInfoFile, cmd[last])
+}
+
+// withoutAudio drops audio from a segment command, for the demuxed layout
+// where the primary audio is segmented separately.
+func withoutAudio(cmd []string) []string {
+	last := len(cmd) - 1
+	out := append([]string(nil), cmd[:last]...)
+	return append(out, "-an", cmd[last])
+}
+
+// withWebMSegments makes a DASH segment command write WebM init and media
+// segments, keeping WebM variants (VP9, AV1, Opus) in the container they were
+// encoded for rather than the one ffmpeg would pick by codec.
+func withWebMSegments(cmd []string) []string {
+	last := len(cmd) - 1
+	out := append([]string(nil), cmd[:last]...)
+	return append(out,
+		"-dash_segment_type", "webm",
+		"-init_seg_name", "init-stream$RepresentationID$.webm",
+		"-media_seg_name", "chunk-stream$RepresentationID$-$Number%05d$.webm",
+		cmd[last],
+	)
+}
+
+// isWebM reports whether container is WebM.
+func isWebM(container string) bool {
+	return strings.EqualFold(container, "webm")
+}
+
+// usesFMP4 reports whether HLS segments for a codec family must be fragmented
+// MP4 rather than MPEG-TS.
+func usesFMP4(codec string) bool {
+	return codec == "hevc" || codec == "av1"
+}
+
 // manifestExtension returns the appropriate manifest file extension for a given format.
 // e.g. "hls" -> "m3u8", "dash" -> "mpd"
 func manifestExtension(format string) string {
@@ -0,0 +1,61 @@
+package segmenter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/remux"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+)
+
+// packageRunner is implemented by packagers that segment in-process rather
+// than through Commands.
+type packageRunner interface {
+	Package(ctx context.Context, job PackageJob) error
+}
+
+// nativePackager segments MP4 variants into HLS with the remux package,
+// copying samples into MPEG-TS (H.264) or fMP4 (HEVC, AV1) segments cut at
+// keyframes, so EXTINF durations are exact. It doesn't encrypt, and
+// profiles with other codecs fall back to ffmpeg.
+type nativePackager struct{}
+
+func (nativePackager) Name() string { return transcoder.PackagerNative }
+
+func (nativePackager) Supports(profile *transcoder.TranscodeProfile, format string, encrypted bool) error {
+	switch {
+	case !strings.EqualFold(format, "hls"):
+		return fmt.Errorf("the native segmenter writes HLS only, not %s", format)
+	case encrypted:
+		return fmt.Errorf("the native segmenter doesn't encrypt; use ffmpeg, Shaka Packager, or Bento4")
+	case !strings.EqualFold(profile.Container, "mp4") && !strings.EqualFold(profile.Container, "mov"):
+		return fmt.Errorf("the native segmenter reads MP4 only, not %s variants", profile.Container)
+	}
+	// Segments are fMP4 for HEVC and AV1 and MPEG-TS otherwise, which the
+	// remux package writes for H.264 and AAC only
+	video := helpers.CodecFamily(profile.VideoCodec)
+	switch {
+	case usesFMP4(video):
+	case video != "h264":
+		return fmt.Errorf("the native segmenter writes MPEG-TS segments, which carry H.264 only, not %s", profile.VideoCodec)
+	case !profile.Demuxed() && profile.AudioCodec != "" && helpers.CodecFamily(profile.AudioCodec) != "aac":
+		return fmt.Errorf("the native segmenter writes MPEG-TS segments, which carry AAC audio only, not %s", profile.AudioCodec)
+	}
+	return nil
+}
+
+func (nativePackager) EncryptionMethod(format string) string { return "" }
+
+func (nativePackager) Commands(job PackageJob) [][]string { return nil }
+
+func (nativePackager) Package(ctx context.Context, job PackageJob) error {
+	_, err := remux.SegmentFile(ctx, job.InputPath, remux.Options{
+		ManifestPath:  job.ManifestPath,
+		SegmentLength: float64(job.SegmentLength),
+		FMP4:          usesFMP4(job.Codec),
+		VideoOnly:     job.Demuxed,
+	})
+	return err
+}
@@ -0,0 +1,305 @@
+package segmenter
+
+import (
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/drm"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+)
+
+// Packager builds the commands that cut one transcoded variant into HLS or
+// DASH segments, encrypting them when the run has a key. The variant
+// manifest must end up at PackageJob.ManifestPath for the master to list.
+type Packager interface {
+	// Name returns the packager's TranscodeProfile.Packager name.
+	Name() string
+	// Supports returns nil when the packager can package the profile's
+	// variants into format, encrypted or not, else the reason it can't.
+	Supports(profile *transcoder.TranscodeProfile, format string, encrypted bool) error
+	// Commands returns the commands to run for job, in order; the last one
+	// writes the manifest. Packagers that run in-process return none.
+	Commands(job PackageJob) [][]string
+	// EncryptionMethod names how encrypted segments in format are protected
+	// (e.g. "AES-128", "SAMPLE-AES", "cenc").
+	EncryptionMethod(format string) string
+}
+
+// PackageJob is one variant to package.
+type PackageJob struct {
+	InputPath     string              // Transcoded variant file
+	OutputDir     string              // Directory receiving segments and the variant manifest
+	ManifestPath  string              // Variant manifest path
+	Format        string              // "hls" or "dash"
+	Codec         string              // Video codec family; HEVC and AV1 need fMP4 HLS segments
+	Container     string              // Variant container (e.g. "mp4", "webm"); WebM variants get WebM DASH segments
+	SegmentLength int                 // Segment duration in seconds
+	Media         *analyzer.MediaInfo // Source info for keyframe alignment, if known
+	Demuxed       bool                // Package video only; the audio stage writes the primary audio
+	Key           *drm.Key            // Content key, nil to write segments in the clear
+	KeyInfoFile   string              // ffmpeg key info file for Key (see drm.WriteKeyInfo)
+	Scratch       string              // Directory for intermediates kept out of the output (e.g. the run's workspace)
+}
+
+// intermediateWriter is implemented by packagers whose commands write files
+// besides the segments; they are removed once the commands have run.
+type intermediateWriter interface {
+	Intermediates(job PackageJob) []string
+}
+
+// scratchPath returns a path for an intermediate of job named after its
+// variant, e.g. "720p_3000kbps_fragmented.mp4", in job.Scratch or, without
+// one, job.OutputDir.
+func scratchPath(job PackageJob, suffix string) string {
+	dir := job.Scratch
+	if dir == "" {
+		dir = job.OutputDir
+	}
+	base := filepath.Base(job.InputPath)
+	return filepath.Join(dir, strings.TrimSuffix(base, filepath.Ext(base))+suffix)
+}
+
+// packagers holds every packager by name, in the order PackagerAuto tries them.
+var packagers = []Packager{shakaPackager{}, bento4Packager{}, ffmpegPackager{}, nativePackager{}}
+
+// ResolvePackager returns the packager for profile's variants in format: the
+// one the profile names, or with PackagerAuto the first installed one that
+// supports the run. A named packager that isn't installed or can't package
+// the run falls back to ffmpeg, with the reason returned for logging.
+func ResolvePackager(profile *transcoder.TranscodeProfile, format string, encrypted bool) (Packager, string) {
+	name := profile.PackagerMode()
+	for _, p := range packagers {
+		switch {
+		case name == transcoder.PackagerAuto:
+			if transcoder.PackagerAvailable(p.Name()) && p.Supports(profile, format, encrypted) == nil {
+				return p, ""
+			}
+		case p.Name() != name || name == transcoder.PackagerFFmpeg:
+		case !transcoder.PackagerAvailable(name):
+			return ffmpegPackager{}, fmt.Sprintf("%s is not installed", name)
+		default:
+			if err := p.Supports(profile, format, encrypted); err != nil {
+				return ffmpegPackager{}, err.Error()
+			}
+			return p, ""
+		}
+	}
+	return ffmpegPackager{}, ""
+}
+
+// ffmpegPackager segments with ffmpeg's HLS and DASH muxers, encrypting HLS
+// segments with AES-128.
+type ffmpegPackager struct{}
+
+func (ffmpegPackager) Name() string { return transcoder.PackagerFFmpeg }
+
+func (ffmpegPackager) Supports(profile *transcoder.TranscodeProfile, format string, encrypted bool) error {
+	switch {
+	case encrypted && !strings.EqualFold(format, "hls"):
+		return fmt.Errorf("ffmpeg only encrypts HLS, not %s; use Shaka Packager or Bento4", format)
+	case isWebM(profile.Container) && strings.EqualFold(format, "hls"):
+		return fmt.Errorf("HLS can't carry WebM segments; package webm variants as DASH")
+	}
+	return nil
+}
+
+func (ffmpegPackager) EncryptionMethod(format string) string { return "AES-128" }
+
+func (ffmpegPackager) Commands(job PackageJob) [][]string {
+	cmd := buildSegmentCommand(job.InputPath, job.OutputDir, job.ManifestPath, job.Format, job.Codec, job.SegmentLength, job.Media)
+	if job.Demuxed {
+		cmd = withoutAudio(cmd)
+	}
+	if isWebM(job.Container) && strings.EqualFold(job.Format, "dash") {
+		cmd = withWebMSegments(cmd)
+	}
+	if job.KeyInfoFile != "" {
+		cmd = withKeyInfo(cmd, job.KeyInfoFile)
+	}
+	return [][]string{cmd}
+}
+
+// shakaMaster is the master playlist Shaka Packager requires for HLS
+// output; the run's own master lists the variant playlist instead.
+const shakaMaster = "packager.m3u8"
+
+// shakaPackager segments with Shaka Packager, which writes each stream on
+// its own: HLS variants must be demuxed, and DASH variants list audio as a
+// second adaptation set. Encryption uses raw keys, SAMPLE-AES (cbcs) for HLS
+// and CENC for DASH.
+type shakaPackager struct{}
+
+func (shakaPackager) Name() string { return transcoder.PackagerShaka }
+
+func (shakaPackager) Supports(profile *transcoder.TranscodeProfile, format string, encrypted bool) error {
+	switch {
+	case isWebM(profile.Container) && strings.EqualFold(format, "hls"):
+		return fmt.Errorf("HLS can't carry WebM segments; package webm variants as DASH")
+	case strings.EqualFold(format, "hls") && !profile.Demuxed():
+		return fmt.Errorf("Shaka Packager writes audio and video as separate HLS playlists; set audio_layout to %s", transcoder.AudioLayoutDemuxed)
+	}
+	return nil
+}
+
+func (shakaPackager) EncryptionMethod(format string) string {
+	if strings.EqualFold(format, "hls") {
+		return "SAMPLE-AES"
+	}
+	return "cenc"
+}
+
+func (shakaPackager) Commands(job PackageJob) [][]string {
+	hls := strings.EqualFold(job.Format, "hls")
+	// Shaka picks the segment container from the extension
+	initExt, segExt := ".mp4", ".m4s"
+	if isWebM(job.Container) {
+		initExt, segExt = ".webm", ".webm"
+	}
+	video := []string{"in=" + job.InputPath, "stream=video"}
+	switch {
+	case hls && !usesFMP4(job.Codec):
+		video = append(video, "segment_template="+filepath.Join(job.OutputDir, "segment_$Number%03d$.ts"))
+	default:
+		video = append(video,
+			"init_segment="+filepath.Join(job.OutputDir, "init"+initExt),
+			"segment_template="+filepath.Join(job.OutputDir, "segment_$Number%03d$"+segExt),
+		)
+	}
+	if hls {
+		video = append(video, "playlist_name="+filepath.Base(job.ManifestPath))
+	}
+	cmd := []string{"packager", strings.Join(video, ",")}
+	if !hls && !job.Demuxed {
+		cmd = append(cmd, strings.Join([]string{
+			"in=" + job.InputPath,
+			"stream=audio",
+			"init_segment=" + filepath.Join(job.OutputDir, "audio_init"+initExt),
+			"segment_template=" + filepath.Join(job.OutputDir, "audio_$Number%03d$"+segExt),
+		}, ","))
+	}
+	cmd = append(cmd, "--segment_duration", strconv.Itoa(job.SegmentLength))
+	if k := job.Key; k != nil {
+		scheme := "cenc"
+		if hls {
+			scheme = "cbcs"
+		}
+		cmd = append(cmd,
+			"--enable_raw_key_encryption",
+			"--keys", fmt.Sprintf("key_id=%s:key=%s", k.KeyIDHex(), hex.EncodeToString(k.Key)),
+			"--protection_scheme", scheme,
+		)
+		if k.IV != nil {
+			cmd = append(cmd, "--iv", hex.EncodeToString(k.IV))
+		}
+		if hls {
+			cmd = append(cmd, "--hls_key_uri", k.LicenseURL)
+		}
+	}
+	if hls {
+		return [][]string{append(cmd,
+			"--hls_playlist_type", "VOD",
+			"--hls_master_playlist_output", filepath.Join(job.OutputDir, shakaMaster),
+		)}
+	}
+	return [][]string{append(cmd, "--mpd_output", job.ManifestPath)}
+}
+
+// bento4Packager segments with Bento4: mp42hls for HLS with AES-128
+// encryption, and for DASH mp4fragment followed by mp4dash with CENC
+// encryption. Bento4 reads MP4 only and keeps the variant's audio muxed.
+type bento4Packager struct{}
+
+func (bento4Packager) Name() string { return transcoder.PackagerBento4 }
+
+func (bento4Packager) Supports(profile *transcoder.TranscodeProfile, format string, encrypted bool) error {
+	switch {
+	case profile.Demuxed():
+		return fmt.Errorf("Bento4 packages the variant's audio with its video; set audio_layout to %s", transcoder.AudioLayoutMuxed)
+	case !strings.EqualFold(profile.Container, "mp4") && !strings.EqualFold(profile.Container, "mov"):
+		return fmt.Errorf("Bento4 reads MP4 only, not %s variants", profile.Container)
+	case strings.EqualFold(format, "hls") && !strings.Contains(strings.ToLower(profile.VideoCodec), "264"):
+		return fmt.Errorf("Bento4's mp42hls writes MPEG-TS segments, which carry H.264 only, not %s", profile.VideoCodec)
+	}
+	return nil
+}
+
+func (bento4Packager) EncryptionMethod(format string) string {
+	if strings.EqualFold(format, "hls") {
+		return "AES-128"
+	}
+	return "cenc"
+}
+
+func (bento4Packager) Commands(job PackageJob) [][]string {
+	if strings.EqualFold(job.Format, "hls") {
+		cmd := []string{
+			"mp42hls",
+			"--segment-duration", strconv.Itoa(job.SegmentLength),
+			"--index-filename", job.ManifestPath,
+			"--segment-filename-template", filepath.Join(job.OutputDir, "segment_%03d.ts"),
+			"--segment-url-template", "segment_%03d.ts",
+		}
+		if k := job.Key; k != nil {
+			cmd = append(cmd,
+				"--encryption-key", hex.EncodeToString(k.Key),
+				"--encryption-key-uri", k.LicenseURL,
+			)
+		}
+		return [][]string{append(cmd, job.InputPath)}
+	}
+
+	// mp4dash needs fragmented input; fragments become the segments
+	fragmented := scratchPath(job, "_fragmented.mp4")
+	fragment := []string{
+		"mp4fragment",
+		"--fragment-duration", strconv.Itoa(job.SegmentLength * 1000),
+		job.InputPath, fragmented,
+	}
+	dash := []string{
+		"mp4dash",
+		"--force",
+		"--output-dir", job.OutputDir,
+		"--mpd-name", filepath.Base(job.ManifestPath),
+		"--use-segment-timeline",
+	}
+	if k := job.Key; k != nil {
+		key := k.KeyIDHex() + ":" + hex.EncodeToString(k.Key)
+		if k.IV != nil {
+			key += ":" + hex.EncodeToString(k.IV)
+		}
+		dash = append(dash, "--encryption-key", key)
+	}
+	return [][]string{fragment, append(dash, fragmented)}
+}
+
+// Intermediates returns the fragmented MP4 mp4fragment writes for mp4dash.
+func (bento4Packager) Intermediates(job PackageJob) []string {
+	if strings.EqualFold(job.Format, "hls") {
+		return nil
+	}
+	return []string{scratchPath(job, "_fragmented.mp4")}
+}
+
+// redactKey masks the content key, and its IV, wherever they appear in a
+// command so it can be logged.
+func redactKey(cmd []string, key *drm.Key) []string {
+	if key == nil {
+		return cmd
+	}
+	secrets := []string{hex.EncodeToString(key.Key)}
+	if key.IV != nil {
+		secrets = append(secrets, hex.EncodeToString(key.IV))
+	}
+	out := make([]string, len(cmd))
+	for i, arg := range cmd {
+		for _, s := range secrets {
+			arg = strings.ReplaceAll(arg, s, "<redacted>")
+		}
+		out[i] = arg
+	}
+	return out
+}
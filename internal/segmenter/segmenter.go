@@ -4,39 +4,88 @@
 package segmenter
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 
 	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/drm"
 	"github.com/dotsoulja/dotgo-transcode/internal/executil"
 	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
 	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/layout"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
 )
 
 // SegmentMedia performs segmentation of transcoded media variants into HLS or DASH format.
-// It uses ffmpeg to slice each variant into segments, optionally aligning segment boundaries
-// to keyframes for adaptive bitrate (ABR) safety.
+// It uses ffmpeg, or the profile's packager (see ResolvePackager), to slice each variant into
+// segments, optionally aligning segment boundaries to keyframes for adaptive bitrate (ABR) safety.
 //
 // Segment length is determined by the TranscodeProfile:
 //   - If SegmentLength > 0, that value is used directly.
 //   - If SegmentLength == 0, the function falls back to the keyframe interval from MediaInfo.
 //
 // This function assumes that MediaInfo has already been extracted once upstream (e.g. in main.go)
-// and is passed in to avoid redundant analysis.
+// and is passed in to avoid redundant analysis. Progress and failures are reported
+// through logger; a nil logger falls back to the standard library log package.
 //
-// Output structure per variant:
+// Output structure per variant (default layout; see TranscodeProfile.VariantLayout):
 //
 //	media/output/<slug>/<resolution>_<bitrate>kbps/
 //	  ├── segment_000.ts
 //	  └── <resolution>_<bitrate>.m3u8
-func SegmentMedia(result *transcoder.TranscodeResult, format string, media *analyzer.MediaInfo) (*SegmentResult, error) {
+func SegmentMedia(result *transcoder.TranscodeResult, format string, media *analyzer.MediaInfo, logger stagelog.Logger) (*SegmentResult, error) {
+	return SegmentMediaWithOptions(result, format, media, logger, SegmentOptions{})
+}
+
+// SegmentOptions customizes a single SegmentMedia run.
+type SegmentOptions struct {
+	// KeyInfoFile, when set, encrypts HLS segments with AES-128 using ffmpeg's
+	// -hls_key_info_file (see drm.WriteKeyInfo). DASH output cannot be
+	// encrypted this way and is rejected.
+	KeyInfoFile string
+
+	// Key is the content key KeyInfoFile was written from. Shaka Packager and
+	// Bento4 (see TranscodeProfile.Packager) encrypt with it directly, which
+	// also covers DASH.
+	Key *drm.Key
+
+	// Scratch is the directory packagers write intermediates to, such as
+	// Bento4's fragmented MP4s; the variant's segment directory when empty.
+	// Intermediates are removed once the variant is packaged.
+	Scratch string
+}
+
+// encrypted reports whether the options ask for encrypted segments.
+func (o SegmentOptions) encrypted() bool {
+	return o.KeyInfoFile != "" || o.Key != nil
+}
+
+// SegmentMediaWithOptions is SegmentMedia with per-run options such as segment encryption.
+func SegmentMediaWithOptions(result *transcoder.TranscodeResult, format string, media *analyzer.MediaInfo, logger stagelog.Logger, opts SegmentOptions) (*SegmentResult, error) {
+	return SegmentMediaContext(context.Background(), result, format, media, logger, opts)
+}
+
+// SegmentMediaContext is SegmentMediaWithOptions with cancellation: ffmpeg
+// processes are killed when ctx is done, and paused with it (see executil.WithPauser).
+func SegmentMediaContext(ctx context.Context, result *transcoder.TranscodeResult, format string, media *analyzer.MediaInfo, logger stagelog.Logger, opts SegmentOptions) (*SegmentResult, error) {
+	logger = stagelog.OrStd(logger)
+
 	if result == nil || len(result.Variants) == 0 {
 		return nil, NewSegmenterError("validate", "no variants to segment", nil)
 	}
+	packager, reason := ResolvePackager(result.Profile, format, opts.encrypted())
+	if reason != "" {
+		logger.LogStage("segment", fmt.Sprintf("⚠️ Packaging with ffmpeg: %s", reason))
+	}
+	if opts.encrypted() {
+		if err := packager.Supports(result.Profile, format, true); err != nil {
+			return nil, NewSegmenterError("validate", fmt.Sprintf("segment encryption is not supported: %v", err), nil)
+		}
+	}
 
 	// Initialize result container
 	segResult := &SegmentResult{
@@ -44,6 +93,12 @@ func SegmentMedia(result *transcoder.TranscodeResult, format string, media *anal
 		Format:    format,
 		Success:   true,
 		Media:     media,
+		Variants:  make(map[string]transcoder.ResolutionVariant),
+		Durations: make(map[string]DurationStats),
+		Packager:  packager.Name(),
+	}
+	if opts.encrypted() {
+		segResult.EncryptionMethod = packager.EncryptionMethod(format)
 	}
 
 	var wg sync.WaitGroup
@@ -55,21 +110,11 @@ func SegmentMedia(result *transcoder.TranscodeResult, format string, media *anal
 		go func(variant transcoder.ResolutionVariant) {
 			defer wg.Done()
 
-			inputPath := filepath.Join(result.OutputDir, variant.OutputFilename)
-
-			// Normalize bitrate string (e.g. "3000k" -> "3000kbps")
-			bitrateInt := helpers.ParseBitrateKbps(variant.Bitrate)
-			bitrateLabel := "unknown"
-			if bitrateInt > 0 {
-				bitrateLabel = fmt.Sprintf("%dkbps", bitrateInt)
-			}
-
-			// Construct directory label using resolution and normalized bitrate
-			label := fmt.Sprintf("%dp_%s", variant.Height, bitrateLabel)
-			outputDir := filepath.Join(result.OutputDir, label)
+			plan := planSegment(result, variant, format, media, logger, packager, opts)
+			label := plan.Label
 
 			// Create output directory for segments
-			if err := os.MkdirAll(outputDir, os.ModePerm); err != nil {
+			if err := os.MkdirAll(plan.OutputDir, os.ModePerm); err != nil {
 				mu.Lock()
 				segResult.Success = false
 				segResult.Errors = append(segResult.Errors, NewSegmenterError(
@@ -79,26 +124,12 @@ func SegmentMedia(result *transcoder.TranscodeResult, format string, media *anal
 				return
 			}
 
-			// Determine segment length based on profile or keyframe interval
-			segmentLength := result.Profile.SegmentLength
-			if segmentLength == 0 && media != nil && media.KeyframeInterval > 0 {
-				segmentLength = int(media.KeyframeInterval + 0.5) // round up to nearest second
-				log.Printf("⏰ Using keyframe-aligned segment length: %ds for %s", segmentLength, label)
-			} else if segmentLength > 0 {
-				log.Printf("📐 Using configured segment length: %ds for %s", segmentLength, label)
-			} else {
-				log.Printf("⚠️ No segment length or keyframe data available, defaulting to 4s for %s", label)
-				segmentLength = 4
+			if opts.encrypted() {
+				logger.LogVariant(label, fmt.Sprintf("🔒 Encrypting segments with %s", plan.Packager))
 			}
-
-			// Build ffmpeg command for segmentation
-			manifestName := fmt.Sprintf("%s.%s", label, manifestExtension(format))
-			manifestPath := filepath.Join(outputDir, manifestName)
-			cmd := buildSegmentCommand(inputPath, outputDir, manifestPath, format, segmentLength, media)
-
-			log.Printf("🔪 Segmenting %s into %s format", variant.OutputFilename, format)
-			log.Printf("FFmpeg command: %s", strings.Join(cmd, " "))
-			if err := executil.RunCommand(cmd); err != nil {
+			logger.LogVariant(label, fmt.Sprintf("🔪 Segmenting %s into %s format with %s", variant.OutputFilename, format, plan.Packager))
+			if err := runPackager(ctx, packager, plan, result.Profile, logger, opts); err != nil {
+				logger.LogError("segment", err)
 				mu.Lock()
 				segResult.Success = false
 				segResult.Errors = append(segResult.Errors, NewSegmenterError(
@@ -108,9 +139,33 @@ func SegmentMedia(result *transcoder.TranscodeResult, format string, media *anal
 				return
 			}
 
+			// Measure the segments actually written; the target duration
+			// must cover the longest, whatever was requested
+			var stats *DurationStats
+			if strings.EqualFold(format, "hls") {
+				s, changed, err := measureSegments(plan.ManifestPath, plan.SegmentLength)
+				switch {
+				case err != nil:
+					logger.LogVariant(label, fmt.Sprintf("⚠️ Failed to measure segment durations: %v", err))
+				default:
+					stats = &s
+					logger.LogVariant(label, fmt.Sprintf("📏 %s", s))
+					if changed {
+						logger.LogVariant(label, fmt.Sprintf("🎯 EXT-X-TARGETDURATION set to %ds from the longest segment", s.TargetDuration))
+					}
+					if s.Overruns > 0 {
+						logger.LogVariant(label, fmt.Sprintf("⚠️ %d segment(s) overrun the requested %ds by up to %.2fs; keyframes don't land on segment boundaries", s.Overruns, s.Requested, s.Drift))
+					}
+				}
+			}
+
 			// Record manifest path
 			mu.Lock()
-			segResult.Manifests = append(segResult.Manifests, manifestPath)
+			segResult.Manifests = append(segResult.Manifests, plan.ManifestPath)
+			segResult.Variants[plan.ManifestPath] = variant
+			if stats != nil {
+				segResult.Durations[plan.ManifestPath] = *stats
+			}
 			mu.Unlock()
 		}(variant)
 	}
@@ -118,3 +173,126 @@ func SegmentMedia(result *transcoder.TranscodeResult, format string, media *anal
 	wg.Wait()
 	return segResult, nil
 }
+
+// runPackager writes plan's segments: in-process for packagers that can,
+// else by running its commands in order and then removing their
+// intermediates.
+func runPackager(ctx context.Context, packager Packager, plan PlannedSegment, profile *transcoder.TranscodeProfile, logger stagelog.Logger, opts SegmentOptions) error {
+	if runner, ok := packager.(packageRunner); ok {
+		return runner.Package(ctx, plan.job)
+	}
+	if w, ok := packager.(intermediateWriter); ok {
+		defer func() {
+			for _, path := range w.Intermediates(plan.job) {
+				if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+					logger.LogVariant(plan.Label, fmt.Sprintf("⚠️ Failed to remove %s: %v", path, err))
+				}
+			}
+		}()
+	}
+	for _, cmd := range append(plan.Prepare, plan.Command) {
+		logger.LogVariant(plan.Label, fmt.Sprintf("Packager command: %s", strings.Join(redactKey(cmd, opts.Key), " ")))
+		if err := executil.RunCommandContext(ctx, cmd, profile.CommandLimits()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SegmentLength returns the segment duration in seconds used for profile:
+// its SegmentLength, else the source keyframe interval rounded to the nearest
+// second, else 4.
+func SegmentLength(profile *transcoder.TranscodeProfile, media *analyzer.MediaInfo) int {
+	switch {
+	case profile.SegmentLength > 0:
+		return profile.SegmentLength
+	case media != nil && media.KeyframeInterval > 0:
+		return int(media.KeyframeInterval + 0.5)
+	}
+	return 4
+}
+
+// PlannedSegment describes how a single variant will be segmented.
+type PlannedSegment struct {
+	Label         string     // Variant label (e.g. "720p_3000kbps")
+	InputPath     string     // Transcoded variant file
+	OutputDir     string     // Directory receiving segments and the variant manifest
+	ManifestPath  string     // Variant manifest path
+	SegmentLength int        // Effective segment duration in seconds
+	Packager      string     // Packager that writes the segments (e.g. "ffmpeg", "shaka")
+	Prepare       [][]string // Commands run before Command (e.g. Bento4's mp4fragment)
+	Command       []string   // Command that writes the segments and variant manifest; nil when the packager runs in-process
+
+	job PackageJob // Job handed to an in-process packager
+}
+
+// PlanSegment computes the segment directory, manifest path, effective segment
+// length, and packager commands for one variant without touching the filesystem.
+//
+// Segment length is taken from the profile, falling back to the rounded keyframe
+// interval, and finally to 4 seconds when neither is available. Variants of a
+// demuxed profile are segmented video-only. Commands are those of the
+// profile's packager (see ResolvePackager), without encryption.
+func PlanSegment(result *transcoder.TranscodeResult, variant transcoder.ResolutionVariant, format string, media *analyzer.MediaInfo, logger stagelog.Logger) PlannedSegment {
+	packager, _ := ResolvePackager(result.Profile, format, false)
+	return planSegment(result, variant, format, media, logger, packager, SegmentOptions{})
+}
+
+// planSegment is PlanSegment with the packager and encryption options of a run.
+func planSegment(result *transcoder.TranscodeResult, variant transcoder.ResolutionVariant, format string, media *analyzer.MediaInfo, logger stagelog.Logger, packager Packager, opts SegmentOptions) PlannedSegment {
+	logger = stagelog.OrStd(logger)
+
+	inputPath := filepath.Join(result.OutputDir, variant.OutputFilename)
+
+	// Construct directory label using resolution and normalized bitrate;
+	// the profile's variant layout decides where the segments go
+	label := VariantLabel(variant)
+	outputDir := result.Profile.Paths().VariantDir(result.OutputDir, layout.Variant{
+		Label:   label,
+		Height:  variant.Height,
+		Bitrate: helpers.ParseBitrateKbps(variant.Bitrate),
+	})
+
+	// Determine segment length based on profile or keyframe interval
+	segmentLength := SegmentLength(result.Profile, media)
+	switch {
+	case result.Profile.SegmentLength > 0:
+		logger.LogVariant(label, fmt.Sprintf("📐 Using configured segment length: %ds", segmentLength))
+	case media != nil && media.KeyframeInterval > 0:
+		logger.LogVariant(label, fmt.Sprintf("⏰ Using keyframe-aligned segment length: %ds", segmentLength))
+	default:
+		logger.LogVariant(label, "⚠️ No segment length or keyframe data available, defaulting to 4s")
+	}
+
+	// Build ffmpeg command for segmentation
+	manifestName := fmt.Sprintf("%s.%s", label, manifestExtension(format))
+	manifestPath := filepath.Join(outputDir, manifestName)
+
+	job := PackageJob{
+		InputPath:     inputPath,
+		OutputDir:     outputDir,
+		ManifestPath:  manifestPath,
+		Format:        format,
+		Codec:         variant.Codec,
+		Container:     result.Profile.Container,
+		SegmentLength: segmentLength,
+		Media:         media,
+		Demuxed:       result.Profile.Demuxed(),
+		Key:           opts.Key,
+		KeyInfoFile:   opts.KeyInfoFile,
+		Scratch:       opts.Scratch,
+	}
+	plan := PlannedSegment{
+		Label:         label,
+		InputPath:     inputPath,
+		OutputDir:     outputDir,
+		ManifestPath:  manifestPath,
+		SegmentLength: segmentLength,
+		Packager:      packager.Name(),
+		job:           job,
+	}
+	if cmds := packager.Commands(job); len(cmds) > 0 {
+		plan.Prepare, plan.Command = cmds[:len(cmds)-1], cmds[len(cmds)-1]
+	}
+	return plan
+}
@@ -2,7 +2,10 @@
 // These structs capture manifest paths, success flags, and error metadata.
 package segmenter
 
-import "github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+import (
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+)
 
 // SegmentResult captures the outcome of a segmentaion operation.
 // Includes manifest paths, output directory, format, and error records.
@@ -14,4 +17,20 @@ type SegmentResult struct {
 	Errors    []*SegmenterError   // Detailed error records
 	Media     *analyzer.MediaInfo // Optional metadata extracted during segmentation
 
+	// Thumbnails lists scrubber thumbnails relative to OutputDir (e.g.
+	// "thumbnails/thumb_004.jpg"), filled in once thumbnails are generated.
+	Thumbnails []string
+
+	// Variants maps each manifest path to the transcoded variant it was cut
+	// from, so master manifests can report real dimensions and CODECS.
+	Variants map[string]transcoder.ResolutionVariant
+
+	// Packager is the packager that wrote the segments (e.g. "ffmpeg"), and
+	// EncryptionMethod how they are encrypted ("" when in the clear).
+	Packager         string
+	EncryptionMethod string
+
+	// Durations maps each HLS manifest path to the measured durations of its
+	// segments, for diagnosing drift from the requested segment length.
+	Durations map[string]DurationStats
 }
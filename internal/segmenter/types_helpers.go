@@ -3,7 +3,11 @@
 package segmenter
 
 import (
+	"fmt"
 	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
 )
 
 // LabelFromFilename extracts the resolution label from a variant's output filename.
@@ -20,3 +24,19 @@ func LabelFromFilename(filename string) string {
 	}
 	return "unknown"
 }
+
+// VariantLabel returns the directory and manifest label for a transcoded variant.
+// Bitrate strings are normalized (e.g. "3000k" -> "3000kbps"), producing labels
+// like "720p_3000kbps". Unparseable bitrates yield "<height>p_unknown".
+// Secondary codec tiers append the codec (e.g. "720p_1800kbps_hevc").
+func VariantLabel(variant transcoder.ResolutionVariant) string {
+	bitrateLabel := "unknown"
+	if bitrateInt := helpers.ParseBitrateKbps(variant.Bitrate); bitrateInt > 0 {
+		bitrateLabel = fmt.Sprintf("%dkbps", bitrateInt)
+	}
+	label := fmt.Sprintf("%dp_%s", variant.Height, bitrateLabel)
+	if variant.Tier != "" {
+		label += "_" + variant.Tier
+	}
+	return label
+}
@@ -0,0 +1,44 @@
+// Package tracing wraps OpenTelemetry span creation for pipeline stages.
+// Spans are emitted through the global TracerProvider, so callers wire the
+// pipeline into their observability stack with otel.SetTracerProvider.
+// When no provider is configured, every call is a cheap no-op.
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies spans produced by this module.
+const instrumentationName = "github.com/dotsoulja/dotgo-transcode"
+
+// Start opens a span named name as a child of any span carried by ctx.
+// Returns the derived context that should be passed to nested stages.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on the span (if non-nil), sets the span status, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	} else {
+		span.SetStatus(codes.Ok, "")
+	}
+	span.End()
+}
+
+// Common attribute keys shared across stages.
+var (
+	AttrSlug     = attribute.Key("dotgo.slug")
+	AttrInput    = attribute.Key("dotgo.input_path")
+	AttrFormat   = attribute.Key("dotgo.stream_format")
+	AttrTenant   = attribute.Key("dotgo.tenant")
+	AttrVariant  = attribute.Key("dotgo.variant")
+	AttrExitCode = attribute.Key("dotgo.exit_code")
+)
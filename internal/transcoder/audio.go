@@ -0,0 +1,196 @@
+package transcoder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/langtag"
+)
+
+// AudioRendition is an alternate audio track published next to the audio
+// muxed into every variant (TranscodeProfile.AudioRenditions), e.g. Dolby
+// Digital for living-room devices. Each becomes an HLS TYPE=AUDIO rendition
+// or a DASH audio adaptation set.
+type AudioRendition struct {
+	Codec       string `json:"codec" yaml:"codec"`                                 // "ac3", "eac3", or "aac"
+	Bitrate     string `json:"bitrate,omitempty" yaml:"bitrate,omitempty"`         // Encode bitrate; defaults by codec and channel count (e.g. 640k for 5.1 AC-3)
+	Channels    int    `json:"channels,omitempty" yaml:"channels,omitempty"`       // Output channels; 0 keeps the source count up to the codec's limit
+	Passthrough bool   `json:"passthrough,omitempty" yaml:"passthrough,omitempty"` // Copy the source stream when it already is this codec with a matching channel count
+	Language    string `json:"language,omitempty" yaml:"language,omitempty"`       // Source audio stream to use, by language tag; defaults to the primary audio
+	Name        string `json:"name,omitempty" yaml:"name,omitempty"`               // Name players show; defaults to language and codec (e.g. "eng Dolby Digital Plus 5.1")
+	Default     bool   `json:"default,omitempty" yaml:"default,omitempty"`         // Select this rendition when the player has no preference
+	Role        string `json:"role,omitempty" yaml:"role,omitempty"`               // "main" (default), "description" (audio description for blind and low-vision viewers), or "commentary"
+	File        string `json:"file,omitempty" yaml:"file,omitempty"`               // External audio file (e.g. an AD track or director's commentary) used instead of a source stream
+	OffsetMs    int    `json:"offset_ms,omitempty" yaml:"offset_ms,omitempty"`     // File only: positive delays it against the video, negative advances it
+}
+
+// audioRenditionCodecs holds the codecs an AudioRendition may use, with the
+// most channels each encoder accepts and a display name.
+var audioRenditionCodecs = map[string]struct {
+	maxChannels int
+	label       string
+}{
+	"ac3":  {6, "Dolby Digital"},
+	"eac3": {6, "Dolby Digital Plus"},
+	"aac":  {8, "AAC"},
+}
+
+// AudioRenditionCodecs lists the codec families accepted in AudioRendition.Codec.
+func AudioRenditionCodecs() []string {
+	return []string{"aac", "ac3", "eac3"}
+}
+
+// AudioSource returns the source stream r is made from: the first audio
+// stream tagged with r.Language, else the primary audio. Returns nil when the
+// source has no audio.
+func (r AudioRendition) AudioSource(media *analyzer.MediaInfo) *analyzer.StreamInfo {
+	if media == nil {
+		return nil
+	}
+	if r.Language != "" {
+		for _, s := range media.AudioTracks() {
+			if langtag.Equal(s.Language, r.Language) {
+				return &s
+			}
+		}
+	}
+	return media.PrimaryAudio()
+}
+
+// OutputChannels returns the channel count r is encoded with from a source
+// with the given count: r.Channels when set, else the source's, capped at
+// what the codec can carry.
+func (r AudioRendition) OutputChannels(source int) int {
+	n := r.Channels
+	if n <= 0 {
+		n = source
+	}
+	if n <= 0 {
+		n = 2
+	}
+	if limit := audioRenditionCodecs[helpers.CodecFamily(r.Codec)].maxChannels; limit > 0 && n > limit {
+		n = limit
+	}
+	return n
+}
+
+// OutputBitrate returns r.Bitrate, or the default for the codec at channels:
+// 640k/192k for 5.1/stereo E-AC-3 and AC-3 alike, 384k/128k for AAC.
+func (r AudioRendition) OutputBitrate(channels int) string {
+	if r.Bitrate != "" {
+		return r.Bitrate
+	}
+	surround := channels > 2
+	switch helpers.CodecFamily(r.Codec) {
+	case "ac3", "eac3":
+		if surround {
+			return "640k"
+		}
+		return "192k"
+	}
+	if surround {
+		return "384k"
+	}
+	return "128k"
+}
+
+// CopiesSource reports whether r passes source through unchanged: it asks
+// for passthrough, source already is r's codec, and the channel count matches.
+func (r AudioRendition) CopiesSource(source *analyzer.StreamInfo) bool {
+	if !r.Passthrough || source == nil || helpers.CodecFamily(source.Codec) != helpers.CodecFamily(r.Codec) {
+		return false
+	}
+	return r.Channels <= 0 || r.Channels == source.Channels
+}
+
+// DisplayName returns r.Name, else a name built from the language, codec,
+// and channel layout.
+func (r AudioRendition) DisplayName(language string, channels int) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	name := audioRenditionCodecs[helpers.CodecFamily(r.Codec)].label
+	if name == "" {
+		name = strings.ToUpper(r.Codec)
+	}
+	name += " " + channelLabel(channels)
+	if language != "" {
+		name = language + " " + name
+	}
+	switch r.Role {
+	case AudioRoleDescription:
+		name += " (Audio Description)"
+	case AudioRoleCommentary:
+		name += " (Commentary)"
+	}
+	return name
+}
+
+// channelLabel renders a channel count the way players list it (2.0, 5.1, 7.1).
+func channelLabel(channels int) string {
+	switch channels {
+	case 1:
+		return "1.0"
+	case 2:
+		return "2.0"
+	case 6:
+		return "5.1"
+	case 8:
+		return "7.1"
+	}
+	return fmt.Sprintf("%dch", channels)
+}
+
+// AudioCodecString returns the RFC 6381 CODECS entry for an audio codec
+// (e.g. "ec-3" for E-AC-3), or "" if unknown.
+func AudioCodecString(codec string) string {
+	return audioCodecString(codec, "")
+}
+
+// validateAudioRenditions checks the profile's audio renditions against the
+// codecs and channel counts the encoders support, and passthrough against the
+// source.
+func validateAudioRenditions(p TranscodeProfile, media *analyzer.MediaInfo, r *ValidationReport) {
+	defaults := 0
+	for i, a := range p.AudioRenditions {
+		field := fmt.Sprintf("audio_renditions[%d]", i)
+		family := helpers.CodecFamily(a.Codec)
+		codec, ok := audioRenditionCodecs[family]
+		if !ok {
+			r.add(SeverityError, field+".codec", "unsupported audio rendition codec %q (want %s)", a.Codec, strings.Join(AudioRenditionCodecs(), ", "))
+		}
+		if a.Channels < 0 {
+			r.add(SeverityError, field+".channels", "channels must be zero or positive")
+		} else if ok && a.Channels > codec.maxChannels {
+			r.add(SeverityError, field+".channels", "%s supports at most %d channels", a.Codec, codec.maxChannels)
+		}
+		if a.Bitrate != "" {
+			if !bitratePattern.MatchString(strings.TrimSpace(a.Bitrate)) {
+				r.add(SeverityError, field+".bitrate", "invalid bitrate %q; expected kbps like \"640k\"", a.Bitrate)
+			} else if helpers.ParseBitrateKbps(a.Bitrate) == 0 {
+				r.add(SeverityError, field+".bitrate", "bitrate must be greater than zero")
+			}
+		}
+		if a.Default {
+			if defaults++; defaults > 1 {
+				r.add(SeverityWarning, field+".default", "only the first default audio rendition is marked DEFAULT=YES")
+			}
+		}
+		validateAudioRole(a, field, r)
+		if media == nil || !ok || a.File != "" {
+			continue
+		}
+		source := a.AudioSource(media)
+		switch {
+		case source == nil:
+			r.add(SeverityWarning, field, "source has no audio; the rendition will be skipped")
+		case a.Language != "" && !langtag.Equal(source.Language, a.Language):
+			r.add(SeverityWarning, field+".language", "no %s audio stream in the source; using stream %d (%s)", a.Language, source.Index, orUnknown(source.Language))
+		}
+		if a.Passthrough && source != nil && !a.CopiesSource(source) {
+			r.add(SeverityWarning, field+".passthrough", "source audio is %s %dch; it will be re-encoded to %s", source.Codec, source.Channels, a.Codec)
+		}
+	}
+}
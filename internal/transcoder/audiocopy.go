@@ -0,0 +1,73 @@
+package transcoder
+
+import (
+	"fmt"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+)
+
+// Audio copy modes accepted in TranscodeProfile.AudioCopy.
+const (
+	AudioCopyAuto = "auto" // Copy source audio into a variant when it already meets the target (default)
+	AudioCopyOff  = "off"  // Always re-encode audio with audio_codec
+)
+
+// DefaultAudioBitrate is the audio target a variant's source audio must not
+// exceed to be copied when neither the variant nor the profile sets one.
+const DefaultAudioBitrate = "128k"
+
+// copyableAACProfiles are the AAC profiles players decode everywhere.
+var copyableAACProfiles = []string{"", "LC", "HE-AAC", "HE-AACv2"}
+
+// AudioCopyMode returns the profile's audio copy mode, defaulting to AudioCopyAuto.
+func (p *TranscodeProfile) AudioCopyMode() string {
+	if p.AudioCopy == "" {
+		return AudioCopyAuto
+	}
+	return p.AudioCopy
+}
+
+// variantAudioBitrate returns the audio bitrate set for v, else the
+// profile's, else "" to leave the encoder default.
+func (p *TranscodeProfile) variantAudioBitrate(v Variant) string {
+	if v.AudioBitrate != "" {
+		return v.AudioBitrate
+	}
+	return p.AudioBitrate
+}
+
+// canCopyAudio reports whether variant v can carry the source's primary audio
+// unchanged instead of re-encoding it: copy mode is auto, the source is
+// stereo (or mono) AAC-LC/HE-AAC at 44.1 or 48 kHz, its bitrate is known and
+// within the variant's audio target, and no audio filter (offset, resync)
+// has to run. The reason explains a refusal, for logging.
+func (p *TranscodeProfile) canCopyAudio(v Variant, media *analyzer.MediaInfo) (ok bool, reason string) {
+	if p.AudioCopyMode() != AudioCopyAuto || helpers.CodecFamily(p.AudioCodec) != "aac" {
+		return false, ""
+	}
+	audio := primaryAudio(media)
+	target := p.variantAudioBitrate(v)
+	if target == "" {
+		target = DefaultAudioBitrate
+	}
+	switch {
+	case audio == nil:
+		return false, ""
+	case helpers.CodecFamily(audio.Codec) != "aac":
+		return false, fmt.Sprintf("source audio is %s", audio.Codec)
+	case !contains(copyableAACProfiles, audio.Profile):
+		return false, fmt.Sprintf("source AAC profile %s is not widely supported", audio.Profile)
+	case audio.Channels > 2:
+		return false, fmt.Sprintf("source audio has %d channels", audio.Channels)
+	case audio.SampleRate != 0 && audio.SampleRate != 44100 && audio.SampleRate != 48000:
+		return false, fmt.Sprintf("source sample rate is %d Hz", audio.SampleRate)
+	case audio.Bitrate <= 0:
+		return false, "source audio bitrate unknown"
+	case audio.Bitrate > helpers.ParseBitrateKbps(target):
+		return false, fmt.Sprintf("source audio %dk exceeds target %s", audio.Bitrate, target)
+	case p.AudioFilter() != "":
+		return false, "audio filters (offset or resync) require re-encoding"
+	}
+	return true, fmt.Sprintf("source AAC %dk within target %s", audio.Bitrate, target)
+}
@@ -0,0 +1,69 @@
+package transcoder
+
+import (
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+)
+
+// Audio layouts accepted in TranscodeProfile.AudioLayout.
+const (
+	AudioLayoutMuxed   = "muxed"   // Every variant carries the primary audio (default); plays anywhere, but audio is stored once per rung
+	AudioLayoutDemuxed = "demuxed" // Variants are video-only and the primary audio is a separate audio-group rendition shared by all rungs
+)
+
+// AudioLayoutMode returns the profile's audio layout, defaulting to AudioLayoutMuxed.
+func (p *TranscodeProfile) AudioLayoutMode() string {
+	if p.AudioLayout == "" {
+		return AudioLayoutMuxed
+	}
+	return p.AudioLayout
+}
+
+// Demuxed reports whether variants are segmented without audio, the primary
+// audio being published as its own rendition instead.
+func (p *TranscodeProfile) Demuxed() bool {
+	return p.AudioLayoutMode() == AudioLayoutDemuxed
+}
+
+// MainAudioRendition returns the rendition carrying the primary audio in the
+// demuxed layout: the profile's audio codec (AAC unless it is AC-3 or E-AC-3)
+// and bitrate, selected by default. It asks for passthrough when audio_codec
+// is "copy" or the source would be copied into muxed variants.
+func (p *TranscodeProfile) MainAudioRendition(media *analyzer.MediaInfo) AudioRendition {
+	r := AudioRendition{Codec: "aac", Bitrate: p.AudioBitrate, Channels: 2, Default: true}
+	family := helpers.CodecFamily(p.AudioCodec)
+	switch family {
+	case "ac3", "eac3":
+		r.Codec, r.Channels = family, 0
+	case "copy":
+		r.Channels = 0
+	}
+	copyable, _ := p.canCopyAudio(Variant{}, media)
+	r.Passthrough = family == "copy" || copyable
+	return r
+}
+
+// OutputAudioRenditions returns every audio rendition written beside the
+// variants: the main rendition first when demuxed, then AudioRenditions.
+func (p *TranscodeProfile) OutputAudioRenditions(media *analyzer.MediaInfo) []AudioRendition {
+	if !p.Demuxed() {
+		return p.AudioRenditions
+	}
+	return append([]AudioRendition{p.MainAudioRendition(media)}, p.AudioRenditions...)
+}
+
+// validateAudioLayout checks the audio layout and, with media, that a
+// demuxed layout has source audio to publish.
+func validateAudioLayout(p TranscodeProfile, media *analyzer.MediaInfo, r *ValidationReport) {
+	switch p.AudioLayout {
+	case "":
+		r.defaulted("audio_layout", AudioLayoutMuxed)
+	case AudioLayoutMuxed:
+	case AudioLayoutDemuxed:
+		if media != nil && media.PrimaryAudio() == nil {
+			r.add(SeverityWarning, "audio_layout", "source has no audio; variants are written video-only without an audio group")
+		}
+	default:
+		r.add(SeverityError, "audio_layout", "unknown audio layout %q (want muxed or demuxed)", p.AudioLayout)
+	}
+}
@@ -0,0 +1,67 @@
+package transcoder
+
+import (
+	"os"
+	"time"
+)
+
+// Audio rendition roles accepted in AudioRendition.Role.
+const (
+	AudioRoleMain        = "main"        // The programme audio, possibly in another codec or language (default)
+	AudioRoleDescription = "description" // Audio description narrating the picture for blind and low-vision viewers
+	AudioRoleCommentary  = "commentary"  // Director's or cast commentary over the programme
+)
+
+// describesVideo is the HLS CHARACTERISTICS value marking audio description.
+const describesVideo = "public.accessibility.describes-video"
+
+// RoleOrDefault returns r.Role, or AudioRoleMain when unset.
+func (r AudioRendition) RoleOrDefault() string {
+	if r.Role == "" {
+		return AudioRoleMain
+	}
+	return r.Role
+}
+
+// Characteristics returns the HLS CHARACTERISTICS attribute for r:
+// "public.accessibility.describes-video" for audio description, so players
+// with the accessibility preference set pick it, and "" otherwise.
+func (r AudioRendition) Characteristics() string {
+	if r.Role == AudioRoleDescription {
+		return describesVideo
+	}
+	return ""
+}
+
+// FileOffset returns the alignment of r.File against the video (OffsetMs).
+func (r AudioRendition) FileOffset() time.Duration {
+	return time.Duration(r.OffsetMs) * time.Millisecond
+}
+
+// validateAudioRole checks an audio rendition's role and external file.
+func validateAudioRole(a AudioRendition, field string, r *ValidationReport) {
+	switch a.Role {
+	case "", AudioRoleMain, AudioRoleCommentary:
+	case AudioRoleDescription:
+		if a.Language == "" {
+			r.add(SeverityWarning, field+".language", "audio description without a language can't be matched to the viewer's language")
+		}
+	default:
+		r.add(SeverityError, field+".role", "unknown audio role %q (want main, description, or commentary)", a.Role)
+	}
+	if a.Default && a.RoleOrDefault() != AudioRoleMain {
+		r.add(SeverityWarning, field+".default", "a %s rendition is rarely the right default; players with an accessibility preference select audio description on their own", a.Role)
+	}
+	if a.File == "" {
+		if a.OffsetMs != 0 {
+			r.add(SeverityWarning, field+".offset_ms", "offset_ms only applies to an external file; use audio_offset_ms for the source audio")
+		}
+		return
+	}
+	if _, err := os.Stat(a.File); err != nil {
+		r.add(SeverityWarning, field+".file", "audio file not found: %s", a.File)
+	}
+	if offset := a.FileOffset(); offset > maxAudioOffset || offset < -maxAudioOffset {
+		r.add(SeverityWarning, field+".offset_ms", "offset of %s is unusually large", offset)
+	}
+}
@@ -0,0 +1,43 @@
+package transcoder
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// maxAudioOffset is the largest A/V offset ValidateProfile accepts without
+// a warning; real sync errors are rarely more than a few seconds.
+const maxAudioOffset = 10 * time.Second
+
+// AudioOffset returns the profile's A/V sync correction (AudioOffsetMs).
+// Positive values delay the audio, for sources whose audio runs early;
+// negative values advance it.
+func (p *TranscodeProfile) AudioOffset() time.Duration {
+	return time.Duration(p.AudioOffsetMs) * time.Millisecond
+}
+
+// AudioOffsetFilter returns the audio filter that shifts encoded audio by
+// offset: adelay pads the start with silence for positive offsets, atrim
+// drops the head for negative ones. Returns "" for no offset.
+func AudioOffsetFilter(offset time.Duration) string {
+	switch {
+	case offset > 0:
+		return fmt.Sprintf("adelay=%d:all=1", offset.Milliseconds())
+	case offset < 0:
+		return fmt.Sprintf("atrim=start=%s,asetpts=PTS-STARTPTS", offsetSeconds(-offset))
+	}
+	return ""
+}
+
+// AudioOffsetInput returns the arguments that open input a second time with
+// its timestamps shifted by offset (-itsoffset), for audio that is copied
+// rather than encoded and so can't be filtered. Map audio from that input.
+func AudioOffsetInput(input string, offset time.Duration) []string {
+	return []string{"-itsoffset", offsetSeconds(offset), "-i", input}
+}
+
+// offsetSeconds formats d as seconds with millisecond precision.
+func offsetSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
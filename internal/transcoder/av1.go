@@ -0,0 +1,110 @@
+package transcoder
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+)
+
+// svtAV1Encoder is ffmpeg's SVT-AV1 encoder.
+const svtAV1Encoder = "libsvtav1"
+
+// AV1Options tunes SVT-AV1 encodes (TranscodeProfile.AV1). Unset fields keep
+// the encoder defaults. When present on an AV1 profile, software encodes use
+// libsvtav1 so the settings take effect.
+type AV1Options struct {
+	Preset           *int              `json:"preset,omitempty" yaml:"preset,omitempty"`                         // Speed/efficiency trade-off, 0 (slowest, best) to 13 (fastest)
+	FilmGrain        int               `json:"film_grain,omitempty" yaml:"film_grain,omitempty"`                 // Film-grain synthesis level, 0 (off) to 50
+	FilmGrainDenoise *bool             `json:"film_grain_denoise,omitempty" yaml:"film_grain_denoise,omitempty"` // Denoise before grain synthesis (encoder default: on)
+	TileRows         int               `json:"tile_rows,omitempty" yaml:"tile_rows,omitempty"`                   // log2 of tile rows (0-6); more tiles decode faster in parallel
+	TileColumns      int               `json:"tile_columns,omitempty" yaml:"tile_columns,omitempty"`             // log2 of tile columns (0-6)
+	Params           map[string]string `json:"params,omitempty" yaml:"params,omitempty"`                         // Extra -svtav1-params key=value pairs (e.g. {"tune": "0"})
+}
+
+// args returns the ffmpeg options for libsvtav1: -preset and -svtav1-params.
+func (o *AV1Options) args() []string {
+	if o == nil {
+		return nil
+	}
+	var args []string
+	if o.Preset != nil {
+		args = append(args, "-preset", strconv.Itoa(*o.Preset))
+	}
+
+	var params []string
+	if o.FilmGrain > 0 {
+		params = append(params, "film-grain="+strconv.Itoa(o.FilmGrain))
+	}
+	if o.FilmGrainDenoise != nil {
+		params = append(params, "film-grain-denoise="+boolFlag(*o.FilmGrainDenoise))
+	}
+	if o.TileRows > 0 {
+		params = append(params, "tile-rows="+strconv.Itoa(o.TileRows))
+	}
+	if o.TileColumns > 0 {
+		params = append(params, "tile-columns="+strconv.Itoa(o.TileColumns))
+	}
+	// Sort extra params so commands are stable across runs
+	keys := make([]string, 0, len(o.Params))
+	for k := range o.Params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		params = append(params, fmt.Sprintf("%s=%s", k, o.Params[k]))
+	}
+	if len(params) > 0 {
+		args = append(args, "-svtav1-params", strings.Join(params, ":"))
+	}
+	return args
+}
+
+// validate reports out-of-range AV1 settings under the "av1" field path.
+func (o *AV1Options) validate(r *ValidationReport, videoFamily string) {
+	if o == nil {
+		return
+	}
+	if videoFamily != "" && videoFamily != "av1" {
+		r.add(SeverityWarning, "av1", "av1 settings are ignored for %s encodes", videoFamily)
+	}
+	if o.Preset != nil && (*o.Preset < 0 || *o.Preset > 13) {
+		r.add(SeverityError, "av1.preset", "preset must be between 0 and 13")
+	}
+	if o.FilmGrain < 0 || o.FilmGrain > 50 {
+		r.add(SeverityError, "av1.film_grain", "film_grain must be between 0 and 50")
+	}
+	if o.FilmGrain == 0 && o.FilmGrainDenoise != nil {
+		r.add(SeverityWarning, "av1.film_grain_denoise", "film_grain_denoise has no effect without film_grain")
+	}
+	if o.TileRows < 0 || o.TileRows > 6 {
+		r.add(SeverityError, "av1.tile_rows", "tile_rows is log2 and must be between 0 and 6")
+	}
+	if o.TileColumns < 0 || o.TileColumns > 6 {
+		r.add(SeverityError, "av1.tile_columns", "tile_columns is log2 and must be between 0 and 6")
+	}
+	for k := range o.Params {
+		if k == "" || strings.ContainsAny(k, ":=") {
+			r.add(SeverityError, "av1.params", "invalid svtav1 parameter name %q", k)
+		}
+	}
+}
+
+// av1EncoderFor returns the encoder to use when AV1 options are set on an AV1
+// software encode, or "" if the profile's codec should be used unchanged.
+func av1EncoderFor(videoCodec string, opts *AV1Options) string {
+	if opts == nil || helpers.CodecFamily(videoCodec) != "av1" || isHardwareEncoder(videoCodec) {
+		return ""
+	}
+	return svtAV1Encoder
+}
+
+// boolFlag renders b as "1" or "0".
+func boolFlag(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
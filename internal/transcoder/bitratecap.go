@@ -0,0 +1,82 @@
+package transcoder
+
+import (
+	"fmt"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+)
+
+// Source bitrate checks accepted in TranscodeProfile.SourceBitrate. A
+// variant above the source's video bitrate spends storage without adding
+// detail the source never had.
+const (
+	SourceBitrateWarn = "warn" // Log variants above the source video bitrate (default)
+	SourceBitrateCap  = "cap"  // Lower them to the source video bitrate, recorded in TranscodeResult.Adjustments
+	SourceBitrateOff  = "off"  // Don't compare against the source
+)
+
+// SourceBitrateMode returns the profile's source bitrate check, defaulting to
+// SourceBitrateWarn.
+func (p *TranscodeProfile) SourceBitrateMode() string {
+	if p.SourceBitrate == "" {
+		return SourceBitrateWarn
+	}
+	return p.SourceBitrate
+}
+
+// capBitrate checks v's target bitrate against the source video bitrate. In
+// cap mode it returns v lowered to the source bitrate along with a note
+// describing the adjustment; otherwise v is returned unchanged.
+func (p *TranscodeProfile) capBitrate(v Variant, media *analyzer.MediaInfo, logger TranscodeLogger) (Variant, string) {
+	mode := p.SourceBitrateMode()
+	if mode == SourceBitrateOff || media == nil {
+		return v, ""
+	}
+	source := media.VideoBitrate()
+	kbps := helpers.ParseBitrateKbps(v.Bitrate)
+	if source <= 0 || kbps <= source {
+		return v, ""
+	}
+	label := v.Resolution + "_" + v.Bitrate
+	if mode != SourceBitrateCap {
+		logger.LogVariant(label, fmt.Sprintf("⚠️ Target bitrate %dk exceeds the source video bitrate (%dk)", kbps, source))
+		return v, ""
+	}
+	v.Bitrate = fmt.Sprintf("%dk", source)
+	msg := fmt.Sprintf("bitrate capped from %dk to the source video bitrate %dk", kbps, source)
+	logger.LogVariant(label, "🧢 "+msg)
+	return v, label + ": " + msg
+}
+
+// validateSourceBitrate checks the source bitrate mode and, with media,
+// warns about variants above the source video bitrate.
+func validateSourceBitrate(p TranscodeProfile, media *analyzer.MediaInfo, r *ValidationReport) {
+	switch p.SourceBitrate {
+	case "":
+		r.defaulted("source_bitrate", SourceBitrateWarn)
+	case SourceBitrateWarn, SourceBitrateCap, SourceBitrateOff:
+	default:
+		r.add(SeverityError, "source_bitrate", "unknown source bitrate check %q (want warn, cap, or off)", p.SourceBitrate)
+		return
+	}
+	if media == nil || p.SourceBitrateMode() == SourceBitrateOff {
+		return
+	}
+	source := media.VideoBitrate()
+	if source <= 0 {
+		return
+	}
+	for i, v := range p.Variants {
+		kbps := helpers.ParseBitrateKbps(v.Bitrate)
+		if kbps <= source {
+			continue
+		}
+		field := fmt.Sprintf("variants[%d].bitrate", i)
+		if p.SourceBitrateMode() == SourceBitrateCap {
+			r.add(SeverityWarning, field, "%s exceeds the source video bitrate %dk; it will be capped", v.Bitrate, source)
+		} else {
+			r.add(SeverityWarning, field, "%s exceeds the source video bitrate %dk; set source_bitrate to \"cap\" to lower it", v.Bitrate, source)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package transcoder
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+)
+
+// EncodeBudget caps the CPU threads and memory shared by a profile's variant
+// encodes, which all run at once (TranscodeProfile.Budget). Without it every
+// ffmpeg sizes its thread pool to the whole machine, so a seven-rung ladder
+// oversubscribes the cores seven times over.
+type EncodeBudget struct {
+	Threads  int `json:"threads,omitempty" yaml:"threads,omitempty"`     // Total encoder threads across concurrent encodes; 0 uses every core
+	MemoryMB int `json:"memory_mb,omitempty" yaml:"memory_mb,omitempty"` // Total memory for concurrent encodes in MiB; lookahead shrinks to fit; 0 for no limit
+}
+
+// Lookahead bounds for x264/x265 (-rc-lookahead, in frames). The budget
+// shrinks lookahead no further than minLookahead; below that quality drops
+// faster than memory does.
+const (
+	defaultLookahead = 40
+	minLookahead     = 10
+)
+
+// encodeBaseMemoryMB approximates an encoder's footprint before frame buffers.
+const encodeBaseMemoryMB = 64
+
+// variantResources is the thread and lookahead allocation of one encode.
+type variantResources struct {
+	Threads   int     // -threads; 0 lets ffmpeg decide
+	Lookahead int     // x264/x265 rc-lookahead in frames; 0 keeps the encoder default
+	ReadRate  float64 // -readrate for source inputs (see ReadThrottle); 0 reads unthrottled
+}
+
+// budgetRung is a planned encode the budget is split across.
+type budgetRung struct {
+	Key     string
+	Variant Variant
+	Width   int
+	Height  int
+}
+
+// TotalThreads returns the thread budget: b.Threads, else the number of CPUs.
+func (b *EncodeBudget) TotalThreads() int {
+	if b != nil && b.Threads > 0 {
+		return b.Threads
+	}
+	return runtime.NumCPU()
+}
+
+// allocateResources assigns threads and lookahead to rungs encoded
+// concurrently. Rungs set their own threads and lookahead; the profile's
+// threads apply to the rest. With a budget, threads left after explicit
+// rungs are split in proportion to each rung's pixel count (at least one
+// each), and lookahead is lowered uniformly until the estimated memory fits.
+func (p *TranscodeProfile) allocateResources(rungs []budgetRung, logger TranscodeLogger) []variantResources {
+	out := make([]variantResources, len(rungs))
+	for i, r := range rungs {
+		out[i] = variantResources{Threads: r.Variant.Threads, Lookahead: r.Variant.Lookahead}
+		if out[i].Threads == 0 {
+			out[i].Threads = p.Threads
+		}
+	}
+	if p.Budget == nil || len(rungs) == 0 {
+		return out
+	}
+
+	// Split the threads the explicit rungs leave over by pixel count
+	total := p.Budget.TotalThreads()
+	remaining := total
+	var shared []int
+	var pixels int
+	for i, r := range rungs {
+		if r.Variant.Threads > 0 {
+			remaining -= r.Variant.Threads
+			continue
+		}
+		shared = append(shared, i)
+		pixels += r.Width * r.Height
+	}
+	if len(shared) > 0 {
+		if remaining < len(shared) {
+			logger.LogStage("transcode", fmt.Sprintf("⚠️ Thread budget of %d is too small for %d concurrent encodes; giving each one thread", total, len(rungs)))
+			remaining = len(shared)
+		}
+		for i, n := range splitByWeight(remaining, shared, rungs, pixels) {
+			out[shared[i]].Threads = n
+		}
+	}
+
+	if limit := p.Budget.MemoryMB; limit > 0 {
+		p.fitLookahead(rungs, out, limit, logger)
+	}
+
+	parts := make([]string, len(rungs))
+	for i, r := range rungs {
+		parts[i] = fmt.Sprintf("%s:%d", r.Key, out[i].Threads)
+	}
+	logger.LogStage("transcode", fmt.Sprintf("🧮 Thread budget %d across %d encodes (%s)", total, len(rungs), strings.Join(parts, ", ")))
+	return out
+}
+
+// splitByWeight divides n threads among the shared rungs in proportion to
+// their pixel counts, each getting at least one; leftover threads from
+// rounding go to the largest remainders.
+func splitByWeight(n int, shared []int, rungs []budgetRung, pixels int) []int {
+	counts := make([]int, len(shared))
+	spare := n - len(shared)
+	remainders := make([]float64, len(shared))
+	assigned := 0
+	for i, idx := range shared {
+		share := float64(spare) / float64(len(shared))
+		if pixels > 0 {
+			share = float64(spare) * float64(rungs[idx].Width*rungs[idx].Height) / float64(pixels)
+		}
+		counts[i] = 1 + int(share)
+		remainders[i] = share - float64(int(share))
+		assigned += int(share)
+	}
+	order := make([]int, len(shared))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]] > remainders[order[b]] })
+	for i := 0; i < spare-assigned && i < len(order); i++ {
+		counts[order[i]]++
+	}
+	return counts
+}
+
+// fitLookahead lowers the lookahead of rungs that don't set their own until
+// the estimated memory of all encodes fits in limitMB.
+func (p *TranscodeProfile) fitLookahead(rungs []budgetRung, res []variantResources, limitMB int, logger TranscodeLogger) {
+	estimate := func(lookahead int) int {
+		sum := 0
+		for i, r := range rungs {
+			la := res[i].Lookahead
+			if r.Variant.Lookahead == 0 {
+				la = lookahead
+			}
+			sum += EstimateEncodeMemoryMB(r.Width, r.Height, res[i].Threads, la)
+		}
+		return sum
+	}
+	if estimate(defaultLookahead) <= limitMB {
+		return
+	}
+	lookahead := defaultLookahead
+	for lookahead > minLookahead && estimate(lookahead) > limitMB {
+		lookahead -= 5
+	}
+	for i, r := range rungs {
+		if r.Variant.Lookahead == 0 && p.usesLookahead(r.Variant) {
+			res[i].Lookahead = lookahead
+		}
+	}
+	if used := estimate(lookahead); used > limitMB {
+		logger.LogStage("transcode", fmt.Sprintf("⚠️ Encodes need about %d MiB even at lookahead %d, over the %d MiB budget", used, lookahead, limitMB))
+	} else {
+		logger.LogStage("transcode", fmt.Sprintf("🧠 Lookahead lowered to %d frames to fit %d MiB (about %d MiB)", lookahead, limitMB, used))
+	}
+}
+
+// EstimateEncodeMemoryMB roughly estimates the memory of one encode at the
+// given size: a fixed base plus 8-bit 4:2:0 frame buffers for the lookahead,
+// two frames per thread, and eight reference frames.
+func EstimateEncodeMemoryMB(width, height, threads, lookahead int) int {
+	if threads <= 0 {
+		threads = runtime.NumCPU()
+	}
+	if lookahead <= 0 {
+		lookahead = defaultLookahead
+	}
+	frameBytes := width * height * 3 / 2
+	frames := lookahead + 2*threads + 8
+	return encodeBaseMemoryMB + frameBytes*frames/(1<<20)
+}
+
+// usesLookahead reports whether v is encoded with libx264 or libx265, the
+// encoders whose lookahead the budget controls.
+func (p *TranscodeProfile) usesLookahead(v Variant) bool {
+	family := helpers.CodecFamily(p.variantCodec(v))
+	return family == "h264" || family == "hevc"
+}
+
+// args returns the ffmpeg thread and lookahead options for an encode with
+// videoCodec.
+func (r variantResources) args(videoCodec string) []string {
+	var args []string
+	if r.Threads > 0 {
+		args = append(args, "-threads", strconv.Itoa(r.Threads))
+	}
+	if r.Lookahead > 0 && !isHardwareEncoder(videoCodec) {
+		switch helpers.CodecFamily(videoCodec) {
+		case "h264":
+			args = append(args, "-rc-lookahead", strconv.Itoa(r.Lookahead))
+		case "hevc":
+			args = append(args, "-x265-params", "rc-lookahead="+strconv.Itoa(r.Lookahead))
+		}
+	}
+	return args
+}
+
+// validateBudget checks the budget and the per-variant thread and lookahead
+// settings it works with.
+func validateBudget(p TranscodeProfile, r *ValidationReport) {
+	explicit := 0
+	for i, v := range p.Variants {
+		field := fmt.Sprintf("variants[%d]", i)
+		if v.Threads < 0 {
+			r.add(SeverityError, field+".threads", "threads must be zero or positive")
+		}
+		explicit += max(v.Threads, 0)
+		switch {
+		case v.Lookahead < 0:
+			r.add(SeverityError, field+".lookahead", "lookahead must be zero or positive")
+		case v.Lookahead > 250:
+			r.add(SeverityError, field+".lookahead", "lookahead must be at most 250 frames")
+		case v.Lookahead > 0 && !p.usesLookahead(v):
+			r.add(SeverityWarning, field+".lookahead", "lookahead only applies to h264 and hevc software encodes")
+		}
+	}
+
+	b := p.Budget
+	if b == nil {
+		return
+	}
+	if b.Threads < 0 {
+		r.add(SeverityError, "budget.threads", "threads must be zero or positive")
+	} else if b.Threads == 0 {
+		r.defaulted("budget.threads", "%d (every core)", runtime.NumCPU())
+	}
+	if b.MemoryMB < 0 {
+		r.add(SeverityError, "budget.memory_mb", "memory_mb must be zero or positive")
+	}
+	if total := b.TotalThreads(); explicit > total {
+		r.add(SeverityWarning, "budget.threads", "variants set %d threads between them, more than the budget of %d", explicit, total)
+	}
+}
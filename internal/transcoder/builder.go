@@ -0,0 +1,421 @@
+package transcoder
+
+import (
+	"fmt"
+	"time"
+)
+
+// ProfileBuilder constructs a TranscodeProfile fluently for backend automation,
+// where building the struct by hand is error-prone:
+//
+//	profile, err := transcoder.NewProfile("media/movie.mp4", "media/output").
+//		WithLadder(transcoder.DefaultVariants...).
+//		WithHLS(4 * time.Second).
+//		Build()
+//
+// Methods record the first invalid argument and Build reports it together with
+// the full validation result, so calls can be chained without intermediate checks.
+type ProfileBuilder struct {
+	profile TranscodeProfile
+	format  string
+	errs    []error
+}
+
+// NewProfile starts a builder for the given input file and output directory.
+func NewProfile(inputPath, outputDir string) *ProfileBuilder {
+	return &ProfileBuilder{profile: TranscodeProfile{InputPath: inputPath, OutputDir: outputDir}}
+}
+
+// FromProfile starts a builder from a copy of an existing profile (e.g. one
+// loaded from disk), so individual settings can be overridden per title.
+func FromProfile(p TranscodeProfile) *ProfileBuilder {
+	p.Variants = append([]Variant(nil), p.Variants...)
+	p.Resolutions = append([]string(nil), p.Resolutions...)
+	return &ProfileBuilder{profile: p}
+}
+
+// FromPreset starts a builder from a built-in preset (see Presets) for the
+// given input file and output directory. An unknown name is reported by Build.
+func FromPreset(name, inputPath, outputDir string) *ProfileBuilder {
+	p, err := Preset(name)
+	if err != nil {
+		b := NewProfile(inputPath, outputDir)
+		b.errs = append(b.errs, err)
+		return b
+	}
+	p.InputPath, p.OutputDir = inputPath, outputDir
+	return FromProfile(*p)
+}
+
+// WithLadder replaces the variant ladder.
+func (b *ProfileBuilder) WithLadder(variants ...Variant) *ProfileBuilder {
+	b.profile.Variants = append([]Variant(nil), variants...)
+	return b
+}
+
+// WithVariant appends a single resolution/bitrate pair (e.g. "720p", "3000k").
+func (b *ProfileBuilder) WithVariant(resolution, bitrate string) *ProfileBuilder {
+	b.profile.Variants = append(b.profile.Variants, Variant{Resolution: resolution, Bitrate: bitrate})
+	return b
+}
+
+// WithCodecTier appends a ladder encoded with another video codec (e.g. "hevc"
+// or "av1"). Its rungs are listed in the master manifest alongside the primary
+// codec's rungs, so capable players pick the efficient codec and others fall
+// back to the primary ladder.
+func (b *ProfileBuilder) WithCodecTier(codec string, variants ...Variant) *ProfileBuilder {
+	for _, v := range variants {
+		v.Codec = codec
+		b.profile.Variants = append(b.profile.Variants, v)
+	}
+	return b
+}
+
+// WithCodecs sets the video and audio codecs. An empty audio codec keeps the default ("aac").
+func (b *ProfileBuilder) WithCodecs(video, audio string) *ProfileBuilder {
+	b.profile.VideoCodec = video
+	b.profile.AudioCodec = audio
+	return b
+}
+
+// WithContainer sets the output container (e.g. "mp4").
+func (b *ProfileBuilder) WithContainer(container string) *ProfileBuilder {
+	b.profile.Container = container
+	return b
+}
+
+// WithHLS targets HLS output with the given segment duration.
+// A zero duration aligns segments to the source keyframe interval.
+func (b *ProfileBuilder) WithHLS(segment time.Duration) *ProfileBuilder {
+	b.format = StreamFormatHLS
+	b.profile.StreamFormat = StreamFormatHLS
+	return b.WithSegmentLength(segment)
+}
+
+// WithDASH targets DASH output with the given segment duration.
+// A zero duration aligns segments to the source keyframe interval.
+func (b *ProfileBuilder) WithDASH(segment time.Duration) *ProfileBuilder {
+	b.format = StreamFormatDASH
+	b.profile.StreamFormat = StreamFormatDASH
+	return b.WithSegmentLength(segment)
+}
+
+// WithSegmentLength sets the segment duration. Must be whole seconds.
+func (b *ProfileBuilder) WithSegmentLength(segment time.Duration) *ProfileBuilder {
+	if segment%time.Second != 0 || segment < 0 {
+		b.errs = append(b.errs, fmt.Errorf("segment length must be a non-negative whole number of seconds, got %s", segment))
+		return b
+	}
+	b.profile.SegmentLength = int(segment / time.Second)
+	return b
+}
+
+// WithHardwareAccel enables platform hardware acceleration where supported.
+func (b *ProfileBuilder) WithHardwareAccel(enabled bool) *ProfileBuilder {
+	b.profile.UseHardwareAccel = enabled
+	return b
+}
+
+// WithHWAccelBackend enables hardware acceleration restricted to one backend
+// (HWAccelNVENC, HWAccelQSV, HWAccelVAAPI, HWAccelAMF, HWAccelVideoToolbox, or HWAccelAuto).
+func (b *ProfileBuilder) WithHWAccelBackend(backend string) *ProfileBuilder {
+	b.profile.UseHardwareAccel = true
+	b.profile.HWAccel = backend
+	return b
+}
+
+// WithVP9 sets libvpx tuning (deadline, speed, tiles) for VP9 encodes.
+func (b *ProfileBuilder) WithVP9(opts VP9Options) *ProfileBuilder {
+	b.profile.VP9 = &opts
+	return b
+}
+
+// WithAV1 sets SVT-AV1 tuning (preset, film grain, tiles) for AV1 encodes.
+func (b *ProfileBuilder) WithAV1(opts AV1Options) *ProfileBuilder {
+	b.profile.AV1 = &opts
+	return b
+}
+
+// WithRateControl sets the encoder speed preset and peak bitrate cap.
+func (b *ProfileBuilder) WithRateControl(rc RateControl) *ProfileBuilder {
+	b.profile.RateControl = &rc
+	return b
+}
+
+// WithPreview enables the trailer stage with the given settings; zero fields
+// use the defaults (90s from 6 clips at 360p, 600k).
+func (b *ProfileBuilder) WithPreview(s PreviewSettings) *ProfileBuilder {
+	b.profile.Preview = &s
+	return b
+}
+
+// WithSubtitles sets the subtitle renditions published next to the ladder.
+func (b *ProfileBuilder) WithSubtitles(s SubtitleSettings) *ProfileBuilder {
+	b.profile.Subtitles = &s
+	return b
+}
+
+// WithForcedSubtitles sets how forced-narrative tracks are handled: "auto",
+// "rendition", "burn", or "off".
+func (b *ProfileBuilder) WithForcedSubtitles(mode string) *ProfileBuilder {
+	b.profile.ForcedSubtitles = mode
+	return b
+}
+
+// WithThumbnailSource picks where thumbnails are extracted from: one of the
+// ThumbnailSource* constants.
+func (b *ProfileBuilder) WithThumbnailSource(source string) *ProfileBuilder {
+	b.profile.ThumbnailSource = source
+	return b
+}
+
+// WithSprites enables scrubber sprite sheets with a WebVTT storyboard; zero
+// fields take the defaults.
+func (b *ProfileBuilder) WithSprites(s SpriteSettings) *ProfileBuilder {
+	b.profile.Sprites = &s
+	return b
+}
+
+// WithLowSource sets what happens when a low-resolution source leaves too
+// few rungs of the ladder; zero fields take the defaults.
+func (b *ProfileBuilder) WithLowSource(s LowSourceSettings) *ProfileBuilder {
+	b.profile.LowSource = &s
+	return b
+}
+
+// WithPartialFailure sets what happens when some variants fail to encode;
+// zero fields take the defaults.
+func (b *ProfileBuilder) WithPartialFailure(s PartialFailureSettings) *ProfileBuilder {
+	b.profile.PartialFailure = &s
+	return b
+}
+
+// WithMinimumLadder sets the rungs and labels that must be ready before the
+// master manifest is written.
+func (b *ProfileBuilder) WithMinimumLadder(m MinimumLadder) *ProfileBuilder {
+	b.profile.MinimumLadder = &m
+	return b
+}
+
+// WithStill sets the duration and framerate of the clip made from an image
+// input.
+func (b *ProfileBuilder) WithStill(s StillSettings) *ProfileBuilder {
+	b.profile.Still = &s
+	return b
+}
+
+// WithBumpers sets the slates and clips joined before and after every
+// output.
+func (b *ProfileBuilder) WithBumpers(s BumperSettings) *ProfileBuilder {
+	b.profile.Bumpers = &s
+	return b
+}
+
+// WithLanguages sets the language tags for untagged or mislabeled audio and
+// subtitle streams.
+func (b *ProfileBuilder) WithLanguages(s LanguageSettings) *ProfileBuilder {
+	b.profile.Languages = &s
+	return b
+}
+
+// WithProgressive keeps the labeled variants (all of them when none are
+// given) as faststart MP4 downloads.
+func (b *ProfileBuilder) WithProgressive(labels ...string) *ProfileBuilder {
+	b.profile.Progressive = &ProgressiveSettings{Variants: labels}
+	return b
+}
+
+// WithSourceBitrate sets how variants above the source video bitrate are
+// handled: one of the SourceBitrate* constants.
+func (b *ProfileBuilder) WithSourceBitrate(mode string) *ProfileBuilder {
+	b.profile.SourceBitrate = mode
+	return b
+}
+
+// WithLadderTrim trims rungs short clips and screencasts don't need, or caps
+// the ladder size; zero fields take the defaults.
+func (b *ProfileBuilder) WithLadderTrim(t LadderTrim) *ProfileBuilder {
+	b.profile.Trim = &t
+	return b
+}
+
+// WithAudioOffset corrects a known A/V offset: positive values delay the
+// audio, negative values advance it. Millisecond precision.
+func (b *ProfileBuilder) WithAudioOffset(offset time.Duration) *ProfileBuilder {
+	b.profile.AudioOffsetMs = int(offset.Milliseconds())
+	return b
+}
+
+// WithAudioBitrate sets the audio bitrate variants target (e.g. "128k").
+func (b *ProfileBuilder) WithAudioBitrate(bitrate string) *ProfileBuilder {
+	b.profile.AudioBitrate = bitrate
+	return b
+}
+
+// WithAudioCopy sets whether compliant source audio is copied into variants
+// (AudioCopyAuto) or always re-encoded (AudioCopyOff).
+func (b *ProfileBuilder) WithAudioCopy(mode string) *ProfileBuilder {
+	b.profile.AudioCopy = mode
+	return b
+}
+
+// WithAudioLayout sets whether variants carry the primary audio
+// (AudioLayoutMuxed) or are video-only beside an audio group (AudioLayoutDemuxed).
+func (b *ProfileBuilder) WithAudioLayout(layout string) *ProfileBuilder {
+	b.profile.AudioLayout = layout
+	return b
+}
+
+// WithPackager sets the tool that segments and encrypts variants
+// (PackagerFFmpeg, PackagerShaka, PackagerBento4, PackagerNative, or PackagerAuto).
+func (b *ProfileBuilder) WithPackager(packager string) *ProfileBuilder {
+	b.profile.Packager = packager
+	return b
+}
+
+// WithTenant namespaces the job under a tenant (catalog or library): outputs
+// go under <output_dir>/<tenant>/ and job metrics carry the tenant label.
+func (b *ProfileBuilder) WithTenant(tenant string) *ProfileBuilder {
+	b.profile.Tenant = tenant
+	return b
+}
+
+// WithReadThrottle paces ffmpeg's reads of the source, per process as a
+// multiple of realtime or as a bandwidth budget shared by the job's encodes.
+func (b *ProfileBuilder) WithReadThrottle(t ReadThrottle) *ProfileBuilder {
+	b.profile.ReadThrottle = &t
+	return b
+}
+
+// WithCheckpoint encodes long sources in chunks with a progress ledger, so a
+// crashed or preempted job resumes from the last completed chunk; zero fields
+// take the defaults.
+func (b *ProfileBuilder) WithCheckpoint(c CheckpointSettings) *ProfileBuilder {
+	b.profile.Checkpoint = &c
+	return b
+}
+
+// WithTimestampRepair regenerates timestamps and resyncs audio for damaged
+// sources, and sets how far outputs may drift from the source duration.
+func (b *ProfileBuilder) WithTimestampRepair(s TimestampSettings) *ProfileBuilder {
+	b.profile.Timestamps = &s
+	return b
+}
+
+// WithAudioRendition adds an alternate audio rendition (e.g. E-AC-3 5.1).
+// Repeatable; renditions are listed in order.
+func (b *ProfileBuilder) WithAudioRendition(r AudioRendition) *ProfileBuilder {
+	b.profile.AudioRenditions = append(b.profile.AudioRenditions, r)
+	return b
+}
+
+// WithSessionData adds an #EXT-X-SESSION-DATA entry to the HLS master.
+// Repeatable; entries are written in order.
+func (b *ProfileBuilder) WithSessionData(d SessionData) *ProfileBuilder {
+	b.profile.SessionData = append(b.profile.SessionData, d)
+	return b
+}
+
+// WithStartOffset writes #EXT-X-START into the HLS master so players begin
+// at offset (negative values count back from the end).
+func (b *ProfileBuilder) WithStartOffset(offset time.Duration, precise bool) *ProfileBuilder {
+	b.profile.Start = &StartOffset{TimeOffset: offset.Seconds(), Precise: precise}
+	return b
+}
+
+// WithHLSVersion targets players supporting at most #EXT-X-VERSION version
+// (e.g. 3 for legacy set-top boxes); features that need a later one are
+// refused.
+func (b *ProfileBuilder) WithHLSVersion(version int) *ProfileBuilder {
+	b.profile.HLSVersion = version
+	return b
+}
+
+// WithCuePoint adds an ad break, written as an ad period in the DASH master.
+// Repeatable; breaks are sorted into playback order.
+func (b *ProfileBuilder) WithCuePoint(c CuePoint) *ProfileBuilder {
+	b.profile.CuePoints = append(b.profile.CuePoints, c)
+	return b
+}
+
+// PreserveManifest merges new variants into an existing master manifest.
+func (b *ProfileBuilder) PreserveManifest() *ProfileBuilder {
+	b.profile.PreserveManifest = true
+	return b
+}
+
+// WithChecksums writes checksums.json for every output file.
+func (b *ProfileBuilder) WithChecksums() *ProfileBuilder {
+	b.profile.Checksums = true
+	return b
+}
+
+// WithTimeouts sets the per-command wall-clock limit and the no-progress limit.
+// Zero keeps the process default for that limit.
+func (b *ProfileBuilder) WithTimeouts(command, stall time.Duration) *ProfileBuilder {
+	b.profile.CommandTimeout = int((command + time.Second - 1) / time.Second)
+	b.profile.StallTimeout = int((stall + time.Second - 1) / time.Second)
+	return b
+}
+
+// WithPriority runs ffmpeg at the given niceness (1-19) and optionally in the idle I/O class.
+func (b *ProfileBuilder) WithPriority(nice int, idleIO bool) *ProfileBuilder {
+	b.profile.Nice = nice
+	b.profile.IdleIO = idleIO
+	return b
+}
+
+// WithThreads caps ffmpeg encoder threads per variant.
+func (b *ProfileBuilder) WithThreads(n int) *ProfileBuilder {
+	b.profile.Threads = n
+	return b
+}
+
+// WithBudget splits threads (0 for every core) and memoryMB (0 for no
+// limit) across the concurrent variant encodes.
+func (b *ProfileBuilder) WithBudget(threads, memoryMB int) *ProfileBuilder {
+	b.profile.Budget = &EncodeBudget{Threads: threads, MemoryMB: memoryMB}
+	return b
+}
+
+// WithLayout sets the output layout templates: the slug directory under the
+// output dir (e.g. "{date}/{slug}", layout.Flat) and each variant's segment
+// directory (e.g. "{height}p/{bitrate}k"). Empty strings keep the defaults.
+func (b *ProfileBuilder) WithLayout(slugDir, variantDir string) *ProfileBuilder {
+	b.profile.OutputLayout = slugDir
+	b.profile.VariantLayout = variantDir
+	return b
+}
+
+// Format returns the stream format selected by WithHLS or WithDASH ("" if neither was called).
+func (b *ProfileBuilder) Format() string {
+	return b.format
+}
+
+// Build applies defaults and validates the profile.
+// Unset fields default to h264 video, aac audio, mp4 container, and DefaultVariants.
+// Returns a ConfigError wrapping the first builder error or a *ValidationError
+// listing every validation problem.
+func (b *ProfileBuilder) Build() (*TranscodeProfile, error) {
+	if len(b.errs) > 0 {
+		return nil, &ConfigError{Op: "build", Path: b.profile.InputPath, Err: b.errs[0]}
+	}
+
+	p := b.profile
+	p.Variants = append([]Variant(nil), p.Variants...)
+	if p.VideoCodec == "" {
+		p.VideoCodec = "h264"
+	}
+	if p.Container == "" {
+		p.Container = "mp4"
+	}
+	if len(p.Variants) == 0 {
+		p.Variants = append(p.Variants, DefaultVariants...)
+	}
+	applyDefaults(&p)
+
+	if err := ValidateProfile(p, nil).Err(); err != nil {
+		return nil, &ConfigError{Op: "validate", Path: p.InputPath, Err: err}
+	}
+	return &p, nil
+}
@@ -0,0 +1,422 @@
+package transcoder
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/executil"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
+)
+
+// BumperSettings adds branded slates, bumpers, or rating cards before and
+// after every output (TranscodeProfile.Bumpers). Once a variant is encoded,
+// each bumper is rendered with the variant's encoder settings, conformed to
+// its resolution (letterboxed or pillarboxed to keep the aspect), framerate,
+// and audio format, and joined to it without re-encoding the main content
+// (unless a rendered bumper's codec parameters came out different).
+type BumperSettings struct {
+	PreRoll  []Bumper `json:"pre_roll,omitempty" yaml:"pre_roll,omitempty"`   // Played before the content, in order
+	PostRoll []Bumper `json:"post_roll,omitempty" yaml:"post_roll,omitempty"` // Played after the content, in order
+}
+
+// Bumper is one slate or clip in BumperSettings.
+type Bumper struct {
+	Path     string  `json:"path" yaml:"path"`                             // Image (PNG, JPEG, ...) or short video clip
+	Duration float64 `json:"duration,omitempty" yaml:"duration,omitempty"` // Seconds an image plays (default: still.duration), or a clip is cut to; 0 plays a clip in full
+}
+
+// bumperClip is a bumper probed for what rendering it needs.
+type bumperClip struct {
+	Bumper
+	Post     bool    // Post-roll rather than pre-roll
+	Seconds  float64 // How long it plays
+	HasAudio bool    // The clip has audio; otherwise silence is generated
+}
+
+// resolveBumpers probes the profile's bumpers in playing order: pre-roll,
+// then post-roll. Returns nil when there are none.
+func (p *TranscodeProfile) resolveBumpers(ctx context.Context) ([]bumperClip, error) {
+	b := p.Bumpers
+	if b == nil {
+		return nil, nil
+	}
+	still := p.StillSettings()
+	var clips []bumperClip
+	for i, bumper := range append(append([]Bumper(nil), b.PreRoll...), b.PostRoll...) {
+		opts := analyzer.AnalyzeOptions{ImageFramerate: still.Framerate, StillDuration: secondsDuration(still.Duration)}
+		if bumper.Duration > 0 {
+			opts.StillDuration = secondsDuration(bumper.Duration)
+		}
+		info, err := analyzer.AnalyzeMediaContext(ctx, bumper.Path, 1, stagelog.Nop, opts)
+		if err != nil {
+			return nil, fmt.Errorf("bumper %s: %w", bumper.Path, err)
+		}
+		if info.VideoCodec == "" {
+			return nil, fmt.Errorf("bumper %s has no video", bumper.Path)
+		}
+		clip := bumperClip{Bumper: bumper, Post: i >= len(b.PreRoll), Seconds: info.Duration, HasAudio: info.PrimaryAudio() != nil}
+		if info.Image == nil && bumper.Duration > 0 {
+			clip.Seconds = min(clip.Seconds, bumper.Duration)
+		}
+		clips = append(clips, clip)
+	}
+	return clips, nil
+}
+
+// BumperDurations returns the seconds of pre-roll and post-roll the profile
+// adds to every output, probing the bumpers.
+func (p *TranscodeProfile) BumperDurations(ctx context.Context) (pre, post float64, err error) {
+	clips, err := p.resolveBumpers(ctx)
+	pre = preRollSeconds(clips)
+	return pre, bumperSeconds(clips) - pre, err
+}
+
+// OutputDuration returns the duration of each encoded variant: the input's
+// plus any bumpers.
+func (r *TranscodeResult) OutputDuration() float64 {
+	return r.Duration + r.BumperDuration
+}
+
+// bumperSeconds returns how long clips play in total.
+func bumperSeconds(clips []bumperClip) float64 {
+	var total float64
+	for _, c := range clips {
+		total += c.Seconds
+	}
+	return total
+}
+
+// preRollSeconds returns how long the pre-roll clips play.
+func preRollSeconds(clips []bumperClip) float64 {
+	var total float64
+	for _, c := range clips {
+		if !c.Post {
+			total += c.Seconds
+		}
+	}
+	return total
+}
+
+// addBumpers renders clips to match pv's encoded output and joins them to it
+// in place. Bumpers render into a directory named after the variant in work
+// (beside the variant without one), removed once the variant is joined. The
+// pieces are joined without re-encoding when they came out with the
+// variant's codec parameters; otherwise the join re-encodes them together.
+func addBumpers(ctx context.Context, profile *TranscodeProfile, pv PlannedVariant, clips []bumperClip, work string, logger TranscodeLogger) error {
+	dir := bumperDir(pv, work)
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to clear bumper directory: %w", err)
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create bumper directory: %w", err)
+	}
+	defer func() {
+		if err := os.RemoveAll(dir); err != nil {
+			logger.LogError("bumpers", err)
+		}
+	}()
+	ext := filepath.Ext(pv.OutputPath)
+	// The content stays beside the variant: work may be on another filesystem
+	main := strings.TrimSuffix(pv.OutputPath, ext) + ".main" + ext
+	if err := os.Rename(pv.OutputPath, main); err != nil {
+		return fmt.Errorf("failed to move variant for bumpers: %w", err)
+	}
+	defer os.Remove(main)
+	probe, err := analyzer.ProbeOutput(main)
+	if err != nil {
+		return fmt.Errorf("probing encoded variant: %w", err)
+	}
+
+	var pre, post []string
+	mismatch := ""
+	for i, clip := range clips {
+		roll := "pre"
+		if clip.Post {
+			roll = "post"
+		}
+		piece := filepath.Join(dir, fmt.Sprintf("%s_%02d%s", roll, i, ext))
+		logger.LogVariant(pv.Key, fmt.Sprintf("🎬 Rendering %s-roll %s (%.2fs)", roll, filepath.Base(clip.Path), clip.Seconds))
+		cmd := bumperCommand(pv.Command, clip, probe, profile.AudioCodec, piece)
+		if err := executil.RunCommandContext(ctx, cmd, profile.CommandLimits()); err != nil {
+			return fmt.Errorf("rendering bumper %s: %w", clip.Path, err)
+		}
+		if mismatch == "" {
+			rendered, err := analyzer.ProbeOutput(piece)
+			if err != nil {
+				return fmt.Errorf("probing bumper %s: %w", clip.Path, err)
+			}
+			if d := streamMismatch(probe, rendered); d != "" {
+				mismatch = fmt.Sprintf("%s %s", filepath.Base(clip.Path), d)
+			}
+		}
+		if clip.Post {
+			post = append(post, piece)
+		} else {
+			pre = append(pre, piece)
+		}
+	}
+
+	list := filepath.Join(dir, "bumpers.txt")
+	var b strings.Builder
+	for _, piece := range append(append(pre, main), post...) {
+		fmt.Fprintf(&b, "file '%s'\n", strings.ReplaceAll(piece, "'", `'\''`))
+	}
+	if err := os.WriteFile(list, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write bumper list: %w", err)
+	}
+	join := joinCommand(pv.Command, list)
+	if mismatch != "" {
+		// Stream copy would splice parameter sets or timebases the decoder
+		// isn't expecting mid-stream
+		logger.LogVariant(pv.Key, fmt.Sprintf("⚠️ Re-encoding the join: bumper %s", mismatch))
+		join = reencodeJoinCommand(pv.Command, list, probe, profile.AudioCodec)
+	}
+	if err := executil.RunCommandContext(ctx, join, profile.CommandLimits()); err != nil {
+		return fmt.Errorf("joining bumpers: %w", err)
+	}
+	return nil
+}
+
+// bumperDir returns the directory pv's bumpers are rendered into: named
+// after the variant in work, or beside the variant without one.
+func bumperDir(pv PlannedVariant, work string) string {
+	if work == "" {
+		return pv.OutputPath + ".bumpers"
+	}
+	return filepath.Join(work, filepath.Base(pv.OutputPath)+".bumpers")
+}
+
+// streamMismatch describes how a rendered bumper differs from the variant in
+// what a stream-copy join needs to match, or returns "" when nothing does.
+func streamMismatch(want, got *analyzer.OutputProbe) string {
+	diff := func(field string, w, g any) string {
+		return fmt.Sprintf("has %s %v, the variant %v", field, g, w)
+	}
+	switch {
+	case got.VideoCodec != want.VideoCodec:
+		return diff("video codec", want.VideoCodec, got.VideoCodec)
+	case got.VideoProfile != want.VideoProfile:
+		return diff("profile", want.VideoProfile, got.VideoProfile)
+	case got.VideoLevel != want.VideoLevel:
+		return diff("level", want.VideoLevel, got.VideoLevel)
+	case got.PixelFormat != want.PixelFormat:
+		return diff("pixel format", want.PixelFormat, got.PixelFormat)
+	case got.Width != want.Width || got.Height != want.Height:
+		return diff("size", fmt.Sprintf("%dx%d", want.Width, want.Height), fmt.Sprintf("%dx%d", got.Width, got.Height))
+	case want.Framerate > 0 && math.Abs(got.Framerate-want.Framerate) > 0.01:
+		return diff("framerate", want.Framerate, got.Framerate)
+	case got.AudioCodec != want.AudioCodec:
+		return diff("audio codec", want.AudioCodec, got.AudioCodec)
+	case got.AudioProfile != want.AudioProfile:
+		return diff("audio profile", want.AudioProfile, got.AudioProfile)
+	case got.SampleRate != want.SampleRate:
+		return diff("sample rate", want.SampleRate, got.SampleRate)
+	case got.Channels != want.Channels:
+		return diff("channel count", want.Channels, got.Channels)
+	}
+	return ""
+}
+
+// bumperCommand rewrites a variant command to render clip into output,
+// conformed to the variant's probed output: scaled and padded to its
+// resolution at its framerate and pixel format, with its audio sample rate
+// and channels (silence when the clip has none). The variant's encoder
+// options are kept so the pieces can be joined without re-encoding; the
+// source's inputs, filters, and maps are replaced.
+func bumperCommand(cmd []string, clip bumperClip, probe *analyzer.OutputProbe, audioCodec, output string) []string {
+	out := globalOptions(cmd)
+
+	framerate := probe.Framerate
+	if framerate <= 0 {
+		framerate = analyzer.DefaultImageFramerate
+	}
+	rate := strconv.FormatFloat(framerate, 'f', -1, 64)
+	if flags := StillInputFlags(clip.Path, StillSettings{Duration: clip.Seconds, Framerate: framerate}); flags != nil {
+		out = append(out, flags...)
+	} else {
+		out = append(out, "-t", seconds(clip.Seconds))
+	}
+	out = append(out, "-i", clip.Path)
+	silent := probe.AudioCodec != "" && !clip.HasAudio
+	if silent {
+		out = append(out, "-f", "lavfi", "-t", seconds(clip.Seconds), "-i", "anullsrc=r=48000:cl=stereo")
+	}
+
+	vf := fmt.Sprintf("scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,setsar=1,fps=%s",
+		probe.Width, probe.Height, probe.Width, probe.Height, rate)
+	upload := uploadFilter(cmd)
+	if upload == "" && probe.PixelFormat != "" {
+		// Stills and clips would otherwise keep their own format (e.g. yuvj420p)
+		vf += ",format=" + probe.PixelFormat
+	}
+	out = append(out, "-vf", vf+upload, "-map", "0:v:0")
+	switch {
+	case probe.AudioCodec == "":
+		out = append(out, "-an")
+	case silent:
+		out = append(out, "-map", "1:a:0")
+	default:
+		out = append(out, "-map", "0:a:0")
+	}
+	out = append(out, encoderOptions(cmd, probe, audioCodec)...)
+	return append(out, output)
+}
+
+// reencodeJoinCommand concatenates the pieces listed in list into the
+// variant command's output, encoding them with the variant's options. It is
+// the fallback when the pieces can't be joined as they are.
+func reencodeJoinCommand(cmd []string, list string, probe *analyzer.OutputProbe, audioCodec string) []string {
+	out := append(globalOptions(cmd), "-f", "concat", "-safe", "0", "-i", list)
+	if upload := uploadFilter(cmd); upload != "" {
+		out = append(out, "-vf", strings.TrimPrefix(upload, ","))
+	}
+	out = append(out, "-map", "0:v:0")
+	if probe.AudioCodec != "" {
+		out = append(out, "-map", "0:a:0")
+	}
+	out = append(out, encoderOptions(cmd, probe, audioCodec)...)
+	if v := argAfter(cmd, "-movflags"); v != "" {
+		out = append(out, "-movflags", v)
+	}
+	return append(out, cmd[len(cmd)-1])
+}
+
+// globalOptions returns the start of a new command from a variant command:
+// the binary, -y, and the variant's global and hardware options, without
+// the options it applies to the source input.
+func globalOptions(cmd []string) []string {
+	first := slices.Index(cmd, "-i")
+	out := []string{cmd[0], "-y"}
+	for i := 1; i < first; i++ {
+		if isSourceInputOption(cmd[i]) && i+1 < first {
+			i++
+			continue
+		}
+		out = append(out, cmd[i])
+	}
+	return out
+}
+
+// uploadFilter returns the ",format=...,hwupload" tail of the variant's
+// video filter that uploading backends (VA-API) need, or "" for others.
+func uploadFilter(cmd []string) string {
+	f := argAfter(cmd, "-vf") + argAfter(cmd, "-filter_complex")
+	if !strings.Contains(f, "hwupload") {
+		return ""
+	}
+	if i := strings.LastIndex(f, ",format="); i >= 0 {
+		return strings.TrimSuffix(f[i:], "[v]")
+	}
+	return ""
+}
+
+// encoderOptions returns the variant command's output options without its
+// filters, maps, and -movflags (the join applies those to the finished
+// file), copied audio replaced by an encode to the same codec, and the
+// probed audio sample rate and channels pinned.
+func encoderOptions(cmd []string, probe *analyzer.OutputProbe, audioCodec string) []string {
+	last := 0
+	for i, arg := range cmd {
+		if arg == "-i" {
+			last = i
+		}
+	}
+	var out []string
+	args := cmd[last+2 : len(cmd)-1]
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case (arg == "-vf" || arg == "-filter_complex" || arg == "-map" || arg == "-af" || arg == "-movflags") && i+1 < len(args):
+			i++
+		case arg == "-c:a" && i+1 < len(args) && args[i+1] == "copy":
+			encoder := audioCodec
+			if encoder == "" || encoder == "copy" {
+				encoder = probe.AudioCodec
+			}
+			out = append(out, arg, softwareEncoder(encoder))
+			i++
+		default:
+			out = append(out, arg)
+		}
+	}
+	if probe.AudioCodec != "" {
+		if probe.SampleRate > 0 {
+			out = append(out, "-ar", strconv.Itoa(probe.SampleRate))
+		}
+		if probe.Channels > 0 {
+			out = append(out, "-ac", strconv.Itoa(probe.Channels))
+		}
+	}
+	return out
+}
+
+// isSourceInputOption reports whether arg is an input option the variant
+// command applies to the source (see InputFlags and ReadFlags), taking a
+// value.
+func isSourceInputOption(arg string) bool {
+	switch arg {
+	case "-fflags", "-readrate", "-loop", "-framerate", "-t", "-start_number", "-itsoffset":
+		return true
+	}
+	return false
+}
+
+// joinCommand concatenates the pieces listed in list into the variant
+// command's output without re-encoding, keeping the variant's muxer flags.
+func joinCommand(cmd []string, list string) []string {
+	out := []string{cmd[0], "-y", "-f", "concat", "-safe", "0", "-i", list, "-map", "0", "-c", "copy"}
+	for _, flag := range []string{"-movflags", "-tag:v"} {
+		if v := argAfter(cmd, flag); v != "" {
+			out = append(out, flag, v)
+		}
+	}
+	return append(out, cmd[len(cmd)-1])
+}
+
+// secondsDuration converts seconds to a time.Duration.
+func secondsDuration(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// validateBumpers checks the bumper settings.
+func validateBumpers(p TranscodeProfile, r *ValidationReport) {
+	b := p.Bumpers
+	if b == nil {
+		return
+	}
+	if len(b.PreRoll) == 0 && len(b.PostRoll) == 0 {
+		r.add(SeverityWarning, "bumpers", "neither pre_roll nor post_roll is set")
+		return
+	}
+	check := func(roll string, bumpers []Bumper) {
+		for i, bumper := range bumpers {
+			field := fmt.Sprintf("bumpers.%s[%d]", roll, i)
+			if bumper.Path == "" {
+				r.add(SeverityError, field+".path", "missing path")
+			} else if _, err := os.Stat(bumper.Path); err != nil {
+				r.add(SeverityWarning, field+".path", "bumper file not found: %s", bumper.Path)
+			}
+			if bumper.Duration < 0 {
+				r.add(SeverityError, field+".duration", "duration must be zero or positive")
+			}
+		}
+	}
+	check("pre_roll", b.PreRoll)
+	check("post_roll", b.PostRoll)
+
+	if p.Demuxed() {
+		r.add(SeverityError, "bumpers", "bumpers need the muxed audio layout; a separate audio rendition would play against the wrong video")
+	} else if len(p.AudioRenditions) > 0 {
+		r.add(SeverityWarning, "bumpers", "alternate audio renditions don't include the bumpers and will be out of sync")
+	}
+	if len(b.PreRoll) > 0 && (p.Subtitles != nil || len(p.CuePoints) > 0) {
+		r.add(SeverityWarning, "bumpers.pre_roll", "pre-roll delays the content; subtitle and cue point times are not shifted to match")
+	}
+}
@@ -0,0 +1,342 @@
+package transcoder
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/executil"
+)
+
+// Checkpoint defaults, used when the CheckpointSettings fields are zero.
+const (
+	DefaultCheckpointChunk       = 600  // Seconds of source per chunk
+	DefaultCheckpointMinDuration = 1800 // Sources shorter than this are encoded in one pass
+)
+
+// CheckpointSettings splits long encodes into chunks so a crashed or
+// preempted job resumes from the last completed chunk instead of starting
+// over (TranscodeProfile.Checkpoint). Each variant's video is encoded chunk
+// by chunk into a "<output>.chunks" directory in the run's scratch directory
+// (TranscodeOptions.Scratch), with a ledger of the finished chunks; once all
+// are done they are joined without re-encoding, the audio is added in a
+// single pass, and the directory is removed. Encodes burning in text
+// subtitles run in one pass, since the subtitles filter can't follow the
+// seek into each chunk.
+type CheckpointSettings struct {
+	ChunkSeconds int `json:"chunk_seconds,omitempty" yaml:"chunk_seconds,omitempty"` // Source seconds per chunk, rounded up to whole segments (default 600)
+	MinDuration  int `json:"min_duration,omitempty" yaml:"min_duration,omitempty"`   // Only checkpoint sources at least this many seconds long (default 1800)
+}
+
+// checkpointChunk returns the chunk length in seconds for a source of
+// duration, or 0 when its encodes run in one pass. Chunks are whole
+// multiples of the segment length, so chunk boundaries (which always start
+// on a keyframe) fall on segment cuts.
+func (p *TranscodeProfile) checkpointChunk(duration float64) float64 {
+	c := p.Checkpoint
+	if c == nil {
+		return 0
+	}
+	minDuration := c.MinDuration
+	if minDuration <= 0 {
+		minDuration = DefaultCheckpointMinDuration
+	}
+	chunk := c.ChunkSeconds
+	if chunk <= 0 {
+		chunk = DefaultCheckpointChunk
+	}
+	if seg := p.SegmentLength; seg > 0 {
+		chunk = (chunk + seg - 1) / seg * seg
+	}
+	if duration < float64(minDuration) || duration <= float64(chunk) {
+		return 0
+	}
+	return float64(chunk)
+}
+
+// validateCheckpoint checks the checkpoint settings.
+func validateCheckpoint(p TranscodeProfile, r *ValidationReport) {
+	c := p.Checkpoint
+	if c == nil {
+		return
+	}
+	switch {
+	case c.ChunkSeconds < 0:
+		r.add(SeverityError, "checkpoint.chunk_seconds", "chunk_seconds must be zero or positive")
+	case c.ChunkSeconds == 0:
+		r.defaulted("checkpoint.chunk_seconds", "%d", DefaultCheckpointChunk)
+	case c.ChunkSeconds < 60:
+		r.add(SeverityWarning, "checkpoint.chunk_seconds", "%ds chunks restart the encoder often; rate control and lookahead work best on chunks of a few minutes", c.ChunkSeconds)
+	}
+	switch {
+	case c.MinDuration < 0:
+		r.add(SeverityError, "checkpoint.min_duration", "min_duration must be zero or positive")
+	case c.MinDuration == 0:
+		r.defaulted("checkpoint.min_duration", "%ds", DefaultCheckpointMinDuration)
+	}
+}
+
+// encodeVariant runs a planned encode, chunked with checkpoints in
+// opts.Scratch when the profile asks for it and the source is long enough,
+// otherwise in one pass, then adds any bumpers in opts.Workdir. onProgress
+// receives the overall percentage either way.
+func encodeVariant(ctx context.Context, profile *TranscodeProfile, pv PlannedVariant, media *analyzer.MediaInfo, bumpers []bumperClip, opts TranscodeOptions, logger TranscodeLogger, onProgress func(percent float64)) error {
+	duration := media.Duration
+	chunk := profile.checkpointChunk(duration)
+	if burn := profile.BurnedSubtitle(media); chunk > 0 && burn != nil && burn.IsTextSubtitle() {
+		// Each chunk seeks the input, but the subtitles filter reads the
+		// subtitle file from its start, so every chunk would show the
+		// opening cues
+		logger.LogVariant(pv.Key, "⚠️ Encoding in one pass without checkpoints: burned-in text subtitles can't follow chunk seeks")
+		chunk = 0
+	}
+	var err error
+	if chunk == 0 {
+		err = executil.RunCommandWithProgressContext(ctx, pv.Command, duration, profile.CommandLimits(), onProgress)
+	} else {
+		err = encodeChunked(ctx, profile, pv, duration, chunk, chunkDir(pv, opts.Scratch), logger, onProgress)
+	}
+	if err != nil || len(bumpers) == 0 {
+		return err
+	}
+	return addBumpers(ctx, profile, pv, bumpers, opts.Workdir, logger)
+}
+
+// chunkLedger records the chunks of a checkpointed encode that finished.
+// It is rewritten after every chunk, so it never lists a partial one.
+type chunkLedger struct {
+	Command   string  `json:"command"`       // Fingerprint of the variant's ffmpeg command; a mismatch discards the ledger
+	Chunk     float64 `json:"chunk_seconds"` // Chunk length the ledger was started with
+	Completed []int   `json:"completed"`     // Indexes of finished chunks
+}
+
+// ledgerFile is the chunk ledger's name inside a chunk directory.
+const ledgerFile = "ledger.json"
+
+// chunkDir returns the directory pv's chunks are encoded into: named after
+// the variant in scratch, or beside the variant without one.
+func chunkDir(pv PlannedVariant, scratch string) string {
+	if scratch == "" {
+		return pv.OutputPath + ".chunks"
+	}
+	return filepath.Join(scratch, filepath.Base(pv.OutputPath)+".chunks")
+}
+
+// encodeChunked encodes pv's video in chunk-second pieces into dir, skipping
+// pieces an earlier attempt finished, then joins them and muxes in the audio.
+func encodeChunked(ctx context.Context, profile *TranscodeProfile, pv PlannedVariant, duration, chunk float64, dir string, logger TranscodeLogger, onProgress func(percent float64)) error {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create chunk directory: %w", err)
+	}
+
+	fingerprint := commandFingerprint(pv.Command)
+	ledger := loadLedger(filepath.Join(dir, ledgerFile))
+	if ledger.Command != fingerprint || ledger.Chunk != chunk {
+		if len(ledger.Completed) > 0 {
+			logger.LogVariant(pv.Key, "♻️ Encode settings changed; discarding checkpointed chunks")
+		}
+		ledger = chunkLedger{Command: fingerprint, Chunk: chunk}
+	}
+
+	ext := filepath.Ext(pv.OutputPath)
+	count := int(math.Ceil(duration / chunk))
+	names := make([]string, count)
+	for i := range names {
+		names[i] = fmt.Sprintf("chunk_%04d%s", i, ext)
+	}
+	if resumed := len(ledger.Completed); resumed > 0 {
+		logger.LogVariant(pv.Key, fmt.Sprintf("⏯️ Resuming from checkpoint: %d/%d chunks already encoded", resumed, count))
+	} else {
+		logger.LogVariant(pv.Key, fmt.Sprintf("🧩 Encoding in %d checkpointed chunks of %.0fs", count, chunk))
+	}
+
+	// The final mux only copies video and encodes audio, so it gets a small
+	// fixed slice of the progress bar
+	const muxShare = 0.05
+	report := func(done, percent float64) {
+		onProgress(min(done+percent/100*(1-muxShare), 1) * 100)
+	}
+	limits := profile.CommandLimits()
+	for i, name := range names {
+		start := float64(i) * chunk
+		length := min(chunk, duration-start)
+		done := start / duration
+		path := filepath.Join(dir, name)
+		if slices.Contains(ledger.Completed, i) {
+			if _, err := os.Stat(path); err == nil {
+				report(done+length/duration, 0)
+				continue
+			}
+			ledger.Completed = slices.DeleteFunc(ledger.Completed, func(n int) bool { return n == i })
+		}
+
+		cmd := chunkCommand(pv.Command, start, length, path)
+		err := executil.RunCommandWithProgressContext(ctx, cmd, length, limits, func(percent float64) {
+			report(done, percent*length/duration)
+		})
+		if err != nil {
+			return fmt.Errorf("chunk %d/%d: %w", i+1, count, err)
+		}
+		ledger.Completed = append(ledger.Completed, i)
+		if err := saveLedger(filepath.Join(dir, ledgerFile), ledger); err != nil {
+			logger.LogError("checkpoint", err)
+		}
+	}
+
+	list := filepath.Join(dir, "chunks.txt")
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "file '%s'\n", name)
+	}
+	if err := os.WriteFile(list, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write chunk list: %w", err)
+	}
+	err := executil.RunCommandWithProgressContext(ctx, muxCommand(pv.Command, list), duration, limits, func(percent float64) {
+		onProgress((1 - muxShare + percent/100*muxShare) * 100)
+	})
+	if err != nil {
+		return fmt.Errorf("joining chunks: %w", err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		logger.LogError("checkpoint", err)
+	}
+	return nil
+}
+
+// chunkCommand rewrites a variant command to encode length seconds of video
+// from start into output: the first input is seeked and cut, and audio maps
+// and options are dropped, since audio is added when the chunks are joined.
+func chunkCommand(cmd []string, start, length float64, output string) []string {
+	out := []string{cmd[0], "-y"}
+	seeked := false
+	args := cmd[1 : len(cmd)-1]
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-i" && !seeked:
+			out = append(out, "-ss", seconds(start), "-t", seconds(length), arg)
+			seeked = true
+		case arg == "-map" && i+1 < len(args) && !isVideoMap(args[i+1]):
+			i++
+		case isAudioOption(arg) && i+1 < len(args):
+			i++
+		case arg == "-movflags" && i+1 < len(args):
+			// Chunks are read once by the mux, so relocating their moov is wasted work
+			i++
+		default:
+			out = append(out, arg)
+		}
+	}
+	return append(out, "-an", output)
+}
+
+// muxCommand joins the chunks listed in list into the command's output,
+// copying their video and taking the audio from the command's own inputs
+// with its own audio maps and options, and keeping its muxer flags. The
+// chunk list is added as the last input so the command's input indexes stay
+// valid.
+func muxCommand(cmd []string, list string) []string {
+	lastInput := 0
+	inputs := 0
+	for i, arg := range cmd {
+		if arg == "-i" {
+			lastInput = i
+			inputs++
+		}
+	}
+	out := []string{cmd[0], "-y"}
+	out = append(out, cmd[1:lastInput+2]...)
+	out = append(out, "-f", "concat", "-safe", "0", "-i", list, "-map", fmt.Sprintf("%d:v:0", inputs))
+
+	var audio []string
+	mapped := false
+	args := cmd[lastInput+2 : len(cmd)-1]
+	for i := 0; i+1 < len(args); i++ {
+		switch {
+		case args[i] == "-map" && !isVideoMap(args[i+1]):
+			out = append(out, args[i], args[i+1])
+			mapped = true
+			i++
+		case isAudioOption(args[i]):
+			audio = append(audio, args[i], args[i+1])
+			i++
+		}
+	}
+	if !mapped {
+		out = append(out, "-map", "0:a:0?")
+	}
+	out = append(out, "-c:v", "copy")
+	out = append(out, audio...)
+	for _, flag := range []string{"-movflags", "-tag:v"} {
+		if v := argAfter(cmd, flag); v != "" {
+			out = append(out, flag, v)
+		}
+	}
+	return append(out, "-reset_timestamps", "1", cmd[len(cmd)-1])
+}
+
+// isVideoMap reports whether a -map value selects video: the filter graph
+// output or an input's video stream.
+func isVideoMap(v string) bool {
+	return strings.HasPrefix(v, "[") || strings.Contains(v, ":v")
+}
+
+// isAudioOption reports whether arg is an audio encoding option taking a value.
+func isAudioOption(arg string) bool {
+	switch arg {
+	case "-c:a", "-b:a", "-af":
+		return true
+	}
+	return false
+}
+
+// seconds formats s for -ss and -t with millisecond precision.
+func seconds(s float64) string {
+	return strconv.FormatFloat(s, 'f', 3, 64)
+}
+
+// commandFingerprint identifies an encode's settings for its chunk ledger.
+func commandFingerprint(cmd []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(cmd, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// loadLedger reads the ledger at path, returning an empty one when it is
+// missing or unreadable.
+func loadLedger(path string) chunkLedger {
+	var ledger chunkLedger
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ledger
+	}
+	if err := json.Unmarshal(data, &ledger); err != nil {
+		return chunkLedger{}
+	}
+	return ledger
+}
+
+// saveLedger writes the ledger through a temporary file, so a crash mid-write
+// leaves the previous ledger in place.
+func saveLedger(path string, ledger chunkLedger) error {
+	data, err := json.MarshalIndent(ledger, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write chunk ledger: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write chunk ledger: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package transcoder
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+)
+
+// rfc6381Codecs builds the CODECS value players use to decide whether they can
+// decode a variant before fetching it (e.g. "avc1.64001f,mp4a.40.2").
+// Profile and level come from probe when available and are otherwise
+// estimated from the codec family and output height. audioCodec is used when
+// probe is nil or has no audio stream. container picks the registered form
+// where it differs (Opus is "opus" in WebM).
+func rfc6381Codecs(family string, height int, probe *analyzer.OutputProbe, audioCodec, container string) string {
+	var parts []string
+	if video := videoCodecString(family, height, probe); video != "" {
+		parts = append(parts, video)
+	}
+	audioProfile := ""
+	if probe != nil && probe.AudioCodec != "" {
+		audioCodec, audioProfile = probe.AudioCodec, probe.AudioProfile
+	}
+	if audio := audioCodecString(audioCodec, audioProfile); audio != "" {
+		// "Opus" is the MP4 sample entry; WebM registers the codec in lowercase
+		if strings.EqualFold(container, "webm") {
+			audio = strings.ToLower(audio)
+		}
+		parts = append(parts, audio)
+	}
+	return strings.Join(parts, ",")
+}
+
+// videoCodecString returns the RFC 6381 video codec string for family.
+func videoCodecString(family string, height int, probe *analyzer.OutputProbe) string {
+	var profile, pixFmt string
+	var level int
+	if probe != nil && helpers.CodecFamily(probe.VideoCodec) == family {
+		profile, level, pixFmt = probe.VideoProfile, probe.VideoLevel, probe.PixelFormat
+		if probe.Height > 0 {
+			height = probe.Height
+		}
+	}
+	tenBit := strings.Contains(pixFmt, "10") || strings.Contains(profile, "10")
+
+	switch family {
+	case "h264":
+		if level <= 0 {
+			level = pickLevel(height, []int{30, 31, 40, 50, 51}) // 3.0, 3.1, 4.0, 5.0, 5.1
+		}
+		idc, constraints := 0x64, 0x00 // High
+		switch profile {
+		case "Baseline":
+			idc = 0x42
+		case "Constrained Baseline":
+			idc, constraints = 0x42, 0xe0
+		case "Main":
+			idc, constraints = 0x4d, 0x40
+		case "High 10":
+			idc = 0x6e
+		case "High 4:2:2":
+			idc = 0x7a
+		}
+		return fmt.Sprintf("avc1.%02x%02x%02x", idc, constraints, level)
+	case "hevc":
+		if level <= 0 {
+			level = pickLevel(height, []int{90, 93, 120, 150, 153}) // level * 30
+		}
+		if tenBit {
+			return fmt.Sprintf("hvc1.2.4.L%d.B0", level)
+		}
+		return fmt.Sprintf("hvc1.1.6.L%d.B0", level)
+	case "av1":
+		if level <= 0 {
+			level = pickLevel(height, []int{4, 5, 8, 12, 13}) // seq_level_idx for 3.0 .. 5.1
+		}
+		depth := 8
+		if tenBit {
+			depth = 10
+		}
+		return fmt.Sprintf("av01.0.%02dM.%02d", level, depth)
+	case "vp9":
+		// 10-bit 4:2:0 needs profile 2
+		vp9Profile, depth := 0, 8
+		if tenBit {
+			vp9Profile, depth = 2, 10
+		}
+		return fmt.Sprintf("vp09.%02d.%d.%02d", vp9Profile, pickLevel(height, []int{30, 31, 40, 50, 51}), depth)
+	}
+	return ""
+}
+
+// pickLevel chooses from levels (for up to 480p, 720p, 1080p, 2160p, and
+// beyond) the one matching height.
+func pickLevel(height int, levels []int) int {
+	switch {
+	case height <= 480:
+		return levels[0]
+	case height <= 720:
+		return levels[1]
+	case height <= 1080:
+		return levels[2]
+	case height <= 2160:
+		return levels[3]
+	}
+	return levels[4]
+}
+
+// audioCodecString returns the RFC 6381 audio codec string, or "" if unknown.
+func audioCodecString(codec, profile string) string {
+	switch helpers.CodecFamily(codec) {
+	case "aac":
+		switch profile {
+		case "HE-AAC":
+			return "mp4a.40.5"
+		case "HE-AACv2":
+			return "mp4a.40.29"
+		}
+		return "mp4a.40.2"
+	case "mp3":
+		return "mp4a.40.34"
+	case "ac3":
+		return "ac-3"
+	case "eac3":
+		return "ec-3"
+	case "opus":
+		return "Opus"
+	case "vorbis":
+		return "vorbis"
+	case "flac":
+		return "fLaC"
+	case "alac":
+		return "alac"
+	}
+	return ""
+}
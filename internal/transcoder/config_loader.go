@@ -1,57 +1,130 @@
 package transcoder
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
-// LoadProfile loads a TranscodeProfile from a JSON or YAML file in the profiles/ directory.
-// Infers format from file extension and unmarshals into a validated TranscodeProfile.
+// ProfileDir is the directory bare profile filenames are resolved against.
+const ProfileDir = "profiles"
+
+// StdinProfile is the filename that makes LoadProfile read from standard input.
+const StdinProfile = "-"
+
+// stdinPath is the path reported in errors for profiles read from stdin.
+const stdinPath = "<stdin>"
+
+// LoadProfile loads a TranscodeProfile from a JSON or YAML source.
+//
+// The filename may be:
+//   - "-" to read the profile from stdin (format sniffed from content)
+//   - an absolute or relative path (e.g. "/etc/dotgo/movie.yaml", "./job.json")
+//   - a bare filename, resolved under profiles/ first and then the working directory
+//   - a built-in preset name such as "streaming-high" (see Presets)
+//
+// ${VAR} and ${VAR:-default} references are expanded from the environment before
+// parsing, so containerized deployments can inject paths and settings. A profile
+// may set "extends" to inherit from a base profile (see LoadProfileLayers).
 // Returns a fully populated profile or a wrapped ConfigError with operation details.
 func LoadProfile(filename string) (*TranscodeProfile, error) {
-	if filename == "" {
-		return nil, &ConfigError{
-			Op:   "validate",
-			Path: "profiles/",
-			Err:  fmt.Errorf("filename is empty"),
+	return LoadProfileLayers(filename)
+}
+
+// PathCheck vets a profile file before it is read, e.g. to keep a service's
+// profiles under one directory. It is called with the resolved path of every
+// file a load reads: the profile, its overlays, and each "extends" target.
+type PathCheck func(path string) error
+
+// readProfileSource resolves filename and returns the raw profile bytes,
+// the resolved path (used in errors), and the format extension. Files are
+// passed to check, when set, before they are read.
+func readProfileSource(filename string, check PathCheck) ([]byte, string, string, error) {
+	if filename == StdinProfile {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, stdinPath, "", &ConfigError{Op: "read", Path: stdinPath, Err: err}
 		}
+		return data, stdinPath, sniffFormat(data), nil
+	}
+
+	// Names without an extension or directory are built-in presets
+	if name, ok := presetName(filename); ok {
+		if !IsPreset(name) {
+			return nil, filename, "", unknownPresetError(name)
+		}
+		data, path, err := readPreset(name)
+		return data, path, ".yaml", err
 	}
 
 	// Infer file format from extension
 	ext := strings.ToLower(filepath.Ext(filename))
 	if ext != ".json" && ext != ".yaml" && ext != ".yml" {
-		return nil, &ConfigError{
+		return nil, filename, ext, &ConfigError{
 			Op:   "validate",
 			Path: filename,
 			Err:  fmt.Errorf("unsupported file extension %q", ext),
 		}
 	}
 
-	// Construct full path to profile file
-	path := filepath.Join("profiles", filename)
+	path := resolveProfilePath(filename)
+	if check != nil {
+		if err := check(path); err != nil {
+			return nil, path, ext, &ConfigError{Op: "read", Path: path, Err: err}
+		}
+	}
 
 	// Read file contents
 	data, err := os.ReadFile(path)
 	if err != nil {
-		return nil, &ConfigError{
+		return nil, path, ext, &ConfigError{
 			Op:   "read",
 			Path: path,
 			Err:  err,
 		}
 	}
+	return data, path, ext, nil
+}
+
+// resolveProfilePath maps filename to a readable path. Paths with a directory
+// component are used as given; bare filenames prefer profiles/<name> and fall
+// back to the working directory.
+func resolveProfilePath(filename string) string {
+	if filepath.IsAbs(filename) || filepath.Base(filename) != filename {
+		return filename
+	}
+	inProfiles := filepath.Join(ProfileDir, filename)
+	if fileExists(inProfiles) {
+		return inProfiles
+	}
+	if fileExists(filename) {
+		return filename
+	}
+	return inProfiles
+}
+
+// decodeLayer unmarshals data into a generic map according to ext (".json",
+// ".yaml", or ".yml"), ready for merging. With expand, environment references
+// are expanded first.
+func decodeLayer(data []byte, ext, path string, expand bool) (map[string]any, error) {
+	if expand {
+		data = ExpandEnv(data)
+	}
 
-	var profile TranscodeProfile
+	layer := map[string]any{}
 
 	// Unmarshal based on format
 	switch ext {
 	case ".json":
-		if err := json.Unmarshal(data, &profile); err != nil {
+		if err := json.Unmarshal(data, &layer); err != nil {
 			return nil, &ConfigError{
 				Op:   "unmarshal_json",
 				Path: path,
@@ -59,28 +132,47 @@ func LoadProfile(filename string) (*TranscodeProfile, error) {
 			}
 		}
 	case ".yaml", ".yml":
-		if err := yaml.Unmarshal(data, &profile); err != nil {
+		if err := yaml.Unmarshal(data, &layer); err != nil {
 			return nil, &ConfigError{
 				Op:   "unmarshal_yaml",
 				Path: path,
 				Err:  err,
 			}
 		}
-	}
-
-	// Apply fallback values for optional fields
-	applyDefaults(&profile)
-
-	// Validate required fields and log segment length behavior
-	if err := validateProfile(profile); err != nil {
+	default:
 		return nil, &ConfigError{
 			Op:   "validate",
-			Path: filename,
-			Err:  err,
+			Path: path,
+			Err:  fmt.Errorf("unsupported profile format %q", ext),
 		}
 	}
 
-	return &profile, nil
+	return layer, nil
+}
+
+// sniffFormat guesses the format of profile content without a file extension.
+// Content starting with '{' is treated as JSON, anything else as YAML.
+func sniffFormat(data []byte) string {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return ".json"
+	}
+	return ".yaml"
+}
+
+// envRef matches ${VAR} and ${VAR:-default} references.
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// ExpandEnv replaces ${VAR} and ${VAR:-default} references in data with values
+// from the environment. Unset variables without a default expand to "".
+// Bare $VAR is left untouched so literal dollar signs in values survive.
+func ExpandEnv(data []byte) []byte {
+	return envRef.ReplaceAllFunc(data, func(m []byte) []byte {
+		sub := envRef.FindSubmatch(m)
+		if val, ok := os.LookupEnv(string(sub[1])); ok && val != "" {
+			return []byte(val)
+		}
+		return sub[2]
+	})
 }
 
 // applyDefaults sets fallback values for optional fields in the TranscodeProfile.
@@ -91,36 +183,24 @@ func applyDefaults(p *TranscodeProfile) {
 	}
 }
 
-// validateProfile performs basic sanity checks on required fields.
-// Logs segment length behavior and returns error if any required field is missing.
+// validateProfile runs ValidateProfile and logs segment length behavior and warnings.
+// Returns a *ValidationError listing every error-severity issue at once.
 func validateProfile(p TranscodeProfile) error {
-	if p.InputPath == "" {
-		return fmt.Errorf("missing input_path")
-	}
-	if p.OutputDir == "" {
-		return fmt.Errorf("missing output_dir")
-	}
-	if len(p.Variants) == 0 {
-		return fmt.Errorf("variants must include at least one resolution/bitrate pair")
-	}
-	if p.VideoCodec == "" {
-		return fmt.Errorf("missing video_codec")
-	}
-	if p.Container == "" {
-		return fmt.Errorf("missing container format")
+	report := ValidateProfile(p, nil)
+	if err := report.Err(); err != nil {
+		return err
 	}
 
 	// Interpret segment length behavior
-	switch {
-	case p.SegmentLength < 0:
-		return fmt.Errorf("segment_length must be zero or a positive integer")
-
-	case p.SegmentLength == 0:
+	if p.SegmentLength == 0 {
 		log.Println("📼 segment_length not set in config—using keyframe interval for segmentation")
-
-	default:
+	} else {
 		log.Printf("📐 Using configured segment_length: %ds", p.SegmentLength)
 	}
 
+	for _, w := range report.Warnings() {
+		log.Printf("⚠️ Profile %s: %s", w.Field, w.Message)
+	}
+
 	return nil
 }
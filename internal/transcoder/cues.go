@@ -0,0 +1,102 @@
+package transcoder
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+)
+
+// Ad break positions for CuePoint.Position.
+const (
+	CuePreRoll  = "pre"  // Before the content
+	CueMidRoll  = "mid"  // At CuePoint.Time into the content (default)
+	CuePostRoll = "post" // After the content
+)
+
+// DefaultAdPodSeconds is the length of an ad break whose duration is unset.
+const DefaultAdPodSeconds = 30
+
+// CuePoint is an ad break (TranscodeProfile.CuePoints). DASH masters become
+// multi-period: the content is split into periods at mid-roll cue points and
+// an ad period is inserted at each break, holding a slate or an empty
+// placeholder signalled with SCTE-35 for the SSAI service to fill. HLS
+// masters ignore cue points.
+type CuePoint struct {
+	ID       string  `json:"id,omitempty" yaml:"id,omitempty"`             // Period id of the break; default "ad-<n>"
+	Position string  `json:"position,omitempty" yaml:"position,omitempty"` // "pre", "mid" (default), or "post"
+	Time     float64 `json:"time,omitempty" yaml:"time,omitempty"`         // Mid-roll: seconds into the content, moved to the nearest segment boundary
+	Duration float64 `json:"duration,omitempty" yaml:"duration,omitempty"` // Break length in seconds (default 30)
+	Slate    string  `json:"slate,omitempty" yaml:"slate,omitempty"`       // MPD played during the break, relative to the slug directory or absolute; default a placeholder period
+}
+
+// AdBreaks returns the profile's cue points in playback order with defaults
+// applied: pre-rolls at 0, post-rolls at duration, IDs and lengths filled in.
+// Mid-rolls outside the content (0, duration) are dropped.
+func (p *TranscodeProfile) AdBreaks(duration float64) []CuePoint {
+	var breaks []CuePoint
+	for _, c := range p.CuePoints {
+		switch c.Position {
+		case CuePreRoll:
+			c.Time = 0
+		case CuePostRoll:
+			c.Time = duration
+		default:
+			c.Position = CueMidRoll
+			if c.Time <= 0 || (duration > 0 && c.Time >= duration) {
+				continue
+			}
+		}
+		if c.Duration <= 0 {
+			c.Duration = DefaultAdPodSeconds
+		}
+		breaks = append(breaks, c)
+	}
+	rank := map[string]int{CuePreRoll: 0, CueMidRoll: 1, CuePostRoll: 2}
+	sort.SliceStable(breaks, func(i, j int) bool {
+		if rank[breaks[i].Position] != rank[breaks[j].Position] {
+			return rank[breaks[i].Position] < rank[breaks[j].Position]
+		}
+		return breaks[i].Time < breaks[j].Time
+	})
+	for i := range breaks {
+		if breaks[i].ID == "" {
+			breaks[i].ID = fmt.Sprintf("ad-%d", i+1)
+		}
+	}
+	return breaks
+}
+
+// validateCuePoints checks cue point positions, times, and IDs, and with
+// media whether mid-rolls fall inside the content.
+func validateCuePoints(p TranscodeProfile, media *analyzer.MediaInfo, r *ValidationReport) {
+	ids := make(map[string]bool)
+	for i, c := range p.CuePoints {
+		field := fmt.Sprintf("cue_points[%d]", i)
+		switch c.Position {
+		case "", CueMidRoll:
+			if c.Time <= 0 {
+				r.add(SeverityError, field+".time", "mid-roll time must be positive; use position \"pre\" for a pre-roll")
+			} else if media != nil && media.Duration > 0 && c.Time >= media.Duration {
+				r.add(SeverityError, field+".time", "time %.2fs is past the end of the %.2fs source; use position \"post\" for a post-roll", c.Time, media.Duration)
+			}
+		case CuePreRoll, CuePostRoll:
+			if c.Time != 0 {
+				r.add(SeverityWarning, field+".time", "time is ignored for %s-rolls", c.Position)
+			}
+		default:
+			r.add(SeverityError, field+".position", "unknown position %q (want pre, mid, or post)", c.Position)
+		}
+		if c.Duration < 0 {
+			r.add(SeverityError, field+".duration", "duration must be zero or positive")
+		} else if c.Duration == 0 {
+			r.defaulted(field+".duration", "%ds", DefaultAdPodSeconds)
+		}
+		if c.ID != "" {
+			if ids[c.ID] {
+				r.add(SeverityError, field+".id", "duplicate cue point id %q", c.ID)
+			}
+			ids[c.ID] = true
+		}
+	}
+}
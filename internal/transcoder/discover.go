@@ -0,0 +1,136 @@
+package transcoder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/scaler"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
+)
+
+// variantFilePattern matches Transcode output names: <slug>_<label>_<bitrate>bps.<container>,
+// with a _<codec> suffix for secondary codec tiers (e.g. "movie_720p_3000kbps.mp4",
+// "movie_720p_1800kbps_hevc.mp4"). The slug may itself contain underscores.
+// The extension is checked against the container separately.
+var variantFilePattern = regexp.MustCompile(`^(.+)_(\d+p)_(\d+[kK])bps(?:_([a-z0-9]+))?\.([A-Za-z0-9]+)$`)
+
+// variantContainers are the containers DiscoverVariants accepts when no
+// container is given.
+var variantContainers = []string{"mp4", "m4v", "mov", "webm", "mkv"}
+
+// DiscoverVariants scans slugDir for variant files written by Transcode and
+// rebuilds the ResolutionVariant list, ordered highest resolution and bitrate
+// first. When slug is non-empty, only files for that slug are considered, and
+// when container is non-empty (the profile's container), only files with that
+// extension; otherwise any common video container matches. Files that don't
+// follow the naming scheme are ignored.
+//
+// Each match is ffprobed for its real dimensions (width follows the source
+// aspect ratio, so it can differ from the label), size, and measured bitrate.
+// Files ffprobe can't read, such as encodes interrupted mid-write, are skipped
+// and logged so partial re-runs redo them.
+func DiscoverVariants(slugDir, slug, container string, logger TranscodeLogger) ([]ResolutionVariant, error) {
+	logger = stagelog.OrStd(logger)
+	entries, err := os.ReadDir(slugDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read output directory %s: %w", slugDir, err)
+	}
+
+	var variants []ResolutionVariant
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		m := variantFilePattern.FindStringSubmatch(entry.Name())
+		if m == nil || (slug != "" && m[1] != slug) || !variantContainer(m[5], container) {
+			continue
+		}
+		width, height, err := scaler.DimensionsForLabel(m[2])
+		if err != nil {
+			continue
+		}
+
+		probe, err := analyzer.ProbeOutput(filepath.Join(slugDir, entry.Name()))
+		if err != nil {
+			logger.LogVariant(entry.Name(), "⚠️ Skipping unreadable variant (incomplete encode?)")
+			logger.LogError("discover", err)
+			continue
+		}
+		if probe.Height > 0 {
+			width, height = probe.Width, probe.Height
+		}
+		codec := helpers.CodecFamily(probe.VideoCodec)
+		if codec == "" {
+			codec = m[4]
+		}
+		variants = append(variants, ResolutionVariant{
+			Width:          width,
+			Height:         height,
+			Bitrate:        m[3],
+			ScaleFlag:      "auto",
+			OutputFilename: entry.Name(),
+			Codec:          codec,
+			Tier:           m[4],
+			Codecs:         rfc6381Codecs(codec, height, probe, "", strings.TrimPrefix(filepath.Ext(entry.Name()), ".")),
+			Stats: EncodeStats{
+				FileSize:        probe.Size,
+				MeasuredBitrate: probe.Bitrate,
+			},
+		})
+	}
+
+	sort.SliceStable(variants, func(i, j int) bool {
+		if variants[i].Height != variants[j].Height {
+			return variants[i].Height > variants[j].Height
+		}
+		return helpers.ParseBitrateKbps(variants[i].Bitrate) > helpers.ParseBitrateKbps(variants[j].Bitrate)
+	})
+	return variants, nil
+}
+
+// DiscoverResult rebuilds the TranscodeResult for a profile whose variants were
+// already encoded, so segmentation and thumbnails can run without re-encoding.
+// Returns an error if the slug directory holds no variants.
+func DiscoverResult(profile *TranscodeProfile, duration float64, logger TranscodeLogger) (*TranscodeResult, error) {
+	slug := profile.OutputSlug()
+	slugDir := profile.Layout().For(profile.InputPath, slug).SlugDir(profile.OutputDir)
+
+	variants, err := DiscoverVariants(slugDir, profile.variantBase(), profile.Container, logger)
+	if err != nil {
+		return nil, NewTranscoderError(
+			"discover", "read_dir", profile.InputPath, slugDir,
+			"failed to scan output directory", nil, 0, err,
+		)
+	}
+	if len(variants) == 0 {
+		return nil, NewTranscoderError(
+			"discover", "no_variants", profile.InputPath, slugDir,
+			"no transcoded variants found", nil, 0, fmt.Errorf("no %s_<label>_<bitrate>bps.%s files in %s", profile.variantBase(), profile.Container, slugDir),
+		)
+	}
+	return &TranscodeResult{
+		InputPath: profile.InputPath,
+		OutputDir: slugDir,
+		Duration:  duration,
+		Success:   true,
+		Variants:  variants,
+		Profile:   profile,
+	}, nil
+}
+
+// variantContainer reports whether a variant file extension matches container,
+// or any of variantContainers when container is empty.
+func variantContainer(ext, container string) bool {
+	ext = strings.ToLower(ext)
+	if container == "" {
+		return slices.Contains(variantContainers, ext)
+	}
+	return ext == strings.ToLower(container)
+}
@@ -1,6 +1,11 @@
 package transcoder
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/errclass"
+	"github.com/dotsoulja/dotgo-transcode/internal/executil"
+)
 
 // ConfigError represents an error during config loading or validation.
 // Used when reading, parsing, or validating profile files (JSON/YAML).
@@ -22,6 +27,11 @@ func (e *ConfigError) Unwrap() error {
 	return e.Err
 }
 
+// Class reports every config error as invalid configuration (see errclass).
+func (e *ConfigError) Class() errclass.Class {
+	return errclass.InvalidConfig
+}
+
 // TranscoderError wraps errors that occur during the transcoding process.
 // Captures full forensic context including stage, operation, command, and exit code.
 type TranscoderError struct {
@@ -31,17 +41,22 @@ type TranscoderError struct {
 	OutputPath string   // Target output path (file or directory)
 	Command    []string // Command attempted (e.g. ffmpeg args)
 	ExitCode   int      // Exit code from subprocess, if available
+	Stderr     string   // Tail of subprocess stderr, if available
 	Message    string   // Human-readable summary of the error
 	Err        error    // Underlying error (wrapped for traceability)
 }
 
 // Error returns a formatted string representation of the TranscoderError.
-// Includes stage, operation, input/output paths, command, exit code, and root error.
+// Includes stage, operation, input/output paths, command, exit code, root error, and stderr tail.
 func (e *TranscoderError) Error() string {
-	return fmt.Sprintf(
+	msg := fmt.Sprintf(
 		"[%s/%s] %s\nInput: %s\nOutput: %s\nCmd: %v\nExitCode: %d\nErr: %v",
 		e.Stage, e.Operation, e.Message, e.InputPath, e.OutputPath, e.Command, e.ExitCode, e.Err,
 	)
+	if e.Stderr != "" {
+		msg += "\nStderr:\n" + e.Stderr
+	}
+	return msg
 }
 
 // Unwrap returns the underlying error for compatibility with errors.Is/As.
@@ -50,10 +65,30 @@ func (e *TranscoderError) Unwrap() error {
 	return e.Err
 }
 
+// Class classifies the failure (see errclass) by its cause, or as invalid
+// configuration when validation failed for a reason the cause doesn't show.
+func (e *TranscoderError) Class() errclass.Class {
+	if class := errclass.Classify(e.Err); class != errclass.None && class != errclass.Unknown {
+		return class
+	}
+	if e.Stage == "validation" {
+		return errclass.InvalidConfig
+	}
+	return errclass.Unknown
+}
+
+// Retryable reports whether the failure was transient (e.g. ffmpeg timed out or stalled)
+// and the operation may succeed if re-run.
+func (e *TranscoderError) Retryable() bool {
+	return e.Class().IsRetryable()
+}
+
 // NewTranscoderError creates a new TranscoderError with full context.
 // Preferred constructor for wrapping errors during any pipeline stage.
+// The stderr tail is extracted automatically when err is an executil.CommandError.
 func NewTranscoderError(stage, operation, input, output, msg string, cmd []string, code int, err error) *TranscoderError {
 	return &TranscoderError{
+		Stderr:     executil.StderrTail(err),
 		Stage:      stage,
 		Operation:  operation,
 		InputPath:  input,
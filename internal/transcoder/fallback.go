@@ -0,0 +1,223 @@
+package transcoder
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/scaler"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+)
+
+// Low-source policies accepted in LowSourceSettings.Policy.
+const (
+	LowSourceSkip       = "skip"       // Drop rungs above the source, however few remain (default)
+	LowSourceSynthesize = "synthesize" // Add lower-bitrate rungs at the tallest resolution the source allows
+	LowSourceUpscale    = "upscale"    // Upscale the smallest rungs above the source, within max_upscale
+	LowSourceFail       = "fail"       // Fail the transcode with an error naming the source and ladder
+)
+
+// Low-source defaults used when TranscodeProfile.LowSource leaves a field unset.
+const (
+	DefaultLowSourceMinRungs    = 2
+	DefaultLowSourceMaxUpscale  = 1.5
+	DefaultLowSourceBitrateStep = 0.6
+)
+
+// minSynthesizedKbps is the lowest bitrate a synthesized rung is given.
+const minSynthesizedKbps = 100
+
+// LowSourceSettings decides what happens when a low-resolution source leaves
+// fewer than MinRungs of the ladder (TranscodeProfile.LowSource). Rungs above
+// the source are always dropped under the default skip policy.
+type LowSourceSettings struct {
+	Policy      string  `json:"policy,omitempty" yaml:"policy,omitempty"`             // "skip" (default), "synthesize", "upscale", or "fail"
+	MinRungs    int     `json:"min_rungs,omitempty" yaml:"min_rungs,omitempty"`       // Apply the policy when fewer primary rungs than this fit the source (default 2)
+	MaxUpscale  float64 `json:"max_upscale,omitempty" yaml:"max_upscale,omitempty"`   // upscale: tallest output as a multiple of the source height (default 1.5)
+	BitrateStep float64 `json:"bitrate_step,omitempty" yaml:"bitrate_step,omitempty"` // synthesize: each added rung's bitrate as a fraction of the one above (default 0.6)
+}
+
+// LowSourceSettings returns the profile's low-source settings with defaults
+// applied.
+func (p *TranscodeProfile) LowSourceSettings() LowSourceSettings {
+	var s LowSourceSettings
+	if p.LowSource != nil {
+		s = *p.LowSource
+	}
+	if s.Policy == "" {
+		s.Policy = LowSourceSkip
+	}
+	if s.MinRungs <= 0 {
+		s.MinRungs = DefaultLowSourceMinRungs
+	}
+	if s.MaxUpscale <= 0 {
+		s.MaxUpscale = DefaultLowSourceMaxUpscale
+	}
+	if s.BitrateStep <= 0 || s.BitrateStep >= 1 {
+		s.BitrateStep = DefaultLowSourceBitrateStep
+	}
+	return s
+}
+
+// fallbackLadder applies the low-source policy to the rungs that fit the
+// source and those above it. It returns the rungs to encode, reasons for the
+// rungs dropped, and an error under the fail policy when the ladder falls short.
+func (p *TranscodeProfile) fallbackLadder(fit, above []budgetRung, seen map[string]bool, media *analyzer.MediaInfo, logger TranscodeLogger) ([]budgetRung, []string, error) {
+	s := p.LowSourceSettings()
+	short := s.MinRungs - p.primaryRungs(fit)
+	if short > 0 && len(above) > 0 {
+		switch s.Policy {
+		case LowSourceUpscale:
+			fit, above = p.upscaleRungs(fit, above, short, s.MaxUpscale, media, logger)
+		case LowSourceSynthesize:
+			fit = p.synthesizeRungs(fit, above, seen, short, s.BitrateStep, media, logger)
+		case LowSourceFail:
+			return nil, nil, fmt.Errorf("source is %dp and only %d of the ladder's rungs fit it (low_source.min_rungs is %d); add lower rungs or change low_source.policy", media.Height, p.primaryRungs(fit), s.MinRungs)
+		}
+	}
+
+	var skipped []string
+	for _, r := range above {
+		logger.LogVariant(r.Variant.Resolution, fmt.Sprintf("⛔ Skipping - source resolution (%dp) too low", media.Height))
+		skipped = append(skipped, fmt.Sprintf("%s: source resolution (%dp) too low", r.Variant.Resolution, media.Height))
+	}
+	if n := p.primaryRungs(fit); n < s.MinRungs && len(above) > 0 {
+		logger.LogStage("filter", fmt.Sprintf("⚠️ Only %d rung(s) fit the %dp source (low_source.policy %s)", n, media.Height, s.Policy))
+	}
+	return fit, skipped, nil
+}
+
+// primaryRungs counts the rungs of the profile's primary codec.
+func (p *TranscodeProfile) primaryRungs(rungs []budgetRung) int {
+	n := 0
+	for _, r := range rungs {
+		if p.codecTier(r.Variant) == "" {
+			n++
+		}
+	}
+	return n
+}
+
+// upscaleRungs moves up to short of the smallest primary rungs above the
+// source into the ladder, as long as they stay within maxUpscale of it.
+func (p *TranscodeProfile) upscaleRungs(fit, above []budgetRung, short int, maxUpscale float64, media *analyzer.MediaInfo, logger TranscodeLogger) ([]budgetRung, []budgetRung) {
+	sort.SliceStable(above, func(i, j int) bool { return above[i].Height < above[j].Height })
+	limit := float64(media.Height) * maxUpscale
+	var rest []budgetRung
+	for _, r := range above {
+		if short > 0 && p.codecTier(r.Variant) == "" && float64(r.Height) <= limit {
+			logger.LogVariant(r.Key, fmt.Sprintf("⤴️ Upscaling from the %dp source (low_source.policy upscale)", media.Height))
+			fit = append(fit, r)
+			short--
+			continue
+		}
+		rest = append(rest, r)
+	}
+	return fit, rest
+}
+
+// synthesizeRungs adds short rungs below the tallest primary rung that fits
+// the source, at its resolution and successively lower bitrates. With no
+// rung fitting, the first one takes the tallest standard resolution within
+// the source and a step below the bitrate of the smallest rung above it.
+func (p *TranscodeProfile) synthesizeRungs(fit, above []budgetRung, seen map[string]bool, short int, step float64, media *analyzer.MediaInfo, logger TranscodeLogger) []budgetRung {
+	var base *budgetRung
+	for i := range fit {
+		if p.codecTier(fit[i].Variant) == "" && (base == nil || fit[i].Height > base.Height || (fit[i].Height == base.Height && helpers.ParseBitrateKbps(fit[i].Variant.Bitrate) < helpers.ParseBitrateKbps(base.Variant.Bitrate))) {
+			base = &fit[i]
+		}
+	}
+	var template budgetRung
+	kbps := 0
+	if base != nil {
+		template, kbps = *base, helpers.ParseBitrateKbps(base.Variant.Bitrate)
+	} else {
+		var smallest *budgetRung
+		for i := range above {
+			if p.codecTier(above[i].Variant) == "" && (smallest == nil || above[i].Height < smallest.Height) {
+				smallest = &above[i]
+			}
+		}
+		preset := largestPresetWithin(media.Height)
+		if smallest == nil || preset == nil {
+			return fit
+		}
+		template = *smallest
+		template.Variant.Resolution, template.Width, template.Height = preset.Label, preset.Width, preset.Height
+		kbps = helpers.ParseBitrateKbps(smallest.Variant.Bitrate)
+	}
+
+	for short > 0 {
+		kbps = int(float64(kbps) * step)
+		if kbps < minSynthesizedKbps {
+			break
+		}
+		r := template
+		r.Variant.Bitrate = fmt.Sprintf("%dk", kbps)
+		r.Key = fmt.Sprintf("%s_%s", r.Variant.Resolution, r.Variant.Bitrate)
+		if seen[r.Key] {
+			continue
+		}
+		seen[r.Key] = true
+		logger.LogVariant(r.Key, fmt.Sprintf("➕ Synthesized rung for the %dp source (low_source.policy synthesize)", media.Height))
+		fit = append(fit, r)
+		short--
+	}
+	return fit
+}
+
+// largestPresetWithin returns the tallest standard resolution no taller than
+// height, or nil.
+func largestPresetWithin(height int) *scaler.ResolutionPreset {
+	var best *scaler.ResolutionPreset
+	for i, preset := range scaler.StandardPresets {
+		if preset.Height <= height && (best == nil || preset.Height > best.Height) {
+			best = &scaler.StandardPresets[i]
+		}
+	}
+	return best
+}
+
+// validateLowSource checks the low-source block and, with media, whether the
+// fail policy would stop the run.
+func validateLowSource(p TranscodeProfile, media *analyzer.MediaInfo, r *ValidationReport) {
+	ls := p.LowSource
+	if ls == nil {
+		return
+	}
+	switch ls.Policy {
+	case "":
+		r.defaulted("low_source.policy", LowSourceSkip)
+	case LowSourceSkip, LowSourceSynthesize, LowSourceUpscale, LowSourceFail:
+	default:
+		r.add(SeverityError, "low_source.policy", "unknown low-source policy %q (want skip, synthesize, upscale, or fail)", ls.Policy)
+	}
+	if ls.MinRungs < 0 {
+		r.add(SeverityError, "low_source.min_rungs", "min_rungs must be zero or positive")
+	}
+	switch {
+	case ls.MaxUpscale < 0:
+		r.add(SeverityError, "low_source.max_upscale", "max_upscale must be zero or positive")
+	case ls.MaxUpscale > 0 && ls.MaxUpscale < 1:
+		r.add(SeverityError, "low_source.max_upscale", "max_upscale must be at least 1")
+	case ls.MaxUpscale > 2:
+		r.add(SeverityWarning, "low_source.max_upscale", "max_upscale %.2f more than doubles the source height", ls.MaxUpscale)
+	}
+	if ls.BitrateStep < 0 || ls.BitrateStep >= 1 {
+		r.add(SeverityError, "low_source.bitrate_step", "bitrate_step must be between 0 and 1")
+	}
+
+	if media == nil || media.Height <= 0 || ls.Policy != LowSourceFail {
+		return
+	}
+	s := p.LowSourceSettings()
+	fit := 0
+	for _, v := range p.Variants {
+		if _, h, err := scaler.DimensionsForLabel(v.Resolution); err == nil && h <= media.Height && p.codecTier(v) == "" {
+			fit++
+		}
+	}
+	if fit < s.MinRungs {
+		r.add(SeverityError, "low_source.policy", "source is %dp and only %d rung(s) fit it (min_rungs %d); the transcode will fail", media.Height, fit, s.MinRungs)
+	}
+}
@@ -2,12 +2,13 @@ package transcoder
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
 	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
 )
 
@@ -24,18 +25,23 @@ func validatePaths(input, output string) error {
 }
 
 // buildFFmpegCommand constructs the ffmpeg command for a given resolution.
-// Injects hardware acceleration flags if enabled and platform supports it.
+// Injects hardware decode/encode flags when enabled and a backend is available
+// on this OS (see selectHWAccel),
+// deinterlaces or inverse-telecines interlaced sources, and picks an output pixel
+// format the encoder and players support (e.g. 8-bit 4:2:0 for h264, 10-bit for hevc).
+// Timestamp repair flags and any read throttle precede each input, and the audio filter chain
+// (gap compensation, offset) is applied unless audio is copied, in which case
+// an offset comes from -itsoffset on a second input.
 // Final output path is injected as the last argument.
-func buildFFmpegCommand(profile *TranscodeProfile, variant Variant) []string {
+func buildFFmpegCommand(profile *TranscodeProfile, variant Variant, res variantResources, media *analyzer.MediaInfo, logger TranscodeLogger) []string {
 	// Sanitize input filename for output naming
-	base := strings.TrimSuffix(filepath.Base(profile.InputPath), filepath.Ext(profile.InputPath))
-	safeBase := strings.ReplaceAll(base, " ", "_")
+	safeBase := profile.variantBase()
 
 	// Parse bitrate string (e.g. "3000k") into integer
 	bitrateStr := variant.Bitrate
 	bitrateInt := helpers.ParseBitrateKbps(bitrateStr)
 	if bitrateInt == 0 {
-		log.Printf("⚠️ Bitrate parsing failed for %s: %q. Using fallback bitrate.", variant.Resolution, bitrateStr)
+		logger.LogVariant(variant.Resolution, fmt.Sprintf("⚠️ Bitrate parsing failed: %q. Using fallback bitrate.", bitrateStr))
 		bitrateStr = "2000k"
 		bitrateInt = 2000
 	}
@@ -44,31 +50,303 @@ func buildFFmpegCommand(profile *TranscodeProfile, variant Variant) []string {
 	outputFilename := fmt.Sprintf("%s_%s_%dkbps.%s", safeBase, variant.Resolution, bitrateInt, profile.Container)
 	outputPath := filepath.Join(profile.OutputDir, outputFilename)
 
-	// Determine video codec, optionally override for hardware acceleration
-	videoCodec := profile.VideoCodec
-	if profile.UseHardwareAccel && isMacOS() && strings.EqualFold(videoCodec, "h264") {
-		videoCodec = "h264_videotoolbox"
-		log.Printf("🍎 Using VideoToolbox hardware acceleration for %s", variant.Resolution)
+	// Determine video codec (per-rung codec tiers override the profile), then
+	// optionally switch to a hardware encoder
+	videoCodec := profile.variantCodec(variant)
+	accel, hwEncoder, useHW := hwAccelFor(profile, videoCodec)
+	if useHW {
+		videoCodec = hwEncoder
+		logger.LogVariant(variant.Resolution, fmt.Sprintf("%s hardware acceleration (%s)", accel.Label, hwEncoder))
+	} else if profile.UseHardwareAccel {
+		logger.LogVariant(variant.Resolution, fmt.Sprintf("⚠️ No hardware encoder for %s available on %s - encoding in software", videoCodec, runtime.GOOS))
+	}
+	if !useHW {
+		if enc := av1EncoderFor(videoCodec, profile.AV1); enc != "" {
+			videoCodec = enc
+		}
+		videoCodec = softwareEncoder(videoCodec)
+	}
+
+	// Match output pixel format to encoder capabilities. Uploading backends
+	// (VA-API) convert in the filter chain instead of with -pix_fmt.
+	vf, complexGraph := videoFilter(profile, variant, media, logger)
+	pixFmt := outputPixelFormat(videoCodec, media)
+	if useHW && accel.Upload {
+		uploadFmt := "nv12"
+		if pixFmt == "p010le" {
+			uploadFmt = "p010le"
+		}
+		vf += fmt.Sprintf(",format=%s,hwupload", uploadFmt)
+		pixFmt = ""
 	}
 
 	// Build ffmpeg command with scale filter and codec settings
-	return []string{
+	cmd := []string{
 		"ffmpeg",
 		"-stats",
 		"-loglevel", "info",
 		"-progress", "pipe:2",
-		"-i", profile.InputPath,
-		"-vf", fmt.Sprintf("scale=-2:%s", strings.TrimSuffix(variant.Resolution, "p")), // height-driven scaling
+	}
+	if useHW {
+		cmd = append(cmd, accel.Decode...)
+	}
+	cmd = append(cmd, profile.InputFlags()...)
+	cmd = append(cmd, readRateArgs(res.ReadRate)...)
+	cmd = append(cmd, "-i", profile.InputPath)
+
+	// Source audio that already meets the variant's target is copied as is
+	audioCodec := softwareEncoder(profile.AudioCodec)
+	if copyAudio, reason := profile.canCopyAudio(variant, media); copyAudio {
+		audioCodec = "copy"
+		logger.LogVariant(variant.Resolution, "🔈 Copying audio ("+reason+")")
+	} else if reason != "" {
+		logger.LogVariant(variant.Resolution, "🔊 Re-encoding audio ("+reason+")")
+	}
+
+	// Copied audio can't be filtered, so its offset comes from re-reading the
+	// input with shifted timestamps
+	offset := profile.AudioOffset()
+	shiftInput := offset != 0 && audioCodec == "copy"
+	if offset != 0 {
+		logger.LogVariant(variant.Resolution, fmt.Sprintf("⏱️ Shifting audio by %dms", offset.Milliseconds()))
+	}
+	if shiftInput {
+		cmd = append(cmd, profile.InputFlags()...)
+		cmd = append(cmd, readRateArgs(res.ReadRate)...)
+		cmd = append(cmd, AudioOffsetInput(profile.InputPath, offset)...)
+	}
+	audioInput := 0
+	if shiftInput {
+		audioInput = 1
+	}
+
+	if complexGraph {
+		// Bitmap subtitles are a second filter input, so streams are mapped explicitly
+		cmd = append(cmd, "-filter_complex", vf+"[v]", "-map", "[v]")
+	} else {
+		cmd = append(cmd, "-vf", vf)
+	}
+	if complexGraph || shiftInput {
+		if shiftInput && !complexGraph {
+			cmd = append(cmd, "-map", "0:v:0")
+		}
+		switch audio := primaryAudio(media); {
+		case audio != nil:
+			cmd = append(cmd, "-map", fmt.Sprintf("%d:%d", audioInput, audio.Index))
+		case media == nil:
+			cmd = append(cmd, "-map", fmt.Sprintf("%d:a:0?", audioInput))
+		}
+	}
+	cmd = append(cmd,
 		"-c:v", videoCodec,
 		"-b:v", bitrateStr,
-		"-c:a", profile.AudioCodec,
-		"-reset_timestamps", "1",
-		outputPath,
+	)
+
+	// Tag HEVC as hvc1 so Apple players accept it in MP4/fMP4
+	if codecIs(videoCodec, "265", "hevc") {
+		cmd = append(cmd, "-tag:v", "hvc1")
 	}
+
+	// SVT-AV1 tuning (preset, film grain, tiles)
+	if codecIs(videoCodec, svtAV1Encoder) {
+		cmd = append(cmd, profile.AV1.args()...)
+	}
+
+	// libvpx tuning, with keyframes on segment boundaries
+	if codecIs(videoCodec, vp9Encoder) {
+		cmd = append(cmd, profile.VP9.args()...)
+		if media != nil {
+			cmd = append(cmd, vp9KeyframeArgs(profile.SegmentLength, media.Framerate)...)
+		}
+	}
+
+	// Encoder preset and peak bitrate cap
+	cmd = append(cmd, profile.RateControl.args(videoCodec, bitrateInt)...)
+
+	if pixFmt != "" {
+		cmd = append(cmd, "-pix_fmt", pixFmt)
+	}
+	if (pixFmt == "yuv420p10le" || pixFmt == "p010le") && codecIs(videoCodec, "265", "hevc") {
+		cmd = append(cmd, "-profile:v", "main10")
+	}
+
+	cmd = append(cmd, "-c:a", audioCodec)
+	if audioCodec != "copy" {
+		if br := profile.variantAudioBitrate(variant); br != "" {
+			cmd = append(cmd, "-b:a", br)
+		}
+		if af := profile.AudioFilter(); af != "" {
+			cmd = append(cmd, "-af", af)
+		}
+	}
+	cmd = append(cmd, "-reset_timestamps", "1")
+
+	// Cap encoder threads and lookahead so concurrent encodes share the host
+	cmd = append(cmd, res.args(videoCodec)...)
+
+	// Move the moov ahead of the media data, so the variant plays
+	// progressively and range reads from object storage don't need its tail
+	if isMP4Container(outputPath) {
+		cmd = append(cmd, "-movflags", "+faststart")
+	}
+
+	return append(cmd, outputPath)
 }
 
-// isMacOS returns true if the current platform is macOS.
-// Used to conditionally enable VideoToolbox acceleration.
-func isMacOS() bool {
-	return strings.Contains(strings.ToLower(runtime.GOOS), "darwin")
+// primaryAudio returns media's primary audio stream, or nil without media.
+func primaryAudio(media *analyzer.MediaInfo) *analyzer.StreamInfo {
+	if media == nil {
+		return nil
+	}
+	return media.PrimaryAudio()
+}
+
+// Deinterlace modes accepted in TranscodeProfile.Deinterlace.
+const (
+	DeinterlaceAuto  = "auto"  // Deinterlace when analysis reports interlaced or telecined video (default)
+	DeinterlaceOff   = "off"   // Never deinterlace
+	DeinterlaceForce = "force" // Always deinterlace, regardless of analysis
+)
+
+// videoFilter builds the -vf chain: optional deinterlacing, then any burned-in
+// forced subtitles (see TranscodeProfile.BurnedSubtitle), then height-driven
+// scaling. Bitmap subtitles need the subtitle stream as a second input, so
+// for them the chain is a -filter_complex graph whose output the caller
+// labels; complexGraph reports which.
+func videoFilter(profile *TranscodeProfile, variant Variant, media *analyzer.MediaInfo, logger TranscodeLogger) (chain string, complexGraph bool) {
+	scale := fmt.Sprintf("scale=-2:%s", strings.TrimSuffix(variant.Resolution, "p"))
+	deint := deinterlaceFilter(profile, variant, media, logger)
+
+	burn := profile.BurnedSubtitle(media)
+	switch {
+	case burn == nil:
+	case burn.IsTextSubtitle():
+		logger.LogVariant(variant.Resolution, fmt.Sprintf("🔥 Burning in forced subtitles (stream %d)", burn.Index))
+		scale = subtitlesFilter(profile.InputPath, media.SubtitlePosition(burn.Index)) + "," + scale
+	default:
+		logger.LogVariant(variant.Resolution, fmt.Sprintf("🔥 Burning in forced %s subtitles (stream %d)", burn.Codec, burn.Index))
+		if deint == "" {
+			deint = "null"
+		}
+		return fmt.Sprintf("[0:v:0]%s[base];[base][0:%d]overlay=eof_action=pass,%s", deint, burn.Index, scale), true
+	}
+	if deint == "" {
+		return scale, false
+	}
+	return deint + "," + scale, false
+}
+
+// deinterlaceFilter returns the deinterlacing or inverse telecine filters the
+// profile and source call for, or "".
+func deinterlaceFilter(profile *TranscodeProfile, variant Variant, media *analyzer.MediaInfo, logger TranscodeLogger) string {
+	mode := profile.Deinterlace
+	if mode == "" {
+		mode = DeinterlaceAuto
+	}
+	var scan analyzer.ScanInfo
+	if media != nil {
+		scan = media.Scan
+	}
+
+	switch {
+	case mode == DeinterlaceOff:
+		return ""
+	case scan.Type == analyzer.ScanTelecine && mode == DeinterlaceAuto:
+		logger.LogVariant(variant.Resolution, "🎞️ Telecined source - applying inverse telecine")
+		return "fieldmatch,yadif=deint=interlaced,decimate"
+	case scan.Type == analyzer.ScanInterlaced || mode == DeinterlaceForce:
+		parity := "auto"
+		if scan.FieldOrder == analyzer.FieldTFF || scan.FieldOrder == analyzer.FieldBFF {
+			parity = scan.FieldOrder
+		}
+		logger.LogVariant(variant.Resolution, fmt.Sprintf("🎞️ Interlaced source - deinterlacing (parity=%s)", parity))
+		return fmt.Sprintf("yadif=mode=send_frame:parity=%s:deint=all", parity)
+	}
+	return ""
+}
+
+// subtitlesFilter renders the position-th subtitle stream of input with the
+// subtitles filter, escaping the path for both filter option and filtergraph
+// parsing.
+func subtitlesFilter(input string, position int) string {
+	opt := strings.NewReplacer(`\`, `\\`, `'`, `\'`, `:`, `\:`).Replace(input)
+	graph := strings.NewReplacer(`\`, `\\`, `'`, `\'`, `[`, `\[`, `]`, `\]`, `,`, `\,`, `;`, `\;`).Replace(opt)
+	return fmt.Sprintf("subtitles=filename=%s:si=%d", graph, position)
+}
+
+// outputPixelFormat chooses -pix_fmt for the encoder given the source format.
+// Returns "" to keep ffmpeg's default (8-bit 4:2:0 sources with 8-bit encoders).
+//
+//   - h264 encoders (and every hardware h264 encoder) get 8-bit 4:2:0; 10-bit or
+//     4:2:2/4:4:4 sources otherwise fail on hardware encoders or produce
+//     High 10/4:2:2 streams most players reject
+//   - hevc, vp9, and av1 keep 10-bit sources at 10-bit (p010le for hardware hevc)
+//   - any other non-4:2:0 source is converted to yuv420p
+func outputPixelFormat(videoCodec string, media *analyzer.MediaInfo) string {
+	if media == nil || media.PixelFormat == "" {
+		return ""
+	}
+	tenBit := media.BitDepth > 8
+	is420 := strings.HasPrefix(media.PixelFormat, "yuv420p") || strings.HasPrefix(media.PixelFormat, "yuvj420p") ||
+		media.PixelFormat == "nv12" || media.PixelFormat == "p010le"
+
+	switch {
+	case codecIs(videoCodec, "264"):
+		if media.PixelFormat != "yuv420p" {
+			return "yuv420p"
+		}
+	case tenBit && codecIs(videoCodec, "265", "hevc"):
+		if isHardwareEncoder(videoCodec) {
+			return "p010le"
+		}
+		return "yuv420p10le"
+	case tenBit && codecIs(videoCodec, "vp9", "av1", "aom"):
+		return "yuv420p10le"
+	case !is420 || tenBit:
+		return "yuv420p"
+	}
+	return ""
+}
+
+// codecIs reports whether the encoder name contains any of the given fragments.
+func codecIs(codec string, fragments ...string) bool {
+	c := strings.ToLower(codec)
+	for _, f := range fragments {
+		if strings.Contains(c, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHardwareEncoder reports whether codec names a hardware encoder.
+func isHardwareEncoder(codec string) bool {
+	return codecIs(codec, "_videotoolbox", "_nvenc", "_qsv", "_vaapi", "_amf", "_v4l2m2m", "_mf")
+}
+
+// measureEncode derives encode statistics from wall time, the source media, and
+// an ffprobe of the output, which is returned for codec reporting. Probe
+// failures are logged, leave measured fields zero, and return a nil probe.
+func measureEncode(outputPath string, media *analyzer.MediaInfo, wall time.Duration, logger TranscodeLogger) (EncodeStats, *analyzer.OutputProbe) {
+	stats := EncodeStats{WallTime: wall}
+	if secs := wall.Seconds(); secs > 0 {
+		stats.RealtimeFactor = media.Duration / secs
+		stats.AvgFPS = media.Duration * media.Framerate / secs
+	}
+
+	probe, err := analyzer.ProbeOutput(outputPath)
+	if err != nil {
+		logger.LogError("stats", err)
+		if fi, statErr := os.Stat(outputPath); statErr == nil {
+			stats.FileSize = fi.Size()
+		}
+		return stats, nil
+	}
+	stats.FileSize = probe.Size
+	stats.Duration = probe.Duration
+	stats.MeasuredBitrate = probe.Bitrate
+	if stats.MeasuredBitrate == 0 && probe.Duration > 0 {
+		stats.MeasuredBitrate = int(float64(probe.Size) * 8 / probe.Duration / 1000)
+	}
+	return stats, probe
 }
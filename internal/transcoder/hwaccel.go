@@ -0,0 +1,183 @@
+package transcoder
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+)
+
+// Hardware acceleration backends accepted in TranscodeProfile.HWAccel.
+const (
+	HWAccelAuto         = "auto"         // First backend available on this OS (default)
+	HWAccelVideoToolbox = "videotoolbox" // Apple VideoToolbox (macOS)
+	HWAccelNVENC        = "nvenc"        // NVIDIA NVENC with CUDA decoding (Linux, Windows)
+	HWAccelQSV          = "qsv"          // Intel Quick Sync Video (Linux, Windows)
+	HWAccelVAAPI        = "vaapi"        // VA-API on Intel/AMD render nodes (Linux)
+	HWAccelAMF          = "amf"          // AMD AMF with D3D11VA decoding (Windows)
+)
+
+// vaapiRenderNode is the DRM render node used for VA-API encoding.
+const vaapiRenderNode = "/dev/dri/renderD128"
+
+// hwAccelConfig describes how one backend is wired into an ffmpeg command.
+type hwAccelConfig struct {
+	Name   string   // Backend name (HWAccelNVENC, ...)
+	Label  string   // Human-readable name for logs
+	Suffix string   // Encoder suffix appended to the codec family (e.g. "_nvenc")
+	Decode []string // Input options placed before -i
+	Device string   // Device node that must exist, or "" when none is needed
+	Upload bool     // Frames must be uploaded to the device after software filters
+}
+
+// Encoder returns the ffmpeg encoder name for a codec family (e.g. "h264_nvenc").
+func (c hwAccelConfig) Encoder(family string) string {
+	return family + c.Suffix
+}
+
+// hwAccelByOS lists the backends tried on each OS, in order of preference.
+// Decoding stays on the GPU where the platform has a matching hwaccel, but
+// frames are downloaded for the software scale/deinterlace filters.
+var hwAccelByOS = map[string][]hwAccelConfig{
+	"darwin": {
+		{Name: HWAccelVideoToolbox, Label: "🍎 VideoToolbox", Suffix: "_videotoolbox", Decode: []string{"-hwaccel", "videotoolbox"}},
+	},
+	"linux": {
+		{Name: HWAccelNVENC, Label: "🟩 NVENC", Suffix: "_nvenc", Decode: []string{"-hwaccel", "cuda"}},
+		{Name: HWAccelQSV, Label: "🟦 Quick Sync", Suffix: "_qsv", Decode: []string{"-hwaccel", "qsv"}},
+		{Name: HWAccelVAAPI, Label: "🐧 VA-API", Suffix: "_vaapi", Decode: []string{"-vaapi_device", vaapiRenderNode, "-hwaccel", "vaapi"}, Device: vaapiRenderNode, Upload: true},
+	},
+	"windows": {
+		{Name: HWAccelNVENC, Label: "🟩 NVENC", Suffix: "_nvenc", Decode: []string{"-hwaccel", "cuda"}},
+		{Name: HWAccelQSV, Label: "🟦 Quick Sync", Suffix: "_qsv", Decode: []string{"-hwaccel", "d3d11va"}},
+		{Name: HWAccelAMF, Label: "🟥 AMF", Suffix: "_amf", Decode: []string{"-hwaccel", "d3d11va"}},
+	},
+}
+
+// hwAccelFamilies are the codec families with hardware encoders.
+var hwAccelFamilies = []string{"h264", "hevc", "av1"}
+
+// HWProber reports what the local ffmpeg build and machine support. The
+// default runs "ffmpeg -encoders" once; tests and embedders can install their
+// own with SetHWProber.
+type HWProber interface {
+	Encoders() map[string]bool        // Encoder names compiled into ffmpeg
+	DeviceAvailable(path string) bool // Whether a device node (e.g. a DRM render node) exists
+}
+
+var (
+	hwProberMu sync.RWMutex
+	hwProber   HWProber = &ffmpegHWProber{}
+)
+
+// SetHWProber installs the process-wide prober used to pick hardware encoders.
+// Pass nil to restore the default ffmpeg-based prober.
+func SetHWProber(p HWProber) {
+	hwProberMu.Lock()
+	defer hwProberMu.Unlock()
+	if p == nil {
+		p = &ffmpegHWProber{}
+	}
+	hwProber = p
+}
+
+// DefaultHWProber returns the process-wide hardware prober.
+func DefaultHWProber() HWProber {
+	hwProberMu.RLock()
+	defer hwProberMu.RUnlock()
+	return hwProber
+}
+
+// ffmpegHWProber asks the local ffmpeg binary which encoders it was built with.
+// The encoder list is read once and cached for the life of the process.
+type ffmpegHWProber struct {
+	once     sync.Once
+	encoders map[string]bool
+}
+
+// Encoders returns the encoders reported by "ffmpeg -encoders", or an empty
+// set if ffmpeg cannot be run.
+func (p *ffmpegHWProber) Encoders() map[string]bool {
+	p.once.Do(func() {
+		out, err := exec.Command("ffmpeg", "-hide_banner", "-encoders").Output()
+		if err != nil {
+			p.encoders = map[string]bool{}
+			return
+		}
+		p.encoders = parseEncoders(out)
+	})
+	return p.encoders
+}
+
+// DeviceAvailable reports whether path exists.
+func (p *ffmpegHWProber) DeviceAvailable(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// parseEncoders extracts encoder names from "ffmpeg -encoders" output, whose
+// entries look like " V....D h264_nvenc           NVIDIA NVENC H.264 encoder".
+func parseEncoders(out []byte) map[string]bool {
+	encoders := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || len(fields[0]) != 6 || strings.Trim(fields[0], "VASFXBD.") != "" {
+			continue
+		}
+		if fields[1] == "=" {
+			continue // legend line
+		}
+		encoders[fields[1]] = true
+	}
+	return encoders
+}
+
+// selectHWAccel picks the hardware backend for videoCodec on goos. preferred
+// restricts the choice to one backend ("" or "auto" tries each in order).
+// Returns false when the codec has no hardware encoder, the codec already names
+// a specific encoder, or nothing usable is installed.
+func selectHWAccel(goos, videoCodec, preferred string, prober HWProber) (hwAccelConfig, string, bool) {
+	family := helpers.CodecFamily(videoCodec)
+	if !contains(hwAccelFamilies, family) || isHardwareEncoder(videoCodec) {
+		return hwAccelConfig{}, "", false
+	}
+	encoders := prober.Encoders()
+	for _, cfg := range hwAccelByOS[goos] {
+		if preferred != "" && preferred != HWAccelAuto && preferred != cfg.Name {
+			continue
+		}
+		encoder := cfg.Encoder(family)
+		if !encoders[encoder] {
+			continue
+		}
+		if cfg.Device != "" && !prober.DeviceAvailable(cfg.Device) {
+			continue
+		}
+		return cfg, encoder, true
+	}
+	return hwAccelConfig{}, "", false
+}
+
+// hwAccelFor selects the backend for encoding videoCodec with profile on the
+// running OS, or reports false if hardware acceleration is disabled or unavailable.
+func hwAccelFor(profile *TranscodeProfile, videoCodec string) (hwAccelConfig, string, bool) {
+	if !profile.UseHardwareAccel {
+		return hwAccelConfig{}, "", false
+	}
+	return selectHWAccel(runtime.GOOS, videoCodec, profile.HWAccel, DefaultHWProber())
+}
+
+// hwAccelBackends lists the backend names known on goos.
+func hwAccelBackends(goos string) []string {
+	var names []string
+	for _, cfg := range hwAccelByOS[goos] {
+		names = append(names, cfg.Name)
+	}
+	return names
+}
@@ -0,0 +1,193 @@
+package transcoder
+
+import (
+	"runtime"
+	"testing"
+)
+
+// fakeHWProber reports a fixed encoder list and set of device nodes.
+type fakeHWProber struct {
+	encoders []string
+	devices  []string
+}
+
+func (f fakeHWProber) Encoders() map[string]bool {
+	m := make(map[string]bool, len(f.encoders))
+	for _, e := range f.encoders {
+		m[e] = true
+	}
+	return m
+}
+
+func (f fakeHWProber) DeviceAvailable(path string) bool {
+	return contains(f.devices, path)
+}
+
+func TestSelectHWAccel(t *testing.T) {
+	tests := []struct {
+		name        string
+		goos        string
+		codec       string
+		preferred   string
+		prober      fakeHWProber
+		wantBackend string
+		wantEncoder string
+	}{
+		{
+			name:        "nvenc first on linux",
+			goos:        "linux",
+			codec:       "libx264",
+			prober:      fakeHWProber{encoders: []string{"h264_nvenc", "h264_qsv", "h264_vaapi"}, devices: []string{vaapiRenderNode}},
+			wantBackend: HWAccelNVENC,
+			wantEncoder: "h264_nvenc",
+		},
+		{
+			name:        "falls back to qsv without nvenc",
+			goos:        "linux",
+			codec:       "hevc",
+			prober:      fakeHWProber{encoders: []string{"hevc_qsv", "hevc_vaapi"}},
+			wantBackend: HWAccelQSV,
+			wantEncoder: "hevc_qsv",
+		},
+		{
+			name:        "vaapi needs its render node",
+			goos:        "linux",
+			codec:       "h264",
+			prober:      fakeHWProber{encoders: []string{"h264_vaapi"}},
+			wantBackend: "",
+		},
+		{
+			name:        "vaapi with render node",
+			goos:        "linux",
+			codec:       "h264",
+			prober:      fakeHWProber{encoders: []string{"h264_vaapi"}, devices: []string{vaapiRenderNode}},
+			wantBackend: HWAccelVAAPI,
+			wantEncoder: "h264_vaapi",
+		},
+		{
+			name:        "preferred backend restricts the choice",
+			goos:        "linux",
+			codec:       "libx264",
+			preferred:   HWAccelQSV,
+			prober:      fakeHWProber{encoders: []string{"h264_nvenc", "h264_qsv"}},
+			wantBackend: HWAccelQSV,
+			wantEncoder: "h264_qsv",
+		},
+		{
+			name:        "preferred backend missing falls back to software",
+			goos:        "linux",
+			codec:       "libx264",
+			preferred:   HWAccelNVENC,
+			prober:      fakeHWProber{encoders: []string{"h264_qsv"}},
+			wantBackend: "",
+		},
+		{
+			name:        "auto tries every backend",
+			goos:        "windows",
+			codec:       "libx264",
+			preferred:   HWAccelAuto,
+			prober:      fakeHWProber{encoders: []string{"h264_amf"}},
+			wantBackend: HWAccelAMF,
+			wantEncoder: "h264_amf",
+		},
+		{
+			name:        "videotoolbox on darwin",
+			goos:        "darwin",
+			codec:       "libx265",
+			prober:      fakeHWProber{encoders: []string{"hevc_videotoolbox"}},
+			wantBackend: HWAccelVideoToolbox,
+			wantEncoder: "hevc_videotoolbox",
+		},
+		{
+			name:        "backend of another os is ignored",
+			goos:        "darwin",
+			codec:       "libx264",
+			prober:      fakeHWProber{encoders: []string{"h264_nvenc"}},
+			wantBackend: "",
+		},
+		{
+			name:        "av1 on nvenc",
+			goos:        "linux",
+			codec:       "libsvtav1",
+			prober:      fakeHWProber{encoders: []string{"av1_nvenc"}},
+			wantBackend: HWAccelNVENC,
+			wantEncoder: "av1_nvenc",
+		},
+		{
+			name:        "codec without hardware encoders",
+			goos:        "linux",
+			codec:       "vp9",
+			prober:      fakeHWProber{encoders: []string{"h264_nvenc", "vp9_qsv"}},
+			wantBackend: "",
+		},
+		{
+			name:        "explicit hardware encoder is left alone",
+			goos:        "linux",
+			codec:       "h264_qsv",
+			prober:      fakeHWProber{encoders: []string{"h264_nvenc", "h264_qsv"}},
+			wantBackend: "",
+		},
+		{
+			name:        "nothing installed",
+			goos:        "linux",
+			codec:       "libx264",
+			prober:      fakeHWProber{},
+			wantBackend: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, encoder, ok := selectHWAccel(tt.goos, tt.codec, tt.preferred, tt.prober)
+			if ok != (tt.wantBackend != "") {
+				t.Fatalf("selectHWAccel ok = %v, want %v (backend %q)", ok, !ok, cfg.Name)
+			}
+			if cfg.Name != tt.wantBackend || encoder != tt.wantEncoder {
+				t.Errorf("selectHWAccel = %q, %q; want %q, %q", cfg.Name, encoder, tt.wantBackend, tt.wantEncoder)
+			}
+		})
+	}
+}
+
+func TestHWAccelForUsesInstalledProber(t *testing.T) {
+	backends := hwAccelByOS[runtime.GOOS]
+	if len(backends) == 0 {
+		t.Skipf("no hardware backends on %s", runtime.GOOS)
+	}
+	want := backends[0]
+	SetHWProber(fakeHWProber{encoders: []string{want.Encoder("h264")}, devices: []string{want.Device}})
+	defer SetHWProber(nil)
+
+	profile := &TranscodeProfile{UseHardwareAccel: true, VideoCodec: "libx264"}
+	cfg, encoder, ok := hwAccelFor(profile, profile.VideoCodec)
+	if !ok || cfg.Name != want.Name || encoder != want.Encoder("h264") {
+		t.Errorf("hwAccelFor = %q, %q, %v; want %q, %q", cfg.Name, encoder, ok, want.Name, want.Encoder("h264"))
+	}
+
+	profile.UseHardwareAccel = false
+	if _, _, ok := hwAccelFor(profile, profile.VideoCodec); ok {
+		t.Error("hwAccelFor selected a backend with hardware acceleration disabled")
+	}
+}
+
+func TestParseEncoders(t *testing.T) {
+	out := []byte(`Encoders:
+ V..... = Video
+ A..... = Audio
+ ------
+ V....D libx264              libx264 H.264 / AVC / MPEG-4 AVC (codec h264)
+ V....D h264_nvenc           NVIDIA NVENC H.264 encoder (codec h264)
+ V..... hevc_vaapi           H.265/HEVC (VAAPI) (codec hevc)
+ A....D aac                  AAC (Advanced Audio Coding)
+`)
+	got := parseEncoders(out)
+	for _, name := range []string{"libx264", "h264_nvenc", "hevc_vaapi", "aac"} {
+		if !got[name] {
+			t.Errorf("parseEncoders missed %q", name)
+		}
+	}
+	for _, name := range []string{"=", "Video", "Encoders:", "------"} {
+		if got[name] {
+			t.Errorf("parseEncoders reported %q", name)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package transcoder
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/langtag"
+)
+
+// ErrLanguageMissing is wrapped by ApplyLanguages errors.
+var ErrLanguageMissing = errors.New("track language missing")
+
+// LanguageSettings fixes the language tags published for audio and subtitle
+// tracks (TranscodeProfile.Languages). The analyzer already normalizes the
+// container's tags to RFC 5646 ("eng" becomes "en"); these settings fill in
+// the streams a source leaves untagged and correct mislabeled ones, so no
+// track reaches the manifests or metadata.json as "und".
+type LanguageSettings struct {
+	Default string         `json:"default,omitempty" yaml:"default,omitempty"` // Tag for audio and subtitle streams the source leaves untagged or "und" (e.g. "en")
+	Streams map[int]string `json:"streams,omitempty" yaml:"streams,omitempty"` // Tags by source stream index, overriding the container's
+	Require bool           `json:"require,omitempty" yaml:"require,omitempty"` // Fail rather than publish an audio or subtitle track without a language
+}
+
+// StreamLanguage returns the RFC 5646 tag published for source stream s: a
+// Streams override, else the container's tag, else the profile's Default for
+// audio and subtitle streams. Returns "" when none applies.
+func (p *TranscodeProfile) StreamLanguage(s analyzer.StreamInfo) string {
+	l := p.Languages
+	if l == nil {
+		return langtag.Normalize(s.Language)
+	}
+	if tag := langtag.Normalize(l.Streams[s.Index]); tag != "" {
+		return tag
+	}
+	if tag := langtag.Normalize(s.Language); tag != "" {
+		return tag
+	}
+	if s.Type == analyzer.StreamAudio || s.Type == analyzer.StreamSubtitle {
+		return langtag.Normalize(l.Default)
+	}
+	return ""
+}
+
+// ApplyLanguages rewrites the stream languages in media with the profile's
+// overrides and default (see StreamLanguage). With Require set, it returns an
+// error wrapping ErrLanguageMissing naming the published streams still
+// without a language: the audio the variants and renditions are made from,
+// and the subtitle streams extracted or kept as forced narrative.
+func (p *TranscodeProfile) ApplyLanguages(media *analyzer.MediaInfo) error {
+	if media == nil {
+		return nil
+	}
+	// The slice may be shared with a cached analysis
+	media.Streams = slices.Clone(media.Streams)
+	for i := range media.Streams {
+		media.Streams[i].Language = p.StreamLanguage(media.Streams[i])
+	}
+	if p.Languages == nil || !p.Languages.Require {
+		return nil
+	}
+	if missing := p.untaggedStreams(media); len(missing) > 0 {
+		return fmt.Errorf("%w: %s (set languages.default or languages.streams)", ErrLanguageMissing, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// untaggedStreams describes the published audio and subtitle streams in
+// media without a language, with the profile's overrides applied.
+func (p *TranscodeProfile) untaggedStreams(media *analyzer.MediaInfo) []string {
+	var indexes []int
+	if primary := media.PrimaryAudio(); primary != nil {
+		indexes = append(indexes, primary.Index)
+	}
+	for _, a := range p.AudioRenditions {
+		if a.File != "" {
+			continue
+		}
+		if s := a.AudioSource(media); s != nil && !slices.Contains(indexes, s.Index) {
+			indexes = append(indexes, s.Index)
+		}
+	}
+	for _, s := range media.SubtitleTracks() {
+		embedded := p.Subtitles != nil && p.Subtitles.Embedded && s.IsTextSubtitle()
+		if embedded || s.Forced && p.ForcedSubtitleMode() != ForcedOff {
+			indexes = append(indexes, s.Index)
+		}
+	}
+
+	var missing []string
+	for _, s := range media.Streams {
+		if slices.Contains(indexes, s.Index) && p.StreamLanguage(s) == "" {
+			missing = append(missing, fmt.Sprintf("%s stream %d", s.Type, s.Index))
+		}
+	}
+	return missing
+}
+
+// checkLanguageTag reports tag at field when it isn't a well-formed RFC 5646
+// tag naming a language ("und" doesn't). Tags in a non-canonical form (e.g.
+// "eng") are published normalized and pass.
+func checkLanguageTag(r *ValidationReport, severity Severity, field, tag string) {
+	if tag != "" && !langtag.Valid(tag) {
+		r.add(severity, field, "%q is not an RFC 5646 language tag (want e.g. \"en\" or \"pt-BR\")", tag)
+	}
+}
+
+// validateLanguages checks the language settings and the tags given for
+// audio renditions and subtitle files, and with media, that the published
+// tracks will carry a language.
+func validateLanguages(p TranscodeProfile, media *analyzer.MediaInfo, r *ValidationReport) {
+	for i, a := range p.AudioRenditions {
+		checkLanguageTag(r, SeverityWarning, fmt.Sprintf("audio_renditions[%d].language", i), a.Language)
+	}
+	if p.Subtitles != nil {
+		for i, f := range p.Subtitles.Files {
+			checkLanguageTag(r, SeverityWarning, fmt.Sprintf("subtitles.files[%d].language", i), f.Language)
+		}
+	}
+
+	l := p.Languages
+	if l == nil {
+		if media != nil {
+			if missing := p.untaggedStreams(media); len(missing) > 0 {
+				r.add(SeverityWarning, "languages", "%s without a language will be published untagged; set languages.default or languages.streams", strings.Join(missing, ", "))
+			}
+		}
+		return
+	}
+	checkLanguageTag(r, SeverityError, "languages.default", l.Default)
+	for _, index := range slices.Sorted(maps.Keys(l.Streams)) {
+		field := fmt.Sprintf("languages.streams[%d]", index)
+		checkLanguageTag(r, SeverityError, field, l.Streams[index])
+		if media == nil {
+			continue
+		}
+		if i := slices.IndexFunc(media.Streams, func(s analyzer.StreamInfo) bool { return s.Index == index }); i < 0 {
+			r.add(SeverityWarning, field, "source has no stream %d", index)
+		} else if t := media.Streams[i].Type; t != analyzer.StreamAudio && t != analyzer.StreamSubtitle {
+			r.add(SeverityWarning, field, "stream %d is %s, not audio or subtitles", index, t)
+		}
+	}
+
+	if !l.Require {
+		return
+	}
+	for i, a := range p.AudioRenditions {
+		if a.File != "" && langtag.Normalize(a.Language) == "" {
+			r.add(SeverityError, fmt.Sprintf("audio_renditions[%d].language", i), "languages.require is set; an external audio file needs a language")
+		}
+	}
+	if p.Subtitles != nil {
+		for i, f := range p.Subtitles.Files {
+			if langtag.Normalize(f.Language) == "" {
+				r.add(SeverityError, fmt.Sprintf("subtitles.files[%d].language", i), "languages.require is set; a subtitle file needs a language")
+			}
+		}
+	}
+	if media != nil {
+		if missing := p.untaggedStreams(media); len(missing) > 0 {
+			r.add(SeverityError, "languages", "languages.require is set; %s has no language", strings.Join(missing, ", "))
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package transcoder
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrLadderIncomplete is wrapped by CheckMinimumLadder errors.
+var ErrLadderIncomplete = errors.New("minimum ladder not met")
+
+// MinimumLadder is the ladder that must be ready before the master manifest
+// is written (TranscodeProfile.MinimumLadder). When too few rungs or a
+// required label survived encoding and segmentation, the run fails instead
+// of publishing a master that points at a half-broken ladder.
+type MinimumLadder struct {
+	Rungs    int      `json:"rungs,omitempty" yaml:"rungs,omitempty"`       // Variants that must have been segmented, across codec tiers
+	Required []string `json:"required,omitempty" yaml:"required,omitempty"` // Resolution labels that must be among them (e.g. ["480p", "1080p"])
+}
+
+// CheckMinimumLadder returns an error wrapping ErrLadderIncomplete when
+// variants fall short of the profile's minimum ladder, or nil when it is met
+// or unset.
+func (p *TranscodeProfile) CheckMinimumLadder(variants []ResolutionVariant) error {
+	m := p.MinimumLadder
+	if m == nil {
+		return nil
+	}
+	var problems []string
+	if len(variants) < m.Rungs {
+		problems = append(problems, fmt.Sprintf("only %d rung(s) ready, %d required", len(variants), m.Rungs))
+	}
+	var missing []string
+	for _, label := range m.Required {
+		height, _ := labelHeight(label)
+		if !slices.ContainsFunc(variants, func(v ResolutionVariant) bool { return v.Height == height }) {
+			missing = append(missing, label)
+		}
+	}
+	if len(missing) > 0 {
+		problems = append(problems, "missing required "+strings.Join(missing, ", "))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrLadderIncomplete, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// labelHeight parses a resolution label like "1080p" into its height.
+func labelHeight(label string) (int, bool) {
+	h, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(label)), "p"))
+	return h, err == nil && h > 0
+}
+
+// validateMinimumLadder checks the minimum ladder against the profile's own
+// ladder: a requirement the ladder can never meet fails every run.
+func validateMinimumLadder(p TranscodeProfile, r *ValidationReport) {
+	m := p.MinimumLadder
+	if m == nil {
+		return
+	}
+	if m.Rungs < 0 {
+		r.add(SeverityError, "minimum_ladder.rungs", "rungs must be zero or positive")
+	} else if m.Rungs > len(p.Variants) {
+		r.add(SeverityWarning, "minimum_ladder.rungs", "%d rungs required but the ladder has %d variants", m.Rungs, len(p.Variants))
+	}
+	for i, label := range m.Required {
+		field := fmt.Sprintf("minimum_ladder.required[%d]", i)
+		height, ok := labelHeight(label)
+		if !ok {
+			r.add(SeverityError, field, "invalid resolution label %q (want e.g. \"1080p\")", label)
+			continue
+		}
+		if !slices.ContainsFunc(p.Variants, func(v Variant) bool {
+			h, ok := labelHeight(v.Resolution)
+			return ok && h == height
+		}) {
+			r.add(SeverityError, field, "required %s is not in the ladder", label)
+		}
+	}
+	if m.Rungs == 0 && len(m.Required) == 0 {
+		r.add(SeverityWarning, "minimum_ladder", "neither rungs nor required is set; manifests are always written")
+	}
+}
@@ -0,0 +1,66 @@
+package transcoder
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// Packagers accepted in TranscodeProfile.Packager.
+const (
+	PackagerFFmpeg = "ffmpeg" // ffmpeg's HLS and DASH muxers (default)
+	PackagerShaka  = "shaka"  // Shaka Packager: CMAF, SAMPLE-AES/cbcs HLS and CENC DASH encryption
+	PackagerBento4 = "bento4" // Bento4 mp42hls and mp4fragment/mp4dash: AES-128 HLS and CENC DASH encryption
+	PackagerNative = "native" // Built-in Go segmenter: stream-copies MP4 variants into HLS without spawning a process
+	PackagerAuto   = "auto"   // Shaka Packager, then Bento4, when installed and able to package the run; else ffmpeg
+)
+
+// packagerBinaries lists the executables each packager needs on PATH.
+var packagerBinaries = map[string][]string{
+	PackagerFFmpeg: {"ffmpeg"},
+	PackagerShaka:  {"packager"},
+	PackagerBento4: {"mp42hls", "mp4fragment", "mp4dash"},
+	PackagerNative: nil,
+}
+
+// PackagerMode returns the profile's packager, defaulting to PackagerFFmpeg.
+func (p *TranscodeProfile) PackagerMode() string {
+	if p.Packager == "" {
+		return PackagerFFmpeg
+	}
+	return p.Packager
+}
+
+// PackagerAvailable reports whether every executable the named packager
+// runs is on PATH.
+func PackagerAvailable(name string) bool {
+	binaries, ok := packagerBinaries[name]
+	if !ok {
+		return false
+	}
+	for _, bin := range binaries {
+		if _, err := exec.LookPath(bin); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// validatePackager checks the packager name and that an explicitly chosen
+// one is installed.
+func validatePackager(p TranscodeProfile, r *ValidationReport) {
+	switch p.Packager {
+	case "":
+		r.defaulted("packager", PackagerFFmpeg)
+	case PackagerFFmpeg, PackagerAuto:
+	case PackagerShaka, PackagerBento4:
+		if !PackagerAvailable(p.Packager) {
+			r.add(SeverityWarning, "packager", "%s not found on PATH (needs %s); segmenting will use ffmpeg", p.Packager, strings.Join(packagerBinaries[p.Packager], ", "))
+		}
+	case PackagerNative:
+		if c := strings.ToLower(p.Container); c != "" && c != "mp4" && c != "mov" {
+			r.add(SeverityWarning, "packager", "the native segmenter reads MP4 only, not %s variants; segmenting will use ffmpeg", p.Container)
+		}
+	default:
+		r.add(SeverityError, "packager", "unknown packager %q (want ffmpeg, shaka, bento4, native, or auto)", p.Packager)
+	}
+}
@@ -0,0 +1,85 @@
+package transcoder
+
+import "fmt"
+
+// Partial-failure policies accepted in PartialFailureSettings.Policy.
+const (
+	PartialPublish  = "publish"   // Finish the other encodes and publish the variants that succeeded, marking the run degraded (default)
+	PartialFailFast = "fail_fast" // Cancel the other encodes and fail the transcode at the first failed variant
+	PartialMinRungs = "min_rungs" // Like publish, but fail the transcode when fewer than MinRungs variants succeed
+)
+
+// PartialFailureSettings decides what a transcode does when some variants
+// fail to encode (TranscodeProfile.PartialFailure). Whatever the policy, a
+// transcode with no successful variant fails.
+type PartialFailureSettings struct {
+	Policy   string `json:"policy,omitempty" yaml:"policy,omitempty"`       // "publish" (default), "fail_fast", or "min_rungs"
+	MinRungs int    `json:"min_rungs,omitempty" yaml:"min_rungs,omitempty"` // min_rungs: variants that must succeed, counting kept ones
+}
+
+// PartialFailureSettings returns the profile's partial-failure settings with
+// defaults applied. MinRungs is 1 unless the min_rungs policy sets it.
+func (p *TranscodeProfile) PartialFailureSettings() PartialFailureSettings {
+	var s PartialFailureSettings
+	if p.PartialFailure != nil {
+		s = *p.PartialFailure
+	}
+	if s.Policy == "" {
+		s.Policy = PartialPublish
+	}
+	if s.Policy != PartialMinRungs || s.MinRungs < 1 {
+		s.MinRungs = 1
+	}
+	return s
+}
+
+// ApplyPartialPolicy decides a finished transcode with failed variants per
+// the profile's partial-failure policy: it returns an error wrapping the
+// first failure when the policy fails the run, and otherwise marks the
+// result degraded so only the successful variants are published.
+func (r *TranscodeResult) ApplyPartialPolicy() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	s := r.Profile.PartialFailureSettings()
+	var reason string
+	switch succeeded := len(r.Variants); {
+	case s.Policy == PartialFailFast:
+		reason = fmt.Sprintf("%d variant(s) failed under the fail_fast policy", len(r.Errors))
+	case succeeded < s.MinRungs:
+		reason = fmt.Sprintf("only %d of %d variants succeeded; at least %d required", succeeded, succeeded+len(r.Errors), s.MinRungs)
+	}
+	if reason != "" {
+		return NewTranscoderError(
+			"execution", "partial_failure", r.InputPath, r.OutputDir,
+			reason, nil, 0, r.Errors[0],
+		)
+	}
+	r.Degraded = true
+	return nil
+}
+
+// validatePartialFailure checks the partial-failure settings.
+func validatePartialFailure(p TranscodeProfile, r *ValidationReport) {
+	s := p.PartialFailure
+	if s == nil {
+		return
+	}
+	switch s.Policy {
+	case "":
+		r.defaulted("partial_failure.policy", PartialPublish)
+	case PartialPublish, PartialFailFast:
+		if s.MinRungs != 0 {
+			r.add(SeverityWarning, "partial_failure.min_rungs", "min_rungs only applies to the min_rungs policy")
+		}
+	case PartialMinRungs:
+		switch {
+		case s.MinRungs < 1:
+			r.add(SeverityError, "partial_failure.min_rungs", "the min_rungs policy needs min_rungs of at least 1")
+		case s.MinRungs > len(p.Variants):
+			r.add(SeverityWarning, "partial_failure.min_rungs", "min_rungs %d exceeds the %d variants in the ladder", s.MinRungs, len(p.Variants))
+		}
+	default:
+		r.add(SeverityError, "partial_failure.policy", "unknown partial-failure policy %q (want publish, fail_fast, or min_rungs)", s.Policy)
+	}
+}
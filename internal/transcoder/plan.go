@@ -0,0 +1,162 @@
+package transcoder
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/scaler"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
+)
+
+// PlannedVariant describes a single variant encode without executing it.
+// Produced by PlanTranscode and consumed by Transcode and dry-run reporting.
+type PlannedVariant struct {
+	Key            string   // Unique variant key (e.g. "720p_3000k")
+	Variant        Variant  // Profile entry this plan was derived from
+	Width          int      // Output width in pixels
+	Height         int      // Output height in pixels
+	Codec          string   // Video codec family for this encode (e.g. "hevc")
+	Tier           string   // Secondary codec tier, "" for the primary codec
+	OutputFilename string   // Output filename inside the slug directory
+	OutputPath     string   // Full output path
+	Command        []string // ffmpeg command that will be executed
+	Threads        int      // Encoder threads (-threads); 0 lets ffmpeg decide
+	Lookahead      int      // x264/x265 lookahead in frames; 0 keeps the encoder default
+}
+
+// TranscodePlan captures every encode Transcode would run for a profile.
+// Building a plan has no side effects, so it is safe for dry runs.
+type TranscodePlan struct {
+	Slug     string           // Slug derived from the input filename
+	SlugDir  string           // Output directory for this slug
+	Variants []PlannedVariant // Encodes to run, in profile order
+	Skipped  []string         // Human-readable reasons for skipped variants
+	Adjusted []string         // Human-readable changes made to variants (e.g. bitrates capped to the source)
+	Err      error            // Set when the low-source policy rejects the ladder; Variants is then empty
+}
+
+// PlanTranscode filters the profile's variants against the source resolution,
+// removes duplicates, applies the low-source policy (see LowSourceSettings)
+// and ladder trimming (see LadderTrim), and builds the ffmpeg command for each
+// remaining variant. No files or directories are created.
+func PlanTranscode(profile *TranscodeProfile, media *analyzer.MediaInfo, logger TranscodeLogger) *TranscodePlan {
+	logger = stagelog.OrStd(logger)
+
+	// Derive slug from input filename and output directory from the layout
+	slug := profile.OutputSlug()
+	plan := &TranscodePlan{
+		Slug:    slug,
+		SlugDir: profile.Layout().For(profile.InputPath, slug).SlugDir(profile.OutputDir),
+	}
+
+	var rungs, above []budgetRung
+	seen := make(map[string]bool)
+	for _, v := range profile.Variants {
+		width, height, err := scaler.DimensionsForLabel(v.Resolution)
+		if err != nil {
+			logger.LogVariant(v.Resolution, "⚠️ Unknown resolution label - skipping")
+			plan.Skipped = append(plan.Skipped, fmt.Sprintf("%s: unknown resolution label", v.Resolution))
+			continue
+		}
+
+		// Keep the target bitrate within the source's before keys are derived from it
+		v, note := profile.capBitrate(v, media, logger)
+		if note != "" {
+			plan.Adjusted = append(plan.Adjusted, note)
+		}
+
+		// Ensure variant is not duplicated; rungs of other codec tiers are distinct
+		tier := profile.codecTier(v)
+		key := fmt.Sprintf("%s_%s", v.Resolution, v.Bitrate)
+		if tier != "" {
+			key += "_" + tier
+		}
+		if seen[key] {
+			logger.LogVariant(key, "⚠️ Skipping duplicate variant")
+			plan.Skipped = append(plan.Skipped, fmt.Sprintf("%s: duplicate variant", key))
+			continue
+		}
+		seen[key] = true
+
+		// Resolutions that exceed the source height are left to the low-source policy
+		rung := budgetRung{Key: key, Variant: v, Width: width, Height: height}
+		if height > media.Height {
+			above = append(above, rung)
+			continue
+		}
+		rungs = append(rungs, rung)
+	}
+	rungs, skipped, err := profile.fallbackLadder(rungs, above, seen, media, logger)
+	plan.Skipped = append(plan.Skipped, skipped...)
+	if err != nil {
+		logger.LogError("filter", err)
+		plan.Err = err
+		return plan
+	}
+	rungs, trimmed := profile.trimLadder(rungs, media, logger)
+	plan.Skipped = append(plan.Skipped, trimmed...)
+
+	// Every encode runs at once, so threads, memory, and source reads are
+	// split across them
+	resources := profile.allocateResources(rungs, logger)
+	readRate := profile.ReadRate(media, len(rungs))
+	for i := range resources {
+		resources[i].ReadRate = readRate
+	}
+	for i, r := range rungs {
+		v, key, tier := r.Variant, r.Key, profile.codecTier(r.Variant)
+
+		// Build output path and ffmpeg command
+		outputFilename := fmt.Sprintf("%s_%s_%sbps.mp4", slug, v.Resolution, v.Bitrate)
+		if tier != "" {
+			outputFilename = fmt.Sprintf("%s_%s_%sbps_%s.mp4", slug, v.Resolution, v.Bitrate, tier)
+		}
+		outputPath := filepath.Join(plan.SlugDir, outputFilename)
+		cmd := buildFFmpegCommand(profile, v, resources[i], media, logger)
+		cmd[len(cmd)-1] = outputPath
+
+		plan.Variants = append(plan.Variants, PlannedVariant{
+			Key:            key,
+			Variant:        v,
+			Width:          r.Width,
+			Height:         r.Height,
+			Codec:          helpers.CodecFamily(profile.variantCodec(v)),
+			Tier:           tier,
+			OutputFilename: outputFilename,
+			OutputPath:     outputPath,
+			Command:        cmd,
+			Threads:        resources[i].Threads,
+			Lookahead:      resources[i].Lookahead,
+		})
+	}
+
+	return plan
+}
+
+// Result returns the TranscodeResult that would be produced if every planned
+// variant succeeded. Used to plan downstream stages during dry runs.
+func (p *TranscodePlan) Result(profile *TranscodeProfile, media *analyzer.MediaInfo) *TranscodeResult {
+	result := &TranscodeResult{
+		InputPath:   profile.InputPath,
+		OutputDir:   p.SlugDir,
+		Duration:    media.Duration,
+		Success:     true,
+		Profile:     profile,
+		Adjustments: p.Adjusted,
+	}
+	for _, v := range p.Variants {
+		result.Variants = append(result.Variants, ResolutionVariant{
+			Width:          v.Width,
+			Height:         v.Height,
+			Bitrate:        v.Variant.Bitrate,
+			ScaleFlag:      "auto",
+			OutputFilename: v.OutputFilename,
+			Codec:          v.Codec,
+			Tier:           v.Tier,
+			Codecs:         rfc6381Codecs(v.Codec, v.Height, nil, profile.AudioCodec, profile.Container),
+		})
+	}
+	return result
+}
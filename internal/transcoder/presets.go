@@ -0,0 +1,96 @@
+package transcoder
+
+import (
+	"embed"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+//go:embed presets/*.yaml
+var presetFiles embed.FS
+
+// presetPathPrefix marks the path reported in errors for built-in presets.
+const presetPathPrefix = "preset:"
+
+// PresetInfo describes a built-in profile.
+type PresetInfo struct {
+	Name        string
+	Description string
+}
+
+// builtinPresets lists the built-in profiles, from highest to lowest quality.
+// Each has a presets/<name>.yaml ladder with codecs and rate control.
+var builtinPresets = []PresetInfo{
+	{"archive", "HEVC 2160p-720p at high bitrates, slow encodes, checksums; for long-term storage"},
+	{"streaming-high", "H.264 1080p-360p with capped peaks; broadly compatible on-demand streaming"},
+	{"webm-vp9-dash", "VP9/Opus WebM 1080p-360p as DASH; royalty-free delivery without H.264"},
+	{"mobile-data-saver", "H.264 480p-144p with tight peaks and 64k audio; for metered connections"},
+}
+
+// Presets returns the built-in profiles accepted wherever a profile filename
+// is (the CLI's -profile, Config.ProfilePath, "extends"), e.g. "streaming-high".
+// Presets have no input_path and write to "media/output/"; pass the paths to
+// LoadProfileWithPaths or Preset, or set them in an overlay or extending
+// profile.
+func Presets() []PresetInfo {
+	return append([]PresetInfo(nil), builtinPresets...)
+}
+
+// IsPreset reports whether name is a built-in preset.
+func IsPreset(name string) bool {
+	for _, p := range builtinPresets {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Preset returns built-in preset name merged with overlays, with defaults
+// applied but not validated, so callers can fill in InputPath and OutputDir
+// (e.g. through FromProfile) before running it.
+func Preset(name string, overlays ...string) (*TranscodeProfile, error) {
+	if !IsPreset(name) {
+		return nil, unknownPresetError(name)
+	}
+	profile, err := ReadProfileLayers(name, overlays...)
+	if err != nil {
+		return nil, err
+	}
+	applyDefaults(profile)
+	return profile, nil
+}
+
+// readPreset returns the YAML of built-in preset name and the path reported
+// for it in errors.
+func readPreset(name string) ([]byte, string, error) {
+	path := presetPathPrefix + name
+	data, err := presetFiles.ReadFile("presets/" + name + ".yaml")
+	if err != nil {
+		return nil, path, &ConfigError{Op: "read", Path: path, Err: err}
+	}
+	return data, path, nil
+}
+
+// unknownPresetError reports a profile name that is neither a file nor a preset.
+func unknownPresetError(name string) error {
+	names := make([]string, len(builtinPresets))
+	for i, p := range builtinPresets {
+		names[i] = p.Name
+	}
+	return &ConfigError{
+		Op:   "validate",
+		Path: name,
+		Err:  fmt.Errorf("unknown preset %q (built-in: %s); profile files need a .json, .yaml, or .yml extension", name, strings.Join(names, ", ")),
+	}
+}
+
+// presetName returns filename as a preset name when it has no extension or
+// directory, i.e. it can't be a profile file.
+func presetName(filename string) (string, bool) {
+	if filename == "" || filename == StdinProfile || filepath.Ext(filename) != "" || filepath.Base(filename) != filename {
+		return "", false
+	}
+	return filename, true
+}
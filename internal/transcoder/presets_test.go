@@ -0,0 +1,54 @@
+package transcoder
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestPresetsValidate(t *testing.T) {
+	for _, info := range Presets() {
+		t.Run(info.Name, func(t *testing.T) {
+			profile, err := Preset(info.Name)
+			if err != nil {
+				t.Fatalf("Preset: %v", err)
+			}
+			if profile.InputPath != "" {
+				t.Errorf("preset sets input_path %q; the caller must provide it", profile.InputPath)
+			}
+			profile.InputPath = "media/movie.mp4"
+			if err := ValidateProfile(*profile, nil).Err(); err != nil {
+				t.Errorf("ValidateProfile: %v", err)
+			}
+		})
+	}
+}
+
+func TestPresetsListEveryEmbeddedFile(t *testing.T) {
+	files, err := fs.Glob(presetFiles, "presets/*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		name := strings.TrimSuffix(strings.TrimPrefix(f, "presets/"), ".yaml")
+		if !IsPreset(name) {
+			t.Errorf("%s is embedded but not listed in builtinPresets", f)
+		}
+	}
+	if len(files) != len(Presets()) {
+		t.Errorf("%d preset files embedded, %d presets listed", len(files), len(Presets()))
+	}
+}
+
+func TestLoadProfileWithPathsFillsPresetPaths(t *testing.T) {
+	profile, err := LoadProfileWithPaths("streaming-high", "media/movie.mp4", "out/")
+	if err != nil {
+		t.Fatalf("LoadProfileWithPaths: %v", err)
+	}
+	if profile.InputPath != "media/movie.mp4" || profile.OutputDir != "out/" {
+		t.Errorf("paths = %q, %q; want media/movie.mp4, out/", profile.InputPath, profile.OutputDir)
+	}
+	if _, err := LoadProfile("streaming-high"); err == nil {
+		t.Error("LoadProfile accepted a preset without an input")
+	}
+}
@@ -0,0 +1,40 @@
+package transcoder
+
+// Preview defaults used when TranscodeProfile.Preview leaves a field unset.
+const (
+	DefaultPreviewDuration   = 90
+	DefaultPreviewClips      = 6
+	DefaultPreviewResolution = "360p"
+	DefaultPreviewBitrate    = "600k"
+)
+
+// PreviewSettings configures the trailer built for browse pages
+// (TranscodeProfile.Preview). Setting the block enables the preview stage.
+type PreviewSettings struct {
+	Duration   int    `json:"duration,omitempty" yaml:"duration,omitempty"`     // Total preview length in seconds (default 90)
+	Clips      int    `json:"clips,omitempty" yaml:"clips,omitempty"`           // Number of clips assembled into the preview (default 6)
+	Resolution string `json:"resolution,omitempty" yaml:"resolution,omitempty"` // Output resolution label (default "360p")
+	Bitrate    string `json:"bitrate,omitempty" yaml:"bitrate,omitempty"`       // Video bitrate (default "600k")
+}
+
+// PreviewSettings returns the profile's preview settings with defaults
+// applied. Check Preview != nil to see whether a preview was requested.
+func (p *TranscodeProfile) PreviewSettings() PreviewSettings {
+	var s PreviewSettings
+	if p.Preview != nil {
+		s = *p.Preview
+	}
+	if s.Duration <= 0 {
+		s.Duration = DefaultPreviewDuration
+	}
+	if s.Clips <= 0 {
+		s.Clips = DefaultPreviewClips
+	}
+	if s.Resolution == "" {
+		s.Resolution = DefaultPreviewResolution
+	}
+	if s.Bitrate == "" {
+		s.Bitrate = DefaultPreviewBitrate
+	}
+	return s
+}
@@ -1,5 +1,15 @@
 package transcoder
 
+import (
+	"strings"
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/executil"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/layout"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/namer"
+)
+
 // TranscodeProfile defines the parameters for a transcoding session.
 // Parsed from a config file (JSON or YAML) and passed through the pipeline.
 // Supports resolution-specific bitrates, codec/container choices, and optional hardware acceleration.
@@ -8,17 +18,178 @@ package transcoder
 type Variant struct {
 	Resolution string `json:"resolution" yaml:"resolution"`
 	Bitrate    string `json:"bitrate" yaml:"bitrate"`
+	Codec      string `json:"codec,omitempty" yaml:"codec,omitempty"` // Video codec for this rung (e.g. "hevc", "av1"); default is the profile's video_codec
+
+	AudioBitrate string `json:"audio_bitrate,omitempty" yaml:"audio_bitrate,omitempty"` // Audio bitrate for this rung; default is the profile's audio_bitrate
+	Threads      int    `json:"threads,omitempty" yaml:"threads,omitempty"`             // Encoder threads for this rung; default is the profile's threads or its share of budget.threads
+	Lookahead    int    `json:"lookahead,omitempty" yaml:"lookahead,omitempty"`         // x264/x265 rc-lookahead in frames; default is the encoder's, lowered to fit budget.memory_mb
+}
+
+// codecTier returns the codec family of a rung that overrides the profile's
+// video codec with a different family (e.g. "hevc" on an h264 profile), or ""
+// for rungs in the primary tier. Secondary tiers get their own output names.
+func (p *TranscodeProfile) codecTier(v Variant) string {
+	if v.Codec == "" {
+		return ""
+	}
+	family := helpers.CodecFamily(v.Codec)
+	if family == "" {
+		family = strings.ToLower(v.Codec)
+	}
+	if family == helpers.CodecFamily(p.VideoCodec) {
+		return ""
+	}
+	return family
+}
+
+// variantCodec returns the video codec a rung is encoded with.
+func (p *TranscodeProfile) variantCodec(v Variant) string {
+	if v.Codec != "" {
+		return v.Codec
+	}
+	return p.VideoCodec
 }
 
 type TranscodeProfile struct {
-	InputPath        string    `json:"input_path" yaml:"input_path"`                                   // Path to source media file (e.g. "media/movie.mp4")
-	OutputDir        string    `json:"output_dir" yaml:"output_dir"`                                   // Directory to write output files (e.g. "media/output/")
-	Resolutions      []string  `json:"target_res" yaml:"target_res"`                                   // Target resolutions (e.g. ["1080p", "720p", "480p"])
-	AudioCodec       string    `json:"audio_codec,omitempty" yaml:"audio_codec,omitempty"`             // Audio codec (e.g. "aac", "copy"); defaults to "aac"
-	VideoCodec       string    `json:"video_codec" yaml:"video_codec"`                                 // Video codec (e.g. "h264", "vp9"); may be overridden for hardware acceleration
-	Variants         []Variant `json:"variants" yaml:"variants"`                                       // Bitrate per resolution (e.g. {"720p": "3000k", "480p": "1500k"})
-	SegmentLength    int       `json:"segment_length" yaml:"segment_length"`                           // Segment duration in seconds; used during segmentation phase
-	Container        string    `json:"container" yaml:"container"`                                     // Output container format (e.g. "mp4", "mkv")
-	UseHardwareAccel bool      `json:"use_hwaccel,omitempty" yaml:"use_hwaccel,omitempty"`             // Enable platform-specific hardware acceleration (e.g. VideoToolbox on macOS)
-	PreserveManifest bool      `json:"preserve_manifest,omitempty" yaml:"preserve_manifest,omitempty"` // Merge new variants into existing master.m3u8
+	Extends          string                  `json:"extends,omitempty" yaml:"extends,omitempty"`                     // Base profile to inherit from; resolved relative to this file, then profiles/
+	InputPath        string                  `json:"input_path" yaml:"input_path"`                                   // Path to source media file (e.g. "media/movie.mp4")
+	OutputDir        string                  `json:"output_dir" yaml:"output_dir"`                                   // Directory to write output files (e.g. "media/output/")
+	Resolutions      []string                `json:"target_res" yaml:"target_res"`                                   // Target resolutions (e.g. ["1080p", "720p", "480p"])
+	AudioCodec       string                  `json:"audio_codec,omitempty" yaml:"audio_codec,omitempty"`             // Audio codec (e.g. "aac", "copy"); defaults to "aac"
+	AudioBitrate     string                  `json:"audio_bitrate,omitempty" yaml:"audio_bitrate,omitempty"`         // Audio bitrate (e.g. "128k"); unset leaves the encoder default
+	AudioCopy        string                  `json:"audio_copy,omitempty" yaml:"audio_copy,omitempty"`               // "auto" (default) copies compliant AAC source audio instead of re-encoding; "off" always encodes
+	AudioLayout      string                  `json:"audio_layout,omitempty" yaml:"audio_layout,omitempty"`           // "muxed" (default) puts the primary audio in every variant; "demuxed" writes video-only variants and an audio group
+	AudioOffsetMs    int                     `json:"audio_offset_ms,omitempty" yaml:"audio_offset_ms,omitempty"`     // A/V sync correction: positive delays the audio, negative advances it
+	VideoCodec       string                  `json:"video_codec" yaml:"video_codec"`                                 // Video codec (e.g. "h264", "vp9"); may be overridden for hardware acceleration
+	Variants         []Variant               `json:"variants" yaml:"variants"`                                       // Bitrate per resolution (e.g. {"720p": "3000k", "480p": "1500k"})
+	SegmentLength    int                     `json:"segment_length" yaml:"segment_length"`                           // Segment duration in seconds; used during segmentation phase
+	Container        string                  `json:"container" yaml:"container"`                                     // Output container format (e.g. "mp4", "mkv")
+	StreamFormat     string                  `json:"stream_format,omitempty" yaml:"stream_format,omitempty"`         // "hls" (default) or "dash"; used when the run doesn't name a format
+	UseHardwareAccel bool                    `json:"use_hwaccel,omitempty" yaml:"use_hwaccel,omitempty"`             // Enable platform-specific hardware acceleration (VideoToolbox, NVENC, QSV, VA-API, AMF)
+	HWAccel          string                  `json:"hwaccel,omitempty" yaml:"hwaccel,omitempty"`                     // Preferred backend when use_hwaccel is set: "auto" (default), "nvenc", "qsv", "vaapi", "amf", "videotoolbox"
+	PreserveManifest bool                    `json:"preserve_manifest,omitempty" yaml:"preserve_manifest,omitempty"` // Merge new variants into existing master.m3u8
+	Packager         string                  `json:"packager,omitempty" yaml:"packager,omitempty"`                   // Segments variants with "ffmpeg" (default), "shaka" (Shaka Packager), "bento4", "native" (built-in HLS segmenter), or "auto" (the first installed)
+	KeepClear        bool                    `json:"keep_clear,omitempty" yaml:"keep_clear,omitempty"`               // Keep the unencrypted variant files in the output directory of encrypted runs; by default they are removed once segmented
+	Checksums        bool                    `json:"checksums,omitempty" yaml:"checksums,omitempty"`                 // Write checksums.json with SHA-256 digests of every output file
+	CommandTimeout   int                     `json:"command_timeout,omitempty" yaml:"command_timeout,omitempty"`     // Max seconds any single ffmpeg command may run; 0 uses the process default
+	StallTimeout     int                     `json:"stall_timeout,omitempty" yaml:"stall_timeout,omitempty"`         // Kill an encode if progress hasn't advanced for this many seconds; 0 uses the process default
+	Nice             int                     `json:"nice,omitempty" yaml:"nice,omitempty"`                           // Run ffmpeg at lower CPU priority (1-19); priority class on Windows
+	IdleIO           bool                    `json:"idle_io,omitempty" yaml:"idle_io,omitempty"`                     // Run ffmpeg in the idle I/O scheduling class (Linux only)
+	Threads          int                     `json:"threads,omitempty" yaml:"threads,omitempty"`                     // Cap ffmpeg encoder threads per variant (-threads); 0 lets ffmpeg decide
+	Budget           *EncodeBudget           `json:"budget,omitempty" yaml:"budget,omitempty"`                       // Threads and memory shared by concurrent variant encodes
+	LowSource        *LowSourceSettings      `json:"low_source,omitempty" yaml:"low_source,omitempty"`               // What to do when a low-resolution source leaves too few rungs: skip (default), synthesize, upscale, or fail
+	Trim             *LadderTrim             `json:"trim,omitempty" yaml:"trim,omitempty"`                           // Drop rungs short clips and screencasts don't need, or cap the ladder size
+	SourceBitrate    string                  `json:"source_bitrate,omitempty" yaml:"source_bitrate,omitempty"`       // Variants above the source video bitrate: "warn" (default), "cap", or "off"
+	Deinterlace      string                  `json:"deinterlace,omitempty" yaml:"deinterlace,omitempty"`             // "auto" (default), "off", or "force"
+	OutputLayout     string                  `json:"output_layout,omitempty" yaml:"output_layout,omitempty"`         // Slug directory template under output_dir (e.g. "{date}/{slug}", "flat"); default "{slug}"
+	VariantLayout    string                  `json:"variant_layout,omitempty" yaml:"variant_layout,omitempty"`       // Segment directory template per variant (e.g. "hls/{height}p/{bitrate}k"); default "{label}"
+	Tenant           string                  `json:"tenant,omitempty" yaml:"tenant,omitempty"`                       // Catalog or library the job belongs to: outputs go under <output_dir>/<tenant>/ and metrics carry it as a label
+	Slug             string                  `json:"slug,omitempty" yaml:"slug,omitempty"`                           // Explicit output slug (still sanitized); default derives it from the input filename
+	AV1              *AV1Options             `json:"av1,omitempty" yaml:"av1,omitempty"`                             // SVT-AV1 preset, film grain, and tile settings for AV1 encodes
+	VP9              *VP9Options             `json:"vp9,omitempty" yaml:"vp9,omitempty"`                             // libvpx deadline, speed, and tile settings for VP9 encodes
+	RateControl      *RateControl            `json:"rate_control,omitempty" yaml:"rate_control,omitempty"`           // Encoder speed preset and peak bitrate cap (-preset, -maxrate, -bufsize)
+	Preview          *PreviewSettings        `json:"preview,omitempty" yaml:"preview,omitempty"`                     // Build a short trailer (MP4 + HLS) for browse pages
+	ThumbnailSource  string                  `json:"thumbnail_source,omitempty" yaml:"thumbnail_source,omitempty"`   // Where thumbnails are extracted from: "auto" (default), "variant", "input", or "live" (the input, during the transcode)
+	Sprites          *SpriteSettings         `json:"sprites,omitempty" yaml:"sprites,omitempty"`                     // Scrubber sprite sheets with a WebVTT storyboard, written next to the thumbnails
+	SessionData      []SessionData           `json:"session_data,omitempty" yaml:"session_data,omitempty"`           // #EXT-X-SESSION-DATA entries for the HLS master (title, poster, JSON payloads)
+	Start            *StartOffset            `json:"start,omitempty" yaml:"start,omitempty"`                         // #EXT-X-START offset for the HLS master
+	HLSVersion       int                     `json:"hls_version,omitempty" yaml:"hls_version,omitempty"`             // Compatibility target: highest #EXT-X-VERSION players support (e.g. 3); features needing more are refused
+	CuePoints        []CuePoint              `json:"cue_points,omitempty" yaml:"cue_points,omitempty"`               // Ad breaks: pre-, mid-, and post-roll periods in the DASH master for SSAI
+	Subtitles        *SubtitleSettings       `json:"subtitles,omitempty" yaml:"subtitles,omitempty"`                 // Embedded and external captions published as WebVTT renditions
+	ForcedSubtitles  string                  `json:"forced_subtitles,omitempty" yaml:"forced_subtitles,omitempty"`   // Forced-narrative subtitles: "auto" (default), "rendition", "burn", or "off"
+	AudioRenditions  []AudioRendition        `json:"audio_renditions,omitempty" yaml:"audio_renditions,omitempty"`   // Alternate audio (e.g. AC-3/E-AC-3) published as HLS audio groups or DASH audio sets
+	Timestamps       *TimestampSettings      `json:"timestamps,omitempty" yaml:"timestamps,omitempty"`               // Timestamp repair for sources with gaps or discontinuities
+	ReadThrottle     *ReadThrottle           `json:"read_throttle,omitempty" yaml:"read_throttle,omitempty"`         // Pace source reads (-readrate) so bulk jobs don't saturate shared network storage
+	Checkpoint       *CheckpointSettings     `json:"checkpoint,omitempty" yaml:"checkpoint,omitempty"`               // Encode long sources in resumable chunks so a crashed job picks up where it stopped
+	PartialFailure   *PartialFailureSettings `json:"partial_failure,omitempty" yaml:"partial_failure,omitempty"`     // When some variants fail: "publish" the rest (default), "fail_fast", or require "min_rungs"
+	MinimumLadder    *MinimumLadder          `json:"minimum_ladder,omitempty" yaml:"minimum_ladder,omitempty"`       // Rungs and labels (e.g. 480p and 1080p) that must be segmented before the master manifest is written
+	Still            *StillSettings          `json:"still,omitempty" yaml:"still,omitempty"`                         // Duration and framerate of the clip made from an image or image sequence input_path
+	Bumpers          *BumperSettings         `json:"bumpers,omitempty" yaml:"bumpers,omitempty"`                     // Slates, bumpers, or rating cards (image or clip) joined before and after every output
+	Languages        *LanguageSettings       `json:"languages,omitempty" yaml:"languages,omitempty"`                 // Language tags for untagged or mislabeled audio and subtitle streams, and whether every published track needs one
+	Progressive      *ProgressiveSettings    `json:"progressive,omitempty" yaml:"progressive,omitempty"`             // Keep selected variants as faststart MP4 downloads beside the ABR output
+
+	pinnedSlug string // Slug fixed by PinSlug
+}
+
+// Layout returns the output path templates configured on the profile.
+func (p *TranscodeProfile) Layout() layout.Layout {
+	return layout.Layout{Slug: p.OutputLayout, Variant: p.VariantLayout, Tenant: p.Tenant}
+}
+
+// OutputSlug returns the slug naming this profile's outputs: the sanitized Slug
+// field, or a slug of the input filename. An output tree written before slugs
+// were sanitized keeps its original name (see namer.Existing). When the
+// layout has a per-slug directory already claimed by a different input, a
+// numeric suffix keeps the two apart (e.g. "movie-2").
+//
+// Resolving the slug reads the output directory; use PinSlug to resolve it
+// once for a job.
+func (p *TranscodeProfile) OutputSlug() string {
+	if p.pinnedSlug != "" {
+		return p.pinnedSlug
+	}
+	slug, legacy := namer.SlugFromPath(p.InputPath), namer.LegacySlugFromPath(p.InputPath)
+	if p.Slug != "" {
+		slug, legacy = namer.Slugify(p.Slug), p.Slug
+	}
+	l := p.Layout()
+	if !l.UsesSlug() {
+		return slug
+	}
+	dirFor := func(candidate string) string {
+		return l.For(p.InputPath, candidate).SlugDir(p.OutputDir)
+	}
+	if existing := namer.Existing(slug, legacy, p.InputPath, dirFor); existing != slug {
+		return existing
+	}
+	return namer.Unique(slug, p.InputPath, dirFor)
+}
+
+// PinSlug returns a copy of the profile whose OutputSlug is resolved now and
+// reused afterwards, so every stage of a job agrees on one slug without
+// repeating the directory lookups.
+func (p *TranscodeProfile) PinSlug() *TranscodeProfile {
+	pinned := *p
+	pinned.pinnedSlug = p.OutputSlug()
+	return &pinned
+}
+
+// variantBase returns the prefix of variant filenames: the slug of the input
+// filename, or in a tree kept under its unsanitized name, the prefix earlier
+// releases wrote there.
+func (p *TranscodeProfile) variantBase() string {
+	base := namer.SlugFromPath(p.InputPath)
+	if slug := p.OutputSlug(); slug != base && slug == namer.LegacySlugFromPath(p.InputPath) {
+		return namer.LegacyVariantBase(p.InputPath)
+	}
+	return base
+}
+
+// Paths resolves the profile's output layout for its input file.
+func (p *TranscodeProfile) Paths() layout.Resolver {
+	return p.Layout().For(p.InputPath, p.OutputSlug())
+}
+
+// SlugDir returns the directory receiving every output for this profile's input.
+func (p *TranscodeProfile) SlugDir() string {
+	return p.Paths().SlugDir(p.OutputDir)
+}
+
+// CommandLimits returns the executil limits for commands run with this profile.
+// Unset profile values fall back to executil.DefaultLimits.
+func (p *TranscodeProfile) CommandLimits() executil.Limits {
+	limits := executil.DefaultLimits()
+	if p.CommandTimeout > 0 {
+		limits.Timeout = time.Duration(p.CommandTimeout) * time.Second
+	}
+	if p.StallTimeout > 0 {
+		limits.StallTimeout = time.Duration(p.StallTimeout) * time.Second
+	}
+	if p.Nice > 0 {
+		limits.Nice = p.Nice
+	}
+	if p.IdleIO {
+		limits.IdleIO = true
+	}
+	return limits
 }
@@ -0,0 +1,342 @@
+package transcoder
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxExtendsDepth bounds "extends" chains to catch runaway inheritance.
+const maxExtendsDepth = 16
+
+// LoadProfileLayers loads a base profile and applies overlays on top of it, in order.
+// Each layer may itself declare "extends" to inherit from another profile file.
+//
+// Merge semantics:
+//   - Objects are merged key by key; overlay values win
+//   - "variants" entries are matched by resolution: an overlay entry replaces every
+//     base entry with the same resolution, new resolutions are appended, and an
+//     entry with an empty or null bitrate removes that resolution
+//   - Any other list replaces the base list wholesale
+//   - An explicit null resets the field to its zero value
+//
+// This lets a library-wide ladder be defined once while per-title files only
+// override input/output paths or a couple of variant settings.
+func LoadProfileLayers(base string, overlays ...string) (*TranscodeProfile, error) {
+	return LoadProfileWithPaths(base, "", "", overlays...)
+}
+
+// LoadProfileLayersChecked is LoadProfileLayers with every file read,
+// including "extends" targets, first passed to check.
+func LoadProfileLayersChecked(base string, check PathCheck, overlays ...string) (*TranscodeProfile, error) {
+	return loadProfileLayers(base, "", "", check, overlays)
+}
+
+// LoadProfileWithPaths is LoadProfileLayers with inputPath and outputDir, when
+// not empty, replacing the merged profile's input_path and output_dir before
+// validation. Built-in presets, which have no input, are loaded this way.
+func LoadProfileWithPaths(base, inputPath, outputDir string, overlays ...string) (*TranscodeProfile, error) {
+	return loadProfileLayers(base, inputPath, outputDir, nil, overlays)
+}
+
+// loadProfileLayers merges, fills in, and validates the profile layers.
+func loadProfileLayers(base, inputPath, outputDir string, check PathCheck, overlays []string) (*TranscodeProfile, error) {
+	profile, path, err := readLayers(base, overlays, check)
+	if err != nil {
+		return nil, err
+	}
+	if inputPath != "" {
+		profile.InputPath = inputPath
+	}
+	if outputDir != "" {
+		profile.OutputDir = outputDir
+	}
+
+	// Apply fallback values for optional fields
+	applyDefaults(profile)
+
+	// Validate required fields and log segment length behavior
+	if err := validateProfile(*profile); err != nil {
+		return nil, &ConfigError{
+			Op:   "validate",
+			Path: path,
+			Err:  err,
+		}
+	}
+
+	return profile, nil
+}
+
+// ReadProfileLayers merges base and overlays exactly like LoadProfileLayers but
+// skips defaults and validation, so tooling can inspect the raw merged profile
+// (e.g. with ValidateProfile).
+func ReadProfileLayers(base string, overlays ...string) (*TranscodeProfile, error) {
+	profile, _, err := readLayers(base, overlays, nil)
+	return profile, err
+}
+
+// inlinePath is the path reported in errors for profiles passed as bytes.
+const inlinePath = "<inline>"
+
+// ParseProfile loads a profile from an in-memory JSON or YAML document (e.g.
+// one submitted over an API) and applies overlays on top of it, with the same
+// merging, defaults, and validation as LoadProfileLayers. An "extends"
+// reference resolves like a top-level profile file, under profiles/ or the
+// working directory. Unlike profile files, data is not expanded with ${VAR}
+// references, so a submitted document cannot read the server's environment.
+func ParseProfile(data []byte, overlays ...string) (*TranscodeProfile, error) {
+	return ParseProfileChecked(data, nil, overlays...)
+}
+
+// ParseProfileChecked is ParseProfile with every file read, including the
+// document's "extends" chain, first passed to check. Services taking
+// documents from clients use it so an "extends" can't name a file (and the
+// environment references it expands) outside what the client may read.
+func ParseProfileChecked(data []byte, check PathCheck, overlays ...string) (*TranscodeProfile, error) {
+	merged, err := decodeLayer(data, sniffFormat(data), inlinePath, false)
+	if err != nil {
+		return nil, err
+	}
+	if parent, ok := merged["extends"].(string); ok && parent != "" {
+		base, _, err := loadLayer(parent, "", []string{inlinePath}, check)
+		if err != nil {
+			return nil, err
+		}
+		merged = mergeLayers(base, merged)
+	}
+	delete(merged, "extends")
+
+	path := inlinePath
+	for _, overlay := range overlays {
+		layer, overlayPath, err := loadLayer(overlay, "", nil, check)
+		if err != nil {
+			return nil, err
+		}
+		merged = mergeLayers(merged, layer)
+		path = overlayPath
+	}
+
+	profile, err := layerToProfile(merged, path)
+	if err != nil {
+		return nil, err
+	}
+	applyDefaults(profile)
+	if err := validateProfile(*profile); err != nil {
+		return nil, &ConfigError{Op: "validate", Path: path, Err: err}
+	}
+	return profile, nil
+}
+
+// readLayers loads and merges all layers, returning the profile and the path of
+// the last layer applied (used in error context).
+func readLayers(base string, overlays []string, check PathCheck) (*TranscodeProfile, string, error) {
+	if base == "" {
+		return nil, "", &ConfigError{
+			Op:   "validate",
+			Path: ProfileDir + "/",
+			Err:  fmt.Errorf("filename is empty"),
+		}
+	}
+
+	merged, path, err := loadLayer(base, "", nil, check)
+	if err != nil {
+		return nil, path, err
+	}
+	for _, overlay := range overlays {
+		layer, overlayPath, err := loadLayer(overlay, "", nil, check)
+		if err != nil {
+			return nil, overlayPath, err
+		}
+		merged = mergeLayers(merged, layer)
+		path = overlayPath
+	}
+
+	profile, err := layerToProfile(merged, path)
+	return profile, path, err
+}
+
+// loadLayer reads one profile file as a generic map and resolves its "extends"
+// chain, returning the fully merged layer and its resolved path.
+// relativeTo is the directory of the extending file ("" for top-level layers);
+// seen tracks visited paths for cycle detection; check, when set, vets each
+// file before it is read.
+func loadLayer(filename, relativeTo string, seen []string, check PathCheck) (map[string]any, string, error) {
+	if relativeTo != "" && filename != StdinProfile && !filepath.IsAbs(filename) {
+		if sibling := filepath.Join(relativeTo, filename); fileExists(sibling) {
+			filename = sibling
+		}
+	}
+
+	data, path, ext, err := readProfileSource(filename, check)
+	if err != nil {
+		return nil, path, err
+	}
+
+	for _, p := range seen {
+		if p == path {
+			return nil, path, &ConfigError{Op: "extends", Path: path, Err: fmt.Errorf("inheritance cycle: %v", append(seen, path))}
+		}
+	}
+	if len(seen) >= maxExtendsDepth {
+		return nil, path, &ConfigError{Op: "extends", Path: path, Err: fmt.Errorf("extends chain deeper than %d", maxExtendsDepth)}
+	}
+
+	layer, err := decodeLayer(data, ext, path, true)
+	if err != nil {
+		return nil, path, err
+	}
+
+	parent, ok := layer["extends"].(string)
+	delete(layer, "extends")
+	if !ok || parent == "" {
+		return layer, path, nil
+	}
+
+	dir := ""
+	if path != stdinPath {
+		dir = filepath.Dir(path)
+	}
+	baseLayer, _, err := loadLayer(parent, dir, append(seen, path), check)
+	if err != nil {
+		return nil, path, err
+	}
+	return mergeLayers(baseLayer, layer), path, nil
+}
+
+// layerToProfile converts a merged generic layer into a TranscodeProfile.
+// The layer is round-tripped through YAML, whose scalar decoding is lenient
+// (e.g. bitrate: 5000 decodes into a string), so validation can report such
+// values with field paths instead of failing the whole load.
+func layerToProfile(layer map[string]any, path string) (*TranscodeProfile, error) {
+	data, err := yaml.Marshal(normalizeNumbers(layer))
+	if err != nil {
+		return nil, &ConfigError{Op: "merge", Path: path, Err: err}
+	}
+	var profile TranscodeProfile
+	if err := yaml.Unmarshal(data, &profile); err != nil {
+		return nil, &ConfigError{Op: "merge", Path: path, Err: err}
+	}
+	return &profile, nil
+}
+
+// normalizeNumbers converts integral float64 values (as produced by JSON decoding)
+// to int64 so they marshal as plain integers rather than exponent notation.
+func normalizeNumbers(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, item := range t {
+			out[k] = normalizeNumbers(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, item := range t {
+			out[i] = normalizeNumbers(item)
+		}
+		return out
+	case float64:
+		if t == math.Trunc(t) && math.Abs(t) < 1<<53 {
+			return int64(t)
+		}
+	}
+	return v
+}
+
+// mergeLayers deep-merges overlay onto base and returns the result.
+// Neither input is modified.
+func mergeLayers(base, overlay map[string]any) map[string]any {
+	out := make(map[string]any, len(base)+len(overlay))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range overlay {
+		switch {
+		case v == nil:
+			delete(out, k)
+		case k == "variants":
+			baseList, _ := out[k].([]any)
+			overList, ok := v.([]any)
+			if !ok {
+				out[k] = v
+				continue
+			}
+			out[k] = mergeVariants(baseList, overList)
+		default:
+			baseMap, baseIsMap := out[k].(map[string]any)
+			overMap, overIsMap := v.(map[string]any)
+			if baseIsMap && overIsMap {
+				out[k] = mergeLayers(baseMap, overMap)
+			} else {
+				out[k] = v
+			}
+		}
+	}
+	return out
+}
+
+// mergeVariants merges variant lists keyed by resolution, preserving base order.
+func mergeVariants(base, overlay []any) []any {
+	byRes := make(map[string][]any)
+	var order []string
+	for _, entry := range overlay {
+		res := variantResolution(entry)
+		if _, ok := byRes[res]; !ok {
+			order = append(order, res)
+		}
+		if bitrate, _ := variantField(entry, "bitrate"); bitrate == "" {
+			byRes[res] = []any{} // removal marker
+			continue
+		}
+		byRes[res] = append(byRes[res], entry)
+	}
+
+	out := make([]any, 0, len(base)+len(overlay))
+	emitted := make(map[string]bool)
+	for _, entry := range base {
+		res := variantResolution(entry)
+		replacement, overridden := byRes[res]
+		if !overridden {
+			out = append(out, entry)
+			continue
+		}
+		if !emitted[res] {
+			out = append(out, replacement...)
+			emitted[res] = true
+		}
+	}
+	for _, res := range order {
+		if !emitted[res] {
+			out = append(out, byRes[res]...)
+		}
+	}
+	return out
+}
+
+// variantResolution returns the resolution label of a generic variant entry.
+func variantResolution(entry any) string {
+	res, _ := variantField(entry, "resolution")
+	return res
+}
+
+// variantField reads a string field from a generic variant entry.
+func variantField(entry any, key string) (string, bool) {
+	m, ok := entry.(map[string]any)
+	if !ok {
+		return "", false
+	}
+	v, ok := m[key]
+	if !ok || v == nil {
+		return "", ok
+	}
+	return fmt.Sprint(v), true
+}
+
+// fileExists reports whether path names an existing file.
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,180 @@
+package transcoder
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ProgressEvent reports encode progress to library callers.
+// Per-variant events carry the variant key; aggregate events average every
+// active variant and leave Variant empty.
+type ProgressEvent struct {
+	Variant   string  // Variant key (e.g. "720p_3000k"); "" for aggregate events
+	Percent   float64 // 0-100
+	Aggregate bool    // True for the average across all variants
+	Active    int     // Number of variants reporting progress (aggregate only)
+	Done      bool    // Final event for the variant, or for the whole transcode when Aggregate
+	Failed    bool    // Variant encode failed (per-variant Done events only)
+
+	// Speed and ETA are derived from encoded media time vs wall clock. Both are
+	// zero until the first progress update. The aggregate ETA is the slowest
+	// variant's, since variants encode concurrently.
+	Speed float64       // Media seconds encoded per wall-clock second (e.g. 2.5 = 2.5x realtime)
+	ETA   time.Duration // Estimated time remaining
+}
+
+// ETAString renders the ETA for log lines ("ETA 1m20s", or "" when unknown).
+func (e ProgressEvent) ETAString() string {
+	if e.ETA <= 0 || e.Done {
+		return ""
+	}
+	return fmt.Sprintf("ETA %s", e.ETA.Round(time.Second))
+}
+
+// ProgressFunc receives progress events. It is called from encoder goroutines,
+// possibly concurrently, and must return quickly.
+type ProgressFunc func(ProgressEvent)
+
+// TranscodeOptions customizes a single Transcode run.
+type TranscodeOptions struct {
+	Progress  ProgressFunc            // Optional progress callback (per variant and aggregate)
+	OnVariant func(ResolutionVariant) // Optional callback as each variant encode succeeds
+
+	// Keep lists variants already encoded (see PlanUpgrade). Planned encodes
+	// with the same output filename are skipped and these are carried into
+	// the result as they are.
+	Keep []ResolutionVariant
+
+	// Scratch is the directory for encode intermediates, such as
+	// checkpointed chunks; beside each variant when empty. Chunks resume
+	// only if a later run is given the same directory.
+	Scratch string
+
+	// Workdir is the directory for intermediates that don't outlive an
+	// encode, such as rendered bumpers; beside each variant when empty.
+	Workdir string
+}
+
+// Pending returns the planned encodes not covered by o.Keep.
+func (o TranscodeOptions) Pending(plan *TranscodePlan) []PlannedVariant {
+	if len(o.Keep) == 0 {
+		return plan.Variants
+	}
+	kept := make(map[string]bool, len(o.Keep))
+	for _, v := range o.Keep {
+		kept[v.OutputFilename] = true
+	}
+	var out []PlannedVariant
+	for _, pv := range plan.Variants {
+		if !kept[pv.OutputFilename] {
+			out = append(out, pv)
+		}
+	}
+	return out
+}
+
+// emit delivers an event when a callback is registered.
+func (o TranscodeOptions) emit(ev ProgressEvent) {
+	if o.Progress != nil {
+		o.Progress(ev)
+	}
+}
+
+// variantProgress is the latest progress sample for one variant.
+type variantProgress struct {
+	percent float64
+	started time.Time
+	speed   float64
+	eta     time.Duration
+	done    bool
+}
+
+// progressTracker records per-variant progress and derives speed and ETA.
+type progressTracker struct {
+	mu       sync.Mutex
+	duration float64 // Source duration in seconds
+	variants map[string]*variantProgress
+}
+
+func newProgressTracker(duration float64) *progressTracker {
+	return &progressTracker{duration: duration, variants: make(map[string]*variantProgress)}
+}
+
+// start marks the moment a variant's encode begins.
+func (t *progressTracker) start(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.variants[key] = &variantProgress{started: time.Now()}
+}
+
+// update records a percentage and returns the per-variant event.
+func (t *progressTracker) update(key string, percent float64) ProgressEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	v := t.variant(key)
+	v.percent = percent
+	if elapsed := time.Since(v.started).Seconds(); elapsed > 0 && percent > 0 {
+		encoded := percent / 100 * t.duration
+		v.speed = encoded / elapsed
+		if v.speed > 0 {
+			v.eta = time.Duration((t.duration - encoded) / v.speed * float64(time.Second))
+		}
+	}
+	return ProgressEvent{Variant: key, Percent: percent, Speed: v.speed, ETA: v.eta}
+}
+
+// finish marks a variant complete and returns its final event.
+func (t *progressTracker) finish(key string, failed bool) ProgressEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	v := t.variant(key)
+	v.done = true
+	v.eta = 0
+	if !failed {
+		v.percent = 100
+	}
+	return ProgressEvent{Variant: key, Percent: v.percent, Speed: v.speed, Done: true, Failed: failed}
+}
+
+// aggregate averages progress over variants that have reported, returning
+// false until at least one has.
+func (t *progressTracker) aggregate() (ProgressEvent, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ev := ProgressEvent{Aggregate: true}
+	var total, speed float64
+	var speeds int
+	for _, v := range t.variants {
+		if v.percent == 0 && !v.done {
+			continue
+		}
+		ev.Active++
+		total += v.percent
+		if v.speed > 0 {
+			speed += v.speed
+			speeds++
+		}
+		if !v.done && v.eta > ev.ETA {
+			ev.ETA = v.eta
+		}
+	}
+	if ev.Active == 0 {
+		return ev, false
+	}
+	ev.Percent = total / float64(ev.Active)
+	if speeds > 0 {
+		ev.Speed = speed / float64(speeds)
+	}
+	return ev, true
+}
+
+// variant returns the entry for key, creating it if start was never called.
+func (t *progressTracker) variant(key string) *variantProgress {
+	v, ok := t.variants[key]
+	if !ok {
+		v = &variantProgress{started: time.Now()}
+		t.variants[key] = v
+	}
+	return v
+}
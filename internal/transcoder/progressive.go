@@ -0,0 +1,100 @@
+package transcoder
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DefaultProgressiveDir is the directory inside the slug directory that
+// receives progressive downloads when ProgressiveSettings.Dir is unset.
+const DefaultProgressiveDir = "downloads"
+
+// progressiveContainers are the variant containers a faststart pass can
+// relocate the moov of.
+var progressiveContainers = []string{"mp4", "mov", "m4v"}
+
+// ProgressiveSettings keeps selected variants as single downloadable files
+// beside the ABR output (TranscodeProfile.Progressive), for clients that
+// can't play HLS or DASH or want a file to save. Each is copied with its
+// moov moved ahead of the media data, so it plays while downloading.
+type ProgressiveSettings struct {
+	Variants   []string `json:"variants,omitempty" yaml:"variants,omitempty"`       // Resolution labels to keep (e.g. ["480p", "1080p"]); empty keeps every rung
+	Dir        string   `json:"dir,omitempty" yaml:"dir,omitempty"`                 // Directory inside the slug directory (default "downloads")
+	AllowClear bool     `json:"allow_clear,omitempty" yaml:"allow_clear,omitempty"` // Keep downloads of encrypted runs; they are the clear variants, so anyone with the URL can play them
+}
+
+// DirName returns the download directory relative to the slug directory.
+func (s *ProgressiveSettings) DirName() string {
+	if s == nil || s.Dir == "" {
+		return DefaultProgressiveDir
+	}
+	return filepath.Clean(s.Dir)
+}
+
+// ProgressiveVariants returns the variants the profile keeps as progressive
+// downloads: the primary codec tier's MP4 variants whose labels are listed,
+// or all of them when none are. Returns nil when progressive downloads are
+// off.
+func (p *TranscodeProfile) ProgressiveVariants(variants []ResolutionVariant) []ResolutionVariant {
+	s := p.Progressive
+	if s == nil {
+		return nil
+	}
+	var heights []int
+	for _, label := range s.Variants {
+		if h, ok := labelHeight(label); ok {
+			heights = append(heights, h)
+		}
+	}
+	var out []ResolutionVariant
+	for _, v := range variants {
+		if v.Tier != "" || !isMP4Container(v.OutputFilename) {
+			continue
+		}
+		if len(s.Variants) == 0 || slices.Contains(heights, v.Height) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// isMP4Container reports whether the file at path is in an MP4 family
+// container, whose moov can sit ahead of the media data.
+func isMP4Container(path string) bool {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	return slices.Contains(progressiveContainers, ext)
+}
+
+// validateProgressive checks the progressive download settings against the
+// container and the profile's ladder.
+func validateProgressive(p TranscodeProfile, r *ValidationReport) {
+	s := p.Progressive
+	if s == nil {
+		return
+	}
+	if c := strings.ToLower(p.Container); c != "" && !slices.Contains(progressiveContainers, c) {
+		r.add(SeverityError, "progressive", "progressive downloads need MP4 variants; the %s container can't be faststart-optimized", p.Container)
+	}
+	if s.Dir != "" && (filepath.IsAbs(s.Dir) || !filepath.IsLocal(s.Dir)) {
+		r.add(SeverityError, "progressive.dir", "dir must be relative to the slug directory and stay inside it")
+	}
+	if s.Dir == "" {
+		r.defaulted("progressive.dir", DefaultProgressiveDir)
+	}
+	for i, label := range s.Variants {
+		field := fmt.Sprintf("progressive.variants[%d]", i)
+		height, ok := labelHeight(label)
+		if !ok {
+			r.add(SeverityError, field, "invalid resolution label %q (want e.g. \"720p\")", label)
+			continue
+		}
+		if !slices.ContainsFunc(p.Variants, func(v Variant) bool {
+			h, ok := labelHeight(v.Resolution)
+			return ok && h == height && p.codecTier(v) == ""
+		}) {
+			r.add(SeverityWarning, field, "%s is not in the ladder's primary codec tier; no download will be kept", label)
+		}
+	}
+}
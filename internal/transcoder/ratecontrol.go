@@ -0,0 +1,66 @@
+package transcoder
+
+import (
+	"fmt"
+	"strings"
+)
+
+// x26xPresets are the speed presets libx264 and libx265 accept.
+var x26xPresets = []string{"ultrafast", "superfast", "veryfast", "faster", "fast", "medium", "slow", "slower", "veryslow", "placebo"}
+
+// RateControl shapes how variants spend their bitrate
+// (TranscodeProfile.RateControl). Unset fields keep the encoder defaults.
+type RateControl struct {
+	Preset       string  `json:"preset,omitempty" yaml:"preset,omitempty"`               // libx264/libx265 speed preset (e.g. "slow"); ignored by hardware and AV1 encoders
+	MaxrateRatio float64 `json:"maxrate_ratio,omitempty" yaml:"maxrate_ratio,omitempty"` // Cap peaks at this multiple of the variant bitrate (-maxrate); 0 leaves them uncapped
+	BufsizeRatio float64 `json:"bufsize_ratio,omitempty" yaml:"bufsize_ratio,omitempty"` // VBV buffer as a multiple of the variant bitrate (-bufsize); defaults to twice maxrate_ratio
+}
+
+// args returns the ffmpeg rate control options for a variant encoded with
+// videoCodec at kbps.
+func (rc *RateControl) args(videoCodec string, kbps int) []string {
+	if rc == nil {
+		return nil
+	}
+	var args []string
+	if rc.Preset != "" && !isHardwareEncoder(videoCodec) && codecIs(videoCodec, "264", "265", "hevc") {
+		args = append(args, "-preset", rc.Preset)
+	}
+	if rc.MaxrateRatio > 0 && kbps > 0 {
+		bufsize := rc.BufsizeRatio
+		if bufsize <= 0 {
+			bufsize = 2 * rc.MaxrateRatio
+		}
+		args = append(args,
+			"-maxrate", fmt.Sprintf("%dk", int(float64(kbps)*rc.MaxrateRatio)),
+			"-bufsize", fmt.Sprintf("%dk", int(float64(kbps)*bufsize)),
+		)
+	}
+	return args
+}
+
+// validate checks the rate control settings.
+func (rc *RateControl) validate(r *ValidationReport, videoFamily string) {
+	if rc == nil {
+		return
+	}
+	if rc.Preset != "" {
+		if !contains(x26xPresets, rc.Preset) {
+			r.add(SeverityError, "rate_control.preset", "unknown preset %q (want %s)", rc.Preset, strings.Join(x26xPresets, ", "))
+		} else if videoFamily != "" && videoFamily != "h264" && videoFamily != "hevc" {
+			r.add(SeverityWarning, "rate_control.preset", "preset is ignored for %s encodes", videoFamily)
+		}
+	}
+	switch {
+	case rc.MaxrateRatio < 0:
+		r.add(SeverityError, "rate_control.maxrate_ratio", "maxrate_ratio must be zero or positive")
+	case rc.MaxrateRatio > 0 && rc.MaxrateRatio < 1:
+		r.add(SeverityWarning, "rate_control.maxrate_ratio", "maxrate_ratio %.2f caps peaks below the variant bitrate", rc.MaxrateRatio)
+	}
+	switch {
+	case rc.BufsizeRatio < 0:
+		r.add(SeverityError, "rate_control.bufsize_ratio", "bufsize_ratio must be zero or positive")
+	case rc.BufsizeRatio > 0 && rc.MaxrateRatio == 0:
+		r.add(SeverityWarning, "rate_control.bufsize_ratio", "bufsize_ratio has no effect without maxrate_ratio")
+	}
+}
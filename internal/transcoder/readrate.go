@@ -0,0 +1,102 @@
+package transcoder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+)
+
+// ReadThrottle limits how fast ffmpeg reads the source
+// (TranscodeProfile.ReadThrottle), so bulk encodes of sources on shared
+// network storage leave bandwidth for other users of it. Reads are paced
+// with ffmpeg's -readrate (ffmpeg 5.0 or later), a multiple of realtime; a
+// bandwidth budget is turned into one from the source bitrate. Set both and
+// the lower rate wins.
+type ReadThrottle struct {
+	Rate      float64 `json:"rate,omitempty" yaml:"rate,omitempty"`           // Read the source at most this many times faster than realtime per ffmpeg process (e.g. 2)
+	Bandwidth string  `json:"bandwidth,omitempty" yaml:"bandwidth,omitempty"` // Total source reads for the job in bits per second (e.g. "200M", "50000k"), shared by concurrent encodes
+}
+
+// ReadRate returns the -readrate for each of readers ffmpeg processes
+// reading the source at once, or 0 when reads aren't throttled. A bandwidth
+// budget needs the source bitrate, so without it only Rate applies.
+func (p *TranscodeProfile) ReadRate(media *analyzer.MediaInfo, readers int) float64 {
+	t := p.ReadThrottle
+	if t == nil {
+		return 0
+	}
+	rate := t.Rate
+	kbps, err := parseBandwidthKbps(t.Bandwidth)
+	if err == nil && kbps > 0 && media != nil && media.Bitrate > 0 {
+		perReader := float64(kbps) / float64(max(readers, 1)) / float64(media.Bitrate)
+		if rate <= 0 || perReader < rate {
+			rate = perReader
+		}
+	}
+	return max(rate, 0)
+}
+
+// ReadFlags returns the -readrate option placed before an -i reading the
+// source, for one of readers processes reading it at once. Returns nil when
+// reads aren't throttled.
+func (p *TranscodeProfile) ReadFlags(media *analyzer.MediaInfo, readers int) []string {
+	return readRateArgs(p.ReadRate(media, readers))
+}
+
+// readRateArgs returns -readrate rate, or nil for 0.
+func readRateArgs(rate float64) []string {
+	if rate <= 0 {
+		return nil
+	}
+	return []string{"-readrate", strconv.FormatFloat(rate, 'f', 3, 64)}
+}
+
+// parseBandwidthKbps converts a bandwidth like "200M" or "50000k" to kbps.
+// A bare number is bits per second. Returns 0 for "".
+func parseBandwidthKbps(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	num, scale := s, 0.001
+	switch strings.ToLower(s[len(s)-1:]) {
+	case "k":
+		num, scale = s[:len(s)-1], 1
+	case "m":
+		num, scale = s[:len(s)-1], 1000
+	case "g":
+		num, scale = s[:len(s)-1], 1000*1000
+	}
+	v, err := strconv.ParseFloat(num, 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid bandwidth %q; expected bits per second like \"200M\"", s)
+	}
+	return int(v * scale), nil
+}
+
+// validateReadThrottle checks the read throttle, and with media warns when
+// it holds the concurrent encodes below realtime.
+func validateReadThrottle(p TranscodeProfile, media *analyzer.MediaInfo, r *ValidationReport) {
+	t := p.ReadThrottle
+	if t == nil {
+		return
+	}
+	if t.Rate < 0 {
+		r.add(SeverityError, "read_throttle.rate", "rate must be zero or positive")
+	}
+	if _, err := parseBandwidthKbps(t.Bandwidth); err != nil {
+		r.add(SeverityError, "read_throttle.bandwidth", "%v", err)
+	}
+	if t.Rate == 0 && t.Bandwidth == "" {
+		r.add(SeverityWarning, "read_throttle", "neither rate nor bandwidth is set; reads aren't throttled")
+		return
+	}
+	if media == nil || media.Bitrate <= 0 {
+		return
+	}
+	if rate := p.ReadRate(media, len(p.Variants)); rate > 0 && rate < 1 {
+		r.add(SeverityWarning, "read_throttle", "%d concurrent encodes may read the %dkbps source at %.2fx realtime; encodes will run slower than the source plays", len(p.Variants), media.Bitrate, rate)
+	}
+}
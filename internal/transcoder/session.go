@@ -0,0 +1,53 @@
+package transcoder
+
+import (
+	"fmt"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+)
+
+// SessionData is one #EXT-X-SESSION-DATA entry written into the HLS master
+// (TranscodeProfile.SessionData), letting players read title metadata without
+// extra requests. Exactly one of Value, URI, or JSON must be set.
+type SessionData struct {
+	ID       string `json:"id" yaml:"id"`                                 // DATA-ID, reverse-DNS by convention (e.g. "com.example.title")
+	Value    string `json:"value,omitempty" yaml:"value,omitempty"`       // Inline string value (e.g. the title)
+	URI      string `json:"uri,omitempty" yaml:"uri,omitempty"`           // URI of a JSON resource (e.g. a poster manifest)
+	JSON     any    `json:"json,omitempty" yaml:"json,omitempty"`         // Inline JSON payload, written as a data: URI
+	Language string `json:"language,omitempty" yaml:"language,omitempty"` // Language of Value (e.g. "en"); one entry per ID and language
+}
+
+// StartOffset is the #EXT-X-START tag written into the HLS master
+// (TranscodeProfile.Start), telling players where to begin playback.
+type StartOffset struct {
+	TimeOffset float64 `json:"time_offset" yaml:"time_offset"`             // Seconds from the start (negative counts from the end)
+	Precise    bool    `json:"precise,omitempty" yaml:"precise,omitempty"` // Start exactly at the offset rather than the enclosing segment
+}
+
+// latestHLSVersion is the newest #EXT-X-VERSION defined (RFC 8216bis).
+const latestHLSVersion = 12
+
+// validateHLSVersion checks the HLS compatibility target against the
+// features the profile's output needs: ffmpeg writes fractional EXTINF
+// durations (version 3), and HEVC and AV1 variants are segmented as fMP4,
+// whose playlists are version 7.
+func validateHLSVersion(p TranscodeProfile, r *ValidationReport) {
+	target := p.HLSVersion
+	switch {
+	case target == 0:
+		return
+	case target < 0 || target > latestHLSVersion:
+		r.add(SeverityError, "hls_version", "hls_version must be between 1 and %d", latestHLSVersion)
+		return
+	case target < 3:
+		r.add(SeverityError, "hls_version", "hls_version %d is below 3, which the fractional segment durations ffmpeg writes need", target)
+	}
+	if target >= 7 {
+		return
+	}
+	for i, v := range p.Variants {
+		if family := helpers.CodecFamily(p.variantCodec(v)); family == "hevc" || family == "av1" {
+			r.add(SeverityError, fmt.Sprintf("variants[%d]", i), "%s %s is carried in fMP4 segments, which need HLS version 7; hls_version %d forbids it", v.Resolution, family, target)
+		}
+	}
+}
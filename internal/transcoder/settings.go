@@ -0,0 +1,131 @@
+package transcoder
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// EncoderSettings records exactly how a variant was produced, so titles
+// encoded with outdated settings or an older ffmpeg can be found later and
+// selectively re-encoded.
+type EncoderSettings struct {
+	Command     []string          // ffmpeg command line as executed
+	Encoder     string            // Video encoder passed to -c:v (e.g. "libx264", "h264_nvenc")
+	FFmpeg      string            // ffmpeg version, "" if it couldn't be determined
+	Libraries   map[string]string // libav* versions (e.g. "libavcodec": "60.31.102")
+	ProfileHash string            // VariantProfileHash of the settings the variant was encoded with
+	Hash        string            // Fingerprint of the command (paths replaced), ffmpeg version, and profile hash
+}
+
+// VariantProfileHash returns a hex SHA-256 of the profile settings that shape
+// variant v: the profile with its ladder narrowed to v and its input, output,
+// slug, and extends fields cleared. Two titles encoded with the same settings
+// hash alike, and adding or removing other rungs doesn't change it.
+func VariantProfileHash(p *TranscodeProfile, v Variant) string {
+	c := *p
+	c.InputPath, c.OutputDir, c.Slug, c.Extends = "", "", "", ""
+	c.Resolutions = nil
+	c.Variants = []Variant{v}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// newEncoderSettings fingerprints a planned encode against the local ffmpeg.
+func newEncoderSettings(profile *TranscodeProfile, pv PlannedVariant) EncoderSettings {
+	version, libs := FFmpegVersion()
+	s := EncoderSettings{
+		Command:     pv.Command,
+		Encoder:     argAfter(pv.Command, "-c:v"),
+		FFmpeg:      version,
+		Libraries:   libs,
+		ProfileHash: VariantProfileHash(profile, pv.Variant),
+	}
+
+	// Paths differ per title and host; the settings around them are what count
+	h := sha256.New()
+	for _, arg := range normalizeCommand(pv.Command) {
+		fmt.Fprintf(h, "%s\x00", arg)
+	}
+	fmt.Fprintf(h, "ffmpeg=%s\nlibavcodec=%s\nprofile=%s\n", s.FFmpeg, libs["libavcodec"], s.ProfileHash)
+	s.Hash = hex.EncodeToString(h.Sum(nil))
+	return s
+}
+
+// normalizeCommand copies an ffmpeg command with the input (the argument
+// after -i) and output (the last argument) replaced by placeholders, leaving
+// only the settings that shape the encode.
+func normalizeCommand(cmd []string) []string {
+	out := make([]string, len(cmd))
+	copy(out, cmd)
+	for i := 0; i+1 < len(out); i++ {
+		if out[i] == "-i" {
+			out[i+1] = "{input}"
+		}
+	}
+	if len(out) > 1 {
+		out[len(out)-1] = "{output}"
+	}
+	return out
+}
+
+// argAfter returns the argument following flag in cmd, or "".
+func argAfter(cmd []string, flag string) string {
+	for i := 0; i+1 < len(cmd); i++ {
+		if cmd[i] == flag {
+			return cmd[i+1]
+		}
+	}
+	return ""
+}
+
+var (
+	ffmpegVersionOnce sync.Once
+	ffmpegVersion     string
+	ffmpegLibraries   map[string]string
+)
+
+// FFmpegVersion returns the local ffmpeg version and its libav* library
+// versions, read once from "ffmpeg -version" and cached for the life of the
+// process. Both are empty if ffmpeg cannot be run.
+func FFmpegVersion() (string, map[string]string) {
+	ffmpegVersionOnce.Do(func() {
+		out, err := exec.Command("ffmpeg", "-version").Output()
+		if err != nil {
+			return
+		}
+		ffmpegVersion, ffmpegLibraries = parseFFmpegVersion(out)
+	})
+	return ffmpegVersion, ffmpegLibraries
+}
+
+// parseFFmpegVersion extracts the version from the "ffmpeg version 6.1.1 ..."
+// banner line and library versions from lines like
+// "libavcodec     60. 31.102 / 60. 31.102" (the version built against).
+func parseFFmpegVersion(out []byte) (string, map[string]string) {
+	var version string
+	libs := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) >= 3 && fields[0] == "ffmpeg" && fields[1] == "version":
+			version = fields[2]
+		case len(fields) > 1 && strings.HasPrefix(fields[0], "lib"):
+			built, _, _ := strings.Cut(strings.TrimPrefix(line, fields[0]), "/")
+			libs[fields[0]] = strings.ReplaceAll(strings.TrimSpace(built), " ", "")
+		}
+	}
+	return version, libs
+}
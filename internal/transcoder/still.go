@@ -0,0 +1,89 @@
+package transcoder
+
+import (
+	"strconv"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+)
+
+// StillSettings shapes the video clip made from an image input
+// (TranscodeProfile.Still): a poster, slate, or rating card plays for
+// Duration, and a numbered image sequence plays one image per frame. The clip
+// is encoded through the ladder like any source, standalone or as pre-roll.
+type StillSettings struct {
+	Duration  float64 `json:"duration,omitempty" yaml:"duration,omitempty"`   // Seconds a single image plays (default 5); a sequence lasts one frame per image
+	Framerate float64 `json:"framerate,omitempty" yaml:"framerate,omitempty"` // Frames per second of the clip, and the rate a sequence's images play at (default 25)
+}
+
+// StillSettings returns the profile's image input settings with defaults
+// applied.
+func (p *TranscodeProfile) StillSettings() StillSettings {
+	var s StillSettings
+	if p.Still != nil {
+		s = *p.Still
+	}
+	if s.Duration <= 0 {
+		s.Duration = analyzer.DefaultStillDuration.Seconds()
+	}
+	if s.Framerate <= 0 {
+		s.Framerate = analyzer.DefaultImageFramerate
+	}
+	return s
+}
+
+// ImageAnalyzeOptions returns opts with the profile's still settings, so the
+// analyzer reports the synthetic duration and framerate an image input is
+// encoded with.
+func (p *TranscodeProfile) ImageAnalyzeOptions(opts analyzer.AnalyzeOptions) analyzer.AnalyzeOptions {
+	s := p.StillSettings()
+	opts.StillDuration = secondsDuration(s.Duration)
+	opts.ImageFramerate = s.Framerate
+	return opts
+}
+
+// StillInputFlags returns the input options placed before an -i reading path
+// when it is an image: a still is looped for s.Duration at s.Framerate, and a
+// sequence is read from its first image at s.Framerate. Returns nil for
+// anything else.
+func StillInputFlags(path string, s StillSettings) []string {
+	rate := strconv.FormatFloat(s.Framerate, 'f', -1, 64)
+	switch analyzer.ImageInputKind(path) {
+	case analyzer.ImageStill:
+		return []string{"-loop", "1", "-framerate", rate, "-t", strconv.FormatFloat(s.Duration, 'f', 3, 64)}
+	case analyzer.ImageSequence:
+		flags := []string{"-framerate", rate}
+		// Without it ffmpeg only looks for the first image among indexes 0-4
+		if seq, err := analyzer.ScanImageSequence(path); err == nil {
+			flags = append(flags, "-start_number", strconv.Itoa(seq.StartNumber))
+		}
+		return flags
+	}
+	return nil
+}
+
+// validateStill checks the image input settings.
+func validateStill(p TranscodeProfile, r *ValidationReport) {
+	kind := analyzer.ImageInputKind(p.InputPath)
+	s := p.Still
+	if s == nil {
+		if kind == analyzer.ImageStill {
+			r.defaulted("still.duration", "%gs for a still image", analyzer.DefaultStillDuration.Seconds())
+		}
+		return
+	}
+	switch {
+	case s.Duration < 0:
+		r.add(SeverityError, "still.duration", "duration must be zero or positive")
+	case s.Duration > 0 && kind == analyzer.ImageSequence:
+		r.add(SeverityWarning, "still.duration", "duration is ignored for image sequences, which last one frame per image")
+	}
+	switch {
+	case s.Framerate < 0:
+		r.add(SeverityError, "still.framerate", "framerate must be zero or positive")
+	case s.Framerate > 120:
+		r.add(SeverityWarning, "still.framerate", "framerate %g is unusually high", s.Framerate)
+	}
+	if kind == analyzer.ImageNone {
+		r.add(SeverityWarning, "still", "input %q is not a recognized image or image sequence; still settings only apply to images", p.InputPath)
+	}
+}
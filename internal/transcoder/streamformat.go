@@ -0,0 +1,35 @@
+package transcoder
+
+import "strings"
+
+// Stream formats accepted in TranscodeProfile.StreamFormat.
+const (
+	StreamFormatHLS  = "hls"  // HLS with MPEG-TS segments, or fMP4 for HEVC and AV1 (default)
+	StreamFormatDASH = "dash" // DASH with fMP4 segments, or WebM segments for WebM variants
+)
+
+// DeliveryFormat returns the profile's stream format, defaulting to
+// StreamFormatHLS. A format named by the run (the CLI's -format,
+// pipeline.Config.StreamFormat) takes precedence.
+func (p *TranscodeProfile) DeliveryFormat() string {
+	if p.StreamFormat == "" {
+		return StreamFormatHLS
+	}
+	return strings.ToLower(p.StreamFormat)
+}
+
+// validateStreamFormat checks the stream format against the container: HLS
+// can't carry WebM segments.
+func validateStreamFormat(p TranscodeProfile, r *ValidationReport) {
+	switch strings.ToLower(p.StreamFormat) {
+	case "":
+		r.defaulted("stream_format", StreamFormatHLS)
+	case StreamFormatHLS, StreamFormatDASH:
+	default:
+		r.add(SeverityError, "stream_format", "unknown stream format %q (want hls or dash)", p.StreamFormat)
+		return
+	}
+	if strings.EqualFold(p.Container, "webm") && p.DeliveryFormat() == StreamFormatHLS {
+		r.add(SeverityWarning, "stream_format", "HLS can't carry WebM segments; set stream_format to dash for webm variants")
+	}
+}
@@ -0,0 +1,56 @@
+package transcoder
+
+import "github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+
+// SubtitleSettings selects the captions published alongside the video
+// (TranscodeProfile.Subtitles). Each one becomes a WebVTT rendition listed in
+// the master manifest.
+type SubtitleSettings struct {
+	Embedded bool           `json:"embedded,omitempty" yaml:"embedded,omitempty"` // Extract the source's text subtitle streams (SubRip, ASS, mov_text, WebVTT)
+	Files    []SubtitleFile `json:"files,omitempty" yaml:"files,omitempty"`       // External caption files (SRT, WebVTT, or TTML, any common encoding)
+}
+
+// SubtitleFile is an external caption file published as a rendition.
+type SubtitleFile struct {
+	Path     string `json:"path" yaml:"path"`                               // Caption file; format is taken from the extension, else sniffed
+	Language string `json:"language,omitempty" yaml:"language,omitempty"`   // Language tag (e.g. "en")
+	Name     string `json:"name,omitempty" yaml:"name,omitempty"`           // Name players show; defaults to the language
+	OffsetMs int    `json:"offset_ms,omitempty" yaml:"offset_ms,omitempty"` // Shift every cue by this many milliseconds (negative is earlier)
+	Default  bool   `json:"default,omitempty" yaml:"default,omitempty"`     // Select this rendition when the player has no preference
+	Forced   bool   `json:"forced,omitempty" yaml:"forced,omitempty"`       // Forced narrative (foreign-language dialogue only), listed with FORCED=YES
+}
+
+// Forced subtitle modes accepted in TranscodeProfile.ForcedSubtitles.
+const (
+	ForcedAuto      = "auto"      // Text tracks become FORCED=YES renditions, bitmap tracks are burned in (default)
+	ForcedRendition = "rendition" // Publish forced text tracks as FORCED=YES renditions; bitmap tracks are dropped
+	ForcedBurn      = "burn"      // Burn the primary forced track into every variant
+	ForcedOff       = "off"       // Treat forced tracks like any other subtitle stream
+)
+
+// ForcedSubtitleMode returns the profile's forced subtitle mode, defaulting to ForcedAuto.
+func (p *TranscodeProfile) ForcedSubtitleMode() string {
+	if p.ForcedSubtitles == "" {
+		return ForcedAuto
+	}
+	return p.ForcedSubtitles
+}
+
+// BurnedSubtitle returns the forced subtitle stream burned into every
+// variant, or nil: the primary forced track in burn mode, or in auto mode
+// when it is a bitmap track that can't be published as WebVTT.
+func (p *TranscodeProfile) BurnedSubtitle(media *analyzer.MediaInfo) *analyzer.StreamInfo {
+	if media == nil {
+		return nil
+	}
+	forced := media.PrimaryForcedSubtitle()
+	switch {
+	case forced == nil:
+		return nil
+	case p.ForcedSubtitleMode() == ForcedBurn:
+		return forced
+	case p.ForcedSubtitleMode() == ForcedAuto && !forced.IsTextSubtitle():
+		return forced
+	}
+	return nil
+}
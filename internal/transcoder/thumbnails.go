@@ -0,0 +1,55 @@
+package transcoder
+
+// Thumbnail sources accepted in TranscodeProfile.ThumbnailSource.
+const (
+	ThumbnailSourceAuto    = "auto"    // Variant at the source height, else the tallest variant, else the input (default)
+	ThumbnailSourceVariant = "variant" // Tallest transcoded variant, even when it is below the source height
+	ThumbnailSourceInput   = "input"   // Original input, scaled to the tallest variant
+	ThumbnailSourceLive    = "live"    // Original input like "input", sampled while the variants encode so thumbnails are ready when they finish
+)
+
+// ThumbnailSourceMode returns the profile's thumbnail source, defaulting to
+// ThumbnailSourceAuto.
+func (p *TranscodeProfile) ThumbnailSourceMode() string {
+	if p == nil || p.ThumbnailSource == "" {
+		return ThumbnailSourceAuto
+	}
+	return p.ThumbnailSource
+}
+
+// Sprite defaults used when TranscodeProfile.Sprites leaves a field unset.
+const (
+	DefaultSpriteWidth   = 160
+	DefaultSpriteColumns = 10
+	DefaultSpriteRows    = 10
+)
+
+// SpriteSettings configures scrubber sprite sheets: frames sampled at a fixed
+// interval, tiled into JPEG sheets and indexed by a WebVTT storyboard whose
+// cues point at each tile (sheet.jpg#xywh=x,y,w,h).
+type SpriteSettings struct {
+	Interval int `json:"interval,omitempty" yaml:"interval,omitempty"` // Seconds between tiles; defaults to the thumbnail interval
+	Width    int `json:"width,omitempty" yaml:"width,omitempty"`       // Tile width in pixels (default 160); height follows the aspect ratio
+	Columns  int `json:"columns,omitempty" yaml:"columns,omitempty"`   // Tiles per sheet row (default 10)
+	Rows     int `json:"rows,omitempty" yaml:"rows,omitempty"`         // Tile rows per sheet (default 10)
+}
+
+// SpriteSettings returns the profile's sprite settings with defaults applied,
+// leaving Interval zero when unset. Check Sprites != nil to see whether
+// sprites were requested.
+func (p *TranscodeProfile) SpriteSettings() SpriteSettings {
+	var s SpriteSettings
+	if p.Sprites != nil {
+		s = *p.Sprites
+	}
+	if s.Width <= 0 {
+		s.Width = DefaultSpriteWidth
+	}
+	if s.Columns <= 0 {
+		s.Columns = DefaultSpriteColumns
+	}
+	if s.Rows <= 0 {
+		s.Rows = DefaultSpriteRows
+	}
+	return s
+}
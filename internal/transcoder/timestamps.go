@@ -0,0 +1,82 @@
+package transcoder
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ErrDurationMismatch marks an output whose duration differs from the
+// source's by more than the profile's tolerance, usually the sign of
+// timestamp gaps or discontinuities in a damaged source.
+var ErrDurationMismatch = errors.New("duration mismatch")
+
+// DefaultDurationTolerance is how many seconds an output may differ from the
+// source duration before CheckDuration reports it.
+const DefaultDurationTolerance = 1.0
+
+// TimestampSettings repairs sources with timestamp gaps or discontinuities
+// (TranscodeProfile.Timestamps), which otherwise encode into stuttering
+// segments and outputs shorter or longer than the source.
+type TimestampSettings struct {
+	GenPTS            bool    `json:"genpts,omitempty" yaml:"genpts,omitempty"`                         // -fflags +genpts: generate missing presentation timestamps from decode order
+	AudioResync       bool    `json:"audio_resync,omitempty" yaml:"audio_resync,omitempty"`             // aresample=async=1: pad or trim audio across gaps so it keeps pace with the video
+	DurationTolerance float64 `json:"duration_tolerance,omitempty" yaml:"duration_tolerance,omitempty"` // Seconds outputs may differ from the source duration before a warning (default 1)
+}
+
+// InputFlags returns the input options placed before every -i that reads the
+// source: -fflags +genpts when timestamp generation is enabled, and the
+// looping and framerate options that turn an image input into a clip (see
+// StillInputFlags).
+func (p *TranscodeProfile) InputFlags() []string {
+	var flags []string
+	if p.Timestamps != nil && p.Timestamps.GenPTS {
+		flags = append(flags, "-fflags", "+genpts")
+	}
+	return append(flags, StillInputFlags(p.InputPath, p.StillSettings())...)
+}
+
+// AudioFilter returns the filter chain applied to encoded audio: gap
+// compensation (aresample=async) when AudioResync is set, then the A/V offset
+// correction (see AudioOffsetFilter). Returns "" when neither applies.
+// Copied audio can't be filtered.
+func (p *TranscodeProfile) AudioFilter() string {
+	var chain []string
+	if p.Timestamps != nil && p.Timestamps.AudioResync {
+		chain = append(chain, "aresample=async=1:first_pts=0")
+	}
+	if af := AudioOffsetFilter(p.AudioOffset()); af != "" {
+		chain = append(chain, af)
+	}
+	return strings.Join(chain, ",")
+}
+
+// DurationTolerance returns the configured duration tolerance in seconds,
+// or DefaultDurationTolerance.
+func (p *TranscodeProfile) DurationTolerance() float64 {
+	if p.Timestamps != nil && p.Timestamps.DurationTolerance > 0 {
+		return p.Timestamps.DurationTolerance
+	}
+	return DefaultDurationTolerance
+}
+
+// CheckDuration compares an output's measured duration against the duration
+// the source declares, returning an error wrapping ErrDurationMismatch when
+// they differ by more than the profile's tolerance. Unknown durations (zero)
+// are not checked. The audio offset is allowed for, since delaying the audio
+// lengthens the output.
+func (p *TranscodeProfile) CheckDuration(name string, declared, actual float64) error {
+	if declared <= 0 || actual <= 0 {
+		return nil
+	}
+	tolerance := p.DurationTolerance() + math.Abs(p.AudioOffset().Seconds())
+	if diff := actual - declared; math.Abs(diff) > tolerance {
+		hint := ""
+		if p.Timestamps == nil || !p.Timestamps.GenPTS || !p.Timestamps.AudioResync {
+			hint = "; the source may have timestamp gaps (try timestamps.genpts and timestamps.audio_resync)"
+		}
+		return fmt.Errorf("%w: %s is %.2fs, source declares %.2fs (%+.2fs)%s", ErrDurationMismatch, name, actual, declared, diff, hint)
+	}
+	return nil
+}
@@ -1,18 +1,19 @@
 package transcoder
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
 	"github.com/dotsoulja/dotgo-transcode/internal/executil"
-	"github.com/dotsoulja/dotgo-transcode/internal/scaler"
+	"github.com/dotsoulja/dotgo-transcode/internal/tracing"
 	"github.com/dotsoulja/dotgo-transcode/internal/utils/metadata"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/namer"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
 )
 
 // Transcode orchestrates resolution-aware transcoding for a given media file.
@@ -23,6 +24,20 @@ import (
 // This version includes average progress logging across all active variants,
 // and gracefully shuts down the progress ticker once transcoding completes.
 func Transcode(profile *TranscodeProfile, media *analyzer.MediaInfo, logger TranscodeLogger) (*TranscodeResult, error) {
+	return TranscodeContext(context.Background(), profile, media, logger)
+}
+
+// TranscodeContext is Transcode with a caller-supplied context.
+// Each variant encode is recorded as a child span of any trace carried by ctx.
+func TranscodeContext(ctx context.Context, profile *TranscodeProfile, media *analyzer.MediaInfo, logger TranscodeLogger) (*TranscodeResult, error) {
+	return TranscodeWithOptions(ctx, profile, media, logger, TranscodeOptions{})
+}
+
+// TranscodeWithOptions is TranscodeContext with per-run options, such as a
+// progress callback that lets embedding applications drive their own UI.
+func TranscodeWithOptions(ctx context.Context, profile *TranscodeProfile, media *analyzer.MediaInfo, logger TranscodeLogger, opts TranscodeOptions) (*TranscodeResult, error) {
+	logger = stagelog.OrStd(logger)
+
 	// Validate input/output paths and ensure output directory exists
 	logger.LogStage("init", "Validating input/output paths")
 	if err := validatePaths(profile.InputPath, profile.OutputDir); err != nil {
@@ -33,11 +48,18 @@ func Transcode(profile *TranscodeProfile, media *analyzer.MediaInfo, logger Tran
 		)
 	}
 
-	// Derive slug from input filename and create output subdirectory
-	baseName := filepath.Base(profile.InputPath)
-	slug := strings.TrimSuffix(baseName, filepath.Ext(baseName))
-	slugDir := filepath.Join(profile.OutputDir, slug)
+	// Plan variant encodes (resolution filtering, dedupe, command construction)
+	plan := PlanTranscode(profile, media, logger)
+	if plan.Err != nil {
+		return nil, NewTranscoderError(
+			"validation", "ladder", profile.InputPath, plan.SlugDir,
+			"no usable ladder for the source", nil, 0, plan.Err,
+		)
+	}
+	slugDir := plan.SlugDir
+	pending := opts.Pending(plan)
 
+	// Create output subdirectory for this slug
 	if err := os.MkdirAll(slugDir, os.ModePerm); err != nil {
 		logger.LogError("filesystem", err)
 		return nil, NewTranscoderError(
@@ -46,73 +68,82 @@ func Transcode(profile *TranscodeProfile, media *analyzer.MediaInfo, logger Tran
 		)
 	}
 
+	// Record which input owns the slug directory for collision detection
+	if profile.Layout().UsesSlug() {
+		if err := namer.Claim(slugDir, profile.InputPath); err != nil {
+			logger.LogError("filesystem", err)
+		}
+	}
+
+	// Probe the slates and clips joined to every variant
+	bumpers, err := profile.resolveBumpers(ctx)
+	if err != nil {
+		logger.LogError("bumpers", err)
+		return nil, NewTranscoderError(
+			"validation", "bumpers", profile.InputPath, slugDir,
+			"unusable bumper", nil, 0, err,
+		)
+	}
+
 	// Initialize result container
 	result := &TranscodeResult{
-		InputPath: profile.InputPath,
-		OutputDir: slugDir,
-		Duration:  media.Duration,
-		Success:   true,
-		Profile:   profile,
+		InputPath:      profile.InputPath,
+		OutputDir:      slugDir,
+		Duration:       media.Duration,
+		BumperDuration: bumperSeconds(bumpers),
+		PreRoll:        preRollSeconds(bumpers),
+		Success:        true,
+		Profile:        profile,
+		Variants:       append([]ResolutionVariant(nil), opts.Keep...),
+		Adjustments:    plan.Adjusted,
+	}
+	if len(opts.Keep) > 0 {
+		logger.LogStage("transcode", fmt.Sprintf("♻️ Keeping %d up-to-date variant(s)", len(opts.Keep)))
 	}
 
 	// Save duration to json for frontend consumption
-	if err := metadata.WriteMetadata(slugDir, profile.SegmentLength, media.Duration); err != nil {
+	if err := metadata.WriteMetadata(slugDir, profile.SegmentLength, result.OutputDuration()); err != nil {
 		logger.LogError("metadata", err)
-	}
-
-	// Filter out resolutions that exceed source media height
-	allowed := []Variant{}
-	for _, v := range profile.Variants {
-		_, h, err := scaler.DimensionsForLabel(v.Resolution)
-		if err != nil {
-			logger.LogVariant(v.Resolution, "⚠️ Unknown resolution label - skipping")
-			continue
-		}
-		if h <= media.Height {
-			allowed = append(allowed, v)
-		} else {
-			logger.LogVariant(v.Resolution, fmt.Sprintf("⛔ Skipping - source resolution (%dp) too low", media.Height))
-		}
+	} else {
+		logger.LogStage("metadata", fmt.Sprintf("📝 metadata.json written to %s (duration=%.2fs)", slugDir, result.OutputDuration()))
 	}
 
 	// Log resolution filtering summary
 	logger.LogStage("filter", fmt.Sprintf("🎞️ Source resolution: %dx%d", media.Width, media.Height))
-	logger.LogStage("filter", fmt.Sprintf("✅ Proceeding with %d allowed variants", len(allowed)))
+	logger.LogStage("filter", fmt.Sprintf("✅ Proceeding with %d allowed variants", len(plan.Variants)))
 
-	log.Printf("🚀 Starting concurrent transcoding for %d variants...", len(allowed))
+	logger.LogStage("transcode", fmt.Sprintf("🚀 Starting concurrent transcoding for %d variants...", len(pending)))
 	start := time.Now()
 
-	// Track seen variants to avoid duplicates
-	seen := make(map[string]bool)
-	var seenMu sync.Mutex
+	// Guards result mutation across variant goroutines
+	var resultMu sync.Mutex
 
-	// Track per-variant progress for average logging
-	progressMap := make(map[string]float64)
-	var progressMu sync.Mutex
+	// Track per-variant progress and ETA for average logging
+	progress := newProgressTracker(media.Duration)
 
 	// Channel to signal when transcoding is complete
 	done := make(chan struct{})
+	tickerStopped := make(chan struct{})
 
 	// Launch goroutine to emit average progress every 2 seconds
 	go func() {
+		defer close(tickerStopped)
 		ticker := time.NewTicker(2 * time.Second)
 		defer ticker.Stop()
 
 		for {
 			select {
 			case <-ticker.C:
-				progressMu.Lock()
-				if len(progressMap) == 0 {
-					progressMu.Unlock()
+				ev, ok := progress.aggregate()
+				if !ok {
 					continue
 				}
-				var total float64
-				for _, v := range progressMap {
-					total += v
+				label := fmt.Sprintf("⏳ Average across %d variants", ev.Active)
+				if eta := ev.ETAString(); eta != "" {
+					label += fmt.Sprintf(" (%.2fx, %s)", ev.Speed, eta)
 				}
-				avg := total / float64(len(progressMap))
-				log.Printf("[progress][⏳ Average across %d variants] - %.2f%%", len(progressMap), avg)
-				progressMu.Unlock()
+				logger.LogProgress(label, ev.Percent)
+				opts.emit(ev)
 
 			case <-done:
 				return // ✅ Stop emitting once transcoding is done
@@ -120,77 +151,98 @@ func Transcode(profile *TranscodeProfile, media *analyzer.MediaInfo, logger Tran
 		}
 	}()
 
+	// Fail-fast cancels sibling encodes through encodeCtx at the first failure
+	failFast := profile.PartialFailureSettings().Policy == PartialFailFast
+	encodeCtx, cancelEncodes := context.WithCancel(ctx)
+	defer cancelEncodes()
+
 	var wg sync.WaitGroup
 
-	for _, v := range allowed {
+	for _, pv := range pending {
 		wg.Add(1)
-		go func(v Variant) {
+		go func(pv PlannedVariant) {
 			defer wg.Done()
 
-			key := fmt.Sprintf("%s_%s", v.Resolution, v.Bitrate)
-
-			// Ensure variant is not duplicated
-			seenMu.Lock()
-			if seen[key] {
-				logger.LogVariant(key, "⚠️ Skipping duplicate variant")
-				seenMu.Unlock()
-				return
-			}
-			seen[key] = true
-			seenMu.Unlock()
-
-			// Resolve dimensions
-			width, height, err := scaler.DimensionsForLabel(v.Resolution)
-			if err != nil {
-				logger.LogVariant(v.Resolution, "⚠️ Unknown resolution label - using source dimensions")
-				width = media.Width
-				height = media.Height
-			}
-
-			// Build output path and ffmpeg command
-			outputFilename := fmt.Sprintf("%s_%s_%sbps.mp4", slug, v.Resolution, v.Bitrate)
-			outputPath := filepath.Join(slugDir, outputFilename)
-			cmd := buildFFmpegCommand(profile, v)
-			cmd[len(cmd)-1] = outputPath
-
+			key := pv.Key
+			cmd := pv.Command
 			logger.LogVariant(key, fmt.Sprintf("🔧 Building ffmpeg command: %s", strings.Join(cmd, " ")))
 
+			_, span := tracing.Start(encodeCtx, "transcode.variant",
+				tracing.AttrSlug.String(plan.Slug),
+				tracing.AttrVariant.String(key),
+			)
+
 			// Execute ffmpeg with progress tracking
-			err = executil.RunCommandWithProgress(cmd, media.Duration, func(percent float64) {
-				progressMu.Lock()
-				progressMap[key] = percent
-				progressMu.Unlock()
+			encodeStart := time.Now()
+			progress.start(key)
+			err := encodeVariant(encodeCtx, profile, pv, media, bumpers, opts, logger, func(percent float64) {
+				opts.emit(progress.update(key, percent))
 			})
+			span.SetAttributes(tracing.AttrExitCode.Int(executil.ExitCode(err)))
+			tracing.End(span, err)
 			if err != nil {
+				opts.emit(progress.finish(key, true))
+				if encodeCtx.Err() != nil && ctx.Err() == nil {
+					logger.LogVariant(key, "⏹️ Cancelled after another variant failed")
+					return
+				}
 				logger.LogError("transcode", err)
-				seenMu.Lock()
+				resultMu.Lock()
 				result.Success = false
 				result.Errors = append(result.Errors, NewTranscoderError(
-					"execution", "transcode", profile.InputPath, outputPath,
-					"ffmpeg command failed", cmd, 1, err,
+					"execution", "transcode", profile.InputPath, pv.OutputPath,
+					"ffmpeg command failed", cmd, executil.ExitCode(err), err,
 				))
-				seenMu.Unlock()
+				resultMu.Unlock()
+				if failFast {
+					cancelEncodes()
+				}
 				return
 			}
 
+			opts.emit(progress.finish(key, false))
+
+			stats, probe := measureEncode(pv.OutputPath, media, time.Since(encodeStart), logger)
+
 			// Record successful variant
-			seenMu.Lock()
-			result.Variants = append(result.Variants, ResolutionVariant{
-				Width:          width,
-				Height:         height,
-				Bitrate:        v.Bitrate,
+			variant := ResolutionVariant{
+				Width:          pv.Width,
+				Height:         pv.Height,
+				Bitrate:        pv.Variant.Bitrate,
 				ScaleFlag:      "auto",
-				OutputFilename: outputFilename,
-			})
-			seenMu.Unlock()
+				OutputFilename: pv.OutputFilename,
+				Codec:          pv.Codec,
+				Tier:           pv.Tier,
+				Codecs:         rfc6381Codecs(pv.Codec, pv.Height, probe, profile.AudioCodec, profile.Container),
+				Stats:          stats,
+				Settings:       newEncoderSettings(profile, pv),
+			}
+			resultMu.Lock()
+			result.Variants = append(result.Variants, variant)
+			resultMu.Unlock()
+			if opts.OnVariant != nil {
+				opts.OnVariant(variant)
+			}
 
-			logger.LogVariant(key, fmt.Sprintf("✅ Transcoding succeeded: (%dx%d) @ %s)", width, height, v.Bitrate))
-		}(v)
+			logger.LogVariant(key, fmt.Sprintf("✅ Transcoding succeeded: (%dx%d) @ %s)", pv.Width, pv.Height, pv.Variant.Bitrate))
+			logger.LogVariant(key, fmt.Sprintf("📊 %s, %.1f fps, %.2fx realtime, %d kbps measured, %d bytes",
+				stats.WallTime.Round(time.Millisecond), stats.AvgFPS, stats.RealtimeFactor, stats.MeasuredBitrate, stats.FileSize))
+		}(pv)
 	}
 
 	wg.Wait()
 	close(done) // ✅ Signal progress ticker to stop
-	logger.LogStage("complete", fmt.Sprintf("🏁 All transcoding tasks completed in %s", time.Since(start)))
-
+	<-tickerStopped
+	opts.emit(ProgressEvent{Percent: 100, Aggregate: true, Active: len(pending), Done: true})
+	result.WallTime = time.Since(start)
+	logger.LogStage("complete", fmt.Sprintf("🏁 All transcoding tasks completed in %s", result.WallTime))
+
+	if err := result.ApplyPartialPolicy(); err != nil {
+		logger.LogError("transcode", err)
+		return nil, err
+	}
+	if result.Degraded {
+		logger.LogStage("transcode", fmt.Sprintf("⚠️ %d variant(s) failed; publishing the %d that succeeded", len(result.Errors), len(result.Variants)))
+	}
 	return result, nil
 }
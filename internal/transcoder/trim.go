@@ -0,0 +1,163 @@
+package transcoder
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+)
+
+// Content classes accepted in LadderTrim.Content.
+const (
+	ContentAuto       = "auto"                     // Classify from analysis (see analyzer.MediaInfo.ContentClass)
+	ContentFilm       = analyzer.ContentFilm       // Treat the source as film; no content trimming
+	ContentScreencast = analyzer.ContentScreencast // Treat the source as a screencast
+)
+
+// Ladder trim defaults used when TranscodeProfile.Trim leaves a field unset.
+const (
+	DefaultTrimShortDuration         = 120
+	DefaultTrimShortMaxVariants      = 3
+	DefaultTrimScreencastMaxVariants = 2
+)
+
+// LadderTrim drops rungs a source doesn't need (TranscodeProfile.Trim), to
+// save encode time on bulk short-form ingestion. Each rule caps the number of
+// primary-codec rungs; the tightest applicable cap wins. Short clips and
+// max_variants keep the tallest and shortest rungs and spread the rest
+// between them; screencasts keep the tallest rungs, where text stays legible.
+// Secondary codec tiers follow the resolutions their primary rungs keep.
+type LadderTrim struct {
+	ShortDuration         int    `json:"short_duration,omitempty" yaml:"short_duration,omitempty"`                   // Clips shorter than this many seconds are short (default 120)
+	ShortMaxVariants      int    `json:"short_max_variants,omitempty" yaml:"short_max_variants,omitempty"`           // Rungs kept for short clips (default 3)
+	Content               string `json:"content,omitempty" yaml:"content,omitempty"`                                 // "auto" classifies the source, "film" or "screencast" forces it; unset skips content trimming
+	ScreencastMaxVariants int    `json:"screencast_max_variants,omitempty" yaml:"screencast_max_variants,omitempty"` // Rungs kept for screencasts (default 2)
+	MaxVariants           int    `json:"max_variants,omitempty" yaml:"max_variants,omitempty"`                       // Rungs kept for any source; 0 for no cap
+}
+
+// LadderTrim returns the profile's trim settings with defaults applied.
+// Check Trim != nil to see whether trimming was requested.
+func (p *TranscodeProfile) LadderTrim() LadderTrim {
+	var t LadderTrim
+	if p.Trim != nil {
+		t = *p.Trim
+	}
+	if t.ShortDuration <= 0 {
+		t.ShortDuration = DefaultTrimShortDuration
+	}
+	if t.ShortMaxVariants <= 0 {
+		t.ShortMaxVariants = DefaultTrimShortMaxVariants
+	}
+	if t.ScreencastMaxVariants <= 0 {
+		t.ScreencastMaxVariants = DefaultTrimScreencastMaxVariants
+	}
+	return t
+}
+
+// trimLadder applies the profile's trim rules to rungs, returning the rungs
+// kept and reasons for those dropped.
+func (p *TranscodeProfile) trimLadder(rungs []budgetRung, media *analyzer.MediaInfo, logger TranscodeLogger) ([]budgetRung, []string) {
+	if p.Trim == nil {
+		return rungs, nil
+	}
+	t := p.LadderTrim()
+
+	limit, reason, tallest := 0, "", false
+	tighten := func(n int, why string, top bool) {
+		if n > 0 && (limit == 0 || n < limit) {
+			limit, reason, tallest = n, why, top
+		}
+	}
+	tighten(t.MaxVariants, fmt.Sprintf("max_variants %d", t.MaxVariants), false)
+	if media.Duration > 0 && media.Duration < float64(t.ShortDuration) {
+		tighten(t.ShortMaxVariants, fmt.Sprintf("clip shorter than %ds", t.ShortDuration), false)
+	}
+	content := t.Content
+	if content == ContentAuto {
+		content = media.ContentClass()
+		if content != "" {
+			logger.LogStage("filter", fmt.Sprintf("🔎 Source classified as %s", content))
+		}
+	}
+	if content == ContentScreencast {
+		tighten(t.ScreencastMaxVariants, "screencast", true)
+	}
+
+	// Rank the distinct primary resolutions from tallest to shortest
+	var heights []int
+	seen := make(map[int]bool)
+	for _, r := range rungs {
+		if p.codecTier(r.Variant) == "" && !seen[r.Height] {
+			seen[r.Height] = true
+			heights = append(heights, r.Height)
+		}
+	}
+	if limit == 0 || len(heights) <= limit {
+		return rungs, nil
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(heights)))
+	keep := make(map[int]bool, limit)
+	for _, i := range pickRungs(len(heights), limit, tallest) {
+		keep[heights[i]] = true
+	}
+
+	var kept []budgetRung
+	var dropped []string
+	for _, r := range rungs {
+		if keep[r.Height] {
+			kept = append(kept, r)
+			continue
+		}
+		logger.LogVariant(r.Key, fmt.Sprintf("✂️ Trimmed from the ladder (%s)", reason))
+		dropped = append(dropped, fmt.Sprintf("%s: trimmed (%s)", r.Key, reason))
+	}
+	return kept, dropped
+}
+
+// pickRungs chooses n of total indices ordered tallest first: the first n
+// when tallest is set, otherwise the first and last with the rest evenly
+// spaced between them.
+func pickRungs(total, n int, tallest bool) []int {
+	picks := make([]int, n)
+	for i := range picks {
+		switch {
+		case tallest:
+			picks[i] = i
+		case n == 1:
+			picks[i] = 0
+		default:
+			picks[i] = (i*(total-1) + (n-1)/2) / (n - 1)
+		}
+	}
+	return picks
+}
+
+// validateTrim checks the ladder trim block.
+func validateTrim(p TranscodeProfile, r *ValidationReport) {
+	t := p.Trim
+	if t == nil {
+		return
+	}
+	switch t.Content {
+	case "", ContentAuto, ContentFilm, ContentScreencast:
+	default:
+		r.add(SeverityError, "trim.content", "unknown content class %q (want auto, film, or screencast)", t.Content)
+	}
+	counts := []struct {
+		field string
+		n     int
+	}{
+		{"short_duration", t.ShortDuration},
+		{"short_max_variants", t.ShortMaxVariants},
+		{"screencast_max_variants", t.ScreencastMaxVariants},
+		{"max_variants", t.MaxVariants},
+	}
+	for _, c := range counts {
+		if c.n < 0 {
+			r.add(SeverityError, "trim."+c.field, "%s must be zero or positive", c.field)
+		}
+	}
+	if p.LowSource != nil && t.MaxVariants > 0 && t.MaxVariants < p.LowSourceSettings().MinRungs {
+		r.add(SeverityWarning, "trim.max_variants", "max_variants %d is below low_source.min_rungs %d", t.MaxVariants, p.LowSourceSettings().MinRungs)
+	}
+}
@@ -1,13 +1,31 @@
 package transcoder
 
+import "time"
+
 // ResolutionVariant represents a single output resolution and its settings.
 // Used to track successful transcodes and feed into segmentation and manifest generation.
 type ResolutionVariant struct {
-	Width          int    // Output width in pixels (e.g. 1280)
-	Height         int    // Output height in pixels (e.g. 720)
-	Bitrate        string // Target bitrate string (e.g. "1500k")
-	ScaleFlag      string // Scaling behavior: "auto", "force", "skip"
-	OutputFilename string // Final output filename (e.g. "video_720p_1500kbps.mp4")
+	Width          int             // Output width in pixels (e.g. 1280)
+	Height         int             // Output height in pixels (e.g. 720)
+	Bitrate        string          // Target bitrate string (e.g. "1500k")
+	ScaleFlag      string          // Scaling behavior: "auto", "force", "skip"
+	OutputFilename string          // Final output filename (e.g. "video_720p_1500kbps.mp4")
+	Codec          string          // Video codec family (e.g. "h264", "hevc", "av1")
+	Tier           string          // Codec family of a secondary codec tier, "" for the profile's primary codec
+	Codecs         string          // RFC 6381 CODECS value for manifests (e.g. "hvc1.1.6.L120.B0,mp4a.40.2")
+	Stats          EncodeStats     // Measured encode performance and output size
+	Settings       EncoderSettings // Command, ffmpeg version, and profile hash the variant was encoded with
+}
+
+// EncodeStats records how a single variant encode actually performed.
+// Measured values come from ffprobe on the output and are zero if probing failed.
+type EncodeStats struct {
+	WallTime        time.Duration // Wall-clock time spent in ffmpeg
+	AvgFPS          float64       // Average frames encoded per wall-clock second
+	RealtimeFactor  float64       // Media seconds encoded per wall-clock second (>1 is faster than realtime)
+	FileSize        int64         // Output file size in bytes
+	MeasuredBitrate int           // Average bitrate of the output in kbps (from ffprobe)
+	Duration        float64       // Output duration in seconds (from ffprobe)
 }
 
 // TranscodeResult captures the outcome of a transcoding operation.
@@ -15,11 +33,16 @@ type ResolutionVariant struct {
 // ResolutionVariant for each successfully generated output.
 // Errors are tracked with full forensic detail for debugging and logging.
 type TranscodeResult struct {
-	InputPath string              // Original input file path (e.g. "media/movie.mp4")
-	OutputDir string              // Directory where outputs were written (e.g. "media/output/movie/")
-	Duration  float64             // Duration of input media in seconds
-	Success   bool                // Overall success flag (false if any variant failed)
-	Variants  []ResolutionVariant // Successfully transcoded variants
-	Profile   *TranscodeProfile   // Profile used for transcoding (includes codec, bitrate, etc.)
-	Errors    []*TranscoderError  // Detailed error records (stage, command, exit code, etc.)
+	InputPath      string              // Original input file path (e.g. "media/movie.mp4")
+	OutputDir      string              // Directory where outputs were written (e.g. "media/output/movie/")
+	Duration       float64             // Duration of input media in seconds
+	BumperDuration float64             // Seconds of pre- and post-roll joined to every variant (see BumperSettings)
+	PreRoll        float64             // Seconds of BumperDuration played before the content
+	Success        bool                // Overall success flag (false if any variant failed)
+	Variants       []ResolutionVariant // Successfully transcoded variants
+	Profile        *TranscodeProfile   // Profile used for transcoding (includes codec, bitrate, etc.)
+	Errors         []*TranscoderError  // Detailed error records (stage, command, exit code, etc.)
+	WallTime       time.Duration       // Wall-clock time for all variant encodes
+	Adjustments    []string            // Changes planning made to the profile's variants (e.g. bitrates capped to the source)
+	Degraded       bool                // Some variants failed and only the rest are published (see PartialFailureSettings)
 }
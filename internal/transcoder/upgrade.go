@@ -0,0 +1,266 @@
+package transcoder
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/metadata"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
+)
+
+// UpgradeAction says what a ladder upgrade does with one rung.
+type UpgradeAction string
+
+// Upgrade actions.
+const (
+	UpgradeKeep     UpgradeAction = "keep"     // Existing encode matches the profile
+	UpgradeEncode   UpgradeAction = "encode"   // Rung is new to the ladder
+	UpgradeReencode UpgradeAction = "reencode" // Rung exists but was encoded with different settings
+	UpgradeObsolete UpgradeAction = "obsolete" // Existing rung the profile no longer lists
+)
+
+// UpgradeOptions tunes how PlanUpgrade decides a rung is out of date.
+type UpgradeOptions struct {
+	// FFmpegChanges also re-encodes rungs produced by a different ffmpeg
+	// version, even when their arguments are unchanged.
+	FFmpegChanges bool
+	// Prune deletes obsolete rungs, files and segments, once a pipeline run
+	// has written the new master manifest. PlanUpgrade itself never deletes.
+	Prune bool
+}
+
+// UpgradeStep is the decision for one rung.
+type UpgradeStep struct {
+	Action   UpgradeAction
+	Filename string             // Variant file inside the slug directory
+	Reason   string             // Why the action was chosen
+	Planned  *PlannedVariant    // Encode to run or keep; nil for obsolete rungs
+	Existing *ResolutionVariant // Encode on disk; nil for new rungs
+}
+
+// UpgradePlan compares an existing output directory against a profile.
+type UpgradePlan struct {
+	Transcode *TranscodePlan // Full plan for the profile
+	Steps     []UpgradeStep  // Planned rungs in profile order, then obsolete ones
+}
+
+// PlanUpgrade compares the variants already encoded for profile's input with
+// what the profile would encode now, so a run only encodes new rungs and
+// rungs whose settings changed (e.g. adding 1440p, or switching 1080p to
+// HEVC). A rung is out of date when the ffmpeg arguments recorded for it in
+// metadata.json (see EncoderSettings) differ from the planned ones, input and
+// output paths aside. Rungs without recorded settings are kept when their
+// codec and resolution match. Nothing is written.
+func PlanUpgrade(profile *TranscodeProfile, media *analyzer.MediaInfo, logger TranscodeLogger, opts UpgradeOptions) (*UpgradePlan, error) {
+	logger = stagelog.OrStd(logger)
+	plan := &UpgradePlan{Transcode: PlanTranscode(profile, media, logger)}
+	if err := plan.Transcode.Err; err != nil {
+		return nil, err
+	}
+	slugDir := plan.Transcode.SlugDir
+
+	var existing []ResolutionVariant
+	if _, err := os.Stat(slugDir); err == nil {
+		if existing, err = DiscoverVariants(slugDir, profile.variantBase(), profile.Container, logger); err != nil {
+			return nil, err
+		}
+	}
+	onDisk := make(map[string]*ResolutionVariant, len(existing))
+	for i := range existing {
+		onDisk[existing[i].OutputFilename] = &existing[i]
+	}
+
+	recorded := make(map[string]*metadata.EncoderMetadata)
+	meta, err := metadata.ReadMediaMetadata(slugDir)
+	switch {
+	case err == nil:
+		for _, v := range meta.Variants {
+			if v.Encoder != nil {
+				recorded[v.Filename] = v.Encoder
+			}
+		}
+	case !errors.Is(err, fs.ErrNotExist):
+		logger.LogError("upgrade", fmt.Errorf("ignoring unreadable metadata.json: %w", err))
+	}
+
+	version, _ := FFmpegVersion()
+	for i := range plan.Transcode.Variants {
+		pv := &plan.Transcode.Variants[i]
+		step := UpgradeStep{Filename: pv.OutputFilename, Planned: pv, Existing: onDisk[pv.OutputFilename]}
+		delete(onDisk, pv.OutputFilename)
+		rec := recorded[pv.OutputFilename]
+
+		switch {
+		case step.Existing == nil:
+			step.Action, step.Reason = UpgradeEncode, "new rung"
+		case rec != nil && len(rec.Command) > 0:
+			if diff := commandDiff(rec.Command, pv.Command); diff != "" {
+				step.Action, step.Reason = UpgradeReencode, "settings changed: "+diff
+			} else if opts.FFmpegChanges && rec.FFmpeg != version {
+				step.Action, step.Reason = UpgradeReencode, fmt.Sprintf("encoded with ffmpeg %s, now %s", orUnknown(rec.FFmpeg), orUnknown(version))
+			} else {
+				step.Action, step.Reason = UpgradeKeep, "settings unchanged"
+				step.Existing.Settings = settingsFromMetadata(rec)
+			}
+		case step.Existing.Codec != "" && step.Existing.Codec != pv.Codec:
+			step.Action, step.Reason = UpgradeReencode, fmt.Sprintf("codec changed: %s → %s", step.Existing.Codec, pv.Codec)
+		default:
+			step.Action, step.Reason = UpgradeKeep, "no recorded settings; codec and resolution match"
+		}
+		plan.Steps = append(plan.Steps, step)
+	}
+	for i := range existing {
+		if v := &existing[i]; onDisk[v.OutputFilename] != nil {
+			plan.Steps = append(plan.Steps, UpgradeStep{
+				Action:   UpgradeObsolete,
+				Filename: v.OutputFilename,
+				Reason:   "no longer in the profile",
+				Existing: v,
+			})
+		}
+	}
+	return plan, nil
+}
+
+// Kept returns the existing variants the upgrade leaves as they are.
+func (p *UpgradePlan) Kept() []ResolutionVariant {
+	var out []ResolutionVariant
+	for _, s := range p.Steps {
+		if s.Action == UpgradeKeep {
+			out = append(out, *s.Existing)
+		}
+	}
+	return out
+}
+
+// Obsolete returns the existing variants the profile no longer lists.
+func (p *UpgradePlan) Obsolete() []ResolutionVariant {
+	var out []ResolutionVariant
+	for _, s := range p.Steps {
+		if s.Action == UpgradeObsolete {
+			out = append(out, *s.Existing)
+		}
+	}
+	return out
+}
+
+// Pending counts the rungs the upgrade encodes.
+func (p *UpgradePlan) Pending() int {
+	n := 0
+	for _, s := range p.Steps {
+		if s.Action == UpgradeEncode || s.Action == UpgradeReencode {
+			n++
+		}
+	}
+	return n
+}
+
+// Print writes a human-readable summary of the plan to w.
+func (p *UpgradePlan) Print(w io.Writer) {
+	fmt.Fprintf(w, "\n🪜 Ladder upgrade for %s (%d to encode)\n", p.Transcode.SlugDir, p.Pending())
+	icons := map[UpgradeAction]string{
+		UpgradeKeep:     "✅",
+		UpgradeEncode:   "➕",
+		UpgradeReencode: "🔁",
+		UpgradeObsolete: "🗑️",
+	}
+	for _, s := range p.Steps {
+		fmt.Fprintf(w, "   %s %-9s %s (%s)\n", icons[s.Action], s.Action, s.Filename, s.Reason)
+	}
+}
+
+// resourceOptions are ffmpeg options that change how fast an encode runs but
+// not what it produces, so they never make a rung out of date. -threads
+// shifts whenever a thread budget is split across a different ladder.
+var resourceOptions = []string{"-threads"}
+
+// commandDiff describes the first option that differs between two ffmpeg
+// commands once paths are normalized and resource options dropped, or
+// returns "" if they match.
+func commandDiff(old, cur []string) string {
+	old, cur = withoutOptions(old, resourceOptions), withoutOptions(cur, resourceOptions)
+	a, b := optionMap(normalizeCommand(old)), optionMap(normalizeCommand(cur))
+	for _, o := range b.order {
+		if prev, ok := a.values[o]; !ok {
+			return fmt.Sprintf("%s %s added", o, b.values[o])
+		} else if prev != b.values[o] {
+			return fmt.Sprintf("%s %s → %s", o, prev, b.values[o])
+		}
+	}
+	for _, o := range a.order {
+		if _, ok := b.values[o]; !ok {
+			return fmt.Sprintf("%s %s removed", o, a.values[o])
+		}
+	}
+	if strings.Join(normalizeCommand(old), "\x00") != strings.Join(normalizeCommand(cur), "\x00") {
+		return "argument order changed"
+	}
+	return ""
+}
+
+// withoutOptions copies cmd without the given flags and their arguments.
+func withoutOptions(cmd []string, flags []string) []string {
+	out := make([]string, 0, len(cmd))
+	for i := 0; i < len(cmd); i++ {
+		if contains(flags, cmd[i]) && i+1 < len(cmd) {
+			i++
+			continue
+		}
+		out = append(out, cmd[i])
+	}
+	return out
+}
+
+// options is an ffmpeg command split into "-flag" → value pairs.
+type options struct {
+	order  []string
+	values map[string]string
+}
+
+// optionMap pairs each -flag with the argument after it, "" for bare flags.
+// Repeated flags are joined with commas.
+func optionMap(cmd []string) options {
+	o := options{values: make(map[string]string)}
+	for i := 1; i < len(cmd); i++ {
+		if !strings.HasPrefix(cmd[i], "-") {
+			continue
+		}
+		flag, value := cmd[i], ""
+		if i+1 < len(cmd) && !strings.HasPrefix(cmd[i+1], "-") {
+			value = cmd[i+1]
+			i++
+		}
+		if prev, ok := o.values[flag]; ok {
+			o.values[flag] = prev + "," + value
+			continue
+		}
+		o.order = append(o.order, flag)
+		o.values[flag] = value
+	}
+	return o
+}
+
+// settingsFromMetadata converts recorded encoder metadata back to settings.
+func settingsFromMetadata(m *metadata.EncoderMetadata) EncoderSettings {
+	return EncoderSettings{
+		Command:     m.Command,
+		Encoder:     m.Encoder,
+		FFmpeg:      m.FFmpeg,
+		Libraries:   m.Libraries,
+		ProfileHash: m.ProfileHash,
+		Hash:        m.SettingsHash,
+	}
+}
+
+// orUnknown returns s, or "unknown" when it is empty.
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
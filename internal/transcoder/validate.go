@@ -0,0 +1,534 @@
+package transcoder
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/errclass"
+	"github.com/dotsoulja/dotgo-transcode/internal/executil"
+	"github.com/dotsoulja/dotgo-transcode/internal/scaler"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/layout"
+)
+
+// Severity classifies a profile issue.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"   // Profile cannot be used as-is
+	SeverityWarning Severity = "warning" // Profile works but likely not as intended
+)
+
+// ProfileIssue is a single problem found while validating a profile.
+// Field uses the profile's JSON/YAML key path (e.g. "variants[2].bitrate").
+type ProfileIssue struct {
+	Field    string
+	Severity Severity
+	Message  string
+}
+
+// String formats the issue as "<severity>: <field>: <message>".
+func (i ProfileIssue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Severity, i.Field, i.Message)
+}
+
+// DefaultValue records a setting left unset in the profile and what will be used instead.
+type DefaultValue struct {
+	Field string // Profile key (e.g. "audio_codec")
+	Value string // Effective value (e.g. "aac")
+}
+
+// ValidationReport collects every issue found in a profile plus the defaults
+// that will be applied, so all problems can be fixed in one pass.
+type ValidationReport struct {
+	Issues   []ProfileIssue
+	Defaults []DefaultValue
+}
+
+// ValidationError is returned when a profile has one or more error-severity issues.
+type ValidationError struct {
+	Issues []ProfileIssue
+}
+
+// Error lists every error-severity issue with its field path.
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		parts[i] = fmt.Sprintf("%s: %s", issue.Field, issue.Message)
+	}
+	return fmt.Sprintf("%d profile problem(s): %s", len(e.Issues), strings.Join(parts, "; "))
+}
+
+// Class reports validation failures as invalid configuration (see errclass).
+func (e *ValidationError) Class() errclass.Class {
+	return errclass.InvalidConfig
+}
+
+// Errors returns only error-severity issues.
+func (r *ValidationReport) Errors() []ProfileIssue {
+	return r.filter(SeverityError)
+}
+
+// Warnings returns only warning-severity issues.
+func (r *ValidationReport) Warnings() []ProfileIssue {
+	return r.filter(SeverityWarning)
+}
+
+// OK reports whether the profile has no error-severity issues.
+func (r *ValidationReport) OK() bool {
+	return len(r.Errors()) == 0
+}
+
+// Err returns a *ValidationError when the report contains errors, or nil.
+func (r *ValidationReport) Err() error {
+	if errs := r.Errors(); len(errs) > 0 {
+		return &ValidationError{Issues: errs}
+	}
+	return nil
+}
+
+// Print writes a human-readable report of issues and applied defaults to w.
+func (r *ValidationReport) Print(w io.Writer) {
+	if len(r.Issues) == 0 {
+		fmt.Fprintln(w, "✅ No problems found")
+	}
+	for _, issue := range r.Issues {
+		icon := "⚠️"
+		if issue.Severity == SeverityError {
+			icon = "❌"
+		}
+		fmt.Fprintf(w, "%s %-24s %s\n", icon, issue.Field, issue.Message)
+	}
+	if len(r.Defaults) > 0 {
+		fmt.Fprintln(w, "\n🔧 Defaults applied:")
+		for _, d := range r.Defaults {
+			fmt.Fprintf(w, "   • %-22s %s\n", d.Field, d.Value)
+		}
+	}
+}
+
+func (r *ValidationReport) filter(sev Severity) []ProfileIssue {
+	var out []ProfileIssue
+	for _, issue := range r.Issues {
+		if issue.Severity == sev {
+			out = append(out, issue)
+		}
+	}
+	return out
+}
+
+func (r *ValidationReport) add(sev Severity, field, format string, args ...any) {
+	r.Issues = append(r.Issues, ProfileIssue{Field: field, Severity: sev, Message: fmt.Sprintf(format, args...)})
+}
+
+func (r *ValidationReport) defaulted(field, format string, args ...any) {
+	r.Defaults = append(r.Defaults, DefaultValue{Field: field, Value: fmt.Sprintf(format, args...)})
+}
+
+// bitratePattern matches the bitrate strings the transcoder accepts (e.g. "3000k").
+var bitratePattern = regexp.MustCompile(`^[0-9]+[kK]$`)
+
+// containerCodecs lists the video and audio codec families each container can carry.
+// Containers not listed (e.g. mkv) accept any codec.
+var containerCodecs = map[string]struct{ video, audio []string }{
+	"mp4":  {video: []string{"h264", "hevc", "av1"}, audio: []string{"aac", "mp3", "ac3", "eac3", "alac", "flac", "opus"}},
+	"m4v":  {video: []string{"h264", "hevc", "av1"}, audio: []string{"aac", "mp3", "ac3", "eac3", "alac"}},
+	"mov":  {video: []string{"h264", "hevc", "av1", "prores"}, audio: []string{"aac", "mp3", "ac3", "eac3", "alac", "pcm"}},
+	"webm": {video: []string{"vp8", "vp9", "av1"}, audio: []string{"opus", "vorbis"}},
+	"ts":   {video: []string{"h264", "hevc"}, audio: []string{"aac", "mp3", "ac3", "eac3"}},
+}
+
+// knownContainers are containers the pipeline has been exercised with.
+var knownContainers = []string{"mp4", "m4v", "mov", "mkv", "webm", "ts"}
+
+// ValidateProfile checks p and returns every problem found, with field paths.
+// The profile is not modified; unset fields are checked as if defaults were applied
+// and listed in the report's Defaults.
+//
+// Checks include required fields, variant labels against the known resolution
+// presets, bitrate string formats, container/codec compatibility (e.g. vp9 in mp4),
+// numeric ranges, and — when media is non-nil — segment length against the
+// source GOP and variants that exceed the source height.
+func ValidateProfile(p TranscodeProfile, media *analyzer.MediaInfo) *ValidationReport {
+	r := &ValidationReport{}
+
+	// Required fields
+	if p.InputPath == "" {
+		r.add(SeverityError, "input_path", "missing input_path")
+	} else if analyzer.ImageInputKind(p.InputPath) == analyzer.ImageSequence {
+		if _, err := analyzer.ScanImageSequence(p.InputPath); err != nil {
+			r.add(SeverityWarning, "input_path", "image sequence not found: %v", err)
+		}
+	} else if _, err := os.Stat(p.InputPath); err != nil {
+		r.add(SeverityWarning, "input_path", "input file not found: %s", p.InputPath)
+	}
+	if p.OutputDir == "" {
+		r.add(SeverityError, "output_dir", "missing output_dir")
+	}
+	if p.VideoCodec == "" {
+		r.add(SeverityError, "video_codec", "missing video_codec")
+	}
+	if p.Container == "" {
+		r.add(SeverityError, "container", "missing container format")
+	}
+
+	// Codecs and container compatibility
+	audioCodec := p.AudioCodec
+	if audioCodec == "" {
+		audioCodec = "aac"
+		r.defaulted("audio_codec", "aac")
+	}
+	container := strings.ToLower(p.Container)
+	if container != "" && !contains(knownContainers, container) {
+		r.add(SeverityWarning, "container", "unrecognized container %q (known: %s)", p.Container, strings.Join(knownContainers, ", "))
+	}
+	videoFamily := helpers.CodecFamily(p.VideoCodec)
+	if p.VideoCodec != "" && videoFamily == "" {
+		r.add(SeverityWarning, "video_codec", "unrecognized video codec %q; ffmpeg must support it", p.VideoCodec)
+	}
+	if allowed, ok := containerCodecs[container]; ok {
+		if videoFamily != "" && videoFamily != "copy" && !contains(allowed.video, videoFamily) {
+			r.add(SeverityError, "video_codec", "%s video cannot be stored in %s (supported: %s)", p.VideoCodec, container, strings.Join(allowed.video, ", "))
+		}
+		if family := helpers.CodecFamily(audioCodec); family != "" && family != "copy" && !contains(allowed.audio, family) {
+			r.add(SeverityError, "audio_codec", "%s audio cannot be stored in %s (supported: %s)", audioCodec, container, strings.Join(allowed.audio, ", "))
+		}
+	}
+	switch p.HWAccel {
+	case "", HWAccelAuto:
+		if p.UseHardwareAccel {
+			r.defaulted("hwaccel", HWAccelAuto)
+		}
+	case HWAccelVideoToolbox, HWAccelNVENC, HWAccelQSV, HWAccelVAAPI, HWAccelAMF:
+		if !contains(hwAccelBackends(runtime.GOOS), p.HWAccel) {
+			r.add(SeverityWarning, "hwaccel", "%s is not available on %s (supported: %s)", p.HWAccel, runtime.GOOS, strings.Join(hwAccelBackends(runtime.GOOS), ", "))
+		}
+	default:
+		r.add(SeverityError, "hwaccel", "unknown hwaccel backend %q (want auto, videotoolbox, nvenc, qsv, vaapi, or amf)", p.HWAccel)
+	}
+	if p.UseHardwareAccel && !isHardwareEncoder(p.VideoCodec) {
+		if !contains(hwAccelFamilies, videoFamily) {
+			r.add(SeverityWarning, "use_hwaccel", "no hardware encoders exist for %s; encoding will use software", p.VideoCodec)
+		} else if _, _, ok := selectHWAccel(runtime.GOOS, p.VideoCodec, p.HWAccel, DefaultHWProber()); !ok {
+			r.add(SeverityWarning, "use_hwaccel", "no usable hardware %s encoder found on this machine; encoding will use %s", videoFamily, p.VideoCodec)
+		}
+	}
+
+	p.AV1.validate(r, videoFamily)
+	p.VP9.validate(r, videoFamily)
+	p.RateControl.validate(r, videoFamily)
+
+	// Variants
+	if len(p.Variants) == 0 {
+		r.add(SeverityError, "variants", "variants must include at least one resolution/bitrate pair")
+	}
+	seen := make(map[string]int)
+	for i, v := range p.Variants {
+		field := fmt.Sprintf("variants[%d]", i)
+		_, height, err := scaler.DimensionsForLabel(v.Resolution)
+		if err != nil {
+			r.add(SeverityError, field+".resolution", "unknown resolution label %q (known: %s)", v.Resolution, strings.Join(presetLabels(), ", "))
+		}
+		if !bitratePattern.MatchString(strings.TrimSpace(v.Bitrate)) {
+			r.add(SeverityError, field+".bitrate", "invalid bitrate %q; expected kbps like \"3000k\"", v.Bitrate)
+		} else if kbps := helpers.ParseBitrateKbps(v.Bitrate); kbps == 0 {
+			r.add(SeverityError, field+".bitrate", "bitrate must be greater than zero")
+		} else if min := presetMinBitrate(v.Resolution, p.variantCodec(v)); min > 0 && kbps < min {
+			r.add(SeverityWarning, field+".bitrate", "%s is below the recommended minimum of %dk for %s %s", v.Bitrate, min, v.Resolution, p.variantCodec(v))
+		}
+		if v.AudioBitrate != "" && !bitratePattern.MatchString(strings.TrimSpace(v.AudioBitrate)) {
+			r.add(SeverityError, field+".audio_bitrate", "invalid bitrate %q; expected kbps like \"128k\"", v.AudioBitrate)
+		}
+		if v.Codec != "" {
+			family := helpers.CodecFamily(v.Codec)
+			switch {
+			case family == "" || family == "copy":
+				r.add(SeverityError, field+".codec", "unsupported variant codec %q", v.Codec)
+			case !contains(containerCodecs["mp4"].video, family):
+				r.add(SeverityError, field+".codec", "%s variants cannot be stored in mp4 (supported: %s)", v.Codec, strings.Join(containerCodecs["mp4"].video, ", "))
+			}
+		}
+		key := v.Resolution + "_" + v.Bitrate
+		if tier := p.codecTier(v); tier != "" {
+			key += "_" + tier
+		}
+		if first, dup := seen[key]; dup {
+			r.add(SeverityWarning, field, "duplicate of variants[%d] (%s @ %s); it will be skipped", first, v.Resolution, v.Bitrate)
+		} else {
+			seen[key] = i
+		}
+		if media != nil && err == nil && media.Height > 0 && height > media.Height {
+			r.add(SeverityWarning, field+".resolution", "%s exceeds source height %dp; it will be skipped unless low_source adds it back", v.Resolution, media.Height)
+		}
+	}
+	for i, res := range p.Resolutions {
+		if _, _, err := scaler.DimensionsForLabel(res); err != nil {
+			r.add(SeverityError, fmt.Sprintf("target_res[%d]", i), "unknown resolution label %q", res)
+		}
+	}
+
+	// Segment length vs GOP
+	switch {
+	case p.SegmentLength < 0:
+		r.add(SeverityError, "segment_length", "segment_length must be zero or a positive integer")
+	case p.SegmentLength == 0:
+		if media != nil && media.KeyframeInterval > 0 {
+			r.defaulted("segment_length", "%ds (source keyframe interval %.2fs)", int(media.KeyframeInterval+0.5), media.KeyframeInterval)
+		} else {
+			r.defaulted("segment_length", "source keyframe interval")
+		}
+	default:
+		if p.SegmentLength < 2 {
+			r.add(SeverityWarning, "segment_length", "%ds segments add significant manifest and request overhead", p.SegmentLength)
+		} else if p.SegmentLength > 20 {
+			r.add(SeverityWarning, "segment_length", "%ds segments slow down startup and bitrate switching", p.SegmentLength)
+		}
+		if media != nil && media.KeyframeInterval > 0 {
+			gop := media.KeyframeInterval
+			if float64(p.SegmentLength) < gop {
+				r.add(SeverityWarning, "segment_length", "%ds is shorter than the source GOP (%.2fs); segments will be uneven", p.SegmentLength, gop)
+			} else if ratio := float64(p.SegmentLength) / gop; math.Abs(ratio-math.Round(ratio)) > 0.05 {
+				r.add(SeverityWarning, "segment_length", "%ds is not a multiple of the source GOP (%.2fs); segment boundaries will drift", p.SegmentLength, gop)
+			}
+		}
+	}
+
+	// Filters
+	switch p.Deinterlace {
+	case "":
+		r.defaulted("deinterlace", DeinterlaceAuto)
+	case DeinterlaceAuto, DeinterlaceOff, DeinterlaceForce:
+	default:
+		r.add(SeverityError, "deinterlace", "unknown deinterlace mode %q (want auto, off, or force)", p.Deinterlace)
+	}
+
+	if p.AudioBitrate != "" && !bitratePattern.MatchString(strings.TrimSpace(p.AudioBitrate)) {
+		r.add(SeverityError, "audio_bitrate", "invalid bitrate %q; expected kbps like \"128k\"", p.AudioBitrate)
+	}
+	switch p.AudioCopy {
+	case "":
+		r.defaulted("audio_copy", AudioCopyAuto)
+	case AudioCopyAuto, AudioCopyOff:
+	default:
+		r.add(SeverityError, "audio_copy", "unknown audio copy mode %q (want auto or off)", p.AudioCopy)
+	}
+	if offset := p.AudioOffset(); offset < -maxAudioOffset || offset > maxAudioOffset {
+		r.add(SeverityWarning, "audio_offset_ms", "%dms is an unusually large A/V offset; check the sign and units", p.AudioOffsetMs)
+	}
+	validateAudioLayout(p, media, r)
+	validateAudioRenditions(p, media, r)
+	if t := p.Timestamps; t != nil {
+		if t.DurationTolerance < 0 {
+			r.add(SeverityError, "timestamps.duration_tolerance", "duration_tolerance must be zero or positive")
+		}
+		if t.AudioResync && p.AudioCodec == "copy" {
+			r.add(SeverityWarning, "timestamps.audio_resync", "copied audio can't be resampled; set audio_codec to re-encode it")
+		}
+	}
+
+	// Preview
+	if p.Preview != nil {
+		if p.Preview.Duration < 0 {
+			r.add(SeverityError, "preview.duration", "duration must be zero or positive")
+		}
+		if p.Preview.Clips < 0 {
+			r.add(SeverityError, "preview.clips", "clips must be zero or positive")
+		}
+		if res := p.Preview.Resolution; res != "" {
+			if _, _, err := scaler.DimensionsForLabel(res); err != nil {
+				r.add(SeverityError, "preview.resolution", "unknown resolution label %q", res)
+			}
+		}
+		if br := p.Preview.Bitrate; br != "" && !bitratePattern.MatchString(strings.TrimSpace(br)) {
+			r.add(SeverityError, "preview.bitrate", "invalid bitrate %q; expected kbps like \"600k\"", br)
+		}
+	}
+
+	// Thumbnails
+	switch p.ThumbnailSource {
+	case "":
+		r.defaulted("thumbnail_source", ThumbnailSourceAuto)
+	case ThumbnailSourceAuto, ThumbnailSourceVariant, ThumbnailSourceInput, ThumbnailSourceLive:
+	default:
+		r.add(SeverityError, "thumbnail_source", "unknown thumbnail source %q (want auto, variant, input, or live)", p.ThumbnailSource)
+	}
+	if sp := p.Sprites; sp != nil {
+		if sp.Interval < 0 {
+			r.add(SeverityError, "sprites.interval", "interval must be zero or positive")
+		}
+		if sp.Width < 0 {
+			r.add(SeverityError, "sprites.width", "width must be zero or positive")
+		} else if sp.Width%2 != 0 {
+			r.add(SeverityWarning, "sprites.width", "width %d is odd; tiles are rounded to %d pixels", sp.Width, sp.Width+1)
+		}
+		if sp.Columns < 0 {
+			r.add(SeverityError, "sprites.columns", "columns must be zero or positive")
+		}
+		if sp.Rows < 0 {
+			r.add(SeverityError, "sprites.rows", "rows must be zero or positive")
+		}
+	}
+
+	// Subtitles
+	switch p.ForcedSubtitles {
+	case "":
+		if media != nil && len(media.ForcedSubtitles()) > 0 {
+			r.defaulted("forced_subtitles", ForcedAuto)
+		}
+	case ForcedAuto, ForcedRendition, ForcedBurn, ForcedOff:
+	default:
+		r.add(SeverityError, "forced_subtitles", "unknown forced subtitle mode %q (want auto, rendition, burn, or off)", p.ForcedSubtitles)
+	}
+	var forced *analyzer.StreamInfo
+	if media != nil {
+		forced = media.PrimaryForcedSubtitle()
+	}
+	if forced != nil && !forced.IsTextSubtitle() {
+		switch p.ForcedSubtitleMode() {
+		case ForcedAuto:
+			r.add(SeverityWarning, "forced_subtitles", "forced subtitle stream %d is a bitmap (%s) and will be burned into every variant", forced.Index, forced.Codec)
+		case ForcedRendition:
+			r.add(SeverityWarning, "forced_subtitles", "forced subtitle stream %d is a bitmap (%s) and can't be published as WebVTT; use \"burn\" to keep it", forced.Index, forced.Codec)
+		}
+	}
+	if p.Subtitles != nil {
+		for i, f := range p.Subtitles.Files {
+			if strings.TrimSpace(f.Path) == "" {
+				r.add(SeverityError, fmt.Sprintf("subtitles.files[%d].path", i), "path is required")
+			}
+		}
+	}
+
+	// Master manifest metadata
+	sessionKeys := make(map[string]bool)
+	for i, d := range p.SessionData {
+		field := fmt.Sprintf("session_data[%d]", i)
+		if d.ID == "" {
+			r.add(SeverityError, field+".id", "id is required")
+		} else if !strings.Contains(d.ID, ".") {
+			r.add(SeverityWarning, field+".id", "id %q should use reverse-DNS naming (e.g. \"com.example.title\")", d.ID)
+		}
+		set := 0
+		for _, present := range []bool{d.Value != "", d.URI != "", d.JSON != nil} {
+			if present {
+				set++
+			}
+		}
+		if set != 1 {
+			r.add(SeverityError, field, "exactly one of value, uri, or json must be set")
+		}
+		// Values are written as HLS quoted strings
+		for _, attr := range [][2]string{{"id", d.ID}, {"value", d.Value}, {"uri", d.URI}, {"language", d.Language}} {
+			if strings.ContainsAny(attr[1], "\"\r\n") {
+				r.add(SeverityError, field+"."+attr[0], "%s may not contain double quotes or line breaks", attr[0])
+			}
+		}
+		if d.Language != "" && d.Value == "" {
+			r.add(SeverityWarning, field+".language", "language only applies to value entries")
+		}
+		key := d.ID + "|" + d.Language
+		if sessionKeys[key] {
+			r.add(SeverityError, field, "duplicate session data for id %q and language %q", d.ID, d.Language)
+		}
+		sessionKeys[key] = true
+	}
+	validateCuePoints(p, media, r)
+	validateHLSVersion(p, r)
+
+	// Output layout
+	if err := layout.ValidateTenant(p.Tenant); err != nil {
+		r.add(SeverityError, "tenant", "%v", err)
+	}
+	if err := layout.ValidateSlug(p.OutputLayout); err != nil {
+		r.add(SeverityError, "output_layout", "%v", err)
+	} else if p.OutputLayout == "" {
+		r.defaulted("output_layout", layout.DefaultSlug)
+	}
+	if err := layout.ValidateVariant(p.VariantLayout); err != nil {
+		r.add(SeverityError, "variant_layout", "%v", err)
+	} else if p.VariantLayout == "" {
+		r.defaulted("variant_layout", layout.DefaultVariant)
+	}
+
+	// Resource controls
+	if p.Nice < 0 || p.Nice > 19 {
+		r.add(SeverityError, "nice", "nice must be between 0 and 19")
+	}
+	if p.Threads < 0 {
+		r.add(SeverityError, "threads", "threads must be zero or positive")
+	} else if p.Threads == 0 && p.Budget == nil {
+		r.defaulted("threads", "auto (ffmpeg decides)")
+	} else if p.Threads > 0 && p.Budget != nil {
+		r.add(SeverityWarning, "threads", "threads is ignored when budget is set; set threads on individual variants instead")
+	}
+	validateBudget(p, r)
+	validatePackager(p, r)
+	validateLowSource(p, media, r)
+	validateTrim(p, r)
+	validateCheckpoint(p, r)
+	validatePartialFailure(p, r)
+	validateMinimumLadder(p, r)
+	validateStill(p, r)
+	validateBumpers(p, r)
+	validateLanguages(p, media, r)
+	validateProgressive(p, r)
+	validateStreamFormat(p, r)
+	validateSourceBitrate(p, media, r)
+	validateReadThrottle(p, media, r)
+	defaults := executil.DefaultLimits()
+	if p.CommandTimeout < 0 {
+		r.add(SeverityError, "command_timeout", "command_timeout must be zero or positive")
+	} else if p.CommandTimeout == 0 {
+		r.defaulted("command_timeout", "%s", durationOrNone(defaults.Timeout.String(), defaults.Timeout == 0))
+	}
+	if p.StallTimeout < 0 {
+		r.add(SeverityError, "stall_timeout", "stall_timeout must be zero or positive")
+	} else if p.StallTimeout == 0 {
+		r.defaulted("stall_timeout", "%s", durationOrNone(defaults.StallTimeout.String(), defaults.StallTimeout == 0))
+	}
+
+	return r
+}
+
+// presetLabels returns the labels of every standard resolution preset.
+func presetLabels() []string {
+	labels := make([]string, len(scaler.StandardPresets))
+	for i, p := range scaler.StandardPresets {
+		labels[i] = p.Label
+	}
+	return labels
+}
+
+// presetMinBitrate returns the recommended minimum kbps for label encoded
+// with codec, or 0 if unknown.
+func presetMinBitrate(label, codec string) int {
+	norm := scaler.NormalizeLabel(label)
+	for _, p := range scaler.StandardPresets {
+		if scaler.NormalizeLabel(p.Label) == norm {
+			return p.MinBitrateFor(codec)
+		}
+	}
+	return 0
+}
+
+func durationOrNone(value string, none bool) string {
+	if none {
+		return "none"
+	}
+	return value
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package transcoder
+
+import (
+	"math"
+	"strconv"
+)
+
+// vp9Encoder is ffmpeg's libvpx VP9 encoder.
+const vp9Encoder = "libvpx-vp9"
+
+// vp9Deadlines are the quality deadlines libvpx accepts.
+var vp9Deadlines = []string{"good", "best", "realtime"}
+
+// VP9Options tunes libvpx-vp9 encodes (TranscodeProfile.VP9). Unset fields
+// keep the encoder defaults.
+type VP9Options struct {
+	Deadline    string `json:"deadline,omitempty" yaml:"deadline,omitempty"`         // "good" (encoder default), "best", or "realtime"
+	CPUUsed     *int   `json:"cpu_used,omitempty" yaml:"cpu_used,omitempty"`         // Speed/quality trade-off, 0 (slowest, best) to 8 (fastest)
+	TileColumns int    `json:"tile_columns,omitempty" yaml:"tile_columns,omitempty"` // log2 of tile columns (0-6); more tiles encode and decode faster in parallel
+	RowMT       bool   `json:"row_mt,omitempty" yaml:"row_mt,omitempty"`             // Row-based multithreading, for encodes much faster than realtime
+}
+
+// args returns the libvpx-vp9 options for o.
+func (o *VP9Options) args() []string {
+	if o == nil {
+		return nil
+	}
+	var args []string
+	if o.Deadline != "" {
+		args = append(args, "-deadline", o.Deadline)
+	}
+	if o.CPUUsed != nil {
+		args = append(args, "-cpu-used", strconv.Itoa(*o.CPUUsed))
+	}
+	if o.TileColumns > 0 {
+		args = append(args, "-tile-columns", strconv.Itoa(o.TileColumns))
+	}
+	if o.RowMT {
+		args = append(args, "-row-mt", "1")
+	}
+	return args
+}
+
+// validate reports out-of-range VP9 settings under the "vp9" field path.
+func (o *VP9Options) validate(r *ValidationReport, videoFamily string) {
+	if o == nil {
+		return
+	}
+	if videoFamily != "" && videoFamily != "vp9" {
+		r.add(SeverityWarning, "vp9", "vp9 settings are ignored for %s encodes", videoFamily)
+	}
+	if o.Deadline != "" && !contains(vp9Deadlines, o.Deadline) {
+		r.add(SeverityError, "vp9.deadline", "unknown deadline %q (want good, best, or realtime)", o.Deadline)
+	}
+	if o.CPUUsed != nil && (*o.CPUUsed < 0 || *o.CPUUsed > 8) {
+		r.add(SeverityError, "vp9.cpu_used", "cpu_used must be between 0 and 8")
+	}
+	if o.TileColumns < 0 || o.TileColumns > 6 {
+		r.add(SeverityError, "vp9.tile_columns", "tile_columns is log2 and must be between 0 and 6")
+	}
+}
+
+// vp9KeyframeArgs fixes libvpx's keyframe interval at the segment length.
+// libvpx places keyframes by scene, up to 128 frames apart, and segments
+// copied from the variant can only start at a keyframe, so without it
+// WebM DASH segments run long and drift between representations.
+func vp9KeyframeArgs(segmentLength int, framerate float64) []string {
+	if segmentLength <= 0 || framerate <= 0 {
+		return nil
+	}
+	gop := strconv.Itoa(int(math.Round(float64(segmentLength) * framerate)))
+	return []string{"-g", gop, "-keyint_min", gop}
+}
+
+// softwareEncoder returns the ffmpeg encoder a software encode of codec
+// uses: ffmpeg picks its own experimental Opus and Vorbis encoders for the
+// bare codec names, and libvpx-vp9 is the one VP9Options tunes. Other codecs
+// are returned unchanged.
+func softwareEncoder(codec string) string {
+	switch codec {
+	case "vp9":
+		return vp9Encoder
+	case "opus":
+		return "libopus"
+	case "vorbis":
+		return "libvorbis"
+	}
+	return codec
+}
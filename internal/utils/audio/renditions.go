@@ -0,0 +1,266 @@
+// Package audio builds the alternate audio renditions a profile lists
+// (TranscodeProfile.AudioRenditions), such as AC-3 or E-AC-3 for living-room
+// devices. Each rendition is encoded, or passed through when the source
+// already carries that codec, straight into its own HLS or DASH output.
+package audio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/executil"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/langtag"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/namer"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
+)
+
+// Dir is the audio rendition directory inside the slug directory.
+const Dir = "audio"
+
+// Options customizes a single Generate run.
+type Options struct {
+	// SegmentLength is the segment duration in seconds; match the video
+	// ladder's so switching renditions stays aligned. Defaults to 4.
+	SegmentLength int
+	// KeyInfoFile, when set, encrypts HLS segments with AES-128 like the
+	// video variants (see segmenter.SegmentOptions).
+	KeyInfoFile string
+}
+
+// Rendition is one planned or written audio rendition.
+type Rendition struct {
+	Name            string   // Name players show
+	Language        string   // Language tag, "" if unknown
+	Default         bool     // Selected when the player has no preference
+	Codec           string   // Codec family ("ac3", "eac3", "aac")
+	Codecs          string   // RFC 6381 CODECS entry (e.g. "ec-3")
+	Channels        int      // Output channel count
+	Bitrate         int      // Bitrate in kbps (the source's when passed through, if known)
+	Passthrough     bool     // Source stream copied without re-encoding
+	Role            string   // transcoder.AudioRoleMain, AudioRoleDescription, or AudioRoleCommentary
+	Characteristics string   // HLS CHARACTERISTICS (e.g. "public.accessibility.describes-video"), "" for none
+	File            string   // External audio file the rendition is made from, "" for the source
+	Source          int      // Source stream index (in File when set); -1 for the first audio stream of a File that couldn't be probed
+	Dir             string   // Directory receiving the segments
+	Manifest        string   // HLS playlist or DASH manifest path
+	Command         []string // ffmpeg command that writes the rendition
+}
+
+// Plan resolves the profile's audio renditions, led by the main rendition in
+// the demuxed layout, against the source and builds their ffmpeg commands
+// without running them. Renditions made from an external file are resolved
+// against that file, which is probed. Renditions whose source has no
+// audio are left out. Each codec's renditions form one group, with exactly one
+// marked default.
+func Plan(profile *transcoder.TranscodeProfile, media *analyzer.MediaInfo, slugDir, format string, opts Options) []Rendition {
+	segLen := opts.SegmentLength
+	if segLen <= 0 {
+		segLen = 4
+	}
+	var out []Rendition
+	dirs := make(map[string]int)
+	for _, r := range profile.OutputAudioRenditions(media) {
+		var source *analyzer.StreamInfo
+		if r.File == "" {
+			source = r.AudioSource(media)
+		} else if info, err := analyzer.AnalyzeMedia(r.File, 1, stagelog.Nop); err == nil {
+			source = r.AudioSource(info)
+		} else {
+			// Left for ffmpeg to fail on, so Generate reports the file
+			source = &analyzer.StreamInfo{Index: -1}
+		}
+		if source == nil {
+			continue
+		}
+		language := langtag.Normalize(r.Language)
+		if language == "" {
+			language = source.Language
+		}
+		codec := strings.ToLower(r.Codec)
+		channels := r.OutputChannels(source.Channels)
+		copied := r.CopiesSource(source)
+		bitrate := helpers.ParseBitrateKbps(r.OutputBitrate(channels))
+		if copied {
+			channels = source.Channels
+			if source.Bitrate > 0 {
+				bitrate = source.Bitrate
+			}
+		}
+
+		// <slug>/audio/eac3-eng/ (eac3-eng-description/ for other roles),
+		// numbered when a codec/language pair repeats
+		base := codec
+		if language != "" {
+			base += "-" + namer.Slugify(language)
+		}
+		if role := r.RoleOrDefault(); role != transcoder.AudioRoleMain {
+			base += "-" + role
+		}
+		if dirs[base]++; dirs[base] > 1 {
+			base = fmt.Sprintf("%s-%d", base, dirs[base])
+		}
+		dir := filepath.Join(slugDir, Dir, base)
+		manifest := filepath.Join(dir, base+"."+manifestExtension(format))
+
+		rend := Rendition{
+			Name:            r.DisplayName(language, channels),
+			Language:        language,
+			Default:         r.Default,
+			Codec:           codec,
+			Codecs:          transcoder.AudioCodecString(codec),
+			Channels:        channels,
+			Bitrate:         bitrate,
+			Passthrough:     copied,
+			Role:            r.RoleOrDefault(),
+			Characteristics: r.Characteristics(),
+			File:            r.File,
+			Source:          source.Index,
+			Dir:             dir,
+			Manifest:        manifest,
+		}
+		rend.Command = buildCommand(profile, media, rend, r.FileOffset(), format, segLen, opts.KeyInfoFile)
+		out = append(out, rend)
+	}
+	markDefaults(out)
+	return out
+}
+
+// Generate writes every audio rendition the profile lists into
+// <slugDir>/audio/. A rendition that fails is skipped and reported in the
+// returned error alongside the renditions that succeeded.
+func Generate(ctx context.Context, profile *transcoder.TranscodeProfile, media *analyzer.MediaInfo, slugDir, format string, opts Options, logger stagelog.Logger) ([]Rendition, error) {
+	logger = stagelog.OrStd(logger)
+	if !strings.EqualFold(format, "hls") && opts.KeyInfoFile != "" {
+		return nil, fmt.Errorf("audio rendition encryption is only supported for HLS, not %s", format)
+	}
+
+	var out []Rendition
+	var errs []error
+	for _, r := range Plan(profile, media, slugDir, format, opts) {
+		if err := os.MkdirAll(r.Dir, os.ModePerm); err != nil {
+			errs = append(errs, fmt.Errorf("failed to create audio directory: %w", err))
+			continue
+		}
+		action := fmt.Sprintf("Encoding %d kbps", r.Bitrate)
+		if r.Passthrough {
+			action = "Passing through"
+		}
+		logger.LogStage("audio", fmt.Sprintf("🔊 %s %s from stream %d (%s)", action, r.Name, r.Source, r.Codecs))
+		if err := executil.RunCommandContext(ctx, r.Command, profile.CommandLimits()); err != nil {
+			if ctx.Err() != nil {
+				return out, context.Cause(ctx)
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", r.Name, err))
+			continue
+		}
+		out = append(out, r)
+	}
+	markDefaults(out)
+	return out, errors.Join(errs...)
+}
+
+// markDefaults leaves one default per codec group: the first flagged
+// rendition, else the group's first main-role rendition. Audio description
+// and commentary are never picked for viewers who didn't ask for them, so a
+// group of only those has no default.
+func markDefaults(renditions []Rendition) {
+	first := make(map[string]int)
+	chosen := make(map[string]bool)
+	for i := range renditions {
+		r := &renditions[i]
+		if _, ok := first[r.Codec]; !ok && r.Role == transcoder.AudioRoleMain {
+			first[r.Codec] = i
+		}
+		if r.Default && chosen[r.Codec] {
+			r.Default = false
+		}
+		chosen[r.Codec] = chosen[r.Codec] || r.Default
+	}
+	for codec, i := range first {
+		if !chosen[codec] {
+			renditions[i].Default = true
+		}
+	}
+}
+
+// buildCommand maps the rendition's source stream, encodes or copies it,
+// applies the profile's timestamp repair, read throttle, and audio offset, and segments it
+// directly into format. An external file is shifted by fileOffset instead,
+// and padded with silence or cut to the video's duration.
+func buildCommand(profile *transcoder.TranscodeProfile, media *analyzer.MediaInfo, r Rendition, fileOffset time.Duration, format string, segLen int, keyInfoFile string) []string {
+	cmd := []string{"ffmpeg", "-y"}
+	af := profile.AudioFilter()
+	switch {
+	case r.File != "":
+		if fileOffset != 0 {
+			cmd = append(cmd, transcoder.AudioOffsetInput(r.File, fileOffset)...)
+		} else {
+			cmd = append(cmd, "-i", r.File)
+		}
+		af = "apad"
+	case r.Passthrough && profile.AudioOffset() != 0:
+		cmd = append(cmd, profile.InputFlags()...)
+		cmd = append(cmd, profile.ReadFlags(media, 1)...)
+		cmd = append(cmd, transcoder.AudioOffsetInput(profile.InputPath, profile.AudioOffset())...)
+	default:
+		cmd = append(cmd, profile.InputFlags()...)
+		cmd = append(cmd, profile.ReadFlags(media, 1)...)
+		cmd = append(cmd, "-i", profile.InputPath)
+	}
+	stream := fmt.Sprintf("0:%d", r.Source)
+	if r.Source < 0 {
+		stream = "0:a:0"
+	}
+	cmd = append(cmd, "-map", stream, "-vn", "-sn", "-dn")
+	if r.Passthrough {
+		cmd = append(cmd, "-c:a", "copy")
+	} else {
+		cmd = append(cmd,
+			"-c:a", r.Codec,
+			"-b:a", fmt.Sprintf("%dk", r.Bitrate),
+			"-ac", strconv.Itoa(r.Channels),
+		)
+		if af != "" {
+			cmd = append(cmd, "-af", af)
+		}
+	}
+	if r.File != "" && media != nil && media.Duration > 0 {
+		cmd = append(cmd, "-t", strconv.FormatFloat(media.Duration, 'f', 3, 64))
+	}
+	if strings.EqualFold(format, "dash") {
+		return append(cmd,
+			"-f", "dash",
+			"-seg_duration", strconv.Itoa(segLen),
+			"-use_timeline", "1",
+			"-use_template", "1",
+			r.Manifest,
+		)
+	}
+	cmd = append(cmd,
+		"-f", "hls",
+		"-hls_time", strconv.Itoa(segLen),
+		"-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(r.Dir, "segment_%03d.ts"),
+	)
+	if keyInfoFile != "" {
+		cmd = append(cmd, "-hls_key_info_file", keyInfoFile)
+	}
+	return append(cmd, r.Manifest)
+}
+
+// manifestExtension returns the rendition manifest extension for format.
+func manifestExtension(format string) string {
+	if strings.EqualFold(format, "dash") {
+		return "mpd"
+	}
+	return "m3u8"
+}
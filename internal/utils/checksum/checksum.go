@@ -0,0 +1,144 @@
+// Package checksum generates and verifies SHA-256 sidecar manifests for pipeline outputs.
+// A checksums.json file lists every produced file (variants, segments, manifests,
+// thumbnails) so integrity can be verified after CDN replication or archival.
+package checksum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ManifestFilename is the name of the sidecar file written into the slug directory.
+const ManifestFilename = "checksums.json"
+
+// FileChecksum records the digest and size of a single output file.
+type FileChecksum struct {
+	Path   string `json:"path"`   // File path relative to the slug directory (forward slashes)
+	Size   int64  `json:"size"`   // File size in bytes
+	SHA256 string `json:"sha256"` // Hex-encoded SHA-256 digest
+}
+
+// Manifest is the on-disk structure of checksums.json.
+type Manifest struct {
+	Algorithm string         `json:"algorithm"` // Always "sha256"
+	Files     []FileChecksum `json:"files"`     // Sorted by path for stable diffs
+}
+
+// Mismatch describes a file that failed verification.
+type Mismatch struct {
+	Path   string // File path relative to the slug directory
+	Reason string // "missing", "size", or "digest"
+}
+
+// Generate walks dir and computes a SHA-256 digest for every regular file.
+// The checksum manifest itself is skipped so regeneration is idempotent.
+func Generate(dir string) (*Manifest, error) {
+	manifest := &Manifest{Algorithm: "sha256"}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == ManifestFilename {
+			return nil
+		}
+
+		sum, size, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, FileChecksum{Path: rel, Size: size, SHA256: sum})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to checksum %s: %w", dir, err)
+	}
+
+	sort.Slice(manifest.Files, func(i, j int) bool {
+		return manifest.Files[i].Path < manifest.Files[j].Path
+	})
+	return manifest, nil
+}
+
+// WriteChecksums generates checksums for every file under dir and writes
+// checksums.json into dir. Returns the full path to the written manifest.
+func WriteChecksums(dir string) (string, error) {
+	manifest, err := Generate(dir)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, ManifestFilename)
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create checksum manifest: %w", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return "", fmt.Errorf("failed to encode checksum manifest: %w", err)
+	}
+
+	return path, nil
+}
+
+// Verify re-hashes every file listed in dir/checksums.json and reports mismatches.
+// Returns an empty slice when all files are intact.
+func Verify(dir string) ([]Mismatch, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFilename))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checksum manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse checksum manifest: %w", err)
+	}
+
+	var mismatches []Mismatch
+	for _, f := range manifest.Files {
+		sum, size, err := hashFile(filepath.Join(dir, filepath.FromSlash(f.Path)))
+		switch {
+		case err != nil:
+			mismatches = append(mismatches, Mismatch{Path: f.Path, Reason: "missing"})
+		case size != f.Size:
+			mismatches = append(mismatches, Mismatch{Path: f.Path, Reason: "size"})
+		case sum != f.SHA256:
+			mismatches = append(mismatches, Mismatch{Path: f.Path, Reason: "digest"})
+		}
+	}
+	return mismatches, nil
+}
+
+// hashFile streams a file through SHA-256 and returns the hex digest and byte count.
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
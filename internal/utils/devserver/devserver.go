@@ -0,0 +1,164 @@
+// Package devserver hosts an output directory over HTTP so freshly transcoded
+// content can be previewed without configuring nginx. It sets streaming MIME
+// types and CORS headers, optionally honours byte-range requests, and serves a
+// small hls.js test page listing every master manifest under the directory.
+package devserver
+
+import (
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PlayerPath is the URL path of the built-in test page.
+const PlayerPath = "/_player"
+
+// Options configures Handler.
+type Options struct {
+	AllowOrigin string // Access-Control-Allow-Origin value, e.g. "*"; empty sends no CORS headers
+	NoRanges    bool   // Ignore Range headers and serve whole files
+}
+
+// contentTypes maps streaming file extensions to the MIME types players expect.
+// Go's built-in table misses or mislabels most of these.
+var contentTypes = map[string]string{
+	".m3u8": "application/vnd.apple.mpegurl",
+	".mpd":  "application/dash+xml",
+	".ts":   "video/mp2t",
+	".m4s":  "video/iso.segment",
+	".mp4":  "video/mp4",
+	".m4a":  "audio/mp4",
+	".webm": "video/webm",
+	".vtt":  "text/vtt",
+	".ttml": "application/ttml+xml",
+	".json": "application/json",
+	".jpg":  "image/jpeg",
+	".png":  "image/png",
+}
+
+// ContentType returns the MIME type served for name.
+func ContentType(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	if ct, ok := contentTypes[ext]; ok {
+		return ct
+	}
+	if ct := mime.TypeByExtension(ext); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+// Handler serves dir with streaming MIME types, plus the test page at
+// PlayerPath (and at "/" when dir has no index.html). CORS headers are only
+// sent when opts.AllowOrigin is set; the test page is same-origin and needs
+// none.
+func Handler(dir string, opts Options) http.Handler {
+	origin := opts.AllowOrigin
+	files := http.FileServer(http.Dir(dir))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		if origin != "" {
+			h.Set("Access-Control-Allow-Origin", origin)
+			h.Set("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS")
+			h.Set("Access-Control-Allow-Headers", "Range")
+			h.Set("Access-Control-Expose-Headers", "Content-Length, Content-Range")
+		}
+
+		switch r.Method {
+		case http.MethodOptions:
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case http.MethodGet, http.MethodHead:
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if r.URL.Path == PlayerPath || (r.URL.Path == "/" && !hasIndex(dir)) {
+			servePlayer(w, dir)
+			return
+		}
+
+		h.Set("Content-Type", ContentType(r.URL.Path))
+		if strings.HasSuffix(r.URL.Path, ".m3u8") || strings.HasSuffix(r.URL.Path, ".mpd") {
+			// Manifests change between runs; segments are immutable
+			h.Set("Cache-Control", "no-cache")
+		}
+		if opts.NoRanges {
+			r.Header.Del("Range")
+			r.Header.Del("If-Range")
+			w = noRangeWriter{w}
+		}
+		files.ServeHTTP(w, r)
+	})
+}
+
+// noRangeWriter stops the file server advertising byte-range support.
+type noRangeWriter struct {
+	http.ResponseWriter
+}
+
+// WriteHeader drops Accept-Ranges before the headers are sent.
+func (w noRangeWriter) WriteHeader(code int) {
+	w.Header().Del("Accept-Ranges")
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write sends headers through WriteHeader so Accept-Ranges is always dropped.
+func (w noRangeWriter) Write(b []byte) (int, error) {
+	w.Header().Del("Accept-Ranges")
+	return w.ResponseWriter.Write(b)
+}
+
+// hasIndex reports whether dir has an index.html of its own.
+func hasIndex(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, "index.html"))
+	return err == nil
+}
+
+// Masters returns the master manifests under dir as slash paths relative to
+// it, sorted.
+func Masters(dir string) []string {
+	var masters []string
+	_ = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if name := d.Name(); name == "master.m3u8" || name == "master.mpd" {
+			if rel, err := filepath.Rel(dir, p); err == nil {
+				masters = append(masters, filepath.ToSlash(rel))
+			}
+		}
+		return nil
+	})
+	sort.Strings(masters)
+	return masters
+}
+
+// servePlayer renders the test page.
+func servePlayer(w http.ResponseWriter, dir string) {
+	var entries []playerEntry
+	for _, m := range Masters(dir) {
+		entries = append(entries, playerEntry{
+			Name: path.Dir(m),
+			URL:  "/" + m,
+			HLS:  strings.HasSuffix(m, ".m3u8"),
+		})
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	_ = playerPage.Execute(w, entries)
+}
+
+// playerEntry is one manifest listed on the test page.
+type playerEntry struct {
+	Name string
+	URL  string
+	HLS  bool
+}
@@ -0,0 +1,57 @@
+package devserver
+
+import "html/template"
+
+// playerPage lists every master manifest and plays the selected one with
+// hls.js (or natively where the browser supports HLS).
+var playerPage = template.Must(template.New("player").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>dotgo-transcode preview</title>
+<script src="https://cdn.jsdelivr.net/npm/hls.js@1"></script>
+<style>
+body { font-family: sans-serif; margin: 2rem; background: #111; color: #eee; }
+a { color: #8cf; }
+video { width: 100%; max-width: 960px; background: #000; }
+#levels { font-family: monospace; margin-top: .5rem; }
+</style>
+</head>
+<body>
+<h1>🎬 Preview</h1>
+{{if .}}
+<ul>
+{{range .}}<li>{{if .HLS}}<a href="#" data-src="{{.URL}}">{{.Name}}</a>{{else}}{{.Name}} (<a href="{{.URL}}">{{.URL}}</a>, DASH){{end}}</li>
+{{end}}</ul>
+{{else}}
+<p>No master manifests found yet.</p>
+{{end}}
+<video id="video" controls></video>
+<div id="levels"></div>
+<script>
+const video = document.getElementById("video");
+const levels = document.getElementById("levels");
+let hls;
+function play(src) {
+  if (hls) { hls.destroy(); hls = null; }
+  if (window.Hls && Hls.isSupported()) {
+    hls = new Hls();
+    hls.on(Hls.Events.LEVEL_SWITCHED, (_, d) => {
+      const l = hls.levels[d.level];
+      levels.textContent = "level " + d.level + ": " + l.width + "x" + l.height + " @ " + Math.round(l.bitrate / 1000) + " kbps";
+    });
+    hls.loadSource(src);
+    hls.attachMedia(video);
+  } else {
+    video.src = src;
+  }
+  video.play();
+}
+document.querySelectorAll("a[data-src]").forEach(a => a.addEventListener("click", e => {
+  e.preventDefault();
+  play(a.dataset.src);
+}));
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,45 @@
+package helpers
+
+import "strings"
+
+// CodecFamily maps an ffmpeg encoder or codec name to its family
+// (e.g. "libx264" and "h264_nvenc" -> "h264"). Returns "" if unrecognized.
+// Used by transcoder validation and the scaler's codec bitrate factors.
+func CodecFamily(codec string) string {
+	c := strings.ToLower(strings.TrimSpace(codec))
+	switch {
+	case c == "":
+		return ""
+	case c == "copy":
+		return "copy"
+	case strings.Contains(c, "264"):
+		return "h264"
+	case strings.Contains(c, "265") || strings.Contains(c, "hevc"):
+		return "hevc"
+	case strings.Contains(c, "vp9"):
+		return "vp9"
+	case strings.Contains(c, "vp8") || c == "libvpx":
+		return "vp8"
+	case strings.Contains(c, "av1") || strings.Contains(c, "aom") || c == "librav1e":
+		return "av1"
+	case strings.Contains(c, "prores"):
+		return "prores"
+	case strings.Contains(c, "aac"):
+		return "aac"
+	case strings.Contains(c, "mp3"):
+		return "mp3"
+	case c == "eac3":
+		return "eac3"
+	case c == "ac3":
+		return "ac3"
+	case strings.Contains(c, "opus"):
+		return "opus"
+	case strings.Contains(c, "vorbis"):
+		return "vorbis"
+	case c == "flac" || c == "alac":
+		return c
+	case strings.HasPrefix(c, "pcm_"):
+		return "pcm"
+	}
+	return ""
+}
@@ -0,0 +1,69 @@
+package helpers
+
+import "strings"
+
+// ParseHLSAttributes splits an HLS attribute list (KEY=value,KEY="quoted,value")
+// into a map with quotes removed, as RFC 8216 section 4.2 defines it. Values
+// may be quoted strings containing commas; anything between a closing quote
+// and the next comma is ignored, as are entries without a key.
+// Used by the manifester, scaler, and playcheck to read existing playlists.
+func ParseHLSAttributes(list string) map[string]string {
+	attrs := make(map[string]string)
+	for list != "" {
+		eq := strings.IndexByte(list, '=')
+		if eq < 0 {
+			break
+		}
+		// An entry without "=" before the next comma has no value; skip it
+		if comma := strings.IndexByte(list[:eq], ','); comma >= 0 {
+			list = list[comma+1:]
+			continue
+		}
+		key := strings.TrimSpace(list[:eq])
+		rest := strings.TrimLeft(list[eq+1:], " ")
+		var value string
+		if strings.HasPrefix(rest, "\"") {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+			// Skip to the next attribute
+			if comma := strings.IndexByte(rest, ','); comma >= 0 {
+				rest = rest[comma:]
+			} else {
+				rest = ""
+			}
+		} else if comma := strings.IndexByte(rest, ','); comma >= 0 {
+			value, rest = strings.TrimSpace(rest[:comma]), rest[comma:]
+		} else {
+			value, rest = strings.TrimSpace(rest), ""
+		}
+		if key != "" {
+			attrs[key] = value
+		}
+		list = strings.TrimPrefix(rest, ",")
+	}
+	return attrs
+}
+
+// VideoFamilyFromCodecs returns the ffprobe codec name ("h264", "hevc",
+// "av1", "vp9") of the video codec in an RFC 6381 CODECS value, or "" when
+// none is recognised.
+func VideoFamilyFromCodecs(codecs string) string {
+	for _, c := range strings.Split(codecs, ",") {
+		c = strings.TrimSpace(c)
+		switch {
+		case strings.HasPrefix(c, "avc1"), strings.HasPrefix(c, "avc3"):
+			return "h264"
+		case strings.HasPrefix(c, "hvc1"), strings.HasPrefix(c, "hev1"):
+			return "hevc"
+		case strings.HasPrefix(c, "av01"):
+			return "av1"
+		case strings.HasPrefix(c, "vp09"):
+			return "vp9"
+		}
+	}
+	return ""
+}
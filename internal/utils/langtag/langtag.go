@@ -0,0 +1,103 @@
+// Package langtag normalizes track language tags to RFC 5646 (BCP 47), the
+// form HLS LANGUAGE and DASH lang attributes expect. Containers tag streams
+// with ISO 639-2 codes ("eng", "ger"), deprecated codes ("iw"), or "und";
+// each is rewritten to its shortest registered form ("en", "de", "he") or
+// dropped when it says nothing.
+package langtag
+
+import (
+	"strings"
+)
+
+// Undetermined is the ISO 639-2 code containers use for untagged streams.
+const Undetermined = "und"
+
+// Normalize returns tag as a canonical RFC 5646 tag: ISO 639-2 primary
+// languages shortened to their ISO 639-1 code, deprecated codes replaced,
+// underscores turned into hyphens, and subtags cased by kind ("pt-BR",
+// "zh-Hant-TW", "sr-Latn"). Returns "" for an empty, undetermined ("und"), or
+// malformed tag.
+func Normalize(tag string) string {
+	tag = strings.TrimSpace(strings.ReplaceAll(tag, "_", "-"))
+	if tag == "" {
+		return ""
+	}
+	subtags := strings.Split(strings.ToLower(tag), "-")
+	primary := subtags[0]
+	if !isAlpha(primary) || len(primary) < 2 || len(primary) > 3 {
+		return ""
+	}
+	if short, ok := iso6391[primary]; ok {
+		primary = short
+	}
+	if primary == Undetermined {
+		return ""
+	}
+	out := []string{primary}
+	// Script, region, and variants in order; an extension or private use
+	// singleton takes the rest of the tag as is
+	for i, s := range subtags[1:] {
+		switch {
+		case len(s) == 1:
+			if i+2 >= len(subtags) {
+				return ""
+			}
+			return strings.Join(append(out, subtags[i+1:]...), "-")
+		case len(s) == 4 && isAlpha(s):
+			out = append(out, strings.ToUpper(s[:1])+s[1:])
+		case len(s) == 2 && isAlpha(s), len(s) == 3 && isDigit(s):
+			out = append(out, strings.ToUpper(s))
+		case len(s) >= 5 && len(s) <= 8 && isAlnum(s), len(s) == 4 && isDigit(s[:1]) && isAlnum(s):
+			out = append(out, s)
+		default:
+			return ""
+		}
+	}
+	return strings.Join(out, "-")
+}
+
+// Valid reports whether tag is a well-formed language tag Normalize keeps.
+func Valid(tag string) bool {
+	return Normalize(tag) != ""
+}
+
+// Equal reports whether a and b name the same language once normalized
+// ("eng" and "en" do). Two empty or undetermined tags are not equal.
+func Equal(a, b string) bool {
+	na := Normalize(a)
+	return na != "" && na == Normalize(b)
+}
+
+// Primary returns the primary language subtag of tag once normalized ("pt"
+// for "pt-BR"), or "" when tag has none.
+func Primary(tag string) string {
+	primary, _, _ := strings.Cut(Normalize(tag), "-")
+	return primary
+}
+
+func isAlpha(s string) bool {
+	for _, c := range s {
+		if c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isDigit(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+func isAlnum(s string) bool {
+	for _, c := range s {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,226 @@
+// Package layout resolves where a job's outputs are written. The slug
+// directory and each variant's segment directory are built from templates so
+// output trees can match existing CDN or library conventions.
+package layout
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Built-in slug directory layouts.
+const (
+	// DefaultSlug writes every job into <output_dir>/<slug>/.
+	DefaultSlug = "{slug}"
+	// Flat writes straight into <output_dir>/ with no per-job directory.
+	Flat = "flat"
+	// DefaultVariant places each variant's segments in <slug dir>/<label>/.
+	DefaultVariant = "{label}"
+)
+
+// Placeholders accepted in slug and variant templates.
+//
+//	{slug}     job slug derived from the input filename
+//	{tenant}   tenant the job belongs to (see Layout.Tenant)
+//	{date}     input file modification date, YYYY-MM-DD
+//	{year}     input file modification year, YYYY
+//	{month}    input file modification month, MM
+//	{day}      input file modification day, DD
+//	{label}    variant label, e.g. "720p_3000kbps" (variant templates only)
+//	{height}   variant height in pixels (variant templates only)
+//	{bitrate}  variant bitrate in kbps (variant templates only)
+//
+// Dates come from the input file rather than the clock so re-runs and variant
+// discovery resolve the same directory on any day.
+var placeholderPattern = regexp.MustCompile(`\{([a-z]+)\}`)
+
+var (
+	slugPlaceholders    = []string{"slug", "tenant", "date", "year", "month", "day"}
+	variantPlaceholders = []string{"slug", "tenant", "date", "year", "month", "day", "label", "height", "bitrate"}
+)
+
+// Layout holds the output path templates for one job.
+type Layout struct {
+	Slug    string // Slug directory template relative to the output root ("" = DefaultSlug, Flat = root)
+	Variant string // Variant segment directory template relative to the slug directory ("" = DefaultVariant)
+	Tenant  string // Tenant namespace; slug directories go under <root>/<tenant>/ unless Slug places {tenant} itself
+}
+
+// UsesSlug reports whether the slug directory is unique per slug, i.e. the
+// template contains {slug}. Flat and date-only layouts share one directory.
+func (l Layout) UsesSlug() bool {
+	switch l.Slug {
+	case "":
+		return true
+	case Flat:
+		return false
+	}
+	return strings.Contains(l.Slug, "{slug}")
+}
+
+// Variant identifies one encoded variant when resolving its directory.
+type Variant struct {
+	Label   string // e.g. "720p_3000kbps"
+	Height  int    // e.g. 720
+	Bitrate int    // kbps, e.g. 3000
+}
+
+// Resolver expands a Layout for a single input.
+type Resolver struct {
+	layout Layout
+	slug   string
+	date   time.Time
+}
+
+// For returns a resolver for the given input path and slug.
+func (l Layout) For(inputPath, slug string) Resolver {
+	date := time.Now()
+	if fi, err := os.Stat(inputPath); err == nil {
+		date = fi.ModTime()
+	}
+	return Resolver{layout: l, slug: slug, date: date}
+}
+
+// SlugDir returns the directory that holds the job's variants, manifests,
+// thumbnails, and metadata.
+func (r Resolver) SlugDir(root string) string {
+	tmpl := r.layout.Slug
+	if r.layout.Tenant != "" && !strings.Contains(tmpl, "{tenant}") {
+		root = filepath.Join(root, r.layout.Tenant)
+	}
+	switch tmpl {
+	case "":
+		tmpl = DefaultSlug
+	case Flat:
+		return root
+	}
+	return filepath.Join(root, filepath.FromSlash(r.expand(tmpl, nil)))
+}
+
+// VariantDir returns the directory that holds one variant's segments and playlist.
+func (r Resolver) VariantDir(slugDir string, v Variant) string {
+	tmpl := r.layout.Variant
+	if tmpl == "" {
+		tmpl = DefaultVariant
+	}
+	return filepath.Join(slugDir, filepath.FromSlash(r.expand(tmpl, &v)))
+}
+
+// expand substitutes placeholders in tmpl.
+func (r Resolver) expand(tmpl string, v *Variant) string {
+	return placeholderPattern.ReplaceAllStringFunc(tmpl, func(m string) string {
+		switch m[1 : len(m)-1] {
+		case "slug":
+			return r.slug
+		case "tenant":
+			return r.layout.Tenant
+		case "date":
+			return r.date.Format("2006-01-02")
+		case "year":
+			return r.date.Format("2006")
+		case "month":
+			return r.date.Format("01")
+		case "day":
+			return r.date.Format("02")
+		}
+		if v == nil {
+			return m
+		}
+		switch m[1 : len(m)-1] {
+		case "label":
+			return v.Label
+		case "height":
+			return strconv.Itoa(v.Height)
+		case "bitrate":
+			return strconv.Itoa(v.Bitrate)
+		}
+		return m
+	})
+}
+
+// Validate checks both templates (see ValidateSlug and ValidateVariant) and
+// the tenant (see ValidateTenant).
+func (l Layout) Validate() error {
+	if err := ValidateTenant(l.Tenant); err != nil {
+		return fmt.Errorf("tenant: %w", err)
+	}
+	if err := ValidateSlug(l.Slug); err != nil {
+		return fmt.Errorf("output_layout: %w", err)
+	}
+	if err := ValidateVariant(l.Variant); err != nil {
+		return fmt.Errorf("variant_layout: %w", err)
+	}
+	return nil
+}
+
+// tenantPattern matches tenant identifiers: one path segment of letters,
+// digits, dots, dashes, and underscores, not starting with a dot.
+var tenantPattern = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9._-]*$`)
+
+// ValidateTenant checks that a tenant identifier is usable as a single
+// directory name. The empty tenant is valid.
+func ValidateTenant(tenant string) error {
+	if tenant != "" && !tenantPattern.MatchString(tenant) {
+		return fmt.Errorf("%q must be letters, digits, '.', '-', or '_', and not start with '.'", tenant)
+	}
+	return nil
+}
+
+// ValidateSlug checks a slug directory template for unknown placeholders,
+// absolute paths, and parent-directory escapes.
+func ValidateSlug(tmpl string) error {
+	if tmpl == Flat {
+		return nil
+	}
+	return checkTemplate(tmpl, slugPlaceholders)
+}
+
+// ValidateVariant checks a variant directory template like ValidateSlug, and
+// requires it to identify the variant with {label}, or with both {height} and
+// {bitrate}, so segment directories never collide.
+func ValidateVariant(tmpl string) error {
+	if err := checkTemplate(tmpl, variantPlaceholders); err != nil {
+		return err
+	}
+	if tmpl != "" && !strings.Contains(tmpl, "{label}") &&
+		!(strings.Contains(tmpl, "{height}") && strings.Contains(tmpl, "{bitrate}")) {
+		return fmt.Errorf("%q must contain {label} or both {height} and {bitrate}", tmpl)
+	}
+	return nil
+}
+
+// checkTemplate validates a single template against its allowed placeholders.
+func checkTemplate(tmpl string, allowed []string) error {
+	if tmpl == "" {
+		return nil
+	}
+	if strings.HasPrefix(tmpl, "/") || filepath.IsAbs(tmpl) {
+		return fmt.Errorf("%q must be relative", tmpl)
+	}
+	for _, part := range strings.Split(filepath.ToSlash(tmpl), "/") {
+		if part == ".." {
+			return fmt.Errorf("%q must not contain '..'", tmpl)
+		}
+	}
+	for _, m := range placeholderPattern.FindAllStringSubmatch(tmpl, -1) {
+		if !contains(allowed, m[1]) {
+			return fmt.Errorf("%q: unknown placeholder {%s}", tmpl, m[1])
+		}
+	}
+	return nil
+}
+
+// contains reports whether list includes s.
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
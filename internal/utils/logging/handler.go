@@ -0,0 +1,183 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Attribute keys understood by PrettyHandler.
+// Loggers set these so console output keeps the familiar "[stage][x] msg" layout.
+const (
+	KeyStage     = "stage"
+	KeyVariant   = "variant"
+	KeyComponent = "component"
+	KeyProgress  = "progress"
+	KeyPercent   = "percent"
+	KeyJob       = "job"
+)
+
+// consoleLogger is the default logger used by a zero-value UnifiedLogger.
+var consoleLogger = slog.New(NewPrettyHandler(os.Stdout, slog.LevelInfo))
+
+// PrettyHandler is a slog.Handler that renders records in the human-friendly
+// bracketed console format used by the CLI, e.g.:
+//
+//	[stage][transcode] ✅ All transcoding tasks completed
+//	[variant][720p_3000k] 🔧 Building ffmpeg command: ...
+//	[progress][keyframes] 42.00%
+//
+// Attributes that don't map onto the bracketed prefix are appended as key=value pairs.
+type PrettyHandler struct {
+	mu    *sync.Mutex
+	w     io.Writer
+	level slog.Leveler
+	attrs []slog.Attr
+	group string
+}
+
+// NewPrettyHandler creates a PrettyHandler writing to w at or above level.
+func NewPrettyHandler(w io.Writer, level slog.Leveler) *PrettyHandler {
+	return &PrettyHandler{mu: &sync.Mutex{}, w: w, level: level}
+}
+
+// Enabled reports whether the handler emits records at the given level.
+func (h *PrettyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle formats and writes a single record.
+func (h *PrettyHandler) Handle(_ context.Context, r slog.Record) error {
+	fields := map[string]string{}
+	var extra []string
+
+	collect := func(a slog.Attr) {
+		key := a.Key
+		if h.group != "" {
+			key = h.group + "." + key
+		}
+		switch key {
+		case KeyJob:
+			// Job slug is implied on the console; it is kept for file/JSON output
+		case KeyStage, KeyVariant, KeyComponent, KeyProgress, KeyPercent:
+			fields[key] = a.Value.String()
+		default:
+			extra = append(extra, fmt.Sprintf("%s=%v", key, a.Value.Any()))
+		}
+	}
+	for _, a := range h.attrs {
+		collect(a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		collect(a)
+		return true
+	})
+
+	var b strings.Builder
+	if c := fields[KeyComponent]; c != "" {
+		fmt.Fprintf(&b, "[%s]", c)
+	}
+	switch {
+	case fields[KeyProgress] != "":
+		fmt.Fprintf(&b, "[progress][%s] %s%%", fields[KeyProgress], trimPercent(fields[KeyPercent]))
+	case fields[KeyVariant] != "":
+		fmt.Fprintf(&b, "[variant][%s]", fields[KeyVariant])
+	case fields[KeyStage] != "":
+		fmt.Fprintf(&b, "[stage][%s]", fields[KeyStage])
+	}
+	if r.Level >= slog.LevelError {
+		b.WriteString("[error]")
+	} else if r.Level >= slog.LevelWarn {
+		b.WriteString("[warn]")
+	} else if r.Level < slog.LevelInfo {
+		b.WriteString("[debug]")
+	}
+	if fields[KeyProgress] == "" {
+		if b.Len() > 0 {
+			b.WriteString(" ")
+		}
+		b.WriteString(r.Message)
+	}
+	if len(extra) > 0 {
+		b.WriteString(" ")
+		b.WriteString(strings.Join(extra, " "))
+	}
+	b.WriteString("\n")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+// WithAttrs returns a handler that always includes attrs.
+func (h *PrettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(append([]slog.Attr{}, h.attrs...), attrs...)
+	return &clone
+}
+
+// WithGroup returns a handler that prefixes subsequent attribute keys with name.
+func (h *PrettyHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	if clone.group != "" {
+		clone.group += "." + name
+	} else {
+		clone.group = name
+	}
+	return &clone
+}
+
+// trimPercent formats a float attribute as a two-decimal percentage.
+func trimPercent(v string) string {
+	var f float64
+	if _, err := fmt.Sscanf(v, "%g", &f); err != nil {
+		return v
+	}
+	return fmt.Sprintf("%.2f", f)
+}
+
+// multiHandler fans a record out to several handlers (e.g. console + job log file).
+type multiHandler []slog.Handler
+
+func (m multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range m {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var firstErr error
+	for _, h := range m {
+		if !h.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := h.Handle(ctx, r.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (m multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make(multiHandler, len(m))
+	for i, h := range m {
+		out[i] = h.WithAttrs(attrs)
+	}
+	return out
+}
+
+func (m multiHandler) WithGroup(name string) slog.Handler {
+	out := make(multiHandler, len(m))
+	for i, h := range m {
+		out[i] = h.WithGroup(name)
+	}
+	return out
+}
@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Options configures a slog-backed UnifiedLogger.
+// The zero value produces the pretty console handler at info level on stdout.
+type Options struct {
+	Level     slog.Level // Minimum level emitted (e.g. slog.LevelDebug)
+	Format    string     // "pretty" (default), "text", or "json"
+	Output    io.Writer  // Console destination; defaults to os.Stdout
+	JobLogDir string     // If set, each job also writes JSON logs to <JobLogDir>/<slug>.log
+}
+
+// ParseLevel converts a level name ("debug", "info", "warn", "error") into a slog.Level.
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+		return slog.LevelInfo, fmt.Errorf("invalid log level %q", name)
+	}
+	return level, nil
+}
+
+// NewHandler builds the console handler described by opts.
+func NewHandler(opts Options) (slog.Handler, error) {
+	w := opts.Output
+	if w == nil {
+		w = os.Stdout
+	}
+	handlerOpts := &slog.HandlerOptions{Level: opts.Level}
+
+	switch strings.ToLower(opts.Format) {
+	case "", "pretty":
+		return NewPrettyHandler(w, opts.Level), nil
+	case "text":
+		return slog.NewTextHandler(w, handlerOpts), nil
+	case "json":
+		return slog.NewJSONHandler(w, handlerOpts), nil
+	default:
+		return nil, fmt.Errorf("unsupported log format %q", opts.Format)
+	}
+}
+
+// New creates a UnifiedLogger from opts.
+func New(opts Options) (*UnifiedLogger, error) {
+	h, err := NewHandler(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &UnifiedLogger{Logger: slog.New(h)}, nil
+}
+
+// NewJobLogger creates a UnifiedLogger for a single job identified by slug.
+// When opts.JobLogDir is set, records are additionally written as JSON lines
+// to <JobLogDir>/<slug>.log. The returned io.Closer releases the log file and
+// must be called once the job finishes; it is a no-op when no file was opened.
+func NewJobLogger(opts Options, slug string) (*UnifiedLogger, io.Closer, error) {
+	console, err := NewHandler(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.JobLogDir == "" {
+		return &UnifiedLogger{Logger: slog.New(console).With(KeyJob, slug)}, nopCloser{}, nil
+	}
+
+	if err := os.MkdirAll(opts.JobLogDir, 0755); err != nil {
+		return nil, nil, fmt.Errorf("failed to create job log dir: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(opts.JobLogDir, slug+".log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open job log file: %w", err)
+	}
+
+	file := slog.NewJSONHandler(f, &slog.HandlerOptions{Level: opts.Level})
+	logger := slog.New(multiHandler{console, file}).With(KeyJob, slug)
+	return &UnifiedLogger{Logger: logger}, f, nil
+}
+
+// SetDefault routes the standard library log package and slog.Default through
+// logger. Existing log.Printf calls then honor the configured level and format.
+func SetDefault(logger *UnifiedLogger) {
+	slog.SetDefault(logger.slog())
+}
+
+// nopCloser satisfies io.Closer when no resources need releasing.
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
@@ -1,38 +1,74 @@
 package logging
 
 import (
-	"fmt"
+	"log/slog"
 
 	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
 	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
 )
 
 // UnifiedLogger provides a shared logging implementaion across pipeline stages.
-// It satisfies both analyzer.AnalyzerLogger and transcoder.TranscodeLogger interfaces.
+// It satisfies the shared stagelog.Logger interface used by every pipeline stage.
 // This ensures consistent, formatting, scoped progress tracking, and structured error output
 // across concurrent operations like media analysis and multi-variant transcoding.
-type UnifiedLogger struct{}
+//
+// Records are emitted through log/slog. The zero value writes to stdout using the
+// pretty console handler; use New to select levels, JSON output, or per-job log files.
+type UnifiedLogger struct {
+	Logger *slog.Logger // Underlying slog logger; nil uses the pretty console handler
+}
+
+// slog returns the configured logger or the shared pretty console logger.
+func (u *UnifiedLogger) slog() *slog.Logger {
+	if u == nil || u.Logger == nil {
+		return consoleLogger
+	}
+	return u.Logger
+}
 
 func (u *UnifiedLogger) LogStage(stage, msg string) {
-	fmt.Printf("[stage][%s] %s\n", stage, msg)
+	u.slog().Info(msg, slog.String(KeyStage, stage))
 }
 
 func (u *UnifiedLogger) LogVariant(variant, msg string) {
-	fmt.Printf("[variant][%s] %s\n", variant, msg)
+	u.slog().Info(msg, slog.String(KeyVariant, variant))
 }
 
 func (u *UnifiedLogger) LogError(stage string, err error) {
 	switch e := err.(type) {
 	case *analyzer.AnalyzerError:
-		fmt.Printf("[analyzer][%s][error] op=%s path=%q err=%v\n", stage, e.Op, e.Path, e.Err)
+		u.slog().Error(e.Error(),
+			slog.String(KeyStage, stage),
+			slog.String(KeyComponent, "analyzer"),
+			slog.String("op", e.Op),
+			slog.String("path", e.Path),
+			slog.Any("err", e.Err),
+		)
 	case *transcoder.TranscoderError:
-		fmt.Printf("[transcoder][%s][error] stage=%s op=%s input=%q output=%q code=%d err=%v\n",
-			stage, e.Stage, e.Operation, e.InputPath, e.OutputPath, e.ExitCode, e.Err)
+		attrs := []any{
+			slog.String(KeyStage, stage),
+			slog.String(KeyComponent, "transcoder"),
+			slog.String("error_stage", e.Stage),
+			slog.String("op", e.Operation),
+			slog.String("input", e.InputPath),
+			slog.String("output", e.OutputPath),
+			slog.Int("code", e.ExitCode),
+			slog.Any("err", e.Err),
+		}
+		if e.Stderr != "" {
+			attrs = append(attrs, slog.String("stderr", e.Stderr))
+		}
+		u.slog().Error(e.Message, attrs...)
 	default:
-		fmt.Printf("[error][%s] %v\n", stage, err)
+		u.slog().Error(err.Error(), slog.String(KeyStage, stage))
 	}
 }
 
 func (u *UnifiedLogger) LogProgress(label string, percent float64) {
-	fmt.Printf("[progress][%s] %.2f%%\n", label, percent)
+	u.slog().Info("progress", slog.String(KeyProgress, label), slog.Float64(KeyPercent, percent))
+}
+
+// Slog returns the underlying *slog.Logger for callers that want structured logging directly.
+func (u *UnifiedLogger) Slog() *slog.Logger {
+	return u.slog()
 }
@@ -7,15 +7,132 @@ import (
 	"path/filepath"
 )
 
-// MediaMetadata captures key forensic info for frontend use
+// MediaMetadata captures key forensic info for frontend use.
+// Duration and SegmentLength are written as soon as transcoding starts; the
+// remaining fields are filled in once the pipeline has produced its outputs,
+// so a player can be bootstrapped from metadata.json alone.
 type MediaMetadata struct {
-	Duration      float64 `json:"duration"`
-	SegmentLength int     `json:"segment_length"`
+	Duration        float64             `json:"duration"`
+	SegmentLength   int                 `json:"segment_length"`
+	PipelineVersion string              `json:"pipeline_version,omitempty"` // Version of dotgo-transcode that produced the outputs
+	MasterManifest  string              `json:"master_manifest,omitempty"`  // Master manifest path relative to the slug directory
+	Variants        []VariantMetadata   `json:"variants,omitempty"`         // Every successfully transcoded variant
+	Thumbnails      *ThumbnailMetadata  `json:"thumbnails,omitempty"`       // Scrubber thumbnail inventory
+	Sprites         *SpriteMetadata     `json:"sprites,omitempty"`          // Scrubber sprite sheets and their WebVTT storyboard
+	Preview         *PreviewMetadata    `json:"preview,omitempty"`          // Short trailer for browse pages
+	Encryption      *EncryptionMetadata `json:"encryption,omitempty"`       // Segment encryption details; never includes the key
+	AudioTracks     []TrackMetadata     `json:"audio_tracks,omitempty"`     // Audio renditions available to the player
+	SubtitleTracks  []TrackMetadata     `json:"subtitle_tracks,omitempty"`  // Subtitle renditions available to the player
+	Downloads       []DownloadMetadata  `json:"downloads,omitempty"`        // Progressive MP4 files kept for download
+}
+
+// VariantMetadata describes a single rendition in the ladder.
+type VariantMetadata struct {
+	Resolution string           `json:"resolution"`         // e.g. "720p"
+	Width      int              `json:"width"`              // Output width in pixels
+	Height     int              `json:"height"`             // Output height in pixels
+	Bitrate    string           `json:"bitrate"`            // Target bitrate string (e.g. "3000k")
+	Codec      string           `json:"codec"`              // Video codec used for the encode (e.g. "h264")
+	Codecs     string           `json:"codecs,omitempty"`   // RFC 6381 CODECS string (e.g. "avc1.64001f,mp4a.40.2")
+	Filename   string           `json:"filename"`           // Transcoded file relative to the slug directory
+	FileSize   int64            `json:"file_size"`          // Size of the transcoded file in bytes
+	Playlist   string           `json:"playlist,omitempty"` // Variant playlist relative to the slug directory
+	Encoder    *EncoderMetadata `json:"encoder,omitempty"`  // How the variant was encoded
+}
+
+// DownloadMetadata describes a variant kept as a progressive MP4 download.
+type DownloadMetadata struct {
+	Resolution string `json:"resolution"`       // e.g. "720p"
+	Width      int    `json:"width"`            // Width in pixels
+	Height     int    `json:"height"`           // Height in pixels
+	Bitrate    string `json:"bitrate"`          // Target bitrate string (e.g. "3000k")
+	Codecs     string `json:"codecs,omitempty"` // RFC 6381 CODECS string, for choosing a file the client can play
+	Filename   string `json:"filename"`         // Download file relative to the slug directory
+	FileSize   int64  `json:"file_size"`        // Size in bytes
+}
+
+// EncoderMetadata fingerprints a variant's encode, so titles produced with
+// outdated settings or an older ffmpeg can be found and re-encoded.
+type EncoderMetadata struct {
+	Command      []string          `json:"command"`             // Full ffmpeg command line
+	Encoder      string            `json:"encoder"`             // Video encoder (e.g. "libx264")
+	FFmpeg       string            `json:"ffmpeg,omitempty"`    // ffmpeg version
+	Libraries    map[string]string `json:"libraries,omitempty"` // libav* versions (e.g. "libavcodec": "60.31.102")
+	ProfileHash  string            `json:"profile_hash"`        // Hash of the profile settings that shaped the variant
+	SettingsHash string            `json:"settings_hash"`       // Fingerprint of the command, versions, and profile hash
+}
+
+// ThumbnailMetadata lists the generated scrubber thumbnails.
+type ThumbnailMetadata struct {
+	Directory string   `json:"directory"` // Thumbnail directory relative to the slug directory
+	Interval  int      `json:"interval"`  // Seconds between consecutive thumbnails
+	Files     []string `json:"files"`     // Thumbnail filenames in timestamp order
+}
+
+// SpriteMetadata describes scrubber sprite sheets so a player can map a time
+// to a tile without fetching the storyboard: tile k covers
+// [k*interval, (k+1)*interval) and sits at column k%columns, row
+// (k/columns)%rows of sheet k/(columns*rows).
+type SpriteMetadata struct {
+	Directory  string   `json:"directory"`   // Sprite directory relative to the slug directory
+	Storyboard string   `json:"storyboard"`  // WebVTT storyboard filename; cues reference sheet#xywh=x,y,w,h
+	Sheets     []string `json:"sheets"`      // Sheet filenames in order
+	Interval   int      `json:"interval"`    // Seconds between tiles
+	TileWidth  int      `json:"tile_width"`  // Tile width in pixels
+	TileHeight int      `json:"tile_height"` // Tile height in pixels
+	Columns    int      `json:"columns"`     // Tiles per sheet row
+	Rows       int      `json:"rows"`        // Tile rows per sheet
+	Tiles      int      `json:"tiles"`       // Tiles across every sheet
+}
+
+// PreviewMetadata locates the generated trailer.
+type PreviewMetadata struct {
+	MP4      string  `json:"mp4"`      // Preview MP4 relative to the slug directory
+	Playlist string  `json:"playlist"` // Preview HLS playlist relative to the slug directory
+	Duration float64 `json:"duration"` // Preview length in seconds
+}
+
+// EncryptionMetadata records how segments were encrypted so players and
+// license servers can be configured. The content key itself is never written.
+type EncryptionMetadata struct {
+	Method     string `json:"method"`      // e.g. "AES-128", "SAMPLE-AES", "cenc"
+	KeyID      string `json:"key_id"`      // Hex key ID
+	LicenseURL string `json:"license_url"` // URI players fetch the key or license from
+}
+
+// TrackMetadata describes an audio or subtitle track.
+type TrackMetadata struct {
+	Index    int    `json:"index"`              // Track order within the rendition (0-based)
+	Codec    string `json:"codec"`              // e.g. "aac", "subrip"
+	Language string `json:"language,omitempty"` // RFC 5646 language tag if known (e.g. "en", "pt-BR")
+	Title    string `json:"title,omitempty"`    // Human-readable title if present
+	Channels int    `json:"channels,omitempty"` // Channel count (audio only)
+	Forced   bool   `json:"forced,omitempty"`   // Forced-narrative subtitles shown for foreign-language dialogue
+	File     string `json:"file,omitempty"`     // Sidecar file relative to the slug directory (subtitles only)
+	Playlist string `json:"playlist,omitempty"` // Rendition playlist or manifest relative to the slug directory (subtitles and alternate audio)
+}
+
+// ReadMediaMetadata loads metadata.json from slugDir, as written by WriteMediaMetadata.
+func ReadMediaMetadata(slugDir string) (*MediaMetadata, error) {
+	data, err := os.ReadFile(filepath.Join(slugDir, "metadata.json"))
+	if err != nil {
+		return nil, err
+	}
+	var meta MediaMetadata
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to decode metadata: %w", err)
+	}
+	return &meta, nil
 }
 
 // WriteMetadata writes metadata.json into the slugDir
 func WriteMetadata(slugDir string, segmentLength int, duration float64) error {
-	meta := MediaMetadata{Duration: duration, SegmentLength: segmentLength}
+	return WriteMediaMetadata(slugDir, MediaMetadata{Duration: duration, SegmentLength: segmentLength})
+}
+
+// WriteMediaMetadata writes a fully populated MediaMetadata to metadata.json in slugDir.
+// Overwrites any previous metadata.json so the file always reflects the latest run.
+func WriteMediaMetadata(slugDir string, meta MediaMetadata) error {
 	path := filepath.Join(slugDir, "metadata.json")
 
 	file, err := os.Create(path)
@@ -30,6 +147,5 @@ func WriteMetadata(slugDir string, segmentLength int, duration float64) error {
 		return fmt.Errorf("failed to encode metadata: %w", err)
 	}
 
-	fmt.Printf("📝 metadata.json written to %s (duration=%.2fs)\n", path, duration)
 	return nil
 }
@@ -0,0 +1,69 @@
+package namer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// OwnerFile records which input a slug directory belongs to, so two inputs
+// that slugify identically ("Movie!.mp4", "Movie?.mp4") don't share outputs.
+const OwnerFile = ".slug-source"
+
+// maxCollisions bounds how many numbered alternatives Unique tries.
+const maxCollisions = 100
+
+// Unique returns slug, or slug with a numeric suffix ("movie-2", "movie-3", ...)
+// when the directory for slug is already claimed by a different source.
+// dirFor maps a candidate slug to its directory. Directories without an owner
+// file (new, or written before ownership was recorded) are never treated as
+// collisions. Trees written before slugs were sanitized live under a
+// different name altogether; Existing finds those.
+func Unique(slug, source string, dirFor func(string) string) string {
+	source = canonicalSource(source)
+	sep := CurrentSlugifier().Separator
+	if sep == "" {
+		sep = "-"
+	}
+	for i := 1; i <= maxCollisions; i++ {
+		candidate := slug
+		if i > 1 {
+			candidate = fmt.Sprintf("%s%s%d", slug, sep, i)
+		}
+		owner, ok := readOwner(dirFor(candidate))
+		if !ok || owner == source {
+			return candidate
+		}
+	}
+	return slug
+}
+
+// Claim records source as the owner of dir unless an owner is already recorded.
+func Claim(dir, source string) error {
+	if _, ok := readOwner(dir); ok {
+		return nil
+	}
+	path := filepath.Join(dir, OwnerFile)
+	if err := os.WriteFile(path, []byte(canonicalSource(source)+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to record slug owner in %s: %w", dir, err)
+	}
+	return nil
+}
+
+// readOwner returns the source recorded in dir, if any.
+func readOwner(dir string) (string, bool) {
+	data, err := os.ReadFile(filepath.Join(dir, OwnerFile))
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(data)), true
+}
+
+// canonicalSource makes source paths comparable across working directories.
+func canonicalSource(source string) string {
+	if abs, err := filepath.Abs(source); err == nil {
+		return abs
+	}
+	return source
+}
@@ -1,12 +1,50 @@
 package namer
 
 import (
+	"os"
 	"path/filepath"
 	"strings"
 )
 
-// SlugFromPath returns a filename slug without extension or path
+// SlugFromPath returns a filesystem- and URL-safe slug for the input file,
+// derived from its filename without extension using the process-wide
+// Slugifier (see SetSlugifier).
 func SlugFromPath(inputPath string) string {
+	base := filepath.Base(inputPath)
+	return Slugify(strings.TrimSuffix(base, filepath.Ext(base)))
+}
+
+// LegacySlugFromPath returns the slug earlier releases used for the input
+// file: its filename without extension, unsanitized. Output trees written by
+// those releases live under this name.
+func LegacySlugFromPath(inputPath string) string {
 	base := filepath.Base(inputPath)
 	return strings.TrimSuffix(base, filepath.Ext(base))
 }
+
+// LegacyVariantBase returns the prefix earlier releases gave variant files
+// for the input: the legacy slug with spaces replaced by underscores.
+func LegacyVariantBase(inputPath string) string {
+	return strings.ReplaceAll(LegacySlugFromPath(inputPath), " ", "_")
+}
+
+// Existing returns legacy when its directory holds an output tree for source
+// (unclaimed, or claimed by source) and slug's directory doesn't exist yet,
+// so trees written under an unsanitized slug stay where resume, upgrades, and
+// checksums look for them. Otherwise it returns slug. dirFor maps a slug to
+// its directory.
+func Existing(slug, legacy, source string, dirFor func(string) string) string {
+	if legacy == "" || legacy == slug {
+		return slug
+	}
+	if _, err := os.Stat(dirFor(slug)); err == nil {
+		return slug
+	}
+	if fi, err := os.Stat(dirFor(legacy)); err != nil || !fi.IsDir() {
+		return slug
+	}
+	if owner, ok := readOwner(dirFor(legacy)); ok && owner != canonicalSource(source) {
+		return slug
+	}
+	return legacy
+}
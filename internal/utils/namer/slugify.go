@@ -0,0 +1,162 @@
+package namer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Slugifier turns arbitrary titles and filenames into slugs that are safe as
+// directory names, URL path segments, and ffmpeg arguments.
+//
+// Accented Latin, Greek, and Cyrillic letters are transliterated to ASCII.
+// Apostrophes are removed; anything else outside the allowed set (emoji, path
+// separators, whitespace, brackets) becomes a separator. Letters that can't be transliterated, such
+// as CJK, are dropped unless Unicode is set; a short hash of the original name
+// is then appended so distinct titles keep distinct slugs.
+type Slugifier struct {
+	Separator string // Replaces runs of disallowed characters (default "-")
+	Keep      string // Punctuation kept as-is besides letters and digits (default "-_")
+	Lowercase bool   // Lowercase the result
+	Unicode   bool   // Keep non-ASCII letters and digits that have no transliteration
+	MaxLength int    // Maximum slug length in bytes, excluding any hash suffix; 0 means unlimited
+}
+
+// DefaultSlugifier is used until SetSlugifier is called.
+var DefaultSlugifier = Slugifier{
+	Separator: "-",
+	Keep:      "-_",
+	Lowercase: true,
+	MaxLength: 80,
+}
+
+// hashLength is the number of hex characters in hash suffixes and fallbacks.
+const hashLength = 8
+
+var (
+	slugifierMu sync.RWMutex
+	slugifier   = DefaultSlugifier
+)
+
+// SetSlugifier replaces the process-wide Slugifier used by Slugify and SlugFromPath.
+func SetSlugifier(s Slugifier) {
+	slugifierMu.Lock()
+	defer slugifierMu.Unlock()
+	slugifier = s
+}
+
+// CurrentSlugifier returns the process-wide Slugifier.
+func CurrentSlugifier() Slugifier {
+	slugifierMu.RLock()
+	defer slugifierMu.RUnlock()
+	return slugifier
+}
+
+// Slugify converts name with the process-wide Slugifier.
+func Slugify(name string) string {
+	return CurrentSlugifier().Slugify(name)
+}
+
+// Slugify converts name into a slug. The result is never empty and never
+// starts with a separator or "-", so it can't be mistaken for an ffmpeg flag.
+// Examples with the default settings:
+//
+//	"The Lost Boys (1987)" -> "the-lost-boys-1987"
+//	"Amélie"               -> "amelie"
+//	"Don't Look Up 🚀"     -> "dont-look-up"
+//	"千と千尋の神隠し"        -> "media-<hash>"
+func (s Slugifier) Slugify(name string) string {
+	sep := s.Separator
+	if sep == "" {
+		sep = "-"
+	}
+	keep := s.Keep
+	if keep == "" {
+		keep = "-_"
+	}
+
+	var b strings.Builder
+	pending := false   // a separator is owed before the next kept character
+	lastPunct := false // the last written character was a separator or Keep character
+	dropped := false   // letters were removed without transliteration
+	emit := func(r rune) {
+		if s.Lowercase {
+			r = unicode.ToLower(r)
+		}
+		if pending && b.Len() > 0 && !lastPunct {
+			b.WriteString(sep)
+		}
+		pending, lastPunct = false, false
+		b.WriteRune(r)
+	}
+	emitPunct := func(r rune) {
+		// Collapse "a - b" to "a-b" rather than "a---b"
+		if b.Len() > 0 && !lastPunct {
+			b.WriteRune(r)
+			lastPunct = true
+		}
+		pending = false
+	}
+
+	for _, r := range name {
+		t, known := translit[r]
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			emit(r)
+		case r == '\'' || r == '’' || r == '`':
+			// Apostrophes join words: "Don't" -> "dont"
+		case strings.ContainsRune(keep, r):
+			emitPunct(r)
+		case known:
+			for _, tr := range t {
+				emit(tr)
+			}
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if s.Unicode {
+				emit(r)
+				continue
+			}
+			dropped = true
+			pending = true
+		default:
+			pending = true
+		}
+	}
+
+	slug := b.String()
+	if s.MaxLength > 0 && len(slug) > s.MaxLength {
+		slug = truncateRunes(slug, s.MaxLength)
+	}
+	slug = strings.Trim(slug, sep+keep)
+
+	if slug == "" {
+		return "media" + sep + shortHash(name)
+	}
+	if dropped {
+		return slug + sep + shortHash(name)
+	}
+	return slug
+}
+
+// truncateRunes cuts s to at most n bytes without splitting a UTF-8 sequence.
+func truncateRunes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := 0
+	for i := range s {
+		if i > n {
+			break
+		}
+		cut = i
+	}
+	return s[:cut]
+}
+
+// shortHash returns the first hashLength hex characters of name's SHA-256.
+func shortHash(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return hex.EncodeToString(sum[:])[:hashLength]
+}
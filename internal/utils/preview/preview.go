@@ -0,0 +1,238 @@
+// Package preview builds short trailers for browse pages. Clips are picked
+// from stretches of the source that are neither silent, black, nor frozen,
+// concatenated into one low-bitrate MP4, and packaged as a single HLS rendition.
+package preview
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/executil"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
+)
+
+// Dir is the preview directory inside the slug directory.
+const Dir = "preview"
+
+// Output filenames inside Dir.
+const (
+	MP4Name      = "preview.mp4"
+	PlaylistName = "preview.m3u8"
+)
+
+// Portions of the source skipped when picking clips: opening logos/titles and
+// end credits rarely make good preview material.
+const (
+	skipHead = 0.05
+	skipTail = 0.10
+)
+
+// fade is the audio fade applied at each clip boundary, in seconds, so cuts
+// don't click.
+const fade = 0.25
+
+// Preview describes a generated trailer.
+type Preview struct {
+	MP4      string              // Path of the preview MP4
+	Playlist string              // Path of the preview HLS playlist
+	Clips    []analyzer.Interval // Source ranges the preview was assembled from
+	Duration float64             // Total preview length in seconds
+}
+
+// Plan is the clip selection and the commands Generate would run.
+type Plan struct {
+	Clips    []analyzer.Interval
+	Dir      string
+	MP4      string
+	Playlist string
+	Encode   []string // ffmpeg command that cuts, concatenates, and encodes the clips
+	Package  []string // ffmpeg command that segments the MP4 into HLS
+}
+
+// Generate builds the preview for the profile's source into <slugDir>/preview/.
+// Returns an error if no usable clips exist or ffmpeg fails.
+func Generate(ctx context.Context, profile *transcoder.TranscodeProfile, media *analyzer.MediaInfo, slugDir string, logger stagelog.Logger) (*Preview, error) {
+	logger = stagelog.OrStd(logger)
+
+	plan, err := PlanPreview(profile, media, slugDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(plan.Dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create preview directory: %w", err)
+	}
+
+	logger.LogStage("preview", fmt.Sprintf("🎬 Assembling %.0fs preview from %d clip(s)", totalDuration(plan.Clips), len(plan.Clips)))
+	limits := profile.CommandLimits()
+	if err := executil.RunCommandContext(ctx, plan.Encode, limits); err != nil {
+		return nil, fmt.Errorf("failed to encode preview: %w", err)
+	}
+	if err := executil.RunCommandContext(ctx, plan.Package, limits); err != nil {
+		return nil, fmt.Errorf("failed to package preview: %w", err)
+	}
+	logger.LogStage("preview", fmt.Sprintf("✅ Preview written to %s", plan.Playlist))
+
+	return &Preview{
+		MP4:      plan.MP4,
+		Playlist: plan.Playlist,
+		Clips:    plan.Clips,
+		Duration: totalDuration(plan.Clips),
+	}, nil
+}
+
+// PlanPreview selects clips and builds the ffmpeg commands without running them.
+func PlanPreview(profile *transcoder.TranscodeProfile, media *analyzer.MediaInfo, slugDir string) (*Plan, error) {
+	settings := profile.PreviewSettings()
+	clips := SelectClips(media, float64(settings.Duration), settings.Clips)
+	if len(clips) == 0 {
+		return nil, fmt.Errorf("no usable preview clips in %.2fs of media", media.Duration)
+	}
+
+	dir := filepath.Join(slugDir, Dir)
+	plan := &Plan{
+		Clips:    clips,
+		Dir:      dir,
+		MP4:      filepath.Join(dir, MP4Name),
+		Playlist: filepath.Join(dir, PlaylistName),
+	}
+	plan.Encode = buildEncodeCommand(profile, media, plan.MP4, clips, settings, media.PrimaryAudio() != nil)
+	plan.Package = []string{
+		"ffmpeg", "-y",
+		"-i", plan.MP4,
+		"-c", "copy",
+		"-f", "hls",
+		"-hls_time", "4",
+		"-hls_playlist_type", "vod",
+		"-hls_segment_filename", filepath.Join(dir, "segment_%03d.ts"),
+		plan.Playlist,
+	}
+	return plan, nil
+}
+
+// SelectClips picks up to count clips totalling about total seconds, spread
+// evenly across the source. Clips avoid the opening and end credits and, when
+// the analysis found them, silent, black, and frozen stretches. Sources shorter
+// than total yield a single clip of the usable middle.
+func SelectClips(media *analyzer.MediaInfo, total float64, count int) []analyzer.Interval {
+	if media == nil || media.Duration <= 0 || total <= 0 || count <= 0 {
+		return nil
+	}
+
+	// Usable window: skip the head and tail
+	windowStart := media.Duration * skipHead
+	windowEnd := media.Duration * (1 - skipTail)
+	if windowEnd-windowStart <= total {
+		return []analyzer.Interval{{Start: windowStart, End: windowEnd}}
+	}
+
+	clipLen := total / float64(count)
+	var bad []analyzer.Interval
+	bad = append(bad, media.BlackIntervals...)
+	bad = append(bad, media.FreezeIntervals...)
+	if media.Loudness != nil {
+		bad = append(bad, media.Loudness.Silences...)
+	}
+	good := analyzer.Complement(bad, media.Duration, clipLen)
+
+	// Aim for evenly spaced targets; take the first good stretch at or after
+	// each target that can hold a whole clip
+	spacing := (windowEnd - windowStart) / float64(count)
+	var clips []analyzer.Interval
+	cursor := windowStart
+	for i := 0; i < count; i++ {
+		target := windowStart + spacing*float64(i)
+		if target < cursor {
+			target = cursor
+		}
+		start, ok := firstFit(good, target, clipLen, windowEnd)
+		if !ok {
+			break
+		}
+		clips = append(clips, analyzer.Interval{Start: start, End: start + clipLen})
+		cursor = start + clipLen
+	}
+	return clips
+}
+
+// firstFit returns the earliest start >= from where a clip of length fits
+// inside one of the good intervals and ends before limit.
+func firstFit(good []analyzer.Interval, from, length, limit float64) (float64, bool) {
+	for _, g := range good {
+		start := g.Start
+		if start < from {
+			start = from
+		}
+		if start+length <= g.End && start+length <= limit {
+			return start, true
+		}
+	}
+	return 0, false
+}
+
+// buildEncodeCommand trims each clip from the source, scales it, fades its
+// audio in and out, and concatenates everything into one MP4. The profile's
+// audio filters (gap compensation, offset) run before trimming, so clips
+// stay in sync.
+func buildEncodeCommand(profile *transcoder.TranscodeProfile, media *analyzer.MediaInfo, output string, clips []analyzer.Interval, settings transcoder.PreviewSettings, hasAudio bool) []string {
+	height := strings.TrimSuffix(settings.Resolution, "p")
+	shift := ""
+	if af := profile.AudioFilter(); af != "" {
+		shift = af + ","
+	}
+	var filters, inputs []string
+	for i, c := range clips {
+		start, end := ffTime(c.Start), ffTime(c.End)
+		filters = append(filters, fmt.Sprintf("[0:v:0]trim=start=%s:end=%s,setpts=PTS-STARTPTS,scale=-2:%s,setsar=1[v%d]", start, end, height, i))
+		inputs = append(inputs, fmt.Sprintf("[v%d]", i))
+		if hasAudio {
+			fadeOut := ffTime(c.Duration() - fade)
+			filters = append(filters, fmt.Sprintf("[0:a:0]%satrim=start=%s:end=%s,asetpts=PTS-STARTPTS,afade=t=in:d=%s,afade=t=out:st=%s:d=%s[a%d]",
+				shift, start, end, ffTime(fade), fadeOut, ffTime(fade), i))
+			inputs = append(inputs, fmt.Sprintf("[a%d]", i))
+		}
+	}
+	audioStreams := 0
+	if hasAudio {
+		audioStreams = 1
+	}
+	concat := fmt.Sprintf("%sconcat=n=%d:v=1:a=%d[v]", strings.Join(inputs, ""), len(clips), audioStreams)
+	if hasAudio {
+		concat += "[a]"
+	}
+	filters = append(filters, concat)
+
+	cmd := append([]string{"ffmpeg", "-y"}, profile.InputFlags()...)
+	cmd = append(cmd, profile.ReadFlags(media, 1)...)
+	cmd = append(cmd,
+		"-i", profile.InputPath,
+		"-filter_complex", strings.Join(filters, ";"),
+		"-map", "[v]",
+		"-c:v", "libx264",
+		"-b:v", settings.Bitrate,
+		"-pix_fmt", "yuv420p",
+	)
+	if hasAudio {
+		cmd = append(cmd, "-map", "[a]", "-c:a", "aac", "-b:a", "96k")
+	}
+	return append(cmd, "-movflags", "+faststart", output)
+}
+
+// totalDuration sums clip lengths.
+func totalDuration(clips []analyzer.Interval) float64 {
+	var total float64
+	for _, c := range clips {
+		total += c.Duration()
+	}
+	return total
+}
+
+// ffTime formats seconds for ffmpeg filter arguments.
+func ffTime(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', 3, 64)
+}
@@ -0,0 +1,103 @@
+// Package stagelog defines the logging contract shared by every pipeline stage.
+// Analyzer, transcoder, segmenter, manifester, and thumbnailer all accept a
+// stagelog.Logger, so a single implementation (console, slog, zap, ...) can be
+// injected once and observed consistently across the whole pipeline.
+//
+// Concrete implementations live elsewhere (see internal/utils/logging); this
+// package only holds the interface and lightweight adapters so it can be
+// imported by any stage without creating import cycles.
+package stagelog
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+)
+
+// Logger is the stage-aware logging interface used throughout the pipeline.
+//   - LogStage reports a high-level step (e.g. "analyze", "segment")
+//   - LogVariant reports an event scoped to one rendition (e.g. "720p_3000k")
+//   - LogError reports a failure along with the stage it occurred in
+//   - LogProgress reports completion percentage for a long-running task
+type Logger interface {
+	LogStage(stage string, msg string)
+	LogVariant(variant string, msg string)
+	LogError(stage string, err error)
+	LogProgress(label string, percent float64)
+}
+
+// OrStd returns l, or Std when l is nil.
+// Lets stages accept an optional logger without nil checks at every call site.
+func OrStd(l Logger) Logger {
+	if l == nil {
+		return Std
+	}
+	return l
+}
+
+// Std writes through the standard library log package.
+var Std Logger = stdLogger{}
+
+// Nop discards every record. Useful for tests and silent library calls.
+var Nop Logger = nopLogger{}
+
+type stdLogger struct{}
+
+func (stdLogger) LogStage(stage, msg string)     { log.Printf("[stage][%s] %s", stage, msg) }
+func (stdLogger) LogVariant(variant, msg string) { log.Printf("[variant][%s] %s", variant, msg) }
+func (stdLogger) LogError(stage string, err error) {
+	log.Printf("[error][%s] %v", stage, err)
+}
+func (stdLogger) LogProgress(label string, percent float64) {
+	log.Printf("[progress][%s] %.2f%%", label, percent)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) LogStage(string, string)     {}
+func (nopLogger) LogVariant(string, string)   {}
+func (nopLogger) LogError(string, error)      {}
+func (nopLogger) LogProgress(string, float64) {}
+
+// FromSlog adapts a *slog.Logger to the Logger interface.
+// Stage, variant, and progress labels are emitted as structured attributes.
+func FromSlog(s *slog.Logger) Logger {
+	return slogAdapter{s}
+}
+
+type slogAdapter struct{ s *slog.Logger }
+
+func (a slogAdapter) LogStage(stage, msg string) { a.s.Info(msg, "stage", stage) }
+func (a slogAdapter) LogVariant(variant, msg string) {
+	a.s.Info(msg, "variant", variant)
+}
+func (a slogAdapter) LogError(stage string, err error) {
+	a.s.Error(err.Error(), "stage", stage, "err", err)
+}
+func (a slogAdapter) LogProgress(label string, percent float64) {
+	a.s.Info("progress", "progress", label, "percent", percent)
+}
+
+// KeyValueLogger is satisfied by sugared key/value loggers such as *zap.SugaredLogger.
+type KeyValueLogger interface {
+	Infow(msg string, keysAndValues ...any)
+	Errorw(msg string, keysAndValues ...any)
+}
+
+// FromKeyValue adapts a key/value logger (e.g. zap.S()) to the Logger interface.
+func FromKeyValue(kv KeyValueLogger) Logger {
+	return kvAdapter{kv}
+}
+
+type kvAdapter struct{ kv KeyValueLogger }
+
+func (a kvAdapter) LogStage(stage, msg string) { a.kv.Infow(msg, "stage", stage) }
+func (a kvAdapter) LogVariant(variant, msg string) {
+	a.kv.Infow(msg, "variant", variant)
+}
+func (a kvAdapter) LogError(stage string, err error) {
+	a.kv.Errorw(err.Error(), "stage", stage, "err", err)
+}
+func (a kvAdapter) LogProgress(label string, percent float64) {
+	a.kv.Infow("progress", "progress", label, "percent", fmt.Sprintf("%.2f", percent))
+}
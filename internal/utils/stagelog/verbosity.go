@@ -0,0 +1,62 @@
+package stagelog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Verbosity controls how much output the pipeline produces.
+//   - Quiet: errors only; no progress ticker, command echo, or ffmpeg output
+//   - Normal: stage/variant messages, progress, and command echo (default)
+//   - Debug: everything in Normal plus full ffmpeg stderr
+type Verbosity int
+
+const (
+	Normal Verbosity = iota
+	Quiet
+	Debug
+)
+
+// String returns the lowercase name of the verbosity level.
+func (v Verbosity) String() string {
+	switch v {
+	case Quiet:
+		return "quiet"
+	case Debug:
+		return "debug"
+	default:
+		return "normal"
+	}
+}
+
+// ParseVerbosity converts "quiet", "normal", or "debug" into a Verbosity.
+func ParseVerbosity(name string) (Verbosity, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "normal":
+		return Normal, nil
+	case "quiet":
+		return Quiet, nil
+	case "debug":
+		return Debug, nil
+	default:
+		return Normal, fmt.Errorf("invalid verbosity %q (want quiet, normal, or debug)", name)
+	}
+}
+
+// Filter wraps l so records are dropped according to v.
+// In Quiet mode only LogError passes through; other levels pass everything.
+func Filter(l Logger, v Verbosity) Logger {
+	if v != Quiet {
+		return l
+	}
+	return quietLogger{OrStd(l)}
+}
+
+type quietLogger struct{ next Logger }
+
+func (quietLogger) LogStage(string, string)     {}
+func (quietLogger) LogVariant(string, string)   {}
+func (quietLogger) LogProgress(string, float64) {}
+func (q quietLogger) LogError(stage string, err error) {
+	q.next.LogError(stage, err)
+}
@@ -0,0 +1,102 @@
+package subtitles
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding is a detected text encoding.
+type Encoding string
+
+// Encodings DetectEncoding distinguishes.
+const (
+	UTF8        Encoding = "utf-8"
+	UTF16LE     Encoding = "utf-16le"
+	UTF16BE     Encoding = "utf-16be"
+	Windows1252 Encoding = "windows-1252" // Also covers ISO-8859-1, its subset for printable text
+)
+
+// DetectEncoding guesses the encoding of caption data: a byte order mark
+// decides outright, then UTF-16 is recognized by its NUL bytes, then valid
+// UTF-8 is taken as such. Anything else is treated as Windows-1252, the usual
+// encoding of legacy SRT files.
+func DetectEncoding(data []byte) Encoding {
+	switch {
+	case bytes.HasPrefix(data, utf8BOM) && utf8.Valid(data):
+		return UTF8
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return UTF16LE
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return UTF16BE
+	}
+	// ASCII text in UTF-16 has a NUL in every other byte
+	if len(data) >= 4 {
+		var even, odd int
+		for i, b := range data {
+			if b == 0 {
+				if i%2 == 0 {
+					even++
+				} else {
+					odd++
+				}
+			}
+		}
+		half := len(data) / 2
+		switch {
+		case odd > half*3/4:
+			return UTF16LE
+		case even > half*3/4:
+			return UTF16BE
+		}
+	}
+	if utf8.Valid(data) {
+		return UTF8
+	}
+	return Windows1252
+}
+
+// DecodeText converts caption data to a UTF-8 string without a byte order
+// mark and with Unix line endings, reporting the encoding it detected.
+func DecodeText(data []byte) (string, Encoding) {
+	enc := DetectEncoding(data)
+	var s string
+	switch enc {
+	case UTF16LE, UTF16BE:
+		data = bytes.TrimPrefix(bytes.TrimPrefix(data, []byte{0xFF, 0xFE}), []byte{0xFE, 0xFF})
+		units := make([]uint16, len(data)/2)
+		for i := range units {
+			if enc == UTF16LE {
+				units[i] = uint16(data[2*i]) | uint16(data[2*i+1])<<8
+			} else {
+				units[i] = uint16(data[2*i])<<8 | uint16(data[2*i+1])
+			}
+		}
+		s = string(utf16.Decode(units))
+	case Windows1252:
+		var sb strings.Builder
+		for _, b := range bytes.TrimPrefix(data, utf8BOM) {
+			if b >= 0x80 && b < 0xA0 && cp1252[b-0x80] != 0 {
+				sb.WriteRune(cp1252[b-0x80])
+			} else {
+				sb.WriteRune(rune(b))
+			}
+		}
+		s = sb.String()
+	default:
+		s = string(bytes.TrimPrefix(data, utf8BOM))
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\r", "\n"), enc
+}
+
+// utf8BOM is the UTF-8 byte order mark some editors prepend.
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// cp1252 maps Windows-1252 bytes 0x80-0x9F, where it differs from Latin-1.
+// Zero entries are undefined and decoded as their Latin-1 control characters.
+var cp1252 = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
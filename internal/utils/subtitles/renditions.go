@@ -0,0 +1,241 @@
+package subtitles
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/executil"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/langtag"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/namer"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
+)
+
+// Dir is the subtitle directory inside the slug directory.
+const Dir = "subtitles"
+
+// Track is a WebVTT rendition written by Generate.
+type Track struct {
+	Name     string // Name players show
+	Language string // Language tag, "" if unknown
+	Default  bool   // Selected when the player has no preference
+	Forced   bool   // Forced narrative: shown for foreign-language dialogue even with subtitles off
+	Source   string // "stream N" for embedded streams, else the external file path
+	VTT      string // Path of the WebVTT file
+	Playlist string // Path of the single-segment HLS playlist wrapping VTT
+	Cues     int    // Number of cues written
+}
+
+// DefaultVideoStart is the presentation time, in seconds, at which ffmpeg's
+// MPEG-TS muxer starts video by default; Generate callers use it when the
+// segments can't be probed.
+const DefaultVideoStart = 1.4
+
+// Generate writes a WebVTT file and an HLS subtitle playlist into
+// <slugDir>/subtitles/ for every caption the profile selects: the source's
+// text subtitle streams when Subtitles.Embedded is set, forced-narrative
+// text streams unless ForcedSubtitles is "burn" or "off", then each external
+// file. A stream burned into the video (TranscodeProfile.BurnedSubtitle) is
+// never also published. Embedded streams are extracted with ffmpeg into
+// scratch (the system temp directory when empty). A caption that fails is
+// skipped and reported in the returned error alongside the tracks that
+// succeeded. At most one unforced track is marked default. videoStart is the
+// presentation time in seconds at which the video segments start; every
+// WebVTT file maps its zero to it with X-TIMESTAMP-MAP, so cues stay in sync
+// with segments whose timestamps don't start at zero.
+func Generate(ctx context.Context, profile *transcoder.TranscodeProfile, media *analyzer.MediaInfo, slugDir, scratch string, videoStart float64, logger stagelog.Logger) ([]Track, error) {
+	logger = stagelog.OrStd(logger)
+	settings := profile.Subtitles
+	if settings == nil {
+		settings = &transcoder.SubtitleSettings{}
+	}
+	streams := selectStreams(profile, media)
+	if len(streams) == 0 && len(settings.Files) == 0 {
+		return nil, nil
+	}
+	dir := filepath.Join(slugDir, Dir)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create subtitle directory: %w", err)
+	}
+
+	var duration float64
+	if media != nil {
+		duration = media.Duration
+	}
+	var tracks []Track
+	var errs []error
+	files, labels := make(map[string]int), make(map[string]int)
+	add := func(t Track, cues []Cue) {
+		t.VTT, t.Playlist = uniquePaths(dir, t.Language, files)
+		// NAME must be unique within the HLS group
+		if labels[t.Name]++; labels[t.Name] > 1 {
+			t.Name = fmt.Sprintf("%s (%d)", t.Name, labels[t.Name])
+		}
+		if err := writeRendition(t, cues, duration, videoStart); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", t.Source, err))
+			return
+		}
+		t.Cues = len(cues)
+		logger.LogStage("subtitle", fmt.Sprintf("💬 %s (%s): %d cue(s) → %s", t.Name, t.Source, t.Cues, filepath.Base(t.VTT)))
+		tracks = append(tracks, t)
+	}
+
+	forcedMode := profile.ForcedSubtitleMode() != transcoder.ForcedOff
+	for _, s := range streams {
+		source := fmt.Sprintf("stream %d", s.Index)
+		if !s.IsTextSubtitle() {
+			logger.LogStage("subtitle", fmt.Sprintf("⏭️ Skipping bitmap subtitle %s (%s)", source, s.Codec))
+			continue
+		}
+		cues, err := extractStream(ctx, profile, s.Index, scratch)
+		if err != nil {
+			if ctx.Err() != nil {
+				return tracks, context.Cause(ctx)
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", source, err))
+			continue
+		}
+		forced := s.Forced && forcedMode
+		name := s.Title
+		if name == "" && forced {
+			name = displayName("", s.Language, len(tracks)) + " (forced)"
+		}
+		add(Track{Name: displayName(name, s.Language, len(tracks)), Language: s.Language, Default: s.Default && !forced, Forced: forced, Source: source}, cues)
+	}
+
+	for _, f := range settings.Files {
+		data, err := os.ReadFile(f.Path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		cues, err := Parse(data, FormatFromPath(f.Path))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.Path, err))
+			continue
+		}
+		if enc := DetectEncoding(data); enc != UTF8 {
+			logger.LogStage("subtitle", fmt.Sprintf("🔤 %s decoded as %s", filepath.Base(f.Path), enc))
+		}
+		cues = Shift(cues, time.Duration(f.OffsetMs)*time.Millisecond)
+		language := langtag.Normalize(f.Language)
+		add(Track{Name: displayName(f.Name, language, len(tracks)), Language: language, Default: f.Default && !f.Forced, Forced: f.Forced, Source: f.Path}, cues)
+	}
+
+	// HLS allows one DEFAULT=YES per group; the first flagged track wins
+	seenDefault := false
+	for i := range tracks {
+		if tracks[i].Default && seenDefault {
+			tracks[i].Default = false
+		}
+		seenDefault = seenDefault || tracks[i].Default
+	}
+	return tracks, errors.Join(errs...)
+}
+
+// selectStreams returns the embedded subtitle streams Generate publishes.
+func selectStreams(profile *transcoder.TranscodeProfile, media *analyzer.MediaInfo) []analyzer.StreamInfo {
+	if media == nil {
+		return nil
+	}
+	embedded := profile.Subtitles != nil && profile.Subtitles.Embedded
+	mode := profile.ForcedSubtitleMode()
+	burned := profile.BurnedSubtitle(media)
+	var out []analyzer.StreamInfo
+	for _, s := range media.SubtitleTracks() {
+		switch {
+		case burned != nil && s.Index == burned.Index:
+		case embedded:
+			out = append(out, s)
+		case s.Forced && (mode == transcoder.ForcedAuto || mode == transcoder.ForcedRendition):
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// extractStream converts one embedded text subtitle stream to SubRip with
+// ffmpeg and parses it.
+func extractStream(ctx context.Context, profile *transcoder.TranscodeProfile, index int, scratch string) ([]Cue, error) {
+	tmp, err := os.MkdirTemp(scratch, "subtitle-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	out := filepath.Join(tmp, fmt.Sprintf("stream-%d.srt", index))
+	cmd := []string{
+		"ffmpeg", "-y",
+		"-i", profile.InputPath,
+		"-map", fmt.Sprintf("0:%d", index),
+		"-c:s", "srt",
+		out,
+	}
+	if err := executil.RunCommandContext(ctx, cmd, profile.CommandLimits()); err != nil {
+		return nil, fmt.Errorf("failed to extract subtitles: %w", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data, SRT)
+}
+
+// writeRendition writes the track's WebVTT file, with its zero mapped to the
+// video's start PTS, and a playlist that serves it as one segment spanning
+// the whole title.
+func writeRendition(t Track, cues []Cue, duration, videoStart float64) error {
+	f, err := os.Create(t.VTT)
+	if err != nil {
+		return err
+	}
+	timestampMap := fmt.Sprintf("X-TIMESTAMP-MAP=MPEGTS:%d,LOCAL:00:00:00.000", int64(math.Round(videoStart*90000)))
+	if err := writeVTT(f, cues, timestampMap); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if n := len(cues); n > 0 && cues[n-1].End.Seconds() > duration {
+		duration = cues[n-1].End.Seconds()
+	}
+	// TARGETDURATION must be a positive integer at least as long as the segment
+	target := max(int(math.Ceil(duration)), 1)
+	playlist := fmt.Sprintf("#EXTM3U\n#EXT-X-VERSION:3\n#EXT-X-TARGETDURATION:%d\n#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:VOD\n#EXTINF:%.3f,\n%s\n#EXT-X-ENDLIST\n",
+		target, duration, filepath.Base(t.VTT))
+	return os.WriteFile(t.Playlist, []byte(playlist), 0644)
+}
+
+// uniquePaths picks <lang>.vtt and <lang>.m3u8 in dir, numbering repeated
+// languages (en, en-2, ...).
+func uniquePaths(dir, language string, seen map[string]int) (vtt, playlist string) {
+	base := "und"
+	if strings.TrimSpace(language) != "" {
+		base = namer.Slugify(language)
+	}
+	seen[base]++
+	if n := seen[base]; n > 1 {
+		base = fmt.Sprintf("%s-%d", base, n)
+	}
+	return filepath.Join(dir, base+".vtt"), filepath.Join(dir, base+".m3u8")
+}
+
+// displayName returns name, else the language, else a numbered fallback.
+func displayName(name, language string, n int) string {
+	switch {
+	case name != "":
+		return name
+	case language != "":
+		return language
+	}
+	return fmt.Sprintf("Subtitles %d", n+1)
+}
@@ -0,0 +1,38 @@
+package subtitles
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// parseSRT reads SubRip blocks: an optional counter, a timing line with
+// comma (or dot) milliseconds, then the text.
+func parseSRT(text string) ([]Cue, error) {
+	var cues []Cue
+	for _, block := range blocks(text) {
+		i := 0
+		if !strings.Contains(block[0], "-->") {
+			i++ // Counter
+		}
+		if i >= len(block) {
+			continue
+		}
+		start, end, _, err := parseTiming(block[i])
+		if err != nil {
+			return nil, fmt.Errorf("srt: %w", err)
+		}
+		cues = append(cues, Cue{Start: start, End: end, Text: strings.Join(block[i+1:], "\n")})
+	}
+	return cues, nil
+}
+
+// writeSRT writes numbered SubRip blocks.
+func writeSRT(w io.Writer, cues []Cue) error {
+	bw := bufio.NewWriter(w)
+	for i, c := range cues {
+		fmt.Fprintf(bw, "%d\n%s --> %s\n%s\n\n", i+1, formatTimestamp(c.Start, ","), formatTimestamp(c.End, ","), c.Text)
+	}
+	return bw.Flush()
+}
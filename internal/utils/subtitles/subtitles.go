@@ -0,0 +1,234 @@
+// Package subtitles converts caption files between SubRip (SRT), WebVTT, and
+// TTML, shifts their timing, and detects their text encoding. The subtitle
+// stage uses it to turn embedded and external captions into WebVTT renditions;
+// callers that receive caption files in mixed formats can use it directly.
+package subtitles
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Format is a caption file format.
+type Format string
+
+// Supported formats.
+const (
+	SRT  Format = "srt"  // SubRip
+	VTT  Format = "vtt"  // WebVTT
+	TTML Format = "ttml" // TTML / DFXP
+)
+
+// ErrUnknownFormat is returned when a caption format can't be determined.
+var ErrUnknownFormat = errors.New("unknown subtitle format")
+
+// Cue is one timed caption.
+type Cue struct {
+	Start    time.Duration
+	End      time.Duration
+	Text     string // Lines separated by "\n"; inline <i>, <b>, and <u> markup is kept
+	Settings string // WebVTT cue settings (e.g. "line:0 align:start"); dropped by other formats
+}
+
+// ParseFormat converts a format name or file extension ("srt", ".vtt",
+// "webvtt", "dfxp", ...) into a Format.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s), ".")) {
+	case "srt", "subrip":
+		return SRT, nil
+	case "vtt", "webvtt":
+		return VTT, nil
+	case "ttml", "dfxp", "xml", "ttml2":
+		return TTML, nil
+	}
+	return "", fmt.Errorf("%w: %q (want srt, vtt, or ttml)", ErrUnknownFormat, s)
+}
+
+// FormatFromPath returns the format implied by path's extension, or "".
+func FormatFromPath(path string) Format {
+	f, _ := ParseFormat(filepath.Ext(path))
+	return f
+}
+
+// Sniff guesses the format of caption data from its content, or returns "".
+func Sniff(data []byte) Format {
+	text, _ := DecodeText(data)
+	text = strings.TrimSpace(text)
+	switch {
+	case strings.HasPrefix(text, "WEBVTT"):
+		return VTT
+	case strings.HasPrefix(text, "<") && strings.Contains(text, "<tt"):
+		return TTML
+	case strings.Contains(text, "-->"):
+		return SRT
+	}
+	return ""
+}
+
+// Parse decodes caption data in format f, detecting its text encoding first.
+// An empty f sniffs the format from the content.
+func Parse(data []byte, f Format) ([]Cue, error) {
+	if f == "" {
+		if f = Sniff(data); f == "" {
+			return nil, ErrUnknownFormat
+		}
+	}
+	text, _ := DecodeText(data)
+	switch f {
+	case SRT:
+		return parseSRT(text)
+	case VTT:
+		return parseVTT(text)
+	case TTML:
+		return parseTTML(text)
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, f)
+}
+
+// Write encodes cues in format f as UTF-8.
+func Write(w io.Writer, cues []Cue, f Format) error {
+	switch f {
+	case SRT:
+		return writeSRT(w, cues)
+	case VTT:
+		return writeVTT(w, cues)
+	case TTML:
+		return writeTTML(w, cues)
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownFormat, f)
+}
+
+// Shift moves every cue by d. Cues that end up entirely before zero are
+// dropped; cues straddling zero are clipped to start at zero.
+func Shift(cues []Cue, d time.Duration) []Cue {
+	if d == 0 {
+		return cues
+	}
+	out := make([]Cue, 0, len(cues))
+	for _, c := range cues {
+		c.Start += d
+		c.End += d
+		if c.End <= 0 {
+			continue
+		}
+		if c.Start < 0 {
+			c.Start = 0
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// Convert parses data as from (sniffed when empty), shifts it by shift, and
+// returns it encoded as to.
+func Convert(data []byte, from, to Format, shift time.Duration) ([]byte, error) {
+	cues, err := Parse(data, from)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, Shift(cues, shift), to); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ConvertFile converts src into dst, taking both formats from the file
+// extensions (src is sniffed when its extension is unrecognized).
+func ConvertFile(src, dst string, shift time.Duration) error {
+	to := FormatFromPath(dst)
+	if to == "" {
+		return fmt.Errorf("%w: %s", ErrUnknownFormat, dst)
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	out, err := Convert(data, FormatFromPath(src), to, shift)
+	if err != nil {
+		return fmt.Errorf("failed to convert %s: %w", src, err)
+	}
+	return os.WriteFile(dst, out, 0644)
+}
+
+// formatTimestamp renders d as hh:mm:ss<sep>mmm.
+func formatTimestamp(d time.Duration, sep string) string {
+	if d < 0 {
+		d = 0
+	}
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// parseTimestamp reads [hh:]mm:ss[.,]mmm as used by SRT and WebVTT.
+func parseTimestamp(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	main, frac, _ := strings.Cut(strings.Replace(s, ",", ".", 1), ".")
+	parts := strings.Split(main, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+	var total time.Duration
+	for _, p := range parts {
+		var n int
+		if _, err := fmt.Sscanf(p, "%d", &n); err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid timestamp %q", s)
+		}
+		total = total*60 + time.Duration(n)*time.Second
+	}
+	if frac != "" {
+		frac = (frac + "000")[:3]
+		var ms int
+		if _, err := fmt.Sscanf(frac, "%d", &ms); err != nil {
+			return 0, fmt.Errorf("invalid timestamp %q", s)
+		}
+		total += time.Duration(ms) * time.Millisecond
+	}
+	return total, nil
+}
+
+// parseTiming reads a "start --> end [settings]" line.
+func parseTiming(line string) (start, end time.Duration, settings string, err error) {
+	left, right, ok := strings.Cut(line, "-->")
+	if !ok {
+		return 0, 0, "", fmt.Errorf("invalid cue timing %q", line)
+	}
+	fields := strings.Fields(right)
+	if len(fields) == 0 {
+		return 0, 0, "", fmt.Errorf("invalid cue timing %q", line)
+	}
+	if start, err = parseTimestamp(left); err != nil {
+		return 0, 0, "", err
+	}
+	if end, err = parseTimestamp(fields[0]); err != nil {
+		return 0, 0, "", err
+	}
+	return start, end, strings.Join(fields[1:], " "), nil
+}
+
+// blocks splits text into blank-line separated blocks of trimmed lines.
+func blocks(text string) [][]string {
+	var out [][]string
+	var cur []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			if len(cur) > 0 {
+				out = append(out, cur)
+				cur = nil
+			}
+			continue
+		}
+		cur = append(cur, line)
+	}
+	if len(cur) > 0 {
+		out = append(out, cur)
+	}
+	return out
+}
@@ -0,0 +1,235 @@
+package subtitles
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TTML parameter namespace, for ttp:frameRate and ttp:tickRate.
+const ttpNS = "http://www.w3.org/ns/ttml#parameter"
+
+// parseTTML reads the <p> elements of a TTML/DFXP document. Timing comes from
+// begin plus end or dur, in clock time (hh:mm:ss.fff, hh:mm:ss:ff) or offset
+// time (1.5s, 200ms, 12f, 9000t). <br/> becomes a line break and italic or
+// bold spans become <i> and <b>. Timing inherited from enclosing <div>s is
+// not applied.
+func parseTTML(text string) ([]Cue, error) {
+	dec := xml.NewDecoder(strings.NewReader(text))
+	dec.Strict = false
+	rates := ttmlRates{frame: 30, tick: 1}
+
+	var cues []Cue
+	var cur *Cue
+	var sb strings.Builder
+	var closers []string // Markup to close at each open span
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ttml: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "tt":
+				rates.read(t.Attr)
+			case "p":
+				c, err := rates.cue(t.Attr)
+				if err != nil {
+					return nil, fmt.Errorf("ttml: %w", err)
+				}
+				cur = &c
+				sb.Reset()
+			case "br":
+				if cur != nil {
+					sb.WriteString("\n")
+				}
+			case "span":
+				closer := ""
+				for _, a := range t.Attr {
+					switch {
+					case a.Name.Local == "fontStyle" && a.Value == "italic":
+						sb.WriteString("<i>")
+						closer = "</i>" + closer
+					case a.Name.Local == "fontWeight" && a.Value == "bold":
+						sb.WriteString("<b>")
+						closer = "</b>" + closer
+					}
+				}
+				closers = append(closers, closer)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p":
+				if cur != nil {
+					cur.Text = strings.TrimSpace(collapseSpace(sb.String()))
+					cues = append(cues, *cur)
+					cur = nil
+				}
+			case "span":
+				if n := len(closers); n > 0 {
+					sb.WriteString(closers[n-1])
+					closers = closers[:n-1]
+				}
+			}
+		case xml.CharData:
+			if cur != nil {
+				sb.Write(t)
+			}
+		}
+	}
+	return cues, nil
+}
+
+// ttmlRates holds the document's frame and tick rates.
+type ttmlRates struct {
+	frame float64
+	tick  float64
+}
+
+// read applies ttp:frameRate, ttp:frameRateMultiplier, and ttp:tickRate.
+func (r *ttmlRates) read(attrs []xml.Attr) {
+	multiplier := 1.0
+	for _, a := range attrs {
+		if a.Name.Space != ttpNS && a.Name.Space != "ttp" {
+			continue
+		}
+		switch a.Name.Local {
+		case "frameRate":
+			if v, err := strconv.ParseFloat(a.Value, 64); err == nil && v > 0 {
+				r.frame = v
+			}
+		case "frameRateMultiplier":
+			if num, den, ok := strings.Cut(a.Value, " "); ok {
+				n, err1 := strconv.ParseFloat(num, 64)
+				d, err2 := strconv.ParseFloat(den, 64)
+				if err1 == nil && err2 == nil && d > 0 {
+					multiplier = n / d
+				}
+			}
+		case "tickRate":
+			if v, err := strconv.ParseFloat(a.Value, 64); err == nil && v > 0 {
+				r.tick = v
+			}
+		}
+	}
+	r.frame *= multiplier
+}
+
+// cue reads the timing attributes of a <p>.
+func (r ttmlRates) cue(attrs []xml.Attr) (Cue, error) {
+	var c Cue
+	var dur time.Duration
+	var hasEnd, hasDur bool
+	for _, a := range attrs {
+		var err error
+		switch a.Name.Local {
+		case "begin":
+			c.Start, err = r.parse(a.Value)
+		case "end":
+			c.End, err = r.parse(a.Value)
+			hasEnd = true
+		case "dur":
+			dur, err = r.parse(a.Value)
+			hasDur = true
+		}
+		if err != nil {
+			return c, err
+		}
+	}
+	if !hasEnd && hasDur {
+		c.End = c.Start + dur
+	}
+	if !hasEnd && !hasDur {
+		return c, fmt.Errorf("<p> at %s has neither end nor dur", formatTimestamp(c.Start, "."))
+	}
+	return c, nil
+}
+
+var (
+	ttmlClock  = regexp.MustCompile(`^(\d+):(\d{2}):(\d{2})(?:(\.\d+)|:(\d+)(?:\.\d+)?)?$`)
+	ttmlOffset = regexp.MustCompile(`^(\d+(?:\.\d+)?)(h|ms|m|s|f|t)$`)
+)
+
+// parse reads a TTML time expression.
+func (r ttmlRates) parse(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if m := ttmlClock.FindStringSubmatch(s); m != nil {
+		h, _ := strconv.Atoi(m[1])
+		min, _ := strconv.Atoi(m[2])
+		sec, _ := strconv.Atoi(m[3])
+		secs := float64(h*3600 + min*60 + sec)
+		switch {
+		case m[4] != "":
+			frac, _ := strconv.ParseFloat(m[4], 64)
+			secs += frac
+		case m[5] != "":
+			frames, _ := strconv.Atoi(m[5])
+			secs += float64(frames) / r.frame
+		}
+		return seconds(secs), nil
+	}
+	if m := ttmlOffset.FindStringSubmatch(s); m != nil {
+		v, _ := strconv.ParseFloat(m[1], 64)
+		switch m[2] {
+		case "h":
+			v *= 3600
+		case "m":
+			v *= 60
+		case "ms":
+			v /= 1000
+		case "f":
+			v /= r.frame
+		case "t":
+			v /= r.tick
+		}
+		return seconds(v), nil
+	}
+	return 0, fmt.Errorf("invalid time expression %q", s)
+}
+
+// seconds converts fractional seconds to a Duration rounded to the millisecond.
+func seconds(s float64) time.Duration {
+	return time.Duration(s*1000+0.5) * time.Millisecond
+}
+
+// collapseSpace folds XML whitespace runs into single spaces, keeping the
+// line breaks that came from <br/>.
+func collapseSpace(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.Join(strings.Fields(l), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// markup matches the inline tags SRT and WebVTT cues may carry.
+var markup = regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+
+// writeTTML writes a minimal TTML document. Inline markup is stripped.
+func writeTTML(w io.Writer, cues []Cue) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	bw.WriteString(`<tt xmlns="http://www.w3.org/ns/ttml">` + "\n")
+	bw.WriteString("  <body>\n    <div>\n")
+	for _, c := range cues {
+		lines := strings.Split(markup.ReplaceAllString(c.Text, ""), "\n")
+		for i, l := range lines {
+			var esc strings.Builder
+			xml.EscapeText(&esc, []byte(l))
+			lines[i] = esc.String()
+		}
+		fmt.Fprintf(bw, "      <p begin=\"%s\" end=\"%s\">%s</p>\n",
+			formatTimestamp(c.Start, "."), formatTimestamp(c.End, "."), strings.Join(lines, "<br/>"))
+	}
+	bw.WriteString("    </div>\n  </body>\n</tt>\n")
+	return bw.Flush()
+}
@@ -0,0 +1,57 @@
+package subtitles
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// parseVTT reads WebVTT cues, skipping the header and NOTE, STYLE, and
+// REGION blocks. Cue identifiers are dropped; cue settings are kept.
+func parseVTT(text string) ([]Cue, error) {
+	bs := blocks(text)
+	if len(bs) == 0 || !strings.HasPrefix(bs[0][0], "WEBVTT") {
+		return nil, fmt.Errorf("vtt: missing WEBVTT header")
+	}
+	var cues []Cue
+	for _, block := range bs[1:] {
+		switch first := block[0]; {
+		case strings.HasPrefix(first, "NOTE"), first == "STYLE", first == "REGION":
+			continue
+		}
+		i := 0
+		if !strings.Contains(block[0], "-->") {
+			i++ // Cue identifier
+		}
+		if i >= len(block) {
+			continue
+		}
+		start, end, settings, err := parseTiming(block[i])
+		if err != nil {
+			return nil, fmt.Errorf("vtt: %w", err)
+		}
+		cues = append(cues, Cue{Start: start, End: end, Text: strings.Join(block[i+1:], "\n"), Settings: settings})
+	}
+	return cues, nil
+}
+
+// writeVTT writes a WebVTT file. header lines (e.g. an HLS X-TIMESTAMP-MAP)
+// are written right after the WEBVTT line.
+func writeVTT(w io.Writer, cues []Cue, header ...string) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("WEBVTT\n")
+	for _, h := range header {
+		bw.WriteString(h + "\n")
+	}
+	bw.WriteString("\n")
+	for _, c := range cues {
+		fmt.Fprintf(bw, "%s --> %s", formatTimestamp(c.Start, "."), formatTimestamp(c.End, "."))
+		if c.Settings != "" {
+			bw.WriteString(" " + c.Settings)
+		}
+		// A blank line would end the cue early
+		fmt.Fprintf(bw, "\n%s\n\n", strings.ReplaceAll(c.Text, "\n\n", "\n"))
+	}
+	return bw.Flush()
+}
@@ -2,9 +2,10 @@ package thumbnailer
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
 )
 
 // GenerateTimestamps returns a slice of timestamps (in seconds) based on the
@@ -14,7 +15,6 @@ import (
 // If duration is 0 or segmentLength is invalid, it returns an empty slice.
 func GenerateTimestamps(duration float64, segmentLength int) []float64 {
 	if duration <= 0 || segmentLength <= 0 {
-		log.Printf("⚠️ Invalid duration (%.2f) or segment length (%d), skipping timestamp generation", duration, segmentLength)
 		return []float64{}
 	}
 
@@ -25,6 +25,36 @@ func GenerateTimestamps(duration float64, segmentLength int) []float64 {
 	return timestamps
 }
 
+// AvoidBlackFrames moves a thumbnail capture point out of a detected black or
+// frozen interval, staying within its slot [ts, ts+interval). Returns ts unchanged
+// when no detection ran or the slot has no clean frame.
+func AvoidBlackFrames(media analyzer.MediaInfo, ts float64, interval float64) float64 {
+	const margin = 0.5 // Skip past fade-ins at the end of the black stretch
+	iv, bad := media.InBlackOrFrozen(ts)
+	if !bad {
+		return ts
+	}
+	if next := iv.End + margin; next < ts+interval && next < media.Duration {
+		if _, stillBad := media.InBlackOrFrozen(next); !stillBad {
+			return next
+		}
+	}
+	return ts
+}
+
+// ThumbnailInterval returns the number of seconds between thumbnails.
+// Uses the configured segment length when set, otherwise the keyframe interval,
+// falling back to 4s when the keyframe interval is too short to be useful.
+func ThumbnailInterval(media analyzer.MediaInfo, segmentLength int) int {
+	if segmentLength > 0 {
+		return segmentLength
+	}
+	if media.KeyframeInterval >= 3.0 {
+		return int(media.KeyframeInterval)
+	}
+	return 4 // fallback default
+}
+
 // EnsureThumbnailDir creates the thumbnails directory inside the given slug path
 // if it doesn't already exist. Returns the full path to the directory.
 func EnsureThumbnailDir(outputDir string) (string, error) {
@@ -41,8 +71,7 @@ func EnsureThumbnailDir(outputDir string) (string, error) {
 // the source height. Assumes outputDir already includes the slug directory.
 // Filename format: <slug>_<height>p_<bitrate>kbps.mp4
 func GetVariantPath(outputDir string, slug string, height int, bitrate int) (string, error) {
-	filename := fmt.Sprintf("%s_%dp_%dkbps.mp4", slug, height, bitrate)
-	fullPath := filepath.Join(outputDir, filename)
+	fullPath := filepath.Join(outputDir, VariantFilename(slug, height, bitrate))
 
 	if _, err := os.Stat(fullPath); err != nil {
 		return "", fmt.Errorf("transcoded variant not found: %s", fullPath)
@@ -50,6 +79,12 @@ func GetVariantPath(outputDir string, slug string, height int, bitrate int) (str
 	return fullPath, nil
 }
 
+// VariantFilename returns the transcoded variant filename for a slug, height, and bitrate.
+// Example: VariantFilename("movie", 720, 3000) -> "movie_720p_3000kbps.mp4"
+func VariantFilename(slug string, height int, bitrate int) string {
+	return fmt.Sprintf("%s_%dp_%dkbps.mp4", slug, height, bitrate)
+}
+
 // FormatTimestampFilename returns a filename for a thumbnail based on the timestamp.
 // Example: thumb_004.jpg for timestamp 4.0
 func FormatTimestampFilename(timestamp float64) string {
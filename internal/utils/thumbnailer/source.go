@@ -0,0 +1,116 @@
+package thumbnailer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
+)
+
+// Source is the file thumbnails are extracted from.
+type Source struct {
+	Path   string // Transcoded variant or original input
+	Input  bool   // Path is the original input
+	Height int    // Height frames are scaled to; 0 keeps the file's size
+}
+
+// SelectSource picks the thumbnail source per the profile's thumbnail_source:
+//   - auto: the variant at the source height, else the tallest variant, else the input
+//   - variant: the tallest variant
+//   - input: the original input, scaled down to the tallest variant
+//   - live: like input; the pipeline extracts them while the variants encode
+//
+// Variants of the primary codec tier are preferred over secondary tiers.
+func SelectSource(media analyzer.MediaInfo, result transcoder.TranscodeResult, logger stagelog.Logger) (Source, error) {
+	logger = stagelog.OrStd(logger)
+	mode := result.Profile.ThumbnailSourceMode()
+
+	var match, top *transcoder.ResolutionVariant
+	for _, v := range primaryVariants(result.Variants) {
+		if v.Height == media.Height && (match == nil || bitrateKbps(v) > bitrateKbps(*match)) {
+			match = &v
+		}
+		if top == nil || v.Height > top.Height || (v.Height == top.Height && bitrateKbps(v) > bitrateKbps(*top)) {
+			top = &v
+		}
+	}
+
+	switch mode {
+	case transcoder.ThumbnailSourceAuto:
+		if match != nil {
+			return variantSource(result, *match), nil
+		}
+		if top != nil {
+			logger.LogStage("thumbnail", fmt.Sprintf("ℹ️ No variant at source height %dp; using the %dp variant", media.Height, top.Height))
+			return variantSource(result, *top), nil
+		}
+		logger.LogStage("thumbnail", "ℹ️ No transcoded variants; extracting thumbnails from the input")
+		return inputSource(media, result, nil)
+	case transcoder.ThumbnailSourceVariant:
+		if top == nil {
+			return Source{}, fmt.Errorf("no transcoded variant to extract thumbnails from")
+		}
+		return variantSource(result, *top), nil
+	case transcoder.ThumbnailSourceInput, transcoder.ThumbnailSourceLive:
+		return inputSource(media, result, top)
+	}
+	return Source{}, fmt.Errorf("unknown thumbnail source %q", mode)
+}
+
+// primaryVariants returns the variants of the profile's primary codec, or
+// every variant when only secondary tiers were encoded.
+func primaryVariants(variants []transcoder.ResolutionVariant) []transcoder.ResolutionVariant {
+	var primary []transcoder.ResolutionVariant
+	for _, v := range variants {
+		if v.Tier == "" {
+			primary = append(primary, v)
+		}
+	}
+	if len(primary) == 0 {
+		return variants
+	}
+	return primary
+}
+
+// variantSource returns v's output file as a thumbnail source.
+func variantSource(result transcoder.TranscodeResult, v transcoder.ResolutionVariant) Source {
+	return Source{Path: filepath.Join(result.OutputDir, v.OutputFilename)}
+}
+
+// inputSource returns the original input as a thumbnail source, scaled to
+// the tallest variant when the input is taller.
+func inputSource(media analyzer.MediaInfo, result transcoder.TranscodeResult, top *transcoder.ResolutionVariant) (Source, error) {
+	path := result.InputPath
+	if path == "" && result.Profile != nil {
+		path = result.Profile.InputPath
+	}
+	if path == "" {
+		return Source{}, fmt.Errorf("no input path to extract thumbnails from")
+	}
+	src := Source{Path: path, Input: true}
+	if top != nil && top.Height < media.Height {
+		src.Height = top.Height
+	}
+	return src, nil
+}
+
+// bitrateKbps returns v's bitrate in kbps, or 0 when it can't be parsed.
+func bitrateKbps(v transcoder.ResolutionVariant) int {
+	kbps, _ := parseBitrateKbps(v.Bitrate)
+	return kbps
+}
+
+// filter returns the -vf filter chain for frames read from s, or "".
+func (s Source) filter(media analyzer.MediaInfo) string {
+	var filters []string
+	if s.Input && media.Scan.NeedsDeinterlace() {
+		filters = append(filters, "yadif")
+	}
+	if s.Height > 0 {
+		filters = append(filters, fmt.Sprintf("scale=-2:%d", s.Height))
+	}
+	return strings.Join(filters, ",")
+}
@@ -0,0 +1,162 @@
+package thumbnailer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/executil"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
+)
+
+// StoryboardFilename is the WebVTT storyboard written next to the sprite sheets.
+const StoryboardFilename = "storyboard.vtt"
+
+// SpriteSheets describes scrubber sprite sheets and their storyboard: tile k
+// covers [k*Interval, (k+1)*Interval) and sits at column k%Columns, row
+// (k/Columns)%Rows of sheet k/(Columns*Rows).
+type SpriteSheets struct {
+	Source     string   // Variant or input the frames are sampled from
+	Dir        string   // Thumbnails directory holding the sheets and storyboard
+	Interval   int      // Seconds between tiles
+	Offset     float64  // Seconds of pre-roll before the content; storyboard cues start after it
+	TileWidth  int      // Tile width in pixels
+	TileHeight int      // Tile height in pixels
+	Columns    int      // Tiles per sheet row
+	Rows       int      // Tile rows per sheet
+	Tiles      int      // Tiles across every sheet
+	Sheets     []string // Sheet filenames in order (e.g. "sprite_000.jpg")
+	Storyboard string   // WebVTT storyboard filename, cues pointing at sheet#xywh=x,y,w,h
+	Command    []string // ffmpeg command that renders the sheets
+}
+
+// PlanSprites computes the sprite sheets GenerateSprites would write for the
+// profile's sprites block without touching the filesystem. Frames come from
+// the same source as thumbnails (see SelectSource). Returns nil when the
+// profile requests no sprites or the media has no duration.
+func PlanSprites(media analyzer.MediaInfo, result transcoder.TranscodeResult, logger stagelog.Logger) (*SpriteSheets, error) {
+	logger = stagelog.OrStd(logger)
+	if result.Profile == nil || result.Profile.Sprites == nil || media.Duration <= 0 {
+		return nil, nil
+	}
+	if media.Width <= 0 || media.Height <= 0 {
+		return nil, fmt.Errorf("sprites need the source dimensions; got %dx%d", media.Width, media.Height)
+	}
+
+	settings := result.Profile.SpriteSettings()
+	interval := settings.Interval
+	if interval <= 0 {
+		interval = ThumbnailInterval(media, result.Profile.SegmentLength)
+	}
+	width := evenUp(settings.Width)
+	height := evenUp(int(math.Round(float64(width) * float64(media.Height) / float64(media.Width))))
+
+	src, err := SelectSource(media, result, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &SpriteSheets{
+		Source:     src.Path,
+		Dir:        filepath.Join(result.OutputDir, "thumbnails"),
+		Interval:   interval,
+		Offset:     result.PreRoll,
+		TileWidth:  width,
+		TileHeight: height,
+		Columns:    settings.Columns,
+		Rows:       settings.Rows,
+		Tiles:      int(math.Ceil(media.Duration / float64(interval))),
+		Storyboard: StoryboardFilename,
+	}
+	perSheet := s.Columns * s.Rows
+	for i := 0; i*perSheet < s.Tiles; i++ {
+		s.Sheets = append(s.Sheets, fmt.Sprintf("sprite_%03d.jpg", i))
+	}
+
+	var filters []string
+	if src.Input && media.Scan.NeedsDeinterlace() {
+		filters = append(filters, "yadif")
+	}
+	filters = append(filters,
+		fmt.Sprintf("fps=1/%d", interval),
+		fmt.Sprintf("scale=%d:%d", width, height),
+		fmt.Sprintf("tile=%dx%d", s.Columns, s.Rows),
+	)
+	// Sprites decode the whole file, so reads of the input are throttled like encodes
+	s.Command = []string{"ffmpeg"}
+	if src.Input {
+		s.Command = append(s.Command, result.Profile.ReadFlags(&media, 1)...)
+	} else if s.Offset > 0 {
+		// Tiles cover the content, not the bumpers before it
+		s.Command = append(s.Command, "-ss", fmt.Sprintf("%.3f", s.Offset))
+	}
+	s.Command = append(s.Command,
+		"-i", src.Path,
+		"-vf", strings.Join(filters, ","),
+		"-an", "-sn",
+		"-q:v", "3",
+		"-start_number", "0",
+		"-y", filepath.Join(s.Dir, "sprite_%03d.jpg"),
+	)
+	return s, nil
+}
+
+// GenerateSprites renders the profile's sprite sheets into the thumbnails
+// directory and writes the WebVTT storyboard indexing them. Returns nil when
+// the profile requests no sprites.
+func GenerateSprites(ctx context.Context, media analyzer.MediaInfo, result transcoder.TranscodeResult, logger stagelog.Logger) (*SpriteSheets, error) {
+	logger = stagelog.OrStd(logger)
+
+	s, err := PlanSprites(media, result, logger)
+	if err != nil || s == nil {
+		return nil, err
+	}
+	if _, err := os.Stat(s.Source); err != nil {
+		return nil, fmt.Errorf("failed to locate sprite source: file not found: %s", s.Source)
+	}
+	if _, err := EnsureThumbnailDir(result.OutputDir); err != nil {
+		return nil, fmt.Errorf("failed to prepare thumbnail directory: %w", err)
+	}
+
+	logger.LogStage("thumbnail", fmt.Sprintf("🧩 Rendering %d sprite tiles (%dx%d, every %ds) into %d sheets", s.Tiles, s.TileWidth, s.TileHeight, s.Interval, len(s.Sheets)))
+	if err := executil.RunCommandContext(ctx, s.Command, result.Profile.CommandLimits()); err != nil {
+		return nil, fmt.Errorf("failed to render sprite sheets: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.Dir, s.Storyboard), []byte(s.vtt(media.Duration)), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write sprite storyboard: %w", err)
+	}
+	logger.LogStage("thumbnail", fmt.Sprintf("✅ Sprite storyboard written: %s", filepath.Join(s.Dir, s.Storyboard)))
+	return s, nil
+}
+
+// vtt renders the storyboard: one cue per tile, clamped to duration and
+// shifted past the pre-roll.
+func (s *SpriteSheets) vtt(duration float64) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	perSheet := s.Columns * s.Rows
+	for k := 0; k < s.Tiles; k++ {
+		start := float64(k * s.Interval)
+		end := min(float64((k+1)*s.Interval), duration)
+		pos := k % perSheet
+		x, y := (pos%s.Columns)*s.TileWidth, (pos/s.Columns)*s.TileHeight
+		fmt.Fprintf(&b, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n", vttTime(s.Offset+start), vttTime(s.Offset+end), s.Sheets[k/perSheet], x, y, s.TileWidth, s.TileHeight)
+	}
+	return b.String()
+}
+
+// vttTime formats seconds as a WebVTT timestamp (HH:MM:SS.mmm).
+func vttTime(seconds float64) string {
+	ms := int64(math.Round(seconds * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// evenUp rounds n up to an even number, as yuv420p JPEG tiles need.
+func evenUp(n int) int {
+	return n + n%2
+}
@@ -1,100 +1,197 @@
 package thumbnailer
 
 import (
+	"context"
 	"fmt"
-	"log"
-	"os/exec"
+	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/executil"
 	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
 )
 
-// GenerateThumbnails creates thumbnails for a given media slug using the highest
-// resolution transcoded variant. It determines segment length based on profile
-// config or keyframe interval, then generates thumbnails at regular intervals.
+// Options customizes a GenerateThumbnailsContext run.
+type Options struct {
+	// Progress, when set, is called after each extraction with the number of
+	// thumbnails attempted so far and the total planned.
+	Progress func(done, total int)
+}
+
+// Result is the inventory of a thumbnail run.
+type Result struct {
+	Dir        string   // Thumbnails directory (e.g. "media/output/movie/thumbnails")
+	Source     string   // Variant or input the frames were extracted from
+	Thumbnails []string // Generated filenames in timestamp order (e.g. "thumb_004.jpg")
+	Failed     int      // Extractions that failed; each is logged
+}
+
+// Paths returns the generated thumbnails relative to outputDir, the slug
+// directory the thumbnails directory lives in (e.g. "thumbnails/thumb_004.jpg").
+func (r *Result) Paths(outputDir string) []string {
+	if r == nil {
+		return nil
+	}
+	paths := make([]string, len(r.Thumbnails))
+	for i, name := range r.Thumbnails {
+		paths[i] = filepath.ToSlash(filepath.Join(relDir(outputDir, r.Dir), name))
+	}
+	return paths
+}
+
+// relDir returns dir relative to base, or dir unchanged when it isn't below base.
+func relDir(base, dir string) string {
+	if rel, err := filepath.Rel(base, dir); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return dir
+}
+
+// GenerateThumbnails creates thumbnails for a given media slug from the file
+// SelectSource picks: by default the variant matching the source height, else
+// the tallest variant, else the original input. It determines segment length
+// based on profile config or keyframe interval, then generates thumbnails at
+// regular intervals.
 //
-// This function assumes that transcoding has already completed and that the
-// output directory contains the expected .mp4 files.
+// This function assumes that transcoding has already completed, so variant
+// sources exist in the output directory.
 //
 // Returns:
 //   - A slice of thumbnail filenames (e.g. "thumb_000.jpg", "thumb_004.jpg")
 //   - An error if thumbnail generation fails entirely
-func GenerateThumbnails(media analyzer.MediaInfo, result transcoder.TranscodeResult, slug string) ([]string, error) {
-	// Determine effective segment length
-	effectiveSegmentLength := result.Profile.SegmentLength
-	if effectiveSegmentLength == 0 {
-		if media.KeyframeInterval >= 3.0 {
-			effectiveSegmentLength = int(media.KeyframeInterval)
+func GenerateThumbnails(media analyzer.MediaInfo, result transcoder.TranscodeResult, slug string, logger stagelog.Logger) ([]string, error) {
+	res, err := GenerateThumbnailsContext(context.Background(), media, result, slug, logger, Options{})
+	if res == nil {
+		return nil, err
+	}
+	return res.Thumbnails, err
+}
+
+// GenerateThumbnailsContext is GenerateThumbnails with cancellation and
+// progress. Extractions run under the profile's command limits; progress is
+// reported through opts.Progress and logger.LogProgress("thumbnail", ...).
+// When ctx is done the thumbnails generated so far are returned with ctx's error.
+func GenerateThumbnailsContext(ctx context.Context, media analyzer.MediaInfo, result transcoder.TranscodeResult, slug string, logger stagelog.Logger, opts Options) (*Result, error) {
+	logger = stagelog.OrStd(logger)
+
+	plan, err := PlanThumbnails(media, result, slug, logger)
+	if err != nil || len(plan) == 0 {
+		return nil, err
+	}
+
+	// Ensure the source actually exists before spawning ffmpeg per timestamp
+	if _, err := os.Stat(plan[0].VariantPath); err != nil {
+		return nil, fmt.Errorf("failed to locate thumbnail source: file not found: %s", plan[0].VariantPath)
+	}
+
+	// Prepare thumbnails directory
+	thumbDir, err := EnsureThumbnailDir(result.OutputDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare thumbnail directory: %w", err)
+	}
+
+	// Generate thumbnails using ffmpeg
+	res := &Result{Dir: thumbDir, Source: plan[0].VariantPath}
+	logger.LogStage("thumbnail", fmt.Sprintf("🖼️ Extracting %d thumbnails from %s", len(plan), filepath.Base(res.Source)))
+	lastPercent := 0
+	for i, thumb := range plan {
+		if err := ctx.Err(); err != nil {
+			return res, err
+		}
+		if err := executil.RunCommandContext(ctx, thumb.Command, result.Profile.CommandLimits()); err != nil {
+			if ctx.Err() != nil {
+				return res, ctx.Err()
+			}
+			logger.LogError("thumbnail", fmt.Errorf("failed to generate thumbnail at %.2fs for slug %s: %w", thumb.Timestamp, slug, err))
+			res.Failed++
 		} else {
-			effectiveSegmentLength = 4 // fallback default
-			log.Printf("⚠️ Keyframe interval too short (%.2fs), using fallback segment length: %ds", media.KeyframeInterval, effectiveSegmentLength)
+			res.Thumbnails = append(res.Thumbnails, thumb.Filename)
 		}
+
+		if opts.Progress != nil {
+			opts.Progress(i+1, len(plan))
+		}
+		if pct := (i + 1) * 100 / len(plan); pct >= lastPercent+5 || i == len(plan)-1 {
+			lastPercent = pct
+			logger.LogProgress("thumbnail", float64(pct))
+		}
+	}
+
+	logger.LogStage("thumbnail", fmt.Sprintf("✅ %d of %d thumbnails generated in %s", len(res.Thumbnails), len(plan), thumbDir))
+	return res, nil
+}
+
+// PlannedThumbnail describes a single thumbnail extraction.
+type PlannedThumbnail struct {
+	Timestamp   float64  // Position in seconds
+	Filename    string   // Thumbnail filename (e.g. "thumb_004.jpg")
+	OutputPath  string   // Full output path inside the thumbnails directory
+	VariantPath string   // Transcoded variant or input the frame is extracted from (see SelectSource)
+	Command     []string // ffmpeg command that will be executed
+}
+
+// PlanThumbnails computes every thumbnail GenerateThumbnails would extract
+// without touching the filesystem. Returns an empty plan when no timestamps
+// can be generated, or an error if no thumbnail source is available.
+func PlanThumbnails(media analyzer.MediaInfo, result transcoder.TranscodeResult, slug string, logger stagelog.Logger) ([]PlannedThumbnail, error) {
+	logger = stagelog.OrStd(logger)
+
+	// Determine effective segment length
+	effectiveSegmentLength := ThumbnailInterval(media, result.Profile.SegmentLength)
+	if result.Profile.SegmentLength == 0 && media.KeyframeInterval < 3.0 {
+		logger.LogStage("thumbnail", fmt.Sprintf("⚠️ Keyframe interval too short (%.2fs), using fallback segment length: %ds", media.KeyframeInterval, effectiveSegmentLength))
 	}
 
 	// Generate timestamps based on duration and segment length
 	timestamps := GenerateTimestamps(media.Duration, effectiveSegmentLength)
 	if len(timestamps) == 0 {
-		log.Printf("🚫 No valid timestamps generated for slug: %s", slug)
+		logger.LogStage("thumbnail", fmt.Sprintf("🚫 No valid timestamps generated for slug: %s (duration=%.2f, interval=%d)", slug, media.Duration, effectiveSegmentLength))
 		return nil, nil
 	}
 
-	// Locate highest resolution variant
-	var bitrateStr string
-	for _, v := range result.Variants {
-		if v.Height == media.Height {
-			bitrateStr = v.Bitrate
-			break
-		}
-	}
-	if bitrateStr == "" {
-		return nil, fmt.Errorf("no variant found matchin source height: %d", media.Height)
-	}
-
-	// Parse bitrate string like "5000k" into kbps
-	bitrateKbps, err := parseBitrateKbps(bitrateStr)
+	src, err := SelectSource(media, result, logger)
 	if err != nil {
-		return nil, fmt.Errorf("invalid bitrte format: %s", bitrateStr)
+		return nil, err
 	}
-
-	// Resolve full path to variant file
-	variantPath, err := GetVariantPath(result.OutputDir, slug, media.Height, bitrateKbps)
-	if err != nil {
-		return nil, fmt.Errorf("failed to locate variant for thumbnail generation: %w", err)
+	if src.Input {
+		logger.LogStage("thumbnail", fmt.Sprintf("🎬 Extracting thumbnails from the input: %s", src.Path))
 	}
+	filter := src.filter(media)
 
-	// Prepare thumbnails directory
-	thumbDir, err := EnsureThumbnailDir(result.OutputDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to prepare thumbnail directory: %w", err)
-	}
+	thumbDir := filepath.Join(result.OutputDir, "thumbnails")
 
-	// Generate thumbnails using ffmpeg
-	var generated []string
-	for _, ts := range timestamps {
-		filename := FormatTimestampFilename(ts)
+	plan := make([]PlannedThumbnail, 0, len(timestamps))
+	for _, slot := range timestamps {
+		// Filenames stay on the slot grid, in output time past any pre-roll;
+		// the capture point may move off black frames
+		filename := FormatTimestampFilename(slot + result.PreRoll)
 		outputPath := filepath.Join(thumbDir, filename)
-
-		cmd := exec.Command(
-			"ffmpeg",
-			"-ss", fmt.Sprintf("%.2f", ts),
-			"-i", variantPath,
-			"-frames:v", "1",
-			"-q:v", "2",
-			"-y", outputPath,
-		)
-
-		if err := cmd.Run(); err != nil {
-			log.Printf("❌ Failed to generate thumbnail at %.2fs for slug %s: %v", ts, slug, err)
-		} else {
-			log.Printf("✅ Thumbnail generated: %s", outputPath)
-			generated = append(generated, filename)
+		ts := AvoidBlackFrames(media, slot, float64(effectiveSegmentLength))
+		if !src.Input {
+			ts += result.PreRoll
 		}
+		plan = append(plan, PlannedThumbnail{
+			Timestamp:   ts,
+			Filename:    filename,
+			OutputPath:  outputPath,
+			VariantPath: src.Path,
+			Command:     thumbnailCommand(src.Path, ts, filter, outputPath),
+		})
 	}
+	return plan, nil
+}
 
-	return generated, nil
+// thumbnailCommand returns the ffmpeg command extracting the frame at ts from
+// path, applying filter when set.
+func thumbnailCommand(path string, ts float64, filter string, outputPath string) []string {
+	cmd := []string{"ffmpeg", "-ss", fmt.Sprintf("%.2f", ts), "-i", path, "-frames:v", "1"}
+	if filter != "" {
+		cmd = append(cmd, "-vf", filter)
+	}
+	return append(cmd, "-q:v", "2", "-y", outputPath)
 }
 
 // parseBitrateKbps converts a bitrate string like "5000k" to an int (5000)
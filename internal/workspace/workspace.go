@@ -0,0 +1,215 @@
+// Package workspace manages a scratch directory per pipeline run. Intermediates
+// such as key material, downloaded segments, and encoder logs go there instead
+// of the output tree; the directory counts against a size quota while the run
+// is active and is removed when the run ends, whether it succeeded, failed, or
+// was cancelled.
+package workspace
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/errclass"
+)
+
+// ErrQuotaExceeded is the cancellation cause when a workspace outgrows its quota.
+var ErrQuotaExceeded = errclass.New(errclass.DiskFull, "workspace quota exceeded")
+
+// enforceInterval is how often Enforce measures the workspace.
+const enforceInterval = 2 * time.Second
+
+// Options configures the workspaces of a run.
+type Options struct {
+	Root          string // Parent directory of job workspaces (default os.TempDir())
+	Quota         int64  // Maximum bytes a workspace may hold; 0 for no limit
+	KeepOnFailure bool   // Leave the workspace of a failed or cancelled run for inspection
+}
+
+// Workspace is one run's scratch directory.
+type Workspace struct {
+	Dir  string // Absolute path of the directory
+	name string
+	opts Options
+}
+
+// New creates a workspace named after name (typically the job slug) under o.Root.
+func New(name string, o Options) (*Workspace, error) {
+	if o.Root == "" {
+		o.Root = os.TempDir()
+	}
+	if err := os.MkdirAll(o.Root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create workspace root %s: %w", o.Root, err)
+	}
+	dir, err := os.MkdirTemp(o.Root, "dotgo-"+name+"-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	return &Workspace{Dir: dir, name: name, opts: o}, nil
+}
+
+// memoryRoot is the RAM-backed filesystem MemoryRoot looks for.
+const memoryRoot = "/dev/shm"
+
+// MemoryRoot returns a directory on a RAM-backed filesystem (tmpfs) for
+// workspaces whose files should never reach disk, or "" when the system has
+// none.
+func MemoryRoot() string {
+	if info, err := os.Stat(memoryRoot); err == nil && info.IsDir() {
+		return memoryRoot
+	}
+	return ""
+}
+
+// Path joins elem onto the workspace directory.
+func (w *Workspace) Path(elem ...string) string {
+	return filepath.Join(append([]string{w.Dir}, elem...)...)
+}
+
+// CheckpointDir returns a directory beside the workspace for state a later
+// run of the same job resumes from, such as checkpointed encodes. It is
+// named after the workspace name and a hash of job, which should identify
+// the job across runs but not collide with other jobs of the same name
+// (e.g. its tenant, input, and output directory). It outlives the run, so
+// whoever writes to it removes it when done.
+func (w *Workspace) CheckpointDir(job string) string {
+	sum := sha256.Sum256([]byte(job))
+	return filepath.Join(filepath.Dir(w.Dir), fmt.Sprintf("dotgo-%s-%s-checkpoints", w.name, hex.EncodeToString(sum[:6])))
+}
+
+// TempDir creates a new directory in the workspace, like os.MkdirTemp. On a
+// nil workspace it falls back to the system temporary directory, so callers
+// outside a pipeline run need no special case.
+func (w *Workspace) TempDir(pattern string) (string, error) {
+	if w == nil {
+		return os.MkdirTemp("", pattern)
+	}
+	if err := w.Check(); err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(w.Dir, pattern)
+}
+
+// Usage returns the bytes currently stored in the workspace.
+func (w *Workspace) Usage() (int64, error) {
+	var total int64
+	err := filepath.WalkDir(w.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Files removed mid-walk don't count
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total, err
+}
+
+// Check returns an error wrapping ErrQuotaExceeded if the workspace is over quota.
+func (w *Workspace) Check() error {
+	if w == nil || w.opts.Quota <= 0 {
+		return nil
+	}
+	used, err := w.Usage()
+	if err != nil {
+		return fmt.Errorf("failed to measure workspace: %w", err)
+	}
+	if used > w.opts.Quota {
+		return fmt.Errorf("%w: %s holds %s, limit %s", ErrQuotaExceeded, w.Dir, FormatSize(used), FormatSize(w.opts.Quota))
+	}
+	return nil
+}
+
+// Enforce measures the workspace periodically until ctx is done, cancelling
+// with the quota error as cause once it is over quota. Does nothing without
+// a quota.
+func (w *Workspace) Enforce(ctx context.Context, cancel context.CancelCauseFunc) {
+	if w == nil || w.opts.Quota <= 0 {
+		return
+	}
+	ticker := time.NewTicker(enforceInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.Check(); errors.Is(err, ErrQuotaExceeded) {
+				cancel(err)
+				return
+			}
+		}
+	}
+}
+
+// Close removes the workspace, unless failed is set and the workspace keeps
+// failed runs. It reports whether the directory was kept.
+func (w *Workspace) Close(failed bool) (kept bool, err error) {
+	if w == nil {
+		return false, nil
+	}
+	if failed && w.opts.KeepOnFailure {
+		return true, nil
+	}
+	return false, os.RemoveAll(w.Dir)
+}
+
+// ParseSize parses a byte count with an optional K, M, G, or T suffix
+// (powers of 1024, case-insensitive, trailing "B" or "iB" allowed), e.g. "20G".
+func ParseSize(s string) (int64, error) {
+	t := strings.ToUpper(strings.TrimSpace(s))
+	t = strings.TrimSuffix(strings.TrimSuffix(t, "IB"), "B")
+	mult := int64(1)
+	if n := len(t); n > 0 {
+		switch t[n-1] {
+		case 'K':
+			mult = 1 << 10
+		case 'M':
+			mult = 1 << 20
+		case 'G':
+			mult = 1 << 30
+		case 'T':
+			mult = 1 << 40
+		}
+		if mult > 1 {
+			t = t[:n-1]
+		}
+	}
+	n, err := strconv.ParseFloat(strings.TrimSpace(t), 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (want bytes or e.g. 512M, 20G)", s)
+	}
+	return int64(n * float64(mult)), nil
+}
+
+// FormatSize renders n bytes with a binary unit, e.g. "1.5 GiB".
+func FormatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
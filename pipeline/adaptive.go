@@ -0,0 +1,103 @@
+package pipeline
+
+import (
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/scaler"
+	"github.com/dotsoulja/dotgo-transcode/internal/scaler/redisstore"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/helpers"
+)
+
+// Ladder is a re-export of scaler.Ladder, a title's actual variants for
+// start-rung selection and adaptive switching.
+type Ladder = scaler.Ladder
+
+// Rung is a re-export of scaler.Rung, one variant of a Ladder.
+type Rung = scaler.Rung
+
+// ParseMasterPlaylist reads the ladder an HLS master playlist lists.
+func ParseMasterPlaylist(data []byte) (Ladder, error) {
+	return scaler.ParseMasterPlaylist(data)
+}
+
+// StartupRecommendation is a re-export of scaler.StartupRecommendation, the
+// rung a player should start on and its ranked alternatives.
+type StartupRecommendation = scaler.StartupRecommendation
+
+// RecommendStartup recommends the variant of an HLS master playlist a
+// player with client should start on, for backends that prime players with
+// their initial rung.
+func RecommendStartup(master []byte, client *scaler.ClientContext) (*StartupRecommendation, error) {
+	return scaler.RecommendStartup(master, client)
+}
+
+// BandwidthEstimator is a re-export of scaler.BandwidthEstimator, rolling
+// per-session bandwidth estimates from player reports.
+type BandwidthEstimator = scaler.BandwidthEstimator
+
+// NewBandwidthEstimator returns a BandwidthEstimator with the default
+// half-life and maximum session age.
+func NewBandwidthEstimator() *BandwidthEstimator {
+	return scaler.NewBandwidthEstimator()
+}
+
+// SessionStore is a re-export of scaler.SessionStore, playback session
+// state kept between adaptive requests.
+type SessionStore = scaler.SessionStore
+
+// SessionState is a re-export of scaler.SessionState.
+type SessionState = scaler.SessionState
+
+// SessionUpdater is a re-export of scaler.SessionUpdater, a SessionStore
+// that changes sessions atomically.
+type SessionUpdater = scaler.SessionUpdater
+
+// SessionController is a re-export of scaler.SessionController, adaptive
+// decisions with per-session history.
+type SessionController = scaler.SessionController
+
+// ErrSessionNotFound is a re-export of scaler.ErrSessionNotFound.
+var ErrSessionNotFound = scaler.ErrSessionNotFound
+
+// NewMemorySessionStore returns an in-memory SessionStore expiring sessions
+// idle for ttl (30 minutes if zero).
+func NewMemorySessionStore(ttl time.Duration) SessionStore {
+	return scaler.NewMemorySessionStore(ttl)
+}
+
+// RedisClient is a re-export of redisstore.Client, the Redis commands a
+// Redis session store needs; clients that also implement RedisTxClient get
+// atomic session updates.
+type RedisClient = redisstore.Client
+
+// RedisTxClient is a re-export of redisstore.TxClient.
+type RedisTxClient = redisstore.TxClient
+
+// NewRedisSessionStore returns a SessionStore keeping sessions in Redis
+// through client, so every server sees a session's history.
+func NewRedisSessionStore(client RedisClient) SessionStore {
+	return redisstore.New(client)
+}
+
+// NewSessionController returns a SessionController over store choosing
+// from ladder (the standard presets when empty).
+func NewSessionController(store SessionStore, ladder Ladder) *SessionController {
+	return scaler.NewSessionController(store, ladder)
+}
+
+// Ladder returns the variants the run produced as a scaler ladder. Rung
+// bandwidths are the variants' target video bitrates; URIs are left empty,
+// as the report doesn't carry playlist paths (use ParseMasterPlaylist on the
+// written manifest for those).
+func (r *Report) Ladder() Ladder {
+	rungs := make([]Rung, 0, len(r.Variants))
+	for _, v := range r.Variants {
+		rungs = append(rungs, Rung{
+			Width:     v.Width,
+			Height:    v.Height,
+			Bandwidth: helpers.ParseBitrateKbps(v.Bitrate),
+			Codecs:    v.Codecs,
+		})
+	}
+	return scaler.NewLadder(rungs)
+}
@@ -0,0 +1,99 @@
+package pipeline
+
+import (
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/manifester"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/audio"
+)
+
+// AudioRendition is a re-export of transcoder.AudioRendition, an alternate
+// audio track (e.g. E-AC-3 5.1) listed in TranscodeProfile.AudioRenditions.
+type AudioRendition = transcoder.AudioRendition
+
+// Audio copy modes for TranscodeProfile.AudioCopy.
+const (
+	AudioCopyAuto = transcoder.AudioCopyAuto
+	AudioCopyOff  = transcoder.AudioCopyOff
+)
+
+// Audio layouts for TranscodeProfile.AudioLayout.
+const (
+	AudioLayoutMuxed   = transcoder.AudioLayoutMuxed
+	AudioLayoutDemuxed = transcoder.AudioLayoutDemuxed
+)
+
+// Audio rendition roles for AudioRendition.Role.
+const (
+	AudioRoleMain        = transcoder.AudioRoleMain
+	AudioRoleDescription = transcoder.AudioRoleDescription
+	AudioRoleCommentary  = transcoder.AudioRoleCommentary
+)
+
+// AudioTrack is a re-export of audio.Rendition, a rendition written by the
+// audio stage.
+type AudioTrack = audio.Rendition
+
+// dashRole returns the DASH Role a track's role overrides main/alternate
+// with, "" for main-role tracks.
+func dashRole(role string) string {
+	if role == transcoder.AudioRoleMain {
+		return ""
+	}
+	return role
+}
+
+// audioRenditions lists tracks for the master manifest, with paths relative
+// to the slug directory. In the muxed layout, a codec group holding only audio
+// description or commentary would make one of those the default for the
+// variants listed in it, so the variants' own audio is added to the group as
+// its default rendition, without a URI.
+func audioRenditions(slugDir string, tracks []AudioTrack, profile *TranscodeProfile, media *MediaInfo) []manifester.AudioRendition {
+	var out []manifester.AudioRendition
+	hasMain := make(map[string]bool)
+	for _, t := range tracks {
+		hasMain[t.Codec] = hasMain[t.Codec] || t.Role == transcoder.AudioRoleMain
+	}
+	for _, t := range tracks {
+		if codec := t.Codec; !hasMain[codec] && !profile.Demuxed() && codec == muxedAudioCodec(profile, media) {
+			hasMain[codec] = true
+			muxed := manifester.AudioRendition{Name: "Main", Default: true, Codec: codec, Codecs: t.Codecs}
+			if primary := media.PrimaryAudio(); primary != nil {
+				muxed.Language = primary.Language
+			}
+			out = append(out, muxed)
+		}
+		out = append(out, manifester.AudioRendition{
+			Name:     t.Name,
+			Language: t.Language,
+			Default:  t.Default,
+			Codec:    t.Codec,
+			Codecs:   t.Codecs,
+			Channels: t.Channels,
+			Bitrate:  t.Bitrate * 1000,
+			Playlist: relativeTo(slugDir, t.Manifest),
+
+			Role:            dashRole(t.Role),
+			Characteristics: t.Characteristics,
+		})
+	}
+	return out
+}
+
+// muxedAudioCodec returns the codec family of the audio muxed into the
+// variants: the profile's audio codec, or the source's when it is copied.
+// Returns "" when unknown.
+func muxedAudioCodec(profile *TranscodeProfile, media *MediaInfo) string {
+	codec := strings.ToLower(profile.AudioCodec)
+	switch codec {
+	case "":
+		return "aac"
+	case "copy":
+		if primary := media.PrimaryAudio(); primary != nil {
+			return primary.Codec
+		}
+		return ""
+	}
+	return codec
+}
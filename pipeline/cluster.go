@@ -0,0 +1,38 @@
+package pipeline
+
+import (
+	"github.com/dotsoulja/dotgo-transcode/internal/cluster"
+)
+
+// Coordinator is a re-export of cluster.Coordinator, which splits a job into
+// per-variant tasks on a shared queue and collects the workers' results.
+type Coordinator = cluster.Coordinator
+
+// Worker is a re-export of cluster.Worker, which claims and encodes tasks.
+type Worker = cluster.Worker
+
+// TaskQueue is a re-export of cluster.Queue, the transport between the
+// coordinator and workers.
+type TaskQueue = cluster.Queue
+
+// NewDirQueue returns a task queue in a directory shared by every node
+// (local disk for one machine, an NFS mount for several).
+func NewDirQueue(dir string) (TaskQueue, error) {
+	return cluster.NewDirQueue(dir)
+}
+
+// NewMemoryQueue returns an in-process task queue, for running the
+// coordinator and a pool of workers in one binary.
+func NewMemoryQueue() TaskQueue {
+	return cluster.NewMemoryQueue()
+}
+
+// WithCluster hands the transcode stage to c: every rung becomes a task that
+// any worker on the queue may encode. Segmentation, manifests, and the other
+// stages still run in this process once all rungs are back. Input and output
+// paths must be identical on every node.
+func WithCluster(c *Coordinator) Option {
+	return func(o *runOptions) {
+		o.cluster = c
+	}
+}
@@ -0,0 +1,51 @@
+package pipeline
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/manifester"
+	"github.com/dotsoulja/dotgo-transcode/internal/segmenter"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+)
+
+// CuePoint is a re-export of transcoder.CuePoint, an ad break written as an
+// ad period in the DASH master.
+type CuePoint = transcoder.CuePoint
+
+// Ad break positions for CuePoint.Position.
+const (
+	CuePreRoll  = transcoder.CuePreRoll
+	CueMidRoll  = transcoder.CueMidRoll
+	CuePostRoll = transcoder.CuePostRoll
+)
+
+// adBreaks lists the profile's cue points as DASH ad periods, moving
+// mid-rolls to the nearest segment boundary so content periods split
+// between segments. HLS runs get none.
+func adBreaks(job *Job) []manifester.AdBreak {
+	if len(job.Profile.CuePoints) == 0 || job.Media == nil {
+		return nil
+	}
+	if !strings.EqualFold(job.Format, "dash") {
+		job.Logger.LogStage("manifest", "⚠️ Cue points only apply to DASH masters; ignoring them for HLS")
+		return nil
+	}
+	segLen := float64(segmenter.SegmentLength(job.Profile, job.Media))
+	var breaks []manifester.AdBreak
+	for _, c := range job.Profile.AdBreaks(job.Media.Duration) {
+		offset := c.Time
+		if c.Position == transcoder.CueMidRoll && segLen > 0 {
+			offset = math.Round(c.Time/segLen) * segLen
+			if offset != c.Time {
+				job.Logger.LogStage("manifest", fmt.Sprintf("📍 Cue point %s moved from %.2fs to the segment boundary at %.2fs", c.ID, c.Time, offset))
+			}
+		}
+		breaks = append(breaks, manifester.AdBreak{ID: c.ID, Offset: offset, Duration: c.Duration, Slate: c.Slate})
+	}
+	if len(breaks) > 0 {
+		job.Logger.LogStage("manifest", fmt.Sprintf("📺 Writing %d ad break(s) as DASH periods", len(breaks)))
+	}
+	return breaks
+}
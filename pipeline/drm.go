@@ -0,0 +1,106 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/drm"
+	"github.com/dotsoulja/dotgo-transcode/internal/segmenter"
+)
+
+// KeyProvider is a re-export of drm.KeyProvider, which supplies content keys
+// to the encrypt stage.
+type KeyProvider = drm.KeyProvider
+
+// KeyProviderFunc is a re-export of drm.KeyProviderFunc, the hook for SPEKE,
+// Widevine proxy, or KMS integrations.
+type KeyProviderFunc = drm.KeyProviderFunc
+
+// Key is a re-export of drm.Key (key, key ID, optional IV, license URL).
+type Key = drm.Key
+
+// ErrKeyNotFound is returned by providers with no key for a content ID.
+var ErrKeyNotFound = drm.ErrKeyNotFound
+
+// NewStaticKeyProvider returns a provider serving one hex-encoded key for all content.
+func NewStaticKeyProvider(keyHex, keyIDHex, licenseURL string) (KeyProvider, error) {
+	return drm.NewStaticKeyProvider(keyHex, keyIDHex, licenseURL)
+}
+
+// NewFileKeyProvider loads a JSON key table mapping content IDs (output slugs)
+// to keys, with an optional "*" fallback entry.
+func NewFileKeyProvider(path string) (KeyProvider, error) {
+	return drm.NewFileKeyProvider(path)
+}
+
+// WithKeyProvider enables segment encryption for the run. The encrypt stage
+// asks p for the key of the job's slug; without a provider segments are
+// written in the clear.
+func WithKeyProvider(p KeyProvider) Option {
+	return func(o *runOptions) {
+		o.keys = p
+	}
+}
+
+// EncryptStage fetches the content key from the run's KeyProvider and stages
+// it for the segment stage, which encrypts HLS segments with AES-128, or with
+// Shaka Packager or Bento4 as the profile's packager, HLS or DASH. The key
+// is written to a private directory in the run's workspace that is removed
+// when the run ends; only the key ID and license URL reach the report and metadata.
+// The encoded variant files the segments are cut from are not encrypted, so
+// they are removed from <slug>/ before the metadata and checksum stages, or
+// when the run ends, unless the profile sets keep_clear. A later upgrade run
+// then has no rungs to reuse.
+// Does nothing when no KeyProvider is configured.
+func EncryptStage() Stage {
+	return StageFunc(StageEncrypt, func(ctx context.Context, job *Job) error {
+		if job.opts.keys == nil {
+			return nil
+		}
+		packager, _ := segmenter.ResolvePackager(job.Profile, job.Format, true)
+		if err := packager.Supports(job.Profile, job.Format, true); err != nil {
+			return wrap("encrypt", fmt.Errorf("segment encryption is not supported: %w; DASH needs Shaka Packager or Bento4 (packager)", err))
+		}
+		key, err := job.opts.keys.GetKey(ctx, job.Slug)
+		if err != nil {
+			return wrap("encrypt", err)
+		}
+		info, err := drm.WriteKeyInfo(key, job.Workspace.Dir)
+		if err != nil {
+			return wrap("encrypt", err)
+		}
+		job.onCleanup(func() { info.Remove() })
+		job.onCleanup(func() { removeClearVariants(job) })
+
+		job.Encryption = key
+		job.keyInfo = info
+		job.Report.KeyID = key.KeyIDHex()
+		job.Report.LicenseURL = key.LicenseURL
+		job.Logger.LogStage("encrypt", fmt.Sprintf("🔑 Using key %s for %s", job.Report.KeyID, job.Slug))
+		return nil
+	})
+}
+
+// removeClearVariants deletes the encoded variant files of an encrypted run,
+// which would otherwise sit beside the encrypted segments as clear copies,
+// unless the profile keeps them. Safe to call more than once.
+func removeClearVariants(job *Job) {
+	if job.Encryption == nil || job.Profile.KeepClear || job.Result == nil {
+		return
+	}
+	removed := 0
+	for _, v := range job.Result.Variants {
+		err := os.Remove(filepath.Join(job.Result.OutputDir, v.OutputFilename))
+		switch {
+		case err == nil:
+			removed++
+		case !os.IsNotExist(err):
+			job.Warn("encrypt", err)
+		}
+	}
+	if removed > 0 {
+		job.Logger.LogStage("encrypt", fmt.Sprintf("🧹 Removed %d unencrypted variant file(s) (set keep_clear to keep them)", removed))
+	}
+}
@@ -0,0 +1,55 @@
+package pipeline
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/segmenter"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+)
+
+// ErrDurationMismatch is a re-export of transcoder.ErrDurationMismatch,
+// wrapped by report warnings for outputs whose duration drifted from the
+// source's.
+var ErrDurationMismatch = transcoder.ErrDurationMismatch
+
+// TimestampSettings is a re-export of transcoder.TimestampSettings.
+type TimestampSettings = transcoder.TimestampSettings
+
+// SegmentDurationStats is a re-export of segmenter.DurationStats, the
+// measured segment durations of one HLS variant (SegmentResult.Durations).
+type SegmentDurationStats = segmenter.DurationStats
+
+// checkVariantDurations warns about encoded variants whose measured duration
+// differs from the duration the source declares, plus any bumpers.
+func checkVariantDurations(job *Job) {
+	if job.Media == nil {
+		return
+	}
+	for _, v := range job.Result.Variants {
+		if err := job.Profile.CheckDuration(v.OutputFilename, job.Result.OutputDuration(), v.Stats.Duration); err != nil {
+			job.Warn("transcode", err)
+		}
+	}
+}
+
+// checkPlaylistDurations warns about HLS variant playlists whose segment
+// durations don't add up to the source duration, plus any bumpers.
+func checkPlaylistDurations(job *Job) {
+	if job.Media == nil || !strings.EqualFold(job.Format, "hls") {
+		return
+	}
+	expected := job.Media.Duration
+	if job.Result != nil {
+		expected = job.Result.OutputDuration()
+	}
+	for _, manifest := range job.Segments.Manifests {
+		total, err := segmenter.PlaylistDuration(manifest)
+		if err != nil {
+			continue
+		}
+		if err := job.Profile.CheckDuration(filepath.Base(manifest), expected, total); err != nil {
+			job.Warn("segment", err)
+		}
+	}
+}
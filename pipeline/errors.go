@@ -0,0 +1,30 @@
+package pipeline
+
+import "github.com/dotsoulja/dotgo-transcode/internal/errclass"
+
+// ErrorClass is a re-export of errclass.Class, the kind of failure a run
+// error represents.
+type ErrorClass = errclass.Class
+
+// Error classes re-exported for callers.
+const (
+	ErrorUnknown        = errclass.Unknown        // Not recognized; retrying is unlikely to help
+	ErrorInputCorrupt   = errclass.InputCorrupt   // The source is truncated, corrupt, or not media
+	ErrorInputMissing   = errclass.InputMissing   // The source doesn't exist
+	ErrorInvalidConfig  = errclass.InvalidConfig  // The profile or request is invalid
+	ErrorEncoderMissing = errclass.EncoderMissing // ffmpeg or a codec, filter, or option it needs isn't available
+	ErrorDiskFull       = errclass.DiskFull       // Out of disk space or over the workspace quota
+	ErrorTransientIO    = errclass.TransientIO    // Timeout, stall, or network/storage hiccup; may succeed if re-run
+	ErrorCancelled      = errclass.Cancelled      // Cancelled by the caller or its deadline
+)
+
+// ClassifyError returns the class of an error returned by Run (or any stage
+// error), so callers can decide on retries without matching messages.
+func ClassifyError(err error) ErrorClass {
+	return errclass.Classify(err)
+}
+
+// IsRetryable reports whether re-running after err may succeed.
+func IsRetryable(err error) bool {
+	return errclass.IsRetryable(err)
+}
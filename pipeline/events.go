@@ -0,0 +1,207 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event types written to an event stream.
+const (
+	EventStageStarted    = "stage_started"
+	EventStageFinished   = "stage_finished"
+	EventVariantProgress = "variant_progress"
+	EventVariantDone     = "variant_done"
+	EventJobProgress     = "job_progress"
+	EventError           = "error"
+	EventJobFinished     = "job_finished"
+)
+
+// Event is one line of a JSON-lines event stream (see WithEventStream).
+// Fields that don't apply to the event type are omitted.
+type Event struct {
+	Time       time.Time `json:"time"`
+	Type       string    `json:"type"`                  // One of the Event* constants
+	Job        string    `json:"job,omitempty"`         // Output slug of the job
+	Stage      string    `json:"stage,omitempty"`       // Stage the event belongs to
+	Variant    string    `json:"variant,omitempty"`     // Variant key (e.g. "720p_3000k")
+	Percent    float64   `json:"percent,omitempty"`     // Variant or job progress, 0-100
+	Speed      float64   `json:"speed,omitempty"`       // Encode speed as a multiple of realtime
+	ETASeconds float64   `json:"eta_seconds,omitempty"` // Estimated seconds remaining
+	ElapsedMs  int64     `json:"elapsed_ms,omitempty"`  // Stage wall time (stage_finished)
+	Failed     bool      `json:"failed,omitempty"`      // Variant, stage, or job failed
+	Error      string    `json:"error,omitempty"`       // Error message
+	Fatal      bool      `json:"fatal,omitempty"`       // The error ended the run; otherwise it is a warning
+}
+
+// EventWriter writes events as JSON lines. It is safe for concurrent use;
+// write errors are dropped so a broken consumer never fails the run. A nil
+// EventWriter discards events.
+type EventWriter struct {
+	mu       sync.Mutex
+	enc      *json.Encoder
+	job      string
+	reported int // Report errors already written
+}
+
+// NewEventWriter returns an EventWriter writing to w for the job with the
+// given slug ("" to take it from the first stage event).
+func NewEventWriter(w io.Writer, job string) *EventWriter {
+	return &EventWriter{enc: json.NewEncoder(w), job: job}
+}
+
+// Emit writes ev, filling in its time and job.
+func (e *EventWriter) Emit(ev Event) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+	if ev.Job == "" {
+		ev.Job = e.job
+	}
+	_ = e.enc.Encode(ev)
+}
+
+// StageStarted writes a stage_started event.
+func (e *EventWriter) StageStarted(stage string) {
+	e.Emit(Event{Type: EventStageStarted, Stage: stage})
+}
+
+// StageFinished writes a stage_finished event, preceded by a fatal error
+// event when err is set.
+func (e *EventWriter) StageFinished(stage string, elapsed time.Duration, err error) {
+	ev := Event{Type: EventStageFinished, Stage: stage, ElapsedMs: elapsed.Milliseconds()}
+	if err != nil {
+		e.Error(stage, err, true)
+		ev.Failed, ev.Error = true, err.Error()
+	}
+	e.Emit(ev)
+}
+
+// Error writes an error event; fatal marks errors that end the run.
+func (e *EventWriter) Error(stage string, err error, fatal bool) {
+	e.Emit(Event{Type: EventError, Stage: stage, Error: err.Error(), Fatal: fatal})
+}
+
+// Progress writes variant_progress, or variant_done for a variant's final
+// event. Aggregate events are left to job_progress.
+func (e *EventWriter) Progress(p ProgressEvent) {
+	if p.Aggregate {
+		return
+	}
+	ev := Event{
+		Type:       EventVariantProgress,
+		Stage:      StageTranscode,
+		Variant:    p.Variant,
+		Percent:    p.Percent,
+		Speed:      p.Speed,
+		ETASeconds: p.ETA.Seconds(),
+	}
+	if p.Done {
+		ev.Type, ev.Failed, ev.ETASeconds = EventVariantDone, p.Failed, 0
+	}
+	e.Emit(ev)
+}
+
+// JobProgress writes job_progress, or job_finished once the job is done.
+func (e *EventWriter) JobProgress(p JobProgress) {
+	if p.Done {
+		e.Finished(nil)
+		return
+	}
+	e.Emit(Event{Type: EventJobProgress, Stage: p.Stage, Percent: p.Percent})
+}
+
+// Finished writes job_finished, failed when err is set.
+func (e *EventWriter) Finished(err error) {
+	ev := Event{Type: EventJobFinished, Percent: 100}
+	if err != nil {
+		ev.Percent, ev.Failed, ev.Error = 0, true, err.Error()
+	}
+	e.Emit(ev)
+}
+
+// reportErrors writes the report's non-fatal errors not yet written.
+func (e *EventWriter) reportErrors(stage string, report *Report) {
+	if report == nil {
+		return
+	}
+	e.mu.Lock()
+	pending := report.Errors[min(e.reported, len(report.Errors)):]
+	e.reported = len(report.Errors)
+	e.mu.Unlock()
+	for _, err := range pending {
+		e.Error(stage, err, false)
+	}
+}
+
+// setJob records the job slug when it wasn't known up front.
+func (e *EventWriter) setJob(slug string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.job == "" {
+		e.job = slug
+	}
+}
+
+// WithEventStream writes machine-readable JSON-lines events for the run to
+// w: stage starts and finishes, variant progress and completion, job
+// progress, warnings and errors, and a final job_finished. Orchestrators
+// (Airflow, Nomad, ...) can follow a job from it instead of parsing logs.
+// See OpenEventStream for files and inherited descriptors.
+func WithEventStream(w io.Writer) Option {
+	ew := NewEventWriter(w, "")
+	return func(o *runOptions) {
+		WithBeforeStage(func(_ context.Context, ev StageEvent) error {
+			ew.setJob(ev.Slug)
+			ew.StageStarted(ev.Stage)
+			return nil
+		})(o)
+		WithAfterStage(func(_ context.Context, ev StageEvent) error {
+			ew.reportErrors(ev.Stage, ev.Report)
+			ew.StageFinished(ev.Stage, ev.Elapsed, ev.Err)
+			if ev.Err != nil {
+				ew.Finished(ev.Err)
+			}
+			return nil
+		})(o)
+		WithProgress(ew.Progress)(o)
+		WithJobProgress(ew.JobProgress)(o)
+	}
+}
+
+// OpenEventStream opens an event stream destination: "-" for stdout,
+// "fd:N" for an inherited file descriptor (e.g. "fd:3"), or a file path,
+// which is appended to. Closing the result leaves stdout open.
+func OpenEventStream(target string) (io.WriteCloser, error) {
+	switch {
+	case target == "-":
+		return nopWriteCloser{os.Stdout}, nil
+	case strings.HasPrefix(target, "fd:"):
+		fd, err := strconv.Atoi(strings.TrimPrefix(target, "fd:"))
+		if err != nil || fd < 0 {
+			return nil, fmt.Errorf("invalid event stream descriptor %q", target)
+		}
+		return os.NewFile(uintptr(fd), target), nil
+	}
+	f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event stream: %w", err)
+	}
+	return f, nil
+}
+
+// nopWriteCloser is a writer whose Close does nothing.
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,133 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+)
+
+// Stage names passed to stage hooks, in execution order.
+const (
+	StageAnalyze     = "analyze"
+	StageTranscode   = "transcode"
+	StageEncrypt     = "encrypt"
+	StageSegment     = "segment"
+	StageThumbnail   = "thumbnail"
+	StagePreview     = "preview"
+	StageSubtitle    = "subtitle"
+	StageAudio       = "audio"
+	StageProgressive = "progressive"
+	StageManifest    = "manifest"
+	StageVerify      = "verify"
+	StageMetadata    = "metadata"
+	StageChecksum    = "checksum"
+	StagePublish     = "publish" // Only with WithInMemory
+)
+
+// StageEvent describes the job state at a stage boundary.
+type StageEvent struct {
+	Stage   string                       // One of the Stage* constants
+	Slug    string                       // Output slug for the job
+	Profile *transcoder.TranscodeProfile // Profile being executed
+	Media   *analyzer.MediaInfo          // Analysis result; nil before analyze completes
+	Report  *Report                      // Report as filled in so far
+	Job     *Job                         // Full job state (transcode and segment results, ...)
+	Err     error                        // Stage error (AfterStage only)
+	Elapsed time.Duration                // Stage wall time (AfterStage only)
+}
+
+// StageHook runs at a stage boundary. Returning an error aborts the run,
+// e.g. a virus scan rejecting the input after analyze, or a failed upload
+// after segment.
+type StageHook func(ctx context.Context, ev StageEvent) error
+
+// VariantHook runs after each variant encode succeeds, before segmentation.
+// It may be called concurrently from encoder goroutines.
+type VariantHook func(ctx context.Context, v ResolutionVariant)
+
+// hooks holds the hooks registered for a run.
+type hooks struct {
+	before  []StageHook
+	after   []StageHook
+	variant []VariantHook
+}
+
+// WithBeforeStage registers a hook that runs before every stage. Repeatable;
+// hooks run in registration order and the first error aborts the run.
+func WithBeforeStage(fn StageHook) Option {
+	return func(o *runOptions) {
+		if fn != nil {
+			o.hooks.before = append(o.hooks.before, fn)
+		}
+	}
+}
+
+// WithAfterStage registers a hook that runs after every stage, including
+// failed ones (see StageEvent.Err). Repeatable; the first error aborts the run.
+func WithAfterStage(fn StageHook) Option {
+	return func(o *runOptions) {
+		if fn != nil {
+			o.hooks.after = append(o.hooks.after, fn)
+		}
+	}
+}
+
+// WithOnVariantComplete registers a hook called as each variant finishes
+// encoding successfully. Repeatable.
+func WithOnVariantComplete(fn VariantHook) Option {
+	return func(o *runOptions) {
+		if fn != nil {
+			o.hooks.variant = append(o.hooks.variant, fn)
+		}
+	}
+}
+
+// runHooks calls each hook in order, stopping at the first error.
+func runHooks(ctx context.Context, fns []StageHook, ev StageEvent) error {
+	for _, fn := range fns {
+		if err := fn(ctx, ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runStage runs one stage wrapped with before/after hooks, tracing,
+// metrics, and job progress. After-hooks see the stage error; a hook error takes precedence.
+func (h hooks) runStage(ctx context.Context, job *Job, stage Stage) error {
+	name := stage.Name()
+	if err := runHooks(ctx, h.before, job.event(name)); err != nil {
+		return wrap(name+" hook", err)
+	}
+	begin := time.Now()
+	job.progress.begin(name)
+	stageCtx, endStage := startStage(ctx, name)
+	err := stage.Run(stageCtx, job)
+	endStage(err)
+	job.Report.Resources = job.meter.Usage()
+	if err == nil {
+		job.progress.finish(name)
+	}
+
+	ev := job.event(name)
+	ev.Err = err
+	ev.Elapsed = time.Since(begin)
+	if hookErr := runHooks(ctx, h.after, ev); hookErr != nil {
+		return wrap(name+" hook", hookErr)
+	}
+	return err
+}
+
+// variantCallback adapts variant hooks to the transcoder callback.
+func (h hooks) variantCallback(ctx context.Context) func(ResolutionVariant) {
+	if len(h.variant) == 0 {
+		return nil
+	}
+	return func(v ResolutionVariant) {
+		for _, fn := range h.variant {
+			fn(ctx, v)
+		}
+	}
+}
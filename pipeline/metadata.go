@@ -0,0 +1,122 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/segmenter"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/metadata"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/thumbnailer"
+)
+
+// buildMetadata assembles the full output inventory for metadata.json.
+// Paths are stored relative to the slug directory so the file stays valid
+// when the output tree is copied to a CDN or another host.
+func buildMetadata(
+	profile *transcoder.TranscodeProfile,
+	media *analyzer.MediaInfo,
+	result *transcoder.TranscodeResult,
+	segResult *segmenter.SegmentResult,
+	thumbs []string,
+	manifestPath string,
+) metadata.MediaMetadata {
+	meta := metadata.MediaMetadata{
+		Duration:        media.Duration,
+		SegmentLength:   profile.SegmentLength,
+		PipelineVersion: Version,
+		MasterManifest:  relativeTo(result.OutputDir, manifestPath),
+	}
+
+	// Index variant playlists by label so each variant can reference its own
+	playlists := make(map[string]string)
+	if segResult != nil {
+		for _, m := range segResult.Manifests {
+			label := strings.TrimSuffix(filepath.Base(m), filepath.Ext(m))
+			playlists[label] = relativeTo(result.OutputDir, m)
+		}
+	}
+
+	for _, v := range result.Variants {
+		var size int64
+		if fi, err := os.Stat(filepath.Join(result.OutputDir, v.OutputFilename)); err == nil {
+			size = fi.Size()
+		}
+		meta.Variants = append(meta.Variants, metadata.VariantMetadata{
+			Resolution: fmt.Sprintf("%dp", v.Height),
+			Width:      v.Width,
+			Height:     v.Height,
+			Bitrate:    v.Bitrate,
+			Codec:      codecOr(v.Codec, profile.VideoCodec),
+			Codecs:     v.Codecs,
+			Filename:   v.OutputFilename,
+			FileSize:   size,
+			Playlist:   playlists[segmenter.VariantLabel(v)],
+			Encoder:    encoderMetadata(v.Settings),
+		})
+	}
+
+	if len(thumbs) > 0 {
+		meta.Thumbnails = &metadata.ThumbnailMetadata{
+			Directory: "thumbnails",
+			Interval:  thumbnailer.ThumbnailInterval(*media, profile.SegmentLength),
+			Files:     thumbs,
+		}
+	}
+
+	// Muxed audio is in every variant, so a single track describes the
+	// output; demuxed audio is listed with the audio stage's renditions
+	if audio := media.PrimaryAudio(); audio != nil && !profile.Demuxed() {
+		codec := profile.AudioCodec
+		if codec == "copy" {
+			codec = audio.Codec
+		}
+		meta.AudioTracks = append(meta.AudioTracks, metadata.TrackMetadata{
+			Index:    0,
+			Codec:    codec,
+			Language: audio.Language,
+			Title:    audio.Title,
+			Channels: audio.Channels,
+		})
+	}
+
+	return meta
+}
+
+// relativeTo returns path relative to base, or path unchanged if it cannot be made relative.
+func relativeTo(base, path string) string {
+	if path == "" {
+		return ""
+	}
+	if rel, err := filepath.Rel(base, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// codecOr returns codec, or fallback when codec is unknown.
+func codecOr(codec, fallback string) string {
+	if codec != "" {
+		return codec
+	}
+	return fallback
+}
+
+// encoderMetadata converts a variant's encoder settings for metadata.json,
+// or returns nil for variants without a recorded encode (e.g. dry runs).
+func encoderMetadata(s transcoder.EncoderSettings) *metadata.EncoderMetadata {
+	if len(s.Command) == 0 {
+		return nil
+	}
+	return &metadata.EncoderMetadata{
+		Command:      s.Command,
+		Encoder:      s.Encoder,
+		FFmpeg:       s.FFmpeg,
+		Libraries:    s.Libraries,
+		ProfileHash:  s.ProfileHash,
+		SettingsHash: s.Hash,
+	}
+}
@@ -0,0 +1,38 @@
+package pipeline
+
+import (
+	"net/http"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/executil"
+	"github.com/dotsoulja/dotgo-transcode/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MetricsHandler returns an http.Handler serving pipeline metrics in Prometheus format.
+// Mount it on an existing server (e.g. mux.Handle("/metrics", pipeline.MetricsHandler())).
+func MetricsHandler() http.Handler {
+	return metrics.Default.Handler()
+}
+
+// MetricsRegistry returns the registry backing MetricsHandler.
+// Callers can register additional collectors alongside the pipeline metrics.
+func MetricsRegistry() *prometheus.Registry {
+	return metrics.Default.Registry()
+}
+
+// ServeMetrics starts a blocking HTTP server exposing /metrics on addr (e.g. ":9090").
+func ServeMetrics(addr string) error {
+	return metrics.Default.ListenAndServe(addr)
+}
+
+// SetQueueDepth reports the number of jobs waiting to run.
+// Job queues in front of the pipeline should call this whenever depth changes.
+func SetQueueDepth(n int) {
+	metrics.Default.SetQueueDepth(n)
+}
+
+// ResourceUsage is a re-export of executil.ResourceUsage: the CPU time, peak
+// resident set, and block I/O of a run's ffmpeg and ffprobe processes, for
+// cost attribution per title (see Report.Resources). Encodes run on cluster
+// workers are accounted on the worker, not here.
+type ResourceUsage = executil.ResourceUsage
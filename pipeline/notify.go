@@ -0,0 +1,68 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/errclass"
+	"github.com/dotsoulja/dotgo-transcode/internal/notify"
+)
+
+// Notifier is a re-export of notify.Notifier, a destination told how runs
+// finished (Slack, email, PagerDuty, or a webhook).
+type Notifier = notify.Notifier
+
+// Notification is a re-export of notify.Notification.
+type Notification = notify.Notification
+
+// LoadNotifier builds a Notifier from a JSON or YAML file listing sinks (see
+// notify.Config).
+func LoadNotifier(path string) (Notifier, error) {
+	return notify.LoadConfig(path)
+}
+
+// notifyTimeout bounds how long a finished run waits on its notifier.
+const notifyTimeout = 30 * time.Second
+
+// WithNotifier tells n how the run went once it finishes: failed, succeeded
+// with stage errors, or succeeded cleanly (sinks pick which they report).
+// Cancelled runs aren't reported. jobs.Manager reports the jobs it runs
+// itself, so don't also pass this to its pipeline options.
+func WithNotifier(n Notifier) Option {
+	return func(o *runOptions) {
+		o.notifier = n
+	}
+}
+
+// notify reports a finished run to the configured notifier, logging delivery
+// failures.
+func (o runOptions) notify(report *Report, slug string, started time.Time, err error, logger Logger) {
+	if o.notifier == nil || errclass.Classify(err) == errclass.Cancelled {
+		return
+	}
+	n := Notification{
+		JobID:     slug,
+		Tenant:    report.Tenant,
+		InputPath: report.InputPath,
+		Slug:      slug,
+		Elapsed:   time.Since(started),
+		Time:      time.Now(),
+	}
+	for _, e := range report.Errors {
+		n.Warnings = append(n.Warnings, e.Error())
+	}
+	switch {
+	case err != nil:
+		n.Kind, n.Error, n.Class = notify.JobFailed, err.Error(), errclass.Classify(err)
+	case len(n.Warnings) > 0:
+		n.Kind = notify.JobWarnings
+	default:
+		n.Kind = notify.JobSucceeded
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	if err := o.notifier.Notify(ctx, n); err != nil {
+		logger.LogError("notify", fmt.Errorf("failed to send notifications for %s: %w", slug, err))
+	}
+}
@@ -0,0 +1,286 @@
+package pipeline
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/cluster"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/logging"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
+)
+
+// Logger is the stage-aware logging interface shared by every pipeline stage.
+// Implement it directly, or adapt an existing logger with SlogLogger or KeyValueLogger.
+type Logger = stagelog.Logger
+
+// SlogLogger adapts a *slog.Logger into a pipeline Logger.
+// Error records carry the structured fields of analyzer and transcoder errors.
+func SlogLogger(s *slog.Logger) Logger {
+	return &logging.UnifiedLogger{Logger: s}
+}
+
+// KeyValueLogger adapts a sugared key/value logger (e.g. *zap.SugaredLogger) into a pipeline Logger.
+func KeyValueLogger(kv stagelog.KeyValueLogger) Logger {
+	return stagelog.FromKeyValue(kv)
+}
+
+// LogOptions is a re-export of logging.Options for configuring pipeline logging.
+// Selects level, output format ("pretty", "text", "json"), and per-job log files.
+type LogOptions = logging.Options
+
+// Option customizes a single pipeline run.
+// Options are accepted by Run, RunContext, RunPipeline, and RunPipelineContext.
+type Option func(*runOptions)
+
+// runOptions collects settings applied through Option functions.
+type runOptions struct {
+	log          LogOptions
+	logger       Logger
+	verbosity    Verbosity
+	dryRun       bool
+	cache        AnalysisCache
+	keyframes    KeyframeMode
+	analysis     AnalyzeOptions
+	progress     []ProgressFunc
+	progressChs  []chan<- ProgressEvent
+	jobProgress  []JobProgressFunc
+	stageWeights map[string]float64
+	hooks        hooks
+	keys         KeyProvider
+	signer       URLSigner
+	verify       bool
+	cluster      *cluster.Coordinator
+	workspace    WorkspaceOptions
+	storage      Storage
+	upgrade      *UpgradeOptions
+	notifier     Notifier
+	summary      bool // Log the profile before the first stage (RunPipeline)
+
+	stages     []Stage
+	stagesSet  bool
+	stageEdits []func([]Stage) []Stage
+}
+
+// WithLogOptions configures the slog-based logger used for the run.
+func WithLogOptions(opts LogOptions) Option {
+	return func(o *runOptions) {
+		o.log = opts
+	}
+}
+
+// WithLogger injects a Logger used by every stage of the run.
+// Takes precedence over WithLogOptions.
+func WithLogger(l Logger) Option {
+	return func(o *runOptions) {
+		o.logger = l
+	}
+}
+
+// Verbosity controls output volume: Quiet, Normal (default), or Debug.
+type Verbosity = stagelog.Verbosity
+
+// Verbosity levels re-exported for callers.
+const (
+	Quiet  = stagelog.Quiet
+	Normal = stagelog.Normal
+	Debug  = stagelog.Debug
+)
+
+// WithVerbosity sets output volume for the run. Quiet suppresses the profile
+// summary, progress ticker, and command echo, leaving only errors; Debug also
+// streams full ffmpeg stderr. The level travels with the run's context, so
+// concurrent runs may each use their own.
+func WithVerbosity(v Verbosity) Option {
+	return func(o *runOptions) {
+		o.verbosity = v
+	}
+}
+
+// WithDryRun analyzes the input and prints every ffmpeg command, output path,
+// and manifest the run would produce, without executing or writing anything.
+// The resulting Plan is attached to the Report.
+func WithDryRun(enabled bool) Option {
+	return func(o *runOptions) {
+		o.dryRun = enabled
+	}
+}
+
+// WithPlaybackCheck "plays" the master manifest after it is written: every
+// variant playlist is read and its first, middle, and last segments are
+// probed for codec and resolution consistency and monotonic timestamps.
+// Any discrepancy fails the run. HLS only.
+func WithPlaybackCheck(enabled bool) Option {
+	return func(o *runOptions) {
+		o.verify = enabled
+	}
+}
+
+// AnalysisCache stores media analysis results so unchanged inputs skip re-probing.
+// Use analyzer-provided stores via SidecarAnalysisCache, DirAnalysisCache, or
+// MemoryAnalysisCache, or implement the interface for a custom store.
+type AnalysisCache = analyzer.Cache
+
+// SidecarAnalysisCache stores analysis next to each input as <input>.mediainfo.json.
+func SidecarAnalysisCache() AnalysisCache {
+	return analyzer.SidecarCache{}
+}
+
+// DirAnalysisCache stores analysis results in dir, keyed by input path.
+func DirAnalysisCache(dir string) AnalysisCache {
+	return analyzer.DirCache{Dir: dir}
+}
+
+// MemoryAnalysisCache keeps analysis results in memory for the life of the process.
+func MemoryAnalysisCache() AnalysisCache {
+	return &analyzer.MemoryCache{}
+}
+
+// WithAnalysisCache serves media analysis from cache when the input file is
+// unchanged (same size and mtime, or same sampled content hash).
+// Without it, the process-wide cache installed via analyzer.SetCache is used, if any.
+func WithAnalysisCache(c AnalysisCache) Option {
+	return func(o *runOptions) {
+		o.cache = c
+	}
+}
+
+// KeyframeMode selects keyframe extraction speed/accuracy during analysis.
+type KeyframeMode = analyzer.KeyframeMode
+
+// Keyframe extraction modes re-exported for callers.
+const (
+	KeyframePackets = analyzer.KeyframePackets // Packet flags, no decoding (default, fastest)
+	KeyframeKeyOnly = analyzer.KeyframeKeyOnly // Decode keyframes only
+	KeyframeFrames  = analyzer.KeyframeFrames  // Decode every frame (slowest, most robust)
+)
+
+// WithKeyframeMode selects how keyframes are extracted when the profile leaves
+// segment_length unset. Defaults to the process-wide mode (KeyframePackets).
+func WithKeyframeMode(m KeyframeMode) Option {
+	return func(o *runOptions) {
+		o.keyframes = m
+	}
+}
+
+// AnalyzeOptions is a re-export of analyzer.AnalyzeOptions selecting optional
+// analysis passes. Cache and KeyframeMode set here are overridden by
+// WithAnalysisCache and WithKeyframeMode.
+type AnalyzeOptions = analyzer.AnalyzeOptions
+
+// Broken-source errors from analysis; match with errors.Is. ErrCorruptMedia is
+// only returned when AnalyzeOptions.DeepScan is enabled.
+var (
+	ErrMissingMoov  = analyzer.ErrMissingMoov
+	ErrCorruptMedia = analyzer.ErrCorruptMedia
+)
+
+// WithAnalyzeOptions enables optional analysis passes (e.g. DetectScan, DeepScan) for the run.
+func WithAnalyzeOptions(a AnalyzeOptions) Option {
+	return func(o *runOptions) {
+		o.analysis = a
+	}
+}
+
+// ProgressEvent is a re-export of transcoder.ProgressEvent, delivered per
+// variant and as an aggregate across all variants.
+type ProgressEvent = transcoder.ProgressEvent
+
+// ProgressFunc is a re-export of transcoder.ProgressFunc.
+type ProgressFunc = transcoder.ProgressFunc
+
+// WithProgress registers a callback receiving encode progress. It may be called
+// concurrently from encoder goroutines and must return quickly. Repeatable.
+func WithProgress(fn ProgressFunc) Option {
+	return func(o *runOptions) {
+		if fn != nil {
+			o.progress = append(o.progress, fn)
+		}
+	}
+}
+
+// progressDoneWait bounds how long a final (Done) event waits for room on a
+// progress channel.
+const progressDoneWait = 5 * time.Second
+
+// WithProgressChannel delivers progress events on ch. Events are dropped while
+// ch is full, except final (Done) events, which wait for room until the run's
+// context ends or progressDoneWait passes, so an abandoned channel can't stall
+// the run. The pipeline never closes ch. Repeatable.
+func WithProgressChannel(ch chan<- ProgressEvent) Option {
+	return func(o *runOptions) {
+		if ch != nil {
+			o.progressChs = append(o.progressChs, ch)
+		}
+	}
+}
+
+// channelProgress returns a ProgressFunc sending to ch as WithProgressChannel
+// describes.
+func channelProgress(ctx context.Context, ch chan<- ProgressEvent) ProgressFunc {
+	return func(ev ProgressEvent) {
+		select {
+		case ch <- ev:
+			return
+		default:
+		}
+		if !ev.Done {
+			return
+		}
+		timer := time.NewTimer(progressDoneWait)
+		defer timer.Stop()
+		select {
+		case ch <- ev:
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+	}
+}
+
+// transcodeOptions builds transcoder options from the run options, feeding
+// encode progress to tracker as well when it is set.
+func (o runOptions) transcodeOptions(ctx context.Context, tracker *progressTracker) transcoder.TranscodeOptions {
+	t := transcoder.TranscodeOptions{OnVariant: o.hooks.variantCallback(ctx)}
+	fns := o.progress[:len(o.progress):len(o.progress)]
+	for _, ch := range o.progressChs {
+		fns = append(fns, channelProgress(ctx, ch))
+	}
+	if tracker != nil {
+		fns = append(fns, tracker.transcodeProgress)
+	}
+	switch len(fns) {
+	case 0:
+	case 1:
+		t.Progress = fns[0]
+	default:
+		t.Progress = func(ev ProgressEvent) {
+			for _, fn := range fns {
+				fn(ev)
+			}
+		}
+	}
+	return t
+}
+
+// analyzeOptions merges analysis settings from every option.
+func (o runOptions) analyzeOptions() AnalyzeOptions {
+	a := o.analysis
+	if o.cache != nil {
+		a.Cache = o.cache
+	}
+	if o.keyframes != "" {
+		a.KeyframeMode = o.keyframes
+	}
+	return a
+}
+
+// newRunOptions applies opts over the defaults.
+func newRunOptions(opts []Option) runOptions {
+	var o runOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
@@ -0,0 +1,68 @@
+package pipeline
+
+import (
+	"context"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+)
+
+// DiscoverVariants scans an existing slug output directory and rebuilds the
+// encoded variant list from the file names Transcode writes
+// (<slug>_<label>_<bitrate>bps.<container>), ffprobing each for real
+// dimensions and size. An empty slug matches any slug, an empty container any
+// common video container; a nil logger uses the standard logger.
+func DiscoverVariants(slugDir, slug, container string, logger Logger) ([]ResolutionVariant, error) {
+	return transcoder.DiscoverVariants(slugDir, slug, container, logger)
+}
+
+// DiscoverStage stands in for TranscodeStage when variants already exist on
+// disk: it rebuilds the transcode result from the slug output directory.
+func DiscoverStage() Stage {
+	return StageFunc("discover", func(ctx context.Context, job *Job) error {
+		result, err := transcoder.DiscoverResult(job.Profile, job.Media.Duration, job.Logger)
+		if err != nil {
+			return wrap("discover variants", err)
+		}
+		job.Result = result
+		job.Report.VariantCount = len(result.Variants)
+		job.Report.Variants = result.Variants
+		job.Logger.LogStage("discover", "🔎 Found existing variants: "+variantSummary(result.Variants))
+		return nil
+	})
+}
+
+// RunSegmentation segments already-encoded variants and rebuilds the master
+// manifest without transcoding. Variants are discovered in the profile's
+// output directory; the input is still analyzed for duration and keyframes.
+// An empty format uses the profile's stream_format.
+func RunSegmentation(profile *TranscodeProfile, format string, opts ...Option) (*Report, error) {
+	return RunSegmentationContext(context.Background(), profile, format, opts...)
+}
+
+// RunSegmentationContext is RunSegmentation with a caller-supplied context.
+func RunSegmentationContext(ctx context.Context, profile *TranscodeProfile, format string, opts ...Option) (*Report, error) {
+	stages := WithStages(AnalyzeStage(), DiscoverStage(), SegmentStage(), ManifestStage())
+	return execute(ctx, profile, format, nil, newRunOptions(append([]Option{stages}, opts...)))
+}
+
+// RunThumbnails generates scrubber thumbnails from already-encoded variants
+// without transcoding or segmenting. Thumbnail paths are listed in Report.Thumbnails.
+func RunThumbnails(profile *TranscodeProfile, opts ...Option) (*Report, error) {
+	return RunThumbnailsContext(context.Background(), profile, opts...)
+}
+
+// RunThumbnailsContext is RunThumbnails with a caller-supplied context.
+func RunThumbnailsContext(ctx context.Context, profile *TranscodeProfile, opts ...Option) (*Report, error) {
+	stages := WithStages(AnalyzeStage(), DiscoverStage(), ThumbnailStage())
+	return execute(ctx, profile, "", nil, newRunOptions(append([]Option{stages}, opts...)))
+}
+
+// variantSummary lists variant filenames for log lines.
+func variantSummary(variants []ResolutionVariant) string {
+	names := make([]string, len(variants))
+	for i, v := range variants {
+		names[i] = v.OutputFilename
+	}
+	return strings.Join(names, ", ")
+}
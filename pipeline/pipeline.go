@@ -1,25 +1,29 @@
 package pipeline
 
 import (
+	"context"
 	"fmt"
-	"path/filepath"
-	"strings"
+	"time"
 
-	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
-	"github.com/dotsoulja/dotgo-transcode/internal/manifester"
+	"github.com/dotsoulja/dotgo-transcode/internal/executil"
+	"github.com/dotsoulja/dotgo-transcode/internal/metrics"
 	"github.com/dotsoulja/dotgo-transcode/internal/scaler"
-	"github.com/dotsoulja/dotgo-transcode/internal/segmenter"
+	"github.com/dotsoulja/dotgo-transcode/internal/tracing"
 	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
 	"github.com/dotsoulja/dotgo-transcode/internal/utils/logging"
-	"github.com/dotsoulja/dotgo-transcode/internal/utils/thumbnailer"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
+	"github.com/dotsoulja/dotgo-transcode/internal/workspace"
 )
 
 // Config defines the input parameters for running the pipeline.
 // It includes the path to the transcode profile, and optional client context
 // for resolution presets or adaptive logic.
 type Config struct {
-	ProfilePath   string
-	StreamFormat  string // "hls" or "dash"
+	ProfilePath   string            // Profile file path, bare filename under profiles/, built-in preset name, or "-" for stdin
+	Profile       *TranscodeProfile // Already loaded profile; takes precedence over ProfilePath and Overlays
+	Overlays      []string          // Optional overlay profiles merged on top of ProfilePath, in order
+	StreamFormat  string            // "hls" or "dash"; "" uses the profile's stream_format
+	SkipStages    []string          // Stage names to skip (e.g. StageThumbnail), see WithStageSkipped
 	ClientContext scaler.ClientContext
 }
 
@@ -27,79 +31,52 @@ type Config struct {
 // It includes input/output paths, metadata, and any errors encountered.
 type Report struct {
 	InputPath     string
+	Tenant        string // Tenant the run's outputs are namespaced under, "" for none
 	ManifestPath  string
+	MetadataPath  string
+	ChecksumPath  string
+	PreviewPath   string // Preview HLS playlist, when the profile requests a preview
+	KeyID         string // Hex key ID segments were encrypted with, when a KeyProvider is set
+	LicenseURL    string // Key URI written into the variant playlists, when encrypted
 	VariantCount  int
+	Degraded      bool // Some variants failed and only the rest were published (see PartialFailureSettings)
 	ManifestCount int
 	Duration      float64
-	Thumbnails    []string
+	Thumbnails    []string            // Generated thumbnail filenames inside ThumbnailDir
+	ThumbnailDir  string              // Directory holding Thumbnails
+	Variants      []ResolutionVariant // Encoded variants with per-variant encode stats
+	Adjustments   []string            // Changes made to the profile's variants during planning (e.g. bitrates capped to the source)
+	Plan          *Plan               // Populated instead of outputs when running with WithDryRun
+	Playback      *PlaybackReport     // Populated when running with WithPlaybackCheck
+	Upgrade       *UpgradePlan        // Populated when running with WithUpgrade
+	Stored        []string            // Storage keys written when running WithInMemory
+	Resources     ResourceUsage       // CPU time, peak RSS, and I/O of the run's local ffmpeg and ffprobe processes
 	Errors        []error
 }
 
 // Run executes the full pipeline and assumes a valid json/yaml profile located in /profiles directory.
 // It returns a Report summarizing the process and any errors encountered.
-func Run(config Config) (*Report, error) {
-	var report Report
-	logger := &logging.UnifiedLogger{}
+func Run(config Config, opts ...Option) (*Report, error) {
+	return RunContext(context.Background(), config, opts...)
+}
 
+// RunContext is Run with a caller-supplied context.
+// The whole run is recorded as a "pipeline.Run" span under any trace carried by ctx.
+func RunContext(ctx context.Context, config Config, opts ...Option) (*Report, error) {
 	// Load transcode profile
-	profile, err := transcoder.LoadProfile(config.ProfilePath)
-	if err != nil {
-		return nil, wrap("load profile", err)
-	}
-	report.InputPath = profile.InputPath
-
-	// Analyze input media
-	media, err := analyzer.AnalyzeMedia(profile.InputPath, profile.SegmentLength, logger)
-	if err != nil {
-		return nil, wrap("analyze media", err)
-	}
-	report.Duration = media.Duration
-
-	// Select resolution preset
-	initialPreset, err := scaler.SelectPreset(media.Width, media.Height, &config.ClientContext)
-	if err != nil {
-		return nil, wrap("select preset", err)
-	}
-	_ = initialPreset // optional: log or use for override
-
-	// Transcode media
-	result, err := transcoder.Transcode(profile, media, logger)
-	if err != nil {
-		return nil, wrap("transcode", err)
-	}
-	report.VariantCount = len(result.Variants)
-	for _, e := range result.Errors {
-		report.Errors = append(report.Errors, e)
-	}
-
-	// Segment variants
-	segResult, err := segmenter.SegmentMedia(result, config.StreamFormat, media)
-	if err != nil {
-		return nil, wrap("segment", err)
-	}
-	report.ManifestCount = len(segResult.Manifests)
-	for _, e := range segResult.Errors {
-		report.Errors = append(report.Errors, e)
+	profile := config.Profile
+	if profile == nil {
+		var err error
+		profile, err = transcoder.LoadProfileLayers(config.ProfilePath, config.Overlays...)
+		if err != nil {
+			return nil, wrap("load profile", err)
+		}
 	}
 
-	// Generate thumbnails
-	basename := filepath.Base(profile.InputPath)
-	name := strings.TrimSuffix(basename, filepath.Ext(basename))
-	thumbs, err := thumbnailer.GenerateThumbnails(*media, *result, name)
-	if err != nil {
-		report.Errors = append(report.Errors, wrap("thumbnail", err))
-	} else {
-		report.Thumbnails = thumbs
-	}
-
-	// Generate master manifest
-	manifestPath, err := manifester.GenerateMasterManifest(segResult, profile.PreserveManifest)
-	if err != nil {
-		return nil, wrap("manifest", err)
+	if len(config.SkipStages) > 0 {
+		opts = append([]Option{WithStageSkipped(config.SkipStages...)}, opts...)
 	}
-	report.ManifestPath = manifestPath
-
-	return &report, nil
+	return execute(ctx, profile, config.StreamFormat, &config.ClientContext, newRunOptions(opts))
 }
 
 // RunPipeline executes the full media pipeline using a provided TranscodeProfile.
@@ -111,6 +88,11 @@ func Run(config Config) (*Report, error) {
 //  3. Segment each variant into HLS format (full DASH support coming soon)
 //  4. Generate thumbnails for frontend scrubber (based on segment length)
 //  5. Build master manifest referencing all variants (master.m3u8)
+//  6. Write metadata.json with the full output inventory
+//  7. Optionally write checksums.json with SHA-256 digests of every output
+//
+// Each step is a Stage (see DefaultStages); WithStages, WithStageReplaced,
+// WithStageSkipped, and WithStageAfter customize the list.
 //
 // In this version, the caller is responsible for constructing the TranscodeProfile with appropriate
 // input/ output paths and variant ladder. This function returns a structured report
@@ -118,75 +100,163 @@ func Run(config Config) (*Report, error) {
 //
 // Returns:
 //   - A structured Report containing metadata and errors.
-func RunPipeline(profile *transcoder.TranscodeProfile) (*Report, error) {
-	logger := &logging.UnifiedLogger{}
-	report := &Report{InputPath: profile.InputPath}
-
-	// Log profile summary before starting
-	fmt.Println("\n🎬 Starting pipeline for:")
-	fmt.Printf("   📂 InputPath:        %s\n", profile.InputPath)
-	fmt.Printf("   📂 OutputDir:        %s\n", profile.OutputDir)
-	fmt.Printf("   🎞️ VideoCodec:       %s\n", profile.VideoCodec)
-	fmt.Printf("   🎵 AudioCodec:       %s\n", profile.AudioCodec)
-	fmt.Printf("   📦 Container:        %s\n", profile.Container)
-	fmt.Printf("   ⏰ SegmentLength:    %d\n", profile.SegmentLength)
-	fmt.Printf("   🔧 PreserveManifest: %v\n", profile.PreserveManifest)
-	fmt.Printf("   🏎️ UseHardwareAccel: %v\n", profile.UseHardwareAccel)
-
-	fmt.Println("   🎯 Variants:")
+func RunPipeline(profile *transcoder.TranscodeProfile, opts ...Option) (*Report, error) {
+	return RunPipelineContext(context.Background(), profile, opts...)
+}
+
+// RunPipelineContext is RunPipeline with a caller-supplied context for tracing.
+func RunPipelineContext(ctx context.Context, profile *transcoder.TranscodeProfile, opts ...Option) (*Report, error) {
+	o := newRunOptions(opts)
+	o.summary = true
+	return execute(ctx, profile, "", nil, o)
+}
+
+// logProfileSummary writes a human-readable overview of the profile through
+// the run's logger, so it follows the log format and verbosity.
+func logProfileSummary(logger stagelog.Logger, profile *transcoder.TranscodeProfile) {
+	lines := []string{
+		"🎬 Starting pipeline for:",
+		fmt.Sprintf("📂 InputPath:        %s", profile.InputPath),
+		fmt.Sprintf("📂 OutputDir:        %s", profile.OutputDir),
+		fmt.Sprintf("🎞️ VideoCodec:       %s", profile.VideoCodec),
+		fmt.Sprintf("🎵 AudioCodec:       %s", profile.AudioCodec),
+		fmt.Sprintf("🔀 AudioLayout:      %s", profile.AudioLayoutMode()),
+	}
+	if profile.AudioOffsetMs != 0 {
+		lines = append(lines, fmt.Sprintf("⏱️ AudioOffset:      %dms", profile.AudioOffsetMs))
+	}
+	lines = append(lines,
+		fmt.Sprintf("📦 Container:        %s", profile.Container),
+		fmt.Sprintf("⏰ SegmentLength:    %d", profile.SegmentLength),
+		fmt.Sprintf("🔧 PreserveManifest: %v", profile.PreserveManifest),
+		fmt.Sprintf("🏎️ UseHardwareAccel: %v", profile.UseHardwareAccel),
+		"🎯 Variants:",
+	)
 	for i, v := range profile.Variants {
-		fmt.Printf("      • [%d] %s @ %s\n", i, v.Resolution, v.Bitrate)
+		lines = append(lines, fmt.Sprintf("   • [%d] %s @ %s", i, v.Resolution, v.Bitrate))
 	}
+	for _, line := range lines {
+		logger.LogStage("pipeline", line)
+	}
+}
 
-	// Step 1: Analyze media file for metadata
-	media, err := analyzer.AnalyzeMedia(profile.InputPath, profile.SegmentLength, logger)
+// execute runs every pipeline stage for an already loaded profile.
+// Shared by Run and RunPipeline so both entry points report identical
+// results, metrics, and traces. A nil ClientContext skips initial preset
+// selection, and an empty format uses the profile's.
+func execute(ctx context.Context, profile *transcoder.TranscodeProfile, format string, client *scaler.ClientContext, opts runOptions) (report *Report, err error) {
+	if format == "" {
+		format = profile.DeliveryFormat()
+	}
+	stages, err := opts.pipelineStages()
 	if err != nil {
-		return nil, wrap("analyze media", err)
+		return nil, wrap("stages", err)
 	}
-	report.Duration = media.Duration
+	report = &Report{InputPath: profile.InputPath, Tenant: profile.Tenant}
+	profile = profile.PinSlug()
+	slug := profile.OutputSlug()
 
-	// Step 2: Transcode into resolution-bitrate variants
-	result, err := transcoder.Transcode(profile, media, logger)
-	if err != nil {
-		return nil, wrap("transcode", err)
+	// Use the injected logger, or build a slog-backed job logger from LogOptions
+	logger := opts.logger
+	if logger == nil {
+		jobLogger, closeLog, err := logging.NewJobLogger(opts.log, slug)
+		if err != nil {
+			return nil, wrap("logging", err)
+		}
+		defer closeLog.Close()
+		logger = jobLogger
 	}
-	report.VariantCount = len(result.Variants)
-	for _, e := range result.Errors {
-		report.Errors = append(report.Errors, e)
+	logger = stagelog.Filter(logger, opts.verbosity)
+	if opts.summary {
+		logProfileSummary(logger, profile)
 	}
+	ctx = executil.WithVerbosity(ctx, opts.verbosity)
+
+	ctx, span := tracing.Start(ctx, "pipeline.Run",
+		tracing.AttrSlug.String(slug),
+		tracing.AttrInput.String(profile.InputPath),
+		tracing.AttrFormat.String(format),
+		tracing.AttrTenant.String(profile.Tenant),
+	)
+	metrics.Default.JobStarted(profile.Tenant)
+	started, partial := time.Now(), report
+	defer func() {
+		if err != nil {
+			metrics.Default.JobFailed(profile.Tenant)
+		} else {
+			metrics.Default.JobCompleted(profile.Tenant)
+		}
+		tracing.End(span, err)
+		opts.notify(partial, slug, started, err, logger)
+	}()
 
-	// Step 3: Segment each variant into HLS format
-	segResult, err := segmenter.SegmentMedia(result, "hls", media)
+	ws, err := workspace.New(slug, opts.workspaceOptions())
 	if err != nil {
-		return nil, wrap("segment", err)
+		return nil, wrap("workspace", err)
 	}
-	report.ManifestCount = len(segResult.Manifests)
-	for _, e := range segResult.Errors {
-		report.Errors = append(report.Errors, e)
+	defer closeWorkspace(ws, logger, &err)
+	if opts.storage != nil {
+		profile = inMemoryProfile(profile, slug, ws)
 	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go ws.Enforce(ctx, cancel)
+	meter := executil.NewMeter()
+	ctx = executil.WithMeter(ctx, meter)
 
-	// Step 4: Generate thumbnails for scrubber
-	name := strings.TrimSuffix(filepath.Base(profile.InputPath), filepath.Ext(profile.InputPath))
-	thumbs, err := thumbnailer.GenerateThumbnails(*media, *result, name)
-	if err != nil {
-		report.Errors = append(report.Errors, wrap("thumbnail", err))
-	} else {
-		report.Thumbnails = thumbs
+	job := &Job{
+		Slug:      slug,
+		Format:    format,
+		Profile:   profile,
+		Client:    client,
+		Logger:    logger,
+		Report:    report,
+		Workspace: ws,
+		opts:      opts,
+		meter:     meter,
 	}
-
-	// Step 5: Build master manifest referencing all variants
-	manifestPath, err := manifester.GenerateMasterManifest(segResult, profile.PreserveManifest)
-	if err != nil {
-		return nil, wrap("manifest", err)
+	defer job.cleanup()
+	job.progress = newProgressTracker(stages, opts)
+	for _, stage := range stages {
+		if ctx.Err() != nil {
+			return nil, wrap(stage.Name(), context.Cause(ctx))
+		}
+		if err := job.checkPrerequisites(stage.Name()); err != nil {
+			return nil, err
+		}
+		if err := opts.hooks.runStage(ctx, job, stage); err != nil {
+			return nil, err
+		}
+		if job.stopped {
+			break
+		}
 	}
-	report.ManifestPath = manifestPath
+	job.progress.done()
+	logger.LogStage("resources", fmt.Sprintf("🧾 %s", formatUsage(report.Resources)))
 
 	return report, nil
+}
+
+// startStage opens a tracing span for a pipeline stage and returns a function
+// that ends the span and records the stage duration metric.
+func startStage(ctx context.Context, stage string) (context.Context, func(error)) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "pipeline."+stage)
+	return ctx, func(err error) {
+		metrics.Default.ObserveStage(stage, time.Since(start))
+		tracing.End(span, err)
+	}
+}
 
+// formatUsage summarizes u for the log, e.g. "cpu 1m2.5s, peak rss 512.0 MiB,
+// read 1.2 GiB, written 300.0 MiB (14 processes)".
+func formatUsage(u ResourceUsage) string {
+	return fmt.Sprintf("cpu %s, peak rss %s, read %s, written %s (%d processes)",
+		u.CPUTime.Round(time.Millisecond), workspace.FormatSize(u.PeakRSS), workspace.FormatSize(u.BytesRead), workspace.FormatSize(u.BytesWritten), u.Processes)
 }
 
 // wrap adds stage context to errors for structured logging and debugging.
 // Used internally to annotate errors from each pipeline phase.
 func wrap(stage string, err error) error {
-	return fmt.Errorf("[%s] %v", stage, err)
+	return fmt.Errorf("[%s] %w", stage, err)
 }
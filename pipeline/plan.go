@@ -0,0 +1,165 @@
+package pipeline
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/manifester"
+	"github.com/dotsoulja/dotgo-transcode/internal/segmenter"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/audio"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/checksum"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/thumbnailer"
+)
+
+// Plan lists every command and output a pipeline run would produce.
+// Built during dry runs after analysis; nothing is executed or written.
+type Plan struct {
+	Slug           string                         // Slug derived from the input filename
+	OutputDir      string                         // Slug output directory
+	Format         string                         // "hls" or "dash"
+	Transcodes     []transcoder.PlannedVariant    // Variant encodes
+	Skipped        []string                       // Variants dropped during planning, with reasons
+	Adjusted       []string                       // Changes made to variants during planning (e.g. bitrates capped to the source)
+	Segments       []segmenter.PlannedSegment     // Per-variant segmentation
+	Thumbnails     []thumbnailer.PlannedThumbnail // Scrubber thumbnails
+	Sprites        *thumbnailer.SpriteSheets      // Scrubber sprite sheets, when the profile requests them
+	Audio          []audio.Rendition              // Alternate audio renditions
+	MasterManifest string                         // Master manifest path
+	ManifestDiff   *ManifestDiff                  // Changes to the existing HLS master, when the profile preserves it
+	MetadataPath   string                         // metadata.json path
+	ChecksumPath   string                         // checksums.json path, if enabled
+}
+
+// BuildPlan computes the full command plan for profile against analyzed media.
+// The plan assumes every variant encode succeeds.
+func BuildPlan(profile *transcoder.TranscodeProfile, media *analyzer.MediaInfo, format string, logger Logger) *Plan {
+	logger = stagelog.OrStd(logger)
+
+	tp := transcoder.PlanTranscode(profile, media, logger)
+	if tp.Err != nil {
+		tp.Skipped = append(tp.Skipped, fmt.Sprintf("every variant: %v", tp.Err))
+	}
+	result := tp.Result(profile, media)
+
+	plan := &Plan{
+		Slug:           tp.Slug,
+		OutputDir:      tp.SlugDir,
+		Format:         format,
+		Transcodes:     tp.Variants,
+		Skipped:        tp.Skipped,
+		Adjusted:       tp.Adjusted,
+		MasterManifest: manifester.MasterPath(tp.SlugDir, format),
+		MetadataPath:   filepath.Join(tp.SlugDir, "metadata.json"),
+	}
+
+	for _, v := range result.Variants {
+		plan.Segments = append(plan.Segments, segmenter.PlanSegment(result, v, format, media, logger))
+	}
+
+	if thumbs, err := thumbnailer.PlanThumbnails(*media, *result, tp.Slug, logger); err != nil {
+		logger.LogError("thumbnail", err)
+	} else {
+		plan.Thumbnails = thumbs
+	}
+	if sprites, err := thumbnailer.PlanSprites(*media, *result, logger); err != nil {
+		logger.LogError("thumbnail", err)
+	} else {
+		plan.Sprites = sprites
+	}
+
+	plan.Audio = audio.Plan(profile, media, tp.SlugDir, format, audio.Options{SegmentLength: segmenter.SegmentLength(profile, media)})
+
+	if profile.PreserveManifest && format == "hls" {
+		seg := &segmenter.SegmentResult{OutputDir: tp.SlugDir, Format: format, Variants: make(map[string]transcoder.ResolutionVariant)}
+		for i, s := range plan.Segments {
+			seg.Manifests = append(seg.Manifests, s.ManifestPath)
+			seg.Variants[s.ManifestPath] = result.Variants[i]
+		}
+		diff, err := manifester.DiffMasterManifest(seg, logger, manifester.ManifestOptions{
+			Audio:   audioRenditions(tp.SlugDir, plan.Audio, profile, media),
+			Demuxed: profile.Demuxed() && len(plan.Audio) > 0,
+		})
+		if err != nil {
+			logger.LogError("manifest", err)
+		} else {
+			plan.ManifestDiff = diff
+		}
+	}
+
+	if profile.Checksums {
+		plan.ChecksumPath = filepath.Join(tp.SlugDir, checksum.ManifestFilename)
+	}
+	return plan
+}
+
+// Print writes a human-readable rendering of the plan to w.
+func (p *Plan) Print(w io.Writer) {
+	fmt.Fprintf(w, "\n🧪 Dry run plan for %s (%s)\n", p.Slug, p.Format)
+	fmt.Fprintf(w, "   📂 OutputDir: %s\n", p.OutputDir)
+
+	fmt.Fprintf(w, "\n🎞️ Transcodes (%d):\n", len(p.Transcodes))
+	for _, v := range p.Transcodes {
+		fmt.Fprintf(w, "   • %s (%dx%d) -> %s\n", v.Key, v.Width, v.Height, v.OutputPath)
+		fmt.Fprintf(w, "     $ %s\n", strings.Join(v.Command, " "))
+	}
+	for _, s := range p.Skipped {
+		fmt.Fprintf(w, "   ⛔ %s\n", s)
+	}
+	for _, s := range p.Adjusted {
+		fmt.Fprintf(w, "   🧢 %s\n", s)
+	}
+
+	fmt.Fprintf(w, "\n✂️ Segments (%d):\n", len(p.Segments))
+	for _, s := range p.Segments {
+		fmt.Fprintf(w, "   • %s (%ds, %s) -> %s\n", s.Label, s.SegmentLength, s.Packager, s.ManifestPath)
+		if s.Command == nil {
+			fmt.Fprintf(w, "     (in-process)\n")
+			continue
+		}
+		for _, cmd := range append(s.Prepare, s.Command) {
+			fmt.Fprintf(w, "     $ %s\n", strings.Join(cmd, " "))
+		}
+	}
+
+	fmt.Fprintf(w, "\n🖼️ Thumbnails (%d):\n", len(p.Thumbnails))
+	for _, t := range p.Thumbnails {
+		fmt.Fprintf(w, "   • %.2fs -> %s\n", t.Timestamp, t.OutputPath)
+		fmt.Fprintf(w, "     $ %s\n", strings.Join(t.Command, " "))
+	}
+
+	if s := p.Sprites; s != nil {
+		fmt.Fprintf(w, "\n🧩 Sprites (%d sheets, %d tiles of %dx%d every %ds) -> %s\n", len(s.Sheets), s.Tiles, s.TileWidth, s.TileHeight, s.Interval, filepath.Join(s.Dir, s.Storyboard))
+		fmt.Fprintf(w, "     $ %s\n", strings.Join(s.Command, " "))
+	}
+
+	if len(p.Audio) > 0 {
+		fmt.Fprintf(w, "\n🔊 Audio renditions (%d):\n", len(p.Audio))
+		for _, a := range p.Audio {
+			fmt.Fprintf(w, "   • %s (%s, %dch) -> %s\n", a.Name, a.Codecs, a.Channels, a.Manifest)
+			fmt.Fprintf(w, "     $ %s\n", strings.Join(a.Command, " "))
+		}
+	}
+
+	fmt.Fprintln(w, "\n🧾 Manifests & metadata:")
+	fmt.Fprintf(w, "   📜 %s\n", p.MasterManifest)
+	if d := p.ManifestDiff; d != nil {
+		switch {
+		case !d.Exists:
+			fmt.Fprintln(w, "     (no existing master; it will be created)")
+		case d.Empty():
+			fmt.Fprintln(w, "     (existing master unchanged)")
+		}
+		for _, line := range d.Lines() {
+			fmt.Fprintf(w, "     %s\n", line)
+		}
+	}
+	fmt.Fprintf(w, "   📝 %s\n", p.MetadataPath)
+	if p.ChecksumPath != "" {
+		fmt.Fprintf(w, "   🔐 %s\n", p.ChecksumPath)
+	}
+}
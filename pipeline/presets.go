@@ -0,0 +1,27 @@
+package pipeline
+
+import "github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+
+// PresetInfo is a re-export of transcoder.PresetInfo, a built-in profile.
+type PresetInfo = transcoder.PresetInfo
+
+// RateControl is a re-export of transcoder.RateControl.
+type RateControl = transcoder.RateControl
+
+// Presets lists the built-in profiles ("archive", "streaming-high",
+// "mobile-data-saver"). Their names work as Config.ProfilePath or in a
+// profile's "extends".
+func Presets() []PresetInfo {
+	return transcoder.Presets()
+}
+
+// Preset returns built-in preset name, unvalidated, with InputPath and
+// OutputDir set, ready for RunPipeline.
+func Preset(name, inputPath, outputDir string) (*TranscodeProfile, error) {
+	profile, err := transcoder.Preset(name)
+	if err != nil {
+		return nil, err
+	}
+	profile.InputPath, profile.OutputDir = inputPath, outputDir
+	return profile, nil
+}
@@ -0,0 +1,170 @@
+package pipeline
+
+import (
+	"sync"
+)
+
+// JobProgress is progress through a whole run. Each stage counts toward
+// Percent by its weight (see DefaultStageWeights); only the transcode stage
+// reports progress while running, other stages advance it when they finish.
+type JobProgress struct {
+	Stage        string  // Stage currently running, or the last one when Done
+	StagePercent float64 // Progress within Stage, 0-100
+	Percent      float64 // Progress across every stage of the run, 0-100; never decreases
+	Done         bool    // Final event: every stage finished
+}
+
+// JobProgressFunc receives job progress. It may be called concurrently from
+// encoder goroutines and must return quickly.
+type JobProgressFunc func(JobProgress)
+
+// DefaultStageWeights is the share of a run's wall time each stage typically
+// takes. Stages missing from the map, such as custom ones, weigh 1.
+var DefaultStageWeights = map[string]float64{
+	StageAnalyze:     5,
+	StageTranscode:   70,
+	StageEncrypt:     1,
+	StageSegment:     10,
+	StageThumbnail:   4,
+	StagePreview:     3,
+	StageSubtitle:    1,
+	StageAudio:       3,
+	StageProgressive: 1,
+	StageManifest:    1,
+	StageVerify:      2,
+	StageMetadata:    1,
+	StageChecksum:    2,
+	StagePublish:     2,
+}
+
+// WithJobProgress registers a callback receiving progress across the whole
+// run, so callers can show a single 0-100% for the job. Repeatable.
+func WithJobProgress(fn JobProgressFunc) Option {
+	return func(o *runOptions) {
+		if fn != nil {
+			o.jobProgress = append(o.jobProgress, fn)
+		}
+	}
+}
+
+// WithStageWeights overrides the weights of individual stages in job
+// progress (see DefaultStageWeights), e.g. when thumbnails dominate a short
+// clip. A zero weight leaves the stage out of the percentage.
+func WithStageWeights(weights map[string]float64) Option {
+	return func(o *runOptions) {
+		if o.stageWeights == nil {
+			o.stageWeights = make(map[string]float64, len(weights))
+		}
+		for name, w := range weights {
+			o.stageWeights[name] = w
+		}
+	}
+}
+
+// progressTracker turns stage boundaries and transcode progress into
+// JobProgress events.
+type progressTracker struct {
+	mu       sync.Mutex
+	fns      []JobProgressFunc
+	weights  map[string]float64
+	total    float64
+	finished float64 // Weight of the stages already done
+	stage    string
+	percent  float64 // Last Percent delivered
+}
+
+// newProgressTracker weighs stages for a run, or returns nil when no job
+// progress callback is registered.
+func newProgressTracker(stages []Stage, opts runOptions) *progressTracker {
+	if len(opts.jobProgress) == 0 {
+		return nil
+	}
+	t := &progressTracker{fns: opts.jobProgress, weights: make(map[string]float64, len(stages))}
+	for _, st := range stages {
+		name := st.Name()
+		w, ok := opts.stageWeights[name]
+		if !ok {
+			w, ok = DefaultStageWeights[name]
+		}
+		if !ok {
+			w = 1
+		}
+		if w < 0 {
+			w = 0
+		}
+		t.weights[name] += w
+		t.total += w
+	}
+	return t
+}
+
+// begin marks stage as running.
+func (t *progressTracker) begin(stage string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stage = stage
+	t.emitLocked(0, false)
+}
+
+// update reports progress within the running stage.
+func (t *progressTracker) update(stage string, percent float64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if stage != t.stage {
+		return
+	}
+	t.emitLocked(min(max(percent, 0), 100), false)
+}
+
+// finish marks stage as done.
+func (t *progressTracker) finish(stage string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.emitLocked(100, false)
+	t.finished += t.weights[stage]
+	t.weights[stage] = 0
+}
+
+// done delivers the final event once the run succeeded, including runs a
+// stage stopped early.
+func (t *progressTracker) done() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.finished = t.total
+	t.emitLocked(100, true)
+}
+
+// emitLocked delivers the current stage at stagePercent.
+func (t *progressTracker) emitLocked(stagePercent float64, done bool) {
+	percent := 100.0
+	if t.total > 0 {
+		percent = (t.finished + t.weights[t.stage]*stagePercent/100) / t.total * 100
+	}
+	if percent < t.percent {
+		percent = t.percent
+	}
+	t.percent = percent
+	ev := JobProgress{Stage: t.stage, StagePercent: stagePercent, Percent: percent, Done: done}
+	for _, fn := range t.fns {
+		fn(ev)
+	}
+}
+
+// transcodeProgress feeds aggregate encode progress into the tracker.
+func (t *progressTracker) transcodeProgress(ev ProgressEvent) {
+	if ev.Aggregate {
+		t.update(StageTranscode, ev.Percent)
+	}
+}
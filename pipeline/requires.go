@@ -0,0 +1,130 @@
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrMissingPrerequisite is wrapped by run errors when a stage would run
+// without the output of a stage it depends on, e.g. thumbnails with the
+// transcode stage skipped.
+var ErrMissingPrerequisite = errors.New("missing prerequisite stage")
+
+// Job outputs stages depend on.
+const (
+	needMedia    = "media"    // Job.Media, from the analyze stage
+	needResult   = "result"   // Job.Result, from the transcode or discover stage
+	needSegments = "segments" // Job.Segments, from the segment stage
+	needManifest = "manifest" // Job.ManifestPath, from the manifest stage
+)
+
+// stageNeeds lists the job outputs each built-in stage reads.
+var stageNeeds = map[string][]string{
+	StageTranscode:   {needMedia},
+	"discover":       {needMedia},
+	"plan":           {needMedia},
+	StageSegment:     {needMedia, needResult},
+	StageThumbnail:   {needMedia, needResult},
+	StagePreview:     {needMedia, needResult},
+	StageSubtitle:    {needMedia, needResult},
+	StageAudio:       {needMedia, needResult},
+	StageProgressive: {needResult},
+	StageManifest:    {needResult, needSegments},
+	StageMetadata:    {needMedia, needResult},
+	StageChecksum:    {needResult},
+}
+
+// stageProvides lists the job outputs each built-in stage fills in.
+var stageProvides = map[string][]string{
+	StageAnalyze:   {needMedia},
+	StageTranscode: {needResult},
+	"discover":     {needResult},
+	StageSegment:   {needSegments},
+	StageManifest:  {needManifest},
+}
+
+// needProvider names the stage that normally provides each output, for errors.
+var needProvider = map[string]string{
+	needMedia:    StageAnalyze,
+	needResult:   StageTranscode,
+	needSegments: StageSegment,
+	needManifest: StageManifest,
+}
+
+// checkStageOrder rejects a stage list in which a built-in stage runs without
+// an earlier stage providing what it reads, e.g. after WithStageSkipped
+// removed StageTranscode. Stages with other names may stand in for any
+// built-in, so they are assumed to provide everything; Job.checkPrerequisites
+// catches the ones that don't when the run reaches them.
+func checkStageOrder(stages []Stage) error {
+	provided := make(map[string]bool)
+	custom := false
+	for _, st := range stages {
+		name := st.Name()
+		for _, need := range stageNeeds[name] {
+			if !provided[need] && !custom {
+				return fmt.Errorf("%w: stage %q needs the %s stage to run before it", ErrMissingPrerequisite, name, needProvider[need])
+			}
+		}
+		if !builtinStage(name) {
+			custom = true
+		}
+		for _, p := range stageProvides[name] {
+			provided[p] = true
+		}
+	}
+	return nil
+}
+
+// builtinStage reports whether name is one of the built-in stages.
+func builtinStage(name string) bool {
+	_, needs := stageNeeds[name]
+	_, provides := stageProvides[name]
+	return needs || provides || name == StageEncrypt || name == StageVerify || name == StagePublish
+}
+
+// checkPrerequisites returns an error wrapping ErrMissingPrerequisite when a
+// job output the named stage reads is missing.
+func (j *Job) checkPrerequisites(stage string) error {
+	for _, need := range stageNeeds[stage] {
+		if !j.has(need) {
+			return wrap(stage, fmt.Errorf("%w: the %s stage did not run", ErrMissingPrerequisite, needProvider[need]))
+		}
+	}
+	return nil
+}
+
+// has reports whether the job output need has been filled in.
+func (j *Job) has(need string) bool {
+	switch need {
+	case needMedia:
+		return j.Media != nil
+	case needResult:
+		return j.Result != nil
+	case needSegments:
+		return j.Segments != nil
+	case needManifest:
+		return j.ManifestPath != ""
+	}
+	return true
+}
+
+// CheckSkipStages returns an error when names, as passed to WithStageSkipped,
+// include a stage DefaultStages doesn't have or would leave a remaining
+// stage without its prerequisites (wrapping ErrMissingPrerequisite). Services
+// use it to reject a bad skip list at submission instead of mid-run.
+func CheckSkipStages(names []string) error {
+	defaults := DefaultStages()
+	var kept []Stage
+	for _, name := range names {
+		if stageIndex(defaults, name) < 0 {
+			return fmt.Errorf("unknown stage %q", name)
+		}
+	}
+	for _, st := range defaults {
+		if !containsName(names, st.Name()) {
+			kept = append(kept, st)
+		}
+	}
+	return checkStageOrder(kept)
+}
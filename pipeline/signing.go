@@ -0,0 +1,49 @@
+package pipeline
+
+import (
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/manifester"
+)
+
+// URLSigner is a re-export of manifester.URLSigner, which produces the query
+// token appended to variant and segment URIs.
+type URLSigner = manifester.URLSigner
+
+// URLSignerFunc is a re-export of manifester.URLSignerFunc.
+type URLSignerFunc = manifester.URLSignerFunc
+
+// HMACSigner is a re-export of manifester.HMACSigner (expiry plus
+// HMAC-SHA256 signature tokens).
+type HMACSigner = manifester.HMACSigner
+
+// TemplateSigner is a re-export of manifester.TemplateSigner, the signers
+// that can sign DASH segment templates.
+type TemplateSigner = manifester.TemplateSigner
+
+// ErrTemplateSigning is returned by DASH runs whose URLSigner can only sign
+// individual URLs.
+var ErrTemplateSigning = manifester.ErrTemplateSigning
+
+// NewHMACSigner returns a signer appending "expires=<unix>&signature=<hex>"
+// tokens valid for ttl. prefix is the path the CDN serves the output
+// directory under and is included in the signed string.
+func NewHMACSigner(secret []byte, ttl time.Duration, prefix string) *HMACSigner {
+	return manifester.NewHMACSigner(secret, ttl, prefix)
+}
+
+// TokenPlaceholder returns a signer that appends param=placeholder verbatim
+// (e.g. "?token={{TOKEN}}") for an edge that fills in per-viewer tokens.
+func TokenPlaceholder(param, placeholder string) URLSigner {
+	return manifester.TokenPlaceholder(param, placeholder)
+}
+
+// WithURLSigner signs every variant URI in the master manifest and every
+// segment URI in the variant manifests at manifest-generation time, for CDNs
+// that require URL signing. DASH manifests address segments by template, so
+// DASH runs need a TemplateSigner.
+func WithURLSigner(s URLSigner) Option {
+	return func(o *runOptions) {
+		o.signer = s
+	}
+}
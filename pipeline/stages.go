@@ -0,0 +1,743 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/drm"
+	"github.com/dotsoulja/dotgo-transcode/internal/executil"
+	"github.com/dotsoulja/dotgo-transcode/internal/manifester"
+	"github.com/dotsoulja/dotgo-transcode/internal/metrics"
+	"github.com/dotsoulja/dotgo-transcode/internal/playcheck"
+	"github.com/dotsoulja/dotgo-transcode/internal/remux"
+	"github.com/dotsoulja/dotgo-transcode/internal/scaler"
+	"github.com/dotsoulja/dotgo-transcode/internal/segmenter"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/audio"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/checksum"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/metadata"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/preview"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/subtitles"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/thumbnailer"
+)
+
+// Stage is one step of the pipeline. Stages run in order against a shared Job;
+// each reads what earlier stages produced and fills in its own results.
+// Returning an error aborts the run. Stages that fail softly (e.g. thumbnails)
+// record the problem with Job.Warn and return nil.
+type Stage interface {
+	Name() string
+	Run(ctx context.Context, job *Job) error
+}
+
+// StageFunc adapts a function into a Stage.
+func StageFunc(name string, fn func(ctx context.Context, job *Job) error) Stage {
+	return funcStage{name: name, fn: fn}
+}
+
+type funcStage struct {
+	name string
+	fn   func(ctx context.Context, job *Job) error
+}
+
+func (s funcStage) Name() string                            { return s.name }
+func (s funcStage) Run(ctx context.Context, job *Job) error { return s.fn(ctx, job) }
+
+// TranscodeResult is a re-export of transcoder.TranscodeResult.
+type TranscodeResult = transcoder.TranscodeResult
+
+// SegmentResult is a re-export of segmenter.SegmentResult.
+type SegmentResult = segmenter.SegmentResult
+
+// Download is a variant kept as a progressive MP4 download.
+type Download struct {
+	Variant ResolutionVariant // Variant the file was copied from
+	Path    string            // Faststart MP4 under the download directory
+	Size    int64             // File size in bytes
+}
+
+// Job is the shared state stages operate on.
+type Job struct {
+	Slug         string                  // Output slug derived from the input filename
+	Format       string                  // "hls" or "dash"
+	Profile      *TranscodeProfile       // Profile being executed
+	Client       *scaler.ClientContext   // Optional client context; nil skips preset selection
+	Logger       Logger                  // Stage-aware logger for the run
+	Media        *MediaInfo              // Set by the analyze stage
+	Result       *TranscodeResult        // Set by the transcode stage
+	Encryption   *Key                    // Set by the encrypt stage when a KeyProvider is configured
+	Segments     *SegmentResult          // Set by the segment stage
+	Preview      *preview.Preview        // Set by the preview stage when the profile requests one
+	Sprites      *SpriteSheets           // Set by the thumbnail stage when the profile requests sprites
+	Subtitles    []SubtitleTrack         // Set by the subtitle stage when the profile selects captions
+	Audio        []AudioTrack            // Set by the audio stage when the profile lists audio renditions
+	Downloads    []Download              // Set by the progressive stage when the profile keeps downloads
+	ManifestPath string                  // Set by the manifest stage
+	Metadata     *metadata.MediaMetadata // Set by the metadata stage
+	Report       *Report                 // Report returned to the caller
+	Workspace    *Workspace              // Scratch directory for intermediates, removed when the run ends
+
+	opts     runOptions
+	progress *progressTracker
+	meter    *executil.Meter
+	stopped  bool
+	keyInfo  *drm.KeyInfo
+	live     *liveThumbnails
+	cleanups []func()
+}
+
+// Stop ends the run successfully after the current stage.
+func (j *Job) Stop() {
+	j.stopped = true
+}
+
+// Warn records a non-fatal stage error in the report.
+func (j *Job) Warn(stage string, err error) {
+	j.Report.Errors = append(j.Report.Errors, wrap(stage, err))
+}
+
+// onCleanup registers fn to run when the job finishes, successfully or not.
+func (j *Job) onCleanup(fn func()) {
+	j.cleanups = append(j.cleanups, fn)
+}
+
+// cleanup runs registered cleanup functions in reverse order.
+func (j *Job) cleanup() {
+	for i := len(j.cleanups) - 1; i >= 0; i-- {
+		j.cleanups[i]()
+	}
+}
+
+// event builds a StageEvent from the current job state.
+func (j *Job) event(stage string) StageEvent {
+	return StageEvent{Stage: stage, Slug: j.Slug, Profile: j.Profile, Media: j.Media, Report: j.Report, Job: j}
+}
+
+// DefaultStages returns the built-in stages in execution order: analyze,
+// transcode, encrypt, segment, thumbnail, preview, subtitle, audio,
+// progressive, manifest, verify, metadata, checksum.
+// Use it as the base list for WithStages when reordering or inserting custom stages.
+func DefaultStages() []Stage {
+	return []Stage{
+		AnalyzeStage(),
+		TranscodeStage(),
+		EncryptStage(),
+		SegmentStage(),
+		ThumbnailStage(),
+		PreviewStage(),
+		SubtitleStage(),
+		AudioStage(),
+		ProgressiveStage(),
+		ManifestStage(),
+		VerifyStage(),
+		MetadataStage(),
+		ChecksumStage(),
+	}
+}
+
+// AnalyzeStage probes the input (and runs any optional analysis passes),
+// rejects sources that failed a deep integrity scan, and selects the initial
+// preset when a client context is set.
+func AnalyzeStage() Stage {
+	return StageFunc(StageAnalyze, func(ctx context.Context, job *Job) error {
+		analyzeOpts := job.Profile.ImageAnalyzeOptions(job.opts.analyzeOptions())
+		if job.Profile.Preview != nil {
+			// Preview clips avoid silent, black, and frozen stretches
+			analyzeOpts.Loudness, analyzeOpts.BlackFreeze = true, true
+		}
+		media, err := analyzer.AnalyzeMediaContext(ctx, job.Profile.InputPath, job.Profile.SegmentLength, job.Logger, analyzeOpts)
+		if err != nil {
+			return wrap("analyze media", err)
+		}
+		job.Media = media
+		if err := job.Profile.ApplyLanguages(media); err != nil {
+			return wrap("languages", err)
+		}
+		// A deep scan that found corruption stops the run before any encoding
+		if err := media.Integrity.Err(); err != nil {
+			return wrap("integrity scan", err)
+		}
+		job.Report.Duration = media.Duration
+
+		// Select resolution preset
+		if job.Client != nil {
+			// Bitrate thresholds follow the codec the ladder is encoded in
+			client := *job.Client
+			if client.VideoCodec == "" {
+				client.VideoCodec = job.Profile.VideoCodec
+			}
+			initialPreset, err := scaler.SelectPreset(media.Width, media.Height, &client)
+			if err != nil {
+				return wrap("select preset", err)
+			}
+			_ = initialPreset // optional: log or use for override
+		}
+		return nil
+	})
+}
+
+// dryRunStage prints the command plan and stops before anything executes.
+func dryRunStage() Stage {
+	return StageFunc("plan", func(ctx context.Context, job *Job) error {
+		if job.opts.upgrade != nil {
+			plan, err := planUpgrade(job)
+			if err != nil {
+				return err
+			}
+			plan.Print(os.Stdout)
+		}
+		job.Report.Plan = BuildPlan(job.Profile, job.Media, job.Format, job.Logger)
+		job.Report.Plan.Print(os.Stdout)
+		job.Report.VariantCount = len(job.Report.Plan.Transcodes)
+		job.Report.ManifestCount = len(job.Report.Plan.Segments)
+		job.Report.ManifestPath = job.Report.Plan.MasterManifest
+		job.Stop()
+		return nil
+	})
+}
+
+// TranscodeStage encodes every variant of the ladder, locally or across the
+// cluster configured with WithCluster, and warns about variants whose
+// duration drifted from the source's. With thumbnail_source "live" it also
+// starts extracting thumbnails from the input alongside the encodes.
+func TranscodeStage() Stage {
+	return StageFunc(StageTranscode, func(ctx context.Context, job *Job) error {
+		start := time.Now()
+		job.startLiveThumbnails(ctx)
+		transcode := transcoder.TranscodeWithOptions
+		if job.opts.cluster != nil {
+			transcode = job.opts.cluster.Transcode
+		}
+		topts := job.opts.transcodeOptions(ctx, job.progress)
+		// Checkpointed chunks outlive this run's workspace so a retry resumes them
+		topts.Scratch = job.Workspace.CheckpointDir(checkpointKey(job.Profile))
+		topts.Workdir = job.Workspace.Dir
+		if job.opts.upgrade != nil {
+			plan, err := planUpgrade(job)
+			if err != nil {
+				return err
+			}
+			topts.Keep = plan.Kept()
+		}
+		result, err := transcode(ctx, job.Profile, job.Media, job.Logger, topts)
+		if err != nil {
+			return wrap("transcode", err)
+		}
+		// Empty once every chunked variant has been joined
+		os.Remove(topts.Scratch)
+		metrics.Default.ObserveRealtimeFactor(job.Media.Duration, time.Since(start))
+		job.Result = result
+		job.Report.VariantCount = len(result.Variants)
+		job.Report.Variants = result.Variants
+		job.Report.Adjustments = result.Adjustments
+		job.Report.Degraded = result.Degraded
+		for _, e := range result.Errors {
+			job.Report.Errors = append(job.Report.Errors, e)
+		}
+		checkVariantDurations(job)
+		return nil
+	})
+}
+
+// checkpointKey identifies profile's job across runs for its checkpoint
+// directory: jobs sharing a slug differ in tenant, input, or output.
+func checkpointKey(profile *TranscodeProfile) string {
+	input, output := profile.InputPath, profile.SlugDir()
+	if abs, err := filepath.Abs(input); err == nil {
+		input = abs
+	}
+	if abs, err := filepath.Abs(output); err == nil {
+		output = abs
+	}
+	return strings.Join([]string{profile.Tenant, input, output}, "\x00")
+}
+
+// SegmentStage packages each encoded variant into HLS/DASH segments, encrypting
+// them when the encrypt stage prepared a key. HLS playlists whose segments
+// don't add up to the source duration are reported as warnings.
+func SegmentStage() Stage {
+	return StageFunc(StageSegment, func(ctx context.Context, job *Job) error {
+		segOpts := segmenter.SegmentOptions{Key: job.Encryption, Scratch: job.Workspace.Dir}
+		if job.keyInfo != nil {
+			segOpts.KeyInfoFile = job.keyInfo.Path
+		}
+		segResult, err := segmenter.SegmentMediaContext(ctx, job.Result, job.Format, job.Media, job.Logger, segOpts)
+		if err != nil {
+			return wrap("segment", err)
+		}
+		job.Segments = segResult
+		job.Report.ManifestCount = len(segResult.Manifests)
+		for _, e := range segResult.Errors {
+			job.Report.Errors = append(job.Report.Errors, e)
+		}
+		checkPlaylistDurations(job)
+		return nil
+	})
+}
+
+// ThumbnailStage extracts scrubber thumbnails, reporting job progress as it
+// goes and listing them in the Report and SegmentResult, then renders sprite
+// sheets when the profile requests them. With thumbnail_source "live" the
+// transcode stage already started both, and this stage collects them.
+// Failures are non-fatal.
+func ThumbnailStage() Stage {
+	return StageFunc(StageThumbnail, func(ctx context.Context, job *Job) error {
+		if job.live != nil {
+			live, err := job.waitLiveThumbnails(ctx)
+			if err != nil {
+				return wrap("thumbnail", err)
+			}
+			job.useThumbnails(live.thumbs, live.thumbErr)
+			if live.spritesErr != nil {
+				job.Warn("thumbnail", live.spritesErr)
+			}
+			job.Sprites = live.sprites
+			return nil
+		}
+
+		opts := thumbnailer.Options{Progress: func(done, total int) {
+			job.progress.update(StageThumbnail, float64(done)/float64(total)*100)
+		}}
+		thumbs, err := thumbnailer.GenerateThumbnailsContext(ctx, *job.Media, *job.Result, job.Slug, job.Logger, opts)
+		if ctx.Err() != nil {
+			return wrap("thumbnail", context.Cause(ctx))
+		}
+		job.useThumbnails(thumbs, err)
+
+		sprites, err := thumbnailer.GenerateSprites(ctx, *job.Media, *job.Result, job.Logger)
+		if ctx.Err() != nil {
+			return wrap("thumbnail", context.Cause(ctx))
+		}
+		if err != nil {
+			job.Warn("thumbnail", err)
+		}
+		job.Sprites = sprites
+		return nil
+	})
+}
+
+// useThumbnails lists extracted thumbnails in the Report and SegmentResult,
+// recording err as a warning.
+func (j *Job) useThumbnails(thumbs *thumbnailer.Result, err error) {
+	if thumbs != nil {
+		j.Report.Thumbnails = thumbs.Thumbnails
+		j.Report.ThumbnailDir = thumbs.Dir
+		if j.Segments != nil {
+			j.Segments.Thumbnails = thumbs.Paths(j.Segments.OutputDir)
+		}
+	}
+	if err != nil {
+		j.Warn("thumbnail", err)
+	}
+}
+
+// PreviewStage builds a short trailer (MP4 plus a single HLS rendition) from
+// non-silent, non-black sections of the source when the profile has a preview
+// block. Failures are non-fatal.
+func PreviewStage() Stage {
+	return StageFunc(StagePreview, func(ctx context.Context, job *Job) error {
+		if job.Profile.Preview == nil {
+			return nil
+		}
+		p, err := preview.Generate(ctx, job.Profile, job.Media, job.Result.OutputDir, job.Logger)
+		if err != nil {
+			job.Warn("preview", err)
+			return nil
+		}
+		job.Preview = p
+		job.Report.PreviewPath = p.Playlist
+		return nil
+	})
+}
+
+// ProgressiveStage copies the variants the profile keeps as progressive
+// downloads into <slug>/downloads/ (or progressive.dir), with the moov moved
+// ahead of the media data so each file plays while it downloads. Variants
+// that fail to copy are reported as warnings. Encrypted runs are skipped,
+// since the downloads would be clear copies of the protected streams, unless
+// the profile sets progressive.allow_clear.
+func ProgressiveStage() Stage {
+	return StageFunc(StageProgressive, func(ctx context.Context, job *Job) error {
+		variants := job.Profile.ProgressiveVariants(job.Result.Variants)
+		if len(variants) == 0 {
+			return nil
+		}
+		if job.Encryption != nil && !job.Profile.Progressive.AllowClear {
+			job.Logger.LogStage("progressive", "🔒 Skipping progressive downloads: the streams are encrypted and the downloads would not be (set progressive.allow_clear to keep them)")
+			return nil
+		}
+		dir := filepath.Join(job.Result.OutputDir, job.Profile.Progressive.DirName())
+		for _, v := range variants {
+			if err := ctx.Err(); err != nil {
+				return wrap("progressive", err)
+			}
+			dst := filepath.Join(dir, v.OutputFilename)
+			if err := remux.Faststart(filepath.Join(job.Result.OutputDir, v.OutputFilename), dst); err != nil {
+				job.Warn("progressive", err)
+				continue
+			}
+			var size int64
+			if fi, err := os.Stat(dst); err == nil {
+				size = fi.Size()
+			}
+			job.Downloads = append(job.Downloads, Download{Variant: v, Path: dst, Size: size})
+		}
+		job.Logger.LogStage("progressive", fmt.Sprintf("📦 %d progressive download(s) written to %s", len(job.Downloads), dir))
+		return nil
+	})
+}
+
+// SubtitleStage converts the profile's embedded and external captions, and
+// any forced-narrative subtitles not burned into the video, into WebVTT
+// renditions under <slug>/subtitles/ for the master manifest to list.
+// Captions that fail to convert are reported as warnings.
+func SubtitleStage() Stage {
+	return StageFunc(StageSubtitle, func(ctx context.Context, job *Job) error {
+		tracks, err := subtitles.Generate(ctx, job.Profile, job.Media, job.Result.OutputDir, job.Workspace.Dir, videoStart(ctx, job), job.Logger)
+		if ctx.Err() != nil {
+			return wrap("subtitle", context.Cause(ctx))
+		}
+		if err != nil {
+			job.Warn("subtitle", err)
+		}
+		job.Subtitles = tracks
+		return nil
+	})
+}
+
+// videoStart probes the first variant playlist for the time its video
+// starts at, falling back to the MPEG-TS muxer default.
+func videoStart(ctx context.Context, job *Job) float64 {
+	if job.Segments == nil || len(job.Segments.Manifests) == 0 || job.Segments.Format != "hls" {
+		return subtitles.DefaultVideoStart
+	}
+	start, err := analyzer.ProbeStartTime(ctx, job.Segments.Manifests[0])
+	if err != nil {
+		job.Logger.LogStage("subtitle", fmt.Sprintf("⚠️ Could not probe the video start time, assuming %gs: %v", subtitles.DefaultVideoStart, err))
+		return subtitles.DefaultVideoStart
+	}
+	return start
+}
+
+// AudioStage encodes, or passes through, the profile's alternate audio
+// renditions (e.g. AC-3/E-AC-3), and the primary audio in the demuxed layout,
+// under <slug>/audio/, segmented like the video and encrypted with the same
+// key, for the master manifest to list.
+// Renditions that fail are reported as warnings.
+func AudioStage() Stage {
+	return StageFunc(StageAudio, func(ctx context.Context, job *Job) error {
+		if len(job.Profile.OutputAudioRenditions(job.Media)) == 0 {
+			return nil
+		}
+		opts := audio.Options{SegmentLength: segmenter.SegmentLength(job.Profile, job.Media)}
+		if job.keyInfo != nil {
+			opts.KeyInfoFile = job.keyInfo.Path
+		}
+		tracks, err := audio.Generate(ctx, job.Profile, job.Media, job.Result.OutputDir, job.Format, opts, job.Logger)
+		if ctx.Err() != nil {
+			return wrap("audio", context.Cause(ctx))
+		}
+		if err != nil {
+			job.Warn("audio", err)
+		}
+		job.Audio = tracks
+		return nil
+	})
+}
+
+// ManifestStage writes the master manifest referencing every variant, signing
+// URIs when the run has a URLSigner. DASH masters get an ad period per cue
+// point. Ladder upgrades rewrite it from the full ladder, then prune obsolete
+// rungs when asked to.
+func ManifestStage() Stage {
+	return StageFunc(StageManifest, func(ctx context.Context, job *Job) error {
+		preserve := job.Profile.PreserveManifest && job.opts.upgrade == nil
+		opts := manifester.ManifestOptions{
+			Signer:      job.opts.signer,
+			SessionData: job.Profile.SessionData,
+			Start:       job.Profile.Start,
+			Subtitles:   subtitleRenditions(job.Result.OutputDir, job.Subtitles),
+			Audio:       audioRenditions(job.Result.OutputDir, job.Audio, job.Profile, job.Media),
+			Demuxed:     job.Profile.Demuxed() && len(job.Audio) > 0,
+			MaxVersion:  job.Profile.HLSVersion,
+			AdBreaks:    adBreaks(job),
+		}
+		if job.Media != nil {
+			opts.Duration = job.Media.Duration
+		}
+		if err := job.Profile.CheckMinimumLadder(segmentedVariants(job.Segments)); err != nil {
+			return wrap("manifest", err)
+		}
+		manifestPath, err := manifester.GenerateMasterManifestWithOptions(job.Segments, preserve, job.Logger, opts)
+		if err != nil {
+			return wrap("manifest", err)
+		}
+		job.ManifestPath = manifestPath
+		job.Report.ManifestPath = manifestPath
+		if job.opts.upgrade != nil && job.opts.upgrade.Prune {
+			pruneObsolete(job)
+		}
+		return nil
+	})
+}
+
+// segmentedVariants returns each variant segmented into at least one
+// manifest, once.
+func segmentedVariants(seg *segmenter.SegmentResult) []ResolutionVariant {
+	if seg == nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var out []ResolutionVariant
+	for _, m := range seg.Manifests {
+		v, ok := seg.Variants[m]
+		if ok && !seen[v.OutputFilename] {
+			seen[v.OutputFilename] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// PlaybackReport is a re-export of playcheck.Report, the outcome of a playback check.
+type PlaybackReport = playcheck.Report
+
+// VerifyStage plays back the master manifest when WithPlaybackCheck is set,
+// failing the run on any codec, resolution, duration, or timestamp
+// discrepancy. DASH output is not checked.
+func VerifyStage() Stage {
+	return StageFunc(StageVerify, func(ctx context.Context, job *Job) error {
+		if !job.opts.verify {
+			return nil
+		}
+		if job.ManifestPath == "" {
+			return wrap("verify", fmt.Errorf("%w: the %s stage did not run", ErrMissingPrerequisite, StageManifest))
+		}
+		if job.Format != "hls" {
+			job.Logger.LogStage("verify", "⚠️ Playback check only supports HLS; skipping")
+			return nil
+		}
+		report, err := playcheck.Check(ctx, job.ManifestPath, playcheck.Options{Scratch: job.Workspace.Dir})
+		if err != nil {
+			return wrap("verify", err)
+		}
+		job.Report.Playback = report
+		if err := report.Err(); err != nil {
+			return wrap("verify", err)
+		}
+		unverified := report.Unverified()
+		for _, u := range unverified {
+			job.Logger.LogStage("verify", fmt.Sprintf("⚠️ Playback not verified for %s", u))
+		}
+		job.Logger.LogStage("verify", fmt.Sprintf("▶️ Playback check passed for %d rung(s), %d unverified", len(report.Rungs)-len(unverified), len(unverified)))
+		return nil
+	})
+}
+
+// MetadataStage writes metadata.json with the full output inventory.
+// Failures are non-fatal.
+func MetadataStage() Stage {
+	return StageFunc(StageMetadata, func(ctx context.Context, job *Job) error {
+		removeClearVariants(job) // Before the inventory, so it lists what is published
+		meta := buildMetadata(job.Profile, job.Media, job.Result, job.Segments, job.Report.Thumbnails, job.ManifestPath)
+		for i, t := range job.Subtitles {
+			meta.SubtitleTracks = append(meta.SubtitleTracks, metadata.TrackMetadata{
+				Index:    i,
+				Codec:    "webvtt",
+				Language: t.Language,
+				Title:    t.Name,
+				Forced:   t.Forced,
+				File:     relativeTo(job.Result.OutputDir, t.VTT),
+				Playlist: relativeTo(job.Result.OutputDir, t.Playlist),
+			})
+		}
+		for _, t := range job.Audio {
+			meta.AudioTracks = append(meta.AudioTracks, metadata.TrackMetadata{
+				Index:    len(meta.AudioTracks),
+				Codec:    t.Codec,
+				Language: t.Language,
+				Title:    t.Name,
+				Channels: t.Channels,
+				Playlist: relativeTo(job.Result.OutputDir, t.Manifest),
+			})
+		}
+		if s := job.Sprites; s != nil {
+			meta.Sprites = &metadata.SpriteMetadata{
+				Directory:  relativeTo(job.Result.OutputDir, s.Dir),
+				Storyboard: s.Storyboard,
+				Sheets:     s.Sheets,
+				Interval:   s.Interval,
+				TileWidth:  s.TileWidth,
+				TileHeight: s.TileHeight,
+				Columns:    s.Columns,
+				Rows:       s.Rows,
+				Tiles:      s.Tiles,
+			}
+		}
+		for _, d := range job.Downloads {
+			meta.Downloads = append(meta.Downloads, metadata.DownloadMetadata{
+				Resolution: fmt.Sprintf("%dp", d.Variant.Height),
+				Width:      d.Variant.Width,
+				Height:     d.Variant.Height,
+				Bitrate:    d.Variant.Bitrate,
+				Codecs:     d.Variant.Codecs,
+				Filename:   relativeTo(job.Result.OutputDir, d.Path),
+				FileSize:   d.Size,
+			})
+		}
+		if job.Preview != nil {
+			meta.Preview = &metadata.PreviewMetadata{
+				MP4:      relativeTo(job.Result.OutputDir, job.Preview.MP4),
+				Playlist: relativeTo(job.Result.OutputDir, job.Preview.Playlist),
+				Duration: job.Preview.Duration,
+			}
+		}
+		if job.Encryption != nil {
+			method := "AES-128"
+			if job.Segments != nil && job.Segments.EncryptionMethod != "" {
+				method = job.Segments.EncryptionMethod
+			}
+			meta.Encryption = &metadata.EncryptionMetadata{
+				Method:     method,
+				KeyID:      job.Encryption.KeyIDHex(),
+				LicenseURL: job.Encryption.LicenseURL,
+			}
+		}
+		job.Metadata = &meta
+		if err := metadata.WriteMediaMetadata(job.Result.OutputDir, meta); err != nil {
+			job.Warn("metadata", err)
+			return nil
+		}
+		job.Report.MetadataPath = filepath.Join(job.Result.OutputDir, "metadata.json")
+		job.Logger.LogStage("metadata", fmt.Sprintf("📝 metadata.json written to %s (variants=%d)", job.Report.MetadataPath, len(meta.Variants)))
+		return nil
+	})
+}
+
+// ChecksumStage writes checksums.json when the profile enables checksums.
+// Failures are non-fatal.
+func ChecksumStage() Stage {
+	return StageFunc(StageChecksum, func(ctx context.Context, job *Job) error {
+		if !job.Profile.Checksums {
+			return nil
+		}
+		removeClearVariants(job) // Digests of files about to be removed would fail verification
+		checksumPath, err := checksum.WriteChecksums(job.Result.OutputDir)
+		if err != nil {
+			job.Warn("checksum", err)
+			return nil
+		}
+		job.Report.ChecksumPath = checksumPath
+		job.Logger.LogStage("checksum", fmt.Sprintf("🔐 checksums written to %s", checksumPath))
+		return nil
+	})
+}
+
+// WithStages replaces the stage list for the run. Start from DefaultStages to
+// reorder built-ins or insert custom stages.
+func WithStages(stages ...Stage) Option {
+	return func(o *runOptions) {
+		o.stages = append([]Stage(nil), stages...)
+		o.stagesSet = true
+	}
+}
+
+// WithStageReplaced swaps the stage with the given name for s (e.g. replace
+// StageSegment with a Bento4-based packager). Unknown names are ignored.
+func WithStageReplaced(name string, s Stage) Option {
+	return func(o *runOptions) {
+		o.stageEdits = append(o.stageEdits, func(stages []Stage) []Stage {
+			for i, st := range stages {
+				if st.Name() == name {
+					stages[i] = s
+				}
+			}
+			return stages
+		})
+	}
+}
+
+// WithStageSkipped removes the named stages from the run. Skipping a stage
+// that later stages depend on (e.g. StageTranscode) makes the run fail with
+// ErrMissingPrerequisite before any stage runs.
+func WithStageSkipped(names ...string) Option {
+	return func(o *runOptions) {
+		o.stageEdits = append(o.stageEdits, func(stages []Stage) []Stage {
+			kept := stages[:0]
+			for _, st := range stages {
+				if !containsName(names, st.Name()) {
+					kept = append(kept, st)
+				}
+			}
+			return kept
+		})
+	}
+}
+
+// WithStageAfter inserts s immediately after the named stage (e.g. a virus
+// scan after StageAnalyze). Unknown names append s at the end.
+func WithStageAfter(name string, s Stage) Option {
+	return func(o *runOptions) {
+		o.stageEdits = append(o.stageEdits, func(stages []Stage) []Stage {
+			for i, st := range stages {
+				if st.Name() == name {
+					return append(stages[:i+1], append([]Stage{s}, stages[i+1:]...)...)
+				}
+			}
+			return append(stages, s)
+		})
+	}
+}
+
+// pipelineStages resolves the stage list for a run: the configured or default
+// stages with edits applied, plus the publish stage when running WithInMemory
+// and the dry-run planner after analyze. Lists in which a stage runs without
+// the stages it depends on are rejected, as are dry runs without analyze.
+func (o runOptions) pipelineStages() ([]Stage, error) {
+	stages := DefaultStages()
+	if o.stagesSet {
+		stages = append([]Stage(nil), o.stages...)
+	}
+	for _, edit := range o.stageEdits {
+		stages = edit(stages)
+	}
+	if o.storage != nil && !o.dryRun {
+		stages = append(stages, PublishStage())
+	}
+	if o.dryRun {
+		i := stageIndex(stages, StageAnalyze)
+		if i < 0 {
+			return nil, fmt.Errorf("%w: a dry run needs the %s stage to plan from", ErrMissingPrerequisite, StageAnalyze)
+		}
+		stages = append(append(stages[:i+1:i+1], dryRunStage()), stages[i+1:]...)
+	}
+	if err := checkStageOrder(stages); err != nil {
+		return nil, err
+	}
+	return stages, nil
+}
+
+// stageIndex returns the position of the named stage in stages, or -1.
+func stageIndex(stages []Stage, name string) int {
+	for i, st := range stages {
+		if st.Name() == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// containsName reports whether names includes name.
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
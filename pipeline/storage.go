@@ -0,0 +1,136 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/workspace"
+)
+
+// Storage receives a run's outputs when running WithInMemory. Keys are
+// slash-separated paths relative to the profile's output directory (e.g.
+// "my-clip/720p_3000kbps/segment_000.ts"), as they would be laid out on disk.
+type Storage interface {
+	// Put stores size bytes read from r under key, replacing any object
+	// already there.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+}
+
+// DirStorage returns a Storage writing objects as files under dir.
+func DirStorage(dir string) Storage {
+	return dirStorage{dir: dir}
+}
+
+// dirStorage is the Storage returned by DirStorage.
+type dirStorage struct {
+	dir string
+}
+
+func (d dirStorage) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	dst := filepath.Join(d.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WithInMemory runs short clips without disk round trips: the output tree
+// is written to the run's workspace, placed on a RAM-backed filesystem
+// (/dev/shm) unless WithWorkspace sets a root, and a final publish stage
+// hands segments, manifests, and sidecar files to store. Encoded variant
+// files are intermediates and aren't stored. Local paths in the Report name
+// the workspace copies, which are removed when the run ends; Report.Stored
+// lists the keys written to store. Set a workspace quota to bound memory
+// use on clips longer than expected.
+func WithInMemory(store Storage) Option {
+	return func(o *runOptions) {
+		o.storage = store
+	}
+}
+
+// workspaceOptions returns the workspace settings for a run, rooting the
+// workspace in memory when running WithInMemory.
+func (o runOptions) workspaceOptions() WorkspaceOptions {
+	w := o.workspace
+	if o.storage != nil && w.Root == "" {
+		w.Root = workspace.MemoryRoot()
+	}
+	return w
+}
+
+// inMemoryProfile returns a copy of profile writing its outputs under ws,
+// keeping the slug the run was given.
+func inMemoryProfile(profile *TranscodeProfile, slug string, ws *Workspace) *TranscodeProfile {
+	p := *profile
+	p.OutputDir = ws.Path("output")
+	p.Slug = slug
+	return &p
+}
+
+// PublishStage copies the run's outputs to the Storage given to
+// WithInMemory, skipping the encoded variant files segments were cut from.
+// It is added after the last stage when running WithInMemory.
+func PublishStage() Stage {
+	return StageFunc(StagePublish, func(ctx context.Context, job *Job) error {
+		store := job.opts.storage
+		if store == nil || job.Result == nil {
+			return nil
+		}
+		skip := make(map[string]bool, len(job.Result.Variants))
+		for _, v := range job.Result.Variants {
+			skip[filepath.Join(job.Result.OutputDir, v.OutputFilename)] = true
+		}
+
+		root := job.Profile.OutputDir
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || !d.Type().IsRegular() || skip[p] {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			key := path.Clean(filepath.ToSlash(rel))
+			if err := putFile(ctx, store, key, p); err != nil {
+				return fmt.Errorf("failed to store %s: %w", key, err)
+			}
+			job.Report.Stored = append(job.Report.Stored, key)
+			return nil
+		})
+		if err != nil {
+			return wrap("publish", err)
+		}
+		job.Logger.LogStage("publish", fmt.Sprintf("📤 Stored %d files", len(job.Report.Stored)))
+		return nil
+	})
+}
+
+// putFile stores the file at p under key.
+func putFile(ctx context.Context, store Storage, key, p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return store.Put(ctx, key, f, info.Size())
+}
@@ -0,0 +1,79 @@
+package pipeline
+
+import (
+	"time"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/manifester"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/subtitles"
+)
+
+// SubtitleSettings is a re-export of transcoder.SubtitleSettings, the
+// captions a profile publishes as WebVTT renditions.
+type SubtitleSettings = transcoder.SubtitleSettings
+
+// SubtitleFile is a re-export of transcoder.SubtitleFile.
+type SubtitleFile = transcoder.SubtitleFile
+
+// Forced subtitle modes for TranscodeProfile.ForcedSubtitles, re-exported for callers.
+const (
+	ForcedSubtitlesAuto      = transcoder.ForcedAuto      // Text tracks as FORCED=YES renditions, bitmap tracks burned in (default)
+	ForcedSubtitlesRendition = transcoder.ForcedRendition // Text tracks as FORCED=YES renditions only
+	ForcedSubtitlesBurn      = transcoder.ForcedBurn      // Burn the primary forced track into every variant
+	ForcedSubtitlesOff       = transcoder.ForcedOff       // No special handling
+)
+
+// SubtitleTrack is a re-export of subtitles.Track, a rendition written by the
+// subtitle stage.
+type SubtitleTrack = subtitles.Track
+
+// SubtitleFormat is a re-export of subtitles.Format.
+type SubtitleFormat = subtitles.Format
+
+// Subtitle formats re-exported for callers.
+const (
+	SubtitleSRT  = subtitles.SRT
+	SubtitleVTT  = subtitles.VTT
+	SubtitleTTML = subtitles.TTML
+)
+
+// SubtitleCue is a re-export of subtitles.Cue.
+type SubtitleCue = subtitles.Cue
+
+// ErrUnknownSubtitleFormat is returned when a caption format can't be determined.
+var ErrUnknownSubtitleFormat = subtitles.ErrUnknownFormat
+
+// ConvertSubtitles converts caption data between SRT, WebVTT, and TTML,
+// shifting every cue by shift. The source encoding (UTF-8, UTF-16, or
+// Windows-1252) is detected, and an empty from sniffs the source format.
+func ConvertSubtitles(data []byte, from, to SubtitleFormat, shift time.Duration) ([]byte, error) {
+	return subtitles.Convert(data, from, to, shift)
+}
+
+// ConvertSubtitleFile converts src into dst, taking both formats from the
+// file extensions.
+func ConvertSubtitleFile(src, dst string, shift time.Duration) error {
+	return subtitles.ConvertFile(src, dst, shift)
+}
+
+// ParseSubtitles decodes caption data into cues; an empty format is sniffed.
+func ParseSubtitles(data []byte, format SubtitleFormat) ([]SubtitleCue, error) {
+	return subtitles.Parse(data, format)
+}
+
+// subtitleRenditions lists tracks for the master manifest, with paths
+// relative to the slug directory.
+func subtitleRenditions(slugDir string, tracks []SubtitleTrack) []manifester.SubtitleRendition {
+	var out []manifester.SubtitleRendition
+	for _, t := range tracks {
+		out = append(out, manifester.SubtitleRendition{
+			Name:     t.Name,
+			Language: t.Language,
+			Default:  t.Default,
+			Forced:   t.Forced,
+			Playlist: relativeTo(slugDir, t.Playlist),
+			VTT:      relativeTo(slugDir, t.VTT),
+		})
+	}
+	return out
+}
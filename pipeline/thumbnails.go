@@ -0,0 +1,71 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/thumbnailer"
+)
+
+// liveThumbnails is a thumbnail and sprite extraction running beside the
+// transcode stage for profiles with thumbnail_source "live". Fields are set
+// before done is closed.
+type liveThumbnails struct {
+	done       chan struct{}
+	thumbs     *thumbnailer.Result
+	thumbErr   error
+	sprites    *SpriteSheets
+	spritesErr error
+}
+
+// startLiveThumbnails samples the input for thumbnails and sprites in the
+// background while the variants encode. The output directory and scale come
+// from the transcode plan, since no variant exists yet, and timestamps are
+// shifted by the pre-roll the variants will start with. The extraction is
+// canceled and waited for when the job ends, so it never outlives the run.
+func (j *Job) startLiveThumbnails(ctx context.Context) {
+	if j.Profile.ThumbnailSourceMode() != transcoder.ThumbnailSourceLive || j.live != nil {
+		return
+	}
+	result := *transcoder.PlanTranscode(j.Profile, j.Media, stagelog.Nop).Result(j.Profile, j.Media)
+	pre, post, err := j.Profile.BumperDurations(ctx)
+	if err != nil {
+		// The transcode stage reports the unusable bumper
+		return
+	}
+	result.BumperDuration, result.PreRoll = pre+post, pre
+
+	ctx, cancel := context.WithCancel(ctx)
+	live := &liveThumbnails{done: make(chan struct{})}
+	j.live = live
+	j.onCleanup(func() {
+		cancel()
+		<-live.done
+	})
+	j.Logger.LogStage("thumbnail", "🎬 Extracting thumbnails from the input while variants encode")
+	go func() {
+		defer close(live.done)
+		live.thumbs, live.thumbErr = thumbnailer.GenerateThumbnailsContext(ctx, *j.Media, result, j.Slug, j.Logger, thumbnailer.Options{})
+		if ctx.Err() == nil {
+			live.sprites, live.spritesErr = thumbnailer.GenerateSprites(ctx, *j.Media, result, j.Logger)
+		}
+	}()
+}
+
+// waitLiveThumbnails waits for the live extraction to finish and returns its
+// results, in the same shape the thumbnail stage's own extraction has.
+func (j *Job) waitLiveThumbnails(ctx context.Context) (*liveThumbnails, error) {
+	select {
+	case <-j.live.done:
+	default:
+		j.Logger.LogStage("thumbnail", "⏳ Waiting for live thumbnail extraction to finish")
+		select {
+		case <-j.live.done:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for live thumbnails: %w", ctx.Err())
+		}
+	}
+	return j.live, nil
+}
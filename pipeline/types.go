@@ -1,9 +1,190 @@
 package pipeline
 
 import (
+	"github.com/dotsoulja/dotgo-transcode/internal/analyzer"
+	"github.com/dotsoulja/dotgo-transcode/internal/manifester"
 	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/layout"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/namer"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/thumbnailer"
 )
 
+// Output layouts for TranscodeProfile.OutputLayout. Custom templates may use
+// {slug}, {tenant}, {date}, {year}, {month}, and {day}; VariantLayout
+// templates may also use {label}, {height}, and {bitrate}. {tenant} is
+// TranscodeProfile.Tenant; templates without it are placed under
+// <output_dir>/<tenant>/ for jobs that have one.
+const (
+	LayoutPerSlug = layout.DefaultSlug // <output_dir>/<slug>/ (default)
+	LayoutFlat    = layout.Flat        // <output_dir>/ with no per-job directory
+	LayoutByDate  = "{date}/{slug}"    // <output_dir>/<YYYY-MM-DD>/<slug>/
+)
+
+// Thumbnail sources for TranscodeProfile.ThumbnailSource.
+const (
+	ThumbnailSourceAuto    = transcoder.ThumbnailSourceAuto    // Variant at the source height, else the tallest variant, else the input (default)
+	ThumbnailSourceVariant = transcoder.ThumbnailSourceVariant // Tallest transcoded variant
+	ThumbnailSourceInput   = transcoder.ThumbnailSourceInput   // Original input, scaled to the tallest variant
+	ThumbnailSourceLive    = transcoder.ThumbnailSourceLive    // Original input, sampled while the variants encode
+)
+
+// Low-source policies for LowSourceSettings.Policy.
+const (
+	LowSourceSkip       = transcoder.LowSourceSkip       // Drop rungs above the source (default)
+	LowSourceSynthesize = transcoder.LowSourceSynthesize // Add lower-bitrate rungs at the source's resolution
+	LowSourceUpscale    = transcoder.LowSourceUpscale    // Upscale the smallest rungs above the source
+	LowSourceFail       = transcoder.LowSourceFail       // Fail the transcode
+)
+
+// LowSourceSettings is a re-export of transcoder.LowSourceSettings (the
+// fallback ladder policy for low-resolution sources).
+type LowSourceSettings = transcoder.LowSourceSettings
+
+// CheckpointSettings is a re-export of transcoder.CheckpointSettings
+// (chunked, resumable encodes for long sources).
+type CheckpointSettings = transcoder.CheckpointSettings
+
+// Partial-failure policies for PartialFailureSettings.Policy.
+const (
+	PartialPublish  = transcoder.PartialPublish  // Publish the variants that succeeded and mark the run degraded (default)
+	PartialFailFast = transcoder.PartialFailFast // Cancel the other encodes and fail at the first failed variant
+	PartialMinRungs = transcoder.PartialMinRungs // Fail when fewer than MinRungs variants succeed
+)
+
+// PartialFailureSettings is a re-export of transcoder.PartialFailureSettings
+// (what a run does when some variants fail to encode).
+type PartialFailureSettings = transcoder.PartialFailureSettings
+
+// MinimumLadder is a re-export of transcoder.MinimumLadder (the rungs that
+// must be ready before the master manifest is written).
+type MinimumLadder = transcoder.MinimumLadder
+
+// ErrLadderIncomplete is a re-export of transcoder.ErrLadderIncomplete,
+// wrapped by the manifest stage error when the minimum ladder isn't met.
+var ErrLadderIncomplete = transcoder.ErrLadderIncomplete
+
+// StillSettings is a re-export of transcoder.StillSettings (the clip made
+// from an image or image sequence input).
+type StillSettings = transcoder.StillSettings
+
+// BumperSettings and Bumper are re-exports of transcoder.BumperSettings and
+// transcoder.Bumper (slates and clips joined before and after every output).
+type (
+	BumperSettings = transcoder.BumperSettings
+	Bumper         = transcoder.Bumper
+)
+
+// LanguageSettings is a re-export of transcoder.LanguageSettings (language
+// tags for untagged or mislabeled audio and subtitle streams).
+type LanguageSettings = transcoder.LanguageSettings
+
+// ErrLanguageMissing is a re-export of transcoder.ErrLanguageMissing, wrapped
+// by the analyze stage error when languages.require finds an untagged track.
+var ErrLanguageMissing = transcoder.ErrLanguageMissing
+
+// ProgressiveSettings is a re-export of transcoder.ProgressiveSettings
+// (variants kept as faststart MP4 downloads).
+type ProgressiveSettings = transcoder.ProgressiveSettings
+
+// ImageSource is a re-export of analyzer.ImageSource, set in MediaInfo.Image
+// for image inputs.
+type ImageSource = analyzer.ImageSource
+
+// ReadThrottle is a re-export of transcoder.ReadThrottle (paced source reads
+// for shared network storage).
+type ReadThrottle = transcoder.ReadThrottle
+
+// Source bitrate checks for TranscodeProfile.SourceBitrate.
+const (
+	SourceBitrateWarn = transcoder.SourceBitrateWarn // Log variants above the source video bitrate (default)
+	SourceBitrateCap  = transcoder.SourceBitrateCap  // Cap them at the source video bitrate, listed in Report.Adjustments
+	SourceBitrateOff  = transcoder.SourceBitrateOff  // No check
+)
+
+// Content classes for LadderTrim.Content.
+const (
+	ContentAuto       = transcoder.ContentAuto       // Classify the source from analysis
+	ContentFilm       = transcoder.ContentFilm       // Film, TV, and camera footage
+	ContentScreencast = transcoder.ContentScreencast // Screen recordings and slides
+)
+
+// LadderTrim is a re-export of transcoder.LadderTrim (ladder trimming by
+// duration, content class, and variant count).
+type LadderTrim = transcoder.LadderTrim
+
+// AV1Options is a re-export of transcoder.AV1Options (SVT-AV1 preset, film
+// grain, and tile settings).
+type AV1Options = transcoder.AV1Options
+
+// VP9Options is a re-export of transcoder.VP9Options (libvpx deadline, speed,
+// and tile settings).
+type VP9Options = transcoder.VP9Options
+
+// EncodeBudget is a re-export of transcoder.EncodeBudget (threads and memory
+// shared by concurrent variant encodes).
+type EncodeBudget = transcoder.EncodeBudget
+
+// PreviewSettings is a re-export of transcoder.PreviewSettings (trailer length,
+// clip count, resolution, and bitrate).
+type PreviewSettings = transcoder.PreviewSettings
+
+// SpriteSettings is a re-export of transcoder.SpriteSettings (scrubber
+// sprite sheet interval and tile geometry).
+type SpriteSettings = transcoder.SpriteSettings
+
+// SpriteSheets is a re-export of thumbnailer.SpriteSheets, the sprite sheets
+// and storyboard written by the thumbnail stage.
+type SpriteSheets = thumbnailer.SpriteSheets
+
+// ManifestDiff is a re-export of manifester.ManifestDiff, the changes a
+// preserving run would make to an existing HLS master (see Plan.ManifestDiff).
+type ManifestDiff = manifester.ManifestDiff
+
+// SessionData is a re-export of transcoder.SessionData, an
+// #EXT-X-SESSION-DATA entry for the HLS master.
+type SessionData = transcoder.SessionData
+
+// StartOffset is a re-export of transcoder.StartOffset, the #EXT-X-START
+// offset for the HLS master.
+type StartOffset = transcoder.StartOffset
+
+// HWProber is a re-export of transcoder.HWProber, which reports the hardware
+// encoders and devices available for use_hwaccel.
+type HWProber = transcoder.HWProber
+
+// SetHWProber installs the process-wide hardware prober (nil restores the
+// default, which asks the local ffmpeg).
+func SetHWProber(p HWProber) {
+	transcoder.SetHWProber(p)
+}
+
+// Packagers for TranscodeProfile.Packager.
+const (
+	PackagerFFmpeg = transcoder.PackagerFFmpeg
+	PackagerShaka  = transcoder.PackagerShaka
+	PackagerBento4 = transcoder.PackagerBento4
+	PackagerNative = transcoder.PackagerNative
+	PackagerAuto   = transcoder.PackagerAuto
+)
+
+// Stream formats for TranscodeProfile.StreamFormat and Config.StreamFormat.
+const (
+	StreamFormatHLS  = transcoder.StreamFormatHLS
+	StreamFormatDASH = transcoder.StreamFormatDASH
+)
+
+// Slugifier is a re-export of namer.Slugifier, the rules that turn input
+// filenames into output slugs (transliteration, separator, max length).
+type Slugifier = namer.Slugifier
+
+// DefaultSlugifier is the slug pattern used unless SetSlugifier is called.
+var DefaultSlugifier = namer.DefaultSlugifier
+
+// SetSlugifier installs the process-wide slug rules used for every output path.
+func SetSlugifier(s Slugifier) {
+	namer.SetSlugifier(s)
+}
+
 // TranscodeProfile is a re-export of the internal transcoder.TranscodeProfile.
 // This allows external packages to construct dynamic profiles for use with RunPipeline,
 // while keeping the internal transcoder package encapsulated.
@@ -11,3 +192,38 @@ type TranscodeProfile = transcoder.TranscodeProfile
 
 // Variant is a re-export of the transcoder.Variant type for convenience.
 type Variant = transcoder.Variant
+
+// ResolutionVariant is a re-export of transcoder.ResolutionVariant, an encoded
+// output listed in Report.Variants.
+type ResolutionVariant = transcoder.ResolutionVariant
+
+// EncodeStats is a re-export of transcoder.EncodeStats (wall time, fps,
+// realtime factor, output size, measured bitrate).
+type EncodeStats = transcoder.EncodeStats
+
+// MediaInfo is a re-export of analyzer.MediaInfo for callers building plans.
+type MediaInfo = analyzer.MediaInfo
+
+// Interval is a re-export of analyzer.Interval, a time range in seconds
+// (e.g. silent stretches in MediaInfo.Loudness).
+type Interval = analyzer.Interval
+
+// PerceptualFingerprint is a re-export of analyzer.PerceptualFingerprint; compare
+// two with Similarity to detect duplicate uploads.
+type PerceptualFingerprint = analyzer.PerceptualFingerprint
+
+// ProfileBuilder is a re-export of transcoder.ProfileBuilder for constructing
+// profiles fluently (see NewProfile).
+type ProfileBuilder = transcoder.ProfileBuilder
+
+// NewProfile starts a fluent profile builder for backend automation:
+//
+//	profile, err := pipeline.NewProfile(input, output).
+//		WithLadder(ladder...).
+//		WithHLS(4 * time.Second).
+//		Build()
+//
+// Build applies defaults and validation before the profile reaches RunPipeline.
+func NewProfile(inputPath, outputDir string) *ProfileBuilder {
+	return transcoder.NewProfile(inputPath, outputDir)
+}
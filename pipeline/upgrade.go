@@ -0,0 +1,87 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/segmenter"
+	"github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+	"github.com/dotsoulja/dotgo-transcode/internal/utils/stagelog"
+)
+
+// UpgradePlan is a re-export of transcoder.UpgradePlan, the per-rung
+// decisions of a ladder upgrade.
+type UpgradePlan = transcoder.UpgradePlan
+
+// UpgradeStep is a re-export of transcoder.UpgradeStep.
+type UpgradeStep = transcoder.UpgradeStep
+
+// UpgradeOptions is a re-export of transcoder.UpgradeOptions.
+type UpgradeOptions = transcoder.UpgradeOptions
+
+// Upgrade actions re-exported for callers.
+const (
+	UpgradeKeep     = transcoder.UpgradeKeep
+	UpgradeEncode   = transcoder.UpgradeEncode
+	UpgradeReencode = transcoder.UpgradeReencode
+	UpgradeObsolete = transcoder.UpgradeObsolete
+)
+
+// WithUpgrade turns the run into a ladder upgrade of the input's existing
+// output directory: rungs already encoded with the profile's current
+// settings are kept, only new or changed rungs are encoded, and the master
+// manifest is rewritten for the whole ladder. The decisions are recorded in
+// Report.Upgrade; with WithDryRun they are printed and nothing is encoded.
+func WithUpgrade(opts UpgradeOptions) Option {
+	return func(o *runOptions) {
+		o.upgrade = &opts
+	}
+}
+
+// planUpgrade compares the output directory with the profile and records
+// the plan in the report.
+func planUpgrade(job *Job) (*UpgradePlan, error) {
+	plan, err := transcoder.PlanUpgrade(job.Profile, job.Media, job.Logger, *job.opts.upgrade)
+	if err != nil {
+		return nil, wrap("upgrade", err)
+	}
+	job.Report.Upgrade = plan
+	for _, s := range plan.Steps {
+		job.Logger.LogVariant(s.Filename, fmt.Sprintf("🪜 %s: %s", s.Action, s.Reason))
+	}
+	return plan, nil
+}
+
+// pruneObsolete deletes the files and segment directories of rungs the
+// upgraded ladder dropped. Directories still used by a current rung (layouts
+// whose variant directory omits the label) are left alone. Failures are
+// recorded as warnings.
+func pruneObsolete(job *Job) {
+	quiet := stagelog.Filter(job.Logger, stagelog.Quiet)
+	inUse := make(map[string]bool)
+	for _, v := range job.Result.Variants {
+		inUse[segmenter.PlanSegment(job.Result, v, job.Format, job.Media, quiet).OutputDir] = true
+	}
+	for _, v := range job.Report.Upgrade.Obsolete() {
+		if err := os.Remove(filepath.Join(job.Result.OutputDir, v.OutputFilename)); err != nil && !os.IsNotExist(err) {
+			job.Warn("upgrade", err)
+		}
+		dir := segmenter.PlanSegment(job.Result, v, job.Format, job.Media, quiet).OutputDir
+		if inUse[dir] || !within(job.Result.OutputDir, dir) {
+			continue
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			job.Warn("upgrade", err)
+			continue
+		}
+		job.Logger.LogVariant(v.OutputFilename, "🗑️ Pruned obsolete rung")
+	}
+}
+
+// within reports whether path lies strictly inside dir.
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
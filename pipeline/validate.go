@@ -0,0 +1,16 @@
+package pipeline
+
+import "github.com/dotsoulja/dotgo-transcode/internal/transcoder"
+
+// ValidationReport is a re-export of transcoder.ValidationReport.
+type ValidationReport = transcoder.ValidationReport
+
+// ProfileIssue is a re-export of transcoder.ProfileIssue.
+type ProfileIssue = transcoder.ProfileIssue
+
+// ValidateProfile checks a profile and returns every problem found with field paths,
+// plus the defaults that will be applied. Pass media (e.g. from a prior analysis)
+// to also check segment length against the source GOP; nil skips media checks.
+func ValidateProfile(profile *TranscodeProfile, media *MediaInfo) *ValidationReport {
+	return transcoder.ValidateProfile(*profile, media)
+}
@@ -0,0 +1,5 @@
+package pipeline
+
+// Version identifies the dotgo-transcode release that produced a set of outputs.
+// Written into metadata.json so frontends and re-encode tooling can detect stale assets.
+const Version = "0.1.0"
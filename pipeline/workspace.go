@@ -0,0 +1,40 @@
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/dotsoulja/dotgo-transcode/internal/workspace"
+)
+
+// Workspace is a re-export of workspace.Workspace, a run's scratch directory.
+type Workspace = workspace.Workspace
+
+// WorkspaceOptions is a re-export of workspace.Options (root directory,
+// size quota, and whether failed runs keep their workspace).
+type WorkspaceOptions = workspace.Options
+
+// ErrWorkspaceQuota is a re-export of workspace.ErrQuotaExceeded. A run whose
+// workspace outgrows its quota is cancelled with an error wrapping it.
+var ErrWorkspaceQuota = workspace.ErrQuotaExceeded
+
+// WithWorkspace configures the scratch directory every run gets for
+// intermediates (key material, downloaded segments, custom stage files; see
+// Job.Workspace). By default it is created under the system temporary
+// directory without a quota and removed when the run ends.
+func WithWorkspace(opts WorkspaceOptions) Option {
+	return func(o *runOptions) {
+		o.workspace = opts
+	}
+}
+
+// closeWorkspace removes ws once the run ends, keeping it for inspection
+// when the run failed and the options ask for that.
+func closeWorkspace(ws *Workspace, logger Logger, runErr *error) {
+	kept, err := ws.Close(*runErr != nil)
+	switch {
+	case err != nil:
+		logger.LogError("workspace", fmt.Errorf("failed to remove workspace %s: %w", ws.Dir, err))
+	case kept:
+		logger.LogStage("workspace", fmt.Sprintf("🧳 Kept workspace of failed run at %s", ws.Dir))
+	}
+}